@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/engagement"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func approveCmd(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("approve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", os.Getenv("RTECTL_SERVER"), "controller base URL (default $RTECTL_SERVER)")
+	token := fs.String("token", os.Getenv("RTECTL_TOKEN"), "controller bearer token (default $RTECTL_TOKEN)")
+	eng := fs.String("engagement", "", "engagement whose pending approvals to review")
+	ids := fs.String("id", "", "comma-separated task IDs to review (default all pending)")
+	dir := fs.String("f", "", "directory of engagement definitions to diff tasks against")
+	keyPath := fs.String("key", "", "approver ed25519 private key (PKCS#8 PEM)")
+	signCmd := fs.String("sign-command", "", "command that signs stdin with a hardware-held key, instead of -key")
+	pubPath := fs.String("pub", "", "public key (PEM) of the -sign-command key")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl approve: %v\n", err)
+		return exitError
+	}
+	if *eng == "" {
+		return fail(errors.New("-engagement is required"))
+	}
+	var signer rte.Signer
+	var err error
+	switch {
+	case *keyPath != "" && *signCmd != "":
+		return fail(errors.New("-key and -sign-command are exclusive"))
+	case *keyPath != "":
+		signer, err = loadSigner(*keyPath)
+	case *signCmd != "":
+		signer, err = newCommandSigner(*signCmd, *pubPath)
+	default:
+		err = errors.New("-key or -sign-command is required")
+	}
+	if err != nil {
+		return fail(err)
+	}
+	templates := map[string]*engagement.TaskSpec{}
+	lock := &engagement.Lock{}
+	if *dir != "" {
+		files, err := engagement.Load(*dir)
+		if err != nil {
+			return fail(err)
+		}
+		if lock, err = engagement.LoadLock(filepath.Join(*dir, engagement.LockName)); err != nil {
+			return fail(err)
+		}
+		for _, f := range files {
+			if f.Engagement != *eng {
+				continue
+			}
+			for i := range f.Tasks {
+				templates[f.Tasks[i].ID] = &f.Tasks[i]
+			}
+		}
+	}
+	ctl := &engagement.HTTPController{BaseURL: *server, Token: *token}
+	pending, err := ctl.PendingApprovals(ctx, *eng)
+	if err != nil {
+		return fail(err)
+	}
+	if want := splitList(*ids); len(want) > 0 {
+		var selected []engagement.ApprovalRequest
+		for _, req := range pending {
+			for _, id := range want {
+				if req.Task.Task.ID == id {
+					selected = append(selected, req)
+				}
+			}
+		}
+		pending = selected
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(stdout, "No tasks await approval.")
+		return exitOK
+	}
+	// One reader for every prompt, so answers piped in for later tasks are
+	// not lost to an earlier prompt's buffering.
+	in := bufio.NewReader(stdin)
+	approved := 0
+	for _, req := range pending {
+		st := req.Task
+		writeApprovalRequest(stdout, req, templates[st.Task.ID])
+		if prev, ok := lock.Approved(st.Task.Engagement, st.Task.ID); ok {
+			if diffs := rte.Diff(prev.Task, st.Task); len(diffs) > 0 {
+				fmt.Fprintf(stdout, "\n  changed since the approval recorded in %s:\n", engagement.LockName)
+				writeFieldDiffs(stdout, diffs)
+			}
+		}
+		if err := rte.VerifyTaskContext(ctx, &st); err != nil {
+			var te *rte.TamperError
+			if errors.As(err, &te) {
+				fmt.Fprintln(stdout, "\nNot approvable: the task differs from what was signed:")
+				writeFieldDiffs(stdout, te.Diffs)
+				fmt.Fprintln(stdout, "Skipped.")
+				fmt.Fprintln(stdout)
+				continue
+			}
+			fmt.Fprintf(stdout, "\nNot approvable: %v. Skipped.\n\n", err)
+			continue
+		}
+		if st.Approval != nil {
+			fmt.Fprintln(stdout, "\nAlready countersigned. Skipped.")
+			fmt.Fprintln(stdout)
+			continue
+		}
+		fmt.Fprintf(stdout, "\nApprove %s/%s? Only 'yes' will be accepted: ", st.Task.Engagement, st.Task.ID)
+		line, _ := in.ReadString('\n')
+		if strings.TrimSpace(line) != "yes" {
+			fmt.Fprintln(stdout, "Skipped.")
+			fmt.Fprintln(stdout)
+			continue
+		}
+		if err := rte.CountersignContext(ctx, &st, signer); err != nil {
+			return fail(err)
+		}
+		if err := ctl.Submit(ctx, &st); err != nil {
+			return fail(err)
+		}
+		approved++
+		fmt.Fprintf(stdout, "Approved and submitted %s/%s.\n\n", st.Task.Engagement, st.Task.ID)
+	}
+	fmt.Fprintf(stdout, "Approved %d of %d tasks.\n", approved, len(pending))
+	return exitOK
+}
+
+// writeApprovalRequest prints a pending task in full, as a diff against
+// its declared template: unchanged fields indented, fields that differ
+// from the template marked - and +. A task with no template is all +.
+func writeApprovalRequest(w io.Writer, req engagement.ApprovalRequest, tmpl *engagement.TaskSpec) {
+	t := req.Task.Task
+	fmt.Fprintf(w, "Approval requested: %s/%s\n", t.Engagement, t.ID)
+	fmt.Fprintf(w, "  signed by %s, key %s\n", t.Operator, rte.Fingerprint(req.Task.PublicKey))
+	expires := t.Expiry()
+	fmt.Fprintf(w, "  created %s, expires %s\n", t.CreatedAt.Format(time.RFC3339), expires.Format(time.RFC3339))
+	if ttl := t.CreatedAt.Add(time.Duration(t.TTLSeconds) * time.Second); !ttl.Equal(expires) {
+		// Under an approval window the TTL bounds each run from its start.
+		fmt.Fprintf(w, "  approval window ends %s; each run lasts at most %ds (%s if started at creation)\n",
+			expires.Format(time.RFC3339), t.TTLSeconds, ttl.Format(time.RFC3339))
+	}
+	for _, r := range req.Reasons {
+		fmt.Fprintf(w, "  reason: %s\n", r)
+	}
+	if tmpl == nil {
+		fmt.Fprintln(w, "  no template declares this task")
+	}
+	fmt.Fprintln(w)
+
+	spec := engagement.SpecOf(t)
+	fields := engagement.Diff(engagement.TaskSpec{}, spec)
+	var from engagement.TaskSpec
+	if tmpl != nil {
+		from = *tmpl
+	}
+	changed := map[string]engagement.FieldDiff{}
+	for _, d := range engagement.Diff(from, spec) {
+		changed[d.Field] = d
+	}
+	shown := map[string]bool{}
+	line := func(d engagement.FieldDiff) {
+		shown[d.Field] = true
+		c, ok := changed[d.Field]
+		if !ok {
+			fmt.Fprintf(w, "    %s: %q\n", d.Field, d.To)
+			return
+		}
+		if c.From != "" {
+			fmt.Fprintf(w, "  - %s: %q\n", c.Field, c.From)
+		}
+		if c.To != "" {
+			fmt.Fprintf(w, "  + %s: %q\n", c.Field, c.To)
+		}
+	}
+	for _, d := range fields {
+		line(d)
+	}
+	for _, d := range engagement.Diff(from, spec) {
+		if !shown[d.Field] {
+			line(d)
+		}
+	}
+}
+
+// commandSigner signs by running an external program, the seam for keys
+// on hardware tokens: the program gets the message on stdin and writes the
+// raw 64-byte ed25519 signature to stdout.
+type commandSigner struct {
+	argv []string
+	pub  ed25519.PublicKey
+}
+
+// newCommandSigner returns a Signer running command, split on spaces, for
+// the key whose PEM public key is at pubPath.
+func newCommandSigner(command, pubPath string) (rte.Signer, error) {
+	argv := strings.Fields(command)
+	if len(argv) == 0 {
+		return nil, errors.New("sign command is empty")
+	}
+	if pubPath == "" {
+		return nil, errors.New("-pub is required with -sign-command")
+	}
+	data, err := os.ReadFile(pubPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s: expected a PEM PUBLIC KEY block", pubPath)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pubPath, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New(pubPath + ": not an ed25519 key")
+	}
+	return &commandSigner{argv: argv, pub: pub}, nil
+}
+
+func (c *commandSigner) PublicKey() ed25519.PublicKey { return c.pub }
+
+func (c *commandSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	var out, errOut bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.argv[0], c.argv[1:]...)
+	cmd.Stdin = bytes.NewReader(message)
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errOut.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", c.argv[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", c.argv[0], err)
+	}
+	// A token holding a different key than -pub names would otherwise
+	// produce an approval the controller rejects only later.
+	if !ed25519.Verify(c.pub, message, out.Bytes()) {
+		return nil, fmt.Errorf("%s: signature does not verify against the public key", c.argv[0])
+	}
+	return out.Bytes(), nil
+}
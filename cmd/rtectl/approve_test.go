@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/engagement"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// TestSignCommandHelper is not a test: it is the -sign-command the approve
+// test runs, signing stdin with the key named in the environment.
+func TestSignCommandHelper(t *testing.T) {
+	keyPath := os.Getenv("RTECTL_TEST_SIGN_KEY")
+	if keyPath == "" {
+		return
+	}
+	priv, err := loadKey(keyPath)
+	if err != nil {
+		os.Exit(3)
+	}
+	msg, _ := io.ReadAll(os.Stdin)
+	os.Stdout.Write(ed25519.Sign(priv, msg))
+	os.Exit(0)
+}
+
+func TestWriteApprovalRequest_ApprovalWindow(t *testing.T) {
+	created := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	window := created.Add(48 * time.Hour)
+	task := rte.Task{ID: "inv", Engagement: "eng-1", Type: rte.TaskInventory, CreatedAt: created,
+		TTLSeconds: 600, Operator: "op", ApprovedBy: "lead", ApprovalExpiresAt: &window}
+	var b bytes.Buffer
+	writeApprovalRequest(&b, engagement.ApprovalRequest{Task: rte.SignedTask{Task: task}}, nil)
+	for _, want := range []string{
+		"created 2026-03-02T09:00:00Z, expires 2026-03-04T09:00:00Z",
+		"approval window ends 2026-03-04T09:00:00Z; each run lasts at most 600s (2026-03-02T09:10:00Z if started at creation)",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, b.String())
+		}
+	}
+
+	task.ApprovalExpiresAt = nil
+	b.Reset()
+	writeApprovalRequest(&b, engagement.ApprovalRequest{Task: rte.SignedTask{Task: task}}, nil)
+	if !strings.Contains(b.String(), "expires 2026-03-02T09:10:00Z") || strings.Contains(b.String(), "approval window") {
+		t.Errorf("task without an approval window:\n%s", b.String())
+	}
+}
+
+func TestRun_Approve(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	def := `{"engagement": "eng-1", "tasks": [{"id": "inv", "type": "inventory", "ttl_seconds": 600,
+		"operator": "op", "approved_by": "lead"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "eng.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opPub, opPriv, _ := rte.GenerateKeyPair()
+	var pending []engagement.ApprovalRequest
+	for _, id := range []string{"inv", "beacon"} {
+		st, err := rte.SignTask(rte.Task{
+			ID: id, Engagement: "eng-1", Type: rte.TaskInventory, CreatedAt: time.Now().UTC(),
+			TTLSeconds: 900, Operator: "op", ApprovedBy: "lead", State: rte.StatePending,
+		}, opPriv, opPub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pending = append(pending, engagement.ApprovalRequest{Task: *st, Reasons: []string{"new task"}})
+	}
+	// The lock records "inv" as approved with its declared TTL.
+	leadPub, leadPriv, _ := rte.GenerateKeyPair()
+	prev := pending[0].Task.Task
+	prev.TTLSeconds = 600
+	locked, err := rte.SignTask(prev, opPriv, opPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rte.Countersign(locked, leadPriv, leadPub); err != nil {
+		t.Fatal(err)
+	}
+	lock := &engagement.Lock{Tasks: map[string]rte.SignedTask{}}
+	lock.Record(*locked)
+	if err := lock.Save(filepath.Join(dir, engagement.LockName)); err != nil {
+		t.Fatal(err)
+	}
+	var submitted []rte.SignedTask
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/approvals"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"approvals": pending})
+		case r.Method == http.MethodPost:
+			var st rte.SignedTask
+			_ = json.NewDecoder(r.Body).Decode(&st)
+			submitted = append(submitted, st)
+		}
+	}))
+	defer srv.Close()
+	key := filepath.Join(dir, "lead.pem")
+	var stdout, stderr bytes.Buffer
+	if code := run(ctx, []string{"keygen", "-o", key}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("keygen exit = %d, stderr: %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	args := []string{"approve", "-server", srv.URL, "-engagement", "eng-1", "-f", dir, "-key", key}
+	if code := run(ctx, args, strings.NewReader("yes\nno\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		`  - ttl_seconds: "600"`, `  + ttl_seconds: "900"`, `    type: "inventory"`,
+		"reason: new task", "no template declares this task", "Approved 1 of 2 tasks.",
+		"changed since the approval recorded in rte.lock.json", `      ttl_seconds: "600" -> "900"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if len(submitted) != 1 || submitted[0].Task.ID != "inv" {
+		t.Fatalf("submitted = %+v", submitted)
+	}
+	if err := rte.VerifyApproval(&submitted[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("RTECTL_TEST_SIGN_KEY", key)
+	submitted = nil
+	args = []string{"approve", "-server", srv.URL, "-engagement", "eng-1", "-id", "beacon",
+		"-sign-command", os.Args[0] + " -test.run=^TestSignCommandHelper$", "-pub", key + ".pub"}
+	if code := run(ctx, args, strings.NewReader("yes\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	if len(submitted) != 1 || submitted[0].Task.ID != "beacon" || rte.VerifyApproval(&submitted[0]) != nil {
+		t.Fatalf("submitted = %+v", submitted)
+	}
+
+	other := filepath.Join(dir, "other.pem")
+	if code := run(ctx, []string{"keygen", "-o", other}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatal(stderr.String())
+	}
+	args[len(args)-1] = other + ".pub"
+	stderr.Reset()
+	if code := run(ctx, args, strings.NewReader("yes\n"), &stdout, &stderr); code != exitError ||
+		!strings.Contains(stderr.String(), "does not verify") {
+		t.Errorf("token holding another key: exit %d, %s", code, stderr.String())
+	}
+}
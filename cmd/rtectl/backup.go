@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/backup"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// storeFlags registers the flags naming the controller's on-disk state.
+func storeFlags(fs *flag.FlagSet) (store, audit *string) {
+	store = fs.String("store", "", "controller task store file")
+	audit = fs.String("audit", "", "controller audit file (JSON array of audit records)")
+	return store, audit
+}
+
+func backupCmd(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storePath, auditPath := storeFlags(fs)
+	out := fs.String("o", "", "file to write the backup to")
+	since := fs.String("incremental", "", "previous backup to take an incremental backup from")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl backup: %v\n", err)
+		return exitError
+	}
+	if *storePath == "" || *out == "" {
+		return fail(errors.New("-store and -o are required"))
+	}
+	if _, err := os.Stat(*storePath); err != nil {
+		return fail(err)
+	}
+	store, err := rte.OpenFileStore(*storePath)
+	if err != nil {
+		return fail(err)
+	}
+	audit, err := readAudit(*auditPath)
+	if err != nil {
+		return fail(err)
+	}
+	var base *backup.Backup
+	if *since != "" {
+		if base, err = readBackup(*since); err != nil {
+			return fail(err)
+		}
+	}
+	b, err := backup.Take(ctx, store, audit, base, time.Now())
+	if err != nil {
+		return fail(err)
+	}
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fail(err)
+	}
+	if err := backup.Write(f, b); err != nil {
+		f.Close()
+		return fail(err)
+	}
+	if err := f.Close(); err != nil {
+		return fail(err)
+	}
+	kind := "Full"
+	if !b.Full() {
+		kind = "Incremental"
+	}
+	fmt.Fprintf(stdout, "%s backup %s: %d tasks, %d audit records.\n", kind, b.ID, len(b.Tasks), len(b.Audit))
+	return exitOK
+}
+
+func restoreCmd(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storePath, auditPath := storeFlags(fs)
+	at := fs.String("at", "", "restore the latest state backed up at or before this RFC 3339 time")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl restore: %v\n", err)
+		return exitError
+	}
+	if *storePath == "" || fs.NArg() == 0 {
+		return fail(errors.New("-store and at least one backup file are required"))
+	}
+	var until time.Time
+	if *at != "" {
+		var err error
+		if until, err = time.Parse(time.RFC3339, *at); err != nil {
+			return fail(fmt.Errorf("-at: %w", err))
+		}
+	}
+	var backups []*backup.Backup
+	for _, path := range fs.Args() {
+		b, err := readBackup(path)
+		if err != nil {
+			return fail(err)
+		}
+		backups = append(backups, b)
+	}
+	chain, err := backup.Chain(backups, until)
+	if err != nil {
+		return fail(err)
+	}
+	if _, err := os.Stat(*storePath); err == nil {
+		return fail(fmt.Errorf("%s already exists; restore into a new store", *storePath))
+	}
+	store, err := rte.OpenFileStore(*storePath)
+	if err != nil {
+		return fail(err)
+	}
+	restored, err := backup.Restore(ctx, store, chain)
+	if err != nil {
+		// The store was created above; do not leave a half-restored copy.
+		os.Remove(*storePath)
+		return fail(err)
+	}
+	records := 0
+	for _, chain := range restored.Audit {
+		records += len(chain)
+	}
+	if *auditPath != "" {
+		if err := writeAudit(*auditPath, restored.Audit); err != nil {
+			return fail(err)
+		}
+	} else if records > 0 {
+		fmt.Fprintf(stderr, "rtectl restore: %d audit records not restored; pass -audit to keep them\n", records)
+	}
+	last := chain[len(chain)-1]
+	fmt.Fprintf(stdout, "Restored %d tasks and %d audit records from %d backups, as of %s.\n",
+		restored.Tasks, records, len(chain), last.TakenAt.Format(time.RFC3339))
+	fmt.Fprintln(stdout, "All task signatures and audit hash chains verified.")
+	return exitOK
+}
+
+func readBackup(path string) (*backup.Backup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := backup.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// readAudit reads an audit file into per-engagement chains. A missing path
+// or file is no audit records.
+func readAudit(path string) (map[string][]rte.AuditRecord, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []rte.AuditRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	chains := map[string][]rte.AuditRecord{}
+	for _, r := range records {
+		chains[r.EngagementID] = append(chains[r.EngagementID], r)
+	}
+	return chains, nil
+}
+
+// writeAudit writes chains to a new audit file.
+func writeAudit(path string, chains map[string][]rte.AuditRecord) error {
+	engs := make([]string, 0, len(chains))
+	for eng := range chains {
+		engs = append(engs, eng)
+	}
+	sort.Strings(engs)
+	records := []rte.AuditRecord{}
+	for _, eng := range engs {
+		records = append(records, chains[eng]...)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestRun_BackupRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	storePath, auditPath := filepath.Join(dir, "tasks.json"), filepath.Join(dir, "audit.json")
+	store, err := rte.OpenFileStore(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, _ := rte.GenerateKeyPair()
+	st, err := rte.SignTask(rte.Task{
+		ID: "t-1", Engagement: "eng-1", Type: rte.TaskInventory, CreatedAt: time.Now().UTC(),
+		TTLSeconds: 600, Operator: "op", ApprovedBy: "lead", State: rte.StatePending,
+	}, priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, rte.TaskRecord{Task: st, State: rte.StatePending, UpdatedAt: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	audit := rte.NewAuditLog("op")
+	rec, _ := audit.Append("eng-1", "task.create", "t-1", "t-1", nil)
+	data, _ := json.Marshal([]rte.AuditRecord{rec})
+	if err := os.WriteFile(auditPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	full, inc := filepath.Join(dir, "full.json"), filepath.Join(dir, "inc.json")
+	if code := run(ctx, []string{"backup", "-store", storePath, "-audit", auditPath, "-o", full}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("backup exit = %d, stderr: %s", code, stderr.String())
+	}
+	if code := run(ctx, []string{"backup", "-store", storePath, "-audit", auditPath, "-incremental", full, "-o", inc}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("incremental exit = %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Full backup") || !strings.Contains(stdout.String(), ": 0 tasks, 0 audit records") {
+		t.Errorf("stdout = %s", stdout.String())
+	}
+
+	stdout.Reset()
+	newStore, newAudit := filepath.Join(dir, "restored.json"), filepath.Join(dir, "restored-audit.json")
+	if code := run(ctx, []string{"restore", "-store", newStore, "-audit", newAudit, inc, full}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("restore exit = %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Restored 1 tasks and 1 audit records from 2 backups") {
+		t.Errorf("stdout = %s", stdout.String())
+	}
+	restored, err := rte.OpenFileStore(newStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restored.Get(ctx, "eng-1", "t-1"); err != nil {
+		t.Fatal(err)
+	}
+	if chains, err := readAudit(newAudit); err != nil || rte.VerifyAuditChain(chains["eng-1"]) != nil || len(chains["eng-1"]) != 1 {
+		t.Fatalf("restored audit = %v, %v", chains, err)
+	}
+
+	if code := run(ctx, []string{"restore", "-store", newStore, full}, nil, &stdout, &stderr); code != exitError {
+		t.Error("restore overwrote an existing store")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/engagement"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func bundleCmd(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("f", ".", "directory of engagement definitions to snapshot the policy of")
+	keyPath := fs.String("key", "", "controller ed25519 private key to sign the bundle with (PKCS#8 PEM)")
+	pins := fs.String("pin", "", "comma-separated fingerprints of the keys agents may trust to sign tasks")
+	revoked := fs.String("revoke", "", "comma-separated fingerprints of revoked keys")
+	valid := fs.Duration("valid", 7*24*time.Hour, "how long the bundle stays valid")
+	out := fs.String("o", "", "file to write the bundle to (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl bundle: %v\n", err)
+		return exitError
+	}
+	if *keyPath == "" {
+		return fail(errors.New("-key is required"))
+	}
+	signer, err := loadSigner(*keyPath)
+	if err != nil {
+		return fail(err)
+	}
+	keys, err := rte.NewKeyPins(splitList(*pins)...)
+	if err != nil {
+		return fail(err)
+	}
+	files, err := engagement.Load(*dir)
+	if err != nil {
+		return fail(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	b := rte.OfflineBundle{
+		IssuedAt:  now,
+		ExpiresAt: now.Add(*valid),
+		Keys:      keys,
+		Revoked:   splitList(*revoked),
+		Policy: rte.OfflinePolicy{
+			Validator:     rte.CurrentValidatorConfig(),
+			ParamRules:    engagement.ParamRules(files),
+			Phases:        engagement.Phases(files),
+			TargetLimits:  engagement.TargetLimits(files),
+			ImpactBudgets: engagement.ImpactBudgets(files),
+		},
+	}
+	sb, err := rte.SignOfflineBundle(ctx, b, signer)
+	if err != nil {
+		return fail(err)
+	}
+	if err := writeJSON(*out, stdout, sb); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
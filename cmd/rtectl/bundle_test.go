@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestRun_Bundle(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	def := `{"engagement": "eng-1", "phase": "recon", "impact_budget": {"max_per_hour": 20},
+		"tasks": [{"id": "inv", "type": "inventory", "ttl_seconds": 600, "operator": "op", "approved_by": "lead"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "eng.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	key, out := filepath.Join(t.TempDir(), "ctl.pem"), filepath.Join(t.TempDir(), "bundle.json")
+	var stdout, stderr bytes.Buffer
+	if code := run(ctx, []string{"keygen", "-o", key}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("keygen exit = %d, stderr: %s", code, stderr.String())
+	}
+	signer, err := loadSigner(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opPub, _, _ := rte.GenerateKeyPair()
+	args := []string{"bundle", "-f", dir, "-key", key, "-pin", rte.Fingerprint(opPub), "-o", out}
+	if code := run(ctx, args, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("bundle exit = %d, stderr: %s", code, stderr.String())
+	}
+	trust, _ := rte.NewKeyPins(rte.Fingerprint(signer.PublicKey()))
+	b, err := rte.LoadOfflineBundle(out, trust)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Keys.Pinned(opPub) || b.Policy.Phases["eng-1"] != rte.PhaseRecon || b.Policy.ImpactBudgets["eng-1"].MaxPerHour != 20 {
+		t.Fatalf("bundle = %+v", b)
+	}
+
+	if code := run(ctx, []string{"bundle", "-f", dir, "-key", key}, nil, &stdout, &stderr); code != exitError {
+		t.Error("wrote a bundle pinning no keys")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/codethor0/rte-a-reference/pkg/engagement"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func cancelCmd(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cancel", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", os.Getenv("RTECTL_SERVER"), "controller base URL (default $RTECTL_SERVER)")
+	token := fs.String("token", os.Getenv("RTECTL_TOKEN"), "controller bearer token (default $RTECTL_TOKEN)")
+	eng := fs.String("engagement", "", "engagement whose tasks to cancel")
+	types := fs.String("type", "", "comma-separated task types to cancel (default all)")
+	states := fs.String("state", "", "comma-separated states to cancel (default pending,executing,paused)")
+	operator := fs.String("operator", "", "cancel only this operator's tasks")
+	ids := fs.String("id", "", "comma-separated task IDs to cancel (default all)")
+	as := fs.String("as", os.Getenv("USER"), "name recorded on the cancellations")
+	auto := fs.Bool("auto-approve", false, "cancel without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl cancel: %v\n", err)
+		return exitError
+	}
+	f := rte.TaskFilter{Engagement: *eng, Operator: *operator, IDs: splitList(*ids)}
+	for _, t := range splitList(*types) {
+		f.Types = append(f.Types, rte.TaskType(t))
+	}
+	for _, s := range splitList(*states) {
+		f.States = append(f.States, rte.TaskState(s))
+	}
+	if err := f.Validate(); err != nil {
+		return fail(err)
+	}
+	if *as == "" {
+		return fail(errors.New("-as is required"))
+	}
+	ctl := &engagement.HTTPController{BaseURL: *server, Token: *token}
+	remote, err := ctl.Tasks(ctx, f.Engagement)
+	if err != nil {
+		return fail(err)
+	}
+	var selected []string
+	for _, r := range remote {
+		task := r.Task
+		if f.Match(rte.TaskRecord{Task: &task, State: r.State}) {
+			selected = append(selected, fmt.Sprintf("  - cancel %s/%s (%s, %s)", f.Engagement, task.Task.ID, task.Task.Type, r.State))
+		}
+	}
+	if len(selected) == 0 {
+		fmt.Fprintln(stdout, "No tasks match; nothing to cancel.")
+		return exitOK
+	}
+	fmt.Fprintln(stdout, strings.Join(selected, "\n"))
+	fmt.Fprintf(stdout, "\nCancel: %d tasks.\n", len(selected))
+	if !*auto && !confirm(stdin, stdout) {
+		fmt.Fprintln(stdout, "Cancel aborted.")
+		return exitOK
+	}
+	cancelled, err := ctl.CancelWhere(ctx, f, *as)
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(stdout, "\nCancelled %d tasks: %s\n", len(cancelled), strings.Join(cancelled, ", "))
+	return exitOK
+}
+
+// splitList splits a comma-separated flag, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/engagement"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestRun_Cancel(t *testing.T) {
+	ctx := context.Background()
+	store := rte.NewMemoryStore()
+	pub, priv, _ := rte.GenerateKeyPair()
+	for _, tc := range []struct {
+		id  string
+		typ rte.TaskType
+	}{{"beacon-1", rte.TaskSimulateBeacon}, {"beacon-2", rte.TaskSimulateBeacon}, {"inv", rte.TaskInventory}} {
+		st, err := rte.SignTask(rte.Task{
+			ID: tc.id, Engagement: "eng-1", Type: tc.typ, CreatedAt: time.Now().UTC(),
+			TTLSeconds: 600, Operator: "op", ApprovedBy: "lead", State: rte.StatePending,
+		}, priv, pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put(ctx, rte.TaskRecord{Task: st, State: rte.StatePending}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	audit := rte.NewAuditLog("controller")
+	bulk := &rte.BulkCancelHandler{Store: store, Audit: audit}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/tasks/cancel") {
+			bulk.ServeHTTP(w, r)
+			return
+		}
+		recs, _ := store.List(r.Context(), "eng-1")
+		var tasks []engagement.Remote
+		for _, rec := range recs {
+			tasks = append(tasks, engagement.Remote{Task: *rec.Task, State: rec.State})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"tasks": tasks})
+	}))
+	defer srv.Close()
+	args := []string{"cancel", "-server", srv.URL, "-engagement", "eng-1", "-type", "simulate_beacon", "-as", "lead"}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(ctx, args, strings.NewReader("no\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "cancel eng-1/beacon-2 (simulate_beacon, pending)") || !strings.Contains(stdout.String(), "Cancel aborted.") {
+		t.Fatalf("stdout = %s", stdout.String())
+	}
+	if rec, _ := store.Get(ctx, "eng-1", "beacon-1"); rec.State != rte.StatePending {
+		t.Fatal("declined cancel still ran")
+	}
+
+	stdout.Reset()
+	if code := run(ctx, args, strings.NewReader("yes\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Cancelled 2 tasks: beacon-1, beacon-2") {
+		t.Fatalf("stdout = %s", stdout.String())
+	}
+	if rec, _ := store.Get(ctx, "eng-1", "inv"); rec.State != rte.StatePending {
+		t.Error("cancelled a task the filter did not select")
+	}
+	if n := len(audit.Records("eng-1")); n != 2 {
+		t.Errorf("%d audit records", n)
+	}
+
+	stdout.Reset()
+	if code := run(ctx, args, nil, &stdout, &stderr); code != exitOK || !strings.Contains(stdout.String(), "nothing to cancel") {
+		t.Errorf("second cancel: exit %d, %s", code, stdout.String())
+	}
+	if code := run(ctx, []string{"cancel", "-server", srv.URL}, nil, &stdout, &stderr); code != exitError {
+		t.Errorf("cancel without -engagement: exit %d", code)
+	}
+}
@@ -0,0 +1,348 @@
+// Command rtectl manages engagements as code against an RTE-A controller.
+//
+//	rtectl plan  -f DIR -server URL [-token TOKEN] [-detailed-exitcode]
+//	rtectl apply -f DIR -server URL [-token TOKEN] -key KEY.pem [-as NAME] [-auto-approve]
+//	             [-verify-commit [-branch main,...] [-allowed-signers FILE]] [-tsa URL]
+//	rtectl cancel -engagement ENG -server URL [-token TOKEN] [-type T,...] [-state S,...]
+//	              [-operator NAME] [-id ID,...] [-as NAME] [-auto-approve]
+//	rtectl backup  -store FILE [-audit FILE] [-incremental PREV] -o OUT
+//	rtectl restore -store FILE [-audit FILE] [-at TIME] BACKUP...
+//	rtectl approve -engagement ENG -server URL [-token TOKEN] [-id ID,...] [-f DIR]
+//	               (-key KEY.pem | -sign-command CMD -pub PUB.pem)
+//	rtectl keygen -o KEY.pem
+//	rtectl bundle -f DIR -key KEY.pem -pin FP,... [-revoke FP,...] [-valid DUR] [-o FILE]
+//	rtectl task create -engagement ENG -type TYPE -approved-by NAME [-id ID] [-ttl DUR]
+//	                   [-operator NAME] [-param K=V]... [-technique T,...] [-priority N] [-o FILE]
+//	rtectl task sign -key KEY.pem [-o FILE] [TASK]
+//	rtectl task verify [-fingerprint FP,...] [-signature-only] [-against TASK] [SIGNED]
+//	rtectl task submit -server URL [-token TOKEN] [SIGNED]
+//	rtectl task cancel -engagement ENG -id ID -server URL [-token TOKEN] [-as NAME]
+//	rtectl engagement report -engagement ENG -store FILE [-audit FILE] [-client NAME]
+//	                         [-format json|markdown|html] [-key KEY.pem] [-o FILE]
+//
+// plan loads the engagement definitions (JSON or YAML) in DIR, compares them with the
+// controller's task state, and prints the create/update/cancel plan. apply
+// prints the same plan and, once confirmed, carries it out: unchanged tasks
+// with an approval recorded in DIR/rte.lock.json are re-submitted as is,
+// and new, modified, or high-risk tasks are signed with KEY (a PKCS#8
+// ed25519 private key) and filed for approval. The server and token
+// default to $RTECTL_SERVER and $RTECTL_TOKEN. A change the controller
+// rejects on policy is listed with each rule it broke, where that rule is
+// defined, and how to fix the task.
+//
+// With -verify-commit, apply first checks that the definitions are
+// committed unchanged in a signed commit (SSH or Gitsign) reachable from
+// one of the allowed branches, and records that commit's SHA in the
+// provenance of every task it signs. SSH signers come from FILE, or from
+// git's gpg.ssh.allowedSignersFile.
+//
+// With -tsa, every task apply signs is also timestamped by the RFC 3161
+// timestamping authority at URL.
+//
+// cancel lists the engagement's tasks matching every filter given, by
+// default all pending, executing, and paused ones, and once confirmed has
+// the controller cancel them in one transaction, with an audit record per
+// task. It replaces scripting per-task cancels during an abort.
+//
+// backup writes a consistent snapshot of a controller's task store and
+// audit file to OUT: everything, or with -incremental only what changed
+// since backup PREV. restore rebuilds a new store, and audit file, from a
+// full backup and the incrementals that follow it, stopping at the latest
+// backup taken at or before -at, and then re-verifies every task signature
+// and audit hash chain.
+//
+// approve walks the engagement's pending approvals one at a time. Each
+// task is shown in full, diffed against its definition in DIR and against
+// any approval of it recorded in DIR/rte.lock.json, and once the approver
+// answers "yes" is countersigned and submitted. The approver
+// signs with KEY or, for a key held on a hardware token, with CMD: CMD
+// reads the message on stdin and writes the raw ed25519 signature, which
+// must verify against PUB.
+//
+// bundle writes a signed offline verification bundle for air-gapped
+// agents: the pinned keys FP, the revoked ones, the param rules, phases,
+// target limits, and impact budgets of the definitions in DIR, and the
+// time it was issued, valid for DUR. Agents load it with
+// rte.LoadOfflineBundle against the fingerprint of KEY.
+//
+// keygen, task, and engagement report drive single tasks without writing
+// Go, reading and writing the rte package's JSON formats; TASK and SIGNED
+// default to standard input, and are read as YAML if named *.yaml. keygen writes a new PKCS#8 key to KEY.pem,
+// its public key to KEY.pem.pub, and prints its fingerprint. task create
+// writes an unsigned task with a fresh ID and cancel token, task sign
+// signs one, and task verify checks a signed task's signature and
+// validity, or with -signature-only just its signature, optionally pinned
+// to the given key fingerprints; with -against, the signed task must also
+// match TASK, and every field that differs is listed. task submit sends a signed task to the
+// controller and task cancel cancels one by ID. engagement report builds
+// the engagement's report from a task store and audit file.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/codethor0/rte-a-reference/pkg/engagement"
+	"github.com/codethor0/rte-a-reference/pkg/gitprov"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Exit codes follow terraform plan -detailed-exitcode.
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitChanges = 2
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
+	switch args[0] {
+	case "plan":
+		return plan(ctx, args[1:], stdout, stderr)
+	case "apply":
+		return apply(ctx, args[1:], stdin, stdout, stderr)
+	case "cancel":
+		return cancelCmd(ctx, args[1:], stdin, stdout, stderr)
+	case "backup":
+		return backupCmd(ctx, args[1:], stdout, stderr)
+	case "restore":
+		return restoreCmd(ctx, args[1:], stdout, stderr)
+	case "approve":
+		return approveCmd(ctx, args[1:], stdin, stdout, stderr)
+	case "keygen":
+		return keygenCmd(args[1:], stdout, stderr)
+	case "bundle":
+		return bundleCmd(ctx, args[1:], stdout, stderr)
+	case "task":
+		return taskCmd(ctx, args[1:], stdin, stdout, stderr)
+	case "engagement":
+		return engagementCmd(ctx, args[1:], stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		usage(stdout)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "rtectl: unknown command %q\n", args[0])
+		usage(stderr)
+		return exitError
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: rtectl plan  -f DIR -server URL [-token TOKEN] [-detailed-exitcode]")
+	fmt.Fprintln(w, "       rtectl apply -f DIR -server URL [-token TOKEN] -key KEY.pem [-as NAME] [-auto-approve]")
+	fmt.Fprintln(w, "                    [-verify-commit [-branch main,...] [-allowed-signers FILE]] [-tsa URL]")
+	fmt.Fprintln(w, "       rtectl cancel -engagement ENG -server URL [-token TOKEN] [-type T,...] [-state S,...]")
+	fmt.Fprintln(w, "                     [-operator NAME] [-id ID,...] [-as NAME] [-auto-approve]")
+	fmt.Fprintln(w, "       rtectl backup  -store FILE [-audit FILE] [-incremental PREV] -o OUT")
+	fmt.Fprintln(w, "       rtectl restore -store FILE [-audit FILE] [-at TIME] BACKUP...")
+	fmt.Fprintln(w, "       rtectl approve -engagement ENG -server URL [-token TOKEN] [-id ID,...] [-f DIR]")
+	fmt.Fprintln(w, "                      (-key KEY.pem | -sign-command CMD -pub PUB.pem)")
+	fmt.Fprintln(w, "       rtectl keygen -o KEY.pem")
+	fmt.Fprintln(w, "       rtectl bundle -f DIR -key KEY.pem -pin FP,... [-revoke FP,...] [-valid DUR] [-o FILE]")
+	fmt.Fprintln(w, "       rtectl task create -engagement ENG -type TYPE -approved-by NAME [-id ID] [-ttl DUR]")
+	fmt.Fprintln(w, "                          [-operator NAME] [-param K=V]... [-technique T,...] [-priority N] [-o FILE]")
+	fmt.Fprintln(w, "       rtectl task sign -key KEY.pem [-o FILE] [TASK]")
+	fmt.Fprintln(w, "       rtectl task verify [-fingerprint FP,...] [-signature-only] [-against TASK] [SIGNED]")
+	fmt.Fprintln(w, "       rtectl task submit -server URL [-token TOKEN] [SIGNED]")
+	fmt.Fprintln(w, "       rtectl task cancel -engagement ENG -id ID -server URL [-token TOKEN] [-as NAME]")
+	fmt.Fprintln(w, "       rtectl engagement report -engagement ENG -store FILE [-audit FILE] [-client NAME]")
+	fmt.Fprintln(w, "                                [-format json|markdown|html] [-key KEY.pem] [-o FILE]")
+}
+
+// controllerFlags registers the flags every controller command shares.
+func controllerFlags(fs *flag.FlagSet) (dir, server, token *string) {
+	dir = fs.String("f", ".", "directory of engagement definition files")
+	server = fs.String("server", os.Getenv("RTECTL_SERVER"), "controller base URL (default $RTECTL_SERVER)")
+	token = fs.String("token", os.Getenv("RTECTL_TOKEN"), "controller bearer token (default $RTECTL_TOKEN)")
+	return dir, server, token
+}
+
+func plan(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir, server, token := controllerFlags(fs)
+	detailed := fs.Bool("detailed-exitcode", false, "exit 2 when the plan has changes")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	_, p, err := computePlan(ctx, *dir, &engagement.HTTPController{BaseURL: *server, Token: *token})
+	if err != nil {
+		fmt.Fprintf(stderr, "rtectl plan: %v\n", err)
+		return exitError
+	}
+	if err := p.Write(stdout); err != nil {
+		fmt.Fprintf(stderr, "rtectl plan: %v\n", err)
+		return exitError
+	}
+	if *detailed && !p.Empty() {
+		return exitChanges
+	}
+	return exitOK
+}
+
+func apply(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir, server, token := controllerFlags(fs)
+	keyPath := fs.String("key", "", "operator ed25519 private key (PKCS#8 PEM)")
+	as := fs.String("as", os.Getenv("USER"), "name recorded on cancellations")
+	auto := fs.Bool("auto-approve", false, "apply without asking for confirmation")
+	verifyCommit := fs.Bool("verify-commit", false, "require the definitions to come from a signed commit on an allowed branch")
+	branches := fs.String("branch", "main", "comma-separated branches a verified commit must be on")
+	signersPath := fs.String("allowed-signers", "", "SSH allowed signers file (default git's gpg.ssh.allowedSignersFile)")
+	tsaURL := fs.String("tsa", "", "RFC 3161 timestamping authority URL for signed tasks")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl apply: %v\n", err)
+		return exitError
+	}
+	ctl := &engagement.HTTPController{BaseURL: *server, Token: *token}
+	files, p, err := computePlan(ctx, *dir, ctl)
+	if err != nil {
+		return fail(err)
+	}
+	var prov *rte.Provenance
+	if *verifyCommit {
+		if prov, err = verifyProvenance(ctx, *dir, files, *branches, *signersPath); err != nil {
+			return fail(err)
+		}
+		fmt.Fprintf(stdout, "Definitions verified at commit %s on %s.\n\n", prov.Commit, prov.Branch)
+	}
+	lockPath := filepath.Join(*dir, engagement.LockName)
+	lock, err := engagement.LoadLock(lockPath)
+	if err != nil {
+		return fail(err)
+	}
+	if err := p.Write(stdout); err != nil {
+		return fail(err)
+	}
+	lock.Record(p.Approved...)
+	lock.Prune(files)
+	if !p.Empty() {
+		if !*auto && !confirm(stdin, stdout) {
+			fmt.Fprintln(stdout, "Apply cancelled.")
+			return exitOK
+		}
+		var signer rte.Signer
+		if *keyPath != "" {
+			if signer, err = loadSigner(*keyPath); err != nil {
+				return fail(err)
+			}
+		}
+		a := &engagement.Applier{Controller: ctl, Signer: signer, Lock: lock, Provenance: prov, RequestedBy: *as}
+		if *tsaURL != "" {
+			a.Timestamper = &rte.HTTPTimestamper{URL: *tsaURL}
+		}
+		results, applyErr := a.Apply(ctx, p)
+		if err := engagement.WriteApplied(stdout, results); err != nil {
+			return fail(err)
+		}
+		if applyErr != nil {
+			_ = lock.Save(lockPath)
+			return fail(applyErr)
+		}
+	}
+	if err := lock.Save(lockPath); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func computePlan(ctx context.Context, dir string, state engagement.State) ([]engagement.File, engagement.Plan, error) {
+	files, err := engagement.Load(dir)
+	if err != nil {
+		return nil, engagement.Plan{}, err
+	}
+	p, err := engagement.Compute(ctx, files, state)
+	return files, p, err
+}
+
+// verifyProvenance checks that every definition file comes from a signed
+// commit on one of the comma-separated branches.
+func verifyProvenance(ctx context.Context, dir string, files []engagement.File, branches, signersPath string) (*rte.Provenance, error) {
+	v := &gitprov.Verifier{Dir: dir}
+	for _, b := range strings.Split(branches, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			v.Branches = append(v.Branches, b)
+		}
+	}
+	if signersPath != "" {
+		f, err := os.Open(signersPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if v.AllowedSigners, err = gitprov.ParseAllowedSigners(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", signersPath, err)
+		}
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		abs, err := filepath.Abs(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = abs
+	}
+	return v.Verify(ctx, paths...)
+}
+
+// confirm asks, as terraform apply does, for a literal "yes".
+func confirm(stdin io.Reader, stdout io.Writer) bool {
+	fmt.Fprint(stdout, "\nDo you want to perform these actions? Only 'yes' will be accepted: ")
+	line, _ := bufio.NewReader(stdin).ReadString('\n')
+	return strings.TrimSpace(line) == "yes"
+}
+
+// loadSigner reads a PKCS#8 PEM ed25519 private key, as written by
+// "openssl genpkey -algorithm ed25519" or "rtectl keygen".
+func loadSigner(path string) (rte.Signer, error) {
+	priv, err := loadKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return rte.NewKeySigner(priv, priv.Public().(ed25519.PublicKey))
+}
+
+// loadKey reads the private key loadSigner signs with.
+func loadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: expected a PEM PRIVATE KEY block", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New(path + ": not an ed25519 key")
+	}
+	return priv, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestRun_Plan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"tasks": []any{}})
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	def := `{"engagement": "eng-1", "tasks": [{"id": "inv", "type": "inventory", "ttl_seconds": 600,
+		"operator": "op", "approved_by": "lead"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "eng.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"plan", "-f", dir, "-server", srv.URL, "-detailed-exitcode"}, nil, &stdout, &stderr)
+	if code != exitChanges {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "+ create eng-1/inv") {
+		t.Fatalf("stdout = %s", stdout.String())
+	}
+
+	if code := run(context.Background(), []string{"bogus"}, nil, &stdout, &stderr); code != exitError {
+		t.Fatalf("unknown command exit = %d", code)
+	}
+}
+
+func TestRun_Apply(t *testing.T) {
+	var approvals []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"tasks": []any{}})
+		case strings.HasSuffix(r.URL.Path, "/approvals"):
+			var body struct {
+				Task struct {
+					Task struct {
+						ID string `json:"id"`
+					} `json:"task"`
+				} `json:"task"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			approvals = append(approvals, body.Task.Task.ID)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	def := `{"engagement": "eng-1", "tasks": [{"id": "inv", "type": "inventory", "ttl_seconds": 600,
+		"operator": "op", "approved_by": "lead"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "eng.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, priv, _ := ed25519.GenerateKey(nil)
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	key := filepath.Join(t.TempDir(), "op.pem")
+	if err := os.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"apply", "-f", dir, "-server", srv.URL, "-key", key}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), args, strings.NewReader("no\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	if len(approvals) != 0 || !strings.Contains(stdout.String(), "Apply cancelled.") {
+		t.Fatalf("declined apply still ran: %v\n%s", approvals, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run(context.Background(), args, strings.NewReader("yes\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	if len(approvals) != 1 || approvals[0] != "inv" {
+		t.Fatalf("approvals = %v\n%s", approvals, stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "rte.lock.json")); err != nil {
+		t.Fatalf("lock not written: %v", err)
+	}
+}
+
+func TestRun_ApplyExplainsRejection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_ = json.NewEncoder(w).Encode(map[string]any{"tasks": []any{}})
+			return
+		}
+		rte.WritePolicyError(w, &rte.PolicyError{TaskID: "inv", Violations: []rte.Violation{{
+			Rule:        "data.rte.deny",
+			Source:      "policies/inventory.rego:12",
+			Message:     "inventory is frozen this week",
+			Remediation: "resubmit after the change freeze",
+		}}})
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	def := `{"engagement": "eng-1", "tasks": [{"id": "inv", "type": "inventory", "ttl_seconds": 600,
+		"operator": "op", "approved_by": "lead"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "eng.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, priv, _ := ed25519.GenerateKey(nil)
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	key := filepath.Join(t.TempDir(), "op.pem")
+	if err := os.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"apply", "-f", dir, "-server", srv.URL, "-key", key, "-auto-approve"}, nil, &stdout, &stderr)
+	if code != exitError {
+		t.Fatalf("exit = %d, stdout: %s", code, stdout.String())
+	}
+	for _, want := range []string{"inventory is frozen this week", "rule:   data.rte.deny", "source: policies/inventory.rego:12", "fix:    resubmit after the change freeze"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout lacks %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestRun_ApplyVerifyCommit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"tasks": []any{}})
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	def := `{"engagement": "eng-1", "tasks": [{"id": "inv", "type": "inventory", "ttl_seconds": 600,
+		"operator": "op", "approved_by": "lead"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "eng.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A directory outside any repository has no commit to vouch for it.
+	var stdout, stderr bytes.Buffer
+	args := []string{"apply", "-f", dir, "-server", srv.URL, "-auto-approve", "-verify-commit"}
+	if code := run(context.Background(), args, nil, &stdout, &stderr); code != exitError {
+		t.Fatalf("exit = %d, stdout: %s", code, stdout.String())
+	}
+	if strings.Contains(stdout.String(), "create") {
+		t.Fatalf("plan printed before provenance was verified:\n%s", stdout.String())
+	}
+}
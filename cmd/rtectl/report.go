@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/report"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func engagementCmd(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "report" {
+		fmt.Fprintln(stderr, "rtectl engagement: expected report")
+		return exitError
+	}
+	return engagementReport(ctx, args[1:], stdout, stderr)
+}
+
+func engagementReport(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("engagement report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	storePath, auditPath := storeFlags(fs)
+	eng := fs.String("engagement", "", "engagement to report on")
+	client := fs.String("client", "", "customer the engagement was for")
+	format := fs.String("format", "json", "output format: json, markdown, or html")
+	keyPath := fs.String("key", "", "issuer ed25519 private key (PKCS#8 PEM) to sign a JSON report with")
+	out := fs.String("o", "", "file to write the report to (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl engagement report: %v\n", err)
+		return exitError
+	}
+	if *storePath == "" || *eng == "" {
+		return fail(errors.New("-store and -engagement are required"))
+	}
+	if *keyPath != "" && *format != "json" {
+		return fail(errors.New("-key signs only the json format"))
+	}
+	if _, err := os.Stat(*storePath); err != nil {
+		return fail(err)
+	}
+	store, err := rte.OpenFileStore(*storePath)
+	if err != nil {
+		return fail(err)
+	}
+	recs, err := store.List(ctx, *eng)
+	if err != nil {
+		return fail(err)
+	}
+	audit, err := readAudit(*auditPath)
+	if err != nil {
+		return fail(err)
+	}
+	in := report.Input{Engagement: *eng, Client: *client, Audit: audit[*eng]}
+	for _, rec := range recs {
+		if rec.Task != nil {
+			in.Tasks = append(in.Tasks, *rec.Task)
+		}
+		if rec.Result != nil {
+			in.Results = append(in.Results, *rec.Result)
+		}
+	}
+	r, err := report.Build(in, time.Now())
+	if err != nil {
+		return fail(err)
+	}
+	var buf bytes.Buffer
+	switch *format {
+	case "json":
+		var v any = r
+		if *keyPath != "" {
+			priv, err := loadKey(*keyPath)
+			if err != nil {
+				return fail(err)
+			}
+			if v, err = report.Sign(r, priv, priv.Public().(ed25519.PublicKey)); err != nil {
+				return fail(err)
+			}
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fail(err)
+		}
+		buf.Write(append(data, '\n'))
+	case "markdown":
+		err = r.Markdown(&buf)
+	case "html":
+		err = r.HTML(&buf)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if *out == "" {
+		_, err = stdout.Write(buf.Bytes())
+	} else {
+		err = writeNew(*out, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
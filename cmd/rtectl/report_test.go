@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/report"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestRun_EngagementReport(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, "store.json")
+	store, err := rte.OpenFileStore(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, _ := rte.GenerateKeyPair()
+	now := time.Now().UTC()
+	st, err := rte.SignTask(rte.Task{
+		ID: "inv", Engagement: "eng-1", Type: rte.TaskInventory, CreatedAt: now,
+		TTLSeconds: 600, Operator: "op", ApprovedBy: "lead", State: rte.StatePending,
+	}, priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &rte.TaskResult{TaskID: "inv", Engagement: "eng-1", State: rte.StateCompleted, StartedAt: now, FinishedAt: now.Add(time.Second)}
+	if err := store.Put(ctx, rte.TaskRecord{Task: st, State: rte.StateCompleted, Result: res}); err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(dir, "issuer.pem")
+	var stdout, stderr bytes.Buffer
+	if code := run(ctx, []string{"keygen", "-o", key}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("keygen exit = %d, stderr: %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	args := []string{"engagement", "report", "-store", storePath, "-engagement", "eng-1", "-key", key}
+	if code := run(ctx, args, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	var sr report.SignedReport
+	if err := json.Unmarshal(stdout.Bytes(), &sr); err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Verify(&sr); err != nil {
+		t.Fatal(err)
+	}
+	if len(sr.Report.Tasks) != 1 || sr.Report.Tasks[0].ID != "inv" {
+		t.Errorf("tasks = %+v", sr.Report.Tasks)
+	}
+
+	stdout.Reset()
+	args = []string{"engagement", "report", "-store", storePath, "-engagement", "eng-1", "-format", "markdown"}
+	if code := run(ctx, args, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit = %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "eng-1") {
+		t.Errorf("markdown = %s", stdout.String())
+	}
+	args = []string{"engagement", "report", "-store", storePath, "-engagement", "eng-1", "-format", "html", "-key", key}
+	if code := run(ctx, args, nil, &stdout, &stderr); code != exitError {
+		t.Error("signed a non-JSON report")
+	}
+}
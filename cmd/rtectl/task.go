@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/engagement"
+	"github.com/codethor0/rte-a-reference/pkg/miniyaml"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func keygenCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("o", "", "file to write the private key to; the public key goes to FILE.pub")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl keygen: %v\n", err)
+		return exitError
+	}
+	if *out == "" {
+		return fail(errors.New("-o is required"))
+	}
+	pub, priv, err := rte.GenerateKeyPair()
+	if err != nil {
+		return fail(err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return fail(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return fail(err)
+	}
+	if err := writeNew(*out, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600); err != nil {
+		return fail(err)
+	}
+	if err := writeNew(*out+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644); err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(stdout, "Wrote %s and %s.pub.\nFingerprint: %s\n", *out, *out, rte.Fingerprint(pub))
+	return exitOK
+}
+
+func taskCmd(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "rtectl task: expected create, sign, verify, submit, or cancel")
+		return exitError
+	}
+	switch args[0] {
+	case "create":
+		return taskCreate(args[1:], stdout, stderr)
+	case "sign":
+		return taskSign(ctx, args[1:], stdin, stdout, stderr)
+	case "verify":
+		return taskVerify(ctx, args[1:], stdin, stdout, stderr)
+	case "submit":
+		return taskSubmit(ctx, args[1:], stdin, stdout, stderr)
+	case "cancel":
+		return taskCancel(ctx, args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "rtectl task: unknown command %q\n", args[0])
+		return exitError
+	}
+}
+
+// paramFlag collects repeated -param key=value flags.
+type paramFlag map[string]string
+
+func (p paramFlag) String() string { return fmt.Sprint(map[string]string(p)) }
+
+func (p paramFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("%q is not key=value", s)
+	}
+	p[k] = v
+	return nil
+}
+
+func taskCreate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("task create", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	id := fs.String("id", "", "task ID (default a new ULID)")
+	eng := fs.String("engagement", "", "engagement the task belongs to")
+	typ := fs.String("type", "", "task type")
+	ttl := fs.Duration("ttl", 10*time.Minute, "how long the task stays valid once created")
+	operator := fs.String("operator", os.Getenv("USER"), "operator issuing the task")
+	approvedBy := fs.String("approved-by", "", "lead who approved the task")
+	priority := fs.Int("priority", 0, "queue priority, 0 to 9")
+	techniques := fs.String("technique", "", "comma-separated ATT&CK technique IDs")
+	idemKey := fs.String("idempotency-key", "", "key the controller deduplicates retried submissions by")
+	reissues := fs.String("reissue-of", "", "ID of the terminal task this one retries")
+	supersedes := fs.String("supersedes", "", "ID of the terminal task this one replaces")
+	out := fs.String("o", "", "file to write the task to (default stdout)")
+	params := paramFlag{}
+	fs.Var(params, "param", "task parameter as key=value (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl task create: %v\n", err)
+		return exitError
+	}
+	task := rte.Task{
+		SchemaVersion:  rte.TaskSchemaVersion,
+		ID:             *id,
+		Engagement:     *eng,
+		Type:           rte.TaskType(*typ),
+		CreatedAt:      time.Now().UTC().Truncate(time.Second),
+		TTLSeconds:     int(ttl.Seconds()),
+		Operator:       *operator,
+		ApprovedBy:     *approvedBy,
+		State:          rte.StatePending,
+		Priority:       *priority,
+		Techniques:     splitList(*techniques),
+		IdempotencyKey: *idemKey,
+		ReissuedFromID: *reissues,
+		SupersedesID:   *supersedes,
+	}
+	if task.ID == "" {
+		task.ID = rte.NewTaskID()
+	}
+	if len(params) > 0 {
+		task.Params = params
+	}
+	// The cancel token is signed with the task and authorizes cancelling
+	// it, so it must not be guessable.
+	tok := make([]byte, 16)
+	if _, err := rand.Read(tok); err != nil {
+		return fail(err)
+	}
+	task.CancelToken = hex.EncodeToString(tok)
+	if err := task.Validate(task.CreatedAt); err != nil {
+		return fail(err)
+	}
+	if err := writeJSON(*out, stdout, task); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func taskSign(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("task sign", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyPath := fs.String("key", "", "operator ed25519 private key (PKCS#8 PEM)")
+	out := fs.String("o", "", "file to write the signed task to (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl task sign: %v\n", err)
+		return exitError
+	}
+	if *keyPath == "" {
+		return fail(errors.New("-key is required"))
+	}
+	signer, err := loadSigner(*keyPath)
+	if err != nil {
+		return fail(err)
+	}
+	var task rte.Task
+	if err := readJSON(fs.Arg(0), stdin, &task); err != nil {
+		return fail(err)
+	}
+	st, err := rte.SignTaskContext(ctx, task, signer)
+	if err != nil {
+		return fail(err)
+	}
+	if err := writeJSON(*out, stdout, st); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func taskVerify(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("task verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fingerprints := fs.String("fingerprint", "", "comma-separated fingerprints of the keys to trust (default any)")
+	against := fs.String("against", "", "task the signed task must match, such as the one approved")
+	sigOnly := fs.Bool("signature-only", false, "check only the signature, not validity or expiry")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl task verify: %v\n", err)
+		return exitError
+	}
+	var st rte.SignedTask
+	if err := readJSON(fs.Arg(0), stdin, &st); err != nil {
+		return fail(err)
+	}
+	if fps := splitList(*fingerprints); len(fps) > 0 {
+		pins, err := rte.NewKeyPins(fps...)
+		if err != nil {
+			return fail(err)
+		}
+		if err := pins.Check(&st); err != nil {
+			return fail(err)
+		}
+	}
+	if *against != "" {
+		var want rte.Task
+		if err := readJSON(*against, nil, &want); err != nil {
+			return fail(err)
+		}
+		if err := rte.CheckSigned(&st, want); err != nil {
+			return failTampered(stderr, fmt.Sprintf("%s/%s", st.Task.Engagement, st.Task.ID), err)
+		}
+	}
+	verify := rte.VerifyTaskContext
+	if *sigOnly {
+		verify = func(_ context.Context, st *rte.SignedTask) error { return rte.VerifyTaskSignature(st) }
+	}
+	if err := verify(ctx, &st); err != nil {
+		return failTampered(stderr, fmt.Sprintf("%s/%s", st.Task.Engagement, st.Task.ID), err)
+	}
+	fmt.Fprintf(stdout, "%s/%s: signature OK, signed by %s.\n", st.Task.Engagement, st.Task.ID, rte.Fingerprint(st.PublicKey))
+	return exitOK
+}
+
+func taskSubmit(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("task submit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", os.Getenv("RTECTL_SERVER"), "controller base URL (default $RTECTL_SERVER)")
+	token := fs.String("token", os.Getenv("RTECTL_TOKEN"), "controller bearer token (default $RTECTL_TOKEN)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl task submit: %v\n", err)
+		return exitError
+	}
+	var st rte.SignedTask
+	if err := readJSON(fs.Arg(0), stdin, &st); err != nil {
+		return fail(err)
+	}
+	ctl := &engagement.HTTPController{BaseURL: *server, Token: *token}
+	if err := ctl.Submit(ctx, &st); err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(stdout, "Submitted %s/%s.\n", st.Task.Engagement, st.Task.ID)
+	return exitOK
+}
+
+func taskCancel(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("task cancel", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", os.Getenv("RTECTL_SERVER"), "controller base URL (default $RTECTL_SERVER)")
+	token := fs.String("token", os.Getenv("RTECTL_TOKEN"), "controller bearer token (default $RTECTL_TOKEN)")
+	eng := fs.String("engagement", "", "engagement the task belongs to")
+	id := fs.String("id", "", "task ID to cancel")
+	as := fs.String("as", os.Getenv("USER"), "name recorded on the cancellation")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rtectl task cancel: %v\n", err)
+		return exitError
+	}
+	if *eng == "" || *id == "" {
+		return fail(errors.New("-engagement and -id are required"))
+	}
+	ctl := &engagement.HTTPController{BaseURL: *server, Token: *token}
+	remote, err := ctl.Tasks(ctx, *eng)
+	if err != nil {
+		return fail(err)
+	}
+	// The cancel token travels in the signed task, so the cancel must
+	// name the task the controller holds.
+	var found *engagement.Remote
+	for i := range remote {
+		if remote[i].Task.Task.ID == *id {
+			found = &remote[i]
+			break
+		}
+	}
+	if found == nil {
+		return fail(fmt.Errorf("%s/%s: no such task", *eng, *id))
+	}
+	if found.Terminal() {
+		return fail(fmt.Errorf("%s/%s is already %s", *eng, *id, found.State))
+	}
+	c := rte.TaskCancel{Engagement: *eng, TaskID: *id, Token: found.Task.Task.CancelToken, RequestedBy: *as}
+	if err := ctl.Cancel(ctx, c); err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(stdout, "Cancelled %s/%s.\n", *eng, *id)
+	return exitOK
+}
+
+// readJSON decodes the JSON file at path into v; "" or "-" reads stdin.
+// A .yaml or .yml file is read as YAML.
+func readJSON(path string, stdin io.Reader, v any) error {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "" || path == "-" {
+		if stdin == nil {
+			return errors.New("no input file given and no standard input")
+		}
+		path = "stdin"
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		if data, err = miniyaml.JSON(data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON to a new file at path, or to stdout
+// if path is "".
+func writeJSON(path string, stdout io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return writeNew(path, data, 0o644)
+}
+
+// failTampered reports a failed task verify, listing field by field how
+// the task differs from what was signed when err is a *rte.TamperError.
+func failTampered(stderr io.Writer, what string, err error) int {
+	var te *rte.TamperError
+	if !errors.As(err, &te) {
+		fmt.Fprintf(stderr, "rtectl task verify: %s: %v\n", what, err)
+		return exitError
+	}
+	fmt.Fprintf(stderr, "rtectl task verify: %s differs from what was signed:\n", what)
+	writeFieldDiffs(stderr, te.Diffs)
+	return exitError
+}
+
+// writeFieldDiffs lists diffs one per line, as plan does.
+func writeFieldDiffs(w io.Writer, diffs []rte.FieldDiff) {
+	for _, d := range diffs {
+		fmt.Fprintf(w, "      %s\n", d)
+	}
+}
+
+// writeNew writes data to a file that must not already exist.
+func writeNew(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/engagement"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestRun_Task(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := filepath.Join(dir, "op.pem")
+	var stdout, stderr bytes.Buffer
+	if code := run(ctx, []string{"keygen", "-o", key}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("keygen exit = %d, stderr: %s", code, stderr.String())
+	}
+	signer, err := loadSigner(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := rte.Fingerprint(signer.PublicKey())
+	if !strings.Contains(stdout.String(), fp) {
+		t.Fatalf("keygen stdout = %s", stdout.String())
+	}
+	if _, err := os.Stat(key + ".pub"); err != nil {
+		t.Fatal(err)
+	}
+	if code := run(ctx, []string{"keygen", "-o", key}, nil, &stdout, &stderr); code != exitError {
+		t.Error("keygen overwrote an existing key")
+	}
+
+	taskPath, signedPath := filepath.Join(dir, "task.json"), filepath.Join(dir, "signed.json")
+	create := []string{"task", "create", "-engagement", "eng-1", "-type", "simulate_login", "-operator", "op",
+		"-approved-by", "lead", "-param", "target=host-1", "-o", taskPath}
+	if code := run(ctx, create, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("task create exit = %d, stderr: %s", code, stderr.String())
+	}
+	if code := run(ctx, []string{"task", "sign", "-key", key, "-o", signedPath, taskPath}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("task sign exit = %d, stderr: %s", code, stderr.String())
+	}
+	signed, err := os.ReadFile(signedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st rte.SignedTask
+	if err := json.Unmarshal(signed, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Task.Params["target"] != "host-1" || st.Task.CancelToken == "" || len(st.Task.ID) != 26 {
+		t.Fatalf("signed task = %+v", st.Task)
+	}
+
+	stdout.Reset()
+	if code := run(ctx, []string{"task", "verify", "-fingerprint", fp}, bytes.NewReader(signed), &stdout, &stderr); code != exitOK {
+		t.Fatalf("task verify exit = %d, stderr: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "signature OK") {
+		t.Errorf("verify stdout = %s", stdout.String())
+	}
+	other, _, _ := rte.GenerateKeyPair()
+	stderr.Reset()
+	if code := run(ctx, []string{"task", "verify", "-fingerprint", rte.Fingerprint(other), signedPath}, nil, &stdout, &stderr); code != exitError ||
+		!strings.Contains(stderr.String(), "not pinned") {
+		t.Errorf("verify against another key: exit %d, %s", code, stderr.String())
+	}
+	tampered := bytes.Replace(signed, []byte("host-1"), []byte("host-2"), 1)
+	if code := run(ctx, []string{"task", "verify", "-signature-only"}, bytes.NewReader(tampered), &stdout, &stderr); code != exitError {
+		t.Error("verified a tampered task")
+	}
+	stderr.Reset()
+	if code := run(ctx, []string{"task", "verify", "-against", taskPath}, bytes.NewReader(tampered), &stdout, &stderr); code != exitError ||
+		!strings.Contains(stderr.String(), `params.target: "host-1" -> "host-2"`) {
+		t.Errorf("verify against the created task: exit %d, %s", code, stderr.String())
+	}
+
+	var submitted []engagement.Remote
+	var cancels []rte.TaskCancel
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{"tasks": submitted})
+		case strings.HasSuffix(r.URL.Path, "/cancel"):
+			var c rte.TaskCancel
+			_ = json.NewDecoder(r.Body).Decode(&c)
+			cancels = append(cancels, c)
+		default:
+			var st rte.SignedTask
+			_ = json.NewDecoder(r.Body).Decode(&st)
+			submitted = append(submitted, engagement.Remote{Task: st, State: rte.StatePending})
+		}
+	}))
+	defer srv.Close()
+	if code := run(ctx, []string{"task", "submit", "-server", srv.URL, signedPath}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("task submit exit = %d, stderr: %s", code, stderr.String())
+	}
+	if len(submitted) != 1 || submitted[0].Task.Task.ID != st.Task.ID {
+		t.Fatalf("submitted = %+v", submitted)
+	}
+	cancel := []string{"task", "cancel", "-server", srv.URL, "-engagement", "eng-1", "-id", st.Task.ID, "-as", "lead"}
+	if code := run(ctx, cancel, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("task cancel exit = %d, stderr: %s", code, stderr.String())
+	}
+	want := rte.TaskCancel{Engagement: "eng-1", TaskID: st.Task.ID, Token: st.Task.CancelToken, RequestedBy: "lead"}
+	if len(cancels) != 1 || cancels[0] != want {
+		t.Errorf("cancels = %+v", cancels)
+	}
+	if code := run(ctx, []string{"task", "cancel", "-server", srv.URL, "-engagement", "eng-1", "-id", "nope"}, nil, &stdout, &stderr); code != exitError {
+		t.Error("cancelled a task the controller does not hold")
+	}
+}
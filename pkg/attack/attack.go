@@ -0,0 +1,106 @@
+// Package attack embeds a curated MITRE ATT&CK Enterprise catalog covering
+// the techniques RTE-A tasks simulate or are commonly mapped to. Tasks are
+// tagged with technique IDs from this catalog so coverage can be reported.
+package attack
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed catalog.json
+var catalogJSON []byte
+
+// Tactic is an ATT&CK tactic (the adversary's goal).
+type Tactic struct {
+	ID        string `json:"id"`
+	Shortname string `json:"shortname"`
+	Name      string `json:"name"`
+}
+
+// Technique is an ATT&CK technique or sub-technique. Sub-techniques inherit
+// their parent's tactics.
+type Technique struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Tactics []string `json:"tactics,omitempty"`
+}
+
+// Parent returns the parent technique ID of a sub-technique, or "" for a
+// top-level technique.
+func (t Technique) Parent() string {
+	if i := strings.IndexByte(t.ID, '.'); i > 0 {
+		return t.ID[:i]
+	}
+	return ""
+}
+
+var (
+	idPattern  = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+	tactics    []Tactic
+	techniques map[string]Technique
+)
+
+func init() {
+	var c struct {
+		Tactics    []Tactic    `json:"tactics"`
+		Techniques []Technique `json:"techniques"`
+	}
+	if err := json.Unmarshal(catalogJSON, &c); err != nil {
+		panic("attack: embedded catalog: " + err.Error())
+	}
+	tactics = c.Tactics
+	techniques = make(map[string]Technique, len(c.Techniques))
+	for _, t := range c.Techniques {
+		techniques[t.ID] = t
+	}
+	for id, t := range techniques {
+		if p := t.Parent(); p != "" {
+			parent, ok := techniques[p]
+			if !ok {
+				panic("attack: sub-technique " + id + " has no parent in catalog")
+			}
+			t.Name = parent.Name + ": " + t.Name
+			t.Tactics = parent.Tactics
+			techniques[id] = t
+		}
+	}
+}
+
+// Tactics returns the tactics in kill-chain order.
+func Tactics() []Tactic {
+	return append([]Tactic(nil), tactics...)
+}
+
+// Lookup returns the technique with the given ID. Sub-technique names are
+// qualified with the parent name, as in "Brute Force: Password Spraying".
+func Lookup(id string) (Technique, bool) {
+	t, ok := techniques[id]
+	return t, ok
+}
+
+// Validate returns an error unless id is a well-formed technique ID present
+// in the catalog.
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("malformed ATT&CK technique ID %q", id)
+	}
+	if _, ok := techniques[id]; !ok {
+		return fmt.Errorf("ATT&CK technique %s is not in the catalog", id)
+	}
+	return nil
+}
+
+// Techniques returns every catalog technique sorted by ID.
+func Techniques() []Technique {
+	out := make([]Technique, 0, len(techniques))
+	for _, t := range techniques {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
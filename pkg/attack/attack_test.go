@@ -0,0 +1,49 @@
+package attack
+
+import "testing"
+
+func TestCatalogConsistency(t *testing.T) {
+	shortnames := map[string]bool{}
+	for _, tac := range Tactics() {
+		shortnames[tac.Shortname] = true
+	}
+	if len(shortnames) != 14 {
+		t.Fatalf("got %d tactics, want 14", len(shortnames))
+	}
+	for _, tech := range Techniques() {
+		if err := Validate(tech.ID); err != nil {
+			t.Errorf("%s: %v", tech.ID, err)
+		}
+		if len(tech.Tactics) == 0 {
+			t.Errorf("%s has no tactics", tech.ID)
+		}
+		for _, tac := range tech.Tactics {
+			if !shortnames[tac] {
+				t.Errorf("%s references unknown tactic %s", tech.ID, tac)
+			}
+		}
+	}
+}
+
+func TestLookupSubTechnique(t *testing.T) {
+	tech, ok := Lookup("T1110.003")
+	if !ok || tech.Name != "Brute Force: Password Spraying" || tech.Parent() != "T1110" {
+		t.Fatalf("unexpected technique %+v", tech)
+	}
+	if len(tech.Tactics) != 1 || tech.Tactics[0] != "credential-access" {
+		t.Fatalf("sub-technique did not inherit tactics: %v", tech.Tactics)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, id := range []string{"T1110", "T1566.002"} {
+		if err := Validate(id); err != nil {
+			t.Errorf("%s: %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "t1110", "T111", "T1110.3", "T9999"} {
+		if err := Validate(id); err == nil {
+			t.Errorf("expected %q to be rejected", id)
+		}
+	}
+}
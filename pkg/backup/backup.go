@@ -0,0 +1,263 @@
+// Package backup takes consistent, optionally incremental, backups of a
+// controller's task store and audit chains and restores them, re-checking
+// every task signature and audit hash chain once the data is back in place.
+//
+// A full backup holds every record. An incremental backup names the backup
+// it follows and holds only task records updated since that backup was
+// taken and audit records appended since. Restoring applies a full backup
+// and then its incrementals in order, stopping at a chosen point in time.
+//
+// A bundle is narrower: one engagement's tasks, results, and audit chain in
+// a signed archive, for handing to a customer or moving to another
+// controller. See Bundler.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// FormatVersion is the backup format Take writes.
+const FormatVersion = 1
+
+// Snapshotter is a task store that can return all of its records as of one
+// instant. Only such stores can be backed up: a record-by-record walk of a
+// live store could pair one task's old state with another's new one.
+type Snapshotter interface {
+	Snapshot(ctx context.Context) ([]rte.TaskRecord, error)
+}
+
+// Backup is one full or incremental backup.
+type Backup struct {
+	Format int    `json:"format"`
+	ID     string `json:"id"`
+	// Base is the ID of the backup this one follows; empty for a full
+	// backup.
+	Base    string           `json:"base,omitempty"`
+	TakenAt time.Time        `json:"taken_at"`
+	Tasks   []rte.TaskRecord `json:"tasks"`
+	// Audit holds audit records across engagements, each engagement's in
+	// chain order.
+	Audit []rte.AuditRecord `json:"audit"`
+	// Heads is, per engagement, the last audit sequence number covered by
+	// this backup and those before it; the next incremental starts after
+	// it.
+	Heads map[string]int `json:"heads"`
+	// Digest is the SHA-256 of the backup with Digest empty; Read rejects
+	// a backup that does not match it.
+	Digest string `json:"digest"`
+}
+
+// Full reports whether b is a full backup.
+func (b *Backup) Full() bool { return b.Base == "" }
+
+// Take backs up store and audit as of now. With a nil base it takes a full
+// backup; otherwise an incremental one following base. audit maps each
+// engagement to its chain, as AuditLog.Snapshot returns.
+func Take(ctx context.Context, store Snapshotter, audit map[string][]rte.AuditRecord, base *Backup, now time.Time) (*Backup, error) {
+	b := &Backup{Format: FormatVersion, ID: rte.NewTaskID(), TakenAt: now.UTC(), Heads: map[string]int{}}
+	recs, err := store.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot task store: %w", err)
+	}
+	var since time.Time
+	if base != nil {
+		b.Base, since = base.ID, base.TakenAt
+		for eng, seq := range base.Heads {
+			b.Heads[eng] = seq
+		}
+	}
+	for _, rec := range recs {
+		if base == nil || rec.UpdatedAt.After(since) {
+			b.Tasks = append(b.Tasks, rec)
+		}
+	}
+	engs := make([]string, 0, len(audit))
+	for eng := range audit {
+		engs = append(engs, eng)
+	}
+	sort.Strings(engs)
+	for _, eng := range engs {
+		chain, head := audit[eng], b.Heads[eng]
+		if n := len(chain); (n > 0 && chain[n-1].Sequence < head) || (n == 0 && head > 0) {
+			return nil, fmt.Errorf("engagement %s: audit chain is shorter than the base backup's", eng)
+		}
+		for _, r := range chain {
+			if r.Sequence > head {
+				b.Audit = append(b.Audit, r)
+				b.Heads[eng] = r.Sequence
+			}
+		}
+	}
+	if b.Digest, err = b.digest(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Backup) digest() (string, error) {
+	c := *b
+	c.Digest = ""
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Write writes b as JSON.
+func Write(w io.Writer, b *Backup) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// Read reads a backup written by Write and checks its digest.
+func Read(r io.Reader) (*Backup, error) {
+	var b Backup
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("read backup: %w", err)
+	}
+	if b.Format != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format %d", b.Format)
+	}
+	sum, err := b.digest()
+	if err != nil {
+		return nil, err
+	}
+	if sum != b.Digest {
+		return nil, fmt.Errorf("backup %s: digest mismatch", b.ID)
+	}
+	return &b, nil
+}
+
+// Chain orders backups into a restore sequence: one full backup followed
+// by each incremental that names the one before it. It drops backups taken
+// after at, unless at is zero, so the result restores the latest state
+// backed up at or before at.
+func Chain(backups []*Backup, at time.Time) ([]*Backup, error) {
+	next := make(map[string]*Backup, len(backups))
+	var full *Backup
+	for _, b := range backups {
+		if b.Full() {
+			if full != nil {
+				return nil, fmt.Errorf("backups %s and %s are both full backups", full.ID, b.ID)
+			}
+			full = b
+			continue
+		}
+		if other, ok := next[b.Base]; ok {
+			return nil, fmt.Errorf("backups %s and %s both follow %s", other.ID, b.ID, b.Base)
+		}
+		next[b.Base] = b
+	}
+	if full == nil {
+		return nil, errors.New("no full backup given")
+	}
+	if !at.IsZero() && full.TakenAt.After(at) {
+		return nil, fmt.Errorf("full backup %s was taken at %s, after %s", full.ID, full.TakenAt.Format(time.RFC3339), at.Format(time.RFC3339))
+	}
+	chain := []*Backup{full}
+	for b := next[full.ID]; b != nil; b = next[b.ID] {
+		if !at.IsZero() && b.TakenAt.After(at) {
+			break
+		}
+		chain = append(chain, b)
+	}
+	if len(chain) < len(backups) && at.IsZero() {
+		return nil, fmt.Errorf("%d incremental backups do not follow on from full backup %s", len(backups)-len(chain), full.ID)
+	}
+	return chain, nil
+}
+
+// Restored is the state a restore wrote, for the caller to persist the
+// audit chains wherever it keeps them.
+type Restored struct {
+	Tasks int
+	Audit map[string][]rte.AuditRecord
+}
+
+// Restore applies chain, as returned by Chain, to store, which must be
+// empty, then runs Verify against what the store holds afterwards.
+func Restore(ctx context.Context, store rte.TaskStore, chain []*Backup) (*Restored, error) {
+	if len(chain) == 0 || !chain[0].Full() {
+		return nil, errors.New("restore needs a chain starting with a full backup")
+	}
+	existing, err := store.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("restore target already holds %d task records", len(existing))
+	}
+	tasks := map[[2]string]rte.TaskRecord{}
+	var order [][2]string
+	audit := map[string][]rte.AuditRecord{}
+	for i, b := range chain {
+		if i > 0 && b.Base != chain[i-1].ID {
+			return nil, fmt.Errorf("backup %s does not follow %s", b.ID, chain[i-1].ID)
+		}
+		for _, rec := range b.Tasks {
+			key := [2]string{rec.Engagement(), rec.ID()}
+			if _, ok := tasks[key]; !ok {
+				order = append(order, key)
+			}
+			tasks[key] = rec
+		}
+		for _, r := range b.Audit {
+			audit[r.EngagementID] = append(audit[r.EngagementID], r)
+		}
+	}
+	for _, key := range order {
+		if err := store.Put(ctx, tasks[key]); err != nil {
+			return nil, fmt.Errorf("restore %s/%s: %w", key[0], key[1], err)
+		}
+	}
+	if err := Verify(ctx, store, audit); err != nil {
+		return nil, fmt.Errorf("verify restore: %w", err)
+	}
+	return &Restored{Tasks: len(order), Audit: audit}, nil
+}
+
+// Verify re-checks restored data: the operator signature, and approval
+// countersignature if any, of every task in store, and every engagement's
+// audit hash chain. Expiry is not checked; restored tasks are evidence.
+func Verify(ctx context.Context, store rte.TaskStore, audit map[string][]rte.AuditRecord) error {
+	recs, err := store.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, rec := range recs {
+		if err := rte.VerifyTaskSignature(rec.Task); err != nil {
+			errs = append(errs, fmt.Errorf("task %s/%s: %w", rec.Engagement(), rec.ID(), err))
+			continue
+		}
+		if rec.Task.Approval != nil {
+			if err := rte.VerifyApproval(rec.Task); err != nil {
+				errs = append(errs, fmt.Errorf("task %s/%s: %w", rec.Engagement(), rec.ID(), err))
+			}
+		}
+	}
+	engs := make([]string, 0, len(audit))
+	for eng := range audit {
+		engs = append(engs, eng)
+	}
+	sort.Strings(engs)
+	for _, eng := range engs {
+		if err := rte.VerifyAuditChain(audit[eng]); err != nil {
+			errs = append(errs, fmt.Errorf("engagement %s: %w", eng, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func signed(t *testing.T, id string) *rte.SignedTask {
+	t.Helper()
+	pub, priv, err := rte.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := rte.SignTask(rte.Task{
+		ID: id, Engagement: "eng-1", Type: rte.TaskSimulateLogin, CreatedAt: time.Now().UTC(),
+		TTLSeconds: 600, Operator: "op-alice", ApprovedBy: "lead-bob", State: rte.StatePending,
+	}, priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func roundTrip(t *testing.T, b *Backup) *Backup {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	src, err := rte.OpenFileStore(filepath.Join(t.TempDir(), "tasks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := rte.NewAuditLog("op-alice")
+	t0 := time.Now().UTC()
+	put := func(id string, at time.Time) {
+		if err := src.Put(ctx, rte.TaskRecord{Task: signed(t, id), State: rte.StatePending, UpdatedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := audit.Append("eng-1", "task.create", id, id, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("t-1", t0)
+	full, err := Take(ctx, src, audit.Snapshot(), nil, t0.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	full = roundTrip(t, full)
+
+	put("t-2", t0.Add(2*time.Second))
+	if err := src.Update(ctx, "eng-1", "t-1", func(r *rte.TaskRecord) error {
+		r.State, r.UpdatedAt = rte.StateExecuting, t0.Add(2*time.Second)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	inc1, err := Take(ctx, src, audit.Snapshot(), full, t0.Add(3*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inc1.Tasks) != 2 || len(inc1.Audit) != 1 || inc1.Audit[0].Sequence != 2 {
+		t.Fatalf("incremental holds %d tasks, %d audit records", len(inc1.Tasks), len(inc1.Audit))
+	}
+	put("t-3", t0.Add(4*time.Second))
+	inc2, err := Take(ctx, src, audit.Snapshot(), inc1, t0.Add(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore to just after the first incremental.
+	chain, err := Chain([]*Backup{inc2, full, roundTrip(t, inc1)}, t0.Add(3*time.Second))
+	if err != nil || len(chain) != 2 {
+		t.Fatalf("Chain = %d backups, %v", len(chain), err)
+	}
+	dst := rte.NewMemoryStore()
+	got, err := Restore(ctx, dst, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Tasks != 2 || len(got.Audit["eng-1"]) != 2 {
+		t.Errorf("restored %d tasks, %d audit records", got.Tasks, len(got.Audit["eng-1"]))
+	}
+	if rec, _ := dst.Get(ctx, "eng-1", "t-1"); rec.State != rte.StateExecuting {
+		t.Errorf("t-1 restored as %s", rec.State)
+	}
+	if _, err := Restore(ctx, dst, chain); err == nil {
+		t.Error("restored over a non-empty store")
+	}
+
+	// The whole chain restores everything.
+	chain, err = Chain([]*Backup{full, inc1, inc2}, time.Time{})
+	if err != nil || len(chain) != 3 {
+		t.Fatalf("Chain = %d backups, %v", len(chain), err)
+	}
+	if got, err := Restore(ctx, rte.NewMemoryStore(), chain); err != nil || got.Tasks != 3 {
+		t.Fatalf("full restore: %+v, %v", got, err)
+	}
+	if _, err := Chain([]*Backup{full, inc2}, time.Time{}); err == nil {
+		t.Error("Chain accepted a gap")
+	}
+}
+
+func TestRestoreVerifies(t *testing.T) {
+	ctx := context.Background()
+	src := rte.NewMemoryStore()
+	st := signed(t, "t-1")
+	st.Task.Operator = "mallory"
+	if err := src.Put(ctx, rte.TaskRecord{Task: st, State: rte.StatePending}); err != nil {
+		t.Fatal(err)
+	}
+	audit := rte.NewAuditLog("op-alice")
+	_, _ = audit.Append("eng-1", "task.create", "t-1", "t-1", nil)
+	chains := audit.Snapshot()
+	chains["eng-1"][0].Action = "task.cancel"
+	b, err := Take(ctx, src, chains, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Restore(ctx, rte.NewMemoryStore(), []*Backup{b})
+	if err == nil || !strings.Contains(err.Error(), "task eng-1/t-1") || !strings.Contains(err.Error(), "chain_hash mismatch") {
+		t.Fatalf("Restore = %v", err)
+	}
+}
+
+func TestReadRejectsTampering(t *testing.T) {
+	b, err := Take(context.Background(), rte.NewMemoryStore(), nil, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	_ = Write(&buf, b)
+	data := strings.Replace(buf.String(), b.TakenAt.Format(time.RFC3339Nano), "2020-01-01T00:00:00Z", 1)
+	if _, err := Read(strings.NewReader(data)); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Fatalf("Read tampered = %v", err)
+	}
+}
@@ -0,0 +1,476 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// BundleFormatVersion is the bundle format ExportBundle writes.
+const BundleFormatVersion = 1
+
+// Files in a bundle archive. The manifest comes first.
+const (
+	bundleManifest = "manifest.json"
+	bundleTasks    = "tasks.json"
+	bundleResults  = "results.json"
+	bundleAudit    = "audit.json"
+)
+
+// maxBundleFile bounds each file read from a bundle.
+const maxBundleFile = 256 << 20
+
+// Manifest describes an engagement bundle: what it holds, a digest of each
+// file, and the fingerprints of every key that signed or approved a task.
+type Manifest struct {
+	Format     int       `json:"format"`
+	Engagement string    `json:"engagement"`
+	ExportedAt time.Time `json:"exported_at"`
+	Tasks      int       `json:"tasks"`
+	Results    int       `json:"results"`
+	Audit      int       `json:"audit_records"`
+	// Classification is the bundle's level and Marking its banner, the
+	// classification and distribution statement, as ExportBundleMarked
+	// sets them. Withheld counts the tasks and results left out, and the
+	// audit records redacted, as classified above Classification.
+	Classification rte.Classification `json:"classification,omitempty"`
+	Marking        string             `json:"marking,omitempty"`
+	Withheld       int                `json:"withheld,omitempty"`
+	// Keys are the fingerprints of the keys that signed and approved the
+	// bundle's tasks, sorted.
+	Keys []string `json:"keys"`
+	// Files maps each file in the bundle to the hex SHA-256 of its
+	// contents.
+	Files map[string]string `json:"files"`
+}
+
+// SignedManifest wraps a Manifest with the exporting controller's
+// signature. It signs the whole bundle, since the manifest holds the
+// digest of every other file.
+type SignedManifest struct {
+	Manifest  Manifest `json:"manifest"`
+	PublicKey []byte   `json:"public_key"`
+	Signature []byte   `json:"signature"`
+}
+
+// Bundler exports an engagement's full record as one signed archive, to
+// hand to a customer or move to another controller, and imports such
+// archives.
+type Bundler struct {
+	Store rte.TaskStore
+	// Audit holds the engagement audit chains; nil leaves audit out.
+	Audit *rte.AuditLog
+	// Signer signs exported bundles.
+	Signer rte.Signer
+	// Trust, if set, is the set of controller keys whose bundles
+	// ImportBundle accepts. Without it any validly signed bundle is
+	// accepted and the caller should check the manifest's signer.
+	Trust *rte.KeyPins
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+}
+
+// ExportOptions mark an exported bundle and bound what it releases.
+type ExportOptions struct {
+	// Metadata is the engagement's. Its classification is the level of
+	// every task not marked otherwise.
+	Metadata rte.EngagementMetadata
+	// Classification is the bundle's level; "" means the engagement's.
+	Classification rte.Classification
+}
+
+// ExportBundle writes engagementID's tasks, results, and audit chain to w
+// as a gzipped tar archive with a signed manifest. It withholds and marks
+// nothing; use ExportBundleMarked for a deliverable.
+func (b *Bundler) ExportBundle(ctx context.Context, engagementID string, w io.Writer) (*SignedManifest, error) {
+	return b.ExportBundleMarked(ctx, engagementID, w, ExportOptions{})
+}
+
+// ExportBundleMarked is ExportBundle for a deliverable at
+// opts.Classification. Tasks classified above it are left out, with their
+// results, and their audit records are redacted, so the bundle carries the
+// whole audit chain, verified before anything is withheld. Results and
+// audit records without a task are at the engagement's level. The
+// manifest carries the bundle's level and marking. A bundle that withholds
+// anything verifies but cannot be imported, since its audit chain lacks
+// the redacted contents.
+func (b *Bundler) ExportBundleMarked(ctx context.Context, engagementID string, w io.Writer, opts ExportOptions) (*SignedManifest, error) {
+	if b.Signer == nil {
+		return nil, errors.New("bundle export needs a signer")
+	}
+	engLevel := opts.Metadata.Classification
+	level := opts.Classification
+	if level == "" {
+		level = engLevel
+	}
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+	all, err := b.Store.List(ctx, engagementID)
+	if err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("engagement %s has no tasks", engagementID)
+	}
+	taskLevels := make(map[string]rte.Classification, len(all))
+	for _, rec := range all {
+		taskLevels[rec.ID()] = rec.Task.Task.Classification
+	}
+	// Without a level, as for a backup, everything is released.
+	releasable := func(taskID *string) bool {
+		if level == "" {
+			return true
+		}
+		var c rte.Classification
+		if taskID != nil {
+			c = taskLevels[*taskID]
+		}
+		return rte.Releasable(c, engLevel, level)
+	}
+	withheld := 0
+	keys := map[string]struct{}{}
+	var recs []rte.TaskRecord
+	var results []rte.TaskResult
+	for _, rec := range all {
+		id := rec.ID()
+		if !releasable(&id) {
+			withheld++
+			if rec.Result != nil {
+				withheld++
+			}
+			continue
+		}
+		st := rec.Task
+		keys[rte.Fingerprint(st.PublicKey)] = struct{}{}
+		if st.Approval != nil {
+			keys[rte.Fingerprint(st.Approval.PublicKey)] = struct{}{}
+		}
+		if rec.Result != nil {
+			results = append(results, *rec.Result)
+			rec.Result = nil
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("engagement %s has no tasks releasable at %s", engagementID, level)
+	}
+	var audit []rte.AuditRecord
+	if b.Audit != nil {
+		all := b.Audit.Records(engagementID)
+		if err := rte.VerifyAuditChain(all); err != nil {
+			return nil, fmt.Errorf("audit chain of %s: %w", engagementID, err)
+		}
+		for _, rec := range all {
+			if !releasable(rec.TaskID) {
+				rec = rec.Redact()
+				withheld++
+			}
+			audit = append(audit, rec)
+		}
+	}
+	now := time.Now
+	if b.Now != nil {
+		now = b.Now
+	}
+	m := Manifest{
+		Format:         BundleFormatVersion,
+		Engagement:     engagementID,
+		ExportedAt:     now().UTC(),
+		Tasks:          len(recs),
+		Results:        len(results),
+		Audit:          len(audit),
+		Classification: level,
+		Withheld:       withheld,
+		Files:          map[string]string{},
+	}
+	if level != "" {
+		marking := opts.Metadata
+		marking.Classification = level
+		m.Marking = marking.Marking()
+	}
+	for fp := range keys {
+		m.Keys = append(m.Keys, fp)
+	}
+	sort.Strings(m.Keys)
+
+	files := []struct {
+		name string
+		v    any
+	}{{bundleTasks, recs}, {bundleResults, results}, {bundleAudit, audit}}
+	data := make([][]byte, len(files))
+	for i, f := range files {
+		if data[i], err = json.MarshalIndent(f.v, "", "  "); err != nil {
+			return nil, fmt.Errorf("marshal %s: %w", f.name, err)
+		}
+		m.Files[f.name] = sha256Hex(data[i])
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+	sig, err := b.Signer.Sign(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("sign manifest: %w", err)
+	}
+	sm := &SignedManifest{Manifest: m, PublicKey: b.Signer.PublicKey(), Signature: sig}
+	manifest, err := json.MarshalIndent(sm, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, body []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), ModTime: m.ExportedAt, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(body)
+		return err
+	}
+	if err := add(bundleManifest, manifest); err != nil {
+		return nil, err
+	}
+	for i, f := range files {
+		if err := add(f.name, data[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// ImportBundle reads a bundle written by ExportBundle, checks its manifest
+// signature, file digests, task signatures, and audit chain, and only then
+// adds its tasks to the store and its audit chain to the audit log. It
+// adds nothing if any task is already in the store, or if the bundle
+// withholds records above its classification.
+func (b *Bundler) ImportBundle(ctx context.Context, r io.Reader) (*SignedManifest, error) {
+	sm, recs, audit, err := b.readVerified(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	m := sm.Manifest
+	if m.Withheld > 0 {
+		return nil, fmt.Errorf("bundle withholds %d records above %s and cannot be imported", m.Withheld, m.Classification)
+	}
+	for _, rec := range recs {
+		if _, err := b.Store.Get(ctx, m.Engagement, rec.ID()); err == nil {
+			return nil, fmt.Errorf("%w: %s/%s", rte.ErrDuplicateTaskID, m.Engagement, rec.ID())
+		} else if !errors.Is(err, rte.ErrTaskNotFound) {
+			return nil, err
+		}
+	}
+	if b.Audit != nil && len(audit) > 0 {
+		if err := b.Audit.Load(m.Engagement, audit); err != nil {
+			return nil, err
+		}
+	}
+	for _, rec := range recs {
+		if err := b.Store.Put(ctx, rec); err != nil {
+			return nil, fmt.Errorf("import %s/%s: %w", m.Engagement, rec.ID(), err)
+		}
+	}
+	return sm, nil
+}
+
+// VerifyBundle runs every check ImportBundle does on a bundle without
+// importing it, as when confirming an archive was written intact.
+func (b *Bundler) VerifyBundle(ctx context.Context, r io.Reader) (*SignedManifest, error) {
+	sm, _, _, err := b.readVerified(ctx, r)
+	return sm, err
+}
+
+// readVerified reads and checks a bundle, returning its manifest, its task
+// records with their results attached, and its audit chain.
+func (b *Bundler) readVerified(ctx context.Context, r io.Reader) (*SignedManifest, []rte.TaskRecord, []rte.AuditRecord, error) {
+	files, err := readBundle(r)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var sm SignedManifest
+	if err := json.Unmarshal(files[bundleManifest], &sm); err != nil {
+		return nil, nil, nil, fmt.Errorf("bundle %s: %w", bundleManifest, err)
+	}
+	if err := b.verifyManifest(&sm, files); err != nil {
+		return nil, nil, nil, err
+	}
+	m := sm.Manifest
+	var recs []rte.TaskRecord
+	var results []rte.TaskResult
+	var audit []rte.AuditRecord
+	for name, v := range map[string]any{bundleTasks: &recs, bundleResults: &results, bundleAudit: &audit} {
+		if err := json.Unmarshal(files[name], v); err != nil {
+			return nil, nil, nil, fmt.Errorf("bundle %s: %w", name, err)
+		}
+	}
+	if len(recs) != m.Tasks || len(results) != m.Results || len(audit) != m.Audit {
+		return nil, nil, nil, errors.New("bundle contents do not match the manifest counts")
+	}
+
+	byID := make(map[string]int, len(recs))
+	for i, rec := range recs {
+		if rec.Task == nil || rec.Engagement() != m.Engagement {
+			return nil, nil, nil, fmt.Errorf("bundle task %d is not in engagement %s", i, m.Engagement)
+		}
+		byID[rec.ID()] = i
+	}
+	for _, res := range results {
+		i, ok := byID[res.TaskID]
+		if !ok || res.Engagement != m.Engagement {
+			return nil, nil, nil, fmt.Errorf("bundle result for unknown task %s/%s", res.Engagement, res.TaskID)
+		}
+		res := res
+		recs[i].Result = &res
+	}
+	staged := rte.NewMemoryStore()
+	for _, rec := range recs {
+		if err := staged.Put(ctx, rec); err != nil {
+			return nil, nil, nil, fmt.Errorf("bundle task %s: %w", rec.ID(), err)
+		}
+	}
+	if err := Verify(ctx, staged, nil); err != nil {
+		return nil, nil, nil, fmt.Errorf("verify bundle: %w", err)
+	}
+	// A bundle that withholds records redacts their audit records, but
+	// every link of the chain is still checked.
+	verifyChain := rte.VerifyAuditChain
+	if m.Withheld > 0 {
+		verifyChain = rte.VerifyRedactedAuditChain
+	}
+	if err := verifyChain(audit); err != nil {
+		return nil, nil, nil, fmt.Errorf("verify bundle: engagement %s: %w", m.Engagement, err)
+	}
+	if err := checkKeys(recs, m.Keys); err != nil {
+		return nil, nil, nil, err
+	}
+	return &sm, recs, audit, nil
+}
+func (b *Bundler) verifyManifest(sm *SignedManifest, files map[string][]byte) error {
+	if sm.Manifest.Format != BundleFormatVersion {
+		return fmt.Errorf("unsupported bundle format %d", sm.Manifest.Format)
+	}
+	if len(sm.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sm.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	if b.Trust != nil && !b.Trust.Pinned(sm.PublicKey) {
+		return fmt.Errorf("%w: bundle signed by %s", rte.ErrUnpinnedKey, rte.Fingerprint(sm.PublicKey))
+	}
+	payload, err := json.Marshal(sm.Manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if !ed25519.Verify(sm.PublicKey, payload, sm.Signature) {
+		return errors.New("bundle manifest signature verification failed")
+	}
+	for _, name := range []string{bundleTasks, bundleResults, bundleAudit} {
+		want, ok := sm.Manifest.Files[name]
+		if !ok {
+			return fmt.Errorf("bundle manifest does not list %s", name)
+		}
+		data, ok := files[name]
+		if !ok {
+			return fmt.Errorf("bundle is missing %s", name)
+		}
+		if sha256Hex(data) != want {
+			return fmt.Errorf("bundle %s: digest mismatch", name)
+		}
+	}
+	if len(sm.Manifest.Files) != 3 || len(files) != 4 {
+		return errors.New("bundle holds files its manifest does not list")
+	}
+	return nil
+}
+
+// checkKeys confirms the manifest's key list names exactly the keys that
+// signed and approved recs.
+func checkKeys(recs []rte.TaskRecord, keys []string) error {
+	listed := make(map[string]bool, len(keys))
+	for _, fp := range keys {
+		listed[fp] = false
+	}
+	for _, rec := range recs {
+		fps := []string{rte.Fingerprint(rec.Task.PublicKey)}
+		if rec.Task.Approval != nil {
+			fps = append(fps, rte.Fingerprint(rec.Task.Approval.PublicKey))
+		}
+		for _, fp := range fps {
+			if _, ok := listed[fp]; !ok {
+				return fmt.Errorf("task %s is signed by key %s, which the manifest does not list", rec.ID(), fp)
+			}
+			listed[fp] = true
+		}
+	}
+	for fp, used := range listed {
+		if !used {
+			return fmt.Errorf("manifest lists key %s, which signed no task", fp)
+		}
+	}
+	return nil
+}
+
+// readBundle reads every file of a bundle archive into memory.
+func readBundle(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("bundle entry %s is not a regular file", hdr.Name)
+		}
+		if _, dup := files[hdr.Name]; dup {
+			return nil, fmt.Errorf("bundle holds %s twice", hdr.Name)
+		}
+		if len(files) == 0 && hdr.Name != bundleManifest {
+			return nil, fmt.Errorf("bundle must start with %s", bundleManifest)
+		}
+		var buf bytes.Buffer
+		if n, err := io.Copy(&buf, io.LimitReader(tr, maxBundleFile+1)); err != nil {
+			return nil, fmt.Errorf("read bundle %s: %w", hdr.Name, err)
+		} else if n > maxBundleFile {
+			return nil, fmt.Errorf("bundle %s is larger than %d bytes", hdr.Name, maxBundleFile)
+		}
+		files[hdr.Name] = buf.Bytes()
+	}
+	if _, ok := files[bundleManifest]; !ok {
+		return nil, errors.New("bundle has no manifest")
+	}
+	return files, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
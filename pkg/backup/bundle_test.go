@@ -0,0 +1,224 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func exportFixture(t *testing.T) (*Bundler, *bytes.Buffer, rte.Signer) {
+	t.Helper()
+	ctx := context.Background()
+	store := rte.NewMemoryStore()
+	audit := rte.NewAuditLog("op-alice")
+	for _, id := range []string{"t-1", "t-2"} {
+		st := signed(t, id)
+		rec := rte.TaskRecord{Task: st, State: rte.StatePending}
+		if id == "t-1" {
+			_, lead, _ := rte.GenerateKeyPair()
+			if err := rte.Countersign(st, lead, lead.Public().(ed25519.PublicKey)); err != nil {
+				t.Fatal(err)
+			}
+			rec.State = rte.StateCompleted
+			rec.Result = &rte.TaskResult{TaskID: id, Engagement: "eng-1", State: rte.StateCompleted, Output: json.RawMessage(`{"hosts":3}`)}
+		}
+		if err := store.Put(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = audit.Append("eng-1", "task.create", id, id, nil)
+	}
+	pub, priv, _ := rte.GenerateKeyPair()
+	signer, _ := rte.NewKeySigner(priv, pub)
+	b := &Bundler{Store: store, Audit: audit, Signer: signer}
+	var buf bytes.Buffer
+	sm, err := b.ExportBundle(ctx, "eng-1", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := sm.Manifest; m.Tasks != 2 || m.Results != 1 || m.Audit != 2 || len(m.Keys) != 3 {
+		t.Fatalf("manifest = %+v", m)
+	}
+	return b, &buf, signer
+}
+
+func TestBundle_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	_, buf, signer := exportFixture(t)
+	pins, _ := rte.NewKeyPins(rte.Fingerprint(signer.PublicKey()))
+	dst := &Bundler{Store: rte.NewMemoryStore(), Audit: rte.NewAuditLog("ctl-2"), Trust: pins}
+	data := buf.Bytes()
+	if sm, err := dst.VerifyBundle(ctx, bytes.NewReader(data)); err != nil || sm.Manifest.Tasks != 2 {
+		t.Fatalf("VerifyBundle = %+v, %v", sm, err)
+	}
+	if recs, _ := dst.Store.List(ctx, ""); len(recs) != 0 {
+		t.Errorf("VerifyBundle imported %d tasks", len(recs))
+	}
+	sm, err := dst.ImportBundle(ctx, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.Manifest.Engagement != "eng-1" {
+		t.Errorf("manifest engagement = %s", sm.Manifest.Engagement)
+	}
+	rec, err := dst.Store.Get(ctx, "eng-1", "t-1")
+	if err != nil || rec.Result == nil || rec.Task.Approval == nil {
+		t.Fatalf("imported t-1 = %+v, %v", rec, err)
+	}
+	var out bytes.Buffer
+	if err := json.Compact(&out, rec.Result.Output); err != nil || out.String() != `{"hosts":3}` {
+		t.Errorf("imported output = %s", rec.Result.Output)
+	}
+	if n := len(dst.Audit.Records("eng-1")); n != 2 {
+		t.Errorf("imported %d audit records", n)
+	}
+	if _, err := dst.ImportBundle(ctx, bytes.NewReader(data)); !errors.Is(err, rte.ErrDuplicateTaskID) {
+		t.Errorf("second import: %v", err)
+	}
+
+	other, _ := rte.NewKeyPins()
+	if _, err := (&Bundler{Store: rte.NewMemoryStore(), Trust: other}).ImportBundle(ctx, bytes.NewReader(data)); !errors.Is(err, rte.ErrUnpinnedKey) {
+		t.Errorf("untrusted signer: %v", err)
+	}
+}
+
+// rewrite returns the bundle with fn applied to each file's contents.
+func rewrite(t *testing.T, bundle []byte, fn func(name string, body []byte) []byte) []byte {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(tr)
+		body = fn(hdr.Name, body)
+		hdr.Size = int64(len(body))
+		_ = tw.WriteHeader(hdr)
+		_, _ = tw.Write(body)
+	}
+	_ = tw.Close()
+	_ = gw.Close()
+	return out.Bytes()
+}
+
+func TestBundle_RejectsTampering(t *testing.T) {
+	ctx := context.Background()
+	_, buf, _ := exportFixture(t)
+	for _, tc := range []struct {
+		name, file, old, new, want string
+	}{
+		{"result", bundleResults, `"completed"`, `"failed"`, "results.json: digest mismatch"},
+		{"manifest", bundleManifest, `"tasks": 2`, `"tasks": 1`, "signature verification failed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := rewrite(t, buf.Bytes(), func(name string, body []byte) []byte {
+				if name == tc.file {
+					return []byte(strings.Replace(string(body), tc.old, tc.new, 1))
+				}
+				return body
+			})
+			dst := &Bundler{Store: rte.NewMemoryStore()}
+			if _, err := dst.ImportBundle(ctx, bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("ImportBundle = %v, want %q", err, tc.want)
+			}
+			if recs, _ := dst.Store.List(ctx, ""); len(recs) != 0 {
+				t.Errorf("rejected bundle imported %d tasks", len(recs))
+			}
+			if _, err := dst.VerifyBundle(ctx, bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("VerifyBundle = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestBundle_ExportMarked(t *testing.T) {
+	ctx := context.Background()
+	store := rte.NewMemoryStore()
+	audit := rte.NewAuditLog("op-alice")
+	pub, priv, _ := rte.GenerateKeyPair()
+	for _, id := range []string{"t-1", "t-2"} {
+		task := rte.Task{
+			ID: id, Engagement: "eng-1", Type: rte.TaskSimulateLogin, CreatedAt: time.Now().UTC(),
+			TTLSeconds: 600, Operator: "op-alice", ApprovedBy: "lead-bob", State: rte.StatePending,
+		}
+		rec := rte.TaskRecord{State: rte.StatePending}
+		if id == "t-1" {
+			task.Classification = rte.ClassificationRestricted
+			rec.State = rte.StateCompleted
+			rec.Result = &rte.TaskResult{TaskID: id, Engagement: "eng-1", State: rte.StateCompleted}
+		}
+		st, err := rte.SignTask(task, priv, pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.Task = st
+		if err := store.Put(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = audit.Append("eng-1", "task.create", id, id, nil)
+	}
+	cpub, cpriv, _ := rte.GenerateKeyPair()
+	signer, _ := rte.NewKeySigner(cpriv, cpub)
+	b := &Bundler{Store: store, Audit: audit, Signer: signer}
+	meta := rte.EngagementMetadata{Classification: rte.ClassificationInternal, Distribution: "ACME security staff only"}
+
+	var buf bytes.Buffer
+	sm, err := b.ExportBundleMarked(ctx, "eng-1", &buf, ExportOptions{Metadata: meta})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := sm.Manifest
+	if m.Tasks != 1 || m.Results != 0 || m.Audit != 2 || m.Withheld != 3 {
+		t.Fatalf("manifest = %+v", m)
+	}
+	if m.Classification != rte.ClassificationInternal || m.Marking != "INTERNAL // ACME security staff only" {
+		t.Errorf("marking = %q at %s", m.Marking, m.Classification)
+	}
+	dst := &Bundler{Store: rte.NewMemoryStore(), Audit: rte.NewAuditLog("ctl-2")}
+	_, _, chain, err := dst.readVerified(ctx, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("VerifyBundle: %v", err)
+	}
+	if len(chain) != 2 || !chain[0].Redacted || chain[0].TaskID != nil || chain[1].Redacted || *chain[1].TaskID != "t-2" {
+		t.Fatalf("audit chain = %+v, want t-1's record redacted in place", chain)
+	}
+	if _, err := dst.ImportBundle(ctx, bytes.NewReader(buf.Bytes())); err == nil {
+		t.Fatal("imported a bundle withholding records")
+	}
+
+	buf.Reset()
+	sm, err = b.ExportBundleMarked(ctx, "eng-1", &buf, ExportOptions{Metadata: meta, Classification: rte.ClassificationRestricted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := sm.Manifest; m.Tasks != 2 || m.Withheld != 0 || m.Marking != "RESTRICTED // ACME security staff only" {
+		t.Fatalf("manifest = %+v", m)
+	}
+	if _, err := dst.ImportBundle(ctx, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("ImportBundle: %v", err)
+	}
+
+	if _, err := b.ExportBundleMarked(ctx, "eng-1", &buf, ExportOptions{Classification: "SECRET"}); err == nil {
+		t.Error("exported at an unknown level")
+	}
+}
@@ -0,0 +1,370 @@
+// Package closeout closes an engagement out. A Checklist confirms that
+// every task has finished, every agent has been decommissioned, nothing the
+// engagement left on customer systems is still outstanding, the engagement
+// archive is written and verifies, and the signed report is in place; then
+// it issues a signed Certificate recording each check, for the customer and
+// for the engagement file.
+package closeout
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/backup"
+	"github.com/codethor0/rte-a-reference/pkg/enroll"
+	"github.com/codethor0/rte-a-reference/pkg/report"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Names of the close-out checks, in the order they run.
+const (
+	CheckTasks   = "tasks_terminal"
+	CheckAgents  = "agents_decommissioned"
+	CheckCleanup = "cleanup_ledger_empty"
+	CheckArchive = "archive_verified"
+	CheckReport  = "report_signed"
+)
+
+// AgentRegistry lists an engagement's agents that still hold a valid
+// enrollment. *enroll.Authority satisfies it.
+type AgentRegistry interface {
+	Agents(engagement string) []enroll.Agent
+}
+
+// CleanupLedger tracks what an engagement changed on customer systems,
+// such as accounts, files, or scheduled tasks, until it is removed.
+type CleanupLedger interface {
+	// Outstanding describes each item of engagement not yet cleaned up.
+	Outstanding(ctx context.Context, engagement string) ([]string, error)
+}
+
+// Check is the outcome of one close-out check.
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Certificate records that an engagement closed and the evidence it
+// closed on.
+type Certificate struct {
+	Engagement string    `json:"engagement"`
+	ClosedBy   string    `json:"closed_by"`
+	ClosedAt   time.Time `json:"closed_at"`
+	// Tasks counts the engagement's tasks by final state.
+	Tasks map[rte.TaskState]int `json:"tasks"`
+	// ArchiveSHA256 is the hex SHA-256 of the verified engagement archive.
+	ArchiveSHA256 string `json:"archive_sha256"`
+	// ReportSigner is the fingerprint of the key that signed the report.
+	ReportSigner string  `json:"report_signer"`
+	Checks       []Check `json:"checks"`
+}
+
+// SignedCertificate wraps a Certificate with the closing controller's
+// signature.
+type SignedCertificate struct {
+	Certificate Certificate `json:"certificate"`
+	PublicKey   []byte      `json:"public_key"`
+	Signature   []byte      `json:"signature"`
+}
+
+// IncompleteError is returned by Close when any check fails. Checks holds
+// every check, passed or not, so the caller can show what is left to do.
+type IncompleteError struct {
+	Engagement string
+	Checks     []Check
+}
+
+func (e *IncompleteError) Error() string {
+	var failed []string
+	for _, c := range e.Checks {
+		if !c.Passed {
+			failed = append(failed, c.Name+": "+c.Detail)
+		}
+	}
+	return fmt.Sprintf("engagement %s cannot close: %s", e.Engagement, strings.Join(failed, "; "))
+}
+
+// Checklist holds what an engagement closes against. A nil component fails
+// its check rather than being skipped, so a certificate always means every
+// check ran.
+type Checklist struct {
+	Store  rte.TaskStore
+	Agents AgentRegistry
+	// Cleanup is the engagement's cleanup ledger.
+	Cleanup CleanupLedger
+	// Bundler verifies the archive; set its Trust to pin the controller
+	// keys the archive may be signed by.
+	Bundler *backup.Bundler
+	// ArchivePath is the engagement's bundle, as Bundler.ExportBundle
+	// wrote it.
+	ArchivePath string
+	// ReportDir holds the report deliverables, as report.WriteDeliverables
+	// wrote them, and their report.ManifestName.
+	ReportDir string
+	// ReportTrust, if set, is the set of keys the report may be signed by.
+	ReportTrust *rte.KeyPins
+	// Signer signs the certificate.
+	Signer rte.Signer
+	// Audit, if set, gets an "engagement.close" record per certificate.
+	Audit *rte.AuditLog
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+}
+
+// Run performs every check for engagement and returns the certificate it
+// would issue, unsigned. It stops only if ctx ends.
+func (c *Checklist) Run(ctx context.Context, engagement string) (*Certificate, error) {
+	cert := &Certificate{Engagement: engagement}
+	recs, tasks := c.checkTasks(ctx, engagement, cert)
+	cert.Checks = append(cert.Checks,
+		tasks,
+		c.checkAgents(engagement),
+		c.checkCleanup(ctx, engagement),
+		c.checkArchive(ctx, engagement, recs, cert),
+		c.checkReport(engagement, cert),
+	)
+	return cert, ctx.Err()
+}
+
+// Close runs the checklist and, if every check passes, signs and returns
+// the certificate. Otherwise it returns an *IncompleteError.
+func (c *Checklist) Close(ctx context.Context, engagement, closedBy string) (*SignedCertificate, error) {
+	if engagement == "" {
+		return nil, rte.ErrMissingEngagement
+	}
+	if closedBy == "" {
+		return nil, errors.New("closed_by is required")
+	}
+	if c.Signer == nil {
+		return nil, errors.New("close-out needs a signer")
+	}
+	cert, err := c.Run(ctx, engagement)
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range cert.Checks {
+		if !ch.Passed {
+			return nil, &IncompleteError{Engagement: engagement, Checks: cert.Checks}
+		}
+	}
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	cert.ClosedBy, cert.ClosedAt = closedBy, now().UTC()
+	payload, err := json.Marshal(cert)
+	if err != nil {
+		return nil, fmt.Errorf("marshal certificate: %w", err)
+	}
+	sig, err := c.Signer.Sign(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("sign certificate: %w", err)
+	}
+	sc := &SignedCertificate{Certificate: *cert, PublicKey: c.Signer.PublicKey(), Signature: sig}
+	if c.Audit != nil {
+		detail := map[string]any{"archive_sha256": cert.ArchiveSHA256, "tasks": cert.Tasks}
+		if _, err := c.Audit.Append(engagement, "engagement.close", closedBy, "", detail); err != nil {
+			return sc, fmt.Errorf("audit close-out: %w", err)
+		}
+	}
+	return sc, nil
+}
+
+// VerifyCertificate checks the certificate signature and that it records
+// every check as passed.
+func VerifyCertificate(sc *SignedCertificate) error {
+	if sc == nil {
+		return errors.New("signed certificate is nil")
+	}
+	if len(sc.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sc.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	payload, err := json.Marshal(sc.Certificate)
+	if err != nil {
+		return fmt.Errorf("marshal certificate: %w", err)
+	}
+	if !ed25519.Verify(sc.PublicKey, payload, sc.Signature) {
+		return errors.New("certificate signature verification failed")
+	}
+	have := make(map[string]bool, len(sc.Certificate.Checks))
+	for _, ch := range sc.Certificate.Checks {
+		if !ch.Passed {
+			return fmt.Errorf("certificate records failed check %s", ch.Name)
+		}
+		have[ch.Name] = true
+	}
+	for _, name := range []string{CheckTasks, CheckAgents, CheckCleanup, CheckArchive, CheckReport} {
+		if !have[name] {
+			return fmt.Errorf("certificate is missing check %s", name)
+		}
+	}
+	return nil
+}
+
+func (c *Checklist) checkTasks(ctx context.Context, engagement string, cert *Certificate) ([]rte.TaskRecord, Check) {
+	ch := Check{Name: CheckTasks}
+	if c.Store == nil {
+		ch.Detail = "no task store"
+		return nil, ch
+	}
+	recs, err := c.Store.List(ctx, engagement)
+	if err != nil {
+		ch.Detail = err.Error()
+		return nil, ch
+	}
+	cert.Tasks = make(map[rte.TaskState]int)
+	var open []string
+	for _, rec := range recs {
+		cert.Tasks[rec.State]++
+		if !rec.Terminal() {
+			open = append(open, fmt.Sprintf("%s (%s)", rec.ID(), rec.State))
+		}
+	}
+	if len(open) > 0 {
+		ch.Detail = fmt.Sprintf("%d tasks not finished: %s", len(open), strings.Join(open, ", "))
+		return recs, ch
+	}
+	ch.Passed, ch.Detail = true, fmt.Sprintf("%d tasks finished", len(recs))
+	return recs, ch
+}
+
+func (c *Checklist) checkAgents(engagement string) Check {
+	ch := Check{Name: CheckAgents}
+	if c.Agents == nil {
+		ch.Detail = "no agent registry"
+		return ch
+	}
+	agents := c.Agents.Agents(engagement)
+	if len(agents) > 0 {
+		names := make([]string, len(agents))
+		for i, a := range agents {
+			names[i] = a.Name
+		}
+		sort.Strings(names)
+		ch.Detail = fmt.Sprintf("%d agents still enrolled: %s", len(names), strings.Join(names, ", "))
+		return ch
+	}
+	ch.Passed, ch.Detail = true, "no agents enrolled"
+	return ch
+}
+
+func (c *Checklist) checkCleanup(ctx context.Context, engagement string) Check {
+	ch := Check{Name: CheckCleanup}
+	if c.Cleanup == nil {
+		ch.Detail = "no cleanup ledger"
+		return ch
+	}
+	items, err := c.Cleanup.Outstanding(ctx, engagement)
+	switch {
+	case err != nil:
+		ch.Detail = err.Error()
+	case len(items) > 0:
+		ch.Detail = fmt.Sprintf("%d items not cleaned up: %s", len(items), strings.Join(items, "; "))
+	default:
+		ch.Passed, ch.Detail = true, "nothing outstanding"
+	}
+	return ch
+}
+
+// checkArchive verifies the archive and that it holds every task in recs.
+func (c *Checklist) checkArchive(ctx context.Context, engagement string, recs []rte.TaskRecord, cert *Certificate) Check {
+	ch := Check{Name: CheckArchive}
+	if c.Bundler == nil || c.ArchivePath == "" {
+		ch.Detail = "no archive configured"
+		return ch
+	}
+	f, err := os.Open(c.ArchivePath)
+	if err != nil {
+		ch.Detail = err.Error()
+		return ch
+	}
+	defer f.Close()
+	h := sha256.New()
+	sm, err := c.Bundler.VerifyBundle(ctx, io.TeeReader(f, h))
+	if err == nil {
+		// Hash whatever of the file the bundle reader left unread.
+		_, err = io.Copy(h, f)
+	}
+	switch {
+	case err != nil:
+		ch.Detail = err.Error()
+	case sm.Manifest.Engagement != engagement:
+		ch.Detail = fmt.Sprintf("archive is of engagement %s", sm.Manifest.Engagement)
+	case sm.Manifest.Tasks != len(recs):
+		ch.Detail = fmt.Sprintf("archive holds %d tasks, store %d; export it again", sm.Manifest.Tasks, len(recs))
+	default:
+		cert.ArchiveSHA256 = hex.EncodeToString(h.Sum(nil))
+		ch.Passed, ch.Detail = true, fmt.Sprintf("%s verified, signed by %s", filepath.Base(c.ArchivePath), rte.Fingerprint(sm.PublicKey))
+	}
+	return ch
+}
+
+// checkReport verifies the signed report and that the deliverables match
+// their manifest.
+func (c *Checklist) checkReport(engagement string, cert *Certificate) Check {
+	ch := Check{Name: CheckReport}
+	if c.ReportDir == "" {
+		ch.Detail = "no report directory configured"
+		return ch
+	}
+	var sr report.SignedReport
+	if err := readJSON(filepath.Join(c.ReportDir, "report.json"), &sr); err != nil {
+		ch.Detail = err.Error()
+		return ch
+	}
+	if err := report.Verify(&sr); err != nil {
+		ch.Detail = "report.json: " + err.Error()
+		return ch
+	}
+	if sr.Report.Engagement != engagement {
+		ch.Detail = fmt.Sprintf("report is of engagement %s", sr.Report.Engagement)
+		return ch
+	}
+	signer := rte.Fingerprint(sr.PublicKey)
+	if c.ReportTrust != nil && !c.ReportTrust.Pinned(sr.PublicKey) {
+		ch.Detail = fmt.Sprintf("report signed by %s: %v", signer, rte.ErrUnpinnedKey)
+		return ch
+	}
+	var sm report.SignedManifest
+	if err := readJSON(filepath.Join(c.ReportDir, report.ManifestName), &sm); err != nil {
+		ch.Detail = err.Error()
+		return ch
+	}
+	if err := sm.Check(os.DirFS(c.ReportDir)); err != nil {
+		ch.Detail = "deliverables: " + err.Error()
+		return ch
+	}
+	if sm.Manifest.Engagement != engagement {
+		ch.Detail = fmt.Sprintf("deliverables manifest is of engagement %s", sm.Manifest.Engagement)
+		return ch
+	}
+	cert.ReportSigner = signer
+	ch.Passed, ch.Detail = true, fmt.Sprintf("report signed by %s, %d deliverables verified", signer, len(sm.Manifest.Files))
+	return ch
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
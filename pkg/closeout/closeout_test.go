@@ -0,0 +1,163 @@
+package closeout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/backup"
+	"github.com/codethor0/rte-a-reference/pkg/enroll"
+	"github.com/codethor0/rte-a-reference/pkg/report"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+type agents []enroll.Agent
+
+func (a agents) Agents(string) []enroll.Agent { return a }
+
+type ledger []string
+
+func (l ledger) Outstanding(context.Context, string) ([]string, error) { return l, nil }
+
+// fixture returns a checklist for eng-1 that passes: two finished tasks,
+// an archive of them, and signed report deliverables.
+func fixture(t *testing.T) (*Checklist, *rte.MemoryStore) {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	pub, priv, _ := rte.GenerateKeyPair()
+	signer, _ := rte.NewKeySigner(priv, pub)
+
+	store := rte.NewMemoryStore()
+	for _, id := range []string{"t-1", "t-2"} {
+		st, err := rte.SignTask(rte.Task{
+			ID: id, Engagement: "eng-1", Type: rte.TaskSimulateLogin, CreatedAt: time.Now().UTC(),
+			TTLSeconds: 600, Operator: "op-alice", ApprovedBy: "lead-bob", State: rte.StatePending,
+		}, priv, pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := rte.StateCompleted
+		if id == "t-2" {
+			state = rte.StateCancelled
+		}
+		if err := store.Put(ctx, rte.TaskRecord{Task: st, State: state}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := &backup.Bundler{Store: store, Signer: signer}
+	archive, err := os.Create(filepath.Join(dir, "eng-1.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.ExportBundle(ctx, "eng-1", archive); err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+
+	reportDir := filepath.Join(dir, "report")
+	r, err := report.Build(report.Input{Engagement: "eng-1"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, _ := report.Sign(r, priv, pub)
+	if _, err := report.WriteDeliverables(reportDir, sr, report.PDFOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := report.NewManifest("eng-1", "lead-bob", os.DirFS(reportDir), time.Now())
+	sm, _ := report.SignManifest(m, priv, pub)
+	data, _ := json.Marshal(sm)
+	if err := os.WriteFile(filepath.Join(reportDir, report.ManifestName), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Checklist{
+		Store: store, Agents: agents(nil), Cleanup: ledger(nil),
+		Bundler: b, ArchivePath: archive.Name(), ReportDir: reportDir,
+		Signer: signer, Audit: rte.NewAuditLog("ctl-1"),
+	}, store
+}
+
+func TestClose_IssuesCertificate(t *testing.T) {
+	c, _ := fixture(t)
+	sc, err := c.Close(context.Background(), "eng-1", "lead-bob")
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := VerifyCertificate(sc); err != nil {
+		t.Fatalf("VerifyCertificate: %v", err)
+	}
+	cert := sc.Certificate
+	if cert.Tasks[rte.StateCompleted] != 1 || cert.Tasks[rte.StateCancelled] != 1 || len(cert.ArchiveSHA256) != 64 || cert.ReportSigner == "" {
+		t.Errorf("certificate = %+v", cert)
+	}
+	if recs := c.Audit.Records("eng-1"); len(recs) != 1 || recs[0].Action != "engagement.close" {
+		t.Errorf("audit = %+v", recs)
+	}
+
+	sc.Certificate.ClosedBy = "op-mallory"
+	if err := VerifyCertificate(sc); err == nil {
+		t.Error("edited certificate verified")
+	}
+}
+
+func TestClose_Incomplete(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name, check, want string
+		setup             func(*Checklist, *rte.MemoryStore)
+	}{
+		{"open task", CheckTasks, "t-1 (executing)", func(c *Checklist, s *rte.MemoryStore) {
+			_ = s.Update(ctx, "eng-1", "t-1", func(r *rte.TaskRecord) error { r.State = rte.StateExecuting; return nil })
+		}},
+		{"agent", CheckAgents, "agent-01", func(c *Checklist, _ *rte.MemoryStore) {
+			c.Agents = agents{{Name: "agent-01", Engagement: "eng-1"}}
+		}},
+		{"cleanup", CheckCleanup, "svc account rte-test on dc01", func(c *Checklist, _ *rte.MemoryStore) {
+			c.Cleanup = ledger{"svc account rte-test on dc01"}
+		}},
+		{"no ledger", CheckCleanup, "no cleanup ledger", func(c *Checklist, _ *rte.MemoryStore) { c.Cleanup = nil }},
+		{"stale archive", CheckArchive, "archive holds 2 tasks, store 3", func(c *Checklist, s *rte.MemoryStore) {
+			rec, _ := s.Get(ctx, "eng-1", "t-1")
+			st := *rec.Task
+			st.Task.ID = "t-3"
+			_ = s.Put(ctx, rte.TaskRecord{Task: &st, State: rte.StateFailed})
+		}},
+		{"altered archive", CheckArchive, "gzip", func(c *Checklist, _ *rte.MemoryStore) {
+			_ = os.WriteFile(c.ArchivePath, []byte("not a bundle"), 0o644)
+		}},
+		{"altered report", CheckReport, "report.md: altered", func(c *Checklist, _ *rte.MemoryStore) {
+			_ = os.WriteFile(filepath.Join(c.ReportDir, "report.md"), []byte("# edited\n"), 0o644)
+		}},
+		{"untrusted report", CheckReport, rte.ErrUnpinnedKey.Error(), func(c *Checklist, _ *rte.MemoryStore) {
+			c.ReportTrust, _ = rte.NewKeyPins()
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, store := fixture(t)
+			tc.setup(c, store)
+			_, err := c.Close(ctx, "eng-1", "lead-bob")
+			var ie *IncompleteError
+			if !errors.As(err, &ie) {
+				t.Fatalf("Close = %v, want *IncompleteError", err)
+			}
+			for _, ch := range ie.Checks {
+				if ch.Passed == (ch.Name == tc.check) {
+					t.Errorf("check %s passed=%t: %s", ch.Name, ch.Passed, ch.Detail)
+				}
+				if ch.Name == tc.check && !strings.Contains(ch.Detail, tc.want) {
+					t.Errorf("check %s detail = %q, want %q", ch.Name, ch.Detail, tc.want)
+				}
+			}
+			if n := len(c.Audit.Records("eng-1")); n != 0 {
+				t.Errorf("incomplete close-out audited %d records", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package deconflict serves the SOC-facing deconfliction API: responders ask
+// whether a target was touched by the red team at a given time and receive a
+// signed yes/no attestation they can attach to the incident ticket.
+package deconflict
+
+import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+const defaultMaxTolerance = time.Hour
+
+// Attestation answers one deconfliction query.
+type Attestation struct {
+	Target    string        `json:"target"`
+	At        time.Time     `json:"at"`
+	Tolerance time.Duration `json:"tolerance_ns"`
+	// Touched is true when at least one red team activity matched.
+	Touched   bool           `json:"touched"`
+	Activity  []rte.Activity `json:"activity"`
+	Responder string         `json:"responder"`
+	IssuedAt  time.Time      `json:"issued_at"`
+}
+
+// SignedAttestation wraps an attestation with the controller's signature.
+type SignedAttestation struct {
+	Attestation Attestation `json:"attestation"`
+	PublicKey   []byte      `json:"public_key"`
+	Signature   []byte      `json:"signature"`
+}
+
+// Sign signs an attestation with the controller's key.
+func Sign(a Attestation, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedAttestation, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("marshal attestation: %w", err)
+	}
+	return &SignedAttestation{Attestation: a, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// Verify checks a signed attestation's signature.
+func Verify(sa *SignedAttestation) error {
+	if sa == nil {
+		return errors.New("signed attestation is nil")
+	}
+	if len(sa.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sa.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	payload, err := json.Marshal(sa.Attestation)
+	if err != nil {
+		return fmt.Errorf("marshal attestation: %w", err)
+	}
+	if !ed25519.Verify(sa.PublicKey, payload, sa.Signature) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// Authenticator identifies the responder making a request.
+type Authenticator func(r *http.Request) (responder string, err error)
+
+// BearerTokens authenticates requests by an Authorization bearer token,
+// mapping each token to its responder name.
+func BearerTokens(tokens map[string]string) Authenticator {
+	return func(r *http.Request) (string, error) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || got == "" {
+			return "", errors.New("bearer token required")
+		}
+		for token, responder := range tokens {
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				return responder, nil
+			}
+		}
+		return "", errors.New("unknown token")
+	}
+}
+
+// Server answers GET requests of the form
+//
+//	?target=10.4.2.7&at=2026-03-02T14:32:00Z&tolerance=5m
+//
+// with a SignedAttestation. at defaults to now and tolerance to zero.
+type Server struct {
+	Registry *rte.DeconflictionRegistry
+	// Authenticate is required; unauthenticated requests get 401.
+	Authenticate Authenticator
+	PrivateKey   ed25519.PrivateKey
+	PublicKey    ed25519.PublicKey
+	// MaxTolerance caps the tolerance a query may ask for, so responders
+	// cannot sweep the whole engagement in one request. Defaults to 1h.
+	MaxTolerance time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Authenticate == nil || s.Registry == nil {
+		http.Error(w, "deconfliction is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	responder, err := s.Authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="rte-a-deconfliction"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	a, err := s.query(r, now().UTC())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.Responder = responder
+	sa, err := Sign(a, s.PrivateKey, s.PublicKey)
+	if err != nil {
+		http.Error(w, "attestation signing failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(sa)
+}
+
+func (s *Server) query(r *http.Request, now time.Time) (Attestation, error) {
+	q := r.URL.Query()
+	a := Attestation{Target: q.Get("target"), At: now, IssuedAt: now}
+	if v := q.Get("at"); v != "" {
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return Attestation{}, fmt.Errorf("at: %w", err)
+		}
+		a.At = at.UTC()
+	}
+	if v := q.Get("tolerance"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Attestation{}, fmt.Errorf("tolerance: %w", err)
+		}
+		max := s.MaxTolerance
+		if max <= 0 {
+			max = defaultMaxTolerance
+		}
+		if d > max {
+			return Attestation{}, fmt.Errorf("tolerance exceeds %s", max)
+		}
+		a.Tolerance = d
+	}
+	activity, err := s.Registry.Touched(a.Target, a.At, a.Tolerance)
+	if err != nil {
+		return Attestation{}, err
+	}
+	a.Touched = len(activity) > 0
+	a.Activity = append([]rte.Activity{}, activity...)
+	return a, nil
+}
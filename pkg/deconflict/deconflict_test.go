@@ -0,0 +1,91 @@
+package deconflict
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func testServer(t *testing.T) (*Server, time.Time) {
+	t.Helper()
+	start := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	reg := rte.NewDeconflictionRegistry()
+	reg.Begin(rte.Task{
+		ID: "task-001", Engagement: "eng-2026-q1", Type: rte.TaskSimulateLogin,
+		CreatedAt: start, TTLSeconds: 600, Params: map[string]string{"target": "10.4.2.7:22"},
+	}, start)
+	pub, priv, err := rte.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("generate key pair: %v", err)
+	}
+	return &Server{
+		Registry:     reg,
+		Authenticate: BearerTokens(map[string]string{"soc-token": "soc-carol"}),
+		PrivateKey:   priv,
+		PublicKey:    pub,
+		Now:          func() time.Time { return start.Add(time.Hour) },
+	}, start
+}
+
+func get(s *Server, query, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/deconflict?"+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_Attestation(t *testing.T) {
+	s, _ := testServer(t)
+	for query, touched := range map[string]bool{
+		"target=10.4.2.7&at=2026-03-02T14:32:00Z":              true,
+		"target=10.4.2.8&at=2026-03-02T14:32:00Z":              false,
+		"target=10.4.2.7":                                      false,
+		"target=10.4.2.7&at=2026-03-02T14:45:00Z&tolerance=5m": true,
+	} {
+		rec := get(s, query, "soc-token")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, rec.Code, rec.Body)
+		}
+		var sa SignedAttestation
+		if err := json.Unmarshal(rec.Body.Bytes(), &sa); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if err := Verify(&sa); err != nil {
+			t.Fatalf("%s: Verify: %v", query, err)
+		}
+		if sa.Attestation.Touched != touched || sa.Attestation.Responder != "soc-carol" {
+			t.Errorf("%s: unexpected attestation %+v", query, sa.Attestation)
+		}
+		sa.Attestation.Touched = !sa.Attestation.Touched
+		if err := Verify(&sa); err == nil {
+			t.Errorf("%s: flipped answer still verifies", query)
+		}
+	}
+}
+
+func TestServer_Rejects(t *testing.T) {
+	s, _ := testServer(t)
+	if rec := get(s, "target=10.4.2.7", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d", rec.Code)
+	}
+	if rec := get(s, "target=10.4.2.7", "guess"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad token: status %d", rec.Code)
+	}
+	for _, q := range []string{"", "target=10.4.2.7&at=yesterday", "target=10.4.2.7&tolerance=48h"} {
+		if rec := get(s, q, "soc-token"); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status %d", q, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/deconflict", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d", rec.Code)
+	}
+}
@@ -0,0 +1,212 @@
+package engagement
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Outcome is what apply did with one change.
+type Outcome string
+
+const (
+	// OutcomeSubmitted means the task was queued under an existing approval.
+	OutcomeSubmitted Outcome = "submitted"
+	// OutcomeAwaitingApproval means a newly signed task was filed for an
+	// approver's countersignature.
+	OutcomeAwaitingApproval Outcome = "awaiting_approval"
+	// OutcomeCancelled means a task no longer declared was cancelled.
+	OutcomeCancelled Outcome = "cancelled"
+	// OutcomeFailed means the change could not be applied; see Err.
+	OutcomeFailed Outcome = "failed"
+)
+
+// Applied records the outcome of one change.
+type Applied struct {
+	Change  Change
+	Outcome Outcome
+	// Task is the task submitted or filed for approval.
+	Task *rte.SignedTask
+	// Reasons explains why a fresh approval was requested.
+	Reasons []string
+	Err     error
+}
+
+// highRiskTypes need a fresh approval on every apply, even when a
+// recorded approval is still valid.
+var highRiskTypes = map[rte.TaskType]bool{
+	rte.TaskSimulatePhish:           true,
+	rte.TaskSimulateCredentialSpray: true,
+	rte.TaskSimulateExfil:           true,
+}
+
+// DefaultHighRisk treats phishing, credential spraying, and exfiltration
+// simulations, and every standing task, as high risk.
+func DefaultHighRisk(s TaskSpec) bool {
+	return highRiskTypes[s.Type] || s.Standing
+}
+
+// Applier carries out a plan. Unchanged tasks with a valid recorded
+// approval are submitted as is; new, modified, and high-risk tasks are
+// signed afresh and filed for approval. Updated tasks that are still live
+// are cancelled first, since their declared content is no longer what the
+// repository authorizes.
+type Applier struct {
+	Controller Controller
+	// Signer is the operator's key, used for tasks needing a new approval.
+	Signer rte.Signer
+	// Lock holds recorded approvals; with no lock, none are re-used.
+	Lock *Lock
+	// HighRisk defaults to DefaultHighRisk.
+	HighRisk func(TaskSpec) bool
+	// Provenance, if set, is recorded on every task signed, naming the
+	// verified Git commit the definitions were applied from.
+	Provenance *rte.Provenance
+	// Timestamper, if set, timestamps every task signed, so its signing
+	// time is attested by a TSA rather than the operator's clock.
+	Timestamper rte.Timestamper
+	// RequestedBy names who cancels tasks, for the audit trail.
+	RequestedBy string
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Apply carries out every change in the plan and reports each outcome. It
+// keeps going after a failed change; the returned error joins them all.
+func (a *Applier) Apply(ctx context.Context, p Plan) ([]Applied, error) {
+	if a.Controller == nil {
+		return nil, errors.New("controller is required")
+	}
+	out := make([]Applied, 0, len(p.Changes))
+	var errs []error
+	for _, c := range p.Changes {
+		res := a.apply(ctx, c)
+		if res.Err != nil {
+			res.Outcome = OutcomeFailed
+			errs = append(errs, fmt.Errorf("%s %s/%s: %w", c.Action, c.Engagement, c.TaskID, res.Err))
+		}
+		out = append(out, res)
+	}
+	return out, errors.Join(errs...)
+}
+
+func (a *Applier) apply(ctx context.Context, c Change) Applied {
+	res := Applied{Change: c}
+	if c.Current != nil && !c.Current.Terminal() {
+		if res.Err = a.cancel(ctx, c.Current); res.Err != nil {
+			return res
+		}
+	}
+	if c.Action == ActionCancel {
+		res.Outcome = OutcomeCancelled
+		return res
+	}
+	highRisk := a.HighRisk
+	if highRisk == nil {
+		highRisk = DefaultHighRisk
+	}
+	reason := "no recorded approval"
+	if a.Lock != nil {
+		var st *rte.SignedTask
+		if st, reason = a.Lock.reusable(c.Engagement, *c.Spec); st != nil && !highRisk(*c.Spec) {
+			if res.Err = a.Controller.Submit(ctx, st); res.Err == nil {
+				res.Outcome, res.Task = OutcomeSubmitted, st
+			}
+			return res
+		}
+	}
+	if c.Action == ActionUpdate {
+		fields := make([]string, len(c.Diffs))
+		for i, d := range c.Diffs {
+			fields[i] = d.Field
+		}
+		res.Reasons = append(res.Reasons, "modified: "+strings.Join(fields, ", "))
+	} else {
+		res.Reasons = append(res.Reasons, reason)
+	}
+	if highRisk(*c.Spec) {
+		res.Reasons = append(res.Reasons, fmt.Sprintf("high-risk task type %s", c.Spec.Type))
+	}
+	st, err := a.sign(ctx, c)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if res.Err = a.Controller.RequestApproval(ctx, st, res.Reasons); res.Err == nil {
+		res.Outcome, res.Task = OutcomeAwaitingApproval, st
+	}
+	return res
+}
+
+// sign issues the declared task with a fresh cancel token.
+func (a *Applier) sign(ctx context.Context, c Change) (*rte.SignedTask, error) {
+	if a.Signer == nil {
+		return nil, errors.New("an operator signing key is required for tasks needing approval")
+	}
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	task := c.Spec.Task(c.Engagement, now())
+	task.CancelToken = hex.EncodeToString(token)
+	if a.Provenance != nil {
+		p := *a.Provenance
+		task.Provenance = &p
+	}
+	st, err := rte.SignTaskContext(ctx, task, a.Signer)
+	if err != nil || a.Timestamper == nil {
+		return st, err
+	}
+	if err := rte.TimestampTask(ctx, st, a.Timestamper); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (a *Applier) cancel(ctx context.Context, r *Remote) error {
+	return a.Controller.Cancel(ctx, rte.TaskCancel{
+		Engagement:  r.Task.Task.Engagement,
+		TaskID:      r.Task.Task.ID,
+		Token:       r.Task.Task.CancelToken,
+		RequestedBy: a.RequestedBy,
+	})
+}
+
+// WriteApplied renders apply outcomes, one line per change. A change the
+// controller rejected on policy is followed by each violation's rule,
+// source, and remediation.
+func WriteApplied(w io.Writer, results []Applied) error {
+	var b strings.Builder
+	counts := make(map[Outcome]int)
+	for _, r := range results {
+		counts[r.Outcome]++
+		c := r.Change
+		fmt.Fprintf(&b, "  %s %s/%s: %s", c.Action, c.Engagement, c.TaskID, r.Outcome)
+		switch {
+		case r.Err != nil:
+			fmt.Fprintf(&b, " (%v)", r.Err)
+		case len(r.Reasons) > 0:
+			fmt.Fprintf(&b, " (%s)", strings.Join(r.Reasons, "; "))
+		}
+		b.WriteByte('\n')
+		var pe *rte.PolicyError
+		if errors.As(r.Err, &pe) {
+			b.WriteString(pe.Explain())
+		}
+	}
+	fmt.Fprintf(&b, "\nApply: %d submitted, %d awaiting approval, %d cancelled, %d failed.\n",
+		counts[OutcomeSubmitted], counts[OutcomeAwaitingApproval], counts[OutcomeCancelled], counts[OutcomeFailed])
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,179 @@
+package engagement
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+type fakeController struct {
+	mapState
+	submitted []*rte.SignedTask
+	filed     map[string][]string
+	cancelled []rte.TaskCancel
+	failOn    string
+}
+
+func (f *fakeController) Submit(_ context.Context, st *rte.SignedTask) error {
+	if st.Task.ID == f.failOn {
+		return errors.New("controller unavailable")
+	}
+	f.submitted = append(f.submitted, st)
+	return nil
+}
+
+func (f *fakeController) RequestApproval(_ context.Context, st *rte.SignedTask, reasons []string) error {
+	if st.Task.ID == f.failOn {
+		return errors.New("controller unavailable")
+	}
+	if f.filed == nil {
+		f.filed = map[string][]string{}
+	}
+	f.filed[st.Task.ID] = reasons
+	return nil
+}
+
+func (f *fakeController) Cancel(_ context.Context, c rte.TaskCancel) error {
+	f.cancelled = append(f.cancelled, c)
+	return nil
+}
+
+// approved signs and countersigns a spec as a previous apply would have.
+func approved(t *testing.T, s TaskSpec) rte.SignedTask {
+	t.Helper()
+	pub, priv, _ := rte.GenerateKeyPair()
+	st, err := rte.SignTask(s.Task("eng-1", time.Now()), priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rte.Countersign(st, priv, pub); err != nil {
+		t.Fatal(err)
+	}
+	return *st
+}
+
+func TestApplier_Apply(t *testing.T) {
+	spec := func(id string, typ rte.TaskType) TaskSpec {
+		return TaskSpec{ID: id, Type: typ, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead"}
+	}
+	reuse := spec("reuse", rte.TaskInventory)
+	fresh := spec("fresh", rte.TaskInventory)
+	risky := spec("risky", rte.TaskSimulateExfil)
+	changed := spec("changed", rte.TaskInventory)
+	edited := spec("edited", rte.TaskInventory)
+	old := changed
+	old.TTLSeconds = 300
+	lockedEdit := edited
+	lockedEdit.Operator = "someone-else"
+
+	lock := &Lock{Tasks: map[string]rte.SignedTask{}}
+	lock.Record(approved(t, reuse), approved(t, risky), approved(t, lockedEdit))
+	gone := remote(spec("gone", rte.TaskInventory), rte.StateExecuting)
+	gone.Task.Task.CancelToken = "tok-gone"
+	ctl := &fakeController{mapState: mapState{"eng-1": {remote(old, rte.StateExecuting), gone}}}
+
+	files := []File{{Engagement: "eng-1", Tasks: []TaskSpec{reuse, fresh, risky, changed, edited}}}
+	p, err := Compute(context.Background(), files, ctl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, _ := rte.GenerateKeyPair()
+	signer, _ := rte.NewKeySigner(priv, pub)
+	prov := &rte.Provenance{Commit: "0f3c2a9", Branch: "main", Signer: "alice@example.com"}
+	a := &Applier{Controller: ctl, Signer: signer, Lock: lock, Provenance: prov, RequestedBy: "op"}
+	results, err := a.Apply(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("results = %+v", results)
+	}
+
+	if len(ctl.submitted) != 1 || ctl.submitted[0].Task.ID != "reuse" {
+		t.Fatalf("submitted = %+v; only the unchanged, approved task should reuse its approval", ctl.submitted)
+	}
+	wantReasons := map[string]string{
+		"fresh":   "no recorded approval",
+		"risky":   "high-risk task type simulate_exfil",
+		"changed": "modified: ttl_seconds",
+		"edited":  "modified: operator",
+	}
+	for id, want := range wantReasons {
+		if got := strings.Join(ctl.filed[id], "; "); !strings.Contains(got, want) {
+			t.Errorf("%s approval reasons = %q, want %q", id, got, want)
+		}
+	}
+	if len(ctl.cancelled) != 2 {
+		t.Fatalf("cancelled = %+v, want the replaced and the removed task", ctl.cancelled)
+	}
+	for _, c := range ctl.cancelled {
+		if c.TaskID == "gone" && (c.Token != "tok-gone" || c.RequestedBy != "op") {
+			t.Errorf("cancel = %+v", c)
+		}
+	}
+	for _, r := range results {
+		if r.Outcome == OutcomeAwaitingApproval {
+			if err := rte.VerifyTask(r.Task); err != nil || r.Task.Task.CancelToken == "" {
+				t.Errorf("%s: newly signed task invalid (%v) or has no cancel token", r.Change.TaskID, err)
+			}
+			if p := r.Task.Task.Provenance; p == nil || *p != *prov {
+				t.Errorf("%s: provenance = %+v, want %+v", r.Change.TaskID, p, prov)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteApplied(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Apply: 1 submitted, 4 awaiting approval, 1 cancelled, 0 failed.") {
+		t.Fatalf("output:\n%s", buf.String())
+	}
+}
+
+func TestApplier_ApplyContinuesAfterFailure(t *testing.T) {
+	a1 := TaskSpec{ID: "a1", Type: rte.TaskInventory, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead"}
+	a2 := a1
+	a2.ID = "a2"
+	ctl := &fakeController{mapState: mapState{}, failOn: "a1"}
+	pub, priv, _ := rte.GenerateKeyPair()
+	signer, _ := rte.NewKeySigner(priv, pub)
+	p, _ := Compute(context.Background(), []File{{Engagement: "eng-1", Tasks: []TaskSpec{a1, a2}}}, ctl)
+
+	results, err := (&Applier{Controller: ctl, Signer: signer}).Apply(context.Background(), p)
+	if err == nil || !strings.Contains(err.Error(), "eng-1/a1") {
+		t.Fatalf("err = %v", err)
+	}
+	if results[0].Outcome != OutcomeFailed || results[1].Outcome != OutcomeAwaitingApproval {
+		t.Fatalf("outcomes = %s, %s", results[0].Outcome, results[1].Outcome)
+	}
+}
+
+type failingTSA struct{ calls int }
+
+func (f *failingTSA) Timestamp(context.Context, []byte) ([]byte, error) {
+	f.calls++
+	return nil, errors.New("TSA unreachable")
+}
+
+func TestApplier_ApplyTimestampFailureBlocksFiling(t *testing.T) {
+	a1 := TaskSpec{ID: "a1", Type: rte.TaskInventory, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead"}
+	ctl := &fakeController{mapState: mapState{}}
+	pub, priv, _ := rte.GenerateKeyPair()
+	signer, _ := rte.NewKeySigner(priv, pub)
+	p, _ := Compute(context.Background(), []File{{Engagement: "eng-1", Tasks: []TaskSpec{a1}}}, ctl)
+
+	tsa := &failingTSA{}
+	results, err := (&Applier{Controller: ctl, Signer: signer, Timestamper: tsa}).Apply(context.Background(), p)
+	if err == nil || !strings.Contains(err.Error(), "TSA unreachable") {
+		t.Fatalf("err = %v", err)
+	}
+	if tsa.calls != 1 || results[0].Outcome != OutcomeFailed || len(ctl.filed) != 0 {
+		t.Fatalf("calls = %d, outcome = %s, filed = %v", tsa.calls, results[0].Outcome, ctl.filed)
+	}
+}
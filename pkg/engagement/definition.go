@@ -0,0 +1,325 @@
+// Package engagement keeps engagement-as-code repositories authoritative.
+// Engagements, their tasks, task templates, and param policies are
+// declared in JSON or YAML files, so they can be reviewed in pull requests
+// before anything is signed; Compute diffs
+// those definitions against the controller's task state and produces a
+// create/update/cancel plan for review before anything is applied.
+package engagement
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/miniyaml"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// File is one engagement definition file.
+type File struct {
+	Engagement string `json:"engagement"`
+	// Phase is the engagement's current phase. Every file of an
+	// engagement that sets it must agree; see Phases.
+	Phase rte.Phase `json:"phase,omitempty"`
+	// Metadata brands and marks the engagement's deliverables. A relative
+	// logo path is resolved against the file's directory.
+	Metadata *rte.EngagementMetadata `json:"metadata,omitempty"`
+	// TargetLimits throttle the engagement's tasks against each system
+	// they target; see TargetLimits.
+	TargetLimits []rte.TargetLimit `json:"target_limits,omitempty"`
+	// ImpactBudget caps the impact score the engagement's tasks add up
+	// to per hour and day. Every file of an engagement that sets it must
+	// agree; see ImpactBudgets.
+	ImpactBudget *rte.ImpactBudget `json:"impact_budget,omitempty"`
+	Tasks        []TaskSpec        `json:"tasks"`
+	// Templates are shared by the tasks of every file; see Template.
+	Templates []Template `json:"templates,omitempty"`
+	// ParamRules are who may set which task params; see ParamRules.
+	ParamRules []rte.ParamRule `json:"param_rules,omitempty"`
+	// Path is the file the definition was loaded from.
+	Path string `json:"-"`
+}
+
+// TaskSpec declares a task's content. Lifecycle fields (created_at, state,
+// cancel_token) belong to the controller and are not declared.
+type TaskSpec struct {
+	ID                 string                  `json:"id"`
+	Type               rte.TaskType            `json:"type"`
+	TTLSeconds         int                     `json:"ttl_seconds"`
+	Operator           string                  `json:"operator"`
+	ApprovedBy         string                  `json:"approved_by"`
+	Priority           int                     `json:"priority,omitempty"`
+	Params             map[string]string       `json:"params,omitempty"`
+	Techniques         []string                `json:"techniques,omitempty"`
+	ExpectedDetections []rte.ExpectedDetection `json:"expected_detections,omitempty"`
+	Classification     rte.Classification      `json:"classification,omitempty"`
+	Selector           string                  `json:"selector,omitempty"`
+	DryRun             bool                    `json:"dry_run,omitempty"`
+	Phase              rte.Phase               `json:"phase,omitempty"`
+	// ApprovalSeconds, if set, keeps the approval valid this long after
+	// the task is created, with TTLSeconds bounding each run; see
+	// rte.Task.ApprovalExpiresAt.
+	ApprovalSeconds int `json:"approval_seconds,omitempty"`
+	// Standing declares a standing authorization; see rte.Task.Standing.
+	Standing bool `json:"standing,omitempty"`
+	// Template, if set, names the Template the spec starts from, and Vars
+	// are the values its params substitute. Load fills the template's
+	// fields in.
+	Template string            `json:"template,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty"`
+}
+
+// Task returns the pending task the spec declares, created at now.
+func (s TaskSpec) Task(engagement string, now time.Time) rte.Task {
+	t := rte.Task{
+		ID:                 s.ID,
+		Engagement:         engagement,
+		Type:               s.Type,
+		CreatedAt:          now.UTC(),
+		TTLSeconds:         s.TTLSeconds,
+		Operator:           s.Operator,
+		ApprovedBy:         s.ApprovedBy,
+		State:              rte.StatePending,
+		Priority:           s.Priority,
+		Techniques:         append([]string(nil), s.Techniques...),
+		ExpectedDetections: append([]rte.ExpectedDetection(nil), s.ExpectedDetections...),
+		Classification:     s.Classification,
+		Selector:           s.Selector,
+		DryRun:             s.DryRun,
+		Phase:              s.Phase,
+		Standing:           s.Standing,
+	}
+	if s.ApprovalSeconds > 0 {
+		a := t.CreatedAt.Add(time.Duration(s.ApprovalSeconds) * time.Second)
+		t.ApprovalExpiresAt = &a
+	}
+	if len(s.Params) > 0 {
+		t.Params = make(map[string]string, len(s.Params))
+		for k, v := range s.Params {
+			t.Params[k] = v
+		}
+	}
+	return t
+}
+
+// SpecOf returns the declarable content of a task, the inverse of
+// TaskSpec.Task.
+func SpecOf(t rte.Task) TaskSpec {
+	s := TaskSpec{
+		ID:                 t.ID,
+		Type:               t.Type,
+		TTLSeconds:         t.TTLSeconds,
+		Operator:           t.Operator,
+		ApprovedBy:         t.ApprovedBy,
+		Priority:           t.Priority,
+		Params:             t.Params,
+		Techniques:         t.Techniques,
+		ExpectedDetections: t.ExpectedDetections,
+		Classification:     t.Classification,
+		Selector:           t.Selector,
+		DryRun:             t.DryRun,
+		Phase:              t.Phase,
+		Standing:           t.Standing,
+	}
+	if a := t.ApprovalExpiresAt; a != nil {
+		s.ApprovalSeconds = int(a.Sub(t.CreatedAt) / time.Second)
+	}
+	return s
+}
+
+// Load reads every *.json, *.yaml, and *.yml file in dir except the lock,
+// in name order. Unknown fields are refused so typos do not silently drop
+// settings. Templates are filled into the tasks that name them, after
+// which each task must pass rte.Task.Validate, and task IDs must be unique
+// within an engagement.
+func Load(dir string) ([]File, error) {
+	var paths []string
+	for _, pattern := range []string{"*.json", "*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	paths = slices.DeleteFunc(paths, func(p string) bool { return filepath.Base(p) == LockName })
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no engagement definitions in %s", dir)
+	}
+	files := make([]File, 0, len(paths))
+	templates := make(map[string]Template)
+	templatePaths := make(map[string]string)
+	phases := make(map[string]rte.Phase)
+	phasePaths := make(map[string]string)
+	budgets := make(map[string]rte.ImpactBudget)
+	budgetPaths := make(map[string]string)
+	var errs []error
+	for _, p := range paths {
+		f, err := loadFile(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, t := range f.Templates {
+			if prev, dup := templatePaths[t.Name]; dup {
+				errs = append(errs, fmt.Errorf("%s: template %s already defined in %s", p, t.Name, prev))
+				continue
+			}
+			templates[t.Name], templatePaths[t.Name] = t, p
+		}
+		if f.Phase != "" {
+			if prev, ok := phases[f.Engagement]; ok && prev != f.Phase {
+				errs = append(errs, fmt.Errorf("%s: %s is in phase %s, but %s says %s", p, f.Engagement, f.Phase, phasePaths[f.Engagement], prev))
+			}
+			phases[f.Engagement], phasePaths[f.Engagement] = f.Phase, p
+		}
+		if b := f.ImpactBudget; b != nil {
+			if prev, ok := budgets[f.Engagement]; ok && prev != *b {
+				errs = append(errs, fmt.Errorf("%s: impact_budget for %s differs from %s", p, f.Engagement, budgetPaths[f.Engagement]))
+			}
+			budgets[f.Engagement], budgetPaths[f.Engagement] = *b, p
+		}
+		files = append(files, f)
+	}
+	seen := make(map[string]string)
+	now := time.Now().UTC()
+	for fi := range files {
+		f := &files[fi]
+		p := f.Path
+		for i, s := range f.Tasks {
+			if s.Template != "" {
+				t, ok := templates[s.Template]
+				if !ok {
+					errs = append(errs, fmt.Errorf("%s: task %s: unknown template %q", p, s.ID, s.Template))
+					continue
+				}
+				inst, err := t.instantiate(s)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: task %s: %w", p, s.ID, err))
+					continue
+				}
+				s, f.Tasks[i] = inst, inst
+			}
+			key := f.Engagement + "/" + s.ID
+			if prev, dup := seen[key]; dup {
+				errs = append(errs, fmt.Errorf("%s: task %s already defined in %s", p, key, prev))
+				continue
+			}
+			seen[key] = p
+			task := s.Task(f.Engagement, now)
+			if err := task.Validate(now); err != nil {
+				errs = append(errs, fmt.Errorf("%s: task %s: %w", p, s.ID, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return files, nil
+}
+
+func loadFile(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, err
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		// YAML is checked as the JSON it converts to, so both formats
+		// refuse the same mistakes.
+		if data, err = miniyaml.JSON(data); err != nil {
+			return File{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f File
+	if err := dec.Decode(&f); err != nil {
+		return File{}, fmt.Errorf("%s: %w", path, err)
+	}
+	library := len(f.Tasks) == 0 && f.Metadata == nil && len(f.TargetLimits) == 0 && f.ImpactBudget == nil && f.Phase == ""
+	if f.Engagement == "" && !library {
+		return File{}, fmt.Errorf("%s: engagement is required", path)
+	}
+	if err := f.Phase.Validate(); err != nil {
+		return File{}, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, t := range f.Templates {
+		if err := t.validate(); err != nil {
+			return File{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := (&rte.ParamPolicy{Rules: f.ParamRules}).Validate(); err != nil {
+		return File{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if m := f.Metadata; m != nil {
+		if err := m.Validate(); err != nil {
+			return File{}, fmt.Errorf("%s: metadata: %w", path, err)
+		}
+		if m.Logo != "" && !filepath.IsAbs(m.Logo) {
+			m.Logo = filepath.Join(filepath.Dir(path), m.Logo)
+		}
+	}
+	for i, l := range f.TargetLimits {
+		if err := l.Validate(); err != nil {
+			return File{}, fmt.Errorf("%s: target_limits[%d]: %w", path, i, err)
+		}
+	}
+	if b := f.ImpactBudget; b != nil {
+		if err := b.Validate(); err != nil {
+			return File{}, fmt.Errorf("%s: impact_budget: %w", path, err)
+		}
+	}
+	f.Path = path
+	return f, nil
+}
+
+// ParamRules collects every file's param rules, for an rte.ParamPolicy.
+func ParamRules(files []File) []rte.ParamRule {
+	var out []rte.ParamRule
+	for _, f := range files {
+		out = append(out, f.ParamRules...)
+	}
+	return out
+}
+
+// Phases returns each engagement's current phase, for an rte.PhasePolicy.
+// Engagements no file sets a phase for are left out.
+func Phases(files []File) map[string]rte.Phase {
+	out := make(map[string]rte.Phase)
+	for _, f := range files {
+		if f.Phase != "" {
+			out[f.Engagement] = f.Phase
+		}
+	}
+	return out
+}
+
+// TargetLimits collects the files' target limits by engagement, for an
+// rte.TargetLimiter. Limits from several files of one engagement add up.
+func TargetLimits(files []File) map[string][]rte.TargetLimit {
+	out := make(map[string][]rte.TargetLimit)
+	for _, f := range files {
+		if len(f.TargetLimits) > 0 {
+			out[f.Engagement] = append(out[f.Engagement], f.TargetLimits...)
+		}
+	}
+	return out
+}
+
+// ImpactBudgets returns each engagement's impact budget, for an
+// rte.ImpactLimiter. Engagements no file sets a budget for are left out.
+func ImpactBudgets(files []File) map[string]rte.ImpactBudget {
+	out := make(map[string]rte.ImpactBudget)
+	for _, f := range files {
+		if f.ImpactBudget != nil {
+			out[f.Engagement] = *f.ImpactBudget
+		}
+	}
+	return out
+}
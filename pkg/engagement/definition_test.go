@@ -0,0 +1,219 @@
+package engagement
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+const q1Def = `{
+  "engagement": "eng-2026-q1",
+  "tasks": [
+    {"id": "inv-dc", "type": "inventory", "ttl_seconds": 600, "operator": "op-alice",
+     "approved_by": "lead-bob", "params": {"target": "10.0.0.0/24"}},
+    {"id": "beacon-1", "type": "simulate_beacon", "ttl_seconds": 900, "operator": "op-alice",
+     "approved_by": "lead-bob", "techniques": ["T1071.001"]}
+  ]
+}`
+
+func writeDefs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	files, err := Load(writeDefs(t, map[string]string{"q1.json": q1Def, "README.md": "ignored"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Engagement != "eng-2026-q1" || len(files[0].Tasks) != 2 {
+		t.Fatalf("files = %+v", files)
+	}
+	task := files[0].Tasks[0].Task("eng-2026-q1", time.Now())
+	if task.State != rte.StatePending || task.Params["target"] != "10.0.0.0/24" {
+		t.Fatalf("task = %+v", task)
+	}
+}
+
+func TestLoad_Metadata(t *testing.T) {
+	def := `{"engagement": "eng-2026-q1", "tasks": [],
+  "metadata": {"client_name": "ACME", "logo": "acme.png", "classification": "CONFIDENTIAL"}}`
+	dir := writeDefs(t, map[string]string{"q1.json": def})
+	files, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := files[0].Metadata
+	if m == nil || m.ClientName != "ACME" || m.Logo != filepath.Join(dir, "acme.png") {
+		t.Fatalf("metadata = %+v", m)
+	}
+	bad := `{"engagement": "e", "tasks": [], "metadata": {"classification": "SECRET", "distribution": "ACME\nonly"}}`
+	if _, err := Load(writeDefs(t, map[string]string{"a.json": bad})); err == nil ||
+		!strings.Contains(err.Error(), "one line") || !strings.Contains(err.Error(), "unknown classification") {
+		t.Fatalf("bad markings: got %v", err)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Fatal("expected error for empty directory")
+	}
+	if _, err := Load(writeDefs(t, map[string]string{"a.json": `{"engagement": "e", "tasks": [], "owner": "x"}`})); err == nil {
+		t.Fatal("expected unknown field to be refused")
+	}
+	dup := map[string]string{"a.json": q1Def, "b.json": q1Def}
+	if _, err := Load(writeDefs(t, dup)); err == nil || !strings.Contains(err.Error(), "already defined") {
+		t.Fatalf("duplicate task: got %v", err)
+	}
+	bad := `{"engagement": "e", "tasks": [{"id": "x", "type": "malware", "ttl_seconds": 60, "approved_by": "b"}]}`
+	err := func() error { _, err := Load(writeDefs(t, map[string]string{"a.json": bad})); return err }()
+	if !errors.Is(err, rte.ErrUnsupportedType) || !errors.Is(err, rte.ErrMissingOperator) {
+		t.Fatalf("invalid task: got %v", err)
+	}
+}
+
+func TestLoad_TargetLimits(t *testing.T) {
+	def := `{"engagement": "eng-2026-q1", "tasks": [],
+  "target_limits": [{"target": "10.0.0.0/24", "max_concurrent": 1}, {"min_interval_seconds": 30}]}`
+	files, err := Load(writeDefs(t, map[string]string{"q1.json": def, "q1-more.json": `{"engagement": "eng-2026-q1", "tasks": [],
+  "target_limits": [{"target": "ops@acme.example", "min_interval_seconds": 300}]}`}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := TargetLimits(files)["eng-2026-q1"]; len(got) != 3 || got[1].MaxConcurrent != 1 {
+		t.Fatalf("limits = %+v", got)
+	}
+	bad := `{"engagement": "e", "tasks": [], "target_limits": [{"target": "10.0.0.0/24"}]}`
+	if _, err := Load(writeDefs(t, map[string]string{"a.json": bad})); err == nil || !strings.Contains(err.Error(), "target_limits[0]") {
+		t.Fatalf("empty limit: got %v", err)
+	}
+}
+
+func TestLoad_Phase(t *testing.T) {
+	def := `{"engagement": "eng-2026-q1", "phase": "persistence", "tasks": [
+    {"id": "beacon-2", "type": "simulate_beacon", "ttl_seconds": 900, "operator": "op-alice",
+     "approved_by": "lead-bob", "phase": "persistence"}]}`
+	files, err := Load(writeDefs(t, map[string]string{"q1.json": def, "q1-more.json": q1Def}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Phases(files)["eng-2026-q1"]; got != rte.PhasePersistence {
+		t.Fatalf("phase = %q", got)
+	}
+	other := `{"engagement": "eng-2026-q1", "phase": "cleanup", "tasks": []}`
+	if _, err := Load(writeDefs(t, map[string]string{"q1.json": def, "q1-more.json": other})); err == nil || !strings.Contains(err.Error(), "in phase") {
+		t.Fatalf("conflicting phases: got %v", err)
+	}
+	bad := `{"engagement": "e", "phase": "exploitation", "tasks": []}`
+	if _, err := Load(writeDefs(t, map[string]string{"a.json": bad})); err == nil || !strings.Contains(err.Error(), "unknown phase") {
+		t.Fatalf("unknown phase: got %v", err)
+	}
+}
+
+func TestLoad_YAML(t *testing.T) {
+	library := `# Shared by every engagement.
+templates:
+  - name: dc-inventory
+    type: inventory
+    ttl_seconds: 600
+    operator: op-alice
+    approved_by: lead-bob
+    params:
+      target: 10.0.0.0/24
+      depth: "2"
+param_rules:
+  - key: depth
+    above: 3
+    roles: [lead]
+`
+	def := `engagement: eng-2026-q1
+tasks:
+  - id: inv-dc
+    template: dc-inventory
+    params:
+      target: 10.0.1.0/24
+  - id: beacon-1
+    type: simulate_beacon
+    ttl_seconds: 900
+    operator: op-alice
+    approved_by: lead-bob
+    techniques: [T1071.001]
+`
+	files, err := Load(writeDefs(t, map[string]string{"library.yml": library, "q1.yaml": def}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[1].Engagement != "eng-2026-q1" {
+		t.Fatalf("files = %+v", files)
+	}
+	inv := files[1].Tasks[0]
+	if inv.Type != rte.TaskInventory || inv.TTLSeconds != 600 || inv.Params["target"] != "10.0.1.0/24" || inv.Params["depth"] != "2" {
+		t.Fatalf("templated task = %+v", inv)
+	}
+	if rules := ParamRules(files); len(rules) != 1 || rules[0].Key != "depth" {
+		t.Fatalf("param rules = %+v", rules)
+	}
+	p, err := Compute(context.Background(), files, mapState{})
+	if err != nil || p.Count(ActionCreate) != 2 {
+		t.Fatalf("plan = %+v, %v", p, err)
+	}
+
+	for name, bad := range map[string]string{
+		"unknown template":   "engagement: e\ntasks:\n  - id: x\n    template: nope\n",
+		"yaml: line 4":       "engagement: e\ntasks:\n- id: x\n  type: [unclosed\n",
+		"unknown field":      "engagement: e\ntasks: []\nowner: x\n",
+		"belong to the task": "templates:\n  - name: t\n    id: x\n",
+		"invalid role":       "param_rules:\n  - key: depth\n    roles: [janitor]\n",
+	} {
+		if _, err := Load(writeDefs(t, map[string]string{"a.yaml": bad})); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
+
+func TestLoad_ImpactBudget(t *testing.T) {
+	def := `{"engagement": "eng-2026-q1", "tasks": [], "impact_budget": {"max_per_hour": 20, "max_per_day": 60}}`
+	files, err := Load(writeDefs(t, map[string]string{"q1.json": def, "q1-more.json": q1Def}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ImpactBudgets(files)["eng-2026-q1"]; got.MaxPerHour != 20 || got.MaxPerDay != 60 {
+		t.Fatalf("budget = %+v", got)
+	}
+	other := `{"engagement": "eng-2026-q1", "tasks": [], "impact_budget": {"max_per_day": 10}}`
+	if _, err := Load(writeDefs(t, map[string]string{"q1.json": def, "q1-more.json": other})); err == nil || !strings.Contains(err.Error(), "differs") {
+		t.Fatalf("conflicting budgets: got %v", err)
+	}
+	bad := `{"engagement": "e", "tasks": [], "impact_budget": {}}`
+	if _, err := Load(writeDefs(t, map[string]string{"a.json": bad})); err == nil || !strings.Contains(err.Error(), "impact_budget") {
+		t.Fatalf("empty budget: got %v", err)
+	}
+}
+
+func TestTaskSpec_ApprovalSeconds(t *testing.T) {
+	s := TaskSpec{ID: "spray-1", Type: rte.TaskSimulateCredentialSpray, TTLSeconds: 900,
+		Operator: "op-alice", ApprovedBy: "lead-bob", ApprovalSeconds: 72 * 3600}
+	now := time.Now()
+	task := s.Task("eng-2026-q1", now)
+	if task.ApprovalExpiresAt == nil || !task.ApprovalExpiresAt.Equal(now.UTC().Add(72*time.Hour)) {
+		t.Fatalf("approval_expires_at = %v", task.ApprovalExpiresAt)
+	}
+	if err := task.Validate(now); err != nil {
+		t.Fatal(err)
+	}
+	if got := SpecOf(task); got.ApprovalSeconds != s.ApprovalSeconds {
+		t.Fatalf("SpecOf: approval_seconds = %d", got.ApprovalSeconds)
+	}
+}
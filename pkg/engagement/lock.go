@@ -0,0 +1,107 @@
+package engagement
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// LockName is the approvals lock file kept beside the definitions.
+const LockName = "rte.lock.json"
+
+// Lock records countersigned tasks by engagement and task ID, so apply can
+// re-submit a task under its existing approval while the declared content
+// is unchanged and the signed task has not expired. It is committed to the
+// engagement repository alongside the definitions.
+type Lock struct {
+	Tasks map[string]rte.SignedTask `json:"tasks"`
+}
+
+func lockKey(engagement, id string) string { return engagement + "/" + id }
+
+// LoadLock reads a lock file; a missing file is an empty lock.
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Lock{Tasks: map[string]rte.SignedTask{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var l Lock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if l.Tasks == nil {
+		l.Tasks = map[string]rte.SignedTask{}
+	}
+	return &l, nil
+}
+
+// Save writes the lock file.
+func (l *Lock) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Record stores countersigned tasks, replacing earlier entries.
+func (l *Lock) Record(tasks ...rte.SignedTask) {
+	for _, st := range tasks {
+		if st.Approval == nil {
+			continue
+		}
+		l.Tasks[lockKey(st.Task.Engagement, st.Task.ID)] = st
+	}
+}
+
+// Approved returns the recorded countersigned task with the given ID.
+func (l *Lock) Approved(engagement, id string) (rte.SignedTask, bool) {
+	st, ok := l.Tasks[lockKey(engagement, id)]
+	return st, ok
+}
+
+// Prune drops entries that are no longer declared.
+func (l *Lock) Prune(files []File) {
+	declared := make(map[string]bool)
+	for _, f := range files {
+		for _, s := range f.Tasks {
+			declared[lockKey(f.Engagement, s.ID)] = true
+		}
+	}
+	for k := range l.Tasks {
+		if !declared[k] {
+			delete(l.Tasks, k)
+		}
+	}
+}
+
+// reusable returns the locked task for a spec if its approval can be
+// re-used: unchanged content that still verifies and has not expired.
+func (l *Lock) reusable(engagement string, s TaskSpec) (*rte.SignedTask, string) {
+	st, ok := l.Tasks[lockKey(engagement, s.ID)]
+	if !ok {
+		return nil, "no recorded approval"
+	}
+	if diffs := Diff(SpecOf(st.Task), s); len(diffs) > 0 {
+		fields := make([]string, len(diffs))
+		for i, d := range diffs {
+			fields[i] = d.Field
+		}
+		return nil, "modified: " + strings.Join(fields, ", ")
+	}
+	if err := rte.VerifyTask(&st); err != nil {
+		return nil, fmt.Sprintf("recorded task no longer verifies: %v", err)
+	}
+	if err := rte.VerifyApproval(&st); err != nil {
+		return nil, fmt.Sprintf("recorded approval no longer verifies: %v", err)
+	}
+	return &st, ""
+}
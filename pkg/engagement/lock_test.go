@@ -0,0 +1,45 @@
+package engagement
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestLock_SaveLoadPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockName)
+	l, err := LoadLock(path)
+	if err != nil || len(l.Tasks) != 0 {
+		t.Fatalf("missing lock: %v, %+v", err, l)
+	}
+	kept := TaskSpec{ID: "kept", Type: rte.TaskInventory, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead"}
+	dropped := kept
+	dropped.ID = "dropped"
+	unapproved := approved(t, kept)
+	unapproved.Task.ID, unapproved.Approval = "unapproved", nil
+	l.Record(approved(t, kept), approved(t, dropped), unapproved)
+	if len(l.Tasks) != 2 {
+		t.Fatalf("tasks = %d; unapproved tasks must not be recorded", len(l.Tasks))
+	}
+	l.Prune([]File{{Engagement: "eng-1", Tasks: []TaskSpec{kept}}})
+	if err := l.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	l, err = LoadLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Tasks) != 1 {
+		t.Fatalf("tasks after prune = %+v", l.Tasks)
+	}
+	if st, reason := l.reusable("eng-1", kept); st == nil {
+		t.Fatalf("kept task not reusable: %s", reason)
+	}
+	changed := kept
+	changed.TTLSeconds = 60
+	if _, reason := l.reusable("eng-1", changed); !strings.Contains(reason, "ttl_seconds") {
+		t.Fatalf("reason = %q", reason)
+	}
+}
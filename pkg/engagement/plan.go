@@ -0,0 +1,200 @@
+package engagement
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Action is what a plan does to one task.
+type Action string
+
+const (
+	// ActionCreate issues a task that is declared but not on the controller.
+	ActionCreate Action = "create"
+	// ActionUpdate re-issues a task whose declared content changed. Signed
+	// tasks are immutable, so an update cancels the old task, if it is still
+	// live, and issues a new one under the same ID.
+	ActionUpdate Action = "update"
+	// ActionCancel cancels a live task no longer declared.
+	ActionCancel Action = "cancel"
+)
+
+// FieldDiff is one changed field, rendered for display. It is the type
+// rte.Diff reports signed tasks' differences with.
+type FieldDiff = rte.FieldDiff
+
+// Change is one planned action.
+type Change struct {
+	Action     Action    `json:"action"`
+	Engagement string    `json:"engagement"`
+	TaskID     string    `json:"task_id"`
+	Spec       *TaskSpec `json:"spec,omitempty"`
+	Current    *Remote   `json:"current,omitempty"`
+	// Diffs lists changed fields for an update.
+	Diffs []FieldDiff `json:"diffs,omitempty"`
+}
+
+// Plan is the set of changes that brings the controller in line with the
+// definitions.
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Unchanged counts declared tasks that already match.
+	Unchanged int `json:"unchanged"`
+	// Approved holds the unchanged tasks the controller has countersigned,
+	// for recording in the approvals lock.
+	Approved []rte.SignedTask `json:"-"`
+}
+
+// Empty reports whether the plan has no changes.
+func (p Plan) Empty() bool { return len(p.Changes) == 0 }
+
+// Count returns the number of changes with the given action.
+func (p Plan) Count(a Action) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == a {
+			n++
+		}
+	}
+	return n
+}
+
+// Compute diffs each file's tasks against the controller state of its
+// engagement. Finished tasks that are no longer declared are left alone;
+// they are history, not drift.
+func Compute(ctx context.Context, files []File, state State) (Plan, error) {
+	var p Plan
+	for _, f := range files {
+		if f.Engagement == "" {
+			continue // a template or policy library
+		}
+		remote, err := state.Tasks(ctx, f.Engagement)
+		if err != nil {
+			return Plan{}, err
+		}
+		diffEngagement(&p, f, remote)
+	}
+	return p, nil
+}
+
+func diffEngagement(p *Plan, f File, remote []Remote) {
+	current := make(map[string]Remote, len(remote))
+	for _, r := range remote {
+		current[r.Task.Task.ID] = r
+	}
+	declared := make(map[string]bool, len(f.Tasks))
+	for i := range f.Tasks {
+		s := f.Tasks[i]
+		declared[s.ID] = true
+		r, ok := current[s.ID]
+		if !ok {
+			p.Changes = append(p.Changes, Change{Action: ActionCreate, Engagement: f.Engagement, TaskID: s.ID, Spec: &s})
+			continue
+		}
+		diffs := Diff(SpecOf(r.Task.Task), s)
+		if len(diffs) == 0 {
+			p.Unchanged++
+			if r.Task.Approval != nil {
+				p.Approved = append(p.Approved, r.Task)
+			}
+			continue
+		}
+		p.Changes = append(p.Changes, Change{
+			Action: ActionUpdate, Engagement: f.Engagement, TaskID: s.ID, Spec: &s, Current: &r, Diffs: diffs,
+		})
+	}
+	ids := make([]string, 0, len(current))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		r := current[id]
+		if declared[id] || r.Terminal() {
+			continue
+		}
+		p.Changes = append(p.Changes, Change{Action: ActionCancel, Engagement: f.Engagement, TaskID: id, Current: &r})
+	}
+}
+
+// Diff lists the fields that differ between two task specs.
+func Diff(from, to TaskSpec) []FieldDiff {
+	var diffs []FieldDiff
+	add := func(field, a, b string) {
+		if a != b {
+			diffs = append(diffs, FieldDiff{Field: field, From: a, To: b})
+		}
+	}
+	add("type", string(from.Type), string(to.Type))
+	add("ttl_seconds", strconv.Itoa(from.TTLSeconds), strconv.Itoa(to.TTLSeconds))
+	add("operator", from.Operator, to.Operator)
+	add("approved_by", from.ApprovedBy, to.ApprovedBy)
+	add("priority", strconv.Itoa(from.Priority), strconv.Itoa(to.Priority))
+	add("classification", string(from.Classification), string(to.Classification))
+	add("selector", from.Selector, to.Selector)
+	add("dry_run", strconv.FormatBool(from.DryRun), strconv.FormatBool(to.DryRun))
+	add("phase", string(from.Phase), string(to.Phase))
+	add("standing", strconv.FormatBool(from.Standing), strconv.FormatBool(to.Standing))
+	add("approval_seconds", strconv.Itoa(from.ApprovalSeconds), strconv.Itoa(to.ApprovalSeconds))
+	keys := make(map[string]bool)
+	for k := range from.Params {
+		keys[k] = true
+	}
+	for k := range to.Params {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		add("params."+k, from.Params[k], to.Params[k])
+	}
+	add("techniques", strings.Join(from.Techniques, ","), strings.Join(to.Techniques, ","))
+	add("expected_detections", jsonString(from.ExpectedDetections), jsonString(to.ExpectedDetections))
+	return diffs
+}
+
+func jsonString(v any) string {
+	b, _ := json.Marshal(v)
+	if string(b) == "null" {
+		return "[]"
+	}
+	return string(b)
+}
+
+// Write renders the plan for review, one line per change with field diffs
+// for updates, followed by a summary line.
+func (p Plan) Write(w io.Writer) error {
+	var b strings.Builder
+	if p.Empty() {
+		fmt.Fprintf(&b, "No changes. %d task(s) match their definitions.\n", p.Unchanged)
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate:
+			fmt.Fprintf(&b, "  + create %s/%s (%s, ttl %ds, operator %s)\n", c.Engagement, c.TaskID, c.Spec.Type, c.Spec.TTLSeconds, c.Spec.Operator)
+		case ActionUpdate:
+			fmt.Fprintf(&b, "  ~ update %s/%s (currently %s)\n", c.Engagement, c.TaskID, c.Current.State)
+			for _, d := range c.Diffs {
+				fmt.Fprintf(&b, "      %s: %q -> %q\n", d.Field, d.From, d.To)
+			}
+		case ActionCancel:
+			fmt.Fprintf(&b, "  - cancel %s/%s (currently %s)\n", c.Engagement, c.TaskID, c.Current.State)
+		}
+	}
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to cancel, %d unchanged.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionCancel), p.Unchanged)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,88 @@
+package engagement
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+type mapState map[string][]Remote
+
+func (m mapState) Tasks(_ context.Context, engagement string) ([]Remote, error) {
+	return m[engagement], nil
+}
+
+func remote(s TaskSpec, state rte.TaskState) Remote {
+	return Remote{Task: rte.SignedTask{Task: s.Task("eng-1", time.Now())}, State: state}
+}
+
+func TestCompute(t *testing.T) {
+	same := TaskSpec{ID: "same", Type: rte.TaskInventory, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead"}
+	changed := TaskSpec{ID: "changed", Type: rte.TaskInventory, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead",
+		Params: map[string]string{"target": "10.0.0.1"}}
+	fresh := TaskSpec{ID: "fresh", Type: rte.TaskSimulateBeacon, TTLSeconds: 300, Operator: "op", ApprovedBy: "lead"}
+	gone := TaskSpec{ID: "gone", Type: rte.TaskInventory, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead"}
+	done := TaskSpec{ID: "done", Type: rte.TaskInventory, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead"}
+
+	old := changed
+	old.TTLSeconds = 300
+	old.Params = map[string]string{"target": "10.0.0.2"}
+	state := mapState{"eng-1": {
+		remote(same, rte.StateExecuting), remote(old, rte.StatePending),
+		remote(gone, rte.StatePending), remote(done, rte.StateCompleted),
+	}}
+	files := []File{{Engagement: "eng-1", Tasks: []TaskSpec{same, changed, fresh}}}
+
+	p, err := Compute(context.Background(), files, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Unchanged != 1 || p.Count(ActionCreate) != 1 || p.Count(ActionUpdate) != 1 || p.Count(ActionCancel) != 1 {
+		t.Fatalf("plan = %+v", p)
+	}
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate:
+			if c.TaskID != "fresh" {
+				t.Errorf("create %s", c.TaskID)
+			}
+		case ActionUpdate:
+			if c.TaskID != "changed" || len(c.Diffs) != 2 {
+				t.Errorf("update %s diffs %+v", c.TaskID, c.Diffs)
+			}
+		case ActionCancel:
+			if c.TaskID != "gone" {
+				t.Errorf("cancel %s; finished tasks must not be cancelled", c.TaskID)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"+ create eng-1/fresh", "~ update eng-1/changed", `ttl_seconds: "300" -> "600"`,
+		`params.target: "10.0.0.2" -> "10.0.0.1"`, "- cancel eng-1/gone",
+		"Plan: 1 to create, 1 to update, 1 to cancel, 1 unchanged.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPlan_WriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (Plan{Unchanged: 3}).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "No changes.") {
+		t.Fatalf("output = %q", buf.String())
+	}
+}
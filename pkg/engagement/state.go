@@ -0,0 +1,199 @@
+package engagement
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// maxResponseBytes bounds controller API responses.
+const maxResponseBytes = 16 << 20
+
+// Remote is a task as the controller holds it: the signed task and its
+// current lifecycle state.
+type Remote struct {
+	Task  rte.SignedTask `json:"task"`
+	State rte.TaskState  `json:"state"`
+}
+
+// Terminal reports whether the task has finished and can no longer be
+// cancelled.
+func (r Remote) Terminal() bool {
+	switch r.State {
+	case rte.StateCompleted, rte.StateCancelled, rte.StateFailed:
+		return true
+	}
+	return false
+}
+
+// State reads the controller's tasks for an engagement.
+type State interface {
+	Tasks(ctx context.Context, engagement string) ([]Remote, error)
+}
+
+// Controller is State plus the writes apply makes.
+type Controller interface {
+	State
+	// Submit queues a signed, countersigned task for execution.
+	Submit(ctx context.Context, st *rte.SignedTask) error
+	// RequestApproval files an operator-signed task for an approver to
+	// countersign, with the reasons a fresh approval is needed.
+	RequestApproval(ctx context.Context, st *rte.SignedTask, reasons []string) error
+	// Cancel stops a queued or running task.
+	Cancel(ctx context.Context, c rte.TaskCancel) error
+}
+
+// HTTPController talks to the controller API:
+//
+//	GET  {BaseURL}/v1/engagements/{engagement}/tasks            -> {"tasks": [Remote...]}
+//	POST {BaseURL}/v1/engagements/{engagement}/tasks            <- SignedTask
+//	GET  {BaseURL}/v1/engagements/{engagement}/approvals        -> {"approvals": [ApprovalRequest...]}
+//	POST {BaseURL}/v1/engagements/{engagement}/approvals        <- ApprovalRequest
+//	POST {BaseURL}/v1/engagements/{engagement}/tasks/{id}/cancel <- TaskCancel
+//	POST {BaseURL}/v1/engagements/{engagement}/tasks/cancel      <- BulkCancelRequest -> BulkCancelResponse
+//
+// Token, if set, is sent as a bearer token. A rejection carrying denials,
+// as rte.WriteDenial writes, is returned wrapping an *rte.DenialError, and
+// one carrying only policy violations, as rte.WritePolicyError writes, an
+// *rte.PolicyError.
+type HTTPController struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+}
+
+// Tasks implements State.
+func (s *HTTPController) Tasks(ctx context.Context, engagement string) ([]Remote, error) {
+	var out struct {
+		Tasks []Remote `json:"tasks"`
+	}
+	if err := s.do(ctx, http.MethodGet, s.tasksURL(engagement), nil, &out); err != nil {
+		return nil, fmt.Errorf("list %s tasks: %w", engagement, err)
+	}
+	return out.Tasks, nil
+}
+
+// Submit implements Controller.
+func (s *HTTPController) Submit(ctx context.Context, st *rte.SignedTask) error {
+	if err := s.do(ctx, http.MethodPost, s.tasksURL(st.Task.Engagement), st, nil); err != nil {
+		return fmt.Errorf("submit %s: %w", st.Task.ID, err)
+	}
+	return nil
+}
+
+// ApprovalRequest is an operator-signed task filed for an approver to
+// countersign.
+type ApprovalRequest struct {
+	Task    rte.SignedTask `json:"task"`
+	Reasons []string       `json:"reasons,omitempty"`
+}
+
+// RequestApproval implements Controller.
+func (s *HTTPController) RequestApproval(ctx context.Context, st *rte.SignedTask, reasons []string) error {
+	u := s.engagementURL(st.Task.Engagement) + "/approvals"
+	if err := s.do(ctx, http.MethodPost, u, ApprovalRequest{Task: *st, Reasons: reasons}, nil); err != nil {
+		return fmt.Errorf("request approval of %s: %w", st.Task.ID, err)
+	}
+	return nil
+}
+
+// PendingApprovals lists the engagement's tasks awaiting countersignature.
+// Submitting a countersigned task settles its request.
+func (s *HTTPController) PendingApprovals(ctx context.Context, engagement string) ([]ApprovalRequest, error) {
+	var out struct {
+		Approvals []ApprovalRequest `json:"approvals"`
+	}
+	if err := s.do(ctx, http.MethodGet, s.engagementURL(engagement)+"/approvals", nil, &out); err != nil {
+		return nil, fmt.Errorf("list %s approvals: %w", engagement, err)
+	}
+	return out.Approvals, nil
+}
+
+// Cancel implements Controller.
+func (s *HTTPController) Cancel(ctx context.Context, c rte.TaskCancel) error {
+	u := s.tasksURL(c.Engagement) + "/" + url.PathEscape(c.TaskID) + "/cancel"
+	if err := s.do(ctx, http.MethodPost, u, c, nil); err != nil {
+		return fmt.Errorf("cancel %s: %w", c.TaskID, err)
+	}
+	return nil
+}
+
+// CancelWhere cancels every task f selects in one step on the controller
+// (see rte.BulkCancelHandler) and returns the IDs cancelled.
+func (s *HTTPController) CancelWhere(ctx context.Context, f rte.TaskFilter, requestedBy string) ([]string, error) {
+	var out rte.BulkCancelResponse
+	body := rte.BulkCancelRequest{Filter: f, RequestedBy: requestedBy}
+	if err := s.do(ctx, http.MethodPost, s.tasksURL(f.Engagement)+"/cancel", body, &out); err != nil {
+		return nil, fmt.Errorf("cancel %s tasks: %w", f.Engagement, err)
+	}
+	return out.Cancelled, nil
+}
+
+func (s *HTTPController) engagementURL(engagement string) string {
+	return strings.TrimRight(s.BaseURL, "/") + "/v1/engagements/" + url.PathEscape(engagement)
+}
+
+func (s *HTTPController) tasksURL(engagement string) string {
+	return s.engagementURL(engagement) + "/tasks"
+}
+
+// do makes one JSON API call, decoding a 2xx response into out if set.
+func (s *HTTPController) do(ctx context.Context, method, u string, in, out any) error {
+	if s.BaseURL == "" {
+		return errors.New("controller URL is required")
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var de rte.DenialError
+		if json.Unmarshal(msg, &de) == nil && len(de.Denials) > 0 {
+			return fmt.Errorf("controller returned HTTP %d: %w", resp.StatusCode, &de)
+		}
+		var pe rte.PolicyError
+		if json.Unmarshal(msg, &pe) == nil && len(pe.Violations) > 0 {
+			return fmt.Errorf("controller returned HTTP %d: %w", resp.StatusCode, &pe)
+		}
+		return fmt.Errorf("controller returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out != nil {
+		if err := json.Unmarshal(msg, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
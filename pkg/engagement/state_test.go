@@ -0,0 +1,93 @@
+package engagement
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestHTTPController_Tasks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v1/engagements/eng-1/tasks" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"tasks": []Remote{
+			{Task: rte.SignedTask{Task: rte.Task{ID: "t1", Engagement: "eng-1"}}, State: rte.StateExecuting},
+		}})
+	}))
+	defer srv.Close()
+
+	s := &HTTPController{BaseURL: srv.URL + "/", Token: "tok"}
+	tasks, err := s.Tasks(context.Background(), "eng-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Task.Task.ID != "t1" || tasks[0].State != rte.StateExecuting {
+		t.Fatalf("tasks = %+v", tasks)
+	}
+
+	s.Token = "wrong"
+	if _, err := s.Tasks(context.Background(), "eng-1"); err == nil {
+		t.Fatal("expected error on 401")
+	}
+}
+
+func TestHTTPController_PendingApprovals(t *testing.T) {
+	var filed []ApprovalRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/engagements/eng-1/approvals" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var req ApprovalRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			filed = append(filed, req)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"approvals": filed})
+	}))
+	defer srv.Close()
+
+	s := &HTTPController{BaseURL: srv.URL}
+	st := &rte.SignedTask{Task: rte.Task{ID: "t1", Engagement: "eng-1"}}
+	if err := s.RequestApproval(context.Background(), st, []string{"new task"}); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := s.PendingApprovals(context.Background(), "eng-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Task.Task.ID != "t1" || pending[0].Reasons[0] != "new task" {
+		t.Fatalf("pending = %+v", pending)
+	}
+}
+
+func TestHTTPController_Denied(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rte.WriteDenial(w, "t1", fmt.Errorf("%w: t1", rte.ErrUnpinnedKey))
+	}))
+	defer srv.Close()
+
+	s := &HTTPController{BaseURL: srv.URL}
+	err := s.Submit(context.Background(), &rte.SignedTask{Task: rte.Task{ID: "t1", Engagement: "eng-1"}})
+	var de *rte.DenialError
+	if !errors.As(err, &de) || de.Denials[0].Code != rte.DenialUnpinnedKey || de.Denials[0].Field != "public_key" {
+		t.Fatalf("Submit = %v", err)
+	}
+	var pe *rte.PolicyError
+	if !errors.As(err, &pe) || len(pe.Violations) != 1 {
+		t.Errorf("denial does not unwrap to a PolicyError: %v", err)
+	}
+}
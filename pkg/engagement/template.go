@@ -0,0 +1,248 @@
+package engagement
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Template is a reusable task spec. A task that names it starts from the
+// template and overrides whatever fields it sets itself; its params are
+// merged over the template's. Templates may be declared in any definition
+// file, including one that declares no engagement, and are shared by all
+// of them.
+//
+// Template params may substitute the task's vars, as in
+// "{{ .TargetSubnet }}", optionally piped through lower, upper, or trim.
+// Nothing else of text/template is allowed. Every var a param uses must be
+// declared in Variables, and if Scope is set, every target the instance
+// names (see rte.TaskTargets) must be in it, so one approved template can
+// only produce tasks against approved systems.
+type Template struct {
+	Name      string     `json:"name"`
+	Variables []Variable `json:"variables,omitempty"`
+	// Scope lists the addresses, CIDRs, and host patterns instances may
+	// target; see rte.InScope.
+	Scope []string `json:"scope,omitempty"`
+	TaskSpec
+}
+
+// Variable declares a template var.
+type Variable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Required vars must be set by every task; others default to Default.
+	Required bool   `json:"required,omitempty"`
+	Default  string `json:"default,omitempty"`
+}
+
+// templateFuncs are the only functions template params may call.
+var templateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// validate checks the template's own fields and that every param parses,
+// uses only declared vars, and calls only templateFuncs.
+func (t Template) validate() error {
+	if t.Name == "" {
+		return errors.New("template name is required")
+	}
+	if t.ID != "" || t.Template != "" || len(t.Vars) > 0 {
+		return fmt.Errorf("template %s: id, template, and vars belong to the task", t.Name)
+	}
+	declared := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		if v.Name == "" {
+			return fmt.Errorf("template %s: variable name is required", t.Name)
+		}
+		if declared[v.Name] {
+			return fmt.Errorf("template %s: variable %s declared twice", t.Name, v.Name)
+		}
+		declared[v.Name] = true
+	}
+	var errs []error
+	for _, k := range sortedKeys(t.Params) {
+		if _, err := parseParam(k, t.Params[k], declared); err != nil {
+			errs = append(errs, fmt.Errorf("template %s: params.%s: %w", t.Name, k, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// instantiate returns s with the template's fields filled in where s
+// leaves them unset and its params rendered with s's vars.
+func (t Template) instantiate(s TaskSpec) (TaskSpec, error) {
+	vars, err := t.bind(s.Vars)
+	if err != nil {
+		return TaskSpec{}, err
+	}
+	out := t.TaskSpec
+	out.ID, out.Template, out.Vars = s.ID, s.Template, s.Vars
+	if s.Type != "" {
+		out.Type = s.Type
+	}
+	if s.TTLSeconds != 0 {
+		out.TTLSeconds = s.TTLSeconds
+	}
+	if s.Operator != "" {
+		out.Operator = s.Operator
+	}
+	if s.ApprovedBy != "" {
+		out.ApprovedBy = s.ApprovedBy
+	}
+	if s.Priority != 0 {
+		out.Priority = s.Priority
+	}
+	if s.Classification != "" {
+		out.Classification = s.Classification
+	}
+	if s.Selector != "" {
+		out.Selector = s.Selector
+	}
+	out.DryRun = out.DryRun || s.DryRun
+	out.Standing = out.Standing || s.Standing
+	if s.Phase != "" {
+		out.Phase = s.Phase
+	}
+	if s.ApprovalSeconds != 0 {
+		out.ApprovalSeconds = s.ApprovalSeconds
+	}
+	if s.Techniques != nil {
+		out.Techniques = s.Techniques
+	}
+	if s.ExpectedDetections != nil {
+		out.ExpectedDetections = s.ExpectedDetections
+	}
+	out.Params = nil
+	if len(t.Params)+len(s.Params) > 0 {
+		out.Params = make(map[string]string, len(t.Params)+len(s.Params))
+		declared := make(map[string]bool, len(vars))
+		for k := range vars {
+			declared[k] = true
+		}
+		for k, text := range t.Params {
+			tmpl, err := parseParam(k, text, declared)
+			if err != nil {
+				return TaskSpec{}, fmt.Errorf("params.%s: %w", k, err)
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, vars); err != nil {
+				return TaskSpec{}, fmt.Errorf("params.%s: %w", k, err)
+			}
+			out.Params[k] = b.String()
+		}
+		for k, v := range s.Params {
+			out.Params[k] = v
+		}
+	}
+	if len(t.Scope) > 0 {
+		for _, target := range rte.TaskTargets(rte.Task{Params: out.Params}) {
+			if !rte.InScope(target, t.Scope) {
+				return TaskSpec{}, fmt.Errorf("target %s is outside template %s's scope (%s)", target, t.Name, strings.Join(t.Scope, ", "))
+			}
+		}
+	}
+	return out, nil
+}
+
+// bind resolves the template's variables from a task's vars, refusing
+// missing required vars and vars the template does not declare.
+func (t Template) bind(given map[string]string) (map[string]string, error) {
+	vars := make(map[string]string, len(t.Variables))
+	var errs []error
+	for _, v := range t.Variables {
+		val, ok := given[v.Name]
+		switch {
+		case ok:
+			vars[v.Name] = val
+		case v.Required:
+			errs = append(errs, fmt.Errorf("template %s requires var %s", t.Name, v.Name))
+		default:
+			vars[v.Name] = v.Default
+		}
+	}
+	for _, k := range sortedKeys(given) {
+		if !containsVar(t.Variables, k) {
+			errs = append(errs, fmt.Errorf("template %s has no var %s", t.Name, k))
+		}
+	}
+	return vars, errors.Join(errs...)
+}
+
+func containsVar(vars []Variable, name string) bool {
+	for _, v := range vars {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseParam parses a template param and checks that it only substitutes
+// declared vars through templateFuncs.
+func parseParam(name, text string, declared map[string]bool) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNode(tmpl.Tree.Root, declared); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func checkNode(n parse.Node, declared map[string]bool) error {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		for _, c := range n.Nodes {
+			if err := checkNode(c, declared); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.TextNode:
+		return nil
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return fmt.Errorf("%s: variables are not allowed", n)
+		}
+		for _, cmd := range n.Pipe.Cmds {
+			for _, arg := range cmd.Args {
+				switch a := arg.(type) {
+				case *parse.FieldNode:
+					if len(a.Ident) != 1 {
+						return fmt.Errorf("%s: only top-level vars may be used", n)
+					}
+					if !declared[a.Ident[0]] {
+						return fmt.Errorf("%s: var %s is not declared", n, a.Ident[0])
+					}
+				case *parse.IdentifierNode:
+					if _, ok := templateFuncs[a.Ident]; !ok {
+						return fmt.Errorf("%s: function %s is not allowed", n, a.Ident)
+					}
+				case *parse.StringNode:
+				default:
+					return fmt.Errorf("%s: only vars, strings, and lower, upper, and trim are allowed", n)
+				}
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: only {{ .Var }} substitutions are allowed", n)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,112 @@
+package engagement
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func subnetTemplate() Template {
+	return Template{
+		Name: "subnet-inventory",
+		Variables: []Variable{
+			{Name: "TargetSubnet", Required: true},
+			{Name: "Depth", Default: "1"},
+		},
+		Scope: []string{"10.0.0.0/16"},
+		TaskSpec: TaskSpec{
+			Type: rte.TaskInventory, TTLSeconds: 600, Operator: "op", ApprovedBy: "lead",
+			Params: map[string]string{"target": "{{ .TargetSubnet | trim }}", "depth": "{{ .Depth }}", "label": "inv-{{ lower .TargetSubnet }}"},
+		},
+	}
+}
+
+func TestTemplate_Instantiate(t *testing.T) {
+	tmpl := subnetTemplate()
+	if err := tmpl.validate(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := tmpl.instantiate(TaskSpec{ID: "inv-3", Template: tmpl.Name, Vars: map[string]string{"TargetSubnet": " 10.0.3.0/24 "}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != "inv-3" || s.Type != rte.TaskInventory || s.Params["target"] != "10.0.3.0/24" || s.Params["depth"] != "1" {
+		t.Fatalf("instance = %+v", s)
+	}
+	s, err = tmpl.instantiate(TaskSpec{ID: "inv-4", TTLSeconds: 300, Vars: map[string]string{"TargetSubnet": "10.0.4.0/24", "Depth": "3"},
+		Params: map[string]string{"label": "custom"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.TTLSeconds != 300 || s.Params["depth"] != "3" || s.Params["label"] != "custom" {
+		t.Fatalf("instance with overrides = %+v", s)
+	}
+
+	for want, spec := range map[string]TaskSpec{
+		"requires var TargetSubnet": {ID: "x"},
+		"has no var Subnet":         {ID: "x", Vars: map[string]string{"TargetSubnet": "10.0.1.0/24", "Subnet": "x"}},
+		"outside template":          {ID: "x", Vars: map[string]string{"TargetSubnet": "10.9.0.0/24"}},
+		"10.0.0.0/8 is outside":     {ID: "x", Vars: map[string]string{"TargetSubnet": "10.0.0.0/8"}},
+		"evil.example is outside":   {ID: "x", Vars: map[string]string{"TargetSubnet": "10.0.1.0/24"}, Params: map[string]string{"target": "evil.example"}},
+	} {
+		if _, err := tmpl.instantiate(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v", want, err)
+		}
+	}
+}
+
+func TestTemplate_ValidateRestrictsParams(t *testing.T) {
+	for want, param := range map[string]string{
+		"function printf is not allowed": `{{ printf "%s" .TargetSubnet }}`,
+		"var Other is not declared":      "{{ .Other }}",
+		"only {{ .Var }}":                "{{ if .TargetSubnet }}x{{ end }}",
+		"variables are not allowed":      "{{ $x := .TargetSubnet }}",
+		"only top-level vars":            "{{ .TargetSubnet.Len }}",
+		"unclosed action":                "{{ .TargetSubnet ",
+	} {
+		tmpl := subnetTemplate()
+		tmpl.Params = map[string]string{"target": param}
+		if err := tmpl.validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v", param, err)
+		}
+	}
+	tmpl := subnetTemplate()
+	tmpl.Vars = map[string]string{"TargetSubnet": "x"}
+	if err := tmpl.validate(); err == nil {
+		t.Error("template set vars")
+	}
+}
+
+func TestLoad_TemplateVars(t *testing.T) {
+	library := `templates:
+  - name: subnet-inventory
+    variables:
+      - name: TargetSubnet
+        required: true
+    scope: [10.0.0.0/16]
+    type: inventory
+    ttl_seconds: 600
+    operator: op
+    approved_by: lead
+    params:
+      target: "{{ .TargetSubnet }}"
+`
+	def := `engagement: e
+tasks:
+  - {id: inv-1, template: subnet-inventory, vars: {TargetSubnet: 10.0.1.0/24}}
+  - {id: inv-2, template: subnet-inventory, vars: {TargetSubnet: 10.0.2.0/24}}
+`
+	files, err := Load(writeDefs(t, map[string]string{"library.yaml": library, "e.yaml": def}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := files[0].Tasks[1].Params["target"]; got != "10.0.2.0/24" {
+		t.Fatalf("inv-2 target = %q", got)
+	}
+	bad := strings.Replace(def, "10.0.2.0/24", "192.168.0.0/24", 1)
+	if _, err := Load(writeDefs(t, map[string]string{"library.yaml": library, "e.yaml": bad})); err == nil ||
+		!strings.Contains(err.Error(), "task inv-2: target 192.168.0.0/24 is outside") {
+		t.Fatalf("out-of-scope instance: got %v", err)
+	}
+}
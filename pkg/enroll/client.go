@@ -0,0 +1,112 @@
+package enroll
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// NewCSR returns a DER certificate request for the agent named name,
+// signed by priv.
+func NewCSR(name string, priv ed25519.PrivateKey) ([]byte, error) {
+	if err := checkName("agent", name); err != nil {
+		return nil, err
+	}
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	return x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: name}}, priv)
+}
+
+// Client enrolls an agent with a controller's enrollment endpoint. The
+// endpoint authenticates the agent by its token, so HTTPClient only needs
+// to trust the controller's server certificate.
+type Client struct {
+	// URL is the enrollment endpoint, such as
+	// https://controller.example/v1/enroll.
+	URL        string
+	HTTPClient *http.Client
+}
+
+// Enroll requests a credential for the agent named name, whose key is
+// priv, spending token.
+func (c *Client) Enroll(ctx context.Context, token, name string, priv ed25519.PrivateKey) (*Credential, error) {
+	if c.URL == "" {
+		return nil, errors.New("enrollment URL is required")
+	}
+	csr, err := NewCSR(name, priv)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(Request{Token: token, CSR: csr})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("enroll: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, fmt.Errorf("enroll: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("enroll: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var cred Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return nil, fmt.Errorf("decode credential: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cred.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("parse credential: %w", err)
+	}
+	if pub, ok := leaf.PublicKey.(ed25519.PublicKey); !ok || !pub.Equal(priv.Public()) {
+		return nil, errors.New("credential does not certify the agent's key")
+	}
+	return &cred, nil
+}
+
+// TLSCertificate pairs the credential with the agent's key for use as a
+// TLS client certificate.
+func (c *Credential) TLSCertificate(priv ed25519.PrivateKey) (tls.Certificate, error) {
+	leaf, err := x509.ParseCertificate(c.Certificate)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parse credential: %w", err)
+	}
+	if pub, ok := leaf.PublicKey.(ed25519.PublicKey); !ok || !pub.Equal(priv.Public()) {
+		return tls.Certificate{}, errors.New("credential does not certify the key")
+	}
+	return tls.Certificate{Certificate: [][]byte{c.Certificate}, PrivateKey: priv, Leaf: leaf}, nil
+}
+
+// ClientTLSConfig returns a TLS config presenting the credential to
+// servers whose certificates chain to roots; nil roots means the system
+// pool.
+func (c *Credential) ClientTLSConfig(priv ed25519.PrivateKey, roots *x509.CertPool) (*tls.Config, error) {
+	cert, err := c.TLSCertificate(priv)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: roots, MinVersion: tls.VersionTLS13}, nil
+}
@@ -0,0 +1,439 @@
+// Package enroll gives agents an identity. An operator mints a one-time
+// enrollment token for an engagement; the agent generates an ed25519 key,
+// submits a certificate request with the token, and receives a client
+// certificate from the controller's enrollment authority. From then on the
+// agent presents that certificate over mutual TLS to fetch tasks, and signs
+// its results with the same key, so the controller knows which agent it is
+// talking to and which agent produced each result.
+package enroll
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Defaults for an Authority's zero-valued fields.
+const (
+	DefaultTokenTTL = time.Hour
+	DefaultValidity = 7 * 24 * time.Hour
+)
+
+// uriScheme is the scheme of the URI SANs naming an agent,
+// rte-a:agent:<engagement>:<name>, and each of its labels,
+// rte-a:label:<key>=<value>.
+const uriScheme = "rte-a"
+
+// Errors returned by Enroll and Verify.
+var (
+	ErrBadToken = errors.New("enrollment token is unknown, used, or expired")
+	ErrRevoked  = errors.New("agent credential revoked")
+	ErrUnknown  = errors.New("key is not an enrolled agent")
+)
+
+// Agent is an enrolled agent, as named by its credential.
+type Agent struct {
+	Name       string            `json:"name"`
+	Engagement string            `json:"engagement"`
+	Serial     string            `json:"serial"`
+	PublicKey  ed25519.PublicKey `json:"public_key"`
+	NotAfter   time.Time         `json:"not_after"`
+	// Labels are what the enrollment token granted; tasks target agents
+	// by them (see rte.Selector).
+	Labels rte.Labels `json:"labels,omitempty"`
+}
+
+// Request is what an agent submits to enroll: a token and a PKCS#10
+// certificate request signed by the agent's ed25519 key.
+type Request struct {
+	Token string `json:"token"`
+	CSR   []byte `json:"csr"`
+}
+
+// Credential is an issued agent certificate and the CA that issued it,
+// both DER encoded.
+type Credential struct {
+	Agent       Agent  `json:"agent"`
+	Certificate []byte `json:"certificate"`
+	CA          []byte `json:"ca"`
+}
+
+type pendingToken struct {
+	engagement string
+	agent      string
+	labels     rte.Labels
+	expires    time.Time
+}
+
+// Authority is the controller's enrollment CA. It mints enrollment tokens,
+// issues agent certificates against them, and verifies the certificates
+// and result signatures agents present afterwards. It is safe for
+// concurrent use; tokens, enrolled agents, and revocations live in memory.
+type Authority struct {
+	// Validity is how long an issued credential lasts; 0 means
+	// DefaultValidity. It never outlasts the CA certificate.
+	Validity time.Duration
+	// TokenTTL is how long an unused token stays valid; 0 means
+	// DefaultTokenTTL.
+	TokenTTL time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	cert *x509.Certificate
+	key  ed25519.PrivateKey
+	pool *x509.CertPool
+
+	mu      sync.Mutex
+	tokens  map[string]pendingToken // by SHA-256 of the token
+	agents  map[string]Agent        // by key fingerprint
+	revoked map[string]bool         // by serial
+}
+
+// NewCA returns a self-signed ed25519 CA certificate for an enrollment
+// authority, valid from now for validity.
+func NewCA(name string, now time.Time, validity time.Duration) (*x509.Certificate, ed25519.PrivateKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, priv, nil
+}
+
+// NewAuthority returns an authority issuing under the CA cert, whose key
+// is key.
+func NewAuthority(cert *x509.Certificate, key ed25519.PrivateKey) (*Authority, error) {
+	if cert == nil || !cert.IsCA {
+		return nil, errors.New("enrollment authority needs a CA certificate")
+	}
+	if pub, ok := cert.PublicKey.(ed25519.PublicKey); !ok || len(key) != ed25519.PrivateKeySize || !pub.Equal(key.Public()) {
+		return nil, errors.New("key does not match the CA certificate")
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &Authority{
+		cert: cert, key: key, pool: pool,
+		tokens: make(map[string]pendingToken), agents: make(map[string]Agent), revoked: make(map[string]bool),
+	}, nil
+}
+
+// CA returns the authority's certificate.
+func (a *Authority) CA() *x509.Certificate { return a.cert }
+
+// Pool returns a pool holding only the authority's certificate.
+func (a *Authority) Pool() *x509.CertPool { return a.pool }
+
+func (a *Authority) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// NewToken mints a one-time token enrolling one agent into engagement. If
+// agent is set, only an agent of that name may use it.
+func (a *Authority) NewToken(engagement, agent string) (string, error) {
+	return a.NewLabeledToken(engagement, agent, nil)
+}
+
+// NewLabeledToken is NewToken for an agent with labels. The labels are
+// certified in the credential it enrolls, so an agent cannot claim labels
+// the operator minting the token did not grant.
+func (a *Authority) NewLabeledToken(engagement, agent string, labels rte.Labels) (string, error) {
+	if err := checkName("engagement", engagement); err != nil {
+		return "", err
+	}
+	if err := labels.Validate(); err != nil {
+		return "", err
+	}
+	if agent != "" {
+		if err := checkName("agent", agent); err != nil {
+			return "", err
+		}
+	}
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b[:])
+	ttl := a.TokenTTL
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	for h, p := range a.tokens {
+		if !now.Before(p.expires) {
+			delete(a.tokens, h)
+		}
+	}
+	a.tokens[tokenHash(token)] = pendingToken{engagement: engagement, agent: agent, labels: maps.Clone(labels), expires: now.Add(ttl)}
+	return token, nil
+}
+
+// Enroll checks req's token and certificate request and issues the agent
+// its credential. The token is spent only when a credential is issued.
+func (a *Authority) Enroll(req Request) (*Credential, error) {
+	csr, err := x509.ParseCertificateRequest(req.CSR)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate request: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("certificate request: %w", err)
+	}
+	pub, ok := csr.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("certificate request key is not ed25519")
+	}
+	name := csr.Subject.CommonName
+	if err := checkName("agent", name); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h := tokenHash(req.Token)
+	tok, ok := a.tokens[h]
+	now := a.now()
+	if !ok || !now.Before(tok.expires) {
+		return nil, ErrBadToken
+	}
+	if tok.agent != "" && tok.agent != name {
+		return nil, fmt.Errorf("token enrolls agent %s, not %s", tok.agent, name)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	validity := a.Validity
+	if validity <= 0 {
+		validity = DefaultValidity
+	}
+	notAfter := now.Add(validity)
+	if notAfter.After(a.cert.NotAfter) {
+		notAfter = a.cert.NotAfter
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, OrganizationalUnit: []string{tok.engagement}},
+		URIs:         append([]*url.URL{agentURI(tok.engagement, name)}, labelURIs(tok.labels)...),
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, pub, a.key)
+	if err != nil {
+		return nil, fmt.Errorf("issue credential: %w", err)
+	}
+	delete(a.tokens, h)
+	agent := Agent{Name: name, Engagement: tok.engagement, Serial: serialString(serial), PublicKey: pub, NotAfter: notAfter.UTC(), Labels: tok.labels}
+	a.agents[rte.Fingerprint(pub)] = agent
+	return &Credential{Agent: agent, Certificate: der, CA: a.cert.Raw}, nil
+}
+
+// Verify checks that cert is a current agent credential from this
+// authority and returns the agent it names.
+func (a *Authority) Verify(cert *x509.Certificate) (Agent, error) {
+	if cert == nil {
+		return Agent{}, errors.New("no agent certificate")
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots: a.pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, CurrentTime: a.now(),
+	}); err != nil {
+		return Agent{}, fmt.Errorf("agent certificate: %w", err)
+	}
+	pub, ok := cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return Agent{}, errors.New("agent certificate key is not ed25519")
+	}
+	engagement, name, err := parseAgentURI(cert)
+	if err != nil {
+		return Agent{}, err
+	}
+	serial := serialString(cert.SerialNumber)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.revoked[serial] {
+		return Agent{}, fmt.Errorf("%w: %s", ErrRevoked, serial)
+	}
+	return Agent{Name: name, Engagement: engagement, Serial: serial, PublicKey: pub, NotAfter: cert.NotAfter.UTC(), Labels: parseLabelURIs(cert)}, nil
+}
+
+// VerifyResult checks sr's signature and that its key belongs to a
+// current, unrevoked agent of the result's engagement, which it returns.
+// Use it wherever results arrive, whatever the transport.
+func (a *Authority) VerifyResult(sr *rte.SignedResult) (Agent, error) {
+	if err := rte.VerifyResult(sr); err != nil {
+		return Agent{}, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	agent, ok := a.agents[rte.Fingerprint(sr.PublicKey)]
+	switch {
+	case !ok:
+		return Agent{}, ErrUnknown
+	case a.revoked[agent.Serial]:
+		return Agent{}, fmt.Errorf("%w: %s", ErrRevoked, agent.Serial)
+	case !a.now().Before(agent.NotAfter):
+		return Agent{}, fmt.Errorf("agent %s credential expired at %s", agent.Name, agent.NotAfter.Format(time.RFC3339))
+	case agent.Engagement != sr.Result.Engagement:
+		return Agent{}, fmt.Errorf("agent %s is enrolled in %s, not %s", agent.Name, agent.Engagement, sr.Result.Engagement)
+	}
+	return agent, nil
+}
+
+// Agents returns the enrolled agents of engagement, expired credentials
+// included and revoked ones not.
+func (a *Authority) Agents(engagement string) []Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []Agent
+	for _, ag := range a.agents {
+		if ag.Engagement == engagement && !a.revoked[ag.Serial] {
+			out = append(out, ag)
+		}
+	}
+	return out
+}
+
+// Match returns the enrolled, unrevoked agents of engagement whose labels
+// satisfy selector, for checking that some agent can run a task.
+func (a *Authority) Match(engagement, selector string) ([]Agent, error) {
+	sel, err := rte.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	var out []Agent
+	for _, ag := range a.Agents(engagement) {
+		if sel.Matches(ag.Labels) {
+			out = append(out, ag)
+		}
+	}
+	return out, nil
+}
+
+// Revoke revokes every credential issued to the named agent of
+// engagement, returning how many it revoked.
+func (a *Authority) Revoke(engagement, name string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, ag := range a.agents {
+		if ag.Engagement == engagement && ag.Name == name && !a.revoked[ag.Serial] {
+			a.revoked[ag.Serial] = true
+			n++
+		}
+	}
+	return n
+}
+
+func agentURI(engagement, name string) *url.URL {
+	return &url.URL{Scheme: uriScheme, Opaque: "agent:" + engagement + ":" + name}
+}
+
+func labelURIs(labels rte.Labels) []*url.URL {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*url.URL, len(keys))
+	for i, k := range keys {
+		out[i] = &url.URL{Scheme: uriScheme, Opaque: "label:" + k + "=" + labels[k]}
+	}
+	return out
+}
+
+func parseLabelURIs(cert *x509.Certificate) rte.Labels {
+	var labels rte.Labels
+	for _, u := range cert.URIs {
+		kv, ok := strings.CutPrefix(u.Opaque, "label:")
+		if u.Scheme != uriScheme || !ok {
+			continue
+		}
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			if labels == nil {
+				labels = make(rte.Labels)
+			}
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+func parseAgentURI(cert *x509.Certificate) (engagement, name string, err error) {
+	for _, u := range cert.URIs {
+		if u.Scheme != uriScheme {
+			continue
+		}
+		f := strings.Split(u.Opaque, ":")
+		if len(f) == 3 && f[0] == "agent" && f[1] != "" && f[2] != "" {
+			return f[1], f[2], nil
+		}
+	}
+	return "", "", fmt.Errorf("certificate %q does not name an agent", cert.Subject.CommonName)
+}
+
+// checkName keeps names to what fits a URI host or path segment.
+func checkName(kind, v string) error {
+	if v == "" || len(v) > 128 {
+		return fmt.Errorf("%s name must be 1 to 128 characters", kind)
+	}
+	for _, c := range v {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return fmt.Errorf("%s name %q may only contain letters, digits, '-', '_', and '.'", kind, v)
+		}
+	}
+	return nil
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func serialString(n *big.Int) string {
+	return hex.EncodeToString(n.Bytes())
+}
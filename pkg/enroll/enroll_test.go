@@ -0,0 +1,189 @@
+package enroll
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func newAuthority(t *testing.T) *Authority {
+	t.Helper()
+	cert, key, err := NewCA("rte-a enrollment", time.Now(), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAuthority(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func enroll(t *testing.T, a *Authority, engagement, name string) (*Credential, ed25519.PrivateKey) {
+	t.Helper()
+	token, err := a.NewToken(engagement, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, priv, _ := rte.GenerateKeyPair()
+	csr, err := NewCSR(name, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := a.Enroll(Request{Token: token, CSR: csr})
+	if err != nil {
+		t.Fatalf("Enroll: %v", err)
+	}
+	return cred, priv
+}
+
+func TestAuthority_Enroll(t *testing.T) {
+	a := newAuthority(t)
+	cred, priv := enroll(t, a, "eng-1", "agent-7")
+	if cred.Agent.Name != "agent-7" || cred.Agent.Engagement != "eng-1" || !cred.Agent.PublicKey.Equal(priv.Public()) {
+		t.Fatalf("agent %+v", cred.Agent)
+	}
+	leaf, err := x509.ParseCertificate(cred.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(leaf.NotAfter) > 24*time.Hour {
+		t.Errorf("credential outlasts its CA: %s", leaf.NotAfter)
+	}
+	ag, err := a.Verify(leaf)
+	if err != nil || ag.Name != "agent-7" || ag.Engagement != "eng-1" || ag.Serial != cred.Agent.Serial {
+		t.Fatalf("Verify = %+v, %v", ag, err)
+	}
+	if got := a.Agents("eng-1"); len(got) != 1 {
+		t.Errorf("Agents = %+v", got)
+	}
+
+	if n := a.Revoke("eng-1", "agent-7"); n != 1 {
+		t.Fatalf("Revoke = %d", n)
+	}
+	if _, err := a.Verify(leaf); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Verify after revoke: %v", err)
+	}
+	if got := a.Agents("eng-1"); len(got) != 0 {
+		t.Errorf("Agents after revoke = %+v", got)
+	}
+
+	// A certificate from another CA is not a credential.
+	other := newAuthority(t)
+	foreign, _ := enroll(t, other, "eng-1", "agent-7")
+	fleaf, _ := x509.ParseCertificate(foreign.Certificate)
+	if _, err := a.Verify(fleaf); err == nil {
+		t.Error("verified a credential from another authority")
+	}
+}
+
+func TestAuthority_Tokens(t *testing.T) {
+	a := newAuthority(t)
+	now := time.Now()
+	a.Now = func() time.Time { return now }
+	_, priv, _ := rte.GenerateKeyPair()
+	csr, _ := NewCSR("agent-7", priv)
+
+	token, _ := a.NewToken("eng-1", "agent-9")
+	if _, err := a.Enroll(Request{Token: token, CSR: csr}); err == nil || !strings.Contains(err.Error(), "agent-9") {
+		t.Fatalf("token bound to another agent: %v", err)
+	}
+	if _, err := a.Enroll(Request{Token: token + "x", CSR: csr}); !errors.Is(err, ErrBadToken) {
+		t.Fatalf("unknown token: %v", err)
+	}
+
+	token, _ = a.NewToken("eng-1", "")
+	if _, err := a.Enroll(Request{Token: token, CSR: csr}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Enroll(Request{Token: token, CSR: csr}); !errors.Is(err, ErrBadToken) {
+		t.Fatalf("reused token: %v", err)
+	}
+
+	token, _ = a.NewToken("eng-1", "")
+	now = now.Add(DefaultTokenTTL)
+	if _, err := a.Enroll(Request{Token: token, CSR: csr}); !errors.Is(err, ErrBadToken) {
+		t.Fatalf("expired token: %v", err)
+	}
+
+	if _, err := a.NewToken("eng 1", ""); err == nil {
+		t.Error("minted a token for an engagement name with a space")
+	}
+	if _, err := NewCSR("agent:7", priv); err == nil {
+		t.Error("made a CSR for an agent name with a colon")
+	}
+	if _, err := a.Enroll(Request{Token: token, CSR: []byte("junk")}); err == nil {
+		t.Error("enrolled with a malformed CSR")
+	}
+}
+
+func TestAuthority_VerifyResult(t *testing.T) {
+	a := newAuthority(t)
+	cred, priv := enroll(t, a, "eng-1", "agent-7")
+	pub := cred.Agent.PublicKey
+	res := rte.TaskResult{TaskID: "t-1", Engagement: "eng-1", State: rte.StateCompleted}
+
+	sr, _ := rte.SignResult(res, priv, pub)
+	if ag, err := a.VerifyResult(sr); err != nil || ag.Name != "agent-7" {
+		t.Fatalf("VerifyResult = %+v, %v", ag, err)
+	}
+
+	res.Engagement = "eng-2"
+	sr, _ = rte.SignResult(res, priv, pub)
+	if _, err := a.VerifyResult(sr); err == nil || !strings.Contains(err.Error(), "enrolled in eng-1") {
+		t.Errorf("result for another engagement: %v", err)
+	}
+
+	opub, opriv, _ := rte.GenerateKeyPair()
+	sr, _ = rte.SignResult(rte.TaskResult{TaskID: "t-1", Engagement: "eng-1"}, opriv, opub)
+	if _, err := a.VerifyResult(sr); !errors.Is(err, ErrUnknown) {
+		t.Errorf("unenrolled key: %v", err)
+	}
+
+	a.Revoke("eng-1", "agent-7")
+	sr, _ = rte.SignResult(rte.TaskResult{TaskID: "t-1", Engagement: "eng-1"}, priv, pub)
+	if _, err := a.VerifyResult(sr); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked agent: %v", err)
+	}
+}
+
+func TestAuthority_Labels(t *testing.T) {
+	a := newAuthority(t)
+	if _, err := a.NewLabeledToken("eng-1", "", rte.Labels{"os": "windows 11"}); err == nil {
+		t.Error("minted a token with a malformed label")
+	}
+	token, err := a.NewLabeledToken("eng-1", "win-1", rte.Labels{"os": "windows", "zone": "dmz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, priv, _ := rte.GenerateKeyPair()
+	csr, _ := NewCSR("win-1", priv)
+	cred, err := a.Enroll(Request{Token: token, CSR: csr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Agent.Labels.String() != "os=windows,zone=dmz" {
+		t.Errorf("credential labels = %v", cred.Agent.Labels)
+	}
+	cert, _ := x509.ParseCertificate(cred.Certificate)
+	ag, err := a.Verify(cert)
+	if err != nil || ag.Labels.String() != "os=windows,zone=dmz" {
+		t.Fatalf("Verify = %+v, %v", ag, err)
+	}
+	enroll(t, a, "eng-1", "lin-1")
+
+	for sel, want := range map[string]int{"": 2, "os=windows": 1, "zone!=dmz": 1, "os=macos": 0} {
+		got, err := a.Match("eng-1", sel)
+		if err != nil || len(got) != want {
+			t.Errorf("Match(%q) = %d agents, %v; want %d", sel, len(got), err, want)
+		}
+	}
+	if _, err := a.Match("eng-1", "os="); err == nil {
+		t.Error("matched a malformed selector")
+	}
+}
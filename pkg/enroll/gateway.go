@@ -0,0 +1,188 @@
+package enroll
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// maxBody bounds enrollment requests and uploaded results.
+const maxBody = 1 << 20
+
+// DefaultWait is how long a task poll waits for work before answering 204.
+const DefaultWait = 30 * time.Second
+
+// ServeHTTP is the enrollment endpoint: it accepts a POSTed Request and
+// answers with the issued Credential. Serve it over server-authenticated
+// TLS; agents have no certificate yet.
+func (a *Authority) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req Request
+	if err := decodeBody(r, &req); err != nil {
+		http.Error(w, "malformed enrollment request", http.StatusBadRequest)
+		return
+	}
+	cred, err := a.Enroll(req)
+	switch {
+	case errors.Is(err, ErrBadToken):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, cred)
+}
+
+// ServerTLSConfig returns a TLS config for agent-facing listeners serving
+// cert. Clients must present an unrevoked credential from the authority.
+func (a *Authority) ServerTLSConfig(cert ...tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: cert,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    a.pool,
+		MinVersion:   tls.VersionTLS13,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("no agent certificate")
+			}
+			_, err := a.Verify(cs.PeerCertificates[0])
+			return err
+		},
+	}
+}
+
+type agentKey struct{}
+
+// AgentFrom returns the agent RequireAgent authenticated for a request.
+func AgentFrom(ctx context.Context) (Agent, bool) {
+	ag, ok := ctx.Value(agentKey{}).(Agent)
+	return ag, ok
+}
+
+// RequireAgent serves next only to requests whose TLS client certificate
+// is a current credential from the authority, with the agent in the
+// request context (see AgentFrom).
+func (a *Authority) RequireAgent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "agent certificate required", http.StatusUnauthorized)
+			return
+		}
+		ag, err := a.Verify(r.TLS.PeerCertificates[0])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), agentKey{}, ag)))
+	})
+}
+
+// Gateway is the agent-facing API, for enrolled agents over mutual TLS:
+//
+//	GET  /v1/agent/task     -> rte.Message, or 204 if none arrived within Wait
+//	POST /v1/agent/results  <- rte.SignedResult, signed with the agent's enrolled key
+//
+// An agent is handed only tasks whose selector its certified labels
+// satisfy, so agents can share a queue; other tasks stay queued for the
+// agents they target. A message is gone from the queue once handed to an
+// agent; if the agent dies holding it, the janitor requeues the task.
+type Gateway struct {
+	Authority *Authority
+	// Queue returns the queue holding agent's work, or nil if it has none.
+	Queue func(Agent) *rte.Queue
+	// OnResult receives each verified result.
+	OnResult func(Agent, *rte.SignedResult)
+	// Wait bounds a task poll; 0 means DefaultWait.
+	Wait time.Duration
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.Authority == nil {
+		http.Error(w, "gateway is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	g.Authority.RequireAgent(http.HandlerFunc(g.serve)).ServeHTTP(w, r)
+}
+
+func (g *Gateway) serve(w http.ResponseWriter, r *http.Request) {
+	ag, _ := AgentFrom(r.Context())
+	switch {
+	case r.URL.Path == "/v1/agent/task" && r.Method == http.MethodGet:
+		g.task(w, r, ag)
+	case r.URL.Path == "/v1/agent/results" && r.Method == http.MethodPost:
+		g.result(w, r, ag)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *Gateway) task(w http.ResponseWriter, r *http.Request, ag Agent) {
+	var q *rte.Queue
+	if g.Queue != nil {
+		q = g.Queue(ag)
+	}
+	if q == nil {
+		http.Error(w, "no queue for agent "+ag.Name, http.StatusNotFound)
+		return
+	}
+	wait := g.Wait
+	if wait <= 0 {
+		wait = DefaultWait
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	m, err := q.PopMatch(ctx, rte.LabelMatcher(ag.Labels))
+	if err != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (g *Gateway) result(w http.ResponseWriter, r *http.Request, ag Agent) {
+	var sr rte.SignedResult
+	if err := decodeBody(r, &sr); err != nil {
+		http.Error(w, "malformed result", http.StatusBadRequest)
+		return
+	}
+	signer, err := g.Authority.VerifyResult(&sr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if !signer.PublicKey.Equal(ag.PublicKey) {
+		http.Error(w, "result is signed by another agent", http.StatusForbidden)
+		return
+	}
+	if g.OnResult != nil {
+		g.OnResult(ag, &sr)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// decodeBody reads up to maxBody of r's body into v with rte.DecodeStrict.
+func decodeBody(r *http.Request, v any) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil {
+		return err
+	}
+	return rte.DecodeStrict(data, v)
+}
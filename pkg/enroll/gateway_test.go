@@ -0,0 +1,181 @@
+package enroll
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestEnrollAndGateway(t *testing.T) {
+	a := newAuthority(t)
+	enrollSrv := httptest.NewTLSServer(a)
+	defer enrollSrv.Close()
+
+	token, _ := a.NewToken("eng-1", "agent-7")
+	pub, priv, _ := rte.GenerateKeyPair()
+	c := &Client{URL: enrollSrv.URL + "/v1/enroll", HTTPClient: enrollSrv.Client()}
+	cred, err := c.Enroll(context.Background(), token, "agent-7", priv)
+	if err != nil {
+		t.Fatalf("Enroll: %v", err)
+	}
+	if _, err := c.Enroll(context.Background(), token, "agent-7", priv); err == nil {
+		t.Fatal("enrolled twice with one token")
+	}
+
+	queue := rte.NewQueue()
+	results := make(chan string, 1)
+	gw := &Gateway{
+		Authority: a,
+		Queue: func(ag Agent) *rte.Queue {
+			if ag.Name == "agent-7" {
+				return queue
+			}
+			return nil
+		},
+		OnResult: func(ag Agent, sr *rte.SignedResult) { results <- ag.Name + "/" + sr.Result.TaskID },
+		Wait:     50 * time.Millisecond,
+	}
+	srv := httptest.NewUnstartedServer(gw)
+	srv.TLS = a.ServerTLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	cfg, err := cred.ClientTLSConfig(priv, srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+
+	resp, err := agent.Get(srv.URL + "/v1/agent/task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("empty poll: %s", resp.Status)
+	}
+
+	tpub, tpriv, _ := rte.GenerateKeyPair()
+	st, _ := rte.SignTask(rte.Task{
+		ID: "t-1", Engagement: "eng-1", Type: rte.TaskSimulateLogin, CreatedAt: time.Now().UTC(),
+		TTLSeconds: 600, Operator: "op-alice", ApprovedBy: "lead-bob", State: rte.StatePending,
+	}, tpriv, tpub)
+	queue.Push(rte.TaskMessage(st))
+	resp, err = agent.Get(srv.URL + "/v1/agent/task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m rte.Message
+	json.NewDecoder(resp.Body).Decode(&m)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || m.Task == nil || m.Task.Task.ID != "t-1" {
+		t.Fatalf("poll: %s %+v", resp.Status, m)
+	}
+
+	post := func(sr *rte.SignedResult) int {
+		t.Helper()
+		body, _ := json.Marshal(sr)
+		resp, err := agent.Post(srv.URL+"/v1/agent/results", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	sr, _ := rte.SignResult(rte.TaskResult{TaskID: "t-1", Engagement: "eng-1", State: rte.StateCompleted}, priv, pub)
+	if code := post(sr); code != http.StatusAccepted {
+		t.Fatalf("result upload: %d", code)
+	}
+	if got := <-results; got != "agent-7/t-1" {
+		t.Errorf("OnResult got %s", got)
+	}
+
+	// A result signed by another enrolled agent is refused on this
+	// agent's connection.
+	other, opriv := enroll(t, a, "eng-1", "agent-8")
+	sr, _ = rte.SignResult(rte.TaskResult{TaskID: "t-2", Engagement: "eng-1"}, opriv, other.Agent.PublicKey)
+	if code := post(sr); code != http.StatusForbidden {
+		t.Errorf("result from another agent: %d", code)
+	}
+
+	// No credential, no connection.
+	if _, err := srv.Client().Get(srv.URL + "/v1/agent/task"); err == nil {
+		t.Error("served a client without a credential")
+	}
+
+	// Revocation refuses requests on open connections and closes new ones.
+	a.Revoke("eng-1", "agent-7")
+	if code := post(sr); code != http.StatusForbidden {
+		t.Errorf("revoked agent on an open connection: %d", code)
+	}
+	fresh := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	if _, err := fresh.Get(srv.URL + "/v1/agent/task"); err == nil {
+		t.Error("served a revoked agent")
+	}
+}
+
+func TestGateway_DispatchesByLabel(t *testing.T) {
+	a := newAuthority(t)
+	queue := rte.NewQueue()
+	gw := &Gateway{Authority: a, Queue: func(Agent) *rte.Queue { return queue }, Wait: 50 * time.Millisecond}
+	srv := httptest.NewUnstartedServer(gw)
+	srv.TLS = a.ServerTLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+	roots := srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	agentFor := func(name string, labels rte.Labels) *http.Client {
+		t.Helper()
+		token, _ := a.NewLabeledToken("eng-1", name, labels)
+		_, priv, _ := rte.GenerateKeyPair()
+		csr, _ := NewCSR(name, priv)
+		cred, err := a.Enroll(Request{Token: token, CSR: csr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := cred.ClientTLSConfig(priv, roots)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	}
+	poll := func(c *http.Client) string {
+		t.Helper()
+		resp, err := c.Get(srv.URL + "/v1/agent/task")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var m rte.Message
+		if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&m) != nil {
+			return ""
+		}
+		return m.Task.Task.ID
+	}
+	win := agentFor("win-1", rte.Labels{"os": "windows", "zone": "dmz"})
+	lin := agentFor("lin-1", rte.Labels{"os": "linux"})
+
+	tpub, tpriv, _ := rte.GenerateKeyPair()
+	for _, tc := range []struct{ id, selector string }{{"dmz-win", "os=windows,zone=dmz"}, {"lin", "os=linux"}} {
+		st, _ := rte.SignTask(rte.Task{
+			ID: tc.id, Engagement: "eng-1", Type: rte.TaskSimulateLogin, CreatedAt: time.Now().UTC(),
+			TTLSeconds: 600, Operator: "op-alice", ApprovedBy: "lead-bob", State: rte.StatePending, Selector: tc.selector,
+		}, tpriv, tpub)
+		queue.Push(rte.TaskMessage(st))
+	}
+	if got := poll(lin); got != "lin" {
+		t.Errorf("linux agent got %q", got)
+	}
+	if got := poll(lin); got != "" {
+		t.Errorf("linux agent was handed %q", got)
+	}
+	if got := poll(win); got != "dmz-win" {
+		t.Errorf("windows agent got %q", got)
+	}
+}
@@ -0,0 +1,200 @@
+// Package gitprov verifies that engagement content comes from a signed Git
+// commit on an allowed branch. SSH commit signatures are checked in
+// process against an allowed signers file; Gitsign (x509) signatures are
+// checked by git verify-commit, which runs the gitsign program configured
+// as gpg.x509.program.
+package gitprov
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+const x509Begin = "-----BEGIN SIGNED MESSAGE-----"
+
+// Commit is a raw commit object split into its signature and the bytes the
+// signature covers.
+type Commit struct {
+	// Signature is the armored gpgsig header value, or nil if unsigned.
+	Signature []byte
+	// Payload is the commit object without its signature header.
+	Payload []byte
+}
+
+// ParseCommit splits a raw commit object, as printed by git cat-file
+// commit, into its signature and signed payload.
+func ParseCommit(raw []byte) (*Commit, error) {
+	// Headers run up to the first blank line; each ends in a newline.
+	head := raw
+	if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		head = raw[:i+1]
+	}
+	c := &Commit{Payload: make([]byte, 0, len(raw))}
+	lines := bytes.SplitAfter(head, []byte("\n"))
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		v, ok := bytes.CutPrefix(l, []byte("gpgsig "))
+		if !ok {
+			v, ok = bytes.CutPrefix(l, []byte("gpgsig-sha256 "))
+		}
+		if !ok {
+			c.Payload = append(c.Payload, l...)
+			continue
+		}
+		if c.Signature != nil {
+			return nil, errors.New("commit has more than one signature")
+		}
+		sig := append([]byte(nil), v...)
+		for i+1 < len(lines) && bytes.HasPrefix(lines[i+1], []byte(" ")) {
+			i++
+			sig = append(sig, lines[i][1:]...)
+		}
+		c.Signature = sig
+	}
+	c.Payload = append(c.Payload, raw[len(head):]...)
+	return c, nil
+}
+
+// Verifier checks the commit checked out in a working tree.
+type Verifier struct {
+	// Dir is the working tree; "" means the current directory.
+	Dir string
+	// Branches are the refs, such as "main" or "origin/main", a verified
+	// commit must be reachable from. At least one is required.
+	Branches []string
+	// AllowedSigners are the keys trusted for SSH signatures. If nil they
+	// are read from the file named by git's gpg.ssh.allowedSignersFile.
+	AllowedSigners []AllowedSigner
+	// Git is the git binary; "" means "git" on $PATH.
+	Git string
+}
+
+// Verify checks that HEAD is a signed commit on an allowed branch and that
+// every listed file is committed there unchanged, and returns the
+// provenance to record on tasks declared in those files.
+func (v *Verifier) Verify(ctx context.Context, paths ...string) (*rte.Provenance, error) {
+	if len(v.Branches) == 0 {
+		return nil, errors.New("at least one allowed branch is required")
+	}
+	out, err := v.git(ctx, "rev-parse", "--verify", "HEAD^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	sha := strings.TrimSpace(string(out))
+	if len(paths) > 0 {
+		args := append([]string{"status", "--porcelain", "--ignored", "--untracked-files=all", "--"}, paths...)
+		out, err := v.git(ctx, args...)
+		if err != nil {
+			return nil, err
+		}
+		if dirty := strings.TrimSpace(string(out)); dirty != "" {
+			return nil, fmt.Errorf("engagement files differ from commit %s:\n%s", sha, dirty)
+		}
+	}
+	signer, err := v.verifySignature(ctx, sha)
+	if err != nil {
+		return nil, err
+	}
+	branch, err := v.branch(ctx, sha)
+	if err != nil {
+		return nil, err
+	}
+	return &rte.Provenance{Commit: sha, Branch: branch, Signer: signer}, nil
+}
+
+// verifySignature checks the commit's signature and returns the signer's
+// principal, if the signature type names one.
+func (v *Verifier) verifySignature(ctx context.Context, sha string) (string, error) {
+	raw, err := v.git(ctx, "cat-file", "commit", sha)
+	if err != nil {
+		return "", err
+	}
+	c, err := ParseCommit(raw)
+	if err != nil {
+		return "", fmt.Errorf("commit %s: %w", sha, err)
+	}
+	switch {
+	case c.Signature == nil:
+		return "", fmt.Errorf("commit %s is not signed", sha)
+	case bytes.HasPrefix(c.Signature, []byte(sshsigBegin)):
+		signers, err := v.allowedSigners(ctx)
+		if err != nil {
+			return "", err
+		}
+		principal, err := VerifySSHSignature(c.Signature, c.Payload, sshNamespace, signers)
+		if err != nil {
+			return "", fmt.Errorf("commit %s: %w", sha, err)
+		}
+		return principal, nil
+	case bytes.HasPrefix(c.Signature, []byte(x509Begin)):
+		if _, err := v.git(ctx, "verify-commit", sha); err != nil {
+			return "", fmt.Errorf("commit %s: x509 signature: %w", sha, err)
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("commit %s: only SSH and Gitsign signatures are accepted", sha)
+	}
+}
+
+func (v *Verifier) allowedSigners(ctx context.Context) ([]AllowedSigner, error) {
+	if v.AllowedSigners != nil {
+		return v.AllowedSigners, nil
+	}
+	out, err := v.git(ctx, "config", "--path", "--get", "gpg.ssh.allowedSignersFile")
+	path := strings.TrimSpace(string(out))
+	if err != nil || path == "" {
+		return nil, errors.New("no allowed signers: set gpg.ssh.allowedSignersFile or pass them explicitly")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseAllowedSigners(f)
+}
+
+// branch returns the first allowed branch sha is reachable from.
+func (v *Verifier) branch(ctx context.Context, sha string) (string, error) {
+	for _, b := range v.Branches {
+		_, err := v.git(ctx, "merge-base", "--is-ancestor", sha, b)
+		var exit *exec.ExitError
+		switch {
+		case err == nil:
+			return b, nil
+		case errors.As(err, &exit) && exit.ExitCode() == 1:
+			continue
+		default:
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("commit %s is not on an allowed branch (%s)", sha, strings.Join(v.Branches, ", "))
+}
+
+func (v *Verifier) git(ctx context.Context, args ...string) ([]byte, error) {
+	bin := v.Git
+	if bin == "" {
+		bin = "git"
+	}
+	sub := args[0]
+	if v.Dir != "" {
+		args = append([]string{"-C", v.Dir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("git %s: %w: %s", sub, err, msg)
+		}
+		return out, fmt.Errorf("git %s: %w", sub, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,177 @@
+package gitprov
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// repo is a scratch Git repository that signs commits with a fresh SSH key.
+type repo struct {
+	t       *testing.T
+	dir     string
+	pubLine string // the key as written in allowed signers files
+}
+
+func newRepo(t *testing.T) *repo {
+	t.Helper()
+	for _, bin := range []string{"git", "ssh-keygen"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not installed", bin)
+		}
+	}
+	dir := t.TempDir()
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v: %s", err, out)
+	}
+	pub, err := os.ReadFile(key + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &repo{t: t, dir: dir, pubLine: strings.Join(strings.Fields(string(pub))[:2], " ")}
+	r.git("init", "-q", "-b", "main")
+	r.git("config", "user.name", "Alice")
+	r.git("config", "user.email", "alice@example.com")
+	r.git("config", "gpg.format", "ssh")
+	r.git("config", "user.signingkey", key+".pub")
+	return r
+}
+
+func (r *repo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", r.dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *repo) commit(name, content string, sign bool) string {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.dir, name), []byte(content), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.git("add", name)
+	flag := "--no-gpg-sign"
+	if sign {
+		flag = "-S"
+	}
+	r.git("commit", "-q", flag, "-m", "update "+name)
+	return r.git("rev-parse", "HEAD")
+}
+
+func (r *repo) signers(t *testing.T) []AllowedSigner {
+	t.Helper()
+	s, err := ParseAllowedSigners(strings.NewReader(`alice@example.com,alice namespaces="git" ` + r.pubLine + " laptop\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestParseAllowedSigners(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	blob := appendString(appendString(nil, []byte(sshKeyEd25519)), pub)
+	key := base64.StdEncoding.EncodeToString(blob)
+	in := "# team keys\n" +
+		"alice@example.com,alice namespaces=\"git,file\" ssh-ed25519 " + key + " laptop\n" +
+		"\n" +
+		"bob@example.com ssh-rsa AAAAB3NzaC1yc2E= bob\n"
+	got, err := ParseAllowedSigners(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Principals[1] != "alice" || len(got[0].Namespaces) != 2 || !got[0].Key.Equal(pub) {
+		t.Fatalf("signers = %+v", got)
+	}
+	if _, err := ParseAllowedSigners(strings.NewReader("ssh-ed25519 " + key + "\n")); err == nil {
+		t.Fatal("expected a line without principals to fail")
+	}
+}
+
+func TestVerify_SignedCommit(t *testing.T) {
+	r := newRepo(t)
+	sha := r.commit("engagement.json", `{"engagement":"eng-1"}`, true)
+	v := &Verifier{Dir: r.dir, Branches: []string{"release", "main"}, AllowedSigners: r.signers(t)}
+	// release does not exist yet; an unknown ref is an error, not a miss.
+	if _, err := v.Verify(context.Background(), "engagement.json"); err == nil {
+		t.Fatal("expected an unknown branch to fail")
+	}
+	v.Branches = []string{"main"}
+	p, err := v.Verify(context.Background(), "engagement.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Commit != sha || p.Branch != "main" || p.Signer != "alice@example.com" {
+		t.Fatalf("provenance = %+v, want commit %s", p, sha)
+	}
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	r := newRepo(t)
+	r.commit("engagement.json", `{"engagement":"eng-1"}`, true)
+	signers := r.signers(t)
+	ctx := context.Background()
+
+	// Uncommitted edits and untracked files are not from the commit.
+	os.WriteFile(filepath.Join(r.dir, "engagement.json"), []byte(`{"engagement":"eng-2"}`), 0o644)
+	v := &Verifier{Dir: r.dir, Branches: []string{"main"}, AllowedSigners: signers}
+	if _, err := v.Verify(ctx, "engagement.json"); err == nil || !strings.Contains(err.Error(), "differ") {
+		t.Fatalf("modified file: got %v", err)
+	}
+	r.git("checkout", "engagement.json")
+	os.WriteFile(filepath.Join(r.dir, "extra.json"), []byte(`{}`), 0o644)
+	if _, err := v.Verify(ctx, "engagement.json", "extra.json"); err == nil {
+		t.Fatal("expected an untracked file to fail")
+	}
+	os.Remove(filepath.Join(r.dir, "extra.json"))
+
+	// A key outside the allowed signers file.
+	other := &Verifier{Dir: r.dir, Branches: []string{"main"}, AllowedSigners: []AllowedSigner{}}
+	if _, err := other.Verify(ctx); err == nil || !strings.Contains(err.Error(), "not an allowed signer") {
+		t.Fatalf("unknown key: got %v", err)
+	}
+
+	// A commit only on a feature branch.
+	r.git("checkout", "-q", "-b", "feature")
+	r.commit("engagement.json", `{"engagement":"eng-3"}`, true)
+	if _, err := v.Verify(ctx, "engagement.json"); err == nil || !strings.Contains(err.Error(), "not on an allowed branch") {
+		t.Fatalf("feature branch: got %v", err)
+	}
+
+	// An unsigned commit, even on main.
+	r.git("checkout", "-q", "main")
+	r.commit("engagement.json", `{"engagement":"eng-4"}`, false)
+	if _, err := v.Verify(ctx, "engagement.json"); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Fatalf("unsigned commit: got %v", err)
+	}
+}
+
+func TestVerifySSHSignature_Tampered(t *testing.T) {
+	r := newRepo(t)
+	r.commit("engagement.json", `{"engagement":"eng-1"}`, true)
+	raw := r.git("cat-file", "commit", "HEAD")
+	c, err := ParseCommit([]byte(raw + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := r.signers(t)
+	if _, err := VerifySSHSignature(c.Signature, c.Payload, "git", signers); err != nil {
+		t.Fatalf("untampered: %v", err)
+	}
+	forged := strings.Replace(string(c.Payload), "update engagement.json", "update everything", 1)
+	if _, err := VerifySSHSignature(c.Signature, []byte(forged), "git", signers); err == nil {
+		t.Fatal("expected a modified commit to fail")
+	}
+	if _, err := VerifySSHSignature(c.Signature, c.Payload, "file", signers); err == nil {
+		t.Fatal("expected a namespace mismatch to fail")
+	}
+}
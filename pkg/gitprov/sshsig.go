@@ -0,0 +1,231 @@
+package gitprov
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// sshNamespace is the namespace git signs commits under.
+const sshNamespace = "git"
+
+const (
+	sshsigMagic   = "SSHSIG"
+	sshsigBegin   = "-----BEGIN SSH SIGNATURE-----"
+	sshsigEnd     = "-----END SSH SIGNATURE-----"
+	sshKeyEd25519 = "ssh-ed25519"
+)
+
+// AllowedSigner is one entry of an SSH allowed signers file, the format
+// git's gpg.ssh.allowedSignersFile uses (see ssh-keygen(1)).
+type AllowedSigner struct {
+	Principals []string
+	// Namespaces limits the key to these signature namespaces; empty
+	// allows any.
+	Namespaces []string
+	Key        ed25519.PublicKey
+}
+
+// ParseAllowedSigners reads an allowed signers file. Only ssh-ed25519 keys
+// can be verified; entries for other key types are skipped.
+func ParseAllowedSigners(r io.Reader) ([]AllowedSigner, error) {
+	var out []AllowedSigner
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		k := slices.IndexFunc(fields, isKeyType)
+		if k < 1 || k+1 >= len(fields) {
+			return nil, fmt.Errorf("allowed signers line %d: expected principals, options, and a key", n)
+		}
+		if fields[k] != sshKeyEd25519 {
+			continue
+		}
+		key, err := parsePublicKey(fields[k+1])
+		if err != nil {
+			return nil, fmt.Errorf("allowed signers line %d: %w", n, err)
+		}
+		s := AllowedSigner{Principals: strings.Split(fields[0], ","), Key: key}
+		for _, opt := range splitOptions(strings.Join(fields[1:k], " ")) {
+			if v, ok := strings.CutPrefix(opt, "namespaces="); ok {
+				s.Namespaces = strings.Split(strings.Trim(v, `"`), ",")
+			}
+		}
+		out = append(out, s)
+	}
+	return out, sc.Err()
+}
+
+// splitOptions splits comma-separated options, leaving commas inside
+// double quotes alone.
+func splitOptions(s string) []string {
+	var out []string
+	quoted, start := false, 0
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			out = append(out, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(out, strings.TrimSpace(s[start:]))
+}
+
+func isKeyType(f string) bool {
+	return strings.HasPrefix(f, "ssh-") || strings.HasPrefix(f, "ecdsa-sha2-") || strings.HasPrefix(f, "sk-")
+}
+
+// parsePublicKey decodes the base64 field of an ssh-ed25519 public key.
+func parsePublicKey(b64 string) (ed25519.PublicKey, error) {
+	blob, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+	return parseKeyBlob(blob)
+}
+
+func parseKeyBlob(blob []byte) (ed25519.PublicKey, error) {
+	r := sshReader{b: blob}
+	typ, key := r.string(), r.string()
+	if r.err != nil || len(r.b) != 0 {
+		return nil, errors.New("malformed public key")
+	}
+	if string(typ) != sshKeyEd25519 {
+		return nil, fmt.Errorf("unsupported key type %s", typ)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid ed25519 public key size")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// VerifySSHSignature checks an armored SSH signature over message in the
+// given namespace and returns the first principal of the allowed signer
+// whose key made it.
+func VerifySSHSignature(armored, message []byte, namespace string, signers []AllowedSigner) (string, error) {
+	blob, err := dearmor(armored)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(blob, []byte(sshsigMagic)) {
+		return "", errors.New("not an SSH signature")
+	}
+	r := sshReader{b: blob[len(sshsigMagic):]}
+	version := r.uint32()
+	pubBlob, ns, reserved, hashAlg, sigBlob := r.string(), r.string(), r.string(), r.string(), r.string()
+	if r.err != nil {
+		return "", errors.New("malformed SSH signature")
+	}
+	if version != 1 {
+		return "", fmt.Errorf("unsupported SSH signature version %d", version)
+	}
+	if string(ns) != namespace {
+		return "", fmt.Errorf("signature namespace is %q, want %q", ns, namespace)
+	}
+	pub, err := parseKeyBlob(pubBlob)
+	if err != nil {
+		return "", err
+	}
+	sr := sshReader{b: sigBlob}
+	sigType, sig := sr.string(), sr.string()
+	if sr.err != nil || string(sigType) != sshKeyEd25519 || len(sig) != ed25519.SignatureSize {
+		return "", errors.New("malformed ed25519 signature")
+	}
+	var digest []byte
+	switch string(hashAlg) {
+	case "sha256":
+		h := sha256.Sum256(message)
+		digest = h[:]
+	case "sha512":
+		h := sha512.Sum512(message)
+		digest = h[:]
+	default:
+		return "", fmt.Errorf("unsupported signature hash %s", hashAlg)
+	}
+	signed := []byte(sshsigMagic)
+	for _, f := range [][]byte{ns, reserved, hashAlg, digest} {
+		signed = appendString(signed, f)
+	}
+	if !ed25519.Verify(pub, signed, sig) {
+		return "", errors.New("SSH signature verification failed")
+	}
+	for _, s := range signers {
+		if !s.Key.Equal(pub) {
+			continue
+		}
+		if len(s.Namespaces) > 0 && !slices.Contains(s.Namespaces, namespace) {
+			continue
+		}
+		return s.Principals[0], nil
+	}
+	return "", fmt.Errorf("signing key %s is not an allowed signer", fingerprint(pubBlob))
+}
+
+// fingerprint renders a key as ssh-keygen -l does.
+func fingerprint(blob []byte) string {
+	h := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(h[:])
+}
+
+func dearmor(armored []byte) ([]byte, error) {
+	s := strings.TrimSpace(string(armored))
+	body, ok := strings.CutPrefix(s, sshsigBegin)
+	if !ok {
+		return nil, errors.New("missing SSH signature header")
+	}
+	body, ok = strings.CutSuffix(body, sshsigEnd)
+	if !ok {
+		return nil, errors.New("missing SSH signature footer")
+	}
+	blob, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, fmt.Errorf("decode SSH signature: %w", err)
+	}
+	return blob, nil
+}
+
+func appendString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// sshReader reads the SSH wire encoding; the first error sticks.
+type sshReader struct {
+	b   []byte
+	err error
+}
+
+func (r *sshReader) uint32() uint32 {
+	if r.err != nil || len(r.b) < 4 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *sshReader) string() []byte {
+	n := r.uint32()
+	if r.err != nil || uint64(len(r.b)) < uint64(n) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	s := r.b[:n]
+	r.b = r.b[n:]
+	return s
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Beacon transports understood by BeaconHandler.
+const (
+	TransportHTTPS = "https"
+	TransportDNS   = "dns"
+)
+
+const (
+	maxBeaconCallbacks   = 1000
+	maxBeaconInterval    = 86400
+	maxBeaconJitter      = 90
+	maxBeaconPayload     = 64 * 1024
+	maxDNSPayload        = 30
+	defaultBeaconTimeout = 10 * time.Second
+)
+
+// BeaconWatermark opens every beacon payload, so the engagement's sink and
+// the SOC can tell simulated callbacks from real ones.
+const BeaconWatermark = "RTE-A-SYNTHETIC-BEACON"
+
+// BeaconProfile is a named set of beacon timing and size defaults.
+type BeaconProfile struct {
+	Name            string
+	IntervalSeconds int
+	JitterPercent   int
+	PayloadMin      int
+	PayloadMax      int
+}
+
+// BeaconProfiles are the built-in profiles selectable with the "profile" param.
+var BeaconProfiles = map[string]BeaconProfile{
+	"cobaltstrike-like": {Name: "cobaltstrike-like", IntervalSeconds: 60, JitterPercent: 20, PayloadMin: 64, PayloadMax: 512},
+	"low-and-slow":      {Name: "low-and-slow", IntervalSeconds: 900, JitterPercent: 50, PayloadMin: 16, PayloadMax: 128},
+	"interactive":       {Name: "interactive", IntervalSeconds: 5, JitterPercent: 10, PayloadMin: 128, PayloadMax: 4096},
+}
+
+// BeaconCallback is the captured outcome of one callback.
+type BeaconCallback struct {
+	At        time.Time `json:"at"`
+	Bytes     int       `json:"bytes"`
+	Outcome   string    `json:"outcome"`
+	Detail    string    `json:"detail,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+}
+
+// BeaconResult is the output of a simulate_beacon task.
+type BeaconResult struct {
+	Profile   string           `json:"profile,omitempty"`
+	Transport string           `json:"transport"`
+	Sink      string           `json:"sink"`
+	Callbacks []BeaconCallback `json:"callbacks"`
+}
+
+// BeaconHandler executes TaskSimulateBeacon: periodic, watermarked callbacks
+// to an engagement-owned sink until the callback count is reached or the
+// task's TTL ends.
+//
+// Params: transport (https or dns), sink (URL for https, domain for dns),
+// resolver (host:port, dns only), profile, interval_seconds, jitter_percent
+// (0-90; each sleep is shortened by up to this share of the interval),
+// payload_min, payload_max, and count (1-1000, default 10). Explicit params
+// override the profile.
+type BeaconHandler struct {
+	// PauseGates makes the handler rte.Pausable: a paused beacon holds
+	// its next callback until resumed.
+	rte.PauseGates
+	// Timeout bounds each callback. Defaults to 10s.
+	Timeout time.Duration
+	// Client is used for HTTPS callbacks. Under an rte.Guard a copy of
+	// it dials through the guard; callbacks fail if its transport cannot.
+	Client *http.Client
+}
+
+type beaconPlan struct {
+	profile  string
+	interval time.Duration
+	jitter   int
+	min, max int
+	count    int
+}
+
+func parseBeaconPlan(p map[string]string) (beaconPlan, error) {
+	base := BeaconProfile{IntervalSeconds: 60, JitterPercent: 0, PayloadMin: 64, PayloadMax: 64}
+	if name := p["profile"]; name != "" {
+		prof, ok := BeaconProfiles[name]
+		if !ok {
+			return beaconPlan{}, fmt.Errorf("unknown beacon profile: %s", name)
+		}
+		base = prof
+	}
+	interval, err := paramInt(p, "interval_seconds", base.IntervalSeconds, 1, maxBeaconInterval)
+	if err != nil {
+		return beaconPlan{}, err
+	}
+	jitter, err := paramInt(p, "jitter_percent", base.JitterPercent, 0, maxBeaconJitter)
+	if err != nil {
+		return beaconPlan{}, err
+	}
+	lo, err := paramInt(p, "payload_min", base.PayloadMin, 0, maxBeaconPayload)
+	if err != nil {
+		return beaconPlan{}, err
+	}
+	hi, err := paramInt(p, "payload_max", base.PayloadMax, 0, maxBeaconPayload)
+	if err != nil {
+		return beaconPlan{}, err
+	}
+	if hi < lo {
+		return beaconPlan{}, fmt.Errorf("payload_max %d is below payload_min %d", hi, lo)
+	}
+	count, err := paramInt(p, "count", 10, 1, maxBeaconCallbacks)
+	if err != nil {
+		return beaconPlan{}, err
+	}
+	return beaconPlan{
+		profile: base.Name, interval: time.Duration(interval) * time.Second,
+		jitter: jitter, min: lo, max: hi, count: count,
+	}, nil
+}
+
+// sleep returns the jittered delay before the next callback.
+func (b beaconPlan) sleep() time.Duration {
+	if b.jitter == 0 {
+		return b.interval
+	}
+	max := int64(b.interval) * int64(b.jitter) / 100
+	return b.interval - time.Duration(rand.Int64N(max+1))
+}
+
+func (b beaconPlan) size() int {
+	return b.min + rand.IntN(b.max-b.min+1)
+}
+
+// Handle implements rte.Handler.
+func (h *BeaconHandler) Handle(ctx context.Context, task rte.Task) (any, error) {
+	if task.Type != rte.TaskSimulateBeacon {
+		return nil, fmt.Errorf("beacon handler cannot run %s tasks", task.Type)
+	}
+	p := task.Params
+	plan, err := parseBeaconPlan(p)
+	if err != nil {
+		return nil, err
+	}
+	transport, sink := paramString(p, "transport", TransportHTTPS), p["sink"]
+	callback, err := h.callbackFunc(task, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &BeaconResult{Profile: plan.profile, Transport: transport, Sink: sink}
+	var last time.Time
+	var wait time.Duration
+	for i := 0; i < plan.count; i++ {
+		if i > 0 && !pace(ctx.Done(), last, wait) {
+			return res, ctx.Err()
+		}
+		if err := h.Wait(ctx, task); err != nil {
+			return res, err
+		}
+		last = time.Now()
+		wait = plan.sleep()
+		payload := beaconPayload(plan.size())
+		cctx, cancel := context.WithTimeout(ctx, h.timeout())
+		outcome, detail, err := callback(cctx, i, payload)
+		cancel()
+		if err != nil {
+			outcome, detail = "error", err.Error()
+		}
+		res.Callbacks = append(res.Callbacks, BeaconCallback{
+			At:        last.UTC(),
+			Bytes:     len(payload),
+			Outcome:   outcome,
+			Detail:    detail,
+			LatencyMS: time.Since(last).Milliseconds(),
+		})
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+	}
+	return res, nil
+}
+
+// Plan implements rte.Planner.
+func (h *BeaconHandler) Plan(_ context.Context, task rte.Task) ([]rte.PlannedStep, error) {
+	if task.Type != rte.TaskSimulateBeacon {
+		return nil, fmt.Errorf("beacon handler cannot run %s tasks", task.Type)
+	}
+	plan, err := parseBeaconPlan(task.Params)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.callbackFunc(task, plan); err != nil {
+		return nil, err
+	}
+	every := plan.interval.String()
+	if plan.jitter > 0 {
+		every = fmt.Sprintf("%s less up to %d%% jitter", plan.interval, plan.jitter)
+	}
+	detail := fmt.Sprintf("POST a %d-%d byte %s-watermarked payload every %s", plan.min, plan.max, BeaconWatermark, every)
+	target := task.Params["sink"]
+	if paramString(task.Params, "transport", TransportHTTPS) == TransportDNS {
+		detail = fmt.Sprintf("resolve <payload>.<seq>.%s via %s, the payload %d-%d hex-encoded bytes, every %s",
+			strings.TrimSuffix(target, "."), task.Params["resolver"], plan.min, plan.max, every)
+	}
+	return []rte.PlannedStep{{Action: rte.StepConnect, Target: target, Detail: detail, Count: plan.count}}, nil
+}
+
+// callbackFunc checks the task's transport params and returns the function
+// making one callback.
+func (h *BeaconHandler) callbackFunc(task rte.Task, plan beaconPlan) (func(context.Context, int, []byte) (string, string, error), error) {
+	p := task.Params
+	transport := paramString(p, "transport", TransportHTTPS)
+	sink, err := requireParam(p, "sink")
+	if err != nil {
+		return nil, err
+	}
+	switch transport {
+	case TransportHTTPS:
+		u, err := url.Parse(sink)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("https sink must be an absolute http(s) URL, got %q", sink)
+		}
+		return func(ctx context.Context, _ int, payload []byte) (string, string, error) {
+			return h.httpsCallback(ctx, sink, task.ID, payload)
+		}, nil
+	case TransportDNS:
+		resolver, err := requireParam(p, "resolver")
+		if err != nil {
+			return nil, err
+		}
+		if plan.max > maxDNSPayload {
+			return nil, fmt.Errorf("dns payloads are limited to %d bytes", maxDNSPayload)
+		}
+		return func(ctx context.Context, seq int, payload []byte) (string, string, error) {
+			return dnsCallback(ctx, resolver, sink, seq, payload)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported beacon transport: %s", transport)
+	}
+}
+
+func (h *BeaconHandler) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return defaultBeaconTimeout
+}
+
+// beaconPayload returns n bytes starting with the synthetic watermark.
+func beaconPayload(n int) []byte {
+	b := make([]byte, n)
+	copy(b, BeaconWatermark)
+	for i := len(BeaconWatermark); i < n; i++ {
+		b[i] = byte('a' + rand.IntN(26))
+	}
+	return b
+}
+
+func (h *BeaconHandler) httpsCallback(ctx context.Context, sink, taskID string, payload []byte) (string, string, error) {
+	client := runClient(ctx, h.timeout())
+	if h.Client != nil {
+		var err error
+		if client, err = guardClient(ctx, h.Client); err != nil {
+			return "", "", err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink, bytes.NewReader(payload))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("User-Agent", "rte-a-simulate-beacon")
+	req.Header.Set("X-RTE-A-Task", taskID)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHTTPResponseBytes))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return "delivered", fmt.Sprintf("HTTP %d", resp.StatusCode), nil
+	}
+	return "http_error", fmt.Sprintf("HTTP %d", resp.StatusCode), nil
+}
+
+// dnsCallback encodes the payload as a hex label and resolves it through the
+// configured resolver. NXDOMAIN still counts as delivered: the query reached
+// the sink's authoritative server.
+func dnsCallback(ctx context.Context, resolver, domain string, seq int, payload []byte) (string, string, error) {
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(dctx context.Context, network, _ string) (net.Conn, error) {
+			d, _ := rte.RunDialer(ctx)
+			return d.DialContext(dctx, network, resolver)
+		},
+	}
+	name := fmt.Sprintf("%s.%d.%s", hex.EncodeToString(payload), seq, strings.TrimSuffix(domain, "."))
+	addrs, err := r.LookupHost(ctx, name)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return "delivered", "NXDOMAIN", nil
+		}
+		return "", "", err
+	}
+	return "delivered", strings.Join(addrs, ","), nil
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func beaconTask(params map[string]string) rte.Task {
+	task := loginTask(params)
+	task.ID = "task-beacon"
+	task.Type = rte.TaskSimulateBeacon
+	return task
+}
+
+func TestParseBeaconPlan_Profile(t *testing.T) {
+	plan, err := parseBeaconPlan(map[string]string{"profile": "cobaltstrike-like", "jitter_percent": "40"})
+	if err != nil {
+		t.Fatalf("parseBeaconPlan: %v", err)
+	}
+	if plan.interval != time.Minute || plan.jitter != 40 || plan.min != 64 || plan.max != 512 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	for i := 0; i < 100; i++ {
+		if d := plan.sleep(); d > time.Minute || d < 36*time.Second {
+			t.Fatalf("sleep %s outside jitter window", d)
+		}
+		if n := plan.size(); n < 64 || n > 512 {
+			t.Fatalf("size %d outside payload range", n)
+		}
+	}
+}
+
+func TestParseBeaconPlan_Invalid(t *testing.T) {
+	cases := []map[string]string{
+		{"profile": "nope"},
+		{"jitter_percent": "95"},
+		{"interval_seconds": "0"},
+		{"payload_min": "100", "payload_max": "10"},
+		{"count": "5000"},
+	}
+	for _, p := range cases {
+		if _, err := parseBeaconPlan(p); err == nil {
+			t.Errorf("expected %v to fail", p)
+		}
+	}
+}
+
+func TestBeaconHandler_HTTPS(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, r.Header.Get("X-RTE-A-Task")+":"+string(b))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	h := &BeaconHandler{}
+	out, err := h.Handle(context.Background(), beaconTask(map[string]string{
+		"sink": srv.URL + "/cb", "interval_seconds": "1", "count": "2", "payload_min": "32", "payload_max": "64",
+	}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	res := out.(*BeaconResult)
+	if len(res.Callbacks) != 2 {
+		t.Fatalf("callbacks: got %d, want 2", len(res.Callbacks))
+	}
+	for _, c := range res.Callbacks {
+		if c.Outcome != "delivered" {
+			t.Errorf("outcome %q (%s)", c.Outcome, c.Detail)
+		}
+	}
+	if !strings.HasPrefix(bodies[0], "task-beacon:"+BeaconWatermark) {
+		t.Errorf("payload not watermarked: %q", bodies[0])
+	}
+}
+
+// nxdomainServer answers every DNS query with NXDOMAIN and records names.
+func nxdomainServer(t *testing.T) (string, func() []string) {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	var mu sync.Mutex
+	var names []string
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			q := append([]byte(nil), buf[:n]...)
+			var labels []string
+			for i := 12; i < len(q) && q[i] != 0; i += int(q[i]) + 1 {
+				labels = append(labels, string(q[i+1:i+1+int(q[i])]))
+			}
+			mu.Lock()
+			names = append(names, strings.Join(labels, "."))
+			mu.Unlock()
+			q[2], q[3] = 0x81, 0x83
+			_, _ = pc.WriteTo(q, addr)
+		}
+	}()
+	return pc.LocalAddr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), names...)
+	}
+}
+
+func TestBeaconHandler_DNS(t *testing.T) {
+	addr, names := nxdomainServer(t)
+	h := &BeaconHandler{Timeout: 2 * time.Second}
+	out, err := h.Handle(context.Background(), beaconTask(map[string]string{
+		"transport": TransportDNS, "sink": "cb.eng-2026-q1.example", "resolver": addr,
+		"count": "1", "payload_min": "24", "payload_max": "24",
+	}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	res := out.(*BeaconResult)
+	if len(res.Callbacks) != 1 || res.Callbacks[0].Outcome != "delivered" {
+		t.Fatalf("unexpected callbacks %+v", res.Callbacks)
+	}
+	got := names()
+	if len(got) == 0 || !strings.HasSuffix(got[0], ".0.cb.eng-2026-q1.example") {
+		t.Fatalf("unexpected query names %v", got)
+	}
+}
+
+func TestBeaconHandler_InvalidParams(t *testing.T) {
+	h := &BeaconHandler{}
+	cases := []map[string]string{
+		{},
+		{"sink": "not a url"},
+		{"transport": "icmp", "sink": "x"},
+		{"transport": TransportDNS, "sink": "cb.example"},
+		{"transport": TransportDNS, "sink": "cb.example", "resolver": "127.0.0.1:53", "payload_max": "500"},
+	}
+	for _, p := range cases {
+		if _, err := h.Handle(context.Background(), beaconTask(p)); err == nil {
+			t.Errorf("expected %v to fail", p)
+		}
+	}
+}
+
+func TestBeaconHandler_Pause(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { calls.Add(1) }))
+	defer srv.Close()
+	h := &BeaconHandler{}
+	task := beaconTask(map[string]string{"sink": srv.URL, "interval_seconds": "1", "count": "2"})
+	if err := h.Pause(task); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.Handle(context.Background(), task)
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("paused beacon made %d callbacks", n)
+	}
+	if err := h.Resume(task); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("resumed beacon made %d callbacks, want 2", n)
+	}
+}
+
+func TestBeaconHandler_Plan(t *testing.T) {
+	h := &BeaconHandler{}
+	steps, err := h.Plan(context.Background(), beaconTask(map[string]string{
+		"transport": "dns", "sink": "c2.test.example.", "resolver": "127.0.0.1:53", "payload_max": "30", "payload_min": "16", "count": "4",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].Count != 4 || !strings.Contains(steps[0].Detail, "<payload>.<seq>.c2.test.example via 127.0.0.1:53") {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if _, err := h.Plan(context.Background(), beaconTask(map[string]string{"transport": "dns", "sink": "c2.test.example", "resolver": "127.0.0.1:53", "payload_max": "64"})); err == nil {
+		t.Fatal("expected oversized dns payload to fail its plan")
+	}
+}
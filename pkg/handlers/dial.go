@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// runClient returns a new HTTP client with the given timeout for the run
+// ctx belongs to. Under an rte.Guard it dials through the guard, bypassing
+// any proxy, which the guard would refuse unless allowed.
+func runClient(ctx context.Context, timeout time.Duration) *http.Client {
+	c := &http.Client{Timeout: timeout}
+	if d, guarded := rte.RunDialer(ctx); guarded {
+		c.Transport = &http.Transport{
+			DialContext:         d.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true,
+		}
+	}
+	return c
+}
+
+// guardClient returns c for use during the run ctx belongs to. Under an
+// rte.Guard it returns a copy of c whose transport dials through the
+// guard, bypassing any proxy as runClient does, and fails if c's transport
+// is not an *http.Transport or dials TLS itself, since the guard could not
+// hold it to the task's targets.
+func guardClient(ctx context.Context, c *http.Client) (*http.Client, error) {
+	d, guarded := rte.RunDialer(ctx)
+	if !guarded {
+		return c, nil
+	}
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok || t.DialTLSContext != nil || t.DialTLS != nil {
+		return nil, fmt.Errorf("%w: cannot dial a %T client transport through the guard", rte.ErrGuardDenied, rt)
+	}
+	t = t.Clone()
+	t.DialContext, t.Dial = d.DialContext, nil
+	t.Proxy = nil
+	t.DisableKeepAlives = true
+	gc := *c
+	gc.Transport = t
+	return &gc, nil
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+	"github.com/codethor0/rte-a-reference/pkg/synth"
+)
+
+const (
+	maxEmitEvents = 10000
+	maxEmitRate   = 1000
+	defaultEmits  = "failed_login,process_creation,dns_query"
+)
+
+// EmitResult is the output of an emit_synthetic task.
+type EmitResult struct {
+	Format  string         `json:"format"`
+	Emitted int            `json:"emitted"`
+	ByKind  map[string]int `json:"by_kind"`
+	First   time.Time      `json:"first,omitempty"`
+	Last    time.Time      `json:"last,omitempty"`
+}
+
+// EmitHandler executes TaskEmitSynthetic: it generates synthetic security
+// events attributed to the task and delivers them to a log pipeline so blue
+// teams can test ingestion and detection end to end.
+//
+// Params: events (comma-separated failed_login, process_creation,
+// dns_query; default all), format (syslog, cef, leef, jsonl, or evtx-xml;
+// default jsonl), count (1-10000, default 100), rate_per_second (1-1000,
+// default 10), and sink (optional http(s) URL overriding Sink, or "system"
+// for the host's native event log; see SystemLogSink).
+type EmitHandler struct {
+	// Sink receives events when the task does not name its own sink.
+	Sink synth.EventSink
+	// Identities sizes the engagement's identity set.
+	Identities synth.IdentityConfig
+}
+
+// Handle implements rte.Handler.
+func (h *EmitHandler) Handle(ctx context.Context, task rte.Task) (any, error) {
+	if task.Type != rte.TaskEmitSynthetic {
+		return nil, fmt.Errorf("emit handler cannot run %s tasks", task.Type)
+	}
+	ep, err := parseEmitParams(task.Params)
+	if err != nil {
+		return nil, err
+	}
+	kinds, format, formatter, count, rate := ep.kinds, ep.format, ep.formatter, ep.count, ep.rate
+	sink, err := h.sink(ctx, task.Params)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := synth.NewIdentitySet(task.Engagement, h.Identities)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := synth.NewEventGenerator(ids, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Events go out in one batch per second so the sink sees the requested rate.
+	res := &EmitResult{Format: format, ByKind: make(map[string]int, len(kinds))}
+	var last time.Time
+	for res.Emitted < count {
+		if res.Emitted > 0 && !pace(ctx.Done(), last, time.Second) {
+			return res, ctx.Err()
+		}
+		last = time.Now()
+		n := min(rate, count-res.Emitted)
+		events := make([]synth.SecurityEvent, 0, n)
+		for i := 0; i < n; i++ {
+			kind := kinds[(res.Emitted+i)%len(kinds)]
+			at := last.Add(time.Duration(i) * time.Second / time.Duration(n))
+			e, err := gen.Next(kind, at)
+			if err != nil {
+				return res, err
+			}
+			events = append(events, e)
+		}
+		lines, err := synth.FormatEvents(formatter, events)
+		if err != nil {
+			return res, err
+		}
+		if err := sink.Send(ctx, lines); err != nil {
+			return res, fmt.Errorf("deliver events: %w", err)
+		}
+		for _, e := range events {
+			res.ByKind[string(e.Kind)]++
+		}
+		if res.First.IsZero() {
+			res.First = events[0].Time
+		}
+		res.Last = events[len(events)-1].Time
+		res.Emitted += n
+	}
+	return res, nil
+}
+
+// Plan implements rte.Planner.
+func (h *EmitHandler) Plan(ctx context.Context, task rte.Task) ([]rte.PlannedStep, error) {
+	if task.Type != rte.TaskEmitSynthetic {
+		return nil, fmt.Errorf("emit handler cannot run %s tasks", task.Type)
+	}
+	ep, err := parseEmitParams(task.Params)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.sink(ctx, task.Params); err != nil {
+		return nil, err
+	}
+	target := paramString(task.Params, "sink", "the agent's configured event sink")
+	if target == "system" {
+		target = "the host's event log"
+	}
+	kinds := make([]string, len(ep.kinds))
+	for i, k := range ep.kinds {
+		kinds[i] = string(k)
+	}
+	return []rte.PlannedStep{{
+		Action: rte.StepEmit,
+		Target: target,
+		Detail: fmt.Sprintf("deliver synthetic %s events as %s, attributed to task %s, at most %d per second",
+			strings.Join(kinds, ", "), ep.format, task.ID, ep.rate),
+		Count: ep.count,
+	}}, nil
+}
+
+// emitParams are the params of an emit_synthetic task other than its sink.
+type emitParams struct {
+	kinds       []synth.EventKind
+	format      string
+	formatter   synth.Formatter
+	count, rate int
+}
+
+func parseEmitParams(p map[string]string) (emitParams, error) {
+	var ep emitParams
+	var err error
+	if ep.kinds, err = synth.ParseEventKinds(paramString(p, "events", defaultEmits)); err != nil {
+		return ep, err
+	}
+	ep.format = paramString(p, "format", string(synth.FormatJSONL))
+	if ep.formatter, err = synth.FormatterFor(synth.EventFormat(ep.format)); err != nil {
+		return ep, err
+	}
+	if ep.count, err = paramInt(p, "count", 100, 1, maxEmitEvents); err != nil {
+		return ep, err
+	}
+	if ep.rate, err = paramInt(p, "rate_per_second", 10, 1, maxEmitRate); err != nil {
+		return ep, err
+	}
+	return ep, nil
+}
+
+func (h *EmitHandler) sink(ctx context.Context, p map[string]string) (synth.EventSink, error) {
+	if raw := p["sink"]; raw == "system" {
+		return &SystemLogSink{}, nil
+	} else if raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("sink must be an absolute http(s) URL, got %q", raw)
+		}
+		return &synth.HTTPSink{URL: raw, Client: runClient(ctx, 0)}, nil
+	}
+	if h.Sink == nil {
+		return nil, errors.New("no event sink configured")
+	}
+	return h.Sink, nil
+}
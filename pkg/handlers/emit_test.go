@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+	"github.com/codethor0/rte-a-reference/pkg/synth"
+)
+
+func emitTask(params map[string]string) rte.Task {
+	task := loginTask(params)
+	task.ID = "task-emit"
+	task.Type = rte.TaskEmitSynthetic
+	return task
+}
+
+func TestEmitHandler_WriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	h := &EmitHandler{Sink: &synth.WriterSink{W: &buf}}
+	out, err := h.Handle(context.Background(), emitTask(map[string]string{
+		"events": "failed_login,dns_query", "format": "cef", "count": "6", "rate_per_second": "1000",
+	}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	res := out.(*EmitResult)
+	if res.Emitted != 6 || res.ByKind["failed_login"] != 3 || res.ByKind["dns_query"] != 3 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines, want 6", len(lines))
+	}
+	for _, l := range lines {
+		if !strings.HasPrefix(l, "CEF:0|RTE-A|") || !strings.Contains(l, "cs2=task-emit") {
+			t.Errorf("unexpected record %s", l)
+		}
+	}
+}
+
+func TestEmitHandler_TaskSink(t *testing.T) {
+	var got []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	h := &EmitHandler{}
+	_, err := h.Handle(context.Background(), emitTask(map[string]string{
+		"events": "process_creation", "format": "evtx-xml", "count": "2", "sink": srv.URL,
+	}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if n := bytes.Count(got, []byte("<EventID>4688</EventID>")); n != 2 {
+		t.Fatalf("sink received %d process events: %s", n, got)
+	}
+}
+
+func TestEmitHandler_Invalid(t *testing.T) {
+	h := &EmitHandler{Sink: &synth.WriterSink{W: io.Discard}}
+	cases := []map[string]string{
+		{"events": "keylogger"},
+		{"format": "gelf"},
+		{"count": "0"},
+		{"rate_per_second": "5000"},
+		{"sink": "file:///tmp/out"},
+	}
+	for _, p := range cases {
+		if _, err := h.Handle(context.Background(), emitTask(p)); err == nil {
+			t.Errorf("expected %v to fail", p)
+		}
+	}
+	if _, err := (&EmitHandler{}).Handle(context.Background(), emitTask(nil)); err == nil {
+		t.Error("expected missing sink to fail")
+	}
+}
+
+func TestEmitHandler_Plan(t *testing.T) {
+	var buf bytes.Buffer
+	h := &EmitHandler{Sink: &synth.WriterSink{W: &buf}}
+	steps, err := h.Plan(context.Background(), emitTask(map[string]string{"events": "dns_query", "format": "cef", "count": "50"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].Action != rte.StepEmit || steps[0].Count != 50 || !strings.Contains(steps[0].Detail, "dns_query events as cef") {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("plan emitted %d bytes", buf.Len())
+	}
+	if _, err := (&EmitHandler{}).Plan(context.Background(), emitTask(nil)); err == nil {
+		t.Fatal("expected missing sink to fail its plan")
+	}
+}
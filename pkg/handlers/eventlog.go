@@ -0,0 +1,26 @@
+package handlers
+
+import "context"
+
+// DefaultEventLogSource is the source SystemLogSink writes under when Source
+// is empty.
+const DefaultEventLogSource = "rte"
+
+// SystemLogSink is a synth.EventSink that writes each record to the host's
+// native event log: syslog on Linux and macOS (which forwards it to the
+// unified log), and the Application event log on Windows. It lets an
+// emit_synthetic task exercise the host's own collection agent rather than
+// a pipeline endpoint.
+type SystemLogSink struct {
+	// Source is the syslog tag or Windows event source.
+	Source string
+}
+
+// Send implements synth.EventSink.
+func (s *SystemLogSink) Send(ctx context.Context, lines [][]byte) error {
+	source := s.Source
+	if source == "" {
+		source = DefaultEventLogSource
+	}
+	return writeEventLog(ctx, source, lines)
+}
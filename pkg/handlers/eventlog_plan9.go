@@ -0,0 +1,6 @@
+package handlers
+
+import "context"
+
+// writeEventLog is not supported: Plan 9 has no system event log.
+func writeEventLog(context.Context, string, [][]byte) error { return errUnsupportedOS }
@@ -0,0 +1,28 @@
+//go:build !windows && !plan9
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/syslog"
+)
+
+// writeEventLog sends lines to the local syslog daemon at notice level.
+func writeEventLog(ctx context.Context, source string, lines [][]byte) error {
+	w, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_USER, source)
+	if err != nil {
+		return fmt.Errorf("connect to syslog: %w", err)
+	}
+	defer w.Close()
+	for _, l := range lines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.Notice(string(bytes.TrimRight(l, "\n"))); err != nil {
+			return fmt.Errorf("write to syslog: %w", err)
+		}
+	}
+	return nil
+}
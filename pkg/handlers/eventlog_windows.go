@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procRegisterEventSource   = advapi32.NewProc("RegisterEventSourceW")
+	procReportEvent           = advapi32.NewProc("ReportEventW")
+	procDeregisterEventSource = advapi32.NewProc("DeregisterEventSource")
+)
+
+const (
+	eventlogInformationType = 4
+	syntheticEventID        = 1
+)
+
+// writeEventLog reports each line as an informational event in the
+// Application log. The source need not be registered; Event Viewer then
+// shows the line under a note that the message file is missing, and
+// forwarders read it all the same.
+func writeEventLog(ctx context.Context, source string, lines [][]byte) error {
+	h, _, err := procRegisterEventSource.Call(0, uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(source))))
+	if h == 0 {
+		return fmt.Errorf("register event source %s: %w", source, err)
+	}
+	defer procDeregisterEventSource.Call(h)
+	for _, l := range lines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		msg, err := syscall.UTF16PtrFromString(string(bytes.TrimRight(l, "\n")))
+		if err != nil {
+			return fmt.Errorf("encode event: %w", err)
+		}
+		ok, _, err := procReportEvent.Call(h, eventlogInformationType, 0, syntheticEventID, 0, 1, 0,
+			uintptr(unsafe.Pointer(&msg)), 0)
+		if ok == 0 {
+			return fmt.Errorf("report event: %w", err)
+		}
+	}
+	return nil
+}
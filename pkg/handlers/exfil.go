@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+	"github.com/codethor0/rte-a-reference/pkg/synth"
+)
+
+const (
+	defaultExfilSize    = 1 << 20
+	minExfilSize        = 1 << 10
+	maxExfilSize        = 50 << 20
+	maxExfilDocuments   = 20
+	defaultExfilTimeout = 60 * time.Second
+)
+
+// ExfilTransfer is the telemetry for one document.
+type ExfilTransfer struct {
+	Document   string    `json:"document"`
+	SHA256     string    `json:"sha256"`
+	Bytes      int       `json:"bytes"`
+	Chunks     int       `json:"chunks"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	// BytesPerSecond is the observed transfer rate.
+	BytesPerSecond int64  `json:"bytes_per_second"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+}
+
+// ExfilResult is the output of a simulate_exfil task.
+type ExfilResult struct {
+	Destination string          `json:"destination"`
+	Kind        string          `json:"kind"`
+	TotalBytes  int             `json:"total_bytes"`
+	Transfers   []ExfilTransfer `json:"transfers"`
+}
+
+// ExfilHandler executes TaskSimulateExfil: it generates watermarked
+// synthetic documents and uploads them to an engagement-owned destination so
+// DLP and egress monitoring can be tested. No file on the executing host is
+// ever read. The destination host must be registered as a host asset of the
+// task's engagement.
+//
+// Params: destination (http(s) URL), kind (csv_records or memo, default
+// csv_records), size_bytes (1KiB-50MiB, default 1MiB), documents (1-20,
+// default 1), and chunk_bytes (optional, at least 1KiB) to split each upload
+// into several requests.
+type ExfilHandler struct {
+	// Assets is the engagement asset registry used for the scope check.
+	// Required.
+	Assets *rte.AssetRegistry
+	// Identities sizes the identity set that populates documents.
+	Identities synth.IdentityConfig
+	// Timeout bounds each request. Defaults to 60s.
+	Timeout time.Duration
+	// Client is used for uploads. Under an rte.Guard a copy of it dials
+	// through the guard; uploads fail if its transport cannot.
+	Client *http.Client
+}
+
+// Handle implements rte.Handler.
+func (h *ExfilHandler) Handle(ctx context.Context, task rte.Task) (any, error) {
+	if task.Type != rte.TaskSimulateExfil {
+		return nil, fmt.Errorf("exfil handler cannot run %s tasks", task.Type)
+	}
+	xp, err := h.prepare(task)
+	if err != nil {
+		return nil, err
+	}
+	dest, kind, size, count, chunk := xp.dest, xp.kind, xp.size, xp.count, xp.chunk
+	ids, err := synth.NewIdentitySet(task.Engagement, h.Identities)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ExfilResult{Destination: dest, Kind: string(kind)}
+	for i := 0; i < count; i++ {
+		doc, err := synth.GenerateDocument(ids, synth.DocumentConfig{Kind: kind, Size: size, Name: fmt.Sprintf("%s-%02d", task.ID, i+1)})
+		if err != nil {
+			return res, err
+		}
+		tr := h.upload(ctx, task, dest, doc, chunk)
+		res.Transfers = append(res.Transfers, tr)
+		res.TotalBytes += tr.Bytes
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+	}
+	return res, nil
+}
+
+// Plan implements rte.Planner. The scope check runs as for Handle.
+func (h *ExfilHandler) Plan(_ context.Context, task rte.Task) ([]rte.PlannedStep, error) {
+	if task.Type != rte.TaskSimulateExfil {
+		return nil, fmt.Errorf("exfil handler cannot run %s tasks", task.Type)
+	}
+	xp, err := h.prepare(task)
+	if err != nil {
+		return nil, err
+	}
+	parts := (xp.size + xp.chunk - 1) / xp.chunk
+	return []rte.PlannedStep{
+		{
+			Action: rte.StepEmit,
+			Detail: fmt.Sprintf("generate a %d-byte watermarked synthetic %s document; no file on the agent's host is read", xp.size, xp.kind),
+			Count:  xp.count,
+		},
+		{
+			Action: rte.StepConnect,
+			Target: xp.dest,
+			Detail: fmt.Sprintf("upload each document in %d POST request(s) of at most %d bytes", parts, xp.chunk),
+			Count:  xp.count * parts,
+		},
+	}, nil
+}
+
+// exfilParams are an exfil task's checked params.
+type exfilParams struct {
+	dest               string
+	kind               synth.DocumentKind
+	size, count, chunk int
+}
+
+// prepare checks the task's params and that its destination is in scope.
+func (h *ExfilHandler) prepare(task rte.Task) (*exfilParams, error) {
+	if h.Assets == nil {
+		return nil, errors.New("exfil simulation requires an asset registry")
+	}
+	p := task.Params
+	dest, err := requireParam(p, "destination")
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(dest)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("destination must be an absolute http(s) URL, got %q", dest)
+	}
+	if a, ok := h.Assets.Lookup(task.Engagement, rte.AssetHost, u.Hostname()); !ok {
+		return nil, fmt.Errorf("destination %s is not a registered host of %s", u.Hostname(), task.Engagement)
+	} else if a.Owner != "" && a.Owner != task.Engagement {
+		return nil, fmt.Errorf("destination %s is owned by %s, not %s", u.Hostname(), a.Owner, task.Engagement)
+	}
+	xp := &exfilParams{dest: dest, kind: synth.DocumentKind(paramString(p, "kind", string(synth.DocumentRecords)))}
+	if xp.size, err = paramInt(p, "size_bytes", defaultExfilSize, minExfilSize, maxExfilSize); err != nil {
+		return nil, err
+	}
+	if xp.count, err = paramInt(p, "documents", 1, 1, maxExfilDocuments); err != nil {
+		return nil, err
+	}
+	if xp.chunk, err = paramInt(p, "chunk_bytes", xp.size, minExfilSize, maxExfilSize); err != nil {
+		return nil, err
+	}
+	return xp, nil
+}
+
+// upload sends doc in chunk-sized requests and records the transfer.
+func (h *ExfilHandler) upload(ctx context.Context, task rte.Task, dest string, doc *synth.Document, chunk int) ExfilTransfer {
+	tr := ExfilTransfer{Document: doc.Name, SHA256: doc.SHA256, StartedAt: time.Now().UTC(), Status: "completed"}
+	client := runClient(ctx, h.timeout())
+	if h.Client != nil {
+		var err error
+		if client, err = guardClient(ctx, h.Client); err != nil {
+			tr.Status, tr.Error = "failed", err.Error()
+			return tr
+		}
+	}
+	total := len(doc.Content)
+	for off := 0; off < total; off += chunk {
+		end := min(off+chunk, total)
+		if err := h.post(ctx, client, task, dest, doc, off, end); err != nil {
+			tr.Status, tr.Error = "failed", err.Error()
+			break
+		}
+		tr.Bytes += end - off
+		tr.Chunks++
+	}
+	elapsed := time.Since(tr.StartedAt)
+	tr.DurationMS = elapsed.Milliseconds()
+	if elapsed > 0 {
+		tr.BytesPerSecond = int64(float64(tr.Bytes) / elapsed.Seconds())
+	}
+	return tr
+}
+
+func (h *ExfilHandler) post(ctx context.Context, client *http.Client, task rte.Task, dest string, doc *synth.Document, off, end int) error {
+	rctx, cancel := context.WithTimeout(ctx, h.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(rctx, http.MethodPost, dest, bytes.NewReader(doc.Content[off:end]))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("User-Agent", "rte-a-simulate-exfil")
+	req.Header.Set("X-RTE-A-Synthetic", synth.DocumentWatermark)
+	req.Header.Set("X-RTE-A-Task", task.ID)
+	req.Header.Set("X-RTE-A-Document", doc.Name)
+	req.Header.Set("X-RTE-A-Range", strconv.Itoa(off)+"-"+strconv.Itoa(end-1)+"/"+strconv.Itoa(len(doc.Content)))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHTTPResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("destination returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (h *ExfilHandler) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return defaultExfilTimeout
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+	"github.com/codethor0/rte-a-reference/pkg/synth"
+)
+
+func exfilTask(params map[string]string) rte.Task {
+	task := loginTask(params)
+	task.ID = "task-exfil"
+	task.Type = rte.TaskSimulateExfil
+	return task
+}
+
+func exfilAssets(t *testing.T, host string) *rte.AssetRegistry {
+	t.Helper()
+	r := rte.NewAssetRegistry()
+	if err := r.Add("eng-2026-q1", rte.Asset{Kind: rte.AssetHost, Name: host, Owner: "eng-2026-q1"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	_ = r.Add("eng-2026-q1", rte.Asset{Kind: rte.AssetHost, Name: "shared.example", Owner: "eng-other"})
+	return r
+}
+
+func TestExfilHandler_ChunkedUpload(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]*bytes.Buffer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-RTE-A-Synthetic") != synth.DocumentWatermark {
+			http.Error(w, "unmarked", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		name := r.Header.Get("X-RTE-A-Document")
+		if received[name] == nil {
+			received[name] = &bytes.Buffer{}
+		}
+		_, _ = io.Copy(received[name], r.Body)
+	}))
+	defer srv.Close()
+
+	h := &ExfilHandler{Assets: exfilAssets(t, "127.0.0.1")}
+	out, err := h.Handle(context.Background(), exfilTask(map[string]string{
+		"destination": srv.URL + "/upload", "size_bytes": "5000", "chunk_bytes": "2048", "documents": "2",
+	}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	res := out.(*ExfilResult)
+	if res.TotalBytes != 10000 || len(res.Transfers) != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	for _, tr := range res.Transfers {
+		if tr.Status != "completed" || tr.Chunks != 3 {
+			t.Errorf("unexpected transfer %+v", tr)
+		}
+		sum := sha256.Sum256(received[tr.Document].Bytes())
+		if hex.EncodeToString(sum[:]) != tr.SHA256 {
+			t.Errorf("%s: destination content does not match reported hash", tr.Document)
+		}
+	}
+}
+
+func TestExfilHandler_FailedTransfer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "blocked by proxy", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	h := &ExfilHandler{Assets: exfilAssets(t, "127.0.0.1")}
+	out, err := h.Handle(context.Background(), exfilTask(map[string]string{"destination": srv.URL, "size_bytes": "2048"}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	tr := out.(*ExfilResult).Transfers[0]
+	if tr.Status != "failed" || tr.Bytes != 0 || tr.Error == "" {
+		t.Fatalf("expected blocked transfer, got %+v", tr)
+	}
+}
+
+func TestExfilHandler_Scope(t *testing.T) {
+	h := &ExfilHandler{Assets: exfilAssets(t, "drop.eng-2026-q1.example")}
+	for _, p := range []map[string]string{
+		{"destination": "https://attacker.example/upload"},
+		{"destination": "https://shared.example/upload"},
+		{"destination": "ftp://drop.eng-2026-q1.example/"},
+		{"destination": "https://drop.eng-2026-q1.example/", "size_bytes": "10"},
+		{"destination": "https://drop.eng-2026-q1.example/", "kind": "pdf"},
+		{"destination": "https://drop.eng-2026-q1.example/", "documents": "50"},
+	} {
+		if _, err := h.Handle(context.Background(), exfilTask(p)); err == nil {
+			t.Errorf("expected %v to fail", p)
+		}
+	}
+	if _, err := (&ExfilHandler{}).Handle(context.Background(), exfilTask(nil)); err == nil {
+		t.Error("expected missing asset registry to fail")
+	}
+}
+
+func TestExfilHandler_Plan(t *testing.T) {
+	h := &ExfilHandler{Assets: exfilAssets(t, "drop.test.example")}
+	steps, err := h.Plan(context.Background(), exfilTask(map[string]string{
+		"destination": "https://drop.test.example/up", "size_bytes": "4096", "chunk_bytes": "1024", "documents": "2",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].Count != 2 || steps[1].Count != 8 || steps[1].Target != "https://drop.test.example/up" {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if _, err := h.Plan(context.Background(), exfilTask(map[string]string{"destination": "https://shared.example/up"})); err == nil {
+		t.Fatal("expected out-of-scope destination to fail its plan")
+	}
+}
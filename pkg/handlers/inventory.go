@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+const (
+	maxProcesses = 5000
+	maxPackages  = 20000
+)
+
+// Collector gathers one read-only section of an inventory report.
+type Collector interface {
+	Name() string
+	Collect(ctx context.Context) (any, error)
+}
+
+// InventoryReport is the output of an inventory task. Sections are keyed by
+// collector name; a collector that fails leaves its error in Errors rather
+// than failing the whole report. Sign the enclosing TaskResult with
+// rte.SignResult before handing it to the reporting pipeline.
+type InventoryReport struct {
+	Hostname    string            `json:"hostname"`
+	CollectedAt time.Time         `json:"collected_at"`
+	Sections    map[string]any    `json:"sections"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// InventoryHandler executes TaskInventory by running its collectors. The
+// "collectors" param selects a comma-separated subset by name.
+type InventoryHandler struct {
+	Collectors []Collector
+}
+
+// DefaultCollectors returns the OS, port, process, package, and service
+// collectors reading from the real filesystem root. Collectors a platform
+// does not support report errUnsupportedOS in the report's Errors.
+func DefaultCollectors() []Collector {
+	return []Collector{
+		OSCollector{Root: "/"},
+		PortCollector{Root: "/"},
+		ProcessCollector{Root: "/"},
+		PackageCollector{Root: "/"},
+		ServiceCollector{Root: "/"},
+	}
+}
+
+// Handle implements rte.Handler.
+func (h *InventoryHandler) Handle(ctx context.Context, task rte.Task) (any, error) {
+	if task.Type != rte.TaskInventory {
+		return nil, fmt.Errorf("inventory handler cannot run %s tasks", task.Type)
+	}
+	selected, err := h.selected(task)
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	rep := &InventoryReport{
+		Hostname:    host,
+		CollectedAt: time.Now().UTC(),
+		Sections:    make(map[string]any, len(selected)),
+	}
+	for _, c := range selected {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		out, err := c.Collect(ctx)
+		if err != nil {
+			if rep.Errors == nil {
+				rep.Errors = make(map[string]string)
+			}
+			rep.Errors[c.Name()] = err.Error()
+			continue
+		}
+		rep.Sections[c.Name()] = out
+	}
+	return rep, nil
+}
+
+// Plan implements rte.Planner: one run step per selected collector.
+func (h *InventoryHandler) Plan(_ context.Context, task rte.Task) ([]rte.PlannedStep, error) {
+	if task.Type != rte.TaskInventory {
+		return nil, fmt.Errorf("inventory handler cannot run %s tasks", task.Type)
+	}
+	selected, err := h.selected(task)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]rte.PlannedStep, len(selected))
+	for i, c := range selected {
+		detail := "run the collector; it only reads"
+		if d, ok := c.(describer); ok {
+			detail = d.Describe()
+		}
+		steps[i] = rte.PlannedStep{Action: rte.StepRun, Target: "collector " + c.Name(), Detail: detail}
+	}
+	return steps, nil
+}
+
+// describer is implemented by collectors that can say what they read.
+type describer interface {
+	Describe() string
+}
+
+// selected returns the collectors the task's "collectors" param names, or
+// all of them.
+func (h *InventoryHandler) selected(task rte.Task) ([]Collector, error) {
+	collectors := h.Collectors
+	if collectors == nil {
+		collectors = DefaultCollectors()
+	}
+	names := task.Params["collectors"]
+	if names == "" {
+		return collectors, nil
+	}
+	byName := make(map[string]Collector, len(collectors))
+	for _, c := range collectors {
+		byName[c.Name()] = c
+	}
+	var selected []Collector
+	for _, n := range strings.Split(names, ",") {
+		c, ok := byName[strings.TrimSpace(n)]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector: %s", n)
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+var errUnsupportedOS = fmt.Errorf("collector not supported on %s", runtime.GOOS)
+
+// OSInfo describes the operating system.
+type OSInfo struct {
+	GOOS    string `json:"goos"`
+	Arch    string `json:"arch"`
+	Name    string `json:"name,omitempty"`
+	Kernel  string `json:"kernel,omitempty"`
+	Machine string `json:"machine_id,omitempty"`
+}
+
+// OSCollector reports OS release and kernel details.
+type OSCollector struct{ Root string }
+
+// Name implements Collector.
+func (OSCollector) Name() string { return "os" }
+
+// Describe says what Collect reads, for a dry-run plan.
+func (c OSCollector) Describe() string { return hostDescribe("os", c.Root) }
+
+// Collect implements Collector.
+func (c OSCollector) Collect(context.Context) (any, error) {
+	info := OSInfo{GOOS: runtime.GOOS, Arch: runtime.GOARCH}
+	hostOS(c.Root, &info)
+	return info, nil
+}
+
+// ListeningPort is a socket accepting connections (TCP) or bound (UDP).
+type ListeningPort struct {
+	Proto   string `json:"proto"`
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+}
+
+// PortCollector reports listening sockets. Only Linux, where it reads
+// /proc/net, is supported.
+type PortCollector struct{ Root string }
+
+// Name implements Collector.
+func (PortCollector) Name() string { return "ports" }
+
+// Describe says what Collect reads, for a dry-run plan.
+func (c PortCollector) Describe() string { return hostDescribe("ports", c.Root) }
+
+// Collect implements Collector.
+func (c PortCollector) Collect(context.Context) (any, error) {
+	out, err := hostPorts(c.Root)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		return out[i].Proto < out[j].Proto
+	})
+	return out, nil
+}
+
+// parseProcNet reads a /proc/net/{tcp,udp}[6] table. TCP rows are kept only
+// in LISTEN state (0A); UDP rows are kept when unconnected (07).
+func parseProcNet(path, proto string) ([]ListeningPort, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	want := "0A"
+	if strings.HasPrefix(proto, "udp") {
+		want = "07"
+	}
+	var out []ListeningPort
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 || fields[3] != want {
+			continue
+		}
+		addr, port, err := parseProcAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		out = append(out, ListeningPort{Proto: strings.TrimSuffix(proto, "6"), Address: addr.String(), Port: port})
+	}
+	return out, sc.Err()
+}
+
+// parseProcAddr decodes the kernel's little-endian-per-word hex address.
+func parseProcAddr(s string) (netip.Addr, int, error) {
+	hexAddr, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return netip.Addr{}, 0, fmt.Errorf("malformed address %q", s)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return netip.Addr{}, 0, fmt.Errorf("malformed port %q", hexPort)
+	}
+	raw, err := hex.DecodeString(hexAddr)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return netip.Addr{}, 0, fmt.Errorf("malformed address %q", hexAddr)
+	}
+	for i := 0; i < len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	addr, _ := netip.AddrFromSlice(raw)
+	return addr.Unmap(), int(port), nil
+}
+
+// Process is a running process.
+type Process struct {
+	PID  int    `json:"pid"`
+	PPID int    `json:"ppid"`
+	Name string `json:"name"`
+	UID  int    `json:"uid"`
+}
+
+// ProcessCollector reports running processes: from /proc on Linux, ps on
+// macOS, and a process snapshot on Windows, where UID is -1. Command lines
+// are deliberately not collected, since they can carry secrets.
+type ProcessCollector struct{ Root string }
+
+// Name implements Collector.
+func (ProcessCollector) Name() string { return "processes" }
+
+// Describe says what Collect reads, for a dry-run plan.
+func (c ProcessCollector) Describe() string {
+	return hostDescribe("processes", c.Root) + "; command lines are not read"
+}
+
+// Collect implements Collector.
+func (c ProcessCollector) Collect(ctx context.Context) (any, error) {
+	return hostProcesses(ctx, c.Root)
+}
+
+// Package is an installed software package.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Manager string `json:"manager"`
+}
+
+// PackageCollector reports installed packages from the dpkg or apk
+// databases. Only Linux is supported.
+type PackageCollector struct{ Root string }
+
+// Name implements Collector.
+func (PackageCollector) Name() string { return "packages" }
+
+// Describe says what Collect reads, for a dry-run plan.
+func (c PackageCollector) Describe() string { return hostDescribe("packages", c.Root) }
+
+// Collect implements Collector.
+func (c PackageCollector) Collect(context.Context) (any, error) {
+	return hostPackages(c.Root)
+}
+
+// parsePackageDB reads a stanza-per-package database (dpkg status or apk
+// installed). If statusKey is set, only "install ok installed" stanzas count.
+func parsePackageDB(path, manager, nameKey, versionKey, statusKey string) ([]Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []Package
+	var cur Package
+	var installed bool
+	flush := func() {
+		if cur.Name != "" && (statusKey == "" || installed) && len(out) < maxPackages {
+			cur.Manager = manager
+			out = append(out, cur)
+		}
+		cur, installed = Package{}, false
+	}
+	sep := ": "
+	if manager == "apk" {
+		sep = ":"
+	}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		key, val, ok := strings.Cut(line, sep)
+		if !ok {
+			continue
+		}
+		switch key {
+		case nameKey:
+			cur.Name = val
+		case versionKey:
+			cur.Version = val
+		case statusKey:
+			installed = val == "install ok installed"
+		}
+	}
+	flush()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, sc.Err()
+}
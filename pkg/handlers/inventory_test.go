@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", rel, err)
+	}
+}
+
+// fakeRoot builds a minimal Linux filesystem view for the collectors.
+func fakeRoot(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("collectors read Linux /proc layouts")
+	}
+	root := t.TempDir()
+	writeFile(t, root, "etc/os-release", "NAME=\"Ubuntu\"\nPRETTY_NAME=\"Ubuntu 22.04.4 LTS\"\n")
+	writeFile(t, root, "proc/sys/kernel/osrelease", "6.5.0-test\n")
+	writeFile(t, root, "proc/net/tcp", "  sl  local_address rem_address   st\n"+
+		"   0: 0100007F:0016 00000000:0000 0A 00000000:00000000\n"+
+		"   1: 0100007F:C350 0100007F:0016 01 00000000:00000000\n")
+	writeFile(t, root, "proc/net/udp", "  sl  local_address rem_address   st\n"+
+		"   0: 00000000:0035 00000000:0000 07 00000000:00000000\n")
+	writeFile(t, root, "proc/1/status", "Name:\tinit\nPPid:\t0\nUid:\t0\t0\t0\t0\n")
+	writeFile(t, root, "proc/42/status", "Name:\tsshd\nPPid:\t1\nUid:\t0\t0\t0\t0\n")
+	writeFile(t, root, "proc/self/status", "Name:\tignored\n")
+	writeFile(t, root, "var/lib/dpkg/status",
+		"Package: openssl\nStatus: install ok installed\nVersion: 3.0.2\n\n"+
+			"Package: removed-pkg\nStatus: deinstall ok config-files\nVersion: 1.0\n\n"+
+			"Package: bash\nStatus: install ok installed\nVersion: 5.1\n")
+	writeFile(t, root, "lib/systemd/system/ssh.service", "[Unit]\n")
+	return root
+}
+
+func inventoryTask(params map[string]string) rte.Task {
+	task := loginTask(params)
+	task.ID = "task-inventory"
+	task.Type = rte.TaskInventory
+	return task
+}
+
+func TestInventoryHandler_Collects(t *testing.T) {
+	root := fakeRoot(t)
+	h := &InventoryHandler{Collectors: []Collector{
+		OSCollector{Root: root}, PortCollector{Root: root}, ProcessCollector{Root: root}, PackageCollector{Root: root},
+		ServiceCollector{Root: root},
+	}}
+	out, err := h.Handle(context.Background(), inventoryTask(nil))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	rep := out.(*InventoryReport)
+	if len(rep.Errors) != 0 {
+		t.Fatalf("unexpected collector errors: %v", rep.Errors)
+	}
+	if osInfo := rep.Sections["os"].(OSInfo); osInfo.Name != "Ubuntu 22.04.4 LTS" || osInfo.Kernel != "6.5.0-test" {
+		t.Errorf("os: got %+v", osInfo)
+	}
+	ports := rep.Sections["ports"].([]ListeningPort)
+	if len(ports) != 2 || ports[0] != (ListeningPort{Proto: "tcp", Address: "127.0.0.1", Port: 22}) || ports[1].Port != 53 {
+		t.Errorf("ports: got %+v", ports)
+	}
+	procs := rep.Sections["processes"].([]Process)
+	if len(procs) != 2 || procs[1].Name != "sshd" || procs[1].PPID != 1 {
+		t.Errorf("processes: got %+v", procs)
+	}
+	pkgs := rep.Sections["packages"].([]Package)
+	if len(pkgs) != 2 || pkgs[0].Name != "bash" || pkgs[1].Version != "3.0.2" {
+		t.Errorf("packages: got %+v", pkgs)
+	}
+	svcs := rep.Sections["services"].([]Service)
+	if len(svcs) != 1 || svcs[0] != (Service{Name: "ssh", Manager: "systemd", Startup: StartupManual}) {
+		t.Errorf("services: got %+v", svcs)
+	}
+}
+
+type failingCollector struct{}
+
+func (failingCollector) Name() string                         { return "broken" }
+func (failingCollector) Collect(context.Context) (any, error) { return nil, errors.New("boom") }
+
+func TestInventoryHandler_SelectionAndErrors(t *testing.T) {
+	root := fakeRoot(t)
+	h := &InventoryHandler{Collectors: []Collector{OSCollector{Root: root}, failingCollector{}}}
+	out, err := h.Handle(context.Background(), inventoryTask(map[string]string{"collectors": "broken"}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	rep := out.(*InventoryReport)
+	if len(rep.Sections) != 0 || rep.Errors["broken"] != "boom" {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if _, err := h.Handle(context.Background(), inventoryTask(map[string]string{"collectors": "os,shadow"})); err == nil {
+		t.Fatal("expected unknown collector to fail")
+	}
+}
+
+func TestInventoryHandler_SignedReportThroughExecutor(t *testing.T) {
+	root := fakeRoot(t)
+	pub, priv, err := rte.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("generate key pair: %v", err)
+	}
+	exec := rte.NewExecutor()
+	if err := exec.Register(rte.TaskInventory, &InventoryHandler{Collectors: []Collector{PackageCollector{Root: root}}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	task := inventoryTask(nil)
+	task.State = rte.StatePending
+	task.CreatedAt = time.Now().UTC()
+	st, err := rte.SignTask(task, priv, pub)
+	if err != nil {
+		t.Fatalf("SignTask: %v", err)
+	}
+	res, err := exec.Execute(context.Background(), st)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	sr, err := rte.SignResult(*res, priv, pub)
+	if err != nil {
+		t.Fatalf("SignResult: %v", err)
+	}
+	if err := rte.VerifyResult(sr); err != nil {
+		t.Fatalf("VerifyResult: %v", err)
+	}
+	var rep InventoryReport
+	if err := json.Unmarshal(sr.Result.Output, &rep); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if _, ok := rep.Sections["packages"]; !ok {
+		t.Fatalf("report missing packages section: %s", sr.Result.Output)
+	}
+}
+
+func TestParseProcAddr_IPv6(t *testing.T) {
+	addr, port, err := parseProcAddr("00000000000000000000000001000000:1F90")
+	if err != nil {
+		t.Fatalf("parseProcAddr: %v", err)
+	}
+	if addr.String() != "::1" || port != 8080 {
+		t.Fatalf("got %s:%d", addr, port)
+	}
+}
+
+func TestInventoryHandler_Plan(t *testing.T) {
+	h := &InventoryHandler{Collectors: []Collector{ProcessCollector{Root: "/host"}, failingCollector{}}}
+	steps, err := h.Plan(context.Background(), inventoryTask(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].Target != "collector processes" || !strings.HasPrefix(steps[0].Detail, hostDescribe("processes", "/host")) ||
+		steps[1].Detail != "run the collector; it only reads" {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if _, err := h.Plan(context.Background(), inventoryTask(map[string]string{"collectors": "shadow"})); err == nil {
+		t.Fatal("expected unknown collector to fail its plan")
+	}
+}
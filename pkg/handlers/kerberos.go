@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Kerberos message and error codes used by the AS-REQ simulation (RFC 4120).
+const (
+	krbPVNO                 = 5
+	krbMsgASReq             = 10
+	krbMsgASRep             = 11
+	krbMsgError             = 30
+	krbNTPrincipal          = 1
+	krbNTSrvInst            = 2
+	krbErrPrincipalUnknown  = 6
+	krbErrPreauthRequired   = 25
+	krbErrPreauthFailed     = 24
+	krbErrClientRevoked     = 18
+	tagGeneralString        = 27
+	etypeAES256CTSHMACSHA1  = 18
+	etypeAES128CTSHMACSHA1  = 17
+	maxKerberosReplyBytes   = 64 * 1024
+	kerberosForwardableFlag = 0x40
+)
+
+type principalName struct {
+	NameType   int             `asn1:"explicit,tag:0"`
+	NameString []asn1.RawValue `asn1:"explicit,tag:1"`
+}
+
+type kdcReqBody struct {
+	KDCOptions asn1.BitString `asn1:"explicit,tag:0"`
+	CName      principalName  `asn1:"explicit,tag:1"`
+	Realm      asn1.RawValue  // [2] GeneralString; see explicitRealm
+	SName      principalName  `asn1:"explicit,tag:3"`
+	Till       time.Time      `asn1:"generalized,explicit,tag:5"`
+	Nonce      int            `asn1:"explicit,tag:7"`
+	EType      []int          `asn1:"explicit,tag:8"`
+}
+
+type kdcReq struct {
+	PVNO    int        `asn1:"explicit,tag:1"`
+	MsgType int        `asn1:"explicit,tag:2"`
+	ReqBody kdcReqBody `asn1:"explicit,tag:4"`
+}
+
+func generalString(s string) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: tagGeneralString, Bytes: []byte(s)}
+}
+
+// explicitRealm wraps a realm in its [2] tag by hand, because encoding/asn1
+// writes RawValue fields verbatim and ignores struct tag options for them.
+func explicitRealm(realm string) (asn1.RawValue, error) {
+	inner, err := asn1.Marshal(generalString(realm))
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: inner}, nil
+}
+
+func principal(nameType int, parts ...string) principalName {
+	p := principalName{NameType: nameType}
+	for _, s := range parts {
+		p.NameString = append(p.NameString, generalString(s))
+	}
+	return p
+}
+
+// buildASReq encodes an AS-REQ for username@realm carrying no PA-DATA, so a
+// KDC that requires pre-authentication answers without checking a password.
+func buildASReq(realm, username string, now time.Time) ([]byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1<<31-1))
+	if err != nil {
+		return nil, err
+	}
+	realm = strings.ToUpper(realm)
+	realmField, err := explicitRealm(realm)
+	if err != nil {
+		return nil, err
+	}
+	req := kdcReq{
+		PVNO:    krbPVNO,
+		MsgType: krbMsgASReq,
+		ReqBody: kdcReqBody{
+			KDCOptions: asn1.BitString{Bytes: []byte{kerberosForwardableFlag, 0, 0, 0}, BitLength: 32},
+			CName:      principal(krbNTPrincipal, username),
+			Realm:      realmField,
+			SName:      principal(krbNTSrvInst, "krbtgt", realm),
+			Till:       now.Add(10 * time.Hour).UTC().Truncate(time.Second),
+			Nonce:      int(n.Int64()),
+			EType:      []int{etypeAES256CTSHMACSHA1, etypeAES128CTSHMACSHA1},
+		},
+	}
+	body, err := asn1.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal AS-REQ: %w", err)
+	}
+	return asn1.Marshal(asn1.RawValue{Class: asn1.ClassApplication, Tag: krbMsgASReq, IsCompound: true, Bytes: body})
+}
+
+// kerberosASReq sends one AS-REQ over TCP and classifies the KDC reply.
+func kerberosASReq(ctx context.Context, timeout time.Duration, target, realm, username string) (string, string, error) {
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, "88")
+	}
+	msg, err := buildASReq(realm, username, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	dctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	d, _ := rte.RunDialer(ctx)
+	conn, err := d.DialContext(dctx, "tcp", target)
+	if err != nil {
+		return "", "", err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	frame := make([]byte, 4+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	copy(frame[4:], msg)
+	if _, err := conn.Write(frame); err != nil {
+		return "", "", fmt.Errorf("send AS-REQ: %w", err)
+	}
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+		return "", "", fmt.Errorf("read reply length: %w", err)
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n == 0 || n > maxKerberosReplyBytes {
+		return "", "", fmt.Errorf("unexpected kerberos reply length %d", n)
+	}
+	reply := make([]byte, n)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return "", "", fmt.Errorf("read reply: %w", err)
+	}
+	return classifyKerberosReply(reply)
+}
+
+// classifyKerberosReply maps an AS-REP or KRB-ERROR to an attempt outcome.
+func classifyKerberosReply(reply []byte) (string, string, error) {
+	var outer asn1.RawValue
+	if _, err := asn1.Unmarshal(reply, &outer); err != nil {
+		return "", "", fmt.Errorf("decode kerberos reply: %w", err)
+	}
+	if outer.Class != asn1.ClassApplication {
+		return "", "", errors.New("kerberos reply is not an application message")
+	}
+	switch outer.Tag {
+	case krbMsgASRep:
+		// Only reachable for accounts with pre-auth disabled (AS-REP roastable).
+		return "preauth_not_required", "KDC returned AS-REP", nil
+	case krbMsgError:
+	default:
+		return "", "", fmt.Errorf("unexpected kerberos message type %d", outer.Tag)
+	}
+	code, err := krbErrorCode(outer.Bytes)
+	if err != nil {
+		return "", "", err
+	}
+	detail := fmt.Sprintf("KRB-ERROR %d", code)
+	switch code {
+	case krbErrPreauthRequired:
+		return "preauth_required", detail, nil
+	case krbErrPrincipalUnknown:
+		return "principal_unknown", detail, nil
+	case krbErrPreauthFailed:
+		return "rejected", detail, nil
+	case krbErrClientRevoked:
+		return "account_disabled", detail, nil
+	default:
+		return "kdc_error", detail, nil
+	}
+}
+
+// krbErrorCode extracts error-code [6] from a KRB-ERROR sequence.
+func krbErrorCode(seq []byte) (int, error) {
+	var s asn1.RawValue
+	if _, err := asn1.Unmarshal(seq, &s); err != nil {
+		return 0, fmt.Errorf("decode KRB-ERROR: %w", err)
+	}
+	rest := s.Bytes
+	for len(rest) > 0 {
+		var field asn1.RawValue
+		var err error
+		rest, err = asn1.Unmarshal(rest, &field)
+		if err != nil {
+			return 0, fmt.Errorf("decode KRB-ERROR field: %w", err)
+		}
+		if field.Class == asn1.ClassContextSpecific && field.Tag == 6 {
+			var code int
+			if _, err := asn1.Unmarshal(field.Bytes, &code); err != nil {
+				return 0, fmt.Errorf("decode error-code: %w", err)
+			}
+			return code, nil
+		}
+	}
+	return 0, errors.New("KRB-ERROR has no error-code")
+}
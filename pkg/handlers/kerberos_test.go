@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type krbError struct {
+	PVNO      int       `asn1:"explicit,tag:0"`
+	MsgType   int       `asn1:"explicit,tag:1"`
+	STime     time.Time `asn1:"generalized,explicit,tag:4"`
+	SUSec     int       `asn1:"explicit,tag:5"`
+	ErrorCode int       `asn1:"explicit,tag:6"`
+}
+
+func encodeKRBError(t *testing.T, code int) []byte {
+	t.Helper()
+	body, err := asn1.Marshal(krbError{PVNO: krbPVNO, MsgType: krbMsgError, STime: time.Now().UTC().Truncate(time.Second), ErrorCode: code})
+	if err != nil {
+		t.Fatalf("marshal KRB-ERROR: %v", err)
+	}
+	msg, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassApplication, Tag: krbMsgError, IsCompound: true, Bytes: body})
+	if err != nil {
+		t.Fatalf("wrap KRB-ERROR: %v", err)
+	}
+	return msg
+}
+
+func TestBuildASReq_Decodes(t *testing.T) {
+	msg, err := buildASReq("corp.example", "rte-a-test", time.Now())
+	if err != nil {
+		t.Fatalf("buildASReq: %v", err)
+	}
+	var outer asn1.RawValue
+	if _, err := asn1.Unmarshal(msg, &outer); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if outer.Class != asn1.ClassApplication || outer.Tag != krbMsgASReq {
+		t.Fatalf("got class %d tag %d", outer.Class, outer.Tag)
+	}
+	var req kdcReq
+	if _, err := asn1.Unmarshal(outer.Bytes, &req); err != nil {
+		t.Fatalf("unmarshal body: %v", err)
+	}
+	var realm asn1.RawValue
+	if _, err := asn1.Unmarshal(req.ReqBody.Realm.Bytes, &realm); err != nil {
+		t.Fatalf("unmarshal realm: %v", err)
+	}
+	if realm.Tag != tagGeneralString || string(realm.Bytes) != "CORP.EXAMPLE" {
+		t.Errorf("realm: got tag %d %q", realm.Tag, realm.Bytes)
+	}
+}
+
+func TestClassifyKerberosReply(t *testing.T) {
+	cases := map[int]string{
+		krbErrPreauthRequired:  "preauth_required",
+		krbErrPrincipalUnknown: "principal_unknown",
+		68:                     "kdc_error",
+	}
+	for code, want := range cases {
+		got, _, err := classifyKerberosReply(encodeKRBError(t, code))
+		if err != nil {
+			t.Fatalf("classify %d: %v", code, err)
+		}
+		if got != want {
+			t.Errorf("code %d: got %q, want %q", code, got, want)
+		}
+	}
+	if _, _, err := classifyKerberosReply([]byte{0x30, 0x00}); err == nil {
+		t.Error("expected non-application reply to fail")
+	}
+}
+
+func TestKerberosASReq_FakeKDC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	reply := encodeKRBError(t, krbErrPreauthRequired)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var n [4]byte
+		if _, err := io.ReadFull(c, n[:]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(c, make([]byte, binary.BigEndian.Uint32(n[:]))); err != nil {
+			return
+		}
+		binary.BigEndian.PutUint32(n[:], uint32(len(reply)))
+		_, _ = c.Write(append(n[:], reply...))
+	}()
+	outcome, detail, err := kerberosASReq(context.Background(), time.Second, ln.Addr().String(), "corp.example", "rte-a-test")
+	if err != nil {
+		t.Fatalf("kerberosASReq: %v", err)
+	}
+	if outcome != "preauth_required" {
+		t.Fatalf("outcome: got %q (%s)", outcome, detail)
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Login protocols understood by LoginHandler.
+const (
+	ProtocolSSH      = "ssh"
+	ProtocolHTTP     = "http"
+	ProtocolKerberos = "kerberos"
+)
+
+const (
+	defaultLoginUser     = "rte-a-test"
+	syntheticPassword    = "rte-a-simulated-invalid"
+	defaultLoginRate     = 6
+	maxLoginRate         = 60
+	maxLoginAttempts     = 20
+	defaultLoginTimeout  = 10 * time.Second
+	maxBannerBytes       = 255
+	maxHTTPResponseBytes = 4096
+)
+
+// LoginAttempt is the captured outcome of one authentication attempt.
+type LoginAttempt struct {
+	At         time.Time `json:"at"`
+	Outcome    string    `json:"outcome"`
+	Detail     string    `json:"detail,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// LoginResult is the output of a simulate_login task.
+type LoginResult struct {
+	Protocol string         `json:"protocol"`
+	Target   string         `json:"target"`
+	Username string         `json:"username"`
+	Attempts []LoginAttempt `json:"attempts"`
+}
+
+// LoginHandler executes TaskSimulateLogin. It performs benign, rate-limited
+// authentication attempts against the task's target:
+//
+//   - ssh: connects and records the server banner; no credentials are sent.
+//   - http: POSTs a username/password form to a test endpoint URL.
+//   - kerberos: sends an AS-REQ without pre-authentication and records the
+//     KDC's answer (normally PREAUTH_REQUIRED).
+//
+// Params: protocol, target, username, password, realm (kerberos), attempts
+// (1-20, default 1), and rate_per_minute (1-60, default 6).
+type LoginHandler struct {
+	// Timeout bounds each attempt. Defaults to 10s.
+	Timeout time.Duration
+	// Client is used for HTTP attempts. Defaults to a client with Timeout
+	// that does not follow redirects. Under an rte.Guard a copy of it
+	// dials through the guard; attempts fail if its transport cannot.
+	Client *http.Client
+}
+
+// Handle implements rte.Handler.
+func (h *LoginHandler) Handle(ctx context.Context, task rte.Task) (any, error) {
+	if task.Type != rte.TaskSimulateLogin {
+		return nil, fmt.Errorf("login handler cannot run %s tasks", task.Type)
+	}
+	lp, err := parseLoginParams(task.Params)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := h.attemptFunc(task.ID, lp.protocol, lp.target, task.Params)
+	if err != nil {
+		return nil, err
+	}
+	username, password := lp.username, paramString(task.Params, "password", syntheticPassword)
+
+	res := &LoginResult{Protocol: lp.protocol, Target: lp.target, Username: username}
+	interval := time.Minute / time.Duration(lp.rate)
+	var last time.Time
+	for i := 0; i < lp.attempts; i++ {
+		if i > 0 && !pace(ctx.Done(), last, interval) {
+			return res, ctx.Err()
+		}
+		last = time.Now()
+		actx, cancel := context.WithTimeout(ctx, h.timeout())
+		outcome, detail, err := attempt(actx, username, password)
+		cancel()
+		if err != nil {
+			outcome, detail = "error", err.Error()
+		}
+		res.Attempts = append(res.Attempts, LoginAttempt{
+			At:         last.UTC(),
+			Outcome:    outcome,
+			Detail:     detail,
+			DurationMS: time.Since(last).Milliseconds(),
+		})
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+	}
+	return res, nil
+}
+
+// Plan implements rte.Planner.
+func (h *LoginHandler) Plan(_ context.Context, task rte.Task) ([]rte.PlannedStep, error) {
+	if task.Type != rte.TaskSimulateLogin {
+		return nil, fmt.Errorf("login handler cannot run %s tasks", task.Type)
+	}
+	lp, err := parseLoginParams(task.Params)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.attemptFunc(task.ID, lp.protocol, lp.target, task.Params); err != nil {
+		return nil, err
+	}
+	return []rte.PlannedStep{{
+		Action: rte.StepConnect,
+		Target: lp.target,
+		Detail: fmt.Sprintf("%s, at most %d per minute", describeAttempt(lp.protocol, lp.username, task.Params), lp.rate),
+		Count:  lp.attempts,
+	}}, nil
+}
+
+// loginParams are the params of a simulate_login task.
+type loginParams struct {
+	protocol, target, username string
+	attempts, rate             int
+}
+
+func parseLoginParams(p map[string]string) (loginParams, error) {
+	var lp loginParams
+	var err error
+	if lp.protocol, err = requireParam(p, "protocol"); err != nil {
+		return lp, err
+	}
+	if lp.target, err = requireParam(p, "target"); err != nil {
+		return lp, err
+	}
+	if lp.attempts, err = paramInt(p, "attempts", 1, 1, maxLoginAttempts); err != nil {
+		return lp, err
+	}
+	if lp.rate, err = paramInt(p, "rate_per_minute", defaultLoginRate, 1, maxLoginRate); err != nil {
+		return lp, err
+	}
+	lp.username = paramString(p, "username", defaultLoginUser)
+	return lp, nil
+}
+
+// describeAttempt says what one attempt of protocol as username sends,
+// for a dry-run plan. It never repeats a supplied password.
+func describeAttempt(protocol, username string, p map[string]string) string {
+	password := "the synthetic invalid password"
+	if p["password"] != "" || p["passwords"] != "" {
+		password = "a supplied password"
+	}
+	switch protocol {
+	case ProtocolSSH:
+		return "connect and read the SSH banner; no credentials are sent"
+	case ProtocolKerberos:
+		return fmt.Sprintf("send an AS-REQ for %s@%s without pre-authentication", username, p["realm"])
+	default:
+		return fmt.Sprintf("POST a login form as %s with %s", username, password)
+	}
+}
+
+// attemptFunc returns the single-attempt function for protocol. The ssh
+// attempt records the banner and ignores the credentials.
+func (h *LoginHandler) attemptFunc(taskID, protocol, target string, p map[string]string) (func(ctx context.Context, username, password string) (string, string, error), error) {
+	switch protocol {
+	case ProtocolSSH:
+		return func(ctx context.Context, _, _ string) (string, string, error) { return h.ssh(ctx, target) }, nil
+	case ProtocolHTTP:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("http target must be an absolute http(s) URL, got %q", target)
+		}
+		return func(ctx context.Context, username, password string) (string, string, error) {
+			return h.httpForm(ctx, target, taskID, username, password)
+		}, nil
+	case ProtocolKerberos:
+		realm, err := requireParam(p, "realm")
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, username, _ string) (string, string, error) {
+			return kerberosASReq(ctx, h.timeout(), target, realm, username)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported login protocol: %s", protocol)
+	}
+}
+
+func (h *LoginHandler) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return defaultLoginTimeout
+}
+
+func (h *LoginHandler) ssh(ctx context.Context, target string) (string, string, error) {
+	d, _ := rte.RunDialer(ctx)
+	conn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return "", "", err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(dl)
+	}
+	line, err := bufio.NewReader(io.LimitReader(conn, maxBannerBytes)).ReadString('\n')
+	if err != nil && line == "" {
+		return "", "", fmt.Errorf("read banner: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "SSH-") {
+		return "unexpected_banner", line, nil
+	}
+	return "banner", line, nil
+}
+
+func (h *LoginHandler) httpForm(ctx context.Context, target, taskID, username, password string) (string, string, error) {
+	client := runClient(ctx, h.timeout())
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	if h.Client != nil {
+		var err error
+		if client, err = guardClient(ctx, h.Client); err != nil {
+			return "", "", err
+		}
+	}
+	form := url.Values{"username": {username}, "password": {password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "rte-a-simulate-login")
+	req.Header.Set("X-RTE-A-Task", taskID)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHTTPResponseBytes))
+	detail := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "rejected", detail, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 400:
+		return "accepted", detail, nil
+	default:
+		return "http_error", detail, nil
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func loginTask(params map[string]string) rte.Task {
+	return rte.Task{
+		ID:         "task-login",
+		Engagement: "eng-2026-q1",
+		Type:       rte.TaskSimulateLogin,
+		CreatedAt:  time.Now().UTC(),
+		TTLSeconds: 600,
+		Operator:   "op-alice",
+		ApprovedBy: "lead-bob",
+		State:      rte.StateExecuting,
+		Params:     params,
+	}
+}
+
+func TestLoginHandler_SSHBanner(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = c.Write([]byte("SSH-2.0-OpenSSH_9.6 test\r\n"))
+			c.Close()
+		}
+	}()
+	h := &LoginHandler{Timeout: 2 * time.Second}
+	out, err := h.Handle(context.Background(), loginTask(map[string]string{
+		"protocol": ProtocolSSH, "target": ln.Addr().String(),
+	}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	res := out.(*LoginResult)
+	if len(res.Attempts) != 1 || res.Attempts[0].Outcome != "banner" || res.Attempts[0].Detail != "SSH-2.0-OpenSSH_9.6 test" {
+		t.Fatalf("unexpected attempts: %+v", res.Attempts)
+	}
+}
+
+func TestLoginHandler_HTTPFormRateLimited(t *testing.T) {
+	var hits []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, time.Now())
+		if r.Method != http.MethodPost || r.FormValue("username") != "svc-test" || r.FormValue("password") != syntheticPassword {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	h := &LoginHandler{}
+	out, err := h.Handle(context.Background(), loginTask(map[string]string{
+		"protocol": ProtocolHTTP, "target": srv.URL + "/login", "username": "svc-test",
+		"attempts": "2", "rate_per_minute": "60",
+	}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	res := out.(*LoginResult)
+	if len(res.Attempts) != 2 {
+		t.Fatalf("attempts: got %d, want 2", len(res.Attempts))
+	}
+	for _, a := range res.Attempts {
+		if a.Outcome != "rejected" {
+			t.Errorf("outcome: got %q (%s), want rejected", a.Outcome, a.Detail)
+		}
+	}
+	if gap := hits[1].Sub(hits[0]); gap < 900*time.Millisecond {
+		t.Errorf("attempts only %s apart at 60/min", gap)
+	}
+}
+
+func TestLoginHandler_CancelStopsPacing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	h := &LoginHandler{}
+	out, err := h.Handle(ctx, loginTask(map[string]string{
+		"protocol": ProtocolHTTP, "target": srv.URL, "attempts": "5", "rate_per_minute": "1",
+	}))
+	if err == nil {
+		t.Fatal("expected context error")
+	}
+	if res := out.(*LoginResult); len(res.Attempts) != 1 {
+		t.Fatalf("attempts: got %d, want 1", len(res.Attempts))
+	}
+}
+
+func TestLoginHandler_InvalidParams(t *testing.T) {
+	h := &LoginHandler{}
+	cases := []map[string]string{
+		{"target": "127.0.0.1:22"},
+		{"protocol": ProtocolSSH},
+		{"protocol": "telnet", "target": "127.0.0.1:23"},
+		{"protocol": ProtocolHTTP, "target": "ftp://example.test/"},
+		{"protocol": ProtocolKerberos, "target": "127.0.0.1"},
+		{"protocol": ProtocolSSH, "target": "127.0.0.1:22", "attempts": "100"},
+		{"protocol": ProtocolSSH, "target": "127.0.0.1:22", "rate_per_minute": "1000"},
+	}
+	for _, p := range cases {
+		if _, err := h.Handle(context.Background(), loginTask(p)); err == nil {
+			t.Errorf("expected params %v to fail", p)
+		}
+	}
+	task := loginTask(map[string]string{"protocol": ProtocolSSH, "target": "127.0.0.1:22"})
+	task.Type = rte.TaskInventory
+	if _, err := h.Handle(context.Background(), task); err == nil {
+		t.Error("expected wrong task type to fail")
+	}
+}
+
+func TestLoginHandler_Plan(t *testing.T) {
+	h := &LoginHandler{}
+	steps, err := h.Plan(context.Background(), loginTask(map[string]string{
+		"protocol": "http", "target": "https://login.test.example/form", "attempts": "3", "password": "hunter2",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].Count != 3 || steps[0].Target != "https://login.test.example/form" {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if d := steps[0].Detail; !strings.Contains(d, "a supplied password") || strings.Contains(d, "hunter2") {
+		t.Fatalf("detail = %q", d)
+	}
+	if _, err := h.Plan(context.Background(), loginTask(map[string]string{"protocol": "telnet", "target": "x"})); err == nil {
+		t.Fatal("expected unsupported protocol to fail its plan")
+	}
+}
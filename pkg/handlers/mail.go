@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// MailMessage is one outgoing simulation email.
+type MailMessage struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+	// Headers are extra message headers, such as the simulation watermark.
+	Headers map[string]string
+}
+
+// MailTransport delivers a message and returns a transport-specific ID.
+type MailTransport interface {
+	Send(ctx context.Context, msg MailMessage) (string, error)
+}
+
+// SMTPTransport delivers mail through an SMTP relay.
+type SMTPTransport struct {
+	// Addr is the relay's host:port.
+	Addr string
+	// Auth, if set, is used after STARTTLS (when offered).
+	Auth smtp.Auth
+	// Hello is the name sent in EHLO. Defaults to "localhost".
+	Hello string
+	// TLSConfig is used for STARTTLS. Defaults to verifying the relay host.
+	TLSConfig *tls.Config
+}
+
+// Send implements MailTransport. The returned ID is the generated
+// Message-ID header.
+func (t *SMTPTransport) Send(ctx context.Context, msg MailMessage) (string, error) {
+	if t.Addr == "" {
+		return "", errors.New("smtp address is required")
+	}
+	d, _ := rte.RunDialer(ctx)
+	conn, err := d.DialContext(ctx, "tcp", t.Addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	host, _, _ := net.SplitHostPort(t.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return "", fmt.Errorf("smtp greeting: %w", err)
+	}
+	defer c.Close()
+	hello := t.Hello
+	if hello == "" {
+		hello = "localhost"
+	}
+	if err := c.Hello(hello); err != nil {
+		return "", fmt.Errorf("smtp hello: %w", err)
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		cfg := t.TLSConfig
+		if cfg == nil {
+			cfg = &tls.Config{ServerName: host}
+		}
+		if err := c.StartTLS(cfg); err != nil {
+			return "", fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if t.Auth != nil {
+		if err := c.Auth(t.Auth); err != nil {
+			return "", fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := c.Mail(msg.From); err != nil {
+		return "", fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return "", fmt.Errorf("smtp rcpt to: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return "", fmt.Errorf("smtp data: %w", err)
+	}
+	id := fmt.Sprintf("<%d.rte-a@%s>", time.Now().UnixNano(), hello)
+	if _, err := w.Write(rfc5322(msg, id)); err != nil {
+		return "", fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("smtp data: %w", err)
+	}
+	return id, c.Quit()
+}
+
+// rfc5322 renders msg as a single-part HTML message.
+func rfc5322(msg MailMessage, id string) []byte {
+	var b bytes.Buffer
+	hdr := func(k, v string) {
+		b.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+	}
+	hdr("From", msg.From)
+	hdr("To", msg.To)
+	hdr("Subject", msg.Subject)
+	hdr("Date", time.Now().UTC().Format(time.RFC1123Z))
+	hdr("Message-ID", id)
+	hdr("MIME-Version", "1.0")
+	hdr("Content-Type", `text/html; charset="utf-8"`)
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		hdr(k, msg.Headers[k])
+	}
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.HTML, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// GraphTransport delivers mail with the Microsoft Graph sendMail API.
+type GraphTransport struct {
+	// Endpoint defaults to https://graph.microsoft.com/v1.0.
+	Endpoint string
+	// Token returns an OAuth bearer token with Mail.Send permission.
+	Token func(ctx context.Context) (string, error)
+	// Client defaults to http.DefaultClient. Under an rte.Guard a copy of
+	// it dials through the guard; sends fail if its transport cannot.
+	Client *http.Client
+}
+
+// Send implements MailTransport. Graph returns no message ID, so the
+// request ID from the response is reported instead.
+func (t *GraphTransport) Send(ctx context.Context, msg MailMessage) (string, error) {
+	if t.Token == nil {
+		return "", errors.New("graph token source is required")
+	}
+	token, err := t.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("graph token: %w", err)
+	}
+	endpoint := t.Endpoint
+	if endpoint == "" {
+		endpoint = "https://graph.microsoft.com/v1.0"
+	}
+	type addr struct {
+		EmailAddress struct {
+			Address string `json:"address"`
+		} `json:"emailAddress"`
+	}
+	type header struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	var to addr
+	to.EmailAddress.Address = msg.To
+	headers := make([]header, 0, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers = append(headers, header{Name: k, Value: v})
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	body, err := json.Marshal(map[string]any{
+		"message": map[string]any{
+			"subject":                msg.Subject,
+			"body":                   map[string]string{"contentType": "HTML", "content": msg.HTML},
+			"toRecipients":           []addr{to},
+			"internetMessageHeaders": headers,
+		},
+		"saveToSentItems": false,
+	})
+	if err != nil {
+		return "", err
+	}
+	u := strings.TrimRight(endpoint, "/") + "/users/" + url.PathEscape(msg.From) + "/sendMail"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if client, err = guardClient(ctx, client); err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseBytes))
+		return "", fmt.Errorf("graph sendMail: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp.Header.Get("request-id"), nil
+}
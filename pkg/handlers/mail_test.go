@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// fakeSMTP accepts one message and returns its DATA section on the channel.
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 test ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 test")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				reply("250 ok")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				data <- b.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 unsupported")
+			}
+		}
+	}()
+	return ln.Addr().String(), data
+}
+
+func TestSMTPTransport_Send(t *testing.T) {
+	addr, data := fakeSMTP(t)
+	tr := &SMTPTransport{Addr: addr}
+	id, err := tr.Send(context.Background(), MailMessage{
+		From: "sim@corp.example", To: "test1@corp.example", Subject: "hello",
+		HTML: "<p>hi</p>", Headers: map[string]string{"X-RTE-A-Task": "task-1"},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	msg := <-data
+	for _, want := range []string{"To: test1@corp.example\r\n", "X-RTE-A-Task: task-1\r\n", "Message-ID: " + id, "\r\n\r\n<p>hi</p>"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestRFC5322_StripsHeaderInjection(t *testing.T) {
+	msg := string(rfc5322(MailMessage{From: "a@x.example", To: "b@x.example", Subject: "hi\r\nBcc: victim@real.com"}, "<id>"))
+	if strings.Contains(msg, "\r\nBcc:") {
+		t.Fatalf("header injection not stripped:\n%s", msg)
+	}
+}
+
+func TestGraphTransport_Send(t *testing.T) {
+	var got map[string]any
+	var path, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("request-id", "req-42")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	tr := &GraphTransport{Endpoint: srv.URL, Token: func(context.Context) (string, error) { return "tok", nil }}
+	id, err := tr.Send(context.Background(), MailMessage{From: "sim@corp.example", To: "test1@corp.example", Subject: "s", HTML: "<p/>",
+		Headers: map[string]string{"X-RTE-A-Synthetic": "phishing-simulation"}})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if id != "req-42" || path != "/users/sim@corp.example/sendMail" || auth != "Bearer tok" {
+		t.Fatalf("unexpected request id=%s path=%s auth=%s", id, path, auth)
+	}
+	msg := got["message"].(map[string]any)
+	if msg["internetMessageHeaders"].([]any)[0].(map[string]any)["name"] != "X-RTE-A-Synthetic" {
+		t.Errorf("watermark header missing: %v", msg)
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+	if _, err := tr.Send(context.Background(), MailMessage{From: "a@x.example", To: "b@x.example"}); err == nil {
+		t.Fatal("expected non-202 to fail")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestGraphTransport_Guarded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	send := func(tr *GraphTransport, allow ...string) error {
+		tr.Endpoint = srv.URL
+		tr.Token = func(context.Context) (string, error) { return "tok", nil }
+		g := &rte.Guard{Allow: allow, Handler: rte.HandlerFunc(func(ctx context.Context, _ rte.Task) (any, error) {
+			return tr.Send(ctx, MailMessage{From: "sim@corp.example", To: "test1@corp.example"})
+		})}
+		task := rte.Task{ID: "t-1", Type: rte.TaskSimulatePhish, Params: map[string]string{"target": "10.0.0.1"}}
+		_, err := g.Handle(context.Background(), task)
+		return err
+	}
+	if err := send(&GraphTransport{}); !errors.Is(err, rte.ErrGuardDenied) {
+		t.Errorf("default client reached an endpoint the guard does not allow: %v", err)
+	}
+	if err := send(&GraphTransport{Client: srv.Client()}); !errors.Is(err, rte.ErrGuardDenied) {
+		t.Errorf("custom client reached an endpoint the guard does not allow: %v", err)
+	}
+	if err := send(&GraphTransport{Client: srv.Client()}, srv.URL); err != nil {
+		t.Errorf("custom client to an allowed endpoint: %v", err)
+	}
+	opaque := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("round tripper called")
+	})}
+	if err := send(&GraphTransport{Client: opaque}, srv.URL); !errors.Is(err, rte.ErrGuardDenied) {
+		t.Errorf("client the guard cannot wrap: %v", err)
+	}
+}
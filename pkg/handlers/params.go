@@ -0,0 +1,80 @@
+// Package handlers provides reference rte.Handler implementations for the
+// built-in task types. Every handler is benign by construction: it only
+// touches targets named in the signed task's params and bounds its own rate.
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paramString returns params[key], or def if the key is absent.
+func paramString(params map[string]string, key, def string) string {
+	if v, ok := params[key]; ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// requireParam returns params[key] or an error if it is missing.
+func requireParam(params map[string]string, key string) (string, error) {
+	v := params[key]
+	if v == "" {
+		return "", fmt.Errorf("param %s is required", key)
+	}
+	return v, nil
+}
+
+// splitList splits a comma-separated param, dropping blanks and duplicates.
+func splitList(s string) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if _, ok := seen[part]; ok {
+			continue
+		}
+		seen[part] = struct{}{}
+		out = append(out, part)
+	}
+	return out
+}
+
+// paramInt parses params[key] as an integer within [lo, hi], returning def
+// if the key is absent.
+func paramInt(params map[string]string, key string, def, lo, hi int) (int, error) {
+	v, ok := params[key]
+	if !ok || v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", key, err)
+	}
+	if n < lo || n > hi {
+		return 0, fmt.Errorf("param %s must be between %d and %d, got %d", key, lo, hi, n)
+	}
+	return n, nil
+}
+
+// pace blocks until the next attempt may start or ctx is done. It is the
+// shared rate limiter for handlers that repeat an action.
+func pace(done <-chan struct{}, last time.Time, interval time.Duration) bool {
+	wait := time.Until(last.Add(interval))
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
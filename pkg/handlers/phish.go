@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+const (
+	maxPhishRecipients  = 50
+	maxPhishObserve     = 3600
+	phishWatermark      = "RTE-A-PHISHING-SIMULATION"
+	defaultPhishSubject = "Action required: confirm your mailbox settings"
+)
+
+// PhishClick is one recorded visit to a tracking link.
+type PhishClick struct {
+	At         time.Time `json:"at"`
+	RemoteAddr string    `json:"remote_addr"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// PhishDelivery is the outcome for one recipient.
+type PhishDelivery struct {
+	Recipient string       `json:"recipient"`
+	SentAt    time.Time    `json:"sent_at"`
+	Delivered bool         `json:"delivered"`
+	MessageID string       `json:"message_id,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Token     string       `json:"token"`
+	Clicks    []PhishClick `json:"clicks"`
+}
+
+// PhishResult is the output of a simulate_phish task.
+type PhishResult struct {
+	Transport  string          `json:"transport"`
+	Sender     string          `json:"sender"`
+	Deliveries []PhishDelivery `json:"deliveries"`
+}
+
+// ClickTracker serves tracking links and records clicks on them. Only tokens
+// issued by a PhishHandler are recorded; other requests still get the
+// simulation landing page. It is safe for concurrent use.
+type ClickTracker struct {
+	// BaseURL is the externally reachable URL the tracker is served at.
+	BaseURL string
+
+	mu     sync.Mutex
+	clicks map[string][]PhishClick
+}
+
+// NewClickTracker returns a tracker served at baseURL.
+func NewClickTracker(baseURL string) *ClickTracker {
+	return &ClickTracker{BaseURL: baseURL, clicks: make(map[string][]PhishClick)}
+}
+
+func (t *ClickTracker) register(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.clicks == nil {
+		t.clicks = make(map[string][]PhishClick)
+	}
+	if _, ok := t.clicks[token]; !ok {
+		t.clicks[token] = []PhishClick{}
+	}
+}
+
+// Link returns the tracking URL for token.
+func (t *ClickTracker) Link(token string) string {
+	return strings.TrimRight(t.BaseURL, "/") + "/?t=" + url.QueryEscape(token)
+}
+
+// Clicks returns the clicks recorded for token.
+func (t *ClickTracker) Clicks(token string) []PhishClick {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]PhishClick{}, t.clicks[token]...)
+}
+
+// ServeHTTP records the click and tells the visitor it was a simulation.
+func (t *ClickTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("t")
+	t.mu.Lock()
+	if cs, ok := t.clicks[token]; ok {
+		t.clicks[token] = append(cs, PhishClick{At: time.Now().UTC(), RemoteAddr: r.RemoteAddr, UserAgent: r.UserAgent()})
+	}
+	t.mu.Unlock()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintf(w, "<html><body><h1>%s</h1><p>This message was an authorized phishing simulation. No credentials were collected.</p></body></html>\n", phishWatermark)
+}
+
+// PhishHandler executes TaskSimulatePhish: it sends clearly watermarked
+// simulation emails to designated test mailboxes and reports delivery and
+// click telemetry. Every task is first checked against Policy, which should
+// include rte.PhishApprovalPolicy; the handler refuses to run without one.
+//
+// Params: recipients (comma-separated; each must be a designated mailbox),
+// transport (a key of Transports, default smtp), subject, and
+// observe_seconds (0-3600, default 0) to wait for clicks before reporting.
+type PhishHandler struct {
+	// Policy is the phishing approval gate. Required.
+	Policy rte.PolicyEvaluator
+	// Mailboxes are the designated test recipients: full addresses, or
+	// "@domain" to allow a whole test domain.
+	Mailboxes []string
+	// Sender is the From address.
+	Sender string
+	// Transports maps transport names (such as "smtp" or "graph") to senders.
+	Transports map[string]MailTransport
+	// Tracker, if set, issues tracking links and supplies click telemetry.
+	Tracker *ClickTracker
+}
+
+// Handle implements rte.Handler.
+func (h *PhishHandler) Handle(ctx context.Context, task rte.Task) (any, error) {
+	if task.Type != rte.TaskSimulatePhish {
+		return nil, fmt.Errorf("phish handler cannot run %s tasks", task.Type)
+	}
+	pp, err := h.prepare(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	recipients, name, transport, observe, subject := pp.recipients, pp.transport, h.Transports[pp.transport], pp.observe, pp.subject
+
+	res := &PhishResult{Transport: name, Sender: h.Sender}
+	for _, rcpt := range recipients {
+		token, err := phishToken()
+		if err != nil {
+			return res, err
+		}
+		d := PhishDelivery{Recipient: rcpt, SentAt: time.Now().UTC(), Token: token, Clicks: []PhishClick{}}
+		link := ""
+		if h.Tracker != nil {
+			h.Tracker.register(token)
+			link = h.Tracker.Link(token)
+		}
+		id, err := transport.Send(ctx, MailMessage{
+			From:    h.Sender,
+			To:      rcpt,
+			Subject: subject,
+			HTML:    phishBody(task, link),
+			Headers: map[string]string{
+				"X-RTE-A-Synthetic":  "phishing-simulation",
+				"X-RTE-A-Task":       task.ID,
+				"X-RTE-A-Engagement": task.Engagement,
+			},
+		})
+		if err != nil {
+			d.Error = err.Error()
+		} else {
+			d.Delivered, d.MessageID = true, id
+		}
+		res.Deliveries = append(res.Deliveries, d)
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+	}
+
+	var waitErr error
+	if observe > 0 && h.Tracker != nil {
+		timer := time.NewTimer(time.Duration(observe) * time.Second)
+		select {
+		case <-ctx.Done():
+			waitErr = ctx.Err()
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+	if h.Tracker != nil {
+		for i := range res.Deliveries {
+			res.Deliveries[i].Clicks = h.Tracker.Clicks(res.Deliveries[i].Token)
+		}
+	}
+	return res, waitErr
+}
+
+// Plan implements rte.Planner. The approval policy is enforced as for
+// Handle, so an unapproved campaign fails its dry run.
+func (h *PhishHandler) Plan(ctx context.Context, task rte.Task) ([]rte.PlannedStep, error) {
+	if task.Type != rte.TaskSimulatePhish {
+		return nil, fmt.Errorf("phish handler cannot run %s tasks", task.Type)
+	}
+	pp, err := h.prepare(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	link := "no tracking link"
+	if h.Tracker != nil {
+		link = "a tracking link"
+	}
+	steps := make([]rte.PlannedStep, 0, len(pp.recipients)+1)
+	for _, rcpt := range pp.recipients {
+		steps = append(steps, rte.PlannedStep{
+			Action: rte.StepConnect,
+			Target: rcpt,
+			Detail: fmt.Sprintf("send %q from %s through the %s transport, watermarked and with %s", pp.subject, h.Sender, pp.transport, link),
+		})
+	}
+	if pp.observe > 0 && h.Tracker != nil {
+		steps = append(steps, rte.PlannedStep{
+			Action: rte.StepRun,
+			Target: "click tracker",
+			Detail: fmt.Sprintf("record clicks for %ds before reporting", pp.observe),
+		})
+	}
+	return steps, nil
+}
+
+// phishParams are a phish task's checked params.
+type phishParams struct {
+	recipients         []string
+	transport, subject string
+	observe            int
+}
+
+// prepare enforces the approval policy and checks the task's params
+// before any mail is sent.
+func (h *PhishHandler) prepare(ctx context.Context, task rte.Task) (*phishParams, error) {
+	if h.Policy == nil {
+		return nil, errors.New("simulate_phish requires a phishing approval policy")
+	}
+	if err := rte.EnforcePolicy(ctx, h.Policy, task); err != nil {
+		return nil, err
+	}
+	if h.Sender == "" {
+		return nil, errors.New("phish sender is not configured")
+	}
+	p := task.Params
+	pp := &phishParams{transport: paramString(p, "transport", "smtp")}
+	var err error
+	if pp.recipients, err = h.recipients(p); err != nil {
+		return nil, err
+	}
+	if _, ok := h.Transports[pp.transport]; !ok {
+		return nil, fmt.Errorf("mail transport not configured: %s", pp.transport)
+	}
+	if pp.observe, err = paramInt(p, "observe_seconds", 0, 0, maxPhishObserve); err != nil {
+		return nil, err
+	}
+	pp.subject = "[" + phishWatermark + "] " + paramString(p, "subject", defaultPhishSubject)
+	return pp, nil
+}
+
+// recipients parses and checks the recipients param against Mailboxes.
+func (h *PhishHandler) recipients(p map[string]string) ([]string, error) {
+	raw, err := requireParam(p, "recipients")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	var out []string
+	for _, r := range strings.Split(raw, ",") {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r == "" {
+			continue
+		}
+		if _, ok := seen[r]; ok {
+			continue
+		}
+		if !h.designated(r) {
+			return nil, fmt.Errorf("%s is not a designated test mailbox", r)
+		}
+		seen[r] = struct{}{}
+		out = append(out, r)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("param recipients is required")
+	}
+	if len(out) > maxPhishRecipients {
+		return nil, fmt.Errorf("at most %d recipients per task, got %d", maxPhishRecipients, len(out))
+	}
+	return out, nil
+}
+
+func (h *PhishHandler) designated(addr string) bool {
+	at := strings.LastIndexByte(addr, '@')
+	if at <= 0 {
+		return false
+	}
+	for _, m := range h.Mailboxes {
+		m = strings.ToLower(m)
+		if m == addr || (strings.HasPrefix(m, "@") && m == addr[at:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func phishToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("tracking token: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// phishBody builds the message body. The watermark banner is always present
+// so a recipient or mail filter can never mistake the message for a real
+// lure.
+func phishBody(task rte.Task, link string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><body>\n<p style=\"border:2px solid #c00;padding:8px\"><strong>%s</strong> &mdash; authorized test for engagement %s (task %s).</p>\n",
+		phishWatermark, html.EscapeString(task.Engagement), html.EscapeString(task.ID))
+	b.WriteString("<p>Please review your mailbox settings before the end of the week.</p>\n")
+	if link != "" {
+		fmt.Fprintf(&b, "<p><a href=\"%s\">Review settings</a></p>\n", html.EscapeString(link))
+	}
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+type fakeTransport struct {
+	mu   sync.Mutex
+	sent []MailMessage
+	fail string
+}
+
+func (f *fakeTransport) Send(_ context.Context, msg MailMessage) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if msg.To == f.fail {
+		return "", errors.New("550 mailbox unavailable")
+	}
+	f.sent = append(f.sent, msg)
+	return "id-" + msg.To, nil
+}
+
+func phishTask(params map[string]string) rte.Task {
+	task := loginTask(params)
+	task.ID = "task-phish"
+	task.Type = rte.TaskSimulatePhish
+	task.ApprovedBy = "lead-phish"
+	if task.Params == nil {
+		task.Params = map[string]string{}
+	}
+	if _, ok := task.Params["phish_approval_ref"]; !ok {
+		task.Params["phish_approval_ref"] = "CHG-1042"
+	}
+	return task
+}
+
+func phishHandler(tr MailTransport, tracker *ClickTracker) *PhishHandler {
+	return &PhishHandler{
+		Policy:     rte.PhishApprovalPolicy("lead-phish"),
+		Mailboxes:  []string{"test1@corp.example", "@sim.corp.example"},
+		Sender:     "security-awareness@corp.example",
+		Transports: map[string]MailTransport{"smtp": tr},
+		Tracker:    tracker,
+	}
+}
+
+func TestPhishHandler_DeliversAndTracksClicks(t *testing.T) {
+	tr := &fakeTransport{fail: "b@sim.corp.example"}
+	tracker := NewClickTracker("")
+	srv := httptest.NewServer(tracker)
+	defer srv.Close()
+	tracker.BaseURL = srv.URL
+
+	h := phishHandler(tr, tracker)
+	out, err := h.Handle(context.Background(), phishTask(map[string]string{"recipients": "Test1@corp.example, b@sim.corp.example"}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	res := out.(*PhishResult)
+	if len(res.Deliveries) != 2 || !res.Deliveries[0].Delivered || res.Deliveries[1].Delivered || res.Deliveries[1].Error == "" {
+		t.Fatalf("unexpected deliveries %+v", res.Deliveries)
+	}
+	msg := tr.sent[0]
+	if !strings.HasPrefix(msg.Subject, "["+phishWatermark+"] ") || !strings.Contains(msg.HTML, phishWatermark) || msg.Headers["X-RTE-A-Task"] != "task-phish" {
+		t.Errorf("message not watermarked: %+v", msg)
+	}
+
+	resp, err := http.Get(tracker.Link(res.Deliveries[0].Token))
+	if err != nil {
+		t.Fatalf("click: %v", err)
+	}
+	resp.Body.Close()
+	if resp, err := http.Get(srv.URL + "/?t=unknown"); err == nil {
+		resp.Body.Close()
+	}
+	if got := tracker.Clicks(res.Deliveries[0].Token); len(got) != 1 {
+		t.Fatalf("got %d clicks, want 1", len(got))
+	}
+	if got := tracker.Clicks("unknown"); len(got) != 0 {
+		t.Fatalf("unissued token recorded %d clicks", len(got))
+	}
+}
+
+func TestPhishHandler_Gated(t *testing.T) {
+	tr := &fakeTransport{}
+	h := phishHandler(tr, nil)
+
+	task := phishTask(map[string]string{"recipients": "test1@corp.example"})
+	task.ApprovedBy = "lead-bob"
+	if _, err := h.Handle(context.Background(), task); err == nil || !strings.Contains(err.Error(), "phishing approver") {
+		t.Fatalf("expected approval denial, got %v", err)
+	}
+	if _, err := h.Handle(context.Background(), phishTask(map[string]string{"recipients": "test1@corp.example", "phish_approval_ref": ""})); err == nil {
+		t.Fatal("expected missing approval ref to fail")
+	}
+	h.Policy = nil
+	if _, err := h.Handle(context.Background(), phishTask(map[string]string{"recipients": "test1@corp.example"})); err == nil {
+		t.Fatal("expected handler without policy to refuse")
+	}
+	if len(tr.sent) != 0 {
+		t.Fatalf("gated tasks sent %d messages", len(tr.sent))
+	}
+}
+
+func TestPhishHandler_RejectsUndesignated(t *testing.T) {
+	h := phishHandler(&fakeTransport{}, nil)
+	for _, p := range []map[string]string{
+		{"recipients": "ceo@corp.example"},
+		{"recipients": "x@evil-sim.corp.example"},
+		{"recipients": " , "},
+		{"recipients": "test1@corp.example", "transport": "graph"},
+		{"recipients": "test1@corp.example", "observe_seconds": "7200"},
+	} {
+		if _, err := h.Handle(context.Background(), phishTask(p)); err == nil {
+			t.Errorf("expected %v to fail", p)
+		}
+	}
+}
+
+func TestPhishHandler_Plan(t *testing.T) {
+	tr := &fakeTransport{}
+	h := phishHandler(tr, NewClickTracker("https://track.test.example"))
+	steps, err := h.Plan(context.Background(), phishTask(map[string]string{
+		"recipients": "test1@corp.example,a@sim.corp.example", "observe_seconds": "30",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 || steps[0].Target != "test1@corp.example" || steps[2].Action != rte.StepRun {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if len(tr.sent) != 0 {
+		t.Fatalf("plan sent %d messages", len(tr.sent))
+	}
+	unapproved := phishTask(map[string]string{"recipients": "test1@corp.example"})
+	unapproved.ApprovedBy = "lead-bob"
+	if _, err := h.Plan(context.Background(), unapproved); err == nil {
+		t.Fatal("expected unapproved campaign to fail its plan")
+	}
+}
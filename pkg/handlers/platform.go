@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The collectors reach the host through hostOS, hostProcesses, hostPorts,
+// hostPackages, hostServices, and hostDescribe, which each supported OS
+// implements in its own build-tagged platform_<goos>.go. The parsers
+// below are shared by those files and build everywhere, so they are
+// tested on any host.
+
+// Service is a service or daemon the host's service manager knows about.
+type Service struct {
+	Name string `json:"name"`
+	// Manager is systemd, sysv, launchd, or scm (the Windows service
+	// control manager).
+	Manager string `json:"manager"`
+	// Startup is auto, manual, or disabled; empty where the manager does
+	// not say.
+	Startup string `json:"startup,omitempty"`
+}
+
+// Service startup modes.
+const (
+	StartupAuto     = "auto"
+	StartupManual   = "manual"
+	StartupDisabled = "disabled"
+)
+
+// ServiceCollector reports the services the host's service manager
+// defines, from its configuration rather than by querying running state.
+type ServiceCollector struct{ Root string }
+
+// Name implements Collector.
+func (ServiceCollector) Name() string { return "services" }
+
+// Describe says what Collect reads, for a dry-run plan.
+func (c ServiceCollector) Describe() string { return hostDescribe("services", c.Root) }
+
+// Collect implements Collector.
+func (c ServiceCollector) Collect(context.Context) (any, error) {
+	out, err := hostServices(c.Root)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// parsePS parses the output of `ps -axo pid=,ppid=,uid=,comm=`. The
+// command name is last and may contain spaces.
+func parsePS(out []byte) ([]Process, error) {
+	var procs []Process
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() && len(procs) < maxProcesses {
+		f := strings.Fields(sc.Text())
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) < 4 {
+			return nil, fmt.Errorf("malformed ps line %q", sc.Text())
+		}
+		var p Process
+		var err error
+		if p.PID, err = strconv.Atoi(f[0]); err != nil {
+			return nil, fmt.Errorf("malformed pid %q", f[0])
+		}
+		if p.PPID, err = strconv.Atoi(f[1]); err != nil {
+			return nil, fmt.Errorf("malformed ppid %q", f[1])
+		}
+		if p.UID, err = strconv.Atoi(f[2]); err != nil {
+			return nil, fmt.Errorf("malformed uid %q", f[2])
+		}
+		p.Name = filepath.Base(strings.Join(f[3:], " "))
+		procs = append(procs, p)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs, sc.Err()
+}
+
+// systemdServices lists the .service units installed under root. A unit
+// linked from a .wants directory in etc/systemd/system starts
+// automatically; one masked to /dev/null there is disabled.
+func systemdServices(root string) ([]Service, error) {
+	etc := filepath.Join(root, "etc/systemd/system")
+	byName := make(map[string]*Service)
+	for _, dir := range []string{"lib/systemd/system", "usr/lib/systemd/system", "etc/systemd/system"} {
+		entries, err := os.ReadDir(filepath.Join(root, dir))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if name, ok := strings.CutSuffix(e.Name(), ".service"); ok && !strings.HasSuffix(name, "@") {
+				byName[name] = &Service{Name: name, Manager: "systemd", Startup: StartupManual}
+			}
+		}
+	}
+	wants, _ := filepath.Glob(filepath.Join(etc, "*.wants", "*.service"))
+	for _, w := range wants {
+		if s, ok := byName[strings.TrimSuffix(filepath.Base(w), ".service")]; ok {
+			s.Startup = StartupAuto
+		}
+	}
+	for name, s := range byName {
+		if dst, err := os.Readlink(filepath.Join(etc, name+".service")); err == nil && dst == "/dev/null" {
+			s.Startup = StartupDisabled
+		}
+	}
+	out := make([]Service, 0, len(byName))
+	for _, s := range byName {
+		out = append(out, *s)
+	}
+	return out, nil
+}
+
+// sysvServices lists the init scripts in etc/init.d under root.
+func sysvServices(root string) ([]Service, error) {
+	entries, err := os.ReadDir(filepath.Join(root, "etc/init.d"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	var out []Service
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") && e.Name() != "README" {
+			out = append(out, Service{Name: e.Name(), Manager: "sysv"})
+		}
+	}
+	return out, err
+}
+
+// launchdDirs are where launchd reads daemon and agent definitions.
+var launchdDirs = []string{
+	"System/Library/LaunchDaemons", "Library/LaunchDaemons",
+	"System/Library/LaunchAgents", "Library/LaunchAgents",
+}
+
+// launchdServices lists the launchd jobs defined under root. A job whose
+// plist sets Disabled is disabled; launchd does not otherwise record
+// whether a job starts at load in a way worth guessing at.
+func launchdServices(root string) ([]Service, error) {
+	var out []Service
+	for _, dir := range launchdDirs {
+		paths, err := filepath.Glob(filepath.Join(root, dir, "*.plist"))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			s := Service{Name: strings.TrimSuffix(filepath.Base(p), ".plist"), Manager: "launchd"}
+			if kv, err := plistValues(p); err == nil {
+				if label := kv["Label"]; label != "" {
+					s.Name = label
+				}
+				if kv["Disabled"] == "true" {
+					s.Startup = StartupDisabled
+				}
+			}
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// plistValues reads the string and boolean values at the top level of an
+// XML property list. Binary plists are not supported.
+func plistValues(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := make(map[string]string)
+	dec := xml.NewDecoder(f)
+	depth, key := 0, ""
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			depth++
+			// Depth 3 is plist > dict > entry.
+			if depth != 3 {
+				continue
+			}
+			switch el.Name.Local {
+			case "key":
+				var k string
+				if err := dec.DecodeElement(&k, &el); err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				key = k
+				depth--
+				continue
+			case "string":
+				var v string
+				if err := dec.DecodeElement(&v, &el); err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				out[key] = v
+				depth--
+			case "true", "false":
+				out[key] = el.Name.Local
+			}
+			key = ""
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build darwin
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// hostDescribe says what the named collector reads under root.
+func hostDescribe(collector, root string) string {
+	switch collector {
+	case "os":
+		return fmt.Sprintf("read System/Library/CoreServices/SystemVersion.plist under %s and the kernel release", root)
+	case "ports", "packages":
+		return "nothing; the collector is not supported on darwin"
+	case "processes":
+		return "run ps to list process names, parents, and owners"
+	case "services":
+		return fmt.Sprintf("read the launchd daemon and agent directories under %s", root)
+	}
+	return "run the collector; it only reads"
+}
+
+// hostOS fills in the product name and version, kernel, and hardware
+// UUID.
+func hostOS(root string, info *OSInfo) {
+	if kv, err := plistValues(filepath.Join(root, "System/Library/CoreServices/SystemVersion.plist")); err == nil {
+		info.Name = strings.TrimSpace(kv["ProductName"] + " " + kv["ProductVersion"])
+	}
+	if v, err := syscall.Sysctl("kern.osrelease"); err == nil {
+		info.Kernel = v
+	}
+	if v, err := syscall.Sysctl("kern.uuid"); err == nil {
+		info.Machine = v
+	}
+}
+
+// hostPorts is not supported: macOS has no socket tables to read without
+// cgo.
+func hostPorts(string) ([]ListeningPort, error) { return nil, errUnsupportedOS }
+
+// hostProcesses runs ps, which reads the kernel's process table; root is
+// not used.
+func hostProcesses(ctx context.Context, _ string) ([]Process, error) {
+	out, err := exec.CommandContext(ctx, "/bin/ps", "-axo", "pid=,ppid=,uid=,comm=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("ps: %w", err)
+	}
+	return parsePS(out)
+}
+
+// hostPackages is not supported: macOS has no package database.
+func hostPackages(string) ([]Package, error) { return nil, errUnsupportedOS }
+
+// hostServices lists launchd jobs.
+func hostServices(root string) ([]Service, error) { return launchdServices(root) }
@@ -0,0 +1,147 @@
+//go:build linux
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hostDescribe says what the named collector reads under root.
+func hostDescribe(collector, root string) string {
+	switch collector {
+	case "os":
+		return fmt.Sprintf("read etc/os-release, proc/sys/kernel/osrelease, and etc/machine-id under %s", root)
+	case "ports":
+		return fmt.Sprintf("read the proc/net socket tables under %s", root)
+	case "processes":
+		return fmt.Sprintf("read process names, parents, and owners from proc under %s", root)
+	case "packages":
+		return fmt.Sprintf("read the dpkg and apk package databases under %s", root)
+	case "services":
+		return fmt.Sprintf("read the systemd unit directories and etc/init.d under %s", root)
+	}
+	return "run the collector; it only reads"
+}
+
+// hostOS fills in the release, kernel, and machine ID.
+func hostOS(root string, info *OSInfo) {
+	if b, err := os.ReadFile(filepath.Join(root, "etc/os-release")); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if v, ok := strings.CutPrefix(line, "PRETTY_NAME="); ok {
+				info.Name = strings.Trim(v, `"`)
+			}
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "proc/sys/kernel/osrelease")); err == nil {
+		info.Kernel = strings.TrimSpace(string(b))
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "etc/machine-id")); err == nil {
+		info.Machine = strings.TrimSpace(string(b))
+	}
+}
+
+// hostPorts reads the proc/net socket tables.
+func hostPorts(root string) ([]ListeningPort, error) {
+	var out []ListeningPort
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		ports, err := parseProcNet(filepath.Join(root, "proc/net", proto), proto)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		out = append(out, ports...)
+	}
+	return out, nil
+}
+
+// hostProcesses reads each proc/<pid>/status.
+func hostProcesses(ctx context.Context, root string) ([]Process, error) {
+	entries, err := os.ReadDir(filepath.Join(root, "proc"))
+	if err != nil {
+		return nil, err
+	}
+	var out []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(out) >= maxProcesses {
+			break
+		}
+		p, ok := readProcess(filepath.Join(root, "proc", e.Name()), pid)
+		if ok {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PID < out[j].PID })
+	return out, nil
+}
+
+// readProcess parses /proc/<pid>/status; processes that exit mid-scan are
+// skipped.
+func readProcess(dir string, pid int) (Process, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return Process{}, false
+	}
+	p := Process{PID: pid}
+	for _, line := range strings.Split(string(b), "\n") {
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		switch key {
+		case "Name":
+			p.Name = val
+		case "PPid":
+			p.PPID, _ = strconv.Atoi(val)
+		case "Uid":
+			if f := strings.Fields(val); len(f) > 0 {
+				p.UID, _ = strconv.Atoi(f[0])
+			}
+		}
+	}
+	return p, true
+}
+
+// hostPackages reads the dpkg database, or failing that apk's.
+func hostPackages(root string) ([]Package, error) {
+	if pkgs, err := parsePackageDB(filepath.Join(root, "var/lib/dpkg/status"), "dpkg", "Package", "Version", "Status"); err == nil {
+		return pkgs, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if pkgs, err := parsePackageDB(filepath.Join(root, "lib/apk/db/installed"), "apk", "P", "V", ""); err == nil {
+		return pkgs, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return nil, errors.New("no supported package database found")
+}
+
+// hostServices lists systemd units and SysV init scripts.
+func hostServices(root string) ([]Service, error) {
+	units, err := systemdServices(root)
+	if err != nil {
+		return nil, err
+	}
+	scripts, err := sysvServices(root)
+	if err != nil {
+		return nil, err
+	}
+	return append(units, scripts...), nil
+}
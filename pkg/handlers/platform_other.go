@@ -0,0 +1,24 @@
+//go:build !linux && !darwin && !windows
+
+package handlers
+
+import "context"
+
+// hostDescribe says what the named collector reads.
+func hostDescribe(collector, _ string) string {
+	if collector == "os" {
+		return "report the OS and architecture the agent was built for"
+	}
+	return "nothing; the collector is not supported on this platform"
+}
+
+// hostOS adds nothing to the GOOS and architecture.
+func hostOS(string, *OSInfo) {}
+
+func hostPorts(string) ([]ListeningPort, error) { return nil, errUnsupportedOS }
+
+func hostProcesses(context.Context, string) ([]Process, error) { return nil, errUnsupportedOS }
+
+func hostPackages(string) ([]Package, error) { return nil, errUnsupportedOS }
+
+func hostServices(string) ([]Service, error) { return nil, errUnsupportedOS }
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePS(t *testing.T) {
+	out := []byte("  412     1   501 /Applications/Google Chrome.app/Contents/MacOS/Google Chrome\n" +
+		"    1     0     0 /sbin/launchd\n\n")
+	procs, err := parsePS(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(procs) != 2 || procs[0] != (Process{PID: 1, Name: "launchd"}) ||
+		procs[1] != (Process{PID: 412, PPID: 1, UID: 501, Name: "Google Chrome"}) {
+		t.Fatalf("procs = %+v", procs)
+	}
+	if _, err := parsePS([]byte("12 1 x launchd\n")); err == nil {
+		t.Fatal("expected a malformed uid to fail")
+	}
+}
+
+func TestSystemdServices(t *testing.T) {
+	root := t.TempDir()
+	for _, unit := range []string{"sshd.service", "cups.service", "getty@.service", "basic.target"} {
+		writeFile(t, root, "lib/systemd/system/"+unit, "[Unit]\n")
+	}
+	writeFile(t, root, "etc/systemd/system/backup.service", "[Unit]\n")
+	writeFile(t, root, "etc/init.d/networking", "#!/bin/sh\n")
+	symlink(t, "/lib/systemd/system/sshd.service", filepath.Join(root, "etc/systemd/system/multi-user.target.wants/sshd.service"))
+	symlink(t, "/dev/null", filepath.Join(root, "etc/systemd/system/cups.service"))
+
+	units, err := systemdServices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scripts, err := sysvServices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Service)
+	for _, s := range append(units, scripts...) {
+		got[s.Name] = s
+	}
+	want := map[string]Service{
+		"sshd":       {Name: "sshd", Manager: "systemd", Startup: StartupAuto},
+		"cups":       {Name: "cups", Manager: "systemd", Startup: StartupDisabled},
+		"backup":     {Name: "backup", Manager: "systemd", Startup: StartupManual},
+		"networking": {Name: "networking", Manager: "sysv"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("services = %+v", got)
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s = %+v, want %+v", name, got[name], w)
+		}
+	}
+}
+
+func TestLaunchdServices(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "Library/LaunchDaemons/com.example.agent.plist", `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.example.updater</string>
+	<key>ProgramArguments</key>
+	<array><string>/usr/local/bin/updater</string></array>
+	<key>Disabled</key>
+	<true/>
+	<key>RunAtLoad</key>
+	<false/>
+</dict>
+</plist>
+`)
+	writeFile(t, root, "System/Library/LaunchAgents/com.apple.Finder.plist", "bplist00")
+	svcs, err := launchdServices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcs) != 2 || svcs[0] != (Service{Name: "com.example.updater", Manager: "launchd", Startup: StartupDisabled}) ||
+		svcs[1] != (Service{Name: "com.apple.Finder", Manager: "launchd"}) {
+		t.Fatalf("services = %+v", svcs)
+	}
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+}
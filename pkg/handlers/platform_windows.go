@@ -0,0 +1,171 @@
+//go:build windows
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"syscall"
+	"unsafe"
+)
+
+// hostDescribe says what the named collector reads.
+func hostDescribe(collector, _ string) string {
+	switch collector {
+	case "os":
+		return `read the Windows version and MachineGuid from HKLM\SOFTWARE`
+	case "ports", "packages":
+		return "nothing; the collector is not supported on windows"
+	case "processes":
+		return "take a process snapshot for process names and parents"
+	case "services":
+		return `read the service definitions under HKLM\SYSTEM\CurrentControlSet\Services`
+	}
+	return "run the collector; it only reads"
+}
+
+// hostOS fills in the product name, build, and machine GUID from the
+// registry; root is not used.
+func hostOS(_ string, info *OSInfo) {
+	if k, err := openKey(`SOFTWARE\Microsoft\Windows NT\CurrentVersion`); err == nil {
+		info.Name, _ = regString(k, "ProductName")
+		if build, err := regString(k, "CurrentBuild"); err == nil {
+			info.Kernel = "build " + build
+		}
+		syscall.RegCloseKey(k)
+	}
+	if k, err := openKey(`SOFTWARE\Microsoft\Cryptography`); err == nil {
+		info.Machine, _ = regString(k, "MachineGuid")
+		syscall.RegCloseKey(k)
+	}
+}
+
+// hostPorts is not supported: reading the TCP tables needs the IP helper
+// API.
+func hostPorts(string) ([]ListeningPort, error) { return nil, errUnsupportedOS }
+
+// hostProcesses walks a Toolhelp process snapshot. Windows owners are SIDs,
+// not UIDs, so UID is -1.
+func hostProcesses(ctx context.Context, _ string) ([]Process, error) {
+	snap, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("process snapshot: %w", err)
+	}
+	defer syscall.CloseHandle(snap)
+	var out []Process
+	e := syscall.ProcessEntry32{Size: uint32(unsafe.Sizeof(syscall.ProcessEntry32{}))}
+	for err = syscall.Process32First(snap, &e); err == nil && len(out) < maxProcesses; err = syscall.Process32Next(snap, &e) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		out = append(out, Process{
+			PID: int(e.ProcessID), PPID: int(e.ParentProcessID),
+			Name: syscall.UTF16ToString(e.ExeFile[:]), UID: -1,
+		})
+	}
+	if err != nil && !errors.Is(err, syscall.ERROR_NO_MORE_FILES) {
+		return nil, fmt.Errorf("process snapshot: %w", err)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PID < out[j].PID })
+	return out, nil
+}
+
+// hostPackages is not supported: installed programs have no single
+// authoritative database.
+func hostPackages(string) ([]Package, error) { return nil, errUnsupportedOS }
+
+// Service registry values; see the SCM's CreateService documentation.
+const (
+	serviceWin32   = 0x30 // SERVICE_WIN32_OWN_PROCESS | SERVICE_WIN32_SHARE_PROCESS
+	startAuto      = 2
+	startManual    = 3
+	startDisabled  = 4
+	maxServiceName = 256
+
+	errNoMoreItems syscall.Errno = 259 // ERROR_NO_MORE_ITEMS
+)
+
+// hostServices lists the Win32 services the SCM defines in the registry;
+// drivers are left out.
+func hostServices(string) ([]Service, error) {
+	root, err := openKey(`SYSTEM\CurrentControlSet\Services`)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.RegCloseKey(root)
+	var out []Service
+	for i := uint32(0); ; i++ {
+		buf := make([]uint16, maxServiceName)
+		n := uint32(len(buf))
+		err := syscall.RegEnumKeyEx(root, i, &buf[0], &n, nil, nil, nil, nil)
+		if errors.Is(err, errNoMoreItems) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("enumerate services: %w", err)
+		}
+		name := syscall.UTF16ToString(buf[:n])
+		var k syscall.Handle
+		if syscall.RegOpenKeyEx(root, syscall.StringToUTF16Ptr(name), 0, syscall.KEY_READ, &k) != nil {
+			continue
+		}
+		typ, terr := regDWORD(k, "Type")
+		start, serr := regDWORD(k, "Start")
+		syscall.RegCloseKey(k)
+		if terr != nil || typ&serviceWin32 == 0 {
+			continue
+		}
+		s := Service{Name: name, Manager: "scm"}
+		if serr == nil {
+			switch {
+			case start <= startAuto:
+				s.Startup = StartupAuto
+			case start == startManual:
+				s.Startup = StartupManual
+			case start == startDisabled:
+				s.Startup = StartupDisabled
+			}
+		}
+		out = append(out, s)
+	}
+}
+
+// openKey opens a key under HKEY_LOCAL_MACHINE for reading.
+func openKey(path string) (syscall.Handle, error) {
+	var k syscall.Handle
+	if err := syscall.RegOpenKeyEx(syscall.HKEY_LOCAL_MACHINE, syscall.StringToUTF16Ptr(path), 0, syscall.KEY_READ, &k); err != nil {
+		return 0, fmt.Errorf(`open HKLM\%s: %w`, path, err)
+	}
+	return k, nil
+}
+
+// regString reads a REG_SZ value.
+func regString(k syscall.Handle, name string) (string, error) {
+	var typ, n uint32
+	if err := syscall.RegQueryValueEx(k, syscall.StringToUTF16Ptr(name), nil, &typ, nil, &n); err != nil {
+		return "", err
+	}
+	if typ != syscall.REG_SZ || n == 0 {
+		return "", fmt.Errorf("%s is not a string", name)
+	}
+	buf := make([]uint16, n/2+1)
+	if err := syscall.RegQueryValueEx(k, syscall.StringToUTF16Ptr(name), nil, &typ, (*byte)(unsafe.Pointer(&buf[0])), &n); err != nil {
+		return "", err
+	}
+	return syscall.UTF16ToString(buf), nil
+}
+
+// regDWORD reads a REG_DWORD value.
+func regDWORD(k syscall.Handle, name string) (uint32, error) {
+	var typ, v uint32
+	n := uint32(unsafe.Sizeof(v))
+	if err := syscall.RegQueryValueEx(k, syscall.StringToUTF16Ptr(name), nil, &typ, (*byte)(unsafe.Pointer(&v)), &n); err != nil {
+		return 0, err
+	}
+	if typ != syscall.REG_DWORD {
+		return 0, fmt.Errorf("%s is not a DWORD", name)
+	}
+	return v, nil
+}
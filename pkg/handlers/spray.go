@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+const (
+	defaultLockoutThreshold = 5
+	defaultLockoutWindow    = 30 * time.Minute
+	defaultSprayRate        = 30
+	maxSprayAccounts        = 100
+	maxSprayPasswords       = 3
+)
+
+// SprayAttempt is one account/password attempt within a spray.
+type SprayAttempt struct {
+	Account string `json:"account"`
+	Round   int    `json:"round"`
+	LoginAttempt
+}
+
+// SprayResult is the output of a simulate_credential_spray task.
+type SprayResult struct {
+	Protocol      string         `json:"protocol"`
+	Target        string         `json:"target"`
+	Accounts      int            `json:"accounts"`
+	Rounds        int            `json:"rounds"`
+	RoundInterval string         `json:"round_interval"`
+	Attempts      []SprayAttempt `json:"attempts"`
+}
+
+// SprayHandler executes TaskSimulateCredentialSpray: a few passwords tried
+// across many accounts, the way a real spray avoids per-account lockouts.
+// Safety is built in rather than left to the operator:
+//
+//   - every account named in params must be a registered test account in
+//     Assets; one unregistered or non-test account aborts the whole task
+//     before any attempt is made;
+//   - rounds are spaced so no account ever sees LockoutThreshold-1 or more
+//     attempts within LockoutWindow.
+//
+// Params: protocol (http or kerberos), target, realm (kerberos), accounts
+// (comma-separated), passwords (comma-separated, at most 3; default a
+// synthetic invalid password), and rate_per_minute (1-60, default 30).
+type SprayHandler struct {
+	// Assets is the engagement asset registry. Required.
+	Assets *rte.AssetRegistry
+	// Login performs the individual attempts.
+	Login LoginHandler
+	// LockoutThreshold is the target's failed-attempt lockout count.
+	// Defaults to 5.
+	LockoutThreshold int
+	// LockoutWindow is the target's lockout observation window. Defaults
+	// to 30 minutes.
+	LockoutWindow time.Duration
+}
+
+// Handle implements rte.Handler.
+func (h *SprayHandler) Handle(ctx context.Context, task rte.Task) (any, error) {
+	if task.Type != rte.TaskSimulateCredentialSpray {
+		return nil, fmt.Errorf("spray handler cannot run %s tasks", task.Type)
+	}
+	sp, err := h.prepare(task)
+	if err != nil {
+		return nil, err
+	}
+	accounts, attempt, roundGap := sp.accounts, sp.attempt, sp.roundGap
+
+	res := &SprayResult{
+		Protocol: sp.protocol, Target: sp.target, Accounts: len(accounts),
+		Rounds: len(sp.passwords), RoundInterval: roundGap.String(),
+	}
+	interval := time.Minute / time.Duration(sp.rate)
+	var last, roundStart time.Time
+	for round, password := range sp.passwords {
+		if round > 0 && !pace(ctx.Done(), roundStart, roundGap) {
+			return res, ctx.Err()
+		}
+		roundStart = time.Now()
+		for i, account := range accounts {
+			if (round > 0 || i > 0) && !pace(ctx.Done(), last, interval) {
+				return res, ctx.Err()
+			}
+			last = time.Now()
+			actx, cancel := context.WithTimeout(ctx, h.Login.timeout())
+			outcome, detail, err := attempt(actx, account, password)
+			cancel()
+			if err != nil {
+				outcome, detail = "error", err.Error()
+			}
+			res.Attempts = append(res.Attempts, SprayAttempt{
+				Account: account,
+				Round:   round + 1,
+				LoginAttempt: LoginAttempt{
+					At:         last.UTC(),
+					Outcome:    outcome,
+					Detail:     detail,
+					DurationMS: time.Since(last).Milliseconds(),
+				},
+			})
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+		}
+	}
+	return res, nil
+}
+
+// Plan implements rte.Planner. It runs every safety check Handle does, so
+// a spray that would abort fails its dry run too.
+func (h *SprayHandler) Plan(_ context.Context, task rte.Task) ([]rte.PlannedStep, error) {
+	if task.Type != rte.TaskSimulateCredentialSpray {
+		return nil, fmt.Errorf("spray handler cannot run %s tasks", task.Type)
+	}
+	sp, err := h.prepare(task)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]rte.PlannedStep, len(sp.passwords))
+	for i := range sp.passwords {
+		steps[i] = rte.PlannedStep{
+			Action: rte.StepConnect,
+			Target: sp.target,
+			Detail: fmt.Sprintf("round %d of %d: %s, at most %d per minute, rounds %s apart; accounts %s",
+				i+1, len(sp.passwords), describeAttempt(sp.protocol, "each account", task.Params),
+				sp.rate, sp.roundGap, strings.Join(sp.accounts, ", ")),
+			Count: len(sp.accounts),
+		}
+	}
+	return steps, nil
+}
+
+// sprayParams are a spray's checked params.
+type sprayParams struct {
+	accounts, passwords []string
+	protocol, target    string
+	rate                int
+	roundGap            time.Duration
+	attempt             func(ctx context.Context, username, password string) (string, string, error)
+}
+
+// prepare checks a spray's params and safety constraints before any
+// attempt is made.
+func (h *SprayHandler) prepare(task rte.Task) (*sprayParams, error) {
+	if h.Assets == nil {
+		return nil, errors.New("credential spray requires an asset registry")
+	}
+	p := task.Params
+	sp := &sprayParams{}
+	var err error
+	if sp.accounts, err = h.accounts(task); err != nil {
+		return nil, err
+	}
+	if sp.protocol, err = requireParam(p, "protocol"); err != nil {
+		return nil, err
+	}
+	if sp.protocol == ProtocolSSH {
+		return nil, errors.New("ssh login simulation sends no credentials; use simulate_login")
+	}
+	if sp.target, err = requireParam(p, "target"); err != nil {
+		return nil, err
+	}
+	if sp.attempt, err = h.Login.attemptFunc(task.ID, sp.protocol, sp.target, p); err != nil {
+		return nil, err
+	}
+	sp.passwords = splitList(paramString(p, "passwords", syntheticPassword))
+	if len(sp.passwords) == 0 || len(sp.passwords) > maxSprayPasswords {
+		return nil, fmt.Errorf("passwords must list 1 to %d entries, got %d", maxSprayPasswords, len(sp.passwords))
+	}
+	if sp.rate, err = paramInt(p, "rate_per_minute", defaultSprayRate, 1, maxLoginRate); err != nil {
+		return nil, err
+	}
+	if sp.roundGap, err = h.roundInterval(); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// accounts returns the spray accounts after checking that every account
+// named anywhere in params is a designated test account.
+func (h *SprayHandler) accounts(task rte.Task) ([]string, error) {
+	raw, err := requireParam(task.Params, "accounts")
+	if err != nil {
+		return nil, err
+	}
+	accounts := splitList(raw)
+	if len(accounts) > maxSprayAccounts {
+		return nil, fmt.Errorf("at most %d accounts per spray, got %d", maxSprayAccounts, len(accounts))
+	}
+	check := accounts
+	if u := task.Params["username"]; u != "" {
+		check = append(append([]string{}, accounts...), u)
+	}
+	for _, a := range check {
+		if !h.Assets.IsTestAccount(task.Engagement, a) {
+			return nil, fmt.Errorf("spray aborted: %s is not a designated test account in %s", a, task.Engagement)
+		}
+	}
+	return accounts, nil
+}
+
+// roundInterval is the minimum spacing between rounds. Each account gets
+// one attempt per round, so spacing rounds by window/(threshold-1) keeps
+// every account strictly below the lockout threshold in any window.
+func (h *SprayHandler) roundInterval() (time.Duration, error) {
+	threshold := h.LockoutThreshold
+	if threshold == 0 {
+		threshold = defaultLockoutThreshold
+	}
+	if threshold < 2 {
+		return 0, fmt.Errorf("lockout threshold %d leaves no safe attempts", threshold)
+	}
+	window := h.LockoutWindow
+	if window <= 0 {
+		window = defaultLockoutWindow
+	}
+	return window / time.Duration(threshold-1), nil
+}
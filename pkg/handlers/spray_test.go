@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func sprayTask(params map[string]string) rte.Task {
+	task := loginTask(params)
+	task.ID = "task-spray"
+	task.Type = rte.TaskSimulateCredentialSpray
+	return task
+}
+
+func sprayAssets(t *testing.T) *rte.AssetRegistry {
+	t.Helper()
+	r := rte.NewAssetRegistry()
+	for _, a := range []rte.Asset{
+		{Kind: rte.AssetAccount, Name: "rtea-test1", Test: true},
+		{Kind: rte.AssetAccount, Name: "rtea-test2", Test: true},
+		{Kind: rte.AssetAccount, Name: "alice"},
+	} {
+		if err := r.Add("eng-2026-q1", a); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	return r
+}
+
+func TestSprayHandler_LockoutSafePacing(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string][]time.Time{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		seen[r.PostForm.Get("username")] = append(seen[r.PostForm.Get("username")], time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	h := &SprayHandler{Assets: sprayAssets(t), LockoutThreshold: 3, LockoutWindow: 3 * time.Second}
+	out, err := h.Handle(context.Background(), sprayTask(map[string]string{
+		"protocol": "http", "target": srv.URL, "accounts": "rtea-test1",
+		"passwords": "Winter2026!,Spring2026!", "rate_per_minute": "60",
+	}))
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	res := out.(*SprayResult)
+	if len(res.Attempts) != 2 || res.RoundInterval != "1.5s" || res.Attempts[1].Round != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	times := seen["rtea-test1"]
+	if len(times) != 2 {
+		t.Fatalf("server saw %d attempts, want 2", len(times))
+	}
+	if gap := times[1].Sub(times[0]); gap < 1400*time.Millisecond {
+		t.Fatalf("rounds %s apart; lockout pacing not applied", gap)
+	}
+	if res.Attempts[0].Outcome != "rejected" {
+		t.Errorf("unexpected outcome %s", res.Attempts[0].Outcome)
+	}
+}
+
+func TestSprayHandler_AbortsOnNonTestAccount(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hits++ }))
+	defer srv.Close()
+	h := &SprayHandler{Assets: sprayAssets(t)}
+	for _, p := range []map[string]string{
+		{"accounts": "rtea-test1,alice"},
+		{"accounts": "rtea-test1,mallory"},
+		{"accounts": "rtea-test1", "username": "alice"},
+	} {
+		p["protocol"], p["target"] = "http", srv.URL
+		_, err := h.Handle(context.Background(), sprayTask(p))
+		if err == nil || !strings.Contains(err.Error(), "spray aborted") {
+			t.Errorf("%v: expected abort, got %v", p, err)
+		}
+	}
+	if hits != 0 {
+		t.Fatalf("aborted sprays made %d attempts", hits)
+	}
+}
+
+func TestSprayHandler_Invalid(t *testing.T) {
+	h := &SprayHandler{Assets: sprayAssets(t)}
+	cases := []map[string]string{
+		{"protocol": "ssh", "target": "127.0.0.1:22", "accounts": "rtea-test1"},
+		{"protocol": "http", "target": "http://127.0.0.1/", "accounts": "rtea-test1", "passwords": "a,b,c,d"},
+		{"protocol": "http", "target": "http://127.0.0.1/", "accounts": "rtea-test1", "passwords": ","},
+		{"protocol": "kerberos", "target": "127.0.0.1:88", "accounts": "rtea-test1"},
+		{"protocol": "http", "target": "http://127.0.0.1/"},
+	}
+	for _, p := range cases {
+		if _, err := h.Handle(context.Background(), sprayTask(p)); err == nil {
+			t.Errorf("expected %v to fail", p)
+		}
+	}
+	if _, err := (&SprayHandler{}).Handle(context.Background(), sprayTask(cases[0])); err == nil {
+		t.Error("expected missing asset registry to fail")
+	}
+	h.LockoutThreshold = 1
+	if _, err := h.Handle(context.Background(), sprayTask(map[string]string{"protocol": "http", "target": "http://127.0.0.1/", "accounts": "rtea-test1"})); err == nil {
+		t.Error("expected unsafe lockout threshold to fail")
+	}
+}
+
+func TestSprayHandler_Plan(t *testing.T) {
+	h := &SprayHandler{Assets: sprayAssets(t)}
+	steps, err := h.Plan(context.Background(), sprayTask(map[string]string{
+		"protocol": "http", "target": "http://127.0.0.1/", "accounts": "rtea-test1,rtea-test2", "passwords": "a,b",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[1].Count != 2 || !strings.Contains(steps[1].Detail, "round 2 of 2") ||
+		!strings.Contains(steps[1].Detail, "7m30s") {
+		t.Fatalf("steps = %+v", steps)
+	}
+	_, err = h.Plan(context.Background(), sprayTask(map[string]string{"protocol": "http", "target": "http://127.0.0.1/", "accounts": "alice"}))
+	if err == nil || !strings.Contains(err.Error(), "spray aborted") {
+		t.Fatalf("non-test account: got %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+// Package history imports engagements run before RTE-A from the
+// spreadsheets teams tracked them in, so trend reporting can reach back
+// past the first signed report. A Mapping names which tracker columns hold
+// which task fields; each row becomes a Record that is unsigned and marked
+// historical wherever it appears.
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/attack"
+	"github.com/codethor0/rte-a-reference/pkg/report"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// TypeHistorical is the task type of imported rows that name none. It is
+// not a type agents run.
+const TypeHistorical rte.TaskType = "historical"
+
+// Mapping describes a tracker's layout. Columns are named by their header
+// text, matched case-insensitively.
+type Mapping struct {
+	// Client names the customer the tracker's engagements were run for.
+	Client string `json:"client"`
+	// Engagement is the engagement every row belongs to, for trackers
+	// with no engagement column.
+	Engagement string `json:"engagement,omitempty"`
+	// Sheet is the XLSX worksheet to read; empty means the first.
+	Sheet string `json:"sheet,omitempty"`
+	// HeaderRow is the 1-based row holding the column headers; 0 means 1.
+	HeaderRow int     `json:"header_row,omitempty"`
+	Columns   Columns `json:"columns"`
+	// TimeFormats are Go layouts tried in order for time cells; empty
+	// means DefaultTimeFormats. XLSX date cells need no format.
+	TimeFormats []string `json:"time_formats,omitempty"`
+	// Location is the IANA zone for times written without an offset;
+	// empty means UTC.
+	Location string `json:"location,omitempty"`
+	// States maps state cell values, case-insensitively, to task states,
+	// on top of the state names themselves.
+	States map[string]rte.TaskState `json:"states,omitempty"`
+	// DetectedValues are the detected-column values that mean the blue
+	// team caught the activity; empty means yes, y, true, 1, and detected.
+	DetectedValues []string `json:"detected_values,omitempty"`
+}
+
+// Columns names the tracker column for each field. Started is required,
+// and Engagement unless Mapping.Engagement is set; the rest are optional.
+type Columns struct {
+	ID         string `json:"id,omitempty"`
+	Engagement string `json:"engagement,omitempty"`
+	Type       string `json:"type,omitempty"`
+	// Technique holds one or more ATT&CK IDs separated by commas,
+	// semicolons, or spaces.
+	Technique  string `json:"technique,omitempty"`
+	Operator   string `json:"operator,omitempty"`
+	ApprovedBy string `json:"approved_by,omitempty"`
+	Started    string `json:"started"`
+	Finished   string `json:"finished,omitempty"`
+	State      string `json:"state,omitempty"`
+	Detected   string `json:"detected,omitempty"`
+	DetectedAt string `json:"detected_at,omitempty"`
+	Rule       string `json:"rule,omitempty"`
+}
+
+// DefaultTimeFormats are the layouts tried when a Mapping names none.
+var DefaultTimeFormats = []string{
+	time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "01/02/2006 15:04", "01/02/2006",
+}
+
+var defaultDetected = []string{"yes", "y", "true", "1", "detected"}
+
+// LoadMapping reads a JSON mapping file.
+func LoadMapping(path string) (Mapping, error) {
+	var m Mapping
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Record is one imported tracker row. It is never signed: Historical is
+// always true, and Source and Row say where it came from.
+type Record struct {
+	Historical bool                  `json:"historical"`
+	Source     string                `json:"source"`
+	Row        int                   `json:"row"`
+	Task       rte.Task              `json:"task"`
+	Result     rte.TaskResult        `json:"result"`
+	Detections []rte.DetectionRecord `json:"detections,omitempty"`
+}
+
+// RowError reports a tracker row that could not be imported.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string { return fmt.Sprintf("row %d: %v", e.Row, e.Err) }
+func (e *RowError) Unwrap() error { return e.Err }
+
+// ReadCSV imports a CSV tracker. source names it in each Record.
+func ReadCSV(r io.Reader, m Mapping, source string) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return Import(rows, m, source)
+}
+
+// Import converts rows, the header row included, into records. Blank rows
+// are skipped. Every bad row is reported, as RowErrors joined, and no
+// records are returned unless all rows import.
+func Import(rows [][]string, m Mapping, source string) ([]Record, error) {
+	c, err := m.compile()
+	if err != nil {
+		return nil, err
+	}
+	header := m.HeaderRow
+	if header <= 0 {
+		header = 1
+	}
+	if len(rows) < header {
+		return nil, fmt.Errorf("%s: no header row %d", source, header)
+	}
+	if err := c.index(rows[header-1]); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	var recs []Record
+	var errs []error
+	for i := header; i < len(rows); i++ {
+		row := rows[i]
+		if blank(row) {
+			continue
+		}
+		rec, err := c.record(row, i+1)
+		if err != nil {
+			errs = append(errs, &RowError{Row: i + 1, Err: err})
+			continue
+		}
+		rec.Source = source
+		recs = append(recs, rec)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %w", source, errors.Join(errs...))
+	}
+	return recs, nil
+}
+
+func blank(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// compiled is a Mapping ready to apply.
+type compiled struct {
+	m        Mapping
+	loc      *time.Location
+	formats  []string
+	states   map[string]rte.TaskState
+	detected map[string]bool
+	cols     map[string]int
+}
+
+func (m Mapping) compile() (*compiled, error) {
+	if m.Client == "" {
+		return nil, errors.New("mapping needs a client")
+	}
+	if m.Columns.Started == "" {
+		return nil, errors.New("mapping needs a started column")
+	}
+	if m.Engagement == "" && m.Columns.Engagement == "" {
+		return nil, errors.New("mapping needs an engagement or an engagement column")
+	}
+	c := &compiled{m: m, loc: time.UTC, formats: m.TimeFormats, states: map[string]rte.TaskState{}, detected: map[string]bool{}}
+	if m.Location != "" {
+		loc, err := time.LoadLocation(m.Location)
+		if err != nil {
+			return nil, fmt.Errorf("mapping location: %w", err)
+		}
+		c.loc = loc
+	}
+	if len(c.formats) == 0 {
+		c.formats = DefaultTimeFormats
+	}
+	for _, s := range []rte.TaskState{rte.StateCompleted, rte.StateFailed, rte.StateCancelled} {
+		c.states[string(s)] = s
+	}
+	for k, s := range m.States {
+		switch s {
+		case rte.StateCompleted, rte.StateFailed, rte.StateCancelled:
+		default:
+			return nil, fmt.Errorf("mapping state %q: historical tasks must be completed, failed, or cancelled, not %q", k, s)
+		}
+		c.states[strings.ToLower(strings.TrimSpace(k))] = s
+	}
+	values := m.DetectedValues
+	if len(values) == 0 {
+		values = defaultDetected
+	}
+	for _, v := range values {
+		c.detected[strings.ToLower(strings.TrimSpace(v))] = true
+	}
+	return c, nil
+}
+
+// index locates each mapped column in the header row.
+func (c *compiled) index(header []string) error {
+	at := make(map[string]int, len(header))
+	for i, h := range header {
+		at[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	cols := c.m.Columns
+	c.cols = map[string]int{}
+	var missing []string
+	for _, name := range []string{cols.ID, cols.Engagement, cols.Type, cols.Technique, cols.Operator, cols.ApprovedBy,
+		cols.Started, cols.Finished, cols.State, cols.Detected, cols.DetectedAt, cols.Rule} {
+		if name == "" {
+			continue
+		}
+		i, ok := at[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		c.cols[name] = i
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("header row has no column %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (c *compiled) cell(row []string, col string) string {
+	i, ok := c.cols[col]
+	if col == "" || !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+func (c *compiled) record(row []string, n int) (Record, error) {
+	cols := c.m.Columns
+	get := func(col string) string { return c.cell(row, col) }
+	eng := c.m.Engagement
+	if cols.Engagement != "" {
+		if eng = get(cols.Engagement); eng == "" {
+			return Record{}, errors.New("no engagement")
+		}
+	}
+	started, err := c.time(get(cols.Started))
+	if err != nil {
+		return Record{}, fmt.Errorf("started: %w", err)
+	}
+	if started.IsZero() {
+		return Record{}, errors.New("no start time")
+	}
+	finished := started
+	if v := get(cols.Finished); v != "" {
+		if finished, err = c.time(v); err != nil {
+			return Record{}, fmt.Errorf("finished: %w", err)
+		}
+		if finished.Before(started) {
+			return Record{}, errors.New("finished before it started")
+		}
+	}
+	state := rte.StateCompleted
+	if v := get(cols.State); v != "" {
+		s, ok := c.states[strings.ToLower(v)]
+		if !ok {
+			return Record{}, fmt.Errorf("unmapped state %q", v)
+		}
+		state = s
+	}
+	var techniques []string
+	for _, id := range strings.FieldsFunc(get(cols.Technique), func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		id = strings.ToUpper(id)
+		if err := attack.Validate(id); err != nil {
+			return Record{}, err
+		}
+		techniques = append(techniques, id)
+	}
+	id := get(cols.ID)
+	if id == "" {
+		id = "hist-" + strconv.Itoa(n)
+	}
+	typ := rte.TaskType(get(cols.Type))
+	if typ == "" {
+		typ = TypeHistorical
+	}
+	task := rte.Task{
+		ID: id, Engagement: eng, Type: typ, CreatedAt: started, Operator: get(cols.Operator),
+		ApprovedBy: get(cols.ApprovedBy), State: state, Techniques: techniques,
+	}
+	rec := Record{
+		Historical: true,
+		Row:        n,
+		Task:       task,
+		Result: rte.TaskResult{
+			TaskID: id, Engagement: eng, Type: typ, Operator: task.Operator,
+			State: state, StartedAt: started, FinishedAt: finished,
+		},
+	}
+	if cols.Detected == "" {
+		return rec, nil
+	}
+	detected := c.detected[strings.ToLower(get(cols.Detected))]
+	var alerted time.Time
+	if v := get(cols.DetectedAt); detected && v != "" {
+		if alerted, err = c.time(v); err != nil {
+			return Record{}, fmt.Errorf("detected at: %w", err)
+		}
+	}
+	for _, tech := range techniques {
+		d := rte.DetectionRecord{
+			TaskID: id, Engagement: eng, Technique: tech, Rule: get(cols.Rule),
+			EmittedAt: started, Detected: detected,
+		}
+		if !alerted.IsZero() {
+			d.AlertedAt, d.Latency = alerted, max(0, alerted.Sub(started))
+		}
+		rec.Detections = append(rec.Detections, d)
+	}
+	return rec, nil
+}
+
+// excelEpoch is day 0 of Excel's 1900 date system, allowing for its
+// phantom 29 February 1900.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// time parses a time cell, trying each format and then reading a bare
+// number as an Excel date serial, which is how XLSX stores date cells.
+func (c *compiled) time(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	for _, f := range c.formats {
+		if t, err := time.ParseInLocation(f, v, c.loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	if days, err := strconv.ParseFloat(v, 64); err == nil && days > 0 {
+		wall := excelEpoch.Add(time.Duration(days * float64(24*time.Hour))).Round(time.Second)
+		y, mo, d := wall.Date()
+		return time.Date(y, mo, d, wall.Hour(), wall.Minute(), wall.Second(), 0, c.loc).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", v)
+}
+
+// Reports builds one historical report per engagement in recs, for
+// report.Archive.AddHistorical. Engagements are returned sorted.
+func Reports(client string, recs []Record, now time.Time) []report.Report {
+	byEng := map[string][]Record{}
+	for _, r := range recs {
+		byEng[r.Task.Engagement] = append(byEng[r.Task.Engagement], r)
+	}
+	engs := make([]string, 0, len(byEng))
+	for e := range byEng {
+		engs = append(engs, e)
+	}
+	sort.Strings(engs)
+	out := make([]report.Report, 0, len(engs))
+	for _, eng := range engs {
+		out = append(out, buildReport(client, eng, byEng[eng], now))
+	}
+	return out
+}
+
+func buildReport(client, eng string, recs []Record, now time.Time) report.Report {
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Task.ID < recs[j].Task.ID })
+	r := report.Report{
+		SchemaVersion: report.SchemaVersion,
+		Engagement:    eng,
+		Client:        client,
+		Historical:    true,
+		GeneratedAt:   now.UTC(),
+		Summary:       report.Summary{ByState: map[rte.TaskState]int{}},
+		Tasks:         []report.TaskEntry{},
+		Appendix:      []report.SignatureEntry{},
+		Audit:         report.AuditSummary{Entries: []report.AuditRecord{}},
+	}
+	var tasks []rte.Task
+	var results []rte.TaskResult
+	var detections []rte.DetectionRecord
+	for _, rec := range recs {
+		t, res := rec.Task, rec.Result
+		tasks, results = append(tasks, t), append(results, res)
+		detections = append(detections, rec.Detections...)
+		e := report.TaskEntry{
+			ID: t.ID, Type: t.Type, Operator: t.Operator, ApprovedBy: t.ApprovedBy,
+			Techniques: t.Techniques, State: res.State,
+			StartedAt: res.StartedAt, FinishedAt: res.FinishedAt, Duration: res.FinishedAt.Sub(res.StartedAt),
+		}
+		r.Tasks = append(r.Tasks, e)
+		r.Summary.ByState[e.State]++
+		r.Summary.Runtime += e.Duration
+		if r.Window.Start.IsZero() || e.StartedAt.Before(r.Window.Start) {
+			r.Window.Start = e.StartedAt
+		}
+		if e.FinishedAt.After(r.Window.End) {
+			r.Window.End = e.FinishedAt
+		}
+	}
+	r.Summary.Tasks = len(r.Tasks)
+	r.Window.Duration = r.Window.End.Sub(r.Window.Start)
+	r.Coverage = rte.Coverage(eng, tasks, results)
+	r.Detections = append([]rte.LatencySummary{}, rte.SummarizeDetectionLatency(detections)...)
+	return r
+}
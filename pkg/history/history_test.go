@@ -0,0 +1,109 @@
+package history
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/report"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+var trackerMapping = Mapping{
+	Client: "acme",
+	Columns: Columns{
+		Engagement: "Engagement", Technique: "TTPs", Operator: "Tester",
+		Started: "Start", Finished: "End", State: "Status", Detected: "Caught?", DetectedAt: "Alert time",
+	},
+	States: map[string]rte.TaskState{"Done": rte.StateCompleted, "Aborted": rte.StateCancelled},
+}
+
+const tracker = `Engagement,TTPs,Tester,Start,End,Status,Caught?,Alert time
+acme-2023,"T1110, t1566.002",jdoe,2023-04-03 09:00,2023-04-03 10:30,Done,Yes,2023-04-03 09:20
+,,,,,,,
+acme-2023,T1110,jdoe,2023-04-04,,Aborted,no,
+acme-2024,T1110,asmith,2024-05-01 14:00,2024-05-01 15:00,done,yes,2024-05-01 14:05
+`
+
+func TestReadCSV(t *testing.T) {
+	recs, err := ReadCSV(strings.NewReader(tracker), trackerMapping, "tracker.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("imported %d records", len(recs))
+	}
+	r := recs[0]
+	if !r.Historical || r.Source != "tracker.csv" || r.Row != 2 || r.Task.ID != "hist-2" || r.Task.Type != TypeHistorical {
+		t.Errorf("record = %+v", r)
+	}
+	if got := r.Task.Techniques; len(got) != 2 || got[1] != "T1566.002" {
+		t.Errorf("techniques = %v", got)
+	}
+	if len(r.Detections) != 2 || !r.Detections[0].Detected || r.Detections[0].Latency != 20*time.Minute {
+		t.Errorf("detections = %+v", r.Detections)
+	}
+	if c := recs[1]; c.Result.State != rte.StateCancelled || c.Detections[0].Detected || !c.Result.FinishedAt.Equal(c.Result.StartedAt) {
+		t.Errorf("cancelled row = %+v", c)
+	}
+}
+
+func TestReadCSV_RowErrors(t *testing.T) {
+	bad := "Engagement,TTPs,Tester,Start,End,Status,Caught?,Alert time\n" +
+		"acme,T9999,jdoe,2023-04-03,,Done,,\n" +
+		"acme,T1110,jdoe,yesterday,,Done,,\n" +
+		"acme,T1110,jdoe,2023-04-03,,Parked,,\n"
+	_, err := ReadCSV(strings.NewReader(bad), trackerMapping, "bad.csv")
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || rowErr.Row != 2 {
+		t.Fatalf("err = %v", err)
+	}
+	for _, want := range []string{"row 2: ATT&CK technique T9999", "row 3: started", "row 4: unmapped state"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q lacks %q", err, want)
+		}
+	}
+
+	m := trackerMapping
+	m.Columns.Rule = "Detection rule"
+	if _, err := ReadCSV(strings.NewReader(tracker), m, "t.csv"); err == nil || !strings.Contains(err.Error(), "no column Detection rule") {
+		t.Errorf("missing column: %v", err)
+	}
+	m = trackerMapping
+	m.States = map[string]rte.TaskState{"WIP": rte.StateExecuting}
+	if _, err := ReadCSV(strings.NewReader(tracker), m, "t.csv"); err == nil {
+		t.Error("mapped a row to a non-terminal state")
+	}
+}
+
+func TestReports_FeedTrends(t *testing.T) {
+	recs, err := ReadCSV(strings.NewReader(tracker), trackerMapping, "tracker.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := Reports("acme", recs, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(reports) != 2 || reports[0].Engagement != "acme-2023" || !reports[0].Historical || reports[0].Summary.Tasks != 2 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	a := report.NewArchive()
+	for _, r := range reports {
+		if err := a.AddHistorical(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, err := a.TechniqueTrend("acme", "T1110")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Points) != 2 || !tr.Points[0].Historical || tr.Points[0].DetectionRate != 0.5 || tr.RateChange != 0.5 {
+		t.Errorf("trend = %+v", tr)
+	}
+	var md strings.Builder
+	if err := reports[0].Markdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.String(), "**Historical record.**") {
+		t.Error("markdown does not mark the report historical")
+	}
+}
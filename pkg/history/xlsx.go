@@ -0,0 +1,201 @@
+package history
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxXLSXPart bounds each workbook part read.
+const maxXLSXPart = 64 << 20
+
+// ReadXLSX imports the worksheet m.Sheet, or the first, of an XLSX tracker.
+// Formulas contribute their cached values.
+func ReadXLSX(r io.ReaderAt, size int64, m Mapping, source string) ([]Record, error) {
+	rows, err := readSheet(r, size, m.Sheet)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return Import(rows, m, source)
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a string item: plain text, or rich-text runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSST struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readSheet returns a worksheet's cells as rows of strings, blank where a
+// row or cell is absent.
+func readSheet(r io.ReaderAt, size int64, name string) ([][]string, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("read xlsx: %w", err)
+	}
+	parts := make(map[string]*zip.File, len(z.File))
+	for _, f := range z.File {
+		parts[f.Name] = f
+	}
+	var wb xlsxWorkbook
+	if err := readPart(parts, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	var rels xlsxRels
+	if err := readPart(parts, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+	rid := ""
+	for _, s := range wb.Sheets {
+		if name == "" || s.Name == name {
+			rid = s.RID
+			break
+		}
+	}
+	if rid == "" {
+		return nil, fmt.Errorf("workbook has no sheet %q", name)
+	}
+	target := ""
+	for _, rel := range rels.Rels {
+		if rel.ID == rid {
+			target = rel.Target
+		}
+	}
+	if target == "" {
+		return nil, fmt.Errorf("workbook relationship %s not found", rid)
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var sst xlsxSST
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		if err := readPart(parts, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+	}
+	var sheet xlsxSheet
+	if err := readPart(parts, target, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		n := row.R
+		if n <= 0 {
+			n = len(rows) + 1
+		}
+		for len(rows) < n {
+			rows = append(rows, nil)
+		}
+		var cells []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				if col, err = columnIndex(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+			var v string
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(c.Value)
+				if err != nil || idx < 0 || idx >= len(sst.Items) {
+					return nil, fmt.Errorf("cell %s: bad shared string index %q", c.Ref, c.Value)
+				}
+				v = sst.Items[idx].String()
+			case "inlineStr":
+				v = c.Inline.String()
+			default: // n, str, b, e, and formula results
+				v = c.Value
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			cells[col] = v
+		}
+		rows[n-1] = cells
+	}
+	return rows, nil
+}
+
+func readPart(parts map[string]*zip.File, name string, v any) error {
+	f, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("xlsx has no %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPart)).Decode(v); err != nil {
+		return fmt.Errorf("xlsx %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex returns the zero-based column of a cell reference like "AB12".
+func columnIndex(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || col > 16384 {
+		return 0, fmt.Errorf("bad cell reference %q", ref)
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,66 @@
+package history
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+)
+
+// workbook builds an XLSX file with the given worksheet XML as "Log".
+func workbook(t *testing.T, sheet, shared string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Notes" sheetId="1" r:id="rId1"/><sheet name="Log" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml": sheet,
+		"xl/sharedStrings.xml":     shared,
+	}
+	for name, body := range parts {
+		f, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write([]byte(body))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSX(t *testing.T) {
+	shared := `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<si><t>Engagement</t></si><si><t>TTPs</t></si><si><r><t>St</t></r><r><t>art</t></r></si><si><t>acme-2022</t></si></sst>`
+	// Row 3 is absent and row 4 skips column B; 44652.375 is 2022-04-01 09:00.
+	sheet := `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row>` +
+		`<row r="2"><c r="A2" t="s"><v>3</v></c><c r="B2" t="inlineStr"><is><t>T1110</t></is></c><c r="C2"><v>44652.375</v></c></row>` +
+		`<row r="4"><c r="A4" t="s"><v>3</v></c><c r="C4" t="str"><v>2022-04-02</v></c></row>` +
+		`</sheetData></worksheet>`
+	data := workbook(t, sheet, shared)
+	m := Mapping{Client: "acme", Sheet: "Log", Columns: Columns{Engagement: "engagement", Technique: "ttps", Started: "start"}}
+	recs, err := ReadXLSX(bytes.NewReader(data), int64(len(data)), m, "tracker.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[1].Row != 4 {
+		t.Fatalf("records = %+v", recs)
+	}
+	if want := time.Date(2022, 4, 1, 9, 0, 0, 0, time.UTC); !recs[0].Result.StartedAt.Equal(want) {
+		t.Errorf("started = %s, want %s", recs[0].Result.StartedAt, want)
+	}
+	if recs[0].Task.Engagement != "acme-2022" || recs[0].Task.Techniques[0] != "T1110" || len(recs[1].Task.Techniques) != 0 {
+		t.Errorf("records = %+v", recs)
+	}
+
+	m.Sheet = "Missing"
+	if _, err := ReadXLSX(bytes.NewReader(data), int64(len(data)), m, "tracker.xlsx"); err == nil {
+		t.Error("read a sheet the workbook lacks")
+	}
+}
@@ -0,0 +1,127 @@
+package intake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the webhook body's HMAC-SHA256 as
+// "sha256=<hex>", the form Jira Cloud webhooks with a secret send.
+const SignatureHeader = "X-Hub-Signature"
+
+// maxBody bounds webhook payloads.
+const maxBody = 1 << 20
+
+// Receiver is the webhook endpoint for one ticketing system. It accepts
+// POSTed tickets whose HMAC verifies, runs them through the first matching
+// rule, and stores the resulting draft. Tickets no rule matches, or that
+// are not approved yet, are acknowledged with 202 and ignored, so the
+// ticket system does not retry them.
+type Receiver struct {
+	System System
+	Rules  []Rule
+	// Secret is the shared webhook secret; it is required.
+	Secret []byte
+	Drafts *Drafts
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Response is the JSON body the receiver answers with.
+type Response struct {
+	Status string `json:"status"`
+	Ticket string `json:"ticket,omitempty"`
+	Rule   string `json:"rule,omitempty"`
+	TaskID string `json:"task_id,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ServeHTTP implements http.Handler.
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(rc.Secret) == 0 || rc.Drafts == nil {
+		http.Error(w, "intake is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil || len(body) > maxBody {
+		http.Error(w, "payload too large or unreadable", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !validSignature(rc.Secret, body, r.Header.Get(SignatureHeader)) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var t Ticket
+	switch rc.System {
+	case SystemJira:
+		t, err = ParseJira(body)
+	case SystemServiceNow:
+		t, err = ParseServiceNow(body)
+	default:
+		http.Error(w, "intake is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now := time.Now
+	if rc.Now != nil {
+		now = rc.Now
+	}
+	status, resp := rc.intake(t, now())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (rc *Receiver) intake(t Ticket, now time.Time) (int, Response) {
+	resp := Response{Ticket: t.Ref()}
+	for _, rule := range rc.Rules {
+		if !rule.Matches(t) {
+			continue
+		}
+		resp.Rule = rule.Name
+		d, err := rule.Map(t, now)
+		if errors.Is(err, ErrNotApproved) {
+			resp.Status, resp.Reason = "ignored", err.Error()
+			return http.StatusAccepted, resp
+		}
+		if err != nil {
+			resp.Status, resp.Reason = "rejected", err.Error()
+			return http.StatusUnprocessableEntity, resp
+		}
+		rc.Drafts.Put(d)
+		resp.Status, resp.TaskID = "drafted", d.Task.ID
+		return http.StatusCreated, resp
+	}
+	resp.Status, resp.Reason = "ignored", "no rule matches"
+	return http.StatusAccepted, resp
+}
+
+// validSignature checks a "sha256=<hex>" HMAC header in constant time.
+func validSignature(secret, body []byte, header string) bool {
+	got, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	sig, err := hex.DecodeString(got)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
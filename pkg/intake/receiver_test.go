@@ -0,0 +1,84 @@
+package intake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func post(t *testing.T, rc *Receiver, body, secret string) (*httptest.ResponseRecorder, Response) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/intake/servicenow", strings.NewReader(body))
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	rec := httptest.NewRecorder()
+	rc.ServeHTTP(rec, req)
+	var resp Response
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func TestReceiver(t *testing.T) {
+	drafts := NewDrafts()
+	rc := &Receiver{
+		System: SystemServiceNow,
+		Secret: []byte("s3cret"),
+		Drafts: drafts,
+		Now:    func() time.Time { return time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC) },
+		Rules: []Rule{{
+			Name: "snow-login", System: SystemServiceNow, RequireApproval: true,
+			TaskType: rte.TaskSimulateLogin, TTLSeconds: 600,
+			EngagementField: "engagement", OperatorField: "operator",
+			Params: map[string]string{"target": "target"},
+		}},
+	}
+
+	if rec, _ := post(t, rc, serviceNowPayload, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned: got %d", rec.Code)
+	}
+	if rec, _ := post(t, rc, serviceNowPayload, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad secret: got %d", rec.Code)
+	}
+	rec, resp := post(t, rc, serviceNowPayload, "s3cret")
+	if rec.Code != http.StatusCreated || resp.Status != "drafted" || resp.TaskID != "tkt-servicenow-chg0031337" {
+		t.Fatalf("unexpected response %d %+v", rec.Code, resp)
+	}
+	d, ok := drafts.Get(resp.TaskID)
+	if !ok || d.Task.ApprovedBy != "lead-bob" || d.Task.Params["target"] != "vpn.acme.example" {
+		t.Fatalf("unexpected draft %+v", d)
+	}
+	// The draft is ready to sign as is.
+	pub, priv, _ := rte.GenerateKeyPair()
+	if _, err := rte.SignTask(d.TaskAt(time.Now()), priv, pub); err != nil {
+		t.Fatalf("SignTask(draft): %v", err)
+	}
+
+	pending := strings.Replace(serviceNowPayload, `"approval": "approved"`, `"approval": "requested"`, 1)
+	if rec, resp := post(t, rc, pending, "s3cret"); rec.Code != http.StatusAccepted || resp.Status != "ignored" {
+		t.Fatalf("unapproved: got %d %+v", rec.Code, resp)
+	}
+	noOp := strings.Replace(serviceNowPayload, `"operator": "op-alice", `, "", 1)
+	if rec, resp := post(t, rc, noOp, "s3cret"); rec.Code != http.StatusUnprocessableEntity || resp.Status != "rejected" {
+		t.Fatalf("unmappable: got %d %+v", rec.Code, resp)
+	}
+	if rec, _ := post(t, rc, "{", "s3cret"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("malformed: got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	rc.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET: got %d", w.Code)
+	}
+}
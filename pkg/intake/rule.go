@@ -0,0 +1,236 @@
+package intake
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// ErrNotApproved is returned when a matching ticket has not reached an
+// approved state yet.
+var ErrNotApproved = errors.New("ticket is not approved")
+
+// Rule maps one kind of ticket to a draft task. A rule matches on system,
+// and optionally project and label; it fires only once the ticket is
+// approved.
+type Rule struct {
+	Name    string `json:"name"`
+	System  System `json:"system"`
+	Project string `json:"project,omitempty"`
+	Label   string `json:"label,omitempty"`
+	// Statuses lists ticket statuses that count as approved, compared
+	// case-insensitively. RequireApproval additionally requires the
+	// system's own approval flag. At least one must be set.
+	Statuses        []string `json:"statuses,omitempty"`
+	RequireApproval bool     `json:"require_approval,omitempty"`
+
+	TaskType   rte.TaskType `json:"task_type"`
+	TTLSeconds int          `json:"ttl_seconds"`
+	Techniques []string     `json:"techniques,omitempty"`
+	// Engagement is fixed, or read from EngagementField.
+	Engagement      string `json:"engagement,omitempty"`
+	EngagementField string `json:"engagement_field,omitempty"`
+	// Operator is fixed, or read from OperatorField.
+	Operator      string `json:"operator,omitempty"`
+	OperatorField string `json:"operator_field,omitempty"`
+	// ApprovedBy names the RTE-A approver who will countersign; it
+	// defaults to the ticket's approver.
+	ApprovedBy string `json:"approved_by,omitempty"`
+	// Params maps task params to ticket fields; Defaults fills params the
+	// ticket leaves empty.
+	Params   map[string]string `json:"params,omitempty"`
+	Defaults map[string]string `json:"defaults,omitempty"`
+}
+
+// Matches reports whether the rule applies to the ticket, regardless of
+// approval.
+func (r Rule) Matches(t Ticket) bool {
+	if r.System != t.System {
+		return false
+	}
+	if r.Project != "" && !strings.EqualFold(r.Project, t.Project) {
+		return false
+	}
+	if r.Label != "" && !slices.Contains(t.Labels, r.Label) {
+		return false
+	}
+	return true
+}
+
+// approved reports whether the ticket has cleared the rule's approval gate.
+func (r Rule) approved(t Ticket) bool {
+	if r.RequireApproval && !t.Approved {
+		return false
+	}
+	if len(r.Statuses) == 0 {
+		return r.RequireApproval
+	}
+	for _, s := range r.Statuses {
+		if strings.EqualFold(s, t.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the rule is usable.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("rule name is required")
+	}
+	if r.System != SystemJira && r.System != SystemServiceNow {
+		return fmt.Errorf("rule %s: unsupported system %q", r.Name, r.System)
+	}
+	if len(r.Statuses) == 0 && !r.RequireApproval {
+		return fmt.Errorf("rule %s: statuses or require_approval is required", r.Name)
+	}
+	if r.Engagement == "" && r.EngagementField == "" {
+		return fmt.Errorf("rule %s: engagement or engagement_field is required", r.Name)
+	}
+	if r.Operator == "" && r.OperatorField == "" {
+		return fmt.Errorf("rule %s: operator or operator_field is required", r.Name)
+	}
+	return nil
+}
+
+// Draft is a task built from a ticket, waiting for an operator to sign it.
+type Draft struct {
+	Task       rte.Task  `json:"task"`
+	Ticket     Ticket    `json:"ticket"`
+	Rule       string    `json:"rule"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Map builds the draft task for an approved ticket. The task ID is derived
+// from the ticket, so redelivered webhooks produce the same draft, and its
+// ticket param links it back for status sync. The draft must pass
+// rte.Task.Validate, so an operator can sign it as is.
+func (r Rule) Map(t Ticket, now time.Time) (Draft, error) {
+	if !r.approved(t) {
+		return Draft{}, fmt.Errorf("%s (status %q): %w", t.Ref(), t.Status, ErrNotApproved)
+	}
+	field := func(fixed, name, what string) (string, error) {
+		if fixed != "" {
+			return fixed, nil
+		}
+		if v := t.Fields[name]; v != "" {
+			return v, nil
+		}
+		return "", fmt.Errorf("%s: field %s (%s) is empty", t.Ref(), name, what)
+	}
+	engagement, err := field(r.Engagement, r.EngagementField, "engagement")
+	if err != nil {
+		return Draft{}, err
+	}
+	operator, err := field(r.Operator, r.OperatorField, "operator")
+	if err != nil {
+		return Draft{}, err
+	}
+	params := map[string]string{ParamTicket: t.Ref()}
+	for k, v := range r.Defaults {
+		params[k] = v
+	}
+	for param, name := range r.Params {
+		if v := t.Fields[name]; v != "" {
+			params[param] = v
+		}
+	}
+	task := rte.Task{
+		ID:         draftID(t),
+		Engagement: engagement,
+		Type:       r.TaskType,
+		CreatedAt:  now.UTC(),
+		TTLSeconds: r.TTLSeconds,
+		Operator:   operator,
+		ApprovedBy: firstNonEmpty(r.ApprovedBy, t.ApprovedBy),
+		State:      rte.StatePending,
+		Params:     params,
+		Techniques: append([]string(nil), r.Techniques...),
+	}
+	if err := task.Validate(now); err != nil {
+		return Draft{}, fmt.Errorf("%s: draft task: %w", t.Ref(), err)
+	}
+	return Draft{Task: task, Ticket: t, Rule: r.Name, ReceivedAt: now.UTC()}, nil
+}
+
+// TaskAt returns the draft task stamped with the signing time, since the
+// TTL must start when the operator signs, not when the ticket arrived.
+func (d Draft) TaskAt(now time.Time) rte.Task {
+	t := d.Task
+	t.CreatedAt = now.UTC()
+	t.Params = make(map[string]string, len(d.Task.Params))
+	for k, v := range d.Task.Params {
+		t.Params[k] = v
+	}
+	return t
+}
+
+// ParamTicket is the task param carrying the originating ticket reference.
+const ParamTicket = "ticket"
+
+// draftID derives a stable task ID from a ticket reference.
+func draftID(t Ticket) string {
+	return "tkt-" + string(t.System) + "-" + strings.ToLower(t.Key)
+}
+
+// Drafts holds drafts awaiting signature, keyed by task ID. It is safe for
+// concurrent use.
+type Drafts struct {
+	mu     sync.Mutex
+	drafts map[string]Draft
+}
+
+// NewDrafts returns an empty draft store.
+func NewDrafts() *Drafts {
+	return &Drafts{drafts: make(map[string]Draft)}
+}
+
+// Put stores a draft, replacing an earlier draft from the same ticket.
+func (d *Drafts) Put(dr Draft) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.drafts[dr.Task.ID] = dr
+}
+
+// Get returns the draft with the given task ID.
+func (d *Drafts) Get(id string) (Draft, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dr, ok := d.drafts[id]
+	return dr, ok
+}
+
+// Take removes and returns a draft, for an operator about to sign it.
+func (d *Drafts) Take(id string) (Draft, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dr, ok := d.drafts[id]
+	delete(d.drafts, id)
+	return dr, ok
+}
+
+// List returns an engagement's drafts, oldest first; an empty engagement
+// lists all.
+func (d *Drafts) List(engagement string) []Draft {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []Draft
+	for _, dr := range d.drafts {
+		if engagement == "" || dr.Task.Engagement == engagement {
+			out = append(out, dr)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].ReceivedAt.Equal(out[j].ReceivedAt) {
+			return out[i].ReceivedAt.Before(out[j].ReceivedAt)
+		}
+		return out[i].Task.ID < out[j].Task.ID
+	})
+	return out
+}
@@ -0,0 +1,94 @@
+package intake
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func jiraRule() Rule {
+	return Rule{
+		Name: "jira-spray", System: SystemJira, Project: "SEC", Label: "rte-a",
+		Statuses: []string{"approved"}, TaskType: rte.TaskSimulateCredentialSpray, TTLSeconds: 1800,
+		Techniques: []string{"T1110.003"}, EngagementField: "customfield_10100",
+		OperatorField: "customfield_10101", ApprovedBy: "lead-bob",
+		Params:   map[string]string{"target": "customfield_10102", "max_attempts": "customfield_10103"},
+		Defaults: map[string]string{"max_attempts": "5", "usernames": "svc-test"},
+	}
+}
+
+func TestRule_Map(t *testing.T) {
+	tk, _ := ParseJira([]byte(jiraPayload))
+	r := jiraRule()
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if !r.Matches(tk) {
+		t.Fatal("rule does not match")
+	}
+	now := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	d, err := r.Map(tk, now)
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	task := d.Task
+	if task.ID != "tkt-jira-sec-142" || task.Engagement != "eng-2026-q1" || task.Operator != "op-alice" ||
+		task.ApprovedBy != "lead-bob" || task.State != rte.StatePending {
+		t.Fatalf("unexpected draft task %+v", task)
+	}
+	want := map[string]string{ParamTicket: "jira:SEC-142", "target": "10.4.2.0/24", "max_attempts": "3", "usernames": "svc-test"}
+	for k, v := range want {
+		if task.Params[k] != v {
+			t.Errorf("param %s: got %q, want %q", k, task.Params[k], v)
+		}
+	}
+
+	tk.Status = "In Review"
+	if _, err := r.Map(tk, now); !errors.Is(err, ErrNotApproved) {
+		t.Errorf("expected ErrNotApproved, got %v", err)
+	}
+	tk.Status = "Approved"
+	delete(tk.Fields, "customfield_10101")
+	if _, err := r.Map(tk, now); err == nil {
+		t.Error("expected missing operator field to fail")
+	}
+	tk.Labels = nil
+	if r.Matches(tk) {
+		t.Error("expected rule to require its label")
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	for _, r := range []Rule{
+		{},
+		{Name: "x", System: "github"},
+		{Name: "x", System: SystemJira, Engagement: "e", Operator: "o"},
+		{Name: "x", System: SystemJira, RequireApproval: true, Operator: "o"},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("expected %+v to fail", r)
+		}
+	}
+}
+
+func TestDrafts(t *testing.T) {
+	d := NewDrafts()
+	base := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	d.Put(Draft{Task: rte.Task{ID: "b", Engagement: "eng-1"}, ReceivedAt: base.Add(time.Minute)})
+	d.Put(Draft{Task: rte.Task{ID: "a", Engagement: "eng-1"}, ReceivedAt: base})
+	d.Put(Draft{Task: rte.Task{ID: "c", Engagement: "eng-2"}, ReceivedAt: base})
+	if got := d.List("eng-1"); len(got) != 2 || got[0].Task.ID != "a" {
+		t.Fatalf("unexpected list %+v", got)
+	}
+	if _, ok := d.Take("a"); !ok {
+		t.Fatal("Take: draft missing")
+	}
+	if _, ok := d.Get("a"); ok {
+		t.Fatal("taken draft still listed")
+	}
+	if got := d.List(""); len(got) != 2 {
+		t.Fatalf("expected 2 drafts left, got %d", len(got))
+	}
+}
@@ -0,0 +1,187 @@
+// Package intake turns approved change tickets from Jira or ServiceNow into
+// draft RTE-A tasks awaiting operator signature, so the customer's
+// change-management system stays the front door for engagement work. This
+// package never signs anything; a draft becomes a task only when an
+// operator signs it and an approver countersigns it.
+package intake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// System names a ticketing system.
+type System string
+
+const (
+	SystemJira       System = "jira"
+	SystemServiceNow System = "servicenow"
+)
+
+// Ticket is the system-neutral view of a change ticket that mapping rules
+// work from.
+type Ticket struct {
+	System  System `json:"system"`
+	Key     string `json:"key"`
+	Project string `json:"project,omitempty"`
+	Summary string `json:"summary"`
+	Status  string `json:"status"`
+	// Approved is set when the ticket system records an approval.
+	Approved   bool     `json:"approved"`
+	ApprovedBy string   `json:"approved_by,omitempty"`
+	Reporter   string   `json:"reporter,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	// Fields holds custom fields and catalog variables, stringified.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Ref returns the ticket's reference as recorded in draft task params,
+// e.g. "jira:SEC-142".
+func (t Ticket) Ref() string {
+	return string(t.System) + ":" + t.Key
+}
+
+// jiraWebhook is the subset of a Jira issue webhook payload intake reads.
+type jiraWebhook struct {
+	WebhookEvent string `json:"webhookEvent"`
+	Issue        struct {
+		Key    string `json:"key"`
+		Self   string `json:"self"`
+		Fields struct {
+			Summary string   `json:"summary"`
+			Labels  []string `json:"labels"`
+			Status  struct {
+				Name string `json:"name"`
+			} `json:"status"`
+			Project struct {
+				Key string `json:"key"`
+			} `json:"project"`
+			Reporter struct {
+				Name         string `json:"name"`
+				EmailAddress string `json:"emailAddress"`
+			} `json:"reporter"`
+		} `json:"fields"`
+	} `json:"issue"`
+}
+
+// ParseJira decodes a Jira issue webhook. Jira has no built-in approval
+// field, so Approved is left unset; rules gate on Status instead. Custom
+// fields (customfield_*) with scalar or {"value": ...} values are copied
+// into Fields.
+func ParseJira(body []byte) (Ticket, error) {
+	var hook jiraWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return Ticket{}, fmt.Errorf("decode jira webhook: %w", err)
+	}
+	if hook.Issue.Key == "" {
+		return Ticket{}, errors.New("jira webhook has no issue key")
+	}
+	f := hook.Issue.Fields
+	t := Ticket{
+		System:   SystemJira,
+		Key:      hook.Issue.Key,
+		Project:  f.Project.Key,
+		Summary:  f.Summary,
+		Status:   f.Status.Name,
+		Reporter: firstNonEmpty(f.Reporter.EmailAddress, f.Reporter.Name),
+		Labels:   f.Labels,
+		URL:      hook.Issue.Self,
+	}
+	var raw struct {
+		Issue struct {
+			Fields map[string]json.RawMessage `json:"fields"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return Ticket{}, fmt.Errorf("decode jira webhook: %w", err)
+	}
+	for name, v := range raw.Issue.Fields {
+		if !strings.HasPrefix(name, "customfield_") {
+			continue
+		}
+		if s, ok := scalar(v); ok {
+			if t.Fields == nil {
+				t.Fields = make(map[string]string)
+			}
+			t.Fields[name] = s
+		}
+	}
+	return t, nil
+}
+
+// serviceNowRecord is the change_request record a ServiceNow outbound REST
+// message or business rule posts.
+type serviceNowRecord struct {
+	Number           string            `json:"number"`
+	ShortDescription string            `json:"short_description"`
+	State            string            `json:"state"`
+	Approval         string            `json:"approval"`
+	ApprovedBy       string            `json:"approved_by"`
+	RequestedBy      string            `json:"requested_by"`
+	AssignmentGroup  string            `json:"assignment_group"`
+	URL              string            `json:"url"`
+	Variables        map[string]string `json:"variables"`
+}
+
+// ParseServiceNow decodes a ServiceNow change_request record. approval
+// "approved" sets Approved; catalog variables become Fields.
+func ParseServiceNow(body []byte) (Ticket, error) {
+	var rec serviceNowRecord
+	if err := json.Unmarshal(body, &rec); err != nil {
+		return Ticket{}, fmt.Errorf("decode servicenow record: %w", err)
+	}
+	if rec.Number == "" {
+		return Ticket{}, errors.New("servicenow record has no number")
+	}
+	return Ticket{
+		System:     SystemServiceNow,
+		Key:        rec.Number,
+		Project:    rec.AssignmentGroup,
+		Summary:    rec.ShortDescription,
+		Status:     rec.State,
+		Approved:   strings.EqualFold(rec.Approval, "approved"),
+		ApprovedBy: rec.ApprovedBy,
+		Reporter:   rec.RequestedBy,
+		URL:        rec.URL,
+		Fields:     rec.Variables,
+	}, nil
+}
+
+// scalar stringifies a JSON string, number, or bool, or the "value" or
+// "name" member of an object, as Jira uses for select fields.
+func scalar(v json.RawMessage) (string, bool) {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s, s != ""
+	}
+	var n json.Number
+	if json.Unmarshal(v, &n) == nil {
+		return n.String(), true
+	}
+	var b bool
+	if json.Unmarshal(v, &b) == nil {
+		return fmt.Sprint(b), true
+	}
+	var obj struct {
+		Value string `json:"value"`
+		Name  string `json:"name"`
+	}
+	if json.Unmarshal(v, &obj) == nil {
+		if s := firstNonEmpty(obj.Value, obj.Name); s != "" {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
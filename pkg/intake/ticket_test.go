@@ -0,0 +1,73 @@
+package intake
+
+import "testing"
+
+const jiraPayload = `{
+  "webhookEvent": "jira:issue_updated",
+  "issue": {
+    "key": "SEC-142",
+    "self": "https://acme.atlassian.net/rest/api/2/issue/10042",
+    "fields": {
+      "summary": "Q1 password spray against VPN portal",
+      "labels": ["rte-a", "red-team"],
+      "status": {"name": "Approved"},
+      "project": {"key": "SEC"},
+      "reporter": {"name": "jdoe", "emailAddress": "jdoe@acme.example"},
+      "customfield_10100": "eng-2026-q1",
+      "customfield_10101": {"value": "op-alice"},
+      "customfield_10102": "10.4.2.0/24",
+      "customfield_10103": 3,
+      "customfield_10104": null
+    }
+  }
+}`
+
+const serviceNowPayload = `{
+  "number": "CHG0031337",
+  "short_description": "Phishing simulation, finance team",
+  "state": "Scheduled",
+  "approval": "approved",
+  "approved_by": "lead-bob",
+  "requested_by": "ciso@acme.example",
+  "assignment_group": "Red Team",
+  "url": "https://acme.service-now.example/change_request.do?sys_id=abc",
+  "variables": {"engagement": "eng-2026-q1", "operator": "op-alice", "target": "vpn.acme.example"}
+}`
+
+func TestParseJira(t *testing.T) {
+	tk, err := ParseJira([]byte(jiraPayload))
+	if err != nil {
+		t.Fatalf("ParseJira: %v", err)
+	}
+	if tk.Ref() != "jira:SEC-142" || tk.Status != "Approved" || tk.Project != "SEC" || tk.Reporter != "jdoe@acme.example" {
+		t.Fatalf("unexpected ticket %+v", tk)
+	}
+	want := map[string]string{
+		"customfield_10100": "eng-2026-q1", "customfield_10101": "op-alice",
+		"customfield_10102": "10.4.2.0/24", "customfield_10103": "3",
+	}
+	if len(tk.Fields) != len(want) {
+		t.Fatalf("fields: got %v", tk.Fields)
+	}
+	for k, v := range want {
+		if tk.Fields[k] != v {
+			t.Errorf("field %s: got %q, want %q", k, tk.Fields[k], v)
+		}
+	}
+	if _, err := ParseJira([]byte(`{"issue": {}}`)); err == nil {
+		t.Error("expected webhook without issue key to fail")
+	}
+}
+
+func TestParseServiceNow(t *testing.T) {
+	tk, err := ParseServiceNow([]byte(serviceNowPayload))
+	if err != nil {
+		t.Fatalf("ParseServiceNow: %v", err)
+	}
+	if tk.Ref() != "servicenow:CHG0031337" || !tk.Approved || tk.ApprovedBy != "lead-bob" || tk.Fields["target"] != "vpn.acme.example" {
+		t.Fatalf("unexpected ticket %+v", tk)
+	}
+	if _, err := ParseServiceNow([]byte(`not json`)); err == nil {
+		t.Error("expected malformed record to fail")
+	}
+}
@@ -0,0 +1,105 @@
+// Package logging configures log/slog for RTE-A components: JSON lines
+// tagged with the emitting component, correlation keys shared by every
+// component, and redaction of sensitive values before anything is written.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Correlation keys attached to log lines.
+const (
+	KeyComponent  = "component"
+	KeyEngagement = "engagement"
+	KeyTaskID     = "task_id"
+	KeyOperator   = "operator"
+	KeyTaskType   = "task_type"
+)
+
+// RedactedValue replaces sensitive values in log output.
+const RedactedValue = "[REDACTED]"
+
+// DefaultSensitiveKeys are matched as substrings of lowercased attribute
+// keys, so "smtp_password" and "X-Api-Key" are both redacted.
+var DefaultSensitiveKeys = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey", "api-key",
+	"authorization", "cookie", "private_key", "credential", "session",
+}
+
+// Options configures New.
+type Options struct {
+	// Component names the emitting component, such as "controller" or "agent".
+	Component string
+	// Level defaults to slog.LevelInfo.
+	Level slog.Leveler
+	// SensitiveKeys replaces DefaultSensitiveKeys when non-nil.
+	SensitiveKeys []string
+}
+
+// New returns a JSON logger writing to w that tags each line with the
+// component and redacts sensitive attributes.
+func New(w io.Writer, opts Options) *slog.Logger {
+	keys := opts.SensitiveKeys
+	if keys == nil {
+		keys = DefaultSensitiveKeys
+	}
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: opts.Level, ReplaceAttr: Redactor(keys...)})
+	l := slog.New(h)
+	if opts.Component != "" {
+		l = l.With(KeyComponent, opts.Component)
+	}
+	return l
+}
+
+// Redactor returns a slog ReplaceAttr function that masks attributes whose
+// key matches one of keys, including entries of map[string]string values
+// (such as task params logged whole).
+func Redactor(keys ...string) func(groups []string, a slog.Attr) slog.Attr {
+	lowered := make([]string, len(keys))
+	for i, k := range keys {
+		lowered[i] = strings.ToLower(k)
+	}
+	sensitive := func(key string) bool {
+		key = strings.ToLower(key)
+		for _, k := range lowered {
+			if strings.Contains(key, k) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(_ []string, a slog.Attr) slog.Attr {
+		if sensitive(a.Key) {
+			return slog.String(a.Key, RedactedValue)
+		}
+		if a.Value.Kind() == slog.KindAny {
+			if m, ok := a.Value.Any().(map[string]string); ok {
+				out := make(map[string]string, len(m))
+				for k, v := range m {
+					if sensitive(k) {
+						v = RedactedValue
+					}
+					out[k] = v
+				}
+				return slog.Any(a.Key, out)
+			}
+		}
+		return a
+	}
+}
+
+// Discard returns a logger that drops everything, for components that were
+// given no logger.
+func Discard() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
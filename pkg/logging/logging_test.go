@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew_ComponentAndRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Options{Component: "agent"})
+	l.Info("attempt",
+		"password", "hunter2",
+		slog.Group("params", "target", "10.0.0.5", "smtp_password", "p@ss"),
+		"params_map", map[string]string{"username": "rtea-test1", "API_KEY": "abc"},
+		"Authorization", "Bearer xyz",
+	)
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "p@ss", "abc", "xyz"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("secret %q leaked: %s", secret, out)
+		}
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("not JSON: %v", err)
+	}
+	if rec[KeyComponent] != "agent" || rec["params"].(map[string]any)["target"] != "10.0.0.5" {
+		t.Errorf("unexpected record %v", rec)
+	}
+	if rec["params_map"].(map[string]any)["username"] != "rtea-test1" {
+		t.Errorf("non-sensitive map entry lost: %v", rec)
+	}
+}
+
+func TestNew_CustomKeysAndLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Options{Level: slog.LevelWarn, SensitiveKeys: []string{"realm"}})
+	l.Info("dropped")
+	l.Warn("kept", "realm", "CORP.EXAMPLE", "password", "visible")
+	out := buf.String()
+	if strings.Contains(out, "dropped") || strings.Contains(out, "CORP.EXAMPLE") || !strings.Contains(out, "visible") {
+		t.Fatalf("unexpected output %s", out)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	l := Discard().With("a", 1).WithGroup("g")
+	if l.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("discard logger should be disabled")
+	}
+	l.Error("nothing")
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	return b.String()
+}
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("rte_tasks_total", "Tasks seen.", "type")
+	g := r.NewGaugeVec("rte_queue_depth", "Queued tasks.")
+	c.Inc("simulate_login")
+	c.Add(2, "simulate_login")
+	c.Inc(`we"ird`)
+	g.Set(7)
+	g.Add(-2)
+
+	got := render(t, r)
+	for _, want := range []string{
+		"# TYPE rte_queue_depth gauge\nrte_queue_depth 5\n",
+		"# TYPE rte_tasks_total counter\n",
+		`rte_tasks_total{type="simulate_login"} 3`,
+		`rte_tasks_total{type="we\"ird"} 1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "rte_queue_depth") > strings.Index(got, "rte_tasks_total") {
+		t.Error("families not sorted by name")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("rte_duration_seconds", "Durations.", []float64{0.1, 1}, "type")
+	for _, v := range []float64{0.05, 0.5, 2} {
+		h.Observe(v, "inventory")
+	}
+	got := render(t, r)
+	for _, want := range []string{
+		`rte_duration_seconds_bucket{type="inventory",le="0.1"} 1`,
+		`rte_duration_seconds_bucket{type="inventory",le="1"} 2`,
+		`rte_duration_seconds_bucket{type="inventory",le="+Inf"} 3`,
+		`rte_duration_seconds_sum{type="inventory"} 2.55`,
+		`rte_duration_seconds_count{type="inventory"} 3`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("a_total", "A.", "x")
+	for name, f := range map[string]func(){
+		"duplicate":       func() { r.NewCounterVec("a_total", "again") },
+		"label count":     func() { c.Inc() },
+		"negative":        func() { c.Add(-1, "x") },
+		"unsorted bucket": func() { r.NewHistogramVec("h", "H.", []float64{2, 1}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("up_total", "Up.").Inc()
+	srv := httptest.NewServer(Handler(r))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") || !strings.Contains(string(body), "up_total 1") {
+		t.Fatalf("unexpected response %q: %s", resp.Header.Get("Content-Type"), body)
+	}
+	resp, _ = http.Post(srv.URL, "text/plain", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status %d, want 405", resp.StatusCode)
+	}
+}
@@ -0,0 +1,577 @@
+// Package miniyaml decodes the subset of YAML that hand-written rule and
+// definition files use: block mappings and sequences, plain, quoted, and
+// block (| and >) scalars, single-line flow collections, comments, and
+// "---"-separated documents. Anchors, aliases, tags, and complex keys are
+// rejected rather than misread.
+//
+// Documents decode to the values encoding/json produces with UseNumber:
+// map[string]any, []any, string, json.Number, bool, and nil. Unmarshal
+// goes through JSON, so targets use json struct tags.
+package miniyaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a syntax error at a 1-based line.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("yaml: line %d: %s", e.Line, e.Msg) }
+
+// Decode decodes a single-document file. An empty document is nil.
+func Decode(data []byte) (any, error) {
+	docs, err := DecodeAll(data)
+	if err != nil {
+		return nil, err
+	}
+	switch len(docs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return docs[0], nil
+	}
+	return nil, fmt.Errorf("yaml: %d documents where one was expected", len(docs))
+}
+
+// DecodeAll decodes every document in data.
+func DecodeAll(data []byte) ([]any, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("yaml: input is not valid UTF-8")
+	}
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	var docs []any
+	start, explicit := 0, false
+	flush := func(end int) error {
+		p := &parser{lines: lines[start:end], offset: start}
+		if !p.skip() {
+			if explicit {
+				docs = append(docs, nil)
+			}
+			return nil
+		}
+		v, err := p.node(0)
+		if err != nil {
+			return err
+		}
+		if p.skip() {
+			return p.errorf("unexpected content after the document")
+		}
+		docs = append(docs, v)
+		return nil
+	}
+	for i, l := range lines {
+		if strings.HasPrefix(l, "%") {
+			return nil, &Error{Line: i + 1, Msg: "directives are not supported"}
+		}
+		marker := l == "---" || strings.HasPrefix(l, "--- ")
+		if !marker && l != "..." {
+			continue
+		}
+		if err := flush(i); err != nil {
+			return nil, err
+		}
+		if rest := strings.TrimSpace(strings.TrimPrefix(l, "---")); marker && rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, &Error{Line: i + 1, Msg: "content on a document marker is not supported"}
+		}
+		start, explicit = i+1, marker
+	}
+	if err := flush(len(lines)); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// Unmarshal decodes a single-document file into v through encoding/json.
+func Unmarshal(data []byte, v any) error {
+	b, err := JSON(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// JSON converts a single-document file to JSON, for callers that decode
+// with their own json.Decoder settings, such as DisallowUnknownFields.
+func JSON(data []byte) ([]byte, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+type parser struct {
+	lines  []string
+	offset int
+	i      int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &Error{Line: p.offset + p.i + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// skip moves past blank and comment lines, reporting whether a line
+// remains.
+func (p *parser) skip() bool {
+	for ; p.i < len(p.lines); p.i++ {
+		t := strings.TrimLeft(p.lines[p.i], " ")
+		if t != "" && !strings.HasPrefix(t, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// current returns the indentation and comment-free content of the current
+// line; callers have called skip.
+func (p *parser) current() (int, string, error) {
+	l := p.lines[p.i]
+	t := strings.TrimLeft(l, " ")
+	if strings.HasPrefix(t, "\t") {
+		return 0, "", p.errorf("tabs are not allowed in indentation")
+	}
+	return len(l) - len(t), stripComment(t), nil
+}
+
+func isSeqItem(s string) bool { return s == "-" || strings.HasPrefix(s, "- ") }
+
+// node parses the block node starting at the current line, whose
+// indentation must be at least min.
+func (p *parser) node(min int) (any, error) {
+	if !p.skip() {
+		return nil, nil
+	}
+	ind, s, err := p.current()
+	if err != nil {
+		return nil, err
+	}
+	if ind < min {
+		return nil, nil
+	}
+	if isSeqItem(s) {
+		return p.sequence(ind)
+	}
+	if _, _, ok, err := splitKey(s); err != nil {
+		return nil, p.errorf("%v", err)
+	} else if ok {
+		return p.mapping(ind)
+	}
+	return p.value(ind-1, s)
+}
+
+// mapping parses the block mapping whose keys sit at indentation ind.
+func (p *parser) mapping(ind int) (any, error) {
+	m := map[string]any{}
+	for p.skip() {
+		cur, s, err := p.current()
+		if err != nil {
+			return nil, err
+		}
+		if cur < ind {
+			break
+		}
+		if cur > ind {
+			return nil, p.errorf("unexpected indentation")
+		}
+		if isSeqItem(s) {
+			return nil, p.errorf("sequence item where a mapping key was expected")
+		}
+		key, rest, ok, err := splitKey(s)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if !ok {
+			return nil, p.errorf("expected a mapping key")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		if rest != "" {
+			if m[key], err = p.value(ind, rest); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		p.i++
+		m[key] = nil
+		if !p.skip() {
+			break
+		}
+		next, ns, err := p.current()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case next > ind:
+			m[key], err = p.node(next)
+		case next == ind && isSeqItem(ns):
+			m[key], err = p.sequence(ind)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// sequence parses the block sequence whose dashes sit at indentation ind.
+func (p *parser) sequence(ind int) (any, error) {
+	out := []any{}
+	for p.skip() {
+		cur, s, err := p.current()
+		if err != nil {
+			return nil, err
+		}
+		if cur != ind || !isSeqItem(s) {
+			if cur > ind {
+				return nil, p.errorf("unexpected indentation")
+			}
+			break
+		}
+		item := strings.TrimLeft(s[1:], " ")
+		if item == "" {
+			p.i++
+			v, err := p.node(ind + 1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		// Re-read the item as a node indented to where its content starts,
+		// so a mapping's later keys line up with its first.
+		raw := p.lines[p.i]
+		off := len(raw) - len(strings.TrimLeft(raw[ind+1:], " "))
+		p.lines[p.i] = strings.Repeat(" ", off) + raw[off:]
+		v, err := p.node(off)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// value parses a scalar or flow collection, s, that starts on the current
+// line as the value of a node indented at parent. Plain and quoted scalars
+// may continue on more-indented lines, and "|" or ">" starts a block
+// scalar.
+func (p *parser) value(parent int, s string) (any, error) {
+	if s[0] == '|' || s[0] == '>' {
+		return p.blockScalar(parent, s)
+	}
+	line := p.i
+	p.i++
+	if s[0] != '[' && s[0] != '{' {
+		// Fold continuation lines into the scalar.
+		for p.skip() {
+			cur, t, err := p.current()
+			if err != nil {
+				return nil, err
+			}
+			if cur <= parent {
+				break
+			}
+			if s[0] != '"' && s[0] != '\'' {
+				if _, _, key, _ := splitKey(t); key || isSeqItem(t) {
+					return nil, p.errorf("unexpected indentation")
+				}
+			}
+			s += " " + t
+			p.i++
+		}
+	}
+	v, rest, err := flowValue(s, false)
+	if err == nil && strings.TrimSpace(rest) != "" {
+		err = fmt.Errorf("unexpected %q after value", strings.TrimSpace(rest))
+	}
+	if err != nil {
+		return nil, &Error{Line: p.offset + line + 1, Msg: err.Error()}
+	}
+	return v, nil
+}
+
+// blockScalar parses a literal (|) or folded (>) scalar with header s.
+func (p *parser) blockScalar(parent int, s string) (any, error) {
+	folded := s[0] == '>'
+	chomp := byte(0)
+	for _, c := range []byte(strings.TrimSpace(s[1:])) {
+		switch {
+		case c == '-' || c == '+':
+			chomp = c
+		default:
+			return nil, p.errorf("unsupported block scalar header %q", s)
+		}
+	}
+	p.i++
+	var body []string
+	indent := -1
+	for ; p.i < len(p.lines); p.i++ {
+		l := p.lines[p.i]
+		t := strings.TrimLeft(l, " ")
+		n := len(l) - len(t)
+		if t == "" {
+			body = append(body, "")
+			continue
+		}
+		if n <= parent {
+			break
+		}
+		if indent < 0 {
+			indent = n
+		}
+		if n < indent {
+			return nil, p.errorf("block scalar line is less indented than its first line")
+		}
+		body = append(body, l[indent:])
+	}
+	trailing := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trailing++
+	}
+	var b strings.Builder
+	for i, l := range body {
+		if i > 0 {
+			prev := body[i-1]
+			switch {
+			case !folded || l == "":
+				b.WriteByte('\n')
+			case prev == "":
+			case strings.HasPrefix(l, " ") || strings.HasPrefix(prev, " "):
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(l)
+	}
+	out := b.String()
+	switch {
+	case len(body) == 0:
+	case chomp == '+':
+		out += strings.Repeat("\n", trailing+1)
+	case chomp == 0:
+		out += "\n"
+	}
+	return out, nil
+}
+
+// splitKey splits a "key: value" line. ok is false if s is not a mapping
+// entry.
+func splitKey(s string) (key, rest string, ok bool, err error) {
+	if s[0] == '"' || s[0] == '\'' {
+		k, after, err := quoted(s)
+		if err != nil {
+			return "", "", false, err
+		}
+		if after == ":" || strings.HasPrefix(after, ": ") {
+			return k, strings.TrimSpace(after[1:]), true, nil
+		}
+		return "", "", false, nil
+	}
+	if s[0] == '[' || s[0] == '{' {
+		return "", "", false, nil
+	}
+	if s[0] == '?' && (len(s) == 1 || s[1] == ' ') {
+		return "", "", false, errors.New("complex keys are not supported")
+	}
+	i := strings.Index(s, ": ")
+	if i < 0 && strings.HasSuffix(s, ":") {
+		i = len(s) - 1
+	}
+	if i <= 0 {
+		return "", "", false, nil
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), true, nil
+}
+
+// stripComment removes a trailing comment outside quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+					i++
+				} else {
+					quote = 0
+				}
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,:", s[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return strings.TrimRight(s[:i], " ")
+		}
+	}
+	return strings.TrimRight(s, " ")
+}
+
+// flowValue parses one value from the start of s, returning what follows.
+// inFlow stops plain scalars at flow indicators.
+func flowValue(s string, inFlow bool) (any, string, error) {
+	s = strings.TrimLeft(s, " ")
+	if s == "" {
+		return nil, "", nil
+	}
+	switch s[0] {
+	case '"', '\'':
+		v, rest, err := quoted(s)
+		return v, rest, err
+	case '[':
+		var out []any
+		s = strings.TrimLeft(s[1:], " ")
+		for {
+			if strings.HasPrefix(s, "]") {
+				return append([]any{}, out...), s[1:], nil
+			}
+			v, rest, err := flowValue(s, true)
+			if err != nil {
+				return nil, "", err
+			}
+			out = append(out, v)
+			rest = strings.TrimLeft(rest, " ")
+			switch {
+			case strings.HasPrefix(rest, ","):
+				s = strings.TrimLeft(rest[1:], " ")
+			case strings.HasPrefix(rest, "]"):
+				s = rest
+			default:
+				return nil, "", errors.New("unterminated flow sequence")
+			}
+		}
+	case '{':
+		m := map[string]any{}
+		s = strings.TrimLeft(s[1:], " ")
+		for {
+			if strings.HasPrefix(s, "}") {
+				return m, s[1:], nil
+			}
+			k, rest, err := flowValue(s, true)
+			if err != nil {
+				return nil, "", err
+			}
+			key, ok := k.(string)
+			rest = strings.TrimLeft(rest, " ")
+			if !ok || !strings.HasPrefix(rest, ":") {
+				return nil, "", errors.New("flow mapping entry must be a string key and a value")
+			}
+			if _, dup := m[key]; dup {
+				return nil, "", fmt.Errorf("duplicate key %q", key)
+			}
+			if m[key], rest, err = flowValue(rest[1:], true); err != nil {
+				return nil, "", err
+			}
+			rest = strings.TrimLeft(rest, " ")
+			switch {
+			case strings.HasPrefix(rest, ","):
+				s = strings.TrimLeft(rest[1:], " ")
+			case strings.HasPrefix(rest, "}"):
+				s = rest
+			default:
+				return nil, "", errors.New("unterminated flow mapping")
+			}
+		}
+	case '&', '*', '!':
+		return nil, "", errors.New("anchors, aliases, and tags are not supported")
+	}
+	end := len(s)
+	if inFlow {
+		for i := 0; i < len(s); i++ {
+			if strings.IndexByte(",]}", s[i]) >= 0 || (s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ')) {
+				end = i
+				break
+			}
+		}
+	}
+	return plain(strings.TrimSpace(s[:end])), s[end:], nil
+}
+
+// quoted parses the quoted scalar at the start of s.
+func quoted(s string) (string, string, error) {
+	q := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == q && q == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == q:
+			return b.String(), s[i+1:], nil
+		case c == '\\' && q == '"':
+			if i+1 == len(s) {
+				return "", "", errors.New("unterminated escape")
+			}
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case ' ':
+				b.WriteByte(' ')
+			case 'x', 'u', 'U':
+				n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+				if i+n >= len(s) {
+					return "", "", errors.New("short escape")
+				}
+				r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+				if err != nil {
+					return "", "", fmt.Errorf("bad escape: %w", err)
+				}
+				b.WriteRune(rune(r))
+				i += n
+			default:
+				return "", "", fmt.Errorf("unknown escape \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", errors.New("unterminated quoted scalar")
+}
+
+var numberPattern = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?$`)
+
+// plain resolves a plain scalar with the YAML 1.2 core schema.
+func plain(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if numberPattern.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10))
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return s
+}
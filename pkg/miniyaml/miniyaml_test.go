@@ -0,0 +1,89 @@
+package miniyaml
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	for name, tc := range map[string]struct{ in, want string }{
+		"mapping": {"a: 1\nb: two # comment\nc: 'it''s # not a comment'\n", `{"a":1,"b":"two","c":"it's # not a comment"}`},
+		"scalars": {"n: ~\nt: true\nf: 1.50\ni: +007\ns: 0x1F\nq: \"tab\\tline\\u00e9\"\n", `{"f":1.5,"i":7,"n":null,"q":"tab\tlineé","s":"0x1F","t":true}`},
+		"nested": {
+			"detection:\n  selection:\n    EventID: 4625\n    Image|endswith:\n      - '\\cmd.exe'\n      - '\\pwsh.exe'\n  condition: selection\n",
+			`{"detection":{"condition":"selection","selection":{"EventID":4625,"Image|endswith":["\\cmd.exe","\\pwsh.exe"]}}}`,
+		},
+		"compact sequence": {"tags:\n- attack.t1110\n- attack.credential_access\nlevel: high\n", `{"level":"high","tags":["attack.t1110","attack.credential_access"]}`},
+		"sequence of mappings": {
+			"- name: a\n  ttl: 60\n-   name: b\n    params: {target: 10.0.0.5, ports: [22, 443]}\n- - x\n  - y\n-\n  deep: true\n",
+			`[{"name":"a","ttl":60},{"name":"b","params":{"ports":[22,443],"target":"10.0.0.5"}},["x","y"],{"deep":true}]`,
+		},
+		"block scalars": {
+			"lit: |\n  line one\n    indented\n\n  line three\nfold: >-\n  joined\n  words\n\n  new paragraph\nkeep: |+\n  x\n\nend: 1\n",
+			`{"end":1,"fold":"joined words\nnew paragraph","keep":"x\n\n","lit":"line one\n  indented\n\nline three\n"}`,
+		},
+		"multi-line plain": {"description: Detects a thing\n  that continues here\nid: x\n", `{"description":"Detects a thing that continues here","id":"x"}`},
+		"empty values":     {"a:\nb: []\nc: {}\n", `{"a":null,"b":[],"c":{}}`},
+		"url value":        {"ref: https://example.com/a#b\n", `{"ref":"https://example.com/a#b"}`},
+	} {
+		got, err := Decode([]byte(tc.in))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		b, _ := json.Marshal(got)
+		if string(b) != tc.want {
+			t.Errorf("%s:\n got %s\nwant %s", name, b, tc.want)
+		}
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	docs, err := DecodeAll([]byte("---\ntitle: one\n---\n# second\ntitle: two\n...\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[1].(map[string]any)["title"] != "two" {
+		t.Errorf("docs = %v", docs)
+	}
+	if _, err := Decode([]byte("a: 1\n---\nb: 2\n")); err == nil {
+		t.Error("Decode accepted two documents")
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in   string
+		line int
+	}{
+		"duplicate key":  {"a: 1\nb: 2\na: 3\n", 3},
+		"bad indent":     {"a:\n  b: 1\n    c: 2\n", 3},
+		"alias":          {"a: &x 1\nb: *x\n", 1},
+		"tab indent":     {"a:\n\tb: 1\n", 2},
+		"unterminated":   {"a: 'open\nb: 2\n", 1},
+		"trailing flow":  {"a: [1, 2] extra\n", 1},
+		"mixed sequence": {"a: 1\n- b\n", 2},
+	} {
+		_, err := Decode([]byte(tc.in))
+		var ye *Error
+		if !errors.As(err, &ye) || ye.Line != tc.line {
+			t.Errorf("%s: got %v, want an error on line %d", name, err, tc.line)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var v struct {
+		Name  string            `json:"name"`
+		TTL   int               `json:"ttl"`
+		Tags  []string          `json:"tags"`
+		Param map[string]string `json:"params"`
+	}
+	if err := Unmarshal([]byte("name: scan\nttl: 600\ntags: [a, b]\nparams:\n  target: \"10.0.0.5\"\n"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "scan" || v.TTL != 600 || len(v.Tags) != 2 || v.Param["target"] != "10.0.0.5" {
+		t.Errorf("v = %+v", v)
+	}
+}
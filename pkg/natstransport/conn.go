@@ -0,0 +1,399 @@
+package natstransport
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxPayload bounds one message read from the server, whatever the server
+// advertises.
+const maxPayload = 8 << 20
+
+// subscriptionBuffer is how many undelivered messages a subscription holds
+// before it drops new ones, as a NATS slow consumer would.
+const subscriptionBuffer = 256
+
+// ErrClosed is returned once the connection is closed.
+var ErrClosed = errors.New("nats connection closed")
+
+// Options configure a connection.
+type Options struct {
+	// Name identifies the client in server monitoring.
+	Name string
+	// User and Password, or Token, authenticate to the server.
+	User     string
+	Password string
+	Token    string
+	// Dial, if set, opens the connection instead of net.Dialer, for TLS.
+	Dial func(ctx context.Context, addr string) (net.Conn, error)
+}
+
+// Msg is one message received on a subscription.
+type Msg struct {
+	Subject string
+	// Reply is the subject a request expects its response on.
+	Reply string
+	Data  []byte
+
+	conn *Conn
+}
+
+// Respond publishes data to the message's reply subject.
+func (m Msg) Respond(data []byte) error {
+	if m.Reply == "" {
+		return errors.New("message has no reply subject")
+	}
+	return m.conn.Publish(m.Reply, "", data)
+}
+
+// Conn is a connection to a NATS server, speaking the core client protocol
+// without JetStream. It is safe for concurrent use.
+type Conn struct {
+	nc net.Conn
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu     sync.Mutex
+	subs   map[uint64]*Subscription
+	sid    uint64
+	pongs  []chan struct{}
+	err    error
+	closed bool
+	done   chan struct{}
+}
+
+// Subscription receives the messages published to a subject.
+type Subscription struct {
+	Subject string
+
+	conn    *Conn
+	sid     uint64
+	msgs    chan Msg
+	stop    chan struct{}
+	once    sync.Once
+	dropped int
+}
+
+// Dial connects and authenticates to the server at addr, a host:port.
+func Dial(ctx context.Context, addr string, opts Options) (*Conn, error) {
+	var nc net.Conn
+	var err error
+	if opts.Dial != nil {
+		nc, err = opts.Dial(ctx, addr)
+	} else {
+		var d net.Dialer
+		nc, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("nats dial %s: %w", addr, err)
+	}
+	c := &Conn{nc: nc, w: bufio.NewWriter(nc), subs: map[uint64]*Subscription{}, done: make(chan struct{})}
+	stop := context.AfterFunc(ctx, func() { nc.Close() })
+	defer stop()
+	r := bufio.NewReader(nc)
+	if err := c.handshake(r, opts); err != nil {
+		nc.Close()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("nats connect: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("nats connect: %w", err)
+	}
+	go c.readLoop(r)
+	return c, nil
+}
+
+func (c *Conn) handshake(r *bufio.Reader, opts Options) error {
+	line, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("expected INFO, got %q", line)
+	}
+	connect, _ := json.Marshal(map[string]any{
+		"verbose": false, "pedantic": false, "lang": "go", "version": "rte", "protocol": 1,
+		"name": opts.Name, "user": opts.User, "pass": opts.Password, "auth_token": opts.Token,
+	})
+	c.w.WriteString("CONNECT " + string(connect) + "\r\nPING\r\n")
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(serverError(line))
+		}
+	}
+}
+
+// Publish sends data to subject; reply, if set, is where a responder should
+// answer.
+func (c *Conn) Publish(subject, reply string, data []byte) error {
+	if err := checkSubject(subject); err != nil {
+		return err
+	}
+	cmd := "PUB " + subject
+	if reply != "" {
+		cmd += " " + reply
+	}
+	cmd += " " + strconv.Itoa(len(data)) + "\r\n"
+	return c.write(func(w *bufio.Writer) {
+		w.WriteString(cmd)
+		w.Write(data)
+		w.WriteString("\r\n")
+	})
+}
+
+// Subscribe calls fn with each message published to subject, which may end
+// in the wildcards * and >. Calls are sequential, on a goroutine of the
+// subscription's own, and messages arriving while more than
+// subscriptionBuffer are waiting are dropped.
+func (c *Conn) Subscribe(subject string, fn func(Msg)) (*Subscription, error) {
+	s, err := c.subscribe(subject, 0)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			select {
+			case m := <-s.msgs:
+				fn(m)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+func (c *Conn) subscribe(subject string, max int) (*Subscription, error) {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("invalid subject %q", subject)
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	c.sid++
+	s := &Subscription{Subject: subject, conn: c, sid: c.sid, msgs: make(chan Msg, subscriptionBuffer), stop: make(chan struct{})}
+	c.subs[s.sid] = s
+	c.mu.Unlock()
+	sid := strconv.FormatUint(s.sid, 10)
+	err := c.write(func(w *bufio.Writer) {
+		w.WriteString("SUB " + subject + " " + sid + "\r\n")
+		if max > 0 {
+			w.WriteString("UNSUB " + sid + " " + strconv.Itoa(max) + "\r\n")
+		}
+	})
+	if err != nil {
+		s.Unsubscribe()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Unsubscribe stops the subscription.
+func (s *Subscription) Unsubscribe() error {
+	var err error
+	s.once.Do(func() {
+		close(s.stop)
+		s.conn.mu.Lock()
+		delete(s.conn.subs, s.sid)
+		s.conn.mu.Unlock()
+		err = s.conn.write(func(w *bufio.Writer) {
+			w.WriteString("UNSUB " + strconv.FormatUint(s.sid, 10) + "\r\n")
+		})
+		if errors.Is(err, ErrClosed) {
+			err = nil
+		}
+	})
+	return err
+}
+
+// Dropped returns how many messages the subscription dropped because its
+// handler fell behind.
+func (s *Subscription) Dropped() int {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	return s.dropped
+}
+
+// Request publishes data to subject and waits for the first response.
+func (c *Conn) Request(ctx context.Context, subject string, data []byte) (Msg, error) {
+	var b [12]byte
+	rand.Read(b[:])
+	inbox := "_INBOX." + hex.EncodeToString(b[:])
+	s, err := c.subscribe(inbox, 1)
+	if err != nil {
+		return Msg{}, err
+	}
+	defer s.Unsubscribe()
+	if err := c.Publish(subject, inbox, data); err != nil {
+		return Msg{}, err
+	}
+	select {
+	case m := <-s.msgs:
+		return m, nil
+	case <-ctx.Done():
+		return Msg{}, fmt.Errorf("request %s: %w", subject, ctx.Err())
+	case <-c.done:
+		return Msg{}, c.closeErr()
+	}
+}
+
+// Flush waits until the server has processed everything sent so far.
+func (c *Conn) Flush(ctx context.Context) error {
+	pong := make(chan struct{})
+	c.mu.Lock()
+	c.pongs = append(c.pongs, pong)
+	c.mu.Unlock()
+	if err := c.write(func(w *bufio.Writer) { w.WriteString("PING\r\n") }); err != nil {
+		return err
+	}
+	select {
+	case <-pong:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return c.closeErr()
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.fail(ErrClosed)
+	return nil
+}
+
+func (c *Conn) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// fail closes the connection with err, keeping the first error.
+func (c *Conn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed, c.err = true, err
+	c.nc.Close()
+	close(c.done)
+}
+
+func (c *Conn) write(fn func(*bufio.Writer)) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.closeErr(); err != nil {
+		return err
+	}
+	fn(c.w)
+	if err := c.w.Flush(); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Conn) readLoop(r *bufio.Reader) {
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			if err := c.deliver(r, strings.Fields(line[4:])); err != nil {
+				c.fail(err)
+				return
+			}
+		case line == "PING":
+			c.write(func(w *bufio.Writer) { w.WriteString("PONG\r\n") })
+		case line == "PONG":
+			c.mu.Lock()
+			if len(c.pongs) > 0 {
+				close(c.pongs[0])
+				c.pongs = c.pongs[1:]
+			}
+			c.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			c.fail(errors.New(serverError(line)))
+			return
+		}
+		// +OK and INFO updates need no action.
+	}
+}
+
+// deliver reads the payload of a MSG <subject> <sid> [reply] <size> line.
+func (c *Conn) deliver(r *bufio.Reader, f []string) error {
+	if len(f) != 3 && len(f) != 4 {
+		return fmt.Errorf("nats: malformed MSG %q", f)
+	}
+	size, err := strconv.Atoi(f[len(f)-1])
+	if err != nil || size < 0 || size > maxPayload {
+		return fmt.Errorf("nats: bad payload size %q", f[len(f)-1])
+	}
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	m := Msg{Subject: f[0], Data: buf[:size], conn: c}
+	if len(f) == 4 {
+		m.Reply = f[2]
+	}
+	sid, _ := strconv.ParseUint(f[1], 10, 64)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.subs[sid]
+	if s == nil {
+		return nil
+	}
+	select {
+	case s.msgs <- m:
+	default:
+		s.dropped++
+	}
+	return nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// serverError turns "-ERR 'Authorization Violation'" into an error string.
+func serverError(line string) string {
+	return "nats: " + strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+}
+
+func checkSubject(s string) error {
+	if s == "" || strings.ContainsAny(s, " \t\r\n*>") {
+		return fmt.Errorf("invalid publish subject %q", s)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package natstransport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNATS routes core protocol messages between its clients.
+type fakeNATS struct {
+	addr  string
+	token string
+
+	mu   sync.Mutex
+	subs map[*fakeClient]map[string]*fakeSub
+}
+
+type fakeClient struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+type fakeSub struct {
+	subject string
+	left    int // messages before auto-unsubscribe; 0 means unlimited
+}
+
+func startNATS(t *testing.T, token string) *fakeNATS {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeNATS{addr: ln.Addr().String(), token: token, subs: map[*fakeClient]map[string]*fakeSub{}}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeNATS) dial(t *testing.T) *Conn {
+	t.Helper()
+	c, err := Dial(context.Background(), f.addr, Options{Name: t.Name(), Token: f.token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// subscriptions returns how many subscriptions match subject.
+func (f *fakeNATS) subscriptions(subject string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, subs := range f.subs {
+		for _, s := range subs {
+			if subjectMatches(s.subject, subject) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (f *fakeNATS) waitFor(t *testing.T, subject string, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); f.subscriptions(subject) < n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("no subscriber on %s", subject)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *fakeClient) send(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.WriteString(s)
+	c.w.Flush()
+}
+
+func (f *fakeNATS) serve(nc net.Conn) {
+	defer nc.Close()
+	c := &fakeClient{w: bufio.NewWriter(nc)}
+	f.mu.Lock()
+	f.subs[c] = map[string]*fakeSub{}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		delete(f.subs, c)
+		f.mu.Unlock()
+	}()
+	c.send(`INFO {"server_id":"fake","max_payload":1048576}` + "\r\n")
+	r := bufio.NewReader(nc)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		op, rest, _ := strings.Cut(line, " ")
+		args := strings.Fields(rest)
+		switch op {
+		case "CONNECT":
+			var opts struct {
+				Token string `json:"auth_token"`
+			}
+			json.Unmarshal([]byte(rest), &opts)
+			if opts.Token != f.token {
+				c.send("-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			c.send("PONG\r\n")
+		case "SUB":
+			f.mu.Lock()
+			f.subs[c][args[1]] = &fakeSub{subject: args[0]}
+			f.mu.Unlock()
+		case "UNSUB":
+			f.mu.Lock()
+			if len(args) == 2 {
+				n, _ := strconv.Atoi(args[1])
+				if s := f.subs[c][args[0]]; s != nil {
+					s.left = n
+				}
+			} else {
+				delete(f.subs[c], args[0])
+			}
+			f.mu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(args[len(args)-1])
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			reply := ""
+			if len(args) == 3 {
+				reply = args[1] + " "
+			}
+			f.route(args[0], reply, buf[:size])
+		}
+	}
+}
+
+func (f *fakeNATS) route(subject, reply string, data []byte) {
+	f.mu.Lock()
+	type delivery struct {
+		c   *fakeClient
+		msg string
+	}
+	var out []delivery
+	for c, subs := range f.subs {
+		for sid, s := range subs {
+			if !subjectMatches(s.subject, subject) {
+				continue
+			}
+			out = append(out, delivery{c, fmt.Sprintf("MSG %s %s %s%d\r\n%s\r\n", subject, sid, reply, len(data), data)})
+			if s.left > 0 {
+				if s.left--; s.left == 0 {
+					delete(subs, sid)
+				}
+			}
+		}
+	}
+	f.mu.Unlock()
+	for _, d := range out {
+		d.c.send(d.msg)
+	}
+}
+
+func subjectMatches(pattern, subject string) bool {
+	p, s := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, tok := range p {
+		if tok == ">" {
+			return len(s) > i
+		}
+		if i >= len(s) || (tok != "*" && tok != s[i]) {
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
+
+func TestConn_PublishSubscribe(t *testing.T) {
+	f := startNATS(t, "s3cret")
+	a, b := f.dial(t), f.dial(t)
+	got := make(chan Msg, 4)
+	sub, err := a.Subscribe("lab.*.events", func(m Msg) { got <- m })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"lab.one.events", "lab.one.other", "lab.two.events"} {
+		if err := b.Publish(s, "", []byte("hi "+s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"lab.one.events", "lab.two.events"} {
+		select {
+		case m := <-got:
+			if m.Subject != want || string(m.Data) != "hi "+want {
+				t.Errorf("got %s %q, want %s", m.Subject, m.Data, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no message on %s", want)
+		}
+	}
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Publish("lab.*.events", "", nil); err == nil {
+		t.Error("published to a wildcard subject")
+	}
+}
+
+func TestConn_Request(t *testing.T) {
+	f := startNATS(t, "")
+	responder, requester := f.dial(t), f.dial(t)
+	if _, err := responder.Subscribe("echo", func(m Msg) { m.Respond(append([]byte("re: "), m.Data...)) }); err != nil {
+		t.Fatal(err)
+	}
+	f.waitFor(t, "echo", 1)
+	m, err := requester.Request(context.Background(), "echo", []byte("ping"))
+	if err != nil || string(m.Data) != "re: ping" {
+		t.Fatalf("Request = %q, %v", m.Data, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := requester.Request(ctx, "nobody", nil); err == nil {
+		t.Error("request with no responder returned")
+	}
+}
+
+func TestDial_AuthRejected(t *testing.T) {
+	f := startNATS(t, "s3cret")
+	_, err := Dial(context.Background(), f.addr, Options{Token: "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Fatalf("Dial with a bad token: %v", err)
+	}
+}
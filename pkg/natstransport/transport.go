@@ -0,0 +1,363 @@
+// Package natstransport carries tasks, cancels, halts, and results over
+// NATS, for labs that already run it. It speaks the core NATS protocol
+// itself, so it has no dependencies; NATS delivers at most once, so pair it
+// with the janitor, which requeues tasks whose messages were lost.
+//
+// Subjects, under a configurable prefix:
+//
+//	<prefix>.<engagement>.<agent>.tasks   signed tasks for one agent
+//	<prefix>.<engagement>.<agent>.cancel  cancel requests, answered with a CancelAck
+//	<prefix>.<engagement>.halt            signed halts, to every agent
+//	<prefix>.<engagement>.results         signed results from every agent
+//
+// Cancels and halts travel a priority lane: each has its own subscription,
+// and the agent applies them to its executor directly rather than pushing
+// them onto the queue behind simulation work, so a full queue or a flood
+// of tasks cannot delay them. Controllers stamp both with the time they
+// were sent, and agents record the delivery latency in
+// rte_control_delivery_seconds.
+package natstransport
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// DefaultPrefix is the first token of every subject.
+const DefaultPrefix = "rte"
+
+// transportName labels this transport's control delivery metrics.
+const transportName = "nats"
+
+// DefaultCancelTimeout bounds how long an agent waits for a cancelled task
+// to stop before acking without its result.
+const DefaultCancelTimeout = 30 * time.Second
+
+// Subjects names the subjects under Prefix.
+type Subjects struct {
+	// Prefix is the first token; "" means DefaultPrefix.
+	Prefix string
+}
+
+func (s Subjects) prefix() string {
+	if s.Prefix == "" {
+		return DefaultPrefix
+	}
+	return s.Prefix
+}
+
+// Tasks is the subject an agent receives its tasks on.
+func (s Subjects) Tasks(engagement, agent string) string {
+	return s.prefix() + "." + engagement + "." + agent + ".tasks"
+}
+
+// Cancel is the subject an agent answers cancel requests on.
+func (s Subjects) Cancel(engagement, agent string) string {
+	return s.prefix() + "." + engagement + "." + agent + ".cancel"
+}
+
+// Halt is the subject every agent in an engagement receives halts on.
+func (s Subjects) Halt(engagement string) string {
+	return s.prefix() + "." + engagement + ".halt"
+}
+
+// Results is the subject agents publish an engagement's results on.
+func (s Subjects) Results(engagement string) string {
+	return s.prefix() + "." + engagement + ".results"
+}
+
+// checkToken rejects names that would split or wildcard a subject.
+func checkToken(kind, v string) error {
+	if v == "" || strings.ContainsAny(v, ". \t\r\n*>") {
+		return fmt.Errorf("%s %q cannot be used in a NATS subject", kind, v)
+	}
+	return nil
+}
+
+// sentCancel and sentHalt stamp control messages with the time the
+// controller sent them. The embedded fields flatten, so an agent that does
+// not know SentAt still reads the bare cancel or halt.
+type sentCancel struct {
+	rte.TaskCancel
+	SentAt time.Time `json:"sent_at,omitempty"`
+}
+
+type sentHalt struct {
+	rte.Message
+	SentAt time.Time `json:"sent_at,omitempty"`
+}
+
+// CancelAck answers a cancel request.
+type CancelAck struct {
+	Engagement string `json:"engagement"`
+	TaskID     string `json:"task_id"`
+	Agent      string `json:"agent"`
+	// Accepted reports whether the agent stopped, or began stopping, the
+	// task.
+	Accepted bool `json:"accepted"`
+	// Result is the cancelled task's result, if it stopped in time.
+	Result *rte.TaskResult `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Controller publishes work to agents and collects their results.
+type Controller struct {
+	Conn     *Conn
+	Subjects Subjects
+}
+
+// Dispatch sends a signed task to agent.
+func (c *Controller) Dispatch(agent string, st *rte.SignedTask) error {
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	if err := checkToken("agent", agent); err != nil {
+		return err
+	}
+	if err := checkToken("engagement", st.Task.Engagement); err != nil {
+		return err
+	}
+	return c.publish(c.Subjects.Tasks(st.Task.Engagement, agent), rte.TaskMessage(st))
+}
+
+// Halt sends a signed halt to every agent in its engagement.
+func (c *Controller) Halt(sh *rte.SignedHalt) error {
+	if sh == nil {
+		return errors.New("signed halt is nil")
+	}
+	if err := checkToken("engagement", sh.Halt.Engagement); err != nil {
+		return err
+	}
+	return c.publish(c.Subjects.Halt(sh.Halt.Engagement), sentHalt{Message: rte.HaltMessage(sh), SentAt: time.Now().UTC()})
+}
+
+func (c *Controller) publish(subject string, m any) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return c.Conn.Publish(subject, "", data)
+}
+
+// Cancel asks agent to stop a running task and waits for its ack. A
+// rejected cancel is returned as an ack with Accepted false, not an error.
+func (c *Controller) Cancel(ctx context.Context, agent string, tc rte.TaskCancel) (*CancelAck, error) {
+	if err := checkToken("agent", agent); err != nil {
+		return nil, err
+	}
+	if err := checkToken("engagement", tc.Engagement); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(sentCancel{TaskCancel: tc, SentAt: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.Conn.Request(ctx, c.Subjects.Cancel(tc.Engagement, agent), data)
+	if err != nil {
+		return nil, err
+	}
+	var ack CancelAck
+	if err := json.Unmarshal(m.Data, &ack); err != nil {
+		return nil, fmt.Errorf("decode cancel ack: %w", err)
+	}
+	return &ack, nil
+}
+
+// Results calls fn with each result published for engagement, or with the
+// error if a message is malformed or its signature does not verify.
+func (c *Controller) Results(engagement string, fn func(*rte.SignedResult, error)) (*Subscription, error) {
+	if err := checkToken("engagement", engagement); err != nil {
+		return nil, err
+	}
+	return c.Conn.Subscribe(c.Subjects.Results(engagement), func(m Msg) {
+		var sr rte.SignedResult
+		if err := json.Unmarshal(m.Data, &sr); err != nil {
+			fn(nil, fmt.Errorf("decode result on %s: %w", m.Subject, err))
+			return
+		}
+		if err := rte.VerifyResult(&sr); err != nil {
+			fn(nil, fmt.Errorf("result for %s: %w", sr.Result.TaskID, err))
+			return
+		}
+		fn(&sr, nil)
+	})
+}
+
+// Agent receives one agent's work from NATS. Tasks are pushed to Queue for
+// a Scheduler to run; cancels and halts are applied to Executor directly,
+// on the priority lane. A halt also drops the engagement's queued tasks.
+// Set the scheduler's OnResult to the agent's OnResult to publish results.
+type Agent struct {
+	Conn       *Conn
+	Subjects   Subjects
+	Engagement string
+	Name       string
+	Queue      *rte.Queue
+	Executor   *rte.Executor
+	// PrivateKey and PublicKey sign published results.
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	// CancelTimeout bounds the wait for a cancelled task; 0 means
+	// DefaultCancelTimeout.
+	CancelTimeout time.Duration
+	// Metrics, if set, records control delivery latency.
+	Metrics *rte.Metrics
+	Logger  *slog.Logger
+}
+
+// Serve subscribes to the agent's subjects and handles messages until ctx
+// ends.
+func (a *Agent) Serve(ctx context.Context) error {
+	if a.Conn == nil || a.Queue == nil || a.Executor == nil {
+		return errors.New("nats agent needs a connection, a queue, and an executor")
+	}
+	if err := checkToken("engagement", a.Engagement); err != nil {
+		return err
+	}
+	if err := checkToken("agent", a.Name); err != nil {
+		return err
+	}
+	var subs []*Subscription
+	defer func() {
+		for _, s := range subs {
+			s.Unsubscribe()
+		}
+	}()
+	for subject, fn := range map[string]func(Msg){
+		a.Subjects.Tasks(a.Engagement, a.Name):  a.enqueue,
+		a.Subjects.Halt(a.Engagement):           func(m Msg) { go a.halt(ctx, m) },
+		a.Subjects.Cancel(a.Engagement, a.Name): func(m Msg) { go a.cancel(ctx, m) },
+	} {
+		s, err := a.Conn.Subscribe(subject, fn)
+		if err != nil {
+			return err
+		}
+		subs = append(subs, s)
+	}
+	if err := a.Conn.Flush(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (a *Agent) enqueue(m Msg) {
+	msg, err := rte.ParseMessage(m.Data)
+	if err == nil && msg.Kind != rte.MessageTask {
+		err = fmt.Errorf("unexpected %q message", msg.Kind)
+	}
+	if err == nil {
+		err = a.Queue.Push(msg)
+	}
+	if err != nil && a.Logger != nil {
+		a.Logger.Warn("nats message dropped", "subject", m.Subject, "error", err)
+	}
+}
+
+// delivered records the latency of a control message stamped at sent.
+func (a *Agent) delivered(kind rte.MessageKind, sent time.Time) {
+	if !sent.IsZero() {
+		a.Metrics.ControlDelivered(transportName, kind, time.Since(sent))
+	}
+}
+
+// halt applies a signed halt, drops the engagement's queued tasks, and
+// reports a result for each task it stopped.
+func (a *Agent) halt(ctx context.Context, m Msg) {
+	var sh sentHalt
+	err := rte.DecodeStrict(m.Data, &sh)
+	if err == nil && (sh.Kind != rte.MessageHalt || sh.Halt == nil) {
+		err = fmt.Errorf("unexpected %q message", sh.Kind)
+	}
+	if err != nil {
+		if a.Logger != nil {
+			a.Logger.Warn("nats message dropped", "subject", m.Subject, "error", err)
+		}
+		return
+	}
+	a.delivered(rte.MessageHalt, sh.SentAt)
+	results, err := a.Executor.Halt(ctx, sh.Halt)
+	if err == nil {
+		eng := sh.Halt.Halt.Engagement
+		a.Queue.Remove(func(q rte.Message) bool {
+			return q.Kind == rte.MessageTask && q.Task.Task.Engagement == eng
+		})
+	}
+	for i := range results {
+		a.OnResult(sh.Message, &results[i], nil)
+	}
+	if err != nil {
+		a.OnResult(sh.Message, nil, err)
+	}
+}
+
+func (a *Agent) cancel(ctx context.Context, m Msg) {
+	ack := CancelAck{Engagement: a.Engagement, Agent: a.Name}
+	var sc sentCancel
+	if err := rte.DecodeStrict(m.Data, &sc); err != nil {
+		ack.Error = "malformed cancel: " + err.Error()
+	} else if tc := sc.TaskCancel; tc.Engagement != a.Engagement {
+		ack.TaskID, ack.Error = tc.TaskID, "cancel is for engagement "+tc.Engagement
+	} else {
+		a.delivered(rte.MessageCancel, sc.SentAt)
+		ack.TaskID = tc.TaskID
+		timeout := a.CancelTimeout
+		if timeout <= 0 {
+			timeout = DefaultCancelTimeout
+		}
+		cctx, cancel := context.WithTimeout(ctx, timeout)
+		res, err := a.Executor.Cancel(cctx, tc)
+		cancel()
+		switch {
+		case err == nil:
+			ack.Accepted, ack.Result = true, res
+		case errors.Is(err, context.DeadlineExceeded):
+			ack.Accepted, ack.Error = true, err.Error()
+		default:
+			ack.Error = err.Error()
+		}
+	}
+	data, _ := json.Marshal(ack)
+	if err := m.Respond(data); err != nil && a.Logger != nil {
+		a.Logger.Warn("cancel ack not sent", "task_id", ack.TaskID, "error", err)
+	}
+}
+
+// OnResult signs and publishes a task's result; its signature matches
+// Scheduler.OnResult. Messages without a result, such as rejected tasks,
+// are logged.
+func (a *Agent) OnResult(m rte.Message, res *rte.TaskResult, err error) {
+	if res == nil {
+		if err != nil && a.Logger != nil {
+			a.Logger.Warn("task produced no result", "kind", m.Kind, "error", err)
+		}
+		return
+	}
+	if perr := a.Publish(res); perr != nil && a.Logger != nil {
+		a.Logger.Warn("result not published", "task_id", res.TaskID, "error", perr)
+	}
+}
+
+// Publish signs res and publishes it on its engagement's results subject.
+func (a *Agent) Publish(res *rte.TaskResult) error {
+	sr, err := rte.SignResult(*res, a.PrivateKey, a.PublicKey)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(sr)
+	if err != nil {
+		return err
+	}
+	if err := checkToken("engagement", res.Engagement); err != nil {
+		return err
+	}
+	return a.Conn.Publish(a.Subjects.Results(res.Engagement), "", data)
+}
@@ -0,0 +1,187 @@
+package natstransport
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/metrics"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func signedTask(t *testing.T, id, token string) *rte.SignedTask {
+	t.Helper()
+	pub, priv, _ := rte.GenerateKeyPair()
+	st, err := rte.SignTask(rte.Task{
+		ID: id, Engagement: "eng-1", Type: rte.TaskSimulateLogin, CreatedAt: time.Now().UTC(),
+		TTLSeconds: 600, Operator: "op-alice", ApprovedBy: "lead-bob", State: rte.StatePending,
+		Params: map[string]string{"target": "192.168.1.0/24"}, CancelToken: token,
+	}, priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestTransport(t *testing.T) {
+	f := startNATS(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started := make(chan string, 2)
+	e := rte.NewExecutor()
+	// The test signs its halt with a throwaway key.
+	e.VerifyHalt = rte.VerifyHalt
+	_ = e.Register(rte.TaskSimulateLogin, rte.HandlerFunc(func(ctx context.Context, task rte.Task) (any, error) {
+		started <- task.ID
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	}))
+	pub, priv, _ := rte.GenerateKeyPair()
+	agent := &Agent{
+		Conn: f.dial(t), Engagement: "eng-1", Name: "agent-7",
+		Queue: rte.NewQueue(), Executor: e, PrivateKey: priv, PublicKey: pub,
+	}
+	sched := &rte.Scheduler{Queue: agent.Queue, Executor: e, Workers: 2, OnResult: agent.OnResult}
+	go agent.Serve(ctx)
+	go sched.Run(ctx)
+	f.waitFor(t, "rte.eng-1.agent-7.cancel", 1)
+
+	ctl := &Controller{Conn: f.dial(t)}
+	results := make(chan *rte.SignedResult, 2)
+	if _, err := ctl.Results("eng-1", func(sr *rte.SignedResult, err error) {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		results <- sr
+	}); err != nil {
+		t.Fatal(err)
+	}
+	f.waitFor(t, "rte.eng-1.results", 1)
+
+	if err := ctl.Dispatch("agent-7", signedTask(t, "t-1", "tok-1")); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-started; id != "t-1" {
+		t.Fatalf("started %s", id)
+	}
+
+	ack, err := ctl.Cancel(ctx, "agent-7", rte.TaskCancel{Engagement: "eng-1", TaskID: "t-1", Token: "wrong"})
+	if err != nil || ack.Accepted || !strings.Contains(ack.Error, "token does not match") {
+		t.Fatalf("cancel with a bad token: %+v, %v", ack, err)
+	}
+	ack, err = ctl.Cancel(ctx, "agent-7", rte.TaskCancel{Engagement: "eng-1", TaskID: "t-1", Token: "tok-1", RequestedBy: "lead-bob"})
+	if err != nil || !ack.Accepted || ack.Agent != "agent-7" || ack.Result == nil || ack.Result.State != rte.StateCancelled {
+		t.Fatalf("cancel: %+v, %v", ack, err)
+	}
+
+	select {
+	case sr := <-results:
+		if sr.Result.TaskID != "t-1" || sr.Result.State != rte.StateCancelled || string(sr.PublicKey) != string(pub) {
+			t.Errorf("result %+v", sr.Result)
+		}
+	case <-ctx.Done():
+		t.Fatal("no result published")
+	}
+
+	// A halt reaches the agent's queue and stops its running work.
+	if err := ctl.Dispatch("agent-7", signedTask(t, "t-2", "")); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	hpub, hpriv, _ := rte.GenerateKeyPair()
+	sh, _ := rte.SignHalt(rte.EngagementHalt{Engagement: "eng-1", IssuedBy: "lead-bob", Reason: "hotline", IssuedAt: time.Now().UTC()}, hpriv, hpub)
+	if err := ctl.Halt(sh); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); !e.Halted("eng-1"); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("halt did not reach the executor")
+		}
+	}
+}
+
+func TestController_RejectsBadSubjects(t *testing.T) {
+	ctl := &Controller{}
+	if err := ctl.Dispatch("agent.7", signedTask(t, "t-1", "")); err == nil {
+		t.Error("dispatched to an agent name with a dot")
+	}
+	if _, err := ctl.Cancel(context.Background(), "*", rte.TaskCancel{Engagement: "eng-1"}); err == nil {
+		t.Error("cancelled on a wildcard agent")
+	}
+	if got := (Subjects{Prefix: "lab"}).Tasks("eng-1", "a"); got != "lab.eng-1.a.tasks" {
+		t.Errorf("Tasks subject = %s", got)
+	}
+}
+
+func TestTransport_ControlUnderLoad(t *testing.T) {
+	f := startNATS(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started := make(chan string, 1)
+	e := rte.NewExecutor()
+	// The test signs its halt with a throwaway key.
+	e.VerifyHalt = rte.VerifyHalt
+	_ = e.Register(rte.TaskSimulateLogin, rte.HandlerFunc(func(ctx context.Context, task rte.Task) (any, error) {
+		started <- task.ID
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	}))
+	reg := metrics.NewRegistry()
+	pub, priv, _ := rte.GenerateKeyPair()
+	q := rte.NewQueue()
+	q.MaxDepth = 10
+	agent := &Agent{
+		Conn: f.dial(t), Engagement: "eng-1", Name: "agent-7", Queue: q, Executor: e,
+		PrivateKey: priv, PublicKey: pub, Metrics: rte.NewMetrics(reg),
+	}
+	sched := &rte.Scheduler{Queue: q, Executor: e, Workers: 1, OnResult: agent.OnResult}
+	go agent.Serve(ctx)
+	go sched.Run(ctx)
+	f.waitFor(t, "rte.eng-1.agent-7.cancel", 1)
+
+	ctl := &Controller{Conn: f.dial(t)}
+	if err := ctl.Dispatch("agent-7", signedTask(t, "t-0", "tok-0")); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// The only worker is busy and the queue fills; the rest of the flood
+	// is dropped, but control messages still get through.
+	st := signedTask(t, "t-flood", "")
+	for i := 0; i < 500; i++ {
+		if err := ctl.Dispatch("agent-7", st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ack, err := ctl.Cancel(ctx, "agent-7", rte.TaskCancel{Engagement: "eng-1", TaskID: "t-0", Token: "tok-0"})
+	if err != nil || !ack.Accepted {
+		t.Fatalf("cancel under load: %+v, %v", ack, err)
+	}
+
+	<-started
+	hpub, hpriv, _ := rte.GenerateKeyPair()
+	sh, _ := rte.SignHalt(rte.EngagementHalt{Engagement: "eng-1", IssuedBy: "lead-bob", Reason: "hotline", IssuedAt: time.Now().UTC()}, hpriv, hpub)
+	if err := ctl.Halt(sh); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); !e.Halted("eng-1") || q.Len() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("halt not applied under load: halted %v, %d queued", e.Halted("eng-1"), q.Len())
+		}
+	}
+
+	var b strings.Builder
+	_, _ = reg.WriteTo(&b)
+	for _, want := range []string{
+		`rte_control_delivery_seconds_count{transport="nats",kind="cancel"} 1`,
+		`rte_control_delivery_seconds_count{transport="nats",kind="halt"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,534 @@
+// Package redisqueue is a task queue on Redis streams, shared by controller
+// replicas that push tasks and a fleet of agents that pop them. It needs
+// Redis 6.2 or later and speaks RESP itself, so it has no dependencies.
+//
+// Delivery is at least once. A popped message stays pending in the
+// stream's consumer group until the agent acks it; if the agent does not
+// ack or extend it within the visibility timeout, the next Pop on any
+// agent reclaims it. A message delivered MaxDeliveries times without an
+// ack moves to the dead-letter stream for an operator to inspect or
+// redrive. Handlers must therefore tolerate a task arriving twice, for
+// instance by taking the task's lease with rte.AcquireLease before running
+// it.
+//
+// Cancels and halts travel a priority lane: their own stream, read ahead
+// of tasks, so they do not wait behind queued simulation work. An agent
+// whose workers may all be busy also runs RunControl, which pops only
+// control messages, so they do not wait for a worker either. Push stamps
+// both with the time they were sent, and a Queue with Metrics records the
+// delivery latency in rte_control_delivery_seconds.
+package redisqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Defaults for a Queue's zero-valued fields.
+const (
+	DefaultGroup         = "agents"
+	DefaultVisibility    = 5 * time.Minute
+	DefaultMaxDeliveries = 5
+	DefaultBlock         = 5 * time.Second
+)
+
+// transportName labels this transport's control delivery metrics.
+const transportName = "redis"
+
+// ErrLost is returned by Extend and Ack when the delivery's visibility
+// timeout lapsed and another consumer reclaimed it.
+var ErrLost = errors.New("delivery was reclaimed by another consumer")
+
+// Queue is a distributed queue. Keys are Name+":control", Name+":tasks",
+// and Name+":dead". It is safe for concurrent use.
+type Queue struct {
+	Client *Client
+	Name   string
+	// Group is the consumer group agents share; "" means DefaultGroup.
+	Group string
+	// Consumer names this agent process within the group and must be
+	// unique across the fleet.
+	Consumer string
+	// Visibility is how long a popped message stays hidden from other
+	// consumers without an ack or extend; 0 means DefaultVisibility.
+	Visibility time.Duration
+	// MaxDeliveries is how many times a message is delivered before it is
+	// dead-lettered; 0 means DefaultMaxDeliveries.
+	MaxDeliveries int
+	// Block bounds one blocking read; 0 means DefaultBlock.
+	Block time.Duration
+	// Metrics, if set, records control delivery latency.
+	Metrics *rte.Metrics
+
+	mu     sync.Mutex
+	inited bool
+	buf    []*Delivery
+}
+
+// New returns a queue named name that pops as consumer.
+func New(c *Client, name, consumer string) *Queue {
+	return &Queue{Client: c, Name: name, Consumer: consumer}
+}
+
+func (q *Queue) control() string { return q.Name + ":control" }
+func (q *Queue) tasks() string   { return q.Name + ":tasks" }
+func (q *Queue) dead() string    { return q.Name + ":dead" }
+
+func (q *Queue) group() string {
+	if q.Group == "" {
+		return DefaultGroup
+	}
+	return q.Group
+}
+
+func (q *Queue) visibility() time.Duration {
+	if q.Visibility <= 0 {
+		return DefaultVisibility
+	}
+	return q.Visibility
+}
+
+func (q *Queue) maxDeliveries() int {
+	if q.MaxDeliveries <= 0 {
+		return DefaultMaxDeliveries
+	}
+	return q.MaxDeliveries
+}
+
+func (q *Queue) block() time.Duration {
+	if q.Block <= 0 {
+		return DefaultBlock
+	}
+	return q.Block
+}
+
+// init creates the streams and consumer group on first use.
+func (q *Queue) init(ctx context.Context) error {
+	q.mu.Lock()
+	done := q.inited
+	q.mu.Unlock()
+	if done {
+		return nil
+	}
+	if q.Client == nil || q.Name == "" {
+		return errors.New("redis queue needs a client and a name")
+	}
+	for _, key := range []string{q.control(), q.tasks()} {
+		_, err := q.Client.Do(ctx, "XGROUP", "CREATE", key, q.group(), "0", "MKSTREAM")
+		var re Error
+		if err != nil && !(errors.As(err, &re) && strings.HasPrefix(string(re), "BUSYGROUP")) {
+			return fmt.Errorf("create consumer group on %s: %w", key, err)
+		}
+	}
+	q.mu.Lock()
+	q.inited = true
+	q.mu.Unlock()
+	return nil
+}
+
+// Push enqueues m. Cancels and halts go on the control stream.
+func (q *Queue) Push(ctx context.Context, m rte.Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if err := q.init(ctx); err != nil {
+		return err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	args := []string{"XADD", q.tasks(), "*", "msg", string(data)}
+	if m.Control() {
+		args[1] = q.control()
+		args = append(args, "sent", strconv.FormatInt(time.Now().UnixNano(), 10))
+	}
+	key := args[1]
+	if _, err := q.Client.Do(ctx, args...); err != nil {
+		return fmt.Errorf("push to %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delivery is one popped message, pending until it is acked.
+type Delivery struct {
+	Message rte.Message
+	// ID is the stream entry ID.
+	ID string
+	// Attempt counts deliveries of this message, from 1.
+	Attempt int
+	// SentAt is when a cancel or halt was pushed; zero for tasks.
+	SentAt time.Time
+
+	q      *Queue
+	stream string
+}
+
+// Ack marks the message done and removes it from the stream.
+func (d *Delivery) Ack(ctx context.Context) error {
+	if err := d.owned(ctx); err != nil {
+		return fmt.Errorf("ack %s: %w", d.ID, err)
+	}
+	if _, err := d.q.Client.Do(ctx, "XACK", d.stream, d.q.group(), d.ID); err != nil {
+		return fmt.Errorf("ack %s: %w", d.ID, err)
+	}
+	if _, err := d.q.Client.Do(ctx, "XDEL", d.stream, d.ID); err != nil {
+		return fmt.Errorf("delete %s: %w", d.ID, err)
+	}
+	return nil
+}
+
+// Extend restarts the delivery's visibility timeout, for handlers that run
+// longer than it.
+func (d *Delivery) Extend(ctx context.Context) error {
+	if err := d.owned(ctx); err != nil {
+		return fmt.Errorf("extend %s: %w", d.ID, err)
+	}
+	if _, err := d.q.Client.Do(ctx, "XCLAIM", d.stream, d.q.group(), d.q.Consumer, "0", d.ID, "JUSTID"); err != nil {
+		return fmt.Errorf("extend %s: %w", d.ID, err)
+	}
+	return nil
+}
+
+// owned checks that the delivery is still pending to this consumer. XACK
+// and XCLAIM do not check, and would otherwise settle or steal a message
+// another consumer reclaimed.
+func (d *Delivery) owned(ctx context.Context) error {
+	reply, err := d.q.Client.Do(ctx, "XPENDING", d.stream, d.q.group(), d.ID, d.ID, "1", d.q.Consumer)
+	if err != nil {
+		return err
+	}
+	if rows, _ := reply.([]any); len(rows) == 0 {
+		return ErrLost
+	}
+	return nil
+}
+
+// Pop blocks until a message is available or ctx ends. Messages whose
+// visibility timeout lapsed are reclaimed first, then new control
+// messages, then new tasks.
+func (q *Queue) Pop(ctx context.Context) (*Delivery, error) {
+	return q.pop(ctx, q.control(), q.tasks())
+}
+
+// PopControl is Pop for cancels and halts only.
+func (q *Queue) PopControl(ctx context.Context) (*Delivery, error) {
+	return q.pop(ctx, q.control())
+}
+
+// pop pops from keys, in order of priority.
+func (q *Queue) pop(ctx context.Context, keys ...string) (*Delivery, error) {
+	if err := q.init(ctx); err != nil {
+		return nil, err
+	}
+	for {
+		if d := q.buffered(keys); d != nil {
+			return d, nil
+		}
+		for _, key := range keys {
+			d, err := q.reclaim(ctx, key)
+			if err != nil || d != nil {
+				return d, err
+			}
+		}
+		if err := q.read(ctx, keys); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// buffered takes the first buffered delivery from one of keys.
+func (q *Queue) buffered(keys []string) *Delivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, d := range q.buf {
+		if slices.Contains(keys, d.stream) {
+			q.buf = slices.Delete(q.buf, i, i+1)
+			return d
+		}
+	}
+	return nil
+}
+
+// reclaim claims the oldest message on key whose visibility timeout
+// lapsed, dead-lettering those out of deliveries.
+func (q *Queue) reclaim(ctx context.Context, key string) (*Delivery, error) {
+	for {
+		reply, err := q.Client.Do(ctx, "XAUTOCLAIM", key, q.group(), q.Consumer,
+			strconv.FormatInt(q.visibility().Milliseconds(), 10), "0-0", "COUNT", "1")
+		if err != nil {
+			return nil, fmt.Errorf("reclaim from %s: %w", key, err)
+		}
+		parts, _ := reply.([]any)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("reclaim from %s: malformed reply", key)
+		}
+		entries, err := parseEntries(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("reclaim from %s: %w", key, err)
+		}
+		if len(entries) == 0 {
+			return nil, nil
+		}
+		e := entries[0]
+		attempt, err := q.deliveries(ctx, key, e.id)
+		if err != nil {
+			return nil, err
+		}
+		d, err := q.deliver(ctx, key, e, attempt)
+		if err != nil || d != nil {
+			return d, err
+		}
+	}
+}
+
+// deliveries returns how many times entry id on key has been delivered.
+func (q *Queue) deliveries(ctx context.Context, key, id string) (int, error) {
+	reply, err := q.Client.Do(ctx, "XPENDING", key, q.group(), id, id, "1")
+	if err != nil {
+		return 0, fmt.Errorf("read deliveries of %s: %w", id, err)
+	}
+	rows, _ := reply.([]any)
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	row, _ := rows[0].([]any)
+	if len(row) < 4 {
+		return 0, fmt.Errorf("read deliveries of %s: malformed reply", id)
+	}
+	n, _ := row[3].(int64)
+	return int(n), nil
+}
+
+// read blocks for new messages on keys, buffering what it gets.
+func (q *Queue) read(ctx context.Context, keys []string) error {
+	args := []string{"XREADGROUP", "GROUP", q.group(), q.Consumer, "COUNT", "1",
+		"BLOCK", strconv.FormatInt(q.block().Milliseconds(), 10), "STREAMS"}
+	args = append(args, keys...)
+	for range keys {
+		args = append(args, ">")
+	}
+	reply, err := q.Client.Do(ctx, args...)
+	if errors.Is(err, Nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", q.Name, err)
+	}
+	streams, _ := reply.([]any)
+	byKey := map[string][]entry{}
+	for _, s := range streams {
+		pair, _ := s.([]any)
+		if len(pair) != 2 {
+			return fmt.Errorf("read %s: malformed reply", q.Name)
+		}
+		key, _ := pair[0].(string)
+		entries, err := parseEntries(pair[1])
+		if err != nil {
+			return fmt.Errorf("read %s: %w", key, err)
+		}
+		byKey[key] = entries
+	}
+	for _, key := range keys {
+		for _, e := range byKey[key] {
+			d, err := q.deliver(ctx, key, e, 1)
+			if err != nil {
+				return err
+			}
+			if d != nil {
+				q.mu.Lock()
+				q.buf = append(q.buf, d)
+				q.mu.Unlock()
+			}
+		}
+	}
+	return nil
+}
+
+// deliver decodes e, or dead-letters it if it is malformed or out of
+// deliveries and returns nil.
+func (q *Queue) deliver(ctx context.Context, key string, e entry, attempt int) (*Delivery, error) {
+	raw := e.fields["msg"]
+	m, err := rte.ParseMessage([]byte(raw))
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	} else if attempt > q.maxDeliveries() {
+		reason = fmt.Sprintf("not acked after %d deliveries", attempt-1)
+	}
+	if reason == "" {
+		d := &Delivery{Message: m, ID: e.id, Attempt: attempt, q: q, stream: key}
+		if ns, err := strconv.ParseInt(e.fields["sent"], 10, 64); err == nil {
+			d.SentAt = time.Unix(0, ns)
+			if attempt == 1 {
+				q.Metrics.ControlDelivered(transportName, m.Kind, time.Since(d.SentAt))
+			}
+		}
+		return d, nil
+	}
+	// Dead-letter before acking, so a crash in between duplicates the
+	// dead letter rather than losing the message.
+	if _, err := q.Client.Do(ctx, "XADD", q.dead(), "*", "stream", key, "id", e.id,
+		"deliveries", strconv.Itoa(attempt-1), "reason", reason, "msg", raw); err != nil {
+		return nil, fmt.Errorf("dead-letter %s: %w", e.id, err)
+	}
+	if _, err := q.Client.Do(ctx, "XACK", key, q.group(), e.id); err != nil {
+		return nil, fmt.Errorf("dead-letter %s: %w", e.id, err)
+	}
+	if _, err := q.Client.Do(ctx, "XDEL", key, e.id); err != nil {
+		return nil, fmt.Errorf("dead-letter %s: %w", e.id, err)
+	}
+	return nil, nil
+}
+
+// DeadLetter is a message the queue gave up on.
+type DeadLetter struct {
+	// ID is the entry ID on the dead-letter stream.
+	ID string
+	// Stream and SourceID locate the original entry.
+	Stream     string
+	SourceID   string
+	Deliveries int
+	Reason     string
+	// Raw is the message as it was queued.
+	Raw string
+}
+
+// DeadLetters returns up to count dead letters, oldest first; count 0
+// means all.
+func (q *Queue) DeadLetters(ctx context.Context, count int) ([]DeadLetter, error) {
+	args := []string{"XRANGE", q.dead(), "-", "+"}
+	if count > 0 {
+		args = append(args, "COUNT", strconv.Itoa(count))
+	}
+	reply, err := q.Client.Do(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("read dead letters: %w", err)
+	}
+	entries, err := parseEntries(reply)
+	if err != nil {
+		return nil, fmt.Errorf("read dead letters: %w", err)
+	}
+	out := make([]DeadLetter, 0, len(entries))
+	for _, e := range entries {
+		n, _ := strconv.Atoi(e.fields["deliveries"])
+		out = append(out, DeadLetter{
+			ID: e.id, Stream: e.fields["stream"], SourceID: e.fields["id"],
+			Deliveries: n, Reason: e.fields["reason"], Raw: e.fields["msg"],
+		})
+	}
+	return out, nil
+}
+
+// Redrive pushes dead letter dl back onto the queue as a new message and
+// removes it from the dead-letter stream.
+func (q *Queue) Redrive(ctx context.Context, dl DeadLetter) error {
+	m, err := rte.ParseMessage([]byte(dl.Raw))
+	if err != nil {
+		return fmt.Errorf("redrive %s: %w", dl.ID, err)
+	}
+	if err := q.Push(ctx, m); err != nil {
+		return fmt.Errorf("redrive %s: %w", dl.ID, err)
+	}
+	if _, err := q.Client.Do(ctx, "XDEL", q.dead(), dl.ID); err != nil {
+		return fmt.Errorf("redrive %s: %w", dl.ID, err)
+	}
+	return nil
+}
+
+// Run pops messages and hands each to handle until ctx ends, extending the
+// delivery while handle runs. A message is acked when handle returns nil;
+// otherwise it is redelivered after the visibility timeout. Run several
+// goroutines for concurrent handling.
+func (q *Queue) Run(ctx context.Context, handle func(context.Context, *Delivery) error) error {
+	return q.run(ctx, q.Pop, handle)
+}
+
+// RunControl is Run for cancels and halts only. Run it on a goroutine of
+// its own beside the Run workers, so control messages are handled even
+// while every worker is busy with a task.
+func (q *Queue) RunControl(ctx context.Context, handle func(context.Context, *Delivery) error) error {
+	return q.run(ctx, q.PopControl, handle)
+}
+
+func (q *Queue) run(ctx context.Context, pop func(context.Context) (*Delivery, error), handle func(context.Context, *Delivery) error) error {
+	for {
+		d, err := pop(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		hctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			t := time.NewTicker(q.visibility() / 3)
+			defer t.Stop()
+			for {
+				select {
+				case <-hctx.Done():
+					return
+				case <-t.C:
+					if errors.Is(d.Extend(hctx), ErrLost) {
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+		err = handle(hctx, d)
+		lost := hctx.Err() != nil && ctx.Err() == nil
+		cancel()
+		<-done
+		if err == nil && !lost {
+			if err := d.Ack(ctx); err != nil && !errors.Is(err, ErrLost) {
+				return err
+			}
+		}
+	}
+}
+
+type entry struct {
+	id     string
+	fields map[string]string
+}
+
+// parseEntries decodes a stream reply of [id, [field, value, ...]] pairs,
+// skipping the nil entries XAUTOCLAIM reports for deleted messages.
+func parseEntries(v any) ([]entry, error) {
+	list, ok := v.([]any)
+	if !ok && v != nil {
+		return nil, errors.New("malformed stream entries")
+	}
+	var out []entry
+	for _, item := range list {
+		if item == nil {
+			continue
+		}
+		pair, _ := item.([]any)
+		if len(pair) != 2 {
+			return nil, errors.New("malformed stream entry")
+		}
+		id, _ := pair[0].(string)
+		kv, _ := pair[1].([]any)
+		e := entry{id: id, fields: make(map[string]string, len(kv)/2)}
+		for i := 0; i+1 < len(kv); i += 2 {
+			k, _ := kv[i].(string)
+			v, _ := kv[i+1].(string)
+			e.fields[k] = v
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
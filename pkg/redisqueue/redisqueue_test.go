@@ -0,0 +1,528 @@
+package redisqueue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/metrics"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// fakeRedis serves the stream commands Queue uses, with a clock the test
+// advances to expire visibility timeouts.
+type fakeRedis struct {
+	mu      sync.Mutex
+	offset  time.Duration
+	streams map[string]*fakeStream
+	log     []string
+}
+
+type fakeStream struct {
+	seq     int
+	entries []fakeEntry
+	groups  map[string]*fakeGroup
+}
+
+type fakeEntry struct {
+	id     int
+	fields []any
+}
+
+type fakeGroup struct {
+	last int
+	pel  map[int]*fakePending
+}
+
+type fakePending struct {
+	consumer  string
+	delivered time.Time
+	count     int64
+}
+
+func startFake(t *testing.T) (*fakeRedis, *Client) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{streams: map[string]*fakeStream{}}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	c := &Client{Addr: ln.Addr().String()}
+	t.Cleanup(func() { c.Close(); ln.Close() })
+	return f, c
+}
+
+func (f *fakeRedis) advance(d time.Duration) {
+	f.mu.Lock()
+	f.offset += d
+	f.mu.Unlock()
+}
+
+func (f *fakeRedis) now() time.Time { return time.Now().Add(f.offset) }
+
+func (f *fakeRedis) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for {
+		v, err := readValue(r)
+		if err != nil {
+			return
+		}
+		items, _ := v.([]any)
+		args := make([]string, len(items))
+		for i, it := range items {
+			args[i], _ = it.(string)
+		}
+		reply := f.exec(args, func() bool {
+			// A blocked client that hung up gets nothing, as in Redis.
+			c.SetReadDeadline(time.Now().Add(time.Millisecond))
+			defer c.SetReadDeadline(time.Time{})
+			_, err := r.Peek(1)
+			var ne net.Error
+			return err == nil || errors.As(err, &ne) && ne.Timeout()
+		})
+		if _, err := c.Write(encode(nil, reply)); err != nil {
+			return
+		}
+	}
+}
+
+func encode(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "*-1\r\n"...)
+	case Error:
+		return append(b, "-"+string(v)+"\r\n"...)
+	case int64:
+		return append(b, ":"+strconv.FormatInt(v, 10)+"\r\n"...)
+	case string:
+		return append(b, "$"+strconv.Itoa(len(v))+"\r\n"+v+"\r\n"...)
+	case []any:
+		b = append(b, "*"+strconv.Itoa(len(v))+"\r\n"...)
+		for _, x := range v {
+			b = encode(b, x)
+		}
+		return b
+	}
+	panic(fmt.Sprintf("fake: cannot encode %T", v))
+}
+
+func fmtID(id int) string { return strconv.Itoa(id) + "-0" }
+
+func parseID(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSuffix(s, "-0"))
+	return n
+}
+
+func (s *fakeStream) entry(id int) *fakeEntry {
+	for i := range s.entries {
+		if s.entries[i].id == id {
+			return &s.entries[i]
+		}
+	}
+	return nil
+}
+
+func (f *fakeRedis) exec(args []string, connected func() bool) any {
+	cmd := strings.ToUpper(args[0])
+	if cmd == "XREADGROUP" {
+		// Poll outside the lock, as BLOCK does.
+		block, _ := strconv.Atoi(args[7])
+		deadline := time.Now().Add(time.Duration(block) * time.Millisecond)
+		for connected() {
+			if reply := f.readGroup(args); reply != nil || time.Now().After(deadline) {
+				return reply
+			}
+		}
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, strings.Join(args, " "))
+	now := f.now()
+	switch cmd {
+	case "AUTH", "SELECT":
+		return "OK"
+	case "XGROUP":
+		s := f.streams[args[2]]
+		if s == nil {
+			s = &fakeStream{groups: map[string]*fakeGroup{}}
+			f.streams[args[2]] = s
+		}
+		if s.groups[args[3]] != nil {
+			return Error("BUSYGROUP Consumer Group name already exists")
+		}
+		s.groups[args[3]] = &fakeGroup{pel: map[int]*fakePending{}}
+		return "OK"
+	case "XADD":
+		s := f.streams[args[1]]
+		if s == nil {
+			s = &fakeStream{groups: map[string]*fakeGroup{}}
+			f.streams[args[1]] = s
+		}
+		s.seq++
+		var fields []any
+		for _, a := range args[3:] {
+			fields = append(fields, a)
+		}
+		s.entries = append(s.entries, fakeEntry{id: s.seq, fields: fields})
+		return fmtID(s.seq)
+	case "XAUTOCLAIM":
+		s, g := f.streams[args[1]], f.streams[args[1]].groups[args[2]]
+		minIdle, _ := strconv.Atoi(args[4])
+		var ids []int
+		for id := range g.pel {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		claimed, deleted := []any{}, []any{}
+		for _, id := range ids {
+			p := g.pel[id]
+			if now.Sub(p.delivered) < time.Duration(minIdle)*time.Millisecond {
+				continue
+			}
+			e := s.entry(id)
+			if e == nil {
+				delete(g.pel, id)
+				deleted = append(deleted, fmtID(id))
+				continue
+			}
+			p.consumer, p.delivered = args[3], now
+			p.count++
+			claimed = append(claimed, []any{fmtID(id), e.fields})
+			break
+		}
+		return []any{"0-0", claimed, deleted}
+	case "XPENDING":
+		g := f.streams[args[1]].groups[args[2]]
+		p := g.pel[parseID(args[3])]
+		if p == nil || (len(args) > 6 && p.consumer != args[6]) {
+			return []any{}
+		}
+		return []any{[]any{args[3], p.consumer, int64(now.Sub(p.delivered) / time.Millisecond), p.count}}
+	case "XCLAIM":
+		g := f.streams[args[1]].groups[args[2]]
+		p := g.pel[parseID(args[5])]
+		if p == nil {
+			return []any{}
+		}
+		p.consumer, p.delivered = args[3], now
+		return []any{args[5]}
+	case "XACK":
+		g := f.streams[args[1]].groups[args[2]]
+		id := parseID(args[3])
+		if g.pel[id] == nil {
+			return int64(0)
+		}
+		delete(g.pel, id)
+		return int64(1)
+	case "XDEL":
+		s := f.streams[args[1]]
+		for i, e := range s.entries {
+			if e.id == parseID(args[2]) {
+				s.entries = append(s.entries[:i], s.entries[i+1:]...)
+				return int64(1)
+			}
+		}
+		return int64(0)
+	case "XRANGE":
+		out := []any{}
+		if s := f.streams[args[1]]; s != nil {
+			for _, e := range s.entries {
+				out = append(out, []any{fmtID(e.id), e.fields})
+			}
+		}
+		return out
+	}
+	return Error("ERR unknown command " + cmd)
+}
+
+// readGroup serves XREADGROUP GROUP g c COUNT n BLOCK ms STREAMS k... >...
+func (f *fakeRedis) readGroup(args []string) any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	group, consumer := args[2], args[3]
+	count, _ := strconv.Atoi(args[5])
+	keys := args[9 : 9+(len(args)-9)/2]
+	var out []any
+	for _, key := range keys {
+		s := f.streams[key]
+		g := s.groups[group]
+		var got []any
+		for _, e := range s.entries {
+			if e.id <= g.last || len(got) == count {
+				continue
+			}
+			g.last = e.id
+			g.pel[e.id] = &fakePending{consumer: consumer, delivered: f.now(), count: 1}
+			got = append(got, []any{fmtID(e.id), e.fields})
+		}
+		if len(got) > 0 {
+			out = append(out, []any{key, got})
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	f.log = append(f.log, strings.Join(args, " "))
+	return out
+}
+
+func (f *fakeRedis) pending(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.streams[key].groups[DefaultGroup].pel)
+}
+
+func (f *fakeRedis) length(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.streams[key].entries)
+}
+
+func taskMessage(t *testing.T, id string) rte.Message {
+	t.Helper()
+	pub, priv, _ := rte.GenerateKeyPair()
+	st, err := rte.SignTask(rte.Task{
+		ID: id, Engagement: "eng-1", Type: rte.TaskInventory, CreatedAt: time.Now().UTC(),
+		TTLSeconds: 600, Operator: "alice", ApprovedBy: "lead", State: rte.StatePending,
+	}, priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rte.TaskMessage(st)
+}
+
+func newQueue(c *Client, consumer string) *Queue {
+	q := New(c, "rte", consumer)
+	q.Visibility = time.Minute
+	q.Block = 10 * time.Millisecond
+	return q
+}
+
+func popWithin(t *testing.T, q *Queue) (*Delivery, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	return q.Pop(ctx)
+}
+
+func TestQueue_ControlFirstAndAck(t *testing.T) {
+	f, c := startFake(t)
+	ctx := context.Background()
+	q := newQueue(c, "agent-a")
+	if err := q.Push(ctx, taskMessage(t, "t-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(ctx, rte.CancelMessage(rte.TaskCancel{Engagement: "eng-1", TaskID: "t-1", Token: "tok"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(ctx, rte.Message{Kind: rte.MessageTask}); err == nil {
+		t.Error("pushed a task message without a task")
+	}
+
+	first, err := popWithin(t, q)
+	if err != nil || first.Message.Kind != rte.MessageCancel {
+		t.Fatalf("first pop = %+v, %v; want the cancel", first, err)
+	}
+	second, err := popWithin(t, q)
+	if err != nil || second.Message.Kind != rte.MessageTask || second.Message.Task.Task.ID != "t-1" || second.Attempt != 1 {
+		t.Fatalf("second pop = %+v, %v", second, err)
+	}
+	for _, d := range []*Delivery{first, second} {
+		if err := d.Ack(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if f.pending("rte:tasks") != 0 || f.length("rte:tasks") != 0 || f.length("rte:control") != 0 {
+		t.Error("acked messages left in the streams")
+	}
+}
+
+func TestQueue_VisibilityTimeout(t *testing.T) {
+	f, c := startFake(t)
+	ctx := context.Background()
+	a, b := newQueue(c, "agent-a"), newQueue(c, "agent-b")
+	if err := a.Push(ctx, taskMessage(t, "t-1")); err != nil {
+		t.Fatal(err)
+	}
+	da, err := popWithin(t, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Extending keeps it from agent-b past the original timeout.
+	f.advance(40 * time.Second)
+	if err := da.Extend(ctx); err != nil {
+		t.Fatal(err)
+	}
+	f.advance(40 * time.Second)
+	if _, err := popWithin(t, b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("agent-b popped an extended message: %v", err)
+	}
+
+	f.advance(time.Minute)
+	db, err := popWithin(t, b)
+	if err != nil || db.ID != da.ID || db.Attempt != 2 {
+		t.Fatalf("redelivery = %+v, %v", db, err)
+	}
+	if err := da.Ack(ctx); !errors.Is(err, ErrLost) {
+		t.Errorf("stale ack: %v", err)
+	}
+	if err := da.Extend(ctx); !errors.Is(err, ErrLost) {
+		t.Errorf("stale extend: %v", err)
+	}
+	if err := db.Ack(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueue_DeadLetters(t *testing.T) {
+	f, c := startFake(t)
+	ctx := context.Background()
+	q := newQueue(c, "agent-a")
+	q.MaxDeliveries = 2
+	if err := q.Push(ctx, taskMessage(t, "t-1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(ctx, "XADD", "rte:tasks", "*", "msg", "{"); err != nil {
+		t.Fatal(err)
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		d, err := popWithin(t, q)
+		if err != nil || d.Attempt != attempt {
+			t.Fatalf("attempt %d: %+v, %v", attempt, d, err)
+		}
+		f.advance(2 * time.Minute)
+	}
+	if _, err := popWithin(t, q); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("pop after max deliveries: %v", err)
+	}
+
+	dead, err := q.DeadLetters(ctx, 0)
+	if err != nil || len(dead) != 2 {
+		t.Fatalf("dead letters = %+v, %v", dead, err)
+	}
+	if dead[0].Reason != "not acked after 2 deliveries" || dead[0].Deliveries != 2 || dead[0].Stream != "rte:tasks" {
+		t.Errorf("dead letter = %+v", dead[0])
+	}
+	if !strings.HasPrefix(dead[1].Reason, "malformed") {
+		t.Errorf("dead letter = %+v", dead[1])
+	}
+	if f.pending("rte:tasks") != 0 || f.length("rte:tasks") != 0 {
+		t.Error("dead-lettered messages left in the stream")
+	}
+
+	if err := q.Redrive(ctx, dead[0]); err != nil {
+		t.Fatal(err)
+	}
+	d, err := popWithin(t, q)
+	if err != nil || d.Attempt != 1 || d.Message.Task.Task.ID != "t-1" {
+		t.Fatalf("redriven pop = %+v, %v", d, err)
+	}
+	if dead, _ := q.DeadLetters(ctx, 0); len(dead) != 1 {
+		t.Errorf("redriven letter still dead: %+v", dead)
+	}
+}
+
+func TestQueue_Run(t *testing.T) {
+	f, c := startFake(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	q := newQueue(c, "agent-a")
+	for _, id := range []string{"t-1", "t-2"} {
+		if err := q.Push(ctx, taskMessage(t, id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var handled []string
+	err := q.Run(ctx, func(_ context.Context, d *Delivery) error {
+		handled = append(handled, d.Message.Task.Task.ID)
+		if d.Message.Task.Task.ID == "t-2" {
+			cancel()
+			return errors.New("interrupted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(handled, ",") != "t-1,t-2" {
+		t.Errorf("handled %v", handled)
+	}
+	if f.pending("rte:tasks") != 1 {
+		t.Errorf("pending = %d, want the failed t-2 left for redelivery", f.pending("rte:tasks"))
+	}
+}
+
+func TestQueue_RunControl(t *testing.T) {
+	f, c := startFake(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	q := newQueue(c, "agent-a")
+	reg := metrics.NewRegistry()
+	q.Metrics = rte.NewMetrics(reg)
+	if err := q.Push(ctx, taskMessage(t, "t-1")); err != nil {
+		t.Fatal(err)
+	}
+	// The only worker is busy with t-1 until the cancel gets through.
+	busy, cancelled := make(chan struct{}), make(chan *Delivery, 1)
+	done := make(chan error, 2)
+	go func() {
+		done <- q.Run(ctx, func(hctx context.Context, d *Delivery) error {
+			close(busy)
+			<-hctx.Done()
+			return errors.New("interrupted")
+		})
+	}()
+	go func() {
+		done <- q.RunControl(ctx, func(_ context.Context, d *Delivery) error {
+			cancelled <- d
+			return nil
+		})
+	}()
+	<-busy
+	if err := q.Push(ctx, rte.CancelMessage(rte.TaskCancel{Engagement: "eng-1", TaskID: "t-1", Token: "tok"})); err != nil {
+		t.Fatal(err)
+	}
+	var d *Delivery
+	select {
+	case d = <-cancelled:
+	case <-ctx.Done():
+		t.Fatal("cancel waited behind the busy worker")
+	}
+	if d.Message.Kind != rte.MessageCancel || d.SentAt.IsZero() {
+		t.Errorf("control delivery = %+v", d)
+	}
+	for f.pending("rte:control") > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	var b strings.Builder
+	_, _ = reg.WriteTo(&b)
+	if want := `rte_control_delivery_seconds_count{transport="redis",kind="cancel"} 1`; !strings.Contains(b.String(), want) {
+		t.Errorf("metrics missing %q:\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,222 @@
+package redisqueue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxBulk bounds one bulk string read from the server.
+const maxBulk = 16 << 20
+
+// maxIdleConns bounds the connections a Client keeps open between commands.
+const maxIdleConns = 8
+
+// Nil is returned by Do for a null reply.
+var Nil = errors.New("redis: nil")
+
+// Error is an error reply from the server, such as "BUSYGROUP Consumer
+// Group name already exists".
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// Client sends commands to a Redis server over RESP. It keeps a small pool
+// of connections so a blocking read does not hold up other commands. It is
+// safe for concurrent use.
+type Client struct {
+	Addr     string
+	Username string
+	Password string
+	// DB selects a logical database; 0 is the default.
+	DB int
+	// Dial, if set, opens connections instead of net.Dialer, for TLS.
+	Dial func(ctx context.Context, addr string) (net.Conn, error)
+
+	mu   sync.Mutex
+	idle []*respConn
+}
+
+type respConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Do sends one command and returns its reply: a string for simple and bulk
+// strings, an int64, a []any for arrays, or Nil. An error reply is returned
+// as an Error.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Cancelling ctx, including at its deadline, unblocks the read.
+	conn.SetDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	reply, err := conn.do(args)
+	stop()
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	var re Error
+	if err != nil && !errors.As(err, &re) && !errors.Is(err, Nil) {
+		conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+	return reply, err
+}
+
+// Close closes the idle connections.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, conn := range c.idle {
+		conn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+func (c *Client) get(ctx context.Context) (*respConn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+	var nc net.Conn
+	var err error
+	if c.Dial != nil {
+		nc, err = c.Dial(ctx, c.Addr)
+	} else {
+		var d net.Dialer
+		nc, err = d.DialContext(ctx, "tcp", c.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis dial %s: %w", c.Addr, err)
+	}
+	conn := &respConn{Conn: nc, r: bufio.NewReader(nc)}
+	if c.Password != "" {
+		args := []string{"AUTH", c.Password}
+		if c.Username != "" {
+			args = []string{"AUTH", c.Username, c.Password}
+		}
+		if _, err := conn.do(args); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if c.DB != 0 {
+		if _, err := conn.do([]string{"SELECT", strconv.Itoa(c.DB)}); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("redis select %d: %w", c.DB, err)
+		}
+	}
+	return conn, nil
+}
+
+func (c *Client) put(conn *respConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) >= maxIdleConns {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+func (c *respConn) do(args []string) (any, error) {
+	if _, err := c.Write(appendCommand(nil, args)); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+// appendCommand encodes args as a RESP array of bulk strings.
+func appendCommand(b []byte, args []string) []byte {
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, '\r', '\n')
+	for _, a := range args {
+		b = append(b, '$')
+		b = strconv.AppendInt(b, int64(len(a)), 10)
+		b = append(b, '\r', '\n')
+		b = append(b, a...)
+		b = append(b, '\r', '\n')
+	}
+	return b
+}
+
+// readReply reads one RESP2 reply. Nested null replies are returned as nil
+// elements; only a top-level null is reported as Nil. An error reply inside
+// an array is returned as an Error element.
+func readReply(r *bufio.Reader) (any, error) {
+	v, err := readValue(r)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil, Nil
+	case Error:
+		return nil, v
+	}
+	return v, nil
+}
+
+func readValue(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return Error(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n > maxBulk {
+			return nil, fmt.Errorf("redis: bad bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = readValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
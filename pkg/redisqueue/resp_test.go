@@ -0,0 +1,62 @@
+package redisqueue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAppendCommand(t *testing.T) {
+	got := string(appendCommand(nil, []string{"XADD", "k", "*", ""}))
+	want := "*4\r\n$4\r\nXADD\r\n$1\r\nk\r\n$1\r\n*\r\n$0\r\n\r\n"
+	if got != want {
+		t.Errorf("appendCommand = %q", got)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want any
+		err  error
+	}{
+		{"+OK\r\n", "OK", nil},
+		{":42\r\n", int64(42), nil},
+		{"$5\r\nhe\r\no\r\n", "he\r\no", nil},
+		{"*2\r\n$1\r\na\r\n*2\r\n:1\r\n$-1\r\n", []any{"a", []any{int64(1), nil}}, nil},
+		{"$-1\r\n", nil, Nil},
+		{"*-1\r\n", nil, Nil},
+		{"-ERR wrong\r\n", nil, Error("ERR wrong")},
+	} {
+		got, err := readReply(bufio.NewReader(strings.NewReader(tc.in)))
+		if !reflect.DeepEqual(got, tc.want) || !errors.Is(err, tc.err) {
+			t.Errorf("readReply(%q) = %#v, %v", tc.in, got, err)
+		}
+	}
+	for _, in := range []string{"OK\r\n", "+OK\n", "$99999999999\r\n", "$3\r\nab"} {
+		if _, err := readReply(bufio.NewReader(strings.NewReader(in))); err == nil {
+			t.Errorf("readReply(%q) accepted a malformed reply", in)
+		}
+	}
+}
+
+func TestClient_Handshake(t *testing.T) {
+	f, c := startFake(t)
+	c.Username, c.Password, c.DB = "rte", "secret", 3
+	if _, err := c.Do(context.Background(), "XRANGE", "none", "-", "+"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(context.Background(), "BOGUS"); err == nil {
+		t.Error("error reply not returned")
+	}
+	want := []string{"AUTH rte secret", "SELECT 3", "XRANGE none - +", "BOGUS"}
+	if !reflect.DeepEqual(f.log, want) {
+		t.Errorf("commands = %q", f.log)
+	}
+	if len(c.idle) != 1 {
+		t.Errorf("%d idle connections, want the one reused", len(c.idle))
+	}
+}
@@ -0,0 +1,15 @@
+package report
+
+import "github.com/codethor0/rte-a-reference/pkg/rte"
+
+// InitialChainHash is the prev_chain_hash of the first audit record.
+const InitialChainHash = rte.InitialChainHash
+
+// AuditRecord is one entry of an engagement's audit trail.
+type AuditRecord = rte.AuditRecord
+
+// VerifyAuditChain checks the hash chain across records in order and
+// reports the first broken link.
+func VerifyAuditChain(records []AuditRecord) error {
+	return rte.VerifyAuditChain(records)
+}
@@ -0,0 +1,41 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// pythonChain was produced by rte_a_audit.AuditLogger; the second action
+// exercises HTML and non-ASCII escaping.
+const pythonChain = `[{"schema_version": "1.0", "engagement_id": "eng-2026-q1", "operator_id": "alice", "sequence": 1, "timestamp": "2026-10-14T17:18:01Z", "action": "sign_task", "task_id": "t1", "authorization": "approval-1", "result_hash": "40264754331e6db5", "prev_chain_hash": "0000000000000000000000000000000000000000000000000000000000000000", "chain_hash": "ebad44904d58943d5dc123009b3e4f35159b9210c09c33f88866e17751773431"}, {"schema_version": "1.0", "engagement_id": "eng-2026-q1", "operator_id": "alice", "sequence": 2, "timestamp": "2026-10-14T17:18:01Z", "action": "execute <task> & café ✓ 😀", "task_id": null, "authorization": "approval-1", "result_hash": "550a488f2f3b5f3c", "prev_chain_hash": "ebad44904d58943d5dc123009b3e4f35159b9210c09c33f88866e17751773431", "chain_hash": "5ebbe908aade188b8a7e6ddfbc8c425bdc09d992d47f23c02ccc6e1c99094694"}]`
+
+func pythonRecords(t *testing.T) []AuditRecord {
+	t.Helper()
+	var records []AuditRecord
+	if err := json.Unmarshal([]byte(pythonChain), &records); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return records
+}
+
+func TestVerifyAuditChain_Python(t *testing.T) {
+	if err := VerifyAuditChain(pythonRecords(t)); err != nil {
+		t.Fatalf("VerifyAuditChain: %v", err)
+	}
+	if err := VerifyAuditChain(nil); err != nil {
+		t.Fatalf("empty chain: %v", err)
+	}
+}
+
+func TestVerifyAuditChain_Tampered(t *testing.T) {
+	records := pythonRecords(t)
+	records[1].Action = "something else"
+	if err := VerifyAuditChain(records); err == nil || !strings.Contains(err.Error(), "record 1") {
+		t.Fatalf("expected tampered record to fail, got %v", err)
+	}
+	records = pythonRecords(t)
+	if err := VerifyAuditChain(records[1:]); err == nil {
+		t.Fatal("expected truncated chain to fail")
+	}
+}
@@ -0,0 +1,208 @@
+package report
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ManifestName is the manifest's file name inside a deliverable bundle. It
+// is never listed in the manifest itself.
+const ManifestName = "MANIFEST.json"
+
+// ManifestFile is one deliverable's size and SHA-256.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists every file of a report bundle as issued, so a client can
+// later show the deliverables they hold are the ones we sent.
+type Manifest struct {
+	Engagement string         `json:"engagement"`
+	Signer     string         `json:"signer"`
+	IssuedAt   time.Time      `json:"issued_at"`
+	Files      []ManifestFile `json:"files"`
+}
+
+// SignedManifest wraps a manifest with the signer's signature.
+type SignedManifest struct {
+	Manifest  Manifest `json:"manifest"`
+	PublicKey []byte   `json:"public_key"`
+	Signature []byte   `json:"signature"`
+}
+
+// NewManifest hashes every regular file in fsys, sorted by slash-separated
+// path, except a top-level ManifestName.
+func NewManifest(engagement, signer string, fsys fs.FS, now time.Time) (*Manifest, error) {
+	if engagement == "" {
+		return nil, errors.New("engagement is required")
+	}
+	if signer == "" {
+		return nil, errors.New("signer is required")
+	}
+	files, err := hashFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("bundle has no files")
+	}
+	return &Manifest{Engagement: engagement, Signer: signer, IssuedAt: now.UTC(), Files: files}, nil
+}
+
+// SignManifest signs the manifest with the signer's key.
+func SignManifest(m *Manifest, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedManifest, error) {
+	if m == nil {
+		return nil, errors.New("manifest is nil")
+	}
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+	return &SignedManifest{Manifest: *m, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// VerifyManifest checks the manifest signature.
+func VerifyManifest(sm *SignedManifest) error {
+	if sm == nil {
+		return errors.New("signed manifest is nil")
+	}
+	if len(sm.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sm.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	payload, err := json.Marshal(sm.Manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if !ed25519.Verify(sm.PublicKey, payload, sm.Signature) {
+		return errors.New("manifest signature verification failed")
+	}
+	return nil
+}
+
+// Check verifies the manifest signature, then that fsys holds exactly the
+// listed files with matching hashes. Every missing, altered, or unlisted
+// file is reported.
+func (sm *SignedManifest) Check(fsys fs.FS) error {
+	if err := VerifyManifest(sm); err != nil {
+		return err
+	}
+	held, err := hashFiles(fsys)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]ManifestFile, len(held))
+	for _, f := range held {
+		have[f.Name] = f
+	}
+	var errs []error
+	for _, want := range sm.Manifest.Files {
+		got, ok := have[want.Name]
+		delete(have, want.Name)
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: missing", want.Name))
+		case got.SHA256 != want.SHA256 || got.Size != want.Size:
+			errs = append(errs, fmt.Errorf("%s: altered (sha256 %s, issued %s)", want.Name, got.SHA256, want.SHA256))
+		}
+	}
+	extra := make([]string, 0, len(have))
+	for name := range have {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		errs = append(errs, fmt.Errorf("%s: not in manifest", name))
+	}
+	return errors.Join(errs...)
+}
+
+// WriteDeliverables renders a signed report into dir as the standard
+// bundle: the signed JSON plus Markdown, HTML, interactive HTML, PDF, and
+// XLSX renderings. It returns the file names written.
+func WriteDeliverables(dir string, sr *SignedReport, opts PDFOptions) ([]string, error) {
+	if sr == nil {
+		return nil, errors.New("signed report is nil")
+	}
+	r := &sr.Report
+	signed, err := json.MarshalIndent(sr, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal signed report: %w", err)
+	}
+	renders := []struct {
+		name   string
+		render func(io.Writer) error
+	}{
+		{"report.json", func(w io.Writer) error { _, err := w.Write(signed); return err }},
+		{"report.md", r.Markdown},
+		{"report.html", r.HTML},
+		{"report-interactive.html", r.InteractiveHTML},
+		{"report.pdf", func(w io.Writer) error { return r.PDF(w, opts) }},
+		{"report.xlsx", r.XLSX},
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(renders))
+	for _, d := range renders {
+		var buf bytes.Buffer
+		if err := d.render(&buf); err != nil {
+			return nil, fmt.Errorf("render %s: %w", d.name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, d.name), buf.Bytes(), 0o644); err != nil {
+			return nil, err
+		}
+		names = append(names, d.name)
+	}
+	return names, nil
+}
+
+func hashFiles(fsys fs.FS) ([]ManifestFile, error) {
+	var files []ManifestFile
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || path == ManifestName {
+			return nil
+		}
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		n, err := io.Copy(h, f)
+		if err != nil {
+			return fmt.Errorf("hash %s: %w", path, err)
+		}
+		files = append(files, ManifestFile{Name: path, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestManifest_Deliverables(t *testing.T) {
+	r, err := Build(fixture(t), time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pub, priv, _ := rte.GenerateKeyPair()
+	sr, err := Sign(r, priv, pub)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	dir := t.TempDir()
+	names, err := WriteDeliverables(dir, sr, PDFOptions{})
+	if err != nil {
+		t.Fatalf("WriteDeliverables: %v", err)
+	}
+	if len(names) != 6 {
+		t.Fatalf("unexpected deliverables %v", names)
+	}
+	m, err := NewManifest(r.Engagement, "lead-bob", os.DirFS(dir), time.Now())
+	if err != nil {
+		t.Fatalf("NewManifest: %v", err)
+	}
+	sm, err := SignManifest(m, priv, pub)
+	if err != nil {
+		t.Fatalf("SignManifest: %v", err)
+	}
+	raw, _ := json.Marshal(sm)
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var held SignedManifest
+	if err := json.Unmarshal(raw, &held); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if err := held.Check(os.DirFS(dir)); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(held.Manifest.Files) != 6 {
+		t.Fatalf("manifest lists %d files, want 6", len(held.Manifest.Files))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "report.md"), []byte("edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "report.pdf")); err != nil {
+		t.Fatal(err)
+	}
+	err = held.Check(os.DirFS(dir))
+	for _, want := range []string{"report.md: altered", "report.pdf: missing"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestManifest_Tampered(t *testing.T) {
+	fsys := fstest.MapFS{"report.json": {Data: []byte("{}")}, "notes/extra.txt": {Data: []byte("x")}}
+	pub, priv, _ := rte.GenerateKeyPair()
+	m, err := NewManifest("eng-2026-q1", "lead-bob", fstest.MapFS{"report.json": fsys["report.json"]}, time.Now())
+	if err != nil {
+		t.Fatalf("NewManifest: %v", err)
+	}
+	sm, _ := SignManifest(m, priv, pub)
+	if err := sm.Check(fsys); err == nil || !strings.Contains(err.Error(), "notes/extra.txt: not in manifest") {
+		t.Errorf("expected unlisted file to be reported, got %v", err)
+	}
+	sm.Manifest.Files[0].SHA256 = strings.Repeat("0", 64)
+	if err := VerifyManifest(sm); err == nil {
+		t.Error("expected edited manifest to fail verification")
+	}
+	if _, err := NewManifest("eng-2026-q1", "lead-bob", fstest.MapFS{}, time.Now()); err == nil {
+		t.Error("expected empty bundle to fail")
+	}
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// maxLogoBytes bounds an embedded logo.
+const maxLogoBytes = 1 << 20
+
+// Metadata is the branding and markings a report carries, resolved from the
+// engagement's metadata when the report is built. The logo is embedded so
+// the signed report renders the same wherever it is opened.
+type Metadata struct {
+	ClientName     string             `json:"client_name,omitempty"`
+	Classification rte.Classification `json:"classification,omitempty"`
+	Distribution   string             `json:"distribution,omitempty"`
+	Logo           *Logo              `json:"logo,omitempty"`
+}
+
+// Logo is an embedded PNG or JPEG image.
+type Logo struct {
+	MediaType string `json:"media_type"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Data      []byte `json:"data"`
+}
+
+// Marking returns the classification and distribution statement as one
+// line, or "" if there are neither. It is nil-safe so templates can call it
+// on reports without metadata.
+func (m *Metadata) Marking() string {
+	if m == nil {
+		return ""
+	}
+	return rte.EngagementMetadata{Classification: m.Classification, Distribution: m.Distribution}.Marking()
+}
+
+// resolveMetadata validates em and reads its logo. The report is marked at
+// level, the deliverable's classification.
+func resolveMetadata(em *rte.EngagementMetadata, level rte.Classification) (*Metadata, error) {
+	if err := level.Validate(); err != nil {
+		return nil, fmt.Errorf("report: %w", err)
+	}
+	if em == nil {
+		if level == "" {
+			return nil, nil
+		}
+		return &Metadata{Classification: level}, nil
+	}
+	if err := em.Validate(); err != nil {
+		return nil, fmt.Errorf("engagement metadata: %w", err)
+	}
+	m := &Metadata{ClientName: em.ClientName, Classification: level, Distribution: em.Distribution}
+	if em.Logo != "" {
+		data, err := os.ReadFile(em.Logo)
+		if err != nil {
+			return nil, fmt.Errorf("engagement logo: %w", err)
+		}
+		if m.Logo, err = decodeLogo(data); err != nil {
+			return nil, fmt.Errorf("engagement logo %s: %w", em.Logo, err)
+		}
+	}
+	return m, nil
+}
+
+func decodeLogo(data []byte) (*Logo, error) {
+	if len(data) > maxLogoBytes {
+		return nil, fmt.Errorf("larger than %d bytes", maxLogoBytes)
+	}
+	var cfg image.Config
+	var err error
+	mt := http.DetectContentType(data)
+	switch mt {
+	case "image/png":
+		cfg, err = png.DecodeConfig(bytes.NewReader(data))
+	case "image/jpeg":
+		cfg, err = jpeg.DecodeConfig(bytes.NewReader(data))
+	default:
+		return nil, errors.New("must be a PNG or JPEG image")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Logo{MediaType: mt, Width: cfg.Width, Height: cfg.Height, Data: data}, nil
+}
@@ -0,0 +1,191 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func writeLogo(t *testing.T) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.NRGBA{R: 0xaa, A: 0xff})
+		img.Set(x, 1, color.NRGBA{B: 0xaa, A: 0x80})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuild_Metadata(t *testing.T) {
+	in := fixture(t)
+	in.Metadata = &rte.EngagementMetadata{
+		ClientName: "Acme & Co", Logo: writeLogo(t),
+		Classification: "CONFIDENTIAL", Distribution: "Acme security team only",
+	}
+	r, err := Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if r.Metadata.Logo == nil || r.Metadata.Logo.Width != 4 || r.Metadata.Logo.MediaType != "image/png" {
+		t.Fatalf("logo %+v", r.Metadata.Logo)
+	}
+	const marking = "CONFIDENTIAL // Acme security team only"
+
+	var md, html, interactive bytes.Buffer
+	if err := r.Markdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HTML(&html); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.InteractiveHTML(&interactive); err != nil {
+		t.Fatal(err)
+	}
+	if s := md.String(); strings.Count(s, "**"+marking+"**") != 2 || !strings.Contains(s, "](data:image/png;base64,") {
+		t.Errorf("markdown missing markings or logo:\n%s", s)
+	}
+	for name, s := range map[string]string{"html": html.String(), "interactive": interactive.String()} {
+		if strings.Count(s, marking) < 2 || !strings.Contains(s, `src="data:image/png;base64,`) || !strings.Contains(s, "Acme &amp; Co") {
+			t.Errorf("%s missing markings, logo, or client", name)
+		}
+	}
+
+	var pdf bytes.Buffer
+	if err := r.PDF(&pdf, PDFOptions{Branding: Branding{Classification: "INTERNAL"}}); err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	pages := pdfObjects(t, pdf.Bytes())
+	if !bytes.Contains(pdf.Bytes(), []byte("/Subtype /Image /Width 4 /Height 2")) || !strings.Contains(pages[0], "/Im1 Do") {
+		t.Error("PDF cover does not draw the logo")
+	}
+	for _, want := range []string{"(CONFIDENTIAL)", "(Prepared for Acme & Co)", "(Acme security team only)"} {
+		if !strings.Contains(pages[0], want) {
+			t.Errorf("cover missing %q", want)
+		}
+	}
+	if strings.Contains(strings.Join(pages, ""), "(INTERNAL)") {
+		t.Error("branding classification overrode the engagement's")
+	}
+
+	var xlsx bytes.Buffer
+	if err := r.XLSX(&xlsx); err != nil {
+		t.Fatalf("XLSX: %v", err)
+	}
+	files := readZip(t, xlsx.Bytes())
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="Cover" sheetId="1" r:id="rId1"/>`) ||
+		!strings.Contains(files["xl/worksheets/sheet1.xml"], ">Acme &amp; Co<") {
+		t.Errorf("workbook missing cover sheet: %s", files["xl/workbook.xml"])
+	}
+	for i := 1; ; i++ {
+		body, ok := files[fmt.Sprintf("xl/worksheets/sheet%d.xml", i)]
+		if !ok {
+			break
+		}
+		if !strings.Contains(body, "<oddHeader>&amp;C&amp;B"+marking+"</oddHeader>") {
+			t.Errorf("sheet %d missing its header marking", i)
+		}
+	}
+}
+
+func TestBuild_MetadataErrors(t *testing.T) {
+	text := filepath.Join(t.TempDir(), "logo.png")
+	os.WriteFile(text, []byte("not an image"), 0o600)
+	for name, m := range map[string]*rte.EngagementMetadata{
+		"missing logo":   {Logo: filepath.Join(t.TempDir(), "absent.png")},
+		"not an image":   {Logo: text},
+		"bad marking":    {Classification: "SECRET\nREL"},
+		"oversized logo": {Logo: oversized(t)},
+	} {
+		in := fixture(t)
+		in.Metadata = m
+		if _, err := Build(in, time.Now()); err == nil {
+			t.Errorf("%s: Build succeeded", name)
+		}
+	}
+}
+
+func oversized(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "big.png")
+	os.WriteFile(path, make([]byte, maxLogoBytes+1), 0o600)
+	return path
+}
+
+func TestBuild_Classification(t *testing.T) {
+	in := fixture(t)
+	pub, priv, _ := rte.GenerateKeyPair()
+	for id, level := range map[string]rte.Classification{"t1": rte.ClassificationRestricted, "t3": rte.ClassificationPublic} {
+		for j := range in.Tasks {
+			if task := in.Tasks[j].Task; task.ID == id {
+				task.Classification = level
+				st, err := rte.SignTask(task, priv, pub)
+				if err != nil {
+					t.Fatal(err)
+				}
+				in.Tasks[j] = *st
+			}
+		}
+	}
+	in.Metadata = &rte.EngagementMetadata{Classification: rte.ClassificationConfidential}
+	in.Sections = NewSectionRegistry()
+	in.Sections.Register("scope", StaticSection(Section{ID: "scope", Title: "Scope", Position: SectionIntro, Paragraphs: []string{"In scope."}}))
+	in.Sections.Register("about", StaticSection(Section{ID: "about", Title: "About", Position: SectionClosing, Classification: rte.ClassificationPublic}))
+
+	r, err := Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if r.Summary.Withheld != 1 || r.Summary.Tasks != 2 || len(r.Appendix) != 2 || len(r.Detections) != 0 {
+		t.Fatalf("CONFIDENTIAL report: withheld %d, tasks %d, appendix %d, detections %d",
+			r.Summary.Withheld, r.Summary.Tasks, len(r.Appendix), len(r.Detections))
+	}
+	if r.Audit.Withheld != 1 || !r.Audit.ChainVerified || r.Scorecard == nil || len(r.Sections) != 2 {
+		t.Errorf("CONFIDENTIAL report audit %+v, sections %d", r.Audit, len(r.Sections))
+	}
+	for _, e := range r.Tasks {
+		if e.ID == "t1" {
+			t.Error("RESTRICTED task in a CONFIDENTIAL report")
+		}
+	}
+	var md bytes.Buffer
+	if err := r.Markdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.String(), "1 tasks classified above this report were withheld.") {
+		t.Error("markdown does not note the withheld task")
+	}
+
+	in.Classification = rte.ClassificationPublic
+	r, err = Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if r.Summary.Tasks != 1 || r.Tasks[0].ID != "t3" || r.Metadata.Classification != rte.ClassificationPublic {
+		t.Fatalf("PUBLIC report tasks %+v, marked %q", r.Tasks, r.Metadata.Classification)
+	}
+	if r.Scorecard != nil || len(r.Audit.Entries) != 0 || len(r.Sections) != 1 || r.Sections[0].ID != "about" {
+		t.Errorf("PUBLIC report kept engagement-level content: scorecard %v, audit %d, sections %+v", r.Scorecard, len(r.Audit.Entries), r.Sections)
+	}
+
+	in.Classification = "TOP SECRET"
+	if _, err := Build(in, time.Now()); err == nil {
+		t.Error("built a report at an unknown level")
+	}
+}
@@ -0,0 +1,629 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Branding customizes the PDF cover page and running footer.
+type Branding struct {
+	// Organization is the issuing firm, shown on the cover band and footer.
+	Organization string
+	// ClientName is the customer's display name; defaults to Report.Client.
+	ClientName string
+	// Title defaults to "Red Team Engagement Report".
+	Title string
+	// AccentColor is a "#RRGGBB" color for the cover band and headings.
+	AccentColor string
+	// Classification is the handling marking printed on every page, such as
+	// "CONFIDENTIAL". The report's metadata, when it has one, takes
+	// precedence, as does its distribution statement.
+	Classification string
+}
+
+// PDFOptions control PDF rendering.
+type PDFOptions struct {
+	Branding Branding
+	// VerifyURL, if set, is the evidence service QR codes link to; the
+	// engagement, task ID, and SHA-256 digest are added as query params.
+	// Without it the codes carry the bare digest.
+	VerifyURL string
+}
+
+const (
+	pdfWidth    = 612.0 // US Letter, in points
+	pdfHeight   = 792.0
+	pdfMargin   = 54.0
+	pdfBottom   = 72.0
+	fontBody    = "F1"
+	fontBold    = "F2"
+	fontMono    = "F3"
+	monoAdvance = 0.6 // Courier glyph width per point of size
+)
+
+var defaultAccent = [3]float64{0.12, 0.23, 0.37}
+
+// Digest returns the hex SHA-256 of the report's signed payload, so a
+// printed digest can be checked against the SignedReport.
+func (r *Report) Digest() (string, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("marshal report: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// EvidenceDigest returns the hex SHA-256 of a signed task as issued, the
+// value appendix QR codes link to.
+func EvidenceDigest(st rte.SignedTask) (string, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return "", fmt.Errorf("marshal signed task: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// PDF renders the report as a paginated PDF with a branded cover page and
+// verification QR codes for the report and every appendix task.
+func (r *Report) PDF(w io.Writer, opts PDFOptions) error {
+	b := opts.Branding
+	accent := defaultAccent
+	if b.AccentColor != "" {
+		c, err := parseColor(b.AccentColor)
+		if err != nil {
+			return err
+		}
+		accent = c
+	}
+	if b.Title == "" {
+		b.Title = "Red Team Engagement Report"
+	}
+	var distribution string
+	var logo *Logo
+	if m := r.Metadata; m != nil {
+		if m.Classification != "" {
+			b.Classification = string(m.Classification)
+		}
+		if b.ClientName == "" {
+			b.ClientName = m.ClientName
+		}
+		distribution, logo = m.Distribution, m.Logo
+	}
+	if b.ClientName == "" {
+		b.ClientName = r.Client
+	}
+	digest, err := r.Digest()
+	if err != nil {
+		return err
+	}
+	doc := &pdfDoc{}
+	if logo != nil {
+		if err := doc.addImage(logo); err != nil {
+			return fmt.Errorf("logo: %w", err)
+		}
+	}
+	if err := r.pdfCover(doc, b, distribution, accent, opts.VerifyURL, digest); err != nil {
+		return err
+	}
+	l := &pdfLayout{doc: doc, accent: accent}
+	l.newPage()
+	r.pdfBody(l)
+	if err := r.pdfAppendix(l, opts.VerifyURL); err != nil {
+		return err
+	}
+	footer := strings.TrimSpace(b.Classification + "   " + b.Organization)
+	for i, p := range doc.pages {
+		if b.Classification != "" {
+			p.text(pdfMargin, pdfHeight-30, fontBold, 8, b.Classification, [3]float64{})
+		}
+		p.text(pdfMargin, 36, fontBody, 8, footer, [3]float64{0.4, 0.4, 0.4})
+		p.text(pdfWidth-pdfMargin-60, 36, fontBody, 8, fmt.Sprintf("Page %d of %d", i+1, len(doc.pages)), [3]float64{0.4, 0.4, 0.4})
+	}
+	info := map[string]string{"Title": b.Title + ": " + r.Engagement, "Producer": "rte-a report", "Author": b.Organization}
+	return doc.write(w, info, r.GeneratedAt)
+}
+
+func (r *Report) pdfCover(doc *pdfDoc, b Branding, distribution string, accent [3]float64, verifyURL, digest string) error {
+	p := doc.newPage()
+	white := [3]float64{1, 1, 1}
+	p.rect(0, pdfHeight-120, pdfWidth, 120, accent)
+	if b.Organization != "" {
+		p.text(pdfMargin, pdfHeight-80, fontBold, 16, b.Organization, white)
+	}
+	if img := doc.image; img != nil {
+		// Fit the logo in an 80-point box at the band's right edge.
+		scale := 80 / float64(max(img.width, img.height))
+		w, h := float64(img.width)*scale, float64(img.height)*scale
+		p.drawImage(pdfWidth-pdfMargin-w, pdfHeight-100, w, h)
+	}
+	y := pdfHeight - 240
+	p.text(pdfMargin, y, fontBold, 26, b.Title, accent)
+	if b.ClientName != "" {
+		y -= 36
+		p.text(pdfMargin, y, fontBody, 18, "Prepared for "+b.ClientName, [3]float64{})
+	}
+	lines := []string{
+		"Engagement: " + r.Engagement,
+		"Window: " + pdfTime(r.Window.Start) + " to " + pdfTime(r.Window.End),
+		"Generated: " + pdfTime(r.GeneratedAt),
+	}
+	y -= 40
+	for _, s := range lines {
+		p.text(pdfMargin, y, fontBody, 12, s, [3]float64{})
+		y -= 18
+	}
+	if distribution != "" {
+		y -= 12
+		for _, s := range wrapText(distribution, 90) {
+			p.text(pdfMargin, y, fontBold, 10, s, [3]float64{})
+			y -= 14
+		}
+	}
+	code, err := encodeQR([]byte(evidenceLink(verifyURL, r.Engagement, "", digest)))
+	if err != nil {
+		return err
+	}
+	const module = 3.0
+	side := module * float64(len(code))
+	p.qr(pdfWidth-pdfMargin-side, pdfBottom+30, module, code)
+	p.text(pdfMargin, pdfBottom+70, fontBold, 10, "Report verification", [3]float64{})
+	p.text(pdfMargin, pdfBottom+56, fontBody, 9, "Scan the code or compare this SHA-256 with the signed report:", [3]float64{})
+	p.text(pdfMargin, pdfBottom+42, fontMono, 7, digest, [3]float64{})
+	return nil
+}
+
+func (r *Report) pdfBody(l *pdfLayout) {
+	l.heading("Summary")
+	l.line(fontBody, 10, fmt.Sprintf("%d tasks, %s total runtime, window %s.", r.Summary.Tasks, r.Summary.Runtime.Round(time.Second), r.Window.Duration.Round(time.Second)))
+	if n := r.Summary.Withheld; n > 0 {
+		l.line(fontBody, 10, fmt.Sprintf("%d tasks classified above this report were withheld.", n))
+	}
+	for _, st := range []rte.TaskState{rte.StateCompleted, rte.StateFailed, rte.StateCancelled, rte.StatePaused, rte.StateExecuting, rte.StatePending} {
+		if n := r.Summary.ByState[st]; n > 0 {
+			l.line(fontBody, 10, fmt.Sprintf("  %s: %d", st, n))
+		}
+	}
+	r.pdfSections(l, SectionIntro)
+	if s := r.Scorecard; s != nil {
+		l.heading("Score")
+		l.line(fontBold, 12, fmt.Sprintf("Overall %.1f  (%d of %d expected detections raised)", s.Score, s.Detected, s.Expected))
+		rows := [][]string{}
+		for _, t := range s.Tactics {
+			rows = append(rows, []string{t.Tactic.Name, fmt.Sprintf("%.1f", t.Score), fmt.Sprintf("%d/%d", t.Detected, t.Expected)})
+		}
+		l.table([]string{"Tactic", "Score", "Detected"}, []int{30, 8, 10}, rows)
+	}
+
+	l.heading("Task Log")
+	rows := [][]string{}
+	for _, t := range r.Tasks {
+		state := string(t.State)
+		if t.Error != "" {
+			state += ": " + t.Error
+		}
+		rows = append(rows, []string{t.ID, string(t.Type), t.Operator, pdfTime(t.StartedAt), t.Duration.Round(time.Second).String(), state})
+	}
+	l.table([]string{"Task", "Type", "Operator", "Started", "Duration", "State"}, []int{14, 26, 12, 20, 9, 30}, rows)
+
+	if len(r.Phases) > 0 {
+		l.heading("Timeline by Phase")
+		rows = nil
+		for _, p := range r.Phases {
+			phase := string(p.Phase)
+			if phase == "" {
+				phase = "(none)"
+			}
+			rows = append(rows, []string{phase, strings.Join(p.Tasks, ", "), pdfTime(p.Start), pdfTime(p.End), p.Duration.Round(time.Second).String()})
+		}
+		l.table([]string{"Phase", "Tasks", "Start", "End", "Duration"}, []int{16, 30, 20, 20, 9}, rows)
+	}
+
+	l.heading("ATT&CK Coverage")
+	l.line(fontBody, 10, fmt.Sprintf("%d techniques exercised.", r.Coverage.Exercised))
+	rows = nil
+	for _, tac := range r.Coverage.Tactics {
+		for _, tc := range tac.Techniques {
+			rows = append(rows, []string{tac.Tactic.Name, tc.ID + " " + tc.Name, strconv.Itoa(tc.Planned), strconv.Itoa(tc.Exercised)})
+		}
+	}
+	l.table([]string{"Tactic", "Technique", "Planned", "Exercised"}, []int{22, 56, 8, 9}, rows)
+
+	l.heading("Detection Latency")
+	rows = nil
+	for _, d := range r.Detections {
+		rows = append(rows, []string{d.Technique, fmt.Sprintf("%d/%d", d.Detected, d.Expected),
+			d.P50.Round(time.Second).String(), d.P95.Round(time.Second).String(), d.Max.Round(time.Second).String()})
+	}
+	l.table([]string{"Technique", "Detected", "p50", "p95", "Max"}, []int{12, 10, 12, 12, 12}, rows)
+
+	l.heading("Audit Trail")
+	status := "verified"
+	if !r.Audit.ChainVerified {
+		status = "FAILED: " + r.Audit.ChainError
+	}
+	l.line(fontBody, 10, fmt.Sprintf("%d records; hash chain %s.", r.Audit.Records, status))
+	if r.Audit.HeadHash != "" {
+		l.line(fontMono, 8, "Head: "+r.Audit.HeadHash)
+	}
+	r.pdfSections(l, SectionClosing)
+}
+
+func (r *Report) pdfSections(l *pdfLayout, pos SectionPosition) {
+	for _, s := range r.SectionsAt(pos) {
+		l.heading(s.Title)
+		for _, p := range s.Paragraphs {
+			l.line(fontBody, 10, p)
+			l.y -= 6
+		}
+	}
+}
+
+func (r *Report) pdfAppendix(l *pdfLayout, verifyURL string) error {
+	l.heading("Appendix: Task Signatures")
+	l.line(fontBody, 9, "Each code links to the signed task as issued; the digest is the SHA-256 of its JSON encoding.")
+	const module, block = 1.5, 96.0
+	for _, e := range r.Appendix {
+		digest, err := EvidenceDigest(e.Signed)
+		if err != nil {
+			return err
+		}
+		code, err := encodeQR([]byte(evidenceLink(verifyURL, r.Engagement, e.Signed.Task.ID, digest)))
+		if err != nil {
+			return err
+		}
+		l.need(block)
+		top := l.y
+		l.page.qr(pdfMargin, top-module*float64(len(code)), module, code)
+		x := pdfMargin + 96
+		t := e.Signed.Task
+		verified := "yes"
+		if !e.Verified {
+			verified = "NO: " + e.Error
+		}
+		lines := []struct {
+			font, s string
+		}{
+			{fontBold, t.ID + "  (" + string(t.Type) + ")"},
+			{fontBody, "Operator " + t.Operator + ", approved by " + t.ApprovedBy + "; signatures verified: " + verified},
+			{fontMono, "sha256    " + digest},
+			{fontMono, "signature " + base64.StdEncoding.EncodeToString(e.Signed.Signature)},
+		}
+		y := top - 10
+		for _, ln := range lines {
+			size := 9.0
+			if ln.font == fontMono {
+				size = 6.5
+			}
+			for _, s := range wrapText(ln.s, int((pdfWidth-pdfMargin-x)/(size*monoAdvance))) {
+				l.page.text(x, y, ln.font, size, s, [3]float64{})
+				y -= size + 3
+			}
+		}
+		l.y = top - block
+	}
+	return nil
+}
+
+// evidenceLink is the payload of a verification QR code.
+func evidenceLink(base, engagement, taskID, digest string) string {
+	if base == "" {
+		return "rte-a:sha256:" + digest
+	}
+	q := url.Values{"engagement": {engagement}, "sha256": {digest}}
+	if taskID != "" {
+		q.Set("task", taskID)
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + q.Encode()
+}
+
+func pdfTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:04 MST")
+}
+
+func parseColor(s string) ([3]float64, error) {
+	hexStr, ok := strings.CutPrefix(s, "#")
+	raw, err := hex.DecodeString(hexStr)
+	if !ok || err != nil || len(raw) != 3 {
+		return [3]float64{}, fmt.Errorf("accent color must be #RRGGBB, got %q", s)
+	}
+	return [3]float64{float64(raw[0]) / 255, float64(raw[1]) / 255, float64(raw[2]) / 255}, nil
+}
+
+// wrapText breaks s into lines of at most width characters, preferring
+// spaces and hard-breaking long tokens such as signatures.
+func wrapText(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var out []string
+	for len(s) > width {
+		cut := strings.LastIndexByte(s[:width+1], ' ')
+		if cut <= 0 {
+			cut = width
+		}
+		out = append(out, strings.TrimRight(s[:cut], " "))
+		s = strings.TrimLeft(s[cut:], " ")
+	}
+	return append(out, s)
+}
+
+// pdfLayout flows headings, lines, and tables down pages.
+type pdfLayout struct {
+	doc    *pdfDoc
+	page   *pdfPage
+	y      float64
+	accent [3]float64
+}
+
+func (l *pdfLayout) newPage() {
+	l.page = l.doc.newPage()
+	l.y = pdfHeight - pdfMargin - 10
+}
+
+func (l *pdfLayout) need(h float64) {
+	if l.y-h < pdfBottom {
+		l.newPage()
+	}
+}
+
+func (l *pdfLayout) heading(s string) {
+	l.need(60)
+	l.y -= 12
+	l.page.text(pdfMargin, l.y, fontBold, 14, s, l.accent)
+	l.y -= 20
+}
+
+func (l *pdfLayout) line(font string, size float64, s string) {
+	width := int((pdfWidth - 2*pdfMargin) / (size * 0.5))
+	if font == fontMono {
+		width = int((pdfWidth - 2*pdfMargin) / (size * monoAdvance))
+	}
+	for _, part := range wrapText(s, width) {
+		l.need(size + 4)
+		l.page.text(pdfMargin, l.y, font, size, part, [3]float64{})
+		l.y -= size + 4
+	}
+}
+
+// table renders fixed-width monospaced columns, truncating long cells; the
+// header repeats on each new page.
+func (l *pdfLayout) table(header []string, widths []int, rows [][]string) {
+	const size = 7.5
+	format := func(cells []string) string {
+		var b strings.Builder
+		for i, c := range cells {
+			w := widths[i]
+			if len([]rune(c)) > w-1 {
+				c = string([]rune(c)[:w-2]) + "~"
+			}
+			b.WriteString(c)
+			b.WriteString(strings.Repeat(" ", w-len([]rune(c))))
+		}
+		return strings.TrimRight(b.String(), " ")
+	}
+	drawHeader := func() {
+		l.page.text(pdfMargin, l.y, fontMono, size, format(header), l.accent)
+		l.y -= size + 5
+	}
+	l.need(3 * (size + 4))
+	drawHeader()
+	if len(rows) == 0 {
+		l.page.text(pdfMargin, l.y, fontMono, size, "(none)", [3]float64{0.4, 0.4, 0.4})
+		l.y -= size + 4
+	}
+	for _, row := range rows {
+		if l.y-(size+4) < pdfBottom {
+			l.newPage()
+			drawHeader()
+		}
+		l.page.text(pdfMargin, l.y, fontMono, size, format(row), [3]float64{})
+		l.y -= size + 4
+	}
+	l.y -= 8
+}
+
+// pdfDoc is a minimal PDF 1.4 writer using the standard Helvetica and
+// Courier fonts, so no font data needs embedding.
+type pdfDoc struct {
+	pages []*pdfPage
+	// image is the one embedded image, the client logo; pages draw it as
+	// /Im1.
+	image *pdfImage
+}
+
+type pdfImage struct {
+	width, height int
+	colorSpace    string
+	filter        string
+	data          []byte
+}
+
+// addImage embeds a logo: a JPEG as is, a PNG decoded to RGB over white.
+func (d *pdfDoc) addImage(l *Logo) error {
+	switch l.MediaType {
+	case "image/jpeg":
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(l.Data))
+		if err != nil {
+			return err
+		}
+		cs := "/DeviceRGB"
+		switch cfg.ColorModel {
+		case color.GrayModel:
+			cs = "/DeviceGray"
+		case color.CMYKModel:
+			cs = "/DeviceCMYK"
+		}
+		d.image = &pdfImage{width: cfg.Width, height: cfg.Height, colorSpace: cs, filter: "/DCTDecode", data: l.Data}
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(l.Data))
+		if err != nil {
+			return err
+		}
+		bounds := img.Bounds()
+		rgb := make([]byte, 0, 3*bounds.Dx()*bounds.Dy())
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, a := img.At(x, y).RGBA()
+				// Composite premultiplied color over white.
+				bg := 0xffff - a
+				rgb = append(rgb, byte((r+bg)>>8), byte((g+bg)>>8), byte((b+bg)>>8))
+			}
+		}
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(rgb)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		d.image = &pdfImage{width: bounds.Dx(), height: bounds.Dy(), colorSpace: "/DeviceRGB", filter: "/FlateDecode", data: z.Bytes()}
+	default:
+		return fmt.Errorf("unsupported image type %s", l.MediaType)
+	}
+	return nil
+}
+
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+func (d *pdfDoc) newPage() *pdfPage {
+	p := &pdfPage{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+func (p *pdfPage) text(x, y float64, font string, size float64, s string, c [3]float64) {
+	fmt.Fprintf(&p.content, "BT %.3f %.3f %.3f rg /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		c[0], c[1], c[2], font, size, x, y, pdfString(s))
+}
+
+// drawImage draws the document's image scaled to w by h points with its
+// lower-left corner at (x, y).
+func (p *pdfPage) drawImage(x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im1 Do Q\n", w, h, x, y)
+}
+
+func (p *pdfPage) rect(x, y, w, h float64, c [3]float64) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n", c[0], c[1], c[2], x, y, w, h)
+}
+
+// qr draws a QR code with its lower-left corner at (x, y), including the
+// four-module quiet zone the standard requires.
+func (p *pdfPage) qr(x, y, module float64, code qrCode) {
+	n := float64(len(code))
+	p.rect(x-4*module, y-4*module, (n+8)*module, (n+8)*module, [3]float64{1, 1, 1})
+	p.content.WriteString("0 0 0 rg\n")
+	for row, line := range code {
+		for col, dark := range line {
+			if dark {
+				fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re\n",
+					x+float64(col)*module, y+(n-1-float64(row))*module, module, module)
+			}
+		}
+	}
+	p.content.WriteString("f\n")
+}
+
+// pdfString escapes s for a literal string in WinAnsiEncoding; characters
+// outside Latin-1 become '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteRune(c)
+		case c >= 0xa0 && c <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func (d *pdfDoc) write(w io.Writer, info map[string]string, created time.Time) error {
+	if len(d.pages) == 0 {
+		return errors.New("pdf has no pages")
+	}
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-6 are fixed; pages follow as (page, content) pairs.
+	const firstPage = 7
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	date := created.UTC().Format("20060102150405Z")
+	obj(fmt.Sprintf("<< /Title (%s) /Author (%s) /Producer (%s) /CreationDate (D:%s) >>",
+		pdfString(info["Title"]), pdfString(info["Author"]), pdfString(info["Producer"]), date))
+	// The image, if any, follows the pages.
+	xobjects := ""
+	if d.image != nil {
+		xobjects = fmt.Sprintf(" /XObject << /Im1 %d 0 R >>", firstPage+2*len(d.pages))
+	}
+	for i, p := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >>%s >> /Contents %d 0 R >>",
+			pdfWidth, pdfHeight, xobjects, firstPage+2*i+1))
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(p.content.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		obj(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.Bytes()))
+	}
+	if img := d.image; img != nil {
+		obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter %s /Length %d >>\nstream\n%s\nendstream",
+			img.width, img.height, img.colorSpace, img.filter, len(img.data), img.data))
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
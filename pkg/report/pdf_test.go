@@ -0,0 +1,102 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pdfObjects checks the xref table and returns the inflated page streams.
+func pdfObjects(t *testing.T, data []byte) []string {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("missing startxref trailer")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(data[off:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", i+1, data[off:off+12])
+		}
+	}
+	var streams []string
+	for _, s := range regexp.MustCompile(`(?s)/Length (\d+) /Filter /FlateDecode >>\nstream\n`).FindAllSubmatchIndex(data, -1) {
+		n, _ := strconv.Atoi(string(data[s[2]:s[3]]))
+		zr, err := zlib.NewReader(bytes.NewReader(data[s[1] : s[1]+n]))
+		if err != nil {
+			t.Fatalf("inflate: %v", err)
+		}
+		b, _ := io.ReadAll(zr)
+		streams = append(streams, string(b))
+	}
+	return streams
+}
+
+func TestReport_PDF(t *testing.T) {
+	in := fixture(t)
+	in.Client = "Acme Corp"
+	r, err := Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	var buf bytes.Buffer
+	err = r.PDF(&buf, PDFOptions{
+		Branding:  Branding{Organization: "Example Red Team (LLC)", AccentColor: "#AA3300", Classification: "CONFIDENTIAL"},
+		VerifyURL: "https://evidence.example/verify",
+	})
+	if err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatal("missing PDF header")
+	}
+	pages := pdfObjects(t, data)
+	if len(pages) < 2 || !strings.Contains(string(data), fmt.Sprintf("/Count %d", len(pages))) {
+		t.Fatalf("page tree does not match %d content streams", len(pages))
+	}
+	digest, _ := r.Digest()
+	cover := pages[0]
+	for _, want := range []string{"(Prepared for Acme Corp)", `(Example Red Team \(LLC\))`, "(" + digest + ")", "0.667 0.200 0.000 rg", "(CONFIDENTIAL)"} {
+		if !strings.Contains(cover, want) {
+			t.Errorf("cover missing %q", want)
+		}
+	}
+	all := strings.Join(pages, "\n")
+	for _, want := range []string{"(Task Log)", "(Appendix: Task Signatures)", "(t1  \\(simulate_credential_spray\\))", "(Page 1 of "} {
+		if !strings.Contains(all, want) {
+			t.Errorf("PDF missing %q", want)
+		}
+	}
+	if strings.Count(all, " re\nf\n") < 1+len(r.Appendix) {
+		t.Errorf("expected a QR code on the cover and per appendix task")
+	}
+	if err := r.PDF(io.Discard, PDFOptions{Branding: Branding{AccentColor: "red"}}); err == nil {
+		t.Error("expected invalid accent color to fail")
+	}
+}
+
+func TestEvidenceLink(t *testing.T) {
+	if got := evidenceLink("", "eng-1", "t1", "ab"); got != "rte-a:sha256:ab" {
+		t.Errorf("bare link %q", got)
+	}
+	if got := evidenceLink("https://e.example/v?k=1", "eng 1", "t1", "ab"); got != "https://e.example/v?k=1&engagement=eng+1&sha256=ab&task=t1" {
+		t.Errorf("URL link %q", got)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	got := wrapText("alpha beta gamma "+strings.Repeat("x", 12), 10)
+	want := []string{"alpha beta", "gamma", "xxxxxxxxxx", "xx"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("wrapText: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// The 1-M "HELLO WORLD" example from the QR specification tutorials.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Fatalf("EC codewords: got %v, want %v", got, want)
+	}
+}
+
+func TestQRFormatAndVersionBits(t *testing.T) {
+	if got := fmt.Sprintf("%015b", qrFormatBits(0)); got != "101010000010010" {
+		t.Errorf("format M/0: got %s", got)
+	}
+	if got := fmt.Sprintf("%015b", qrFormatBits(5)); got != "100000011001110" {
+		t.Errorf("format M/5: got %s", got)
+	}
+	if got := fmt.Sprintf("%015b", qrFormatBits(7)); got != "100101010100000" {
+		t.Errorf("format M/7: got %s", got)
+	}
+	if got := fmt.Sprintf("%018b", qrVersionBits(7)); got != "000111110010010100" {
+		t.Errorf("version 7: got %s", got)
+	}
+}
+
+func TestEncodeQR(t *testing.T) {
+	for _, n := range []int{1, 14, 15, 100, 200} {
+		data := strings.Repeat("x", n)
+		m, err := encodeQR([]byte(data))
+		if err != nil {
+			t.Fatalf("encode %d bytes: %v", n, err)
+		}
+		size := len(m)
+		// All three finder patterns have a dark 3x3 core and a light ring.
+		for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+			if !m[c[1]][c[0]] || m[c[1]-2][c[0]] || !m[c[1]-3][c[0]] {
+				t.Errorf("%d bytes: bad finder pattern at %v", n, c)
+			}
+		}
+		if !m[size-8][8] {
+			t.Errorf("%d bytes: dark module missing", n)
+		}
+		for i := 8; i < size-8; i++ {
+			if m[6][i] != (i%2 == 0) || m[i][6] != (i%2 == 0) {
+				t.Fatalf("%d bytes: timing pattern broken at %d", n, i)
+			}
+		}
+	}
+	if m, _ := encodeQR([]byte("x")); len(m) != 21 {
+		t.Errorf("1 byte should fit version 1, got size %d", len(m))
+	}
+	if _, err := encodeQR(make([]byte, 300)); err == nil {
+		t.Error("expected oversized payload to fail")
+	}
+}
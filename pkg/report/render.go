@@ -0,0 +1,72 @@
+package report
+
+import (
+	"embed"
+	"encoding/base64"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var funcs = map[string]any{
+	"ts": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format(time.RFC3339)
+	},
+	"dur": func(d time.Duration) string { return d.Round(time.Second).String() },
+	"b64": func(b []byte) string { return base64.StdEncoding.EncodeToString(b) },
+	// fingerprint is the key's rte.Fingerprint, as agents pin it.
+	"fingerprint": func(b []byte) string {
+		if len(b) == 0 {
+			return "-"
+		}
+		return rte.Fingerprint(b)
+	},
+	// logoURI embeds a logo as a data URI; any media type but PNG or JPEG
+	// renders nothing.
+	"logoURI": func(l *Logo) htmltemplate.URL {
+		if l.MediaType != "image/png" && l.MediaType != "image/jpeg" {
+			return ""
+		}
+		return htmltemplate.URL("data:" + l.MediaType + ";base64," + base64.StdEncoding.EncodeToString(l.Data))
+	},
+	"join": strings.Join,
+	"md": func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ", "\r", " ").Replace(s)
+	},
+}
+
+var (
+	markdownTmpl = template.Must(template.New("report.md.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/report.md.tmpl"))
+	htmlTmpl     = htmltemplate.Must(htmltemplate.New("report.html.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/report.html.tmpl"))
+	// interactiveTmpl embeds the report JSON in a data script and renders
+	// the task table and ATT&CK matrix client-side.
+	interactiveTmpl = htmltemplate.Must(htmltemplate.New("interactive.html.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/interactive.html.tmpl"))
+)
+
+// Markdown renders the report as Markdown.
+func (r *Report) Markdown(w io.Writer) error {
+	return markdownTmpl.Execute(w, r)
+}
+
+// HTML renders the report as a self-contained HTML page.
+func (r *Report) HTML(w io.Writer) error {
+	return htmlTmpl.Execute(w, r)
+}
+
+// InteractiveHTML renders the report as a single offline HTML page with the
+// report JSON embedded. Readers can filter the task log, expand a task's
+// signed params and audit records, and filter by ATT&CK matrix cell; no
+// resources are fetched.
+func (r *Report) InteractiveHTML(w io.Writer) error {
+	return interactiveTmpl.Execute(w, r)
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestRender(t *testing.T) {
+	in := fixture(t)
+	in.Results[1].Error = "<script>alert(1)</script> | pipe"
+	in.Tasks[2].Task.ReissuedFromID = "t2"
+	in.Tasks[0].Task.Phase = rte.PhasePersistence
+	in.Tasks[1].Task.Phase = rte.PhaseInitialAccess
+	r, err := Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	var md strings.Builder
+	if err := r.Markdown(&md); err != nil {
+		t.Fatalf("Markdown: %v", err)
+	}
+	for _, want := range []string{
+		"# Engagement Report: eng-2026-q1",
+		"Overall: **87.5**",
+		"| t1 | simulate_credential_spray | op-alice | lead-bob | T1110.003 | completed |",
+		"| Credential Access | T1110.003 Brute Force: Password Spraying | 1 | 1 |",
+		"hash chain verified",
+		`\| pipe`,
+		"| t3 (re-issue of t2) | simulate_login |",
+		"## Timeline by Phase",
+		"| initial_access | t1 |",
+		"| (none) | t3 |",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q", want)
+		}
+	}
+
+	var html strings.Builder
+	if err := r.HTML(&html); err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	if strings.Contains(html.String(), "<script>") {
+		t.Error("HTML output does not escape task errors")
+	}
+	if !strings.Contains(html.String(), "<td>T1110.003 Brute Force: Password Spraying</td>") {
+		t.Error("HTML missing coverage row")
+	}
+	if !strings.Contains(html.String(), "<tr><td>persistence</td><td>t2</td>") {
+		t.Error("HTML missing phase timeline")
+	}
+}
+
+func TestInteractiveHTML(t *testing.T) {
+	in := fixture(t)
+	in.Client = "acme"
+	in.Results[1].Error = "</script><script>alert(1)</script>"
+	r, err := Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pub, priv, _ := rte.GenerateKeyPair()
+	sr, err := Sign(r, priv, pub)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	a := NewArchive()
+	if err := a.Add(sr); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	archived, ok := a.Report("eng-2026-q1")
+	if !ok {
+		t.Fatal("report missing from archive")
+	}
+	var out strings.Builder
+	if err := archived.InteractiveHTML(&out); err != nil {
+		t.Fatalf("InteractiveHTML: %v", err)
+	}
+	page := out.String()
+	if strings.Contains(page, "<script>alert") {
+		t.Error("embedded JSON does not escape task errors")
+	}
+	for _, bad := range []string{"src=\"http", "href=\"http"} {
+		if strings.Contains(page, bad) {
+			t.Errorf("page references an external resource: %q", bad)
+		}
+	}
+	start := strings.Index(page, `id="report-data">`)
+	end := strings.Index(page[start:], "</script>")
+	if start < 0 || end < 0 {
+		t.Fatal("report data script not found")
+	}
+	var decoded Report
+	if err := json.Unmarshal([]byte(page[start+len(`id="report-data">`):start+end]), &decoded); err != nil {
+		t.Fatalf("embedded JSON: %v", err)
+	}
+	if decoded.Engagement != "eng-2026-q1" || len(decoded.Tasks) != 3 || len(decoded.Appendix) != 3 {
+		t.Fatalf("unexpected embedded report %+v", decoded)
+	}
+}
@@ -0,0 +1,386 @@
+// Package report assembles an engagement's tasks, results, detections, and
+// audit trail into a signed report. The JSON form is the record of truth;
+// Markdown and HTML renderings are produced from the same data, and the
+// appendix carries every signed task so readers can re-verify attribution.
+package report
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+	"github.com/codethor0/rte-a-reference/pkg/score"
+)
+
+// SchemaVersion identifies the report JSON layout.
+const SchemaVersion = "1.0"
+
+// Input is everything recorded for one engagement.
+type Input struct {
+	Engagement string
+	// Client names the customer, so reports can be compared over time.
+	Client     string
+	Tasks      []rte.SignedTask
+	Results    []rte.TaskResult
+	Detections []rte.DetectionRecord
+	Audit      []AuditRecord
+	// Scorecard, if set, is embedded as computed.
+	Scorecard *score.Scorecard
+	// Sections, if set, contributes custom sections such as methodology
+	// boilerplate and consultant bios.
+	Sections *SectionRegistry
+	// Metadata, if set, brands and marks every rendering of the report.
+	Metadata *rte.EngagementMetadata
+	// Classification is the report's level; "" means the engagement's,
+	// Metadata.Classification. Tasks, sections, and audit records above it
+	// are withheld, and a report below the engagement's level also omits
+	// the scorecard, which covers every task.
+	Classification rte.Classification
+}
+
+// Report is an engagement report.
+type Report struct {
+	SchemaVersion string      `json:"schema_version"`
+	Engagement    string      `json:"engagement"`
+	Client        string      `json:"client,omitempty"`
+	GeneratedAt   time.Time   `json:"generated_at"`
+	Window        Window      `json:"window"`
+	Summary       Summary     `json:"summary"`
+	Tasks         []TaskEntry `json:"tasks"`
+	// Phases is the timeline by engagement phase, set when any task
+	// names a phase.
+	Phases     []PhaseEntry         `json:"phases,omitempty"`
+	Coverage   rte.CoverageMatrix   `json:"coverage"`
+	Detections []rte.LatencySummary `json:"detections"`
+	Scorecard  *score.Scorecard     `json:"scorecard,omitempty"`
+	Audit      AuditSummary         `json:"audit"`
+	Sections   []Section            `json:"sections,omitempty"`
+	Appendix   []SignatureEntry     `json:"appendix"`
+	// Historical marks a report rebuilt from records kept before RTE-A,
+	// such as a spreadsheet tracker. Its tasks were never signed, so it
+	// has no appendix or audit trail to verify.
+	Historical bool `json:"historical,omitempty"`
+	// Metadata brands and marks the deliverables rendered from the report.
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// Window spans the earliest task start to the latest task finish.
+type Window struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Summary counts tasks by final state. Tasks without a result are pending.
+type Summary struct {
+	Tasks   int                   `json:"tasks"`
+	ByState map[rte.TaskState]int `json:"by_state"`
+	// Runtime is the sum of task execution times.
+	Runtime time.Duration `json:"runtime_ns"`
+	// Withheld counts tasks classified above the report and left out.
+	Withheld int `json:"withheld,omitempty"`
+}
+
+// TaskEntry is one task's row in the task log.
+type TaskEntry struct {
+	ID         string        `json:"id"`
+	Type       rte.TaskType  `json:"type"`
+	Operator   string        `json:"operator"`
+	ApprovedBy string        `json:"approved_by"`
+	Techniques []string      `json:"techniques,omitempty"`
+	State      rte.TaskState `json:"state"`
+	StartedAt  time.Time     `json:"started_at,omitempty"`
+	FinishedAt time.Time     `json:"finished_at,omitempty"`
+	Duration   time.Duration `json:"duration_ns,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	// ReissuedFromID and SupersedesID link repeated attempts at one
+	// objective; see rte.Lineage.
+	ReissuedFromID string    `json:"reissued_from_id,omitempty"`
+	SupersedesID   string    `json:"supersedes_id,omitempty"`
+	Phase          rte.Phase `json:"phase,omitempty"`
+	// CleanupOf is the task a cleanup task undid.
+	CleanupOf string `json:"cleanup_of,omitempty"`
+}
+
+// PhaseEntry is one phase of the timeline: its tasks, in ID order, and
+// the span from the first of them starting to the last finishing. Tasks
+// naming no phase are listed under the empty phase, last.
+type PhaseEntry struct {
+	Phase    rte.Phase     `json:"phase"`
+	Tasks    []string      `json:"tasks"`
+	Start    time.Time     `json:"start,omitempty"`
+	End      time.Time     `json:"end,omitempty"`
+	Duration time.Duration `json:"duration_ns,omitempty"`
+}
+
+// AuditSummary records the audit trail and the outcome of verifying its
+// hash chain.
+type AuditSummary struct {
+	Records       int           `json:"records"`
+	ChainVerified bool          `json:"chain_verified"`
+	ChainError    string        `json:"chain_error,omitempty"`
+	HeadHash      string        `json:"head_hash,omitempty"`
+	Entries       []AuditRecord `json:"entries"`
+	// Withheld counts records above the report's classification. The
+	// chain was verified over them but they are not listed.
+	Withheld int `json:"withheld,omitempty"`
+}
+
+// SignatureEntry is an appendix entry: the signed task exactly as issued,
+// plus whether its signatures verified when the report was built.
+type SignatureEntry struct {
+	Signed   rte.SignedTask `json:"signed_task"`
+	Verified bool           `json:"verified"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// Build assembles the report for in.Engagement at now. Tasks, results, and
+// detections from other engagements are dropped, as are those of tasks
+// classified above in.Classification; results, detections, and audit
+// records without a task are at the engagement's level. Signature and audit
+// chain failures do not stop the build; they are recorded so the reader
+// sees them. A failing section provider or unreadable logo does stop it.
+func Build(in Input, now time.Time) (*Report, error) {
+	if in.Engagement == "" {
+		return nil, errors.New("engagement is required")
+	}
+	var engLevel rte.Classification
+	if in.Metadata != nil {
+		engLevel = in.Metadata.Classification
+	}
+	level := in.Classification
+	if level == "" {
+		level = engLevel
+	}
+	meta, err := resolveMetadata(in.Metadata, level)
+	if err != nil {
+		return nil, err
+	}
+	taskLevels := make(map[string]rte.Classification, len(in.Tasks))
+	for _, st := range in.Tasks {
+		if st.Task.Engagement == in.Engagement {
+			taskLevels[st.Task.ID] = st.Task.Classification
+		}
+	}
+	releasable := func(taskID *string) bool {
+		var c rte.Classification
+		if taskID != nil {
+			c = taskLevels[*taskID]
+		}
+		return rte.Releasable(c, engLevel, level)
+	}
+	r := &Report{
+		SchemaVersion: SchemaVersion,
+		Engagement:    in.Engagement,
+		Client:        in.Client,
+		GeneratedAt:   now.UTC(),
+		Summary:       Summary{ByState: make(map[rte.TaskState]int)},
+		Tasks:         []TaskEntry{},
+		Detections:    []rte.LatencySummary{},
+		Appendix:      []SignatureEntry{},
+		Metadata:      meta,
+	}
+	results := make(map[string]rte.TaskResult, len(in.Results))
+	var engResults []rte.TaskResult
+	for _, res := range in.Results {
+		if res.Engagement == in.Engagement && releasable(&res.TaskID) {
+			results[res.TaskID] = res
+			engResults = append(engResults, res)
+		}
+	}
+	signed := append([]rte.SignedTask(nil), in.Tasks...)
+	sort.Slice(signed, func(i, j int) bool { return signed[i].Task.ID < signed[j].Task.ID })
+	var tasks []rte.Task
+	for _, st := range signed {
+		t := st.Task
+		if t.Engagement != in.Engagement {
+			continue
+		}
+		if !releasable(&t.ID) {
+			r.Summary.Withheld++
+			continue
+		}
+		tasks = append(tasks, t)
+		e := TaskEntry{
+			ID: t.ID, Type: t.Type, Operator: t.Operator, ApprovedBy: t.ApprovedBy,
+			Techniques: t.Techniques, State: t.State,
+			ReissuedFromID: t.ReissuedFromID, SupersedesID: t.SupersedesID, Phase: t.Phase,
+			CleanupOf: t.CleanupOf,
+		}
+		if res, ok := results[t.ID]; ok {
+			e.State, e.Error = res.State, res.Error
+			e.StartedAt, e.FinishedAt = res.StartedAt.UTC(), res.FinishedAt.UTC()
+			e.Duration = res.FinishedAt.Sub(res.StartedAt)
+			r.Summary.Runtime += e.Duration
+			if r.Window.Start.IsZero() || e.StartedAt.Before(r.Window.Start) {
+				r.Window.Start = e.StartedAt
+			}
+			if e.FinishedAt.After(r.Window.End) {
+				r.Window.End = e.FinishedAt
+			}
+		}
+		r.Tasks = append(r.Tasks, e)
+		r.Summary.ByState[e.State]++
+
+		entry := SignatureEntry{Signed: st, Verified: true}
+		if err := verifySigned(&st); err != nil {
+			entry.Verified, entry.Error = false, err.Error()
+		}
+		r.Appendix = append(r.Appendix, entry)
+	}
+	r.Summary.Tasks = len(r.Tasks)
+	r.Phases = phaseTimeline(r.Tasks)
+	r.Window.Duration = r.Window.End.Sub(r.Window.Start)
+	r.Coverage = rte.Coverage(in.Engagement, tasks, engResults)
+
+	var detections []rte.DetectionRecord
+	for _, d := range in.Detections {
+		if d.Engagement == in.Engagement && releasable(&d.TaskID) {
+			detections = append(detections, d)
+		}
+	}
+	r.Detections = append(r.Detections, rte.SummarizeDetectionLatency(detections)...)
+
+	r.Audit = AuditSummary{Records: len(in.Audit), Entries: []AuditRecord{}}
+	for _, rec := range in.Audit {
+		if releasable(rec.TaskID) {
+			r.Audit.Entries = append(r.Audit.Entries, rec)
+		} else {
+			r.Audit.Withheld++
+		}
+	}
+	if rte.Releasable("", engLevel, level) {
+		r.Scorecard = in.Scorecard
+	}
+	if err := VerifyAuditChain(in.Audit); err != nil {
+		r.Audit.ChainError = err.Error()
+	} else {
+		r.Audit.ChainVerified = true
+	}
+	if n := len(in.Audit); n > 0 {
+		r.Audit.HeadHash = in.Audit[n-1].ChainHash
+	}
+	sections, err := in.Sections.sections(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sections {
+		if rte.Releasable(s.Classification, engLevel, level) {
+			r.Sections = append(r.Sections, s)
+		}
+	}
+	return r, nil
+}
+
+// phaseTimeline groups tasks by phase, in engagement order, or returns
+// nil if no task names a phase.
+func phaseTimeline(tasks []TaskEntry) []PhaseEntry {
+	byPhase := make(map[rte.Phase]*PhaseEntry)
+	for _, t := range tasks {
+		e, ok := byPhase[t.Phase]
+		if !ok {
+			e = &PhaseEntry{Phase: t.Phase}
+			byPhase[t.Phase] = e
+		}
+		e.Tasks = append(e.Tasks, t.ID)
+		if t.StartedAt.IsZero() {
+			continue
+		}
+		if e.Start.IsZero() || t.StartedAt.Before(e.Start) {
+			e.Start = t.StartedAt
+		}
+		if t.FinishedAt.After(e.End) {
+			e.End = t.FinishedAt
+		}
+	}
+	if _, unphased := byPhase[""]; len(byPhase) == 0 || (unphased && len(byPhase) == 1) {
+		return nil
+	}
+	var out []PhaseEntry
+	for _, p := range append(rte.Phases(), "") {
+		if e, ok := byPhase[p]; ok {
+			e.Duration = e.End.Sub(e.Start)
+			out = append(out, *e)
+		}
+	}
+	return out
+}
+
+// verifySigned checks the operator signature and, when present, the
+// approval countersignature. Expiry is deliberately not checked.
+func verifySigned(st *rte.SignedTask) error {
+	if err := rte.VerifyTaskSignature(st); err != nil {
+		return err
+	}
+	if st.Approval != nil {
+		return rte.VerifyApproval(st)
+	}
+	return nil
+}
+
+// JSON renders the report as indented JSON.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// SignedReport wraps a report with the issuer's signature.
+type SignedReport struct {
+	Report    Report `json:"report"`
+	PublicKey []byte `json:"public_key"`
+	Signature []byte `json:"signature"`
+}
+
+// Sign signs the report with the issuer's key.
+func Sign(r *Report, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedReport, error) {
+	if r == nil {
+		return nil, errors.New("report is nil")
+	}
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("marshal report: %w", err)
+	}
+	return &SignedReport{Report: *r, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// Verify checks the report signature, then re-verifies every signed task in
+// the appendix and the audit hash chain against the verdicts the report
+// records, so a report cannot claim evidence is sound when it is not.
+func Verify(sr *SignedReport) error {
+	if sr == nil {
+		return errors.New("signed report is nil")
+	}
+	if len(sr.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sr.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	payload, err := json.Marshal(sr.Report)
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+	if !ed25519.Verify(sr.PublicKey, payload, sr.Signature) {
+		return errors.New("signature verification failed")
+	}
+	for _, e := range sr.Report.Appendix {
+		st := e.Signed
+		if ok := verifySigned(&st) == nil; ok != e.Verified {
+			return fmt.Errorf("appendix task %s: recorded verified=%t, recomputed %t", st.Task.ID, e.Verified, ok)
+		}
+	}
+	audit := sr.Report.Audit
+	if ok := VerifyAuditChain(audit.Entries) == nil; ok != audit.ChainVerified {
+		return fmt.Errorf("audit chain: recorded verified=%t, recomputed %t", audit.ChainVerified, ok)
+	}
+	return nil
+}
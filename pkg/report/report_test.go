@@ -0,0 +1,125 @@
+package report
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+	"github.com/codethor0/rte-a-reference/pkg/score"
+)
+
+func fixture(t *testing.T) Input {
+	t.Helper()
+	pub, priv, err := rte.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("generate key pair: %v", err)
+	}
+	lpub, lpriv, _ := rte.GenerateKeyPair()
+	now := time.Now().UTC()
+	sign := func(id string, typ rte.TaskType, techniques ...string) rte.SignedTask {
+		st, err := rte.SignTask(rte.Task{
+			ID: id, Engagement: "eng-2026-q1", Type: typ, CreatedAt: now, TTLSeconds: 600,
+			Operator: "op-alice", ApprovedBy: "lead-bob", State: rte.StatePending, Techniques: techniques,
+		}, priv, pub)
+		if err != nil {
+			t.Fatalf("SignTask: %v", err)
+		}
+		if err := rte.Countersign(st, lpriv, lpub); err != nil {
+			t.Fatalf("Countersign: %v", err)
+		}
+		return *st
+	}
+	start := now.Add(time.Minute).Truncate(time.Second)
+	return Input{
+		Engagement: "eng-2026-q1",
+		Tasks: []rte.SignedTask{
+			sign("t2", rte.TaskSimulateBeacon, "T1071.001"),
+			sign("t1", rte.TaskSimulateCredentialSpray, "T1110.003"),
+			sign("t3", rte.TaskSimulateLogin),
+		},
+		Results: []rte.TaskResult{
+			{TaskID: "t1", Engagement: "eng-2026-q1", State: rte.StateCompleted, StartedAt: start, FinishedAt: start.Add(2 * time.Minute)},
+			{TaskID: "t2", Engagement: "eng-2026-q1", State: rte.StateFailed, StartedAt: start.Add(time.Minute), FinishedAt: start.Add(5 * time.Minute), Error: "sink unreachable"},
+			{TaskID: "t9", Engagement: "eng-other", State: rte.StateCompleted},
+		},
+		Detections: []rte.DetectionRecord{
+			{TaskID: "t1", Engagement: "eng-2026-q1", Technique: "T1110.003", Detected: true, Latency: 3 * time.Minute},
+		},
+		Audit:     pythonRecords(t),
+		Scorecard: &score.Scorecard{Engagement: "eng-2026-q1", Score: 87.5, Expected: 1, Detected: 1},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	in := fixture(t)
+	r, err := Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if r.Summary.Tasks != 3 || r.Summary.ByState[rte.StateCompleted] != 1 ||
+		r.Summary.ByState[rte.StateFailed] != 1 || r.Summary.ByState[rte.StatePending] != 1 {
+		t.Fatalf("unexpected summary %+v", r.Summary)
+	}
+	if r.Tasks[0].ID != "t1" || r.Tasks[1].Error != "sink unreachable" {
+		t.Errorf("unexpected task log %+v", r.Tasks)
+	}
+	if r.Window.Duration != 5*time.Minute || r.Summary.Runtime != 6*time.Minute {
+		t.Errorf("unexpected timing window=%s runtime=%s", r.Window.Duration, r.Summary.Runtime)
+	}
+	if r.Coverage.Exercised != 1 || len(r.Detections) != 1 || r.Detections[0].P50 != 3*time.Minute {
+		t.Errorf("unexpected coverage/detections %+v %+v", r.Coverage.Exercised, r.Detections)
+	}
+	if !r.Audit.ChainVerified || r.Audit.HeadHash != in.Audit[1].ChainHash {
+		t.Errorf("unexpected audit summary %+v", r.Audit)
+	}
+	for _, e := range r.Appendix {
+		if !e.Verified {
+			t.Errorf("appendix %s not verified: %s", e.Signed.Task.ID, e.Error)
+		}
+	}
+
+	in.Tasks[0].Signature[0] ^= 0xff
+	in.Audit[0].Action = "tampered"
+	r, _ = Build(in, time.Now())
+	if r.Appendix[1].Verified || r.Audit.ChainVerified {
+		t.Error("tampered evidence reported as verified")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	r, err := Build(fixture(t), time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pub, priv, _ := rte.GenerateKeyPair()
+	sr, err := Sign(r, priv, pub)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	raw, _ := json.Marshal(sr)
+	var decoded SignedReport
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if err := Verify(&decoded); err != nil {
+		t.Fatalf("Verify after round trip: %v", err)
+	}
+
+	// A re-signed report that quietly swaps an appendix task must not pass.
+	forged := decoded.Report
+	forged.Appendix[0].Signed.Task.Operator = "op-mallory"
+	resigned, _ := Sign(&forged, priv, pub)
+	if err := Verify(resigned); err == nil || !strings.Contains(err.Error(), "appendix task") {
+		t.Fatalf("expected forged appendix to fail, got %v", err)
+	}
+	decoded.Report.Summary.Tasks = 99
+	if err := Verify(&decoded); err == nil {
+		t.Fatal("expected tampered report to fail")
+	}
+	if _, err := Sign(r, ed25519.PrivateKey{1}, pub); err == nil {
+		t.Fatal("expected bad key to fail")
+	}
+}
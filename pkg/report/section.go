@@ -0,0 +1,123 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// SectionPosition places a custom section relative to the built-in ones.
+type SectionPosition string
+
+const (
+	// SectionIntro sections follow the summary, before the score and task
+	// log; methodology and scope statements belong here.
+	SectionIntro SectionPosition = "intro"
+	// SectionClosing sections precede the signature appendix; consultant
+	// bios and contact details belong here.
+	SectionClosing SectionPosition = "closing"
+)
+
+// Section is a custom report section. Paragraphs are plain text: HTML and
+// PDF output escape them, and Markdown output passes them through.
+type Section struct {
+	ID         string          `json:"id"`
+	Title      string          `json:"title"`
+	Position   SectionPosition `json:"position"`
+	Paragraphs []string        `json:"paragraphs"`
+	// Classification, if set, is the section's level where it differs
+	// from the engagement's; reports below it leave the section out.
+	Classification rte.Classification `json:"classification,omitempty"`
+}
+
+// SectionProvider contributes one section to each report. It sees the
+// report as built so far, so sections can quote engagement details.
+type SectionProvider interface {
+	Section(r *Report) (Section, error)
+}
+
+// SectionProviderFunc adapts a function to SectionProvider.
+type SectionProviderFunc func(r *Report) (Section, error)
+
+// Section calls f(r).
+func (f SectionProviderFunc) Section(r *Report) (Section, error) {
+	return f(r)
+}
+
+// StaticSection returns a provider for fixed boilerplate.
+func StaticSection(s Section) SectionProvider {
+	return SectionProviderFunc(func(*Report) (Section, error) { return s, nil })
+}
+
+// SectionRegistry holds the section providers applied to reports, in
+// registration order. It is safe for concurrent use.
+type SectionRegistry struct {
+	mu        sync.RWMutex
+	ids       []string
+	providers map[string]SectionProvider
+}
+
+// NewSectionRegistry returns an empty registry.
+func NewSectionRegistry() *SectionRegistry {
+	return &SectionRegistry{providers: make(map[string]SectionProvider)}
+}
+
+// Register installs a provider under id, replacing any previous one while
+// keeping its place in the order.
+func (s *SectionRegistry) Register(id string, p SectionProvider) error {
+	if id == "" {
+		return errors.New("section ID is required")
+	}
+	if p == nil {
+		return errors.New("section provider is nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.providers[id]; !ok {
+		s.ids = append(s.ids, id)
+	}
+	s.providers[id] = p
+	return nil
+}
+
+// sections runs every provider against r.
+func (s *SectionRegistry) sections(r *Report) ([]Section, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Section, 0, len(s.ids))
+	for _, id := range s.ids {
+		sec, err := s.providers[id].Section(r)
+		if err != nil {
+			return nil, fmt.Errorf("section %s: %w", id, err)
+		}
+		sec.ID = id
+		switch sec.Position {
+		case "":
+			sec.Position = SectionClosing
+		case SectionIntro, SectionClosing:
+		default:
+			return nil, fmt.Errorf("section %s: invalid position %q", id, sec.Position)
+		}
+		if sec.Title == "" {
+			return nil, fmt.Errorf("section %s: title is required", id)
+		}
+		out = append(out, sec)
+	}
+	return out, nil
+}
+
+// SectionsAt returns the report's custom sections at pos, in order.
+func (r *Report) SectionsAt(pos SectionPosition) []Section {
+	var out []Section
+	for _, s := range r.Sections {
+		if s.Position == pos {
+			out = append(out, s)
+		}
+	}
+	return out
+}
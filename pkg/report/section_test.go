@@ -0,0 +1,71 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSectionRegistry(t *testing.T) {
+	reg := NewSectionRegistry()
+	_ = reg.Register("bios", StaticSection(Section{Title: "Consultants", Paragraphs: []string{"Alice leads the <team>."}}))
+	_ = reg.Register("methodology", SectionProviderFunc(func(r *Report) (Section, error) {
+		return Section{Title: "Methodology", Position: SectionIntro,
+			Paragraphs: []string{"This engagement ran " + r.Engagement + " under the RTE-A rules of engagement."}}, nil
+	}))
+	in := fixture(t)
+	in.Sections = reg
+	r, err := Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(r.Sections) != 2 || r.Sections[0].ID != "bios" || r.Sections[0].Position != SectionClosing {
+		t.Fatalf("unexpected sections %+v", r.Sections)
+	}
+	if got := r.SectionsAt(SectionIntro); len(got) != 1 || !strings.Contains(got[0].Paragraphs[0], "eng-2026-q1") {
+		t.Fatalf("intro sections %+v", got)
+	}
+
+	var md strings.Builder
+	_ = r.Markdown(&md)
+	intro, task, bios, appendix := strings.Index(md.String(), "## Methodology"), strings.Index(md.String(), "## Task Log"),
+		strings.Index(md.String(), "## Consultants"), strings.Index(md.String(), "## Appendix")
+	if intro < 0 || intro > task || bios < task || bios > appendix {
+		t.Errorf("sections misplaced in markdown: %d %d %d %d", intro, task, bios, appendix)
+	}
+	var html strings.Builder
+	_ = r.HTML(&html)
+	if !strings.Contains(html.String(), "<p>Alice leads the &lt;team&gt;.</p>") {
+		t.Error("HTML section missing or unescaped")
+	}
+	var pdf bytes.Buffer
+	if err := r.PDF(&pdf, PDFOptions{}); err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	if !strings.Contains(strings.Join(pdfObjects(t, pdf.Bytes()), ""), "(Consultants)") {
+		t.Error("PDF missing section")
+	}
+}
+
+func TestSectionRegistry_Errors(t *testing.T) {
+	reg := NewSectionRegistry()
+	if err := reg.Register("", StaticSection(Section{Title: "x"})); err == nil {
+		t.Error("expected empty ID to fail")
+	}
+	_ = reg.Register("broken", SectionProviderFunc(func(*Report) (Section, error) {
+		return Section{}, errors.New("bios service down")
+	}))
+	in := fixture(t)
+	in.Sections = reg
+	if _, err := Build(in, time.Now()); err == nil || !strings.Contains(err.Error(), "section broken") {
+		t.Errorf("expected provider error, got %v", err)
+	}
+	reg = NewSectionRegistry()
+	_ = reg.Register("untitled", StaticSection(Section{Position: SectionIntro}))
+	in.Sections = reg
+	if _, err := Build(in, time.Now()); err == nil {
+		t.Error("expected untitled section to fail")
+	}
+}
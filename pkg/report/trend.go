@@ -0,0 +1,200 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/attack"
+)
+
+// Archive holds the signed reports of finished engagements and answers
+// trend queries across them. Only reports that verify are admitted. It is
+// safe for concurrent use.
+type Archive struct {
+	mu      sync.RWMutex
+	reports map[string]Report
+}
+
+// NewArchive returns an empty archive.
+func NewArchive() *Archive {
+	return &Archive{reports: make(map[string]Report)}
+}
+
+// Add verifies a signed report and archives it, replacing any earlier report
+// for the same engagement.
+func (a *Archive) Add(sr *SignedReport) error {
+	if sr == nil {
+		return errors.New("signed report is nil")
+	}
+	if err := Verify(sr); err != nil {
+		return fmt.Errorf("archive %s: %w", sr.Report.Engagement, err)
+	}
+	if sr.Report.Client == "" {
+		return fmt.Errorf("archive %s: report has no client", sr.Report.Engagement)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reports[sr.Report.Engagement] = sr.Report
+	return nil
+}
+
+// AddHistorical archives a report marked Historical, replacing any earlier
+// report for the same engagement. Historical reports are unsigned, so
+// nothing is verified; trend points drawn from them are marked.
+func (a *Archive) AddHistorical(r Report) error {
+	if !r.Historical {
+		return fmt.Errorf("archive %s: report is not marked historical", r.Engagement)
+	}
+	if r.Client == "" {
+		return fmt.Errorf("archive %s: report has no client", r.Engagement)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reports[r.Engagement] = r
+	return nil
+}
+
+// LoadDir archives every *.json signed report in dir.
+func (a *Archive) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		var sr SignedReport
+		if err := json.Unmarshal(raw, &sr); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if err := a.Add(&sr); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Report returns the archived report for an engagement.
+func (a *Archive) Report(engagement string) (Report, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	r, ok := a.reports[engagement]
+	return r, ok
+}
+
+// Reports returns a client's archived reports, oldest engagement first.
+func (a *Archive) Reports(client string) []Report {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var out []Report
+	for _, r := range a.reports {
+		if r.Client == client {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ei, ej := out[i].endedAt(), out[j].endedAt()
+		if !ei.Equal(ej) {
+			return ei.Before(ej)
+		}
+		return out[i].Engagement < out[j].Engagement
+	})
+	return out
+}
+
+// endedAt places a report on the timeline. Engagements that ran no
+// tasks fall back to when the report was generated.
+func (r Report) endedAt() time.Time {
+	if r.Window.End.IsZero() {
+		return r.GeneratedAt
+	}
+	return r.Window.End
+}
+
+// TrendPoint is one engagement's measurement of a technique.
+type TrendPoint struct {
+	Engagement string    `json:"engagement"`
+	EndedAt    time.Time `json:"ended_at"`
+	Expected   int       `json:"expected"`
+	Detected   int       `json:"detected"`
+	// DetectionRate is Detected/Expected in [0, 1].
+	DetectionRate float64       `json:"detection_rate"`
+	P50           time.Duration `json:"p50_ns"`
+	P95           time.Duration `json:"p95_ns"`
+	// Historical is set when the point comes from an imported pre-RTE-A
+	// record rather than a signed report.
+	Historical bool `json:"historical,omitempty"`
+}
+
+// TechniqueTrend is a technique's detection history for one client.
+type TechniqueTrend struct {
+	Technique string       `json:"technique"`
+	Name      string       `json:"name"`
+	Points    []TrendPoint `json:"points"`
+	// RateChange and P50Change compare the latest point with the first;
+	// a positive RateChange and negative P50Change mean improvement.
+	RateChange float64       `json:"rate_change"`
+	P50Change  time.Duration `json:"p50_change_ns"`
+}
+
+// ErrNoHistory is returned when a client has no archived measurement of the
+// requested technique.
+var ErrNoHistory = errors.New("no archived detections")
+
+// TechniqueTrend returns one technique's trend for a client.
+func (a *Archive) TechniqueTrend(client, technique string) (TechniqueTrend, error) {
+	for _, t := range a.Trends(client) {
+		if t.Technique == technique {
+			return t, nil
+		}
+	}
+	return TechniqueTrend{}, fmt.Errorf("%s for %s: %w", technique, client, ErrNoHistory)
+}
+
+// Trends returns the trend of every technique the client has been measured
+// on, sorted by technique. Engagements that did not exercise a technique
+// contribute no point to it.
+func (a *Archive) Trends(client string) []TechniqueTrend {
+	byTech := make(map[string]*TechniqueTrend)
+	for _, r := range a.Reports(client) {
+		for _, s := range r.Detections {
+			if s.Expected == 0 {
+				continue
+			}
+			t := byTech[s.Technique]
+			if t == nil {
+				t = &TechniqueTrend{Technique: s.Technique}
+				if tech, ok := attack.Lookup(s.Technique); ok {
+					t.Name = tech.Name
+				}
+				byTech[s.Technique] = t
+			}
+			t.Points = append(t.Points, TrendPoint{
+				Engagement: r.Engagement, EndedAt: r.endedAt(),
+				Expected: s.Expected, Detected: s.Detected,
+				DetectionRate: float64(s.Detected) / float64(s.Expected),
+				P50:           s.P50, P95: s.P95,
+				Historical: r.Historical,
+			})
+		}
+	}
+	out := make([]TechniqueTrend, 0, len(byTech))
+	for _, t := range byTech {
+		first, last := t.Points[0], t.Points[len(t.Points)-1]
+		t.RateChange = last.DetectionRate - first.DetectionRate
+		if first.Detected > 0 && last.Detected > 0 {
+			t.P50Change = last.P50 - first.P50
+		}
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Technique < out[j].Technique })
+	return out
+}
@@ -0,0 +1,97 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func archivedReport(t *testing.T, engagement, client string, end time.Time, detections ...rte.DetectionRecord) *SignedReport {
+	t.Helper()
+	for i := range detections {
+		detections[i].Engagement = engagement
+	}
+	r, err := Build(Input{Engagement: engagement, Client: client, Detections: detections}, end)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pub, priv, _ := rte.GenerateKeyPair()
+	sr, err := Sign(r, priv, pub)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return sr
+}
+
+func TestArchive_Trends(t *testing.T) {
+	q1 := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	a := NewArchive()
+	for _, sr := range []*SignedReport{
+		archivedReport(t, "eng-q2", "acme", q1.AddDate(0, 3, 0),
+			rte.DetectionRecord{Technique: "T1110.003", Detected: true, Latency: 4 * time.Minute},
+			rte.DetectionRecord{Technique: "T1110.003", Detected: true, Latency: 6 * time.Minute}),
+		archivedReport(t, "eng-q1", "acme", q1,
+			rte.DetectionRecord{Technique: "T1110.003", Detected: true, Latency: 20 * time.Minute},
+			rte.DetectionRecord{Technique: "T1110.003"},
+			rte.DetectionRecord{Technique: "T1071.001"}),
+		archivedReport(t, "eng-other", "globex", q1,
+			rte.DetectionRecord{Technique: "T1110.003"}),
+	} {
+		if err := a.Add(sr); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	trends := a.Trends("acme")
+	if len(trends) != 2 || trends[0].Technique != "T1071.001" {
+		t.Fatalf("unexpected trends %+v", trends)
+	}
+	spray, err := a.TechniqueTrend("acme", "T1110.003")
+	if err != nil {
+		t.Fatalf("TechniqueTrend: %v", err)
+	}
+	if len(spray.Points) != 2 || spray.Points[0].Engagement != "eng-q1" {
+		t.Fatalf("points not in engagement order: %+v", spray.Points)
+	}
+	if spray.Points[0].DetectionRate != 0.5 || spray.RateChange != 0.5 || spray.P50Change != -16*time.Minute {
+		t.Errorf("unexpected trend %+v", spray)
+	}
+	if _, err := a.TechniqueTrend("acme", "T1486"); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("expected ErrNoHistory, got %v", err)
+	}
+}
+
+func TestArchive_Add(t *testing.T) {
+	a := NewArchive()
+	sr := archivedReport(t, "eng-q1", "acme", time.Now())
+	sr.Report.Client = "globex"
+	if err := a.Add(sr); err == nil {
+		t.Error("expected tampered report to be rejected")
+	}
+	if err := a.Add(archivedReport(t, "eng-q1", "", time.Now())); err == nil {
+		t.Error("expected report without client to be rejected")
+	}
+	if err := a.AddHistorical(Report{Engagement: "eng-2019", Client: "acme"}); err == nil {
+		t.Error("expected unmarked report to be rejected as historical")
+	}
+}
+
+func TestArchive_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	raw, _ := json.Marshal(archivedReport(t, "eng-q1", "acme", time.Now(),
+		rte.DetectionRecord{Technique: "T1078", Detected: true}))
+	if err := os.WriteFile(filepath.Join(dir, "eng-q1.json"), raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := NewArchive()
+	if err := a.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	if got := a.Reports("acme"); len(got) != 1 || got[0].Engagement != "eng-q1" {
+		t.Fatalf("unexpected reports %+v", got)
+	}
+}
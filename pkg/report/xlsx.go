@@ -0,0 +1,228 @@
+package report
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/attack"
+)
+
+// Cell styles defined in xlsxStyles.
+const (
+	styleDefault = iota
+	styleHeader
+	styleTime
+)
+
+// excelEpoch is day zero of the 1900 date system as Excel counts it.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+type cell struct {
+	value any
+	style int
+}
+
+type sheet struct {
+	name string
+	rows [][]cell
+	// marking, if set, is printed in the page header and footer.
+	marking string
+}
+
+func (s *sheet) header(names ...string) {
+	row := make([]cell, len(names))
+	for i, n := range names {
+		row[i] = cell{value: n, style: styleHeader}
+	}
+	s.rows = append(s.rows, row)
+}
+
+func (s *sheet) row(values ...any) {
+	row := make([]cell, len(values))
+	for i, v := range values {
+		row[i] = cell{value: v}
+		if _, ok := v.(time.Time); ok {
+			row[i].style = styleTime
+		}
+	}
+	s.rows = append(s.rows, row)
+}
+
+// XLSX writes the standard deliverable workbook: a task log, findings
+// derived from detection results, and the ATT&CK coverage matrix. A report
+// with metadata gets a cover sheet first, and its marking is printed on
+// every sheet's pages.
+func (r *Report) XLSX(w io.Writer) error {
+	tasks := &sheet{name: "Task Log"}
+	tasks.header("Task", "Type", "Operator", "Approved By", "Techniques", "State", "Started (UTC)", "Finished (UTC)", "Duration (s)", "Error")
+	for _, t := range r.Tasks {
+		tasks.row(t.ID, string(t.Type), t.Operator, t.ApprovedBy, strings.Join(t.Techniques, ", "), string(t.State),
+			t.StartedAt, t.FinishedAt, t.Duration.Seconds(), t.Error)
+	}
+
+	findings := &sheet{name: "Findings"}
+	findings.header("Technique", "Name", "Severity", "Finding", "Expected", "Detected", "p50 Latency (min)", "p95 Latency (min)")
+	for _, d := range r.Detections {
+		severity, finding := findingFor(d.Detected, d.Expected)
+		tech, _ := attack.Lookup(d.Technique)
+		findings.row(d.Technique, tech.Name, severity, finding, d.Expected, d.Detected, d.P50.Minutes(), d.P95.Minutes())
+	}
+
+	coverage := &sheet{name: "Coverage"}
+	coverage.header("Tactic ID", "Tactic", "Technique", "Name", "Planned", "Exercised")
+	for _, tac := range r.Coverage.Tactics {
+		if len(tac.Techniques) == 0 {
+			coverage.row(tac.Tactic.ID, tac.Tactic.Name, "", "", 0, 0)
+		}
+		for _, tc := range tac.Techniques {
+			coverage.row(tac.Tactic.ID, tac.Tactic.Name, tc.ID, tc.Name, tc.Planned, tc.Exercised)
+		}
+	}
+	sheets := []*sheet{tasks, findings, coverage}
+	if m := r.Metadata; m != nil {
+		cover := &sheet{name: "Cover"}
+		cover.header("Field", "Value")
+		cover.row("Engagement", r.Engagement)
+		cover.row("Client", m.ClientName)
+		cover.row("Classification", string(m.Classification))
+		cover.row("Distribution", m.Distribution)
+		cover.row("Generated (UTC)", r.GeneratedAt)
+		if n := r.Summary.Withheld; n > 0 {
+			cover.row("Withheld tasks", n)
+		}
+		sheets = append([]*sheet{cover}, sheets...)
+		for _, s := range sheets {
+			s.marking = m.Marking()
+		}
+	}
+	return writeWorkbook(w, sheets...)
+}
+
+// findingFor classifies a technique's detection outcome.
+func findingFor(detected, expected int) (severity, finding string) {
+	switch {
+	case detected == 0:
+		return "High", "Not detected"
+	case detected < expected:
+		return "Medium", fmt.Sprintf("Partially detected (%d of %d)", detected, expected)
+	default:
+		return "Informational", "Detected"
+	}
+}
+
+// writeWorkbook writes a minimal Office Open XML workbook. Strings are
+// stored inline so no shared-string table is needed.
+func writeWorkbook(w io.Writer, sheets ...*sheet) error {
+	z := zip.NewWriter(w)
+	var overrides, rels, entries strings.Builder
+	for i, s := range sheets {
+		n := i + 1
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		fmt.Fprintf(&entries, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(s.name), n, n)
+	}
+	styleRel := len(sheets) + 1
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, styleRel)
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			overrides.String() + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + entries.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, s := range sheets {
+		parts = append(parts, struct{ name, body string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheetXML(s)})
+	}
+	for _, p := range parts {
+		f, err := z.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+func sheetXML(s *sheet) string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<sheetData>`)
+	for i, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, c := range row {
+			ref := columnName(j) + strconv.Itoa(i+1)
+			switch v := c.value.(type) {
+			case string:
+				if v == "" {
+					continue
+				}
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, c.style, xmlEscape(v))
+			case int:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%d</v></c>`, ref, c.style, v)
+			case float64:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, c.style, strconv.FormatFloat(v, 'f', -1, 64))
+			case time.Time:
+				if v.IsZero() {
+					continue
+				}
+				days := float64(v.UTC().Sub(excelEpoch)) / float64(24*time.Hour)
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, c.style, strconv.FormatFloat(days, 'f', 8, 64))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+	if s.marking != "" {
+		// & introduces header codes, so a literal one is doubled.
+		m := "&amp;C&amp;B" + xmlEscape(strings.ReplaceAll(s.marking, "&", "&&"))
+		fmt.Fprintf(&b, `<headerFooter><oddHeader>%s</oddHeader><oddFooter>%s</oddFooter></headerFooter>`, m, m)
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+// columnName converts a zero-based column index to A, B, ..., Z, AA, ...
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xlsxStyles defines the default, bold header, and UTC timestamp styles.
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs></styleSheet>`
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open workbook: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	return files
+}
+
+func TestReport_XLSX(t *testing.T) {
+	in := fixture(t)
+	in.Results[1].Error = `<bad> & "quoted"`
+	r, err := Build(in, time.Now())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := r.XLSX(&buf); err != nil {
+		t.Fatalf("XLSX: %v", err)
+	}
+	files := readZip(t, buf.Bytes())
+	for name, body := range files {
+		if err := xml.Unmarshal([]byte(body), new(struct{})); err != nil {
+			t.Errorf("%s is not well-formed XML: %v", name, err)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="Task Log" sheetId="1" r:id="rId1"/>`) {
+		t.Errorf("workbook missing task log sheet: %s", files["xl/workbook.xml"])
+	}
+	tasks := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{">simulate_credential_spray<", "&lt;bad&gt; &amp; &#34;quoted&#34;", `<c r="G2" s="2"><v>`} {
+		if !strings.Contains(tasks, want) {
+			t.Errorf("task log missing %q", want)
+		}
+	}
+	if !strings.Contains(files["xl/worksheets/sheet2.xml"], ">Brute Force: Password Spraying<") {
+		t.Error("findings sheet missing technique name")
+	}
+	if !strings.Contains(files["xl/worksheets/sheet3.xml"], ">TA0006<") {
+		t.Error("coverage sheet missing credential access")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
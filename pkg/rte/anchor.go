@@ -0,0 +1,303 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultAnchorInterval is the time between checkpoints when
+// AuditAnchorer.Interval is zero.
+const DefaultAnchorInterval = 15 * time.Minute
+
+// Checkpoint commits to an engagement's audit chain up to one record. The
+// chain hash covers every earlier record, so anchoring it externally
+// anchors the whole prefix.
+type Checkpoint struct {
+	Engagement string    `json:"engagement"`
+	Sequence   int       `json:"sequence"`
+	ChainHash  string    `json:"chain_hash"`
+	Time       time.Time `json:"time"`
+}
+
+// Digest is the SHA-256 of the checkpoint's JSON, the value anchors record.
+func (c Checkpoint) Digest() []byte {
+	b, _ := json.Marshal(c)
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+// AnchorReceipt is an anchor's evidence that it recorded a checkpoint.
+type AnchorReceipt struct {
+	Checkpoint Checkpoint `json:"checkpoint"`
+	// Anchor names the kind of anchor, such as "rfc6962" or "notary".
+	Anchor string `json:"anchor"`
+	// Proof is the anchor's evidence, in its own format.
+	Proof json.RawMessage `json:"proof"`
+}
+
+// Anchor records checkpoints in a system outside the controller's control,
+// such as a transparency log, a notary service, or a public blockchain, so
+// rewriting anchored audit history means compromising that system too.
+type Anchor interface {
+	// Anchor records c and returns the receipt.
+	Anchor(ctx context.Context, c Checkpoint) (*AnchorReceipt, error)
+	// Verify checks that r proves its checkpoint was recorded.
+	Verify(ctx context.Context, r *AnchorReceipt) error
+}
+
+// AuditAnchorer periodically checkpoints every audit chain in Log to
+// Anchor, skipping chains that have not grown since their last checkpoint.
+// Receipts are kept in memory and passed to OnReceipt for storage
+// alongside the audit records. It is safe for concurrent use.
+type AuditAnchorer struct {
+	Log    *AuditLog
+	Anchor Anchor
+	// Interval is the time between checkpoints; 0 means
+	// DefaultAnchorInterval.
+	Interval time.Duration
+	// OnReceipt, if set, receives each new receipt.
+	OnReceipt func(AnchorReceipt)
+	// Now returns the current time; nil means time.Now.
+	Now    func() time.Time
+	Logger *slog.Logger
+
+	mu       sync.Mutex
+	receipts map[string][]AnchorReceipt
+}
+
+// Run checkpoints every Interval until ctx ends. Checkpoint errors are
+// logged, not returned, so an unreachable anchor does not stop the loop;
+// the next pass retries.
+func (a *AuditAnchorer) Run(ctx context.Context) error {
+	if a.Log == nil || a.Anchor == nil {
+		return errors.New("audit anchorer needs a log and an anchor")
+	}
+	interval := a.Interval
+	if interval <= 0 {
+		interval = DefaultAnchorInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := a.Checkpoint(ctx); err != nil && a.Logger != nil && ctx.Err() == nil {
+			a.Logger.Error("audit checkpoint", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Checkpoint anchors the head of every chain that grew since its last
+// receipt and returns the new receipts. It keeps going after a failed
+// anchor and returns the errors joined.
+func (a *AuditAnchorer) Checkpoint(ctx context.Context) ([]AnchorReceipt, error) {
+	if a.Log == nil || a.Anchor == nil {
+		return nil, errors.New("audit anchorer needs a log and an anchor")
+	}
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	chains := a.Log.Snapshot()
+	engagements := make([]string, 0, len(chains))
+	for eng := range chains {
+		engagements = append(engagements, eng)
+	}
+	sort.Strings(engagements)
+	var out []AnchorReceipt
+	var errs []error
+	for _, eng := range engagements {
+		chain := chains[eng]
+		if len(chain) == 0 {
+			continue
+		}
+		head := chain[len(chain)-1]
+		a.mu.Lock()
+		prev := a.receipts[eng]
+		a.mu.Unlock()
+		if n := len(prev); n > 0 && prev[n-1].Checkpoint.Sequence >= head.Sequence {
+			continue
+		}
+		cp := Checkpoint{Engagement: eng, Sequence: head.Sequence, ChainHash: head.ChainHash, Time: now().UTC()}
+		r, err := a.Anchor.Anchor(ctx, cp)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("anchor %s at %d: %w", eng, head.Sequence, err))
+			continue
+		}
+		a.mu.Lock()
+		if a.receipts == nil {
+			a.receipts = make(map[string][]AnchorReceipt)
+		}
+		a.receipts[eng] = append(a.receipts[eng], *r)
+		a.mu.Unlock()
+		if a.OnReceipt != nil {
+			a.OnReceipt(*r)
+		}
+		out = append(out, *r)
+	}
+	return out, errors.Join(errs...)
+}
+
+// Receipts returns the engagement's receipts, oldest first.
+func (a *AuditAnchorer) Receipts(engagement string) []AnchorReceipt {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AnchorReceipt(nil), a.receipts[engagement]...)
+}
+
+// VerifyAnchors checks records' hash chain, then that every receipt
+// commits to a record in it and verifies with anchor. It returns the
+// sequence of the latest anchored record: altering any record up to it
+// would also need the anchor compromised. Records after it rest on the
+// chain alone.
+func VerifyAnchors(ctx context.Context, records []AuditRecord, receipts []AnchorReceipt, anchor Anchor) (int, error) {
+	if err := VerifyAuditChain(records); err != nil {
+		return 0, err
+	}
+	bySeq := make(map[int]AuditRecord, len(records))
+	for _, r := range records {
+		bySeq[r.Sequence] = r
+	}
+	latest := 0
+	for i := range receipts {
+		r := &receipts[i]
+		cp := r.Checkpoint
+		rec, ok := bySeq[cp.Sequence]
+		if !ok {
+			return 0, fmt.Errorf("receipt %d anchors record %d, which the chain does not have", i, cp.Sequence)
+		}
+		if rec.EngagementID != cp.Engagement || rec.ChainHash != cp.ChainHash {
+			return 0, fmt.Errorf("receipt %d: record %d does not match its anchored checkpoint", i, cp.Sequence)
+		}
+		if err := anchor.Verify(ctx, r); err != nil {
+			return 0, fmt.Errorf("receipt %d: %w", i, err)
+		}
+		latest = max(latest, cp.Sequence)
+	}
+	return latest, nil
+}
+
+// NotaryStatement is what a notary signs: a checkpoint digest and when the
+// notary saw it.
+type NotaryStatement struct {
+	Digest   []byte    `json:"digest"`
+	SignedAt time.Time `json:"signed_at"`
+}
+
+// NotaryProof is a NotaryAnchor receipt's proof.
+type NotaryProof struct {
+	Statement NotaryStatement `json:"statement"`
+	Signature []byte          `json:"signature"`
+}
+
+// NotaryAnchor anchors checkpoints with an internal notary service run
+// apart from the controller. It POSTs a checkpoint digest as
+// {"digest": base64} and expects a NotaryProof back, signed by PublicKey.
+type NotaryAnchor struct {
+	URL string
+	// PublicKey is the notary's signing key.
+	PublicKey ed25519.PublicKey
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Anchor implements Anchor.
+func (n *NotaryAnchor) Anchor(ctx context.Context, c Checkpoint) (*AnchorReceipt, error) {
+	var proof NotaryProof
+	if err := postJSON(ctx, n.Client, n.URL, map[string][]byte{"digest": c.Digest()}, &proof); err != nil {
+		return nil, fmt.Errorf("notary: %w", err)
+	}
+	raw, err := json.Marshal(proof)
+	if err != nil {
+		return nil, err
+	}
+	r := &AnchorReceipt{Checkpoint: c, Anchor: "notary", Proof: raw}
+	if err := n.Verify(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Verify implements Anchor. It needs no network access.
+func (n *NotaryAnchor) Verify(_ context.Context, r *AnchorReceipt) error {
+	if r.Anchor != "notary" {
+		return fmt.Errorf("receipt is from a %q anchor, not a notary", r.Anchor)
+	}
+	if len(n.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("notary public key is not configured")
+	}
+	var proof NotaryProof
+	if err := json.Unmarshal(r.Proof, &proof); err != nil {
+		return fmt.Errorf("decode notary proof: %w", err)
+	}
+	if !bytes.Equal(proof.Statement.Digest, r.Checkpoint.Digest()) {
+		return errors.New("notary statement does not cover the checkpoint")
+	}
+	payload, err := json.Marshal(proof.Statement)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(n.PublicKey, payload, proof.Signature) {
+		return errors.New("notary signature verification failed")
+	}
+	return nil
+}
+
+// SignNotaryStatement signs a statement for digest, as a notary service
+// built on this package would.
+func SignNotaryStatement(digest []byte, at time.Time, priv ed25519.PrivateKey) (*NotaryProof, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	st := NotaryStatement{Digest: digest, SignedAt: at.UTC()}
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+	return &NotaryProof{Statement: st, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// postJSON POSTs in as JSON and decodes a 200 response into out.
+func postJSON(ctx context.Context, client *http.Client, url string, in, out any) error {
+	if url == "" {
+		return errors.New("URL is required")
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
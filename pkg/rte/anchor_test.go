@@ -0,0 +1,108 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func notaryServer(t *testing.T, priv ed25519.PrivateKey) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Digest []byte `json:"digest"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		proof, _ := SignNotaryStatement(req.Digest, time.Now(), priv)
+		json.NewEncoder(w).Encode(proof)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAuditAnchorer(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	anchor := &NotaryAnchor{URL: notaryServer(t, priv).URL, PublicKey: pub}
+	log := NewAuditLog("op-alice")
+	log.Append("eng-1", "task.submit", "lead-bob", "t-1", nil)
+	log.Append("eng-1", "task.complete", "lead-bob", "t-1", map[string]any{"ok": true})
+	log.Append("eng-2", "engagement.open", "lead-bob", "", nil)
+
+	var stored []AnchorReceipt
+	a := &AuditAnchorer{Log: log, Anchor: anchor, OnReceipt: func(r AnchorReceipt) { stored = append(stored, r) }}
+	ctx := context.Background()
+	got, err := a.Checkpoint(ctx)
+	if err != nil || len(got) != 2 || got[0].Checkpoint.Engagement != "eng-1" || got[0].Checkpoint.Sequence != 2 {
+		t.Fatalf("first checkpoint = %+v, %v", got, err)
+	}
+	if got, _ := a.Checkpoint(ctx); len(got) != 0 {
+		t.Errorf("re-anchored unchanged chains: %+v", got)
+	}
+	log.Append("eng-1", "task.submit", "lead-bob", "t-2", nil)
+	if got, _ := a.Checkpoint(ctx); len(got) != 1 || got[0].Checkpoint.Sequence != 3 {
+		t.Errorf("checkpoint after growth = %+v", got)
+	}
+	if len(stored) != 3 || len(a.Receipts("eng-1")) != 2 {
+		t.Fatalf("stored %d receipts, eng-1 has %d", len(stored), len(a.Receipts("eng-1")))
+	}
+
+	records := log.Records("eng-1")
+	latest, err := VerifyAnchors(ctx, records, a.Receipts("eng-1"), anchor)
+	if err != nil || latest != 3 {
+		t.Fatalf("VerifyAnchors = %d, %v", latest, err)
+	}
+
+	// Rewriting history and rehashing the chain passes the chain check but
+	// not the anchors.
+	records[0].Action = "task.cancel"
+	prev := InitialChainHash
+	for i := range records {
+		records[i].PrevChainHash = prev
+		records[i].ChainHash, _ = records[i].hash()
+		prev = records[i].ChainHash
+	}
+	if err := VerifyAuditChain(records); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAnchors(ctx, records, a.Receipts("eng-1"), anchor); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("rewritten chain: %v", err)
+	}
+
+	// Truncating the chain below an anchored record is caught too.
+	if _, err := VerifyAnchors(ctx, log.Records("eng-1")[:1], a.Receipts("eng-1"), anchor); err == nil {
+		t.Error("truncated chain verified")
+	}
+}
+
+func TestNotaryAnchor_Verify(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	anchor := &NotaryAnchor{URL: notaryServer(t, priv).URL, PublicKey: pub}
+	ctx := context.Background()
+	r, err := anchor.Anchor(ctx, Checkpoint{Engagement: "eng-1", Sequence: 1, ChainHash: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Checkpoint.Sequence = 2
+	if err := anchor.Verify(ctx, r); err == nil {
+		t.Error("verified a receipt whose checkpoint changed")
+	}
+	other, _, _ := GenerateKeyPair()
+	impostor := &NotaryAnchor{URL: anchor.URL, PublicKey: other}
+	if _, err := impostor.Anchor(ctx, Checkpoint{Engagement: "eng-1", Sequence: 1}); err == nil {
+		t.Error("accepted a notary signature from an unexpected key")
+	}
+	if _, err := (&NotaryAnchor{URL: "http://127.0.0.1:1", PublicKey: pub}).Anchor(ctx, Checkpoint{}); err == nil {
+		t.Error("anchored with no notary listening")
+	}
+	if err := anchor.Verify(ctx, &AnchorReceipt{Anchor: "rfc6962"}); err == nil {
+		t.Error("verified another anchor's receipt")
+	}
+}
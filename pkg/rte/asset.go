@@ -0,0 +1,87 @@
+package rte
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// AssetKind is the category of an in-scope engagement asset.
+type AssetKind string
+
+const (
+	AssetAccount AssetKind = "account"
+	AssetHost    AssetKind = "host"
+	AssetMailbox AssetKind = "mailbox"
+)
+
+var validAssetKinds = map[AssetKind]struct{}{
+	AssetAccount: {},
+	AssetHost:    {},
+	AssetMailbox: {},
+}
+
+// Asset is one system or account registered for an engagement. Test marks
+// assets provisioned solely for simulation, which handlers that touch
+// credentials require.
+type Asset struct {
+	Kind  AssetKind `json:"kind"`
+	Name  string    `json:"name"`
+	Test  bool      `json:"test"`
+	Owner string    `json:"owner,omitempty"`
+}
+
+type assetKey struct {
+	kind AssetKind
+	name string
+}
+
+// AssetRegistry holds the assets registered for each engagement. Names are
+// matched case-insensitively. It is safe for concurrent use.
+type AssetRegistry struct {
+	mu          sync.RWMutex
+	engagements map[string]map[assetKey]Asset
+}
+
+// NewAssetRegistry returns an empty registry.
+func NewAssetRegistry() *AssetRegistry {
+	return &AssetRegistry{engagements: make(map[string]map[assetKey]Asset)}
+}
+
+// Add registers an asset in an engagement, replacing any asset of the same
+// kind and name.
+func (r *AssetRegistry) Add(engagement string, a Asset) error {
+	if engagement == "" {
+		return errors.New("engagement is required")
+	}
+	if _, ok := validAssetKinds[a.Kind]; !ok {
+		return fmt.Errorf("invalid asset kind: %s", a.Kind)
+	}
+	if strings.TrimSpace(a.Name) == "" {
+		return errors.New("asset name is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	assets, ok := r.engagements[engagement]
+	if !ok {
+		assets = make(map[assetKey]Asset)
+		r.engagements[engagement] = assets
+	}
+	assets[assetKey{a.Kind, strings.ToLower(a.Name)}] = a
+	return nil
+}
+
+// Lookup returns the named asset in an engagement.
+func (r *AssetRegistry) Lookup(engagement string, kind AssetKind, name string) (Asset, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.engagements[engagement][assetKey{kind, strings.ToLower(name)}]
+	return a, ok
+}
+
+// IsTestAccount reports whether name is a registered test account.
+func (r *AssetRegistry) IsTestAccount(engagement, name string) bool {
+	a, ok := r.Lookup(engagement, AssetAccount, name)
+	return ok && a.Test
+}
@@ -0,0 +1,32 @@
+package rte
+
+import "testing"
+
+func TestAssetRegistry(t *testing.T) {
+	r := NewAssetRegistry()
+	if err := r.Add("eng-2026", Asset{Kind: AssetAccount, Name: "svc-rtea-test1", Test: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	_ = r.Add("eng-2026", Asset{Kind: AssetAccount, Name: "jdoe"})
+	_ = r.Add("eng-2026", Asset{Kind: AssetHost, Name: "svc-rtea-test2", Test: true})
+
+	if !r.IsTestAccount("eng-2026", "SVC-RTEA-TEST1") {
+		t.Error("expected case-insensitive test account match")
+	}
+	for _, name := range []string{"jdoe", "svc-rtea-test2", "unknown"} {
+		if r.IsTestAccount("eng-2026", name) {
+			t.Errorf("%s should not be a test account", name)
+		}
+	}
+	if r.IsTestAccount("eng-other", "svc-rtea-test1") {
+		t.Error("assets must not leak across engagements")
+	}
+	for _, a := range []Asset{{Kind: "printer", Name: "x"}, {Kind: AssetHost, Name: " "}} {
+		if err := r.Add("eng-2026", a); err == nil {
+			t.Errorf("expected %+v to be rejected", a)
+		}
+	}
+	if err := r.Add("", Asset{Kind: AssetHost, Name: "x"}); err == nil {
+		t.Error("expected missing engagement to be rejected")
+	}
+}
@@ -0,0 +1,287 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// In-toto and SLSA identifiers for attestations attached to tasks and
+// results.
+const (
+	InTotoPayloadType   = "application/vnd.in-toto+json"
+	InTotoStatementType = "https://in-toto.io/Statement/v1"
+	SLSAProvenanceType  = "https://slsa.dev/provenance/v1"
+)
+
+// Envelope is a DSSE envelope, the signed wrapper in-toto attestations
+// travel in. Payload is the statement's JSON.
+type Envelope struct {
+	PayloadType string              `json:"payloadType"`
+	Payload     []byte              `json:"payload"`
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+// EnvelopeSignature is one signature over an envelope. KeyID is the
+// signing key's Fingerprint, a hint that verification never trusts.
+type EnvelopeSignature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   []byte `json:"sig"`
+}
+
+// Statement is an in-toto v1 statement: a typed claim about its subjects.
+type Statement struct {
+	Type          string          `json:"_type"`
+	Subject       []Subject       `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// Subject names an artifact by digest.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// SLSAProvenance is the SLSA v1 provenance predicate. For a task it says
+// which template the task was built from and which pipeline instantiated
+// it; for a result, which agent build produced it.
+type SLSAProvenance struct {
+	BuildDefinition SLSABuildDefinition `json:"buildDefinition"`
+	RunDetails      SLSARunDetails      `json:"runDetails"`
+}
+
+// SLSABuildDefinition describes what was built and from what.
+type SLSABuildDefinition struct {
+	BuildType            string             `json:"buildType"`
+	ExternalParameters   map[string]any     `json:"externalParameters"`
+	InternalParameters   map[string]any     `json:"internalParameters,omitempty"`
+	ResolvedDependencies []SLSAResourceDesc `json:"resolvedDependencies,omitempty"`
+}
+
+// SLSAResourceDesc identifies an input such as a template file or commit.
+type SLSAResourceDesc struct {
+	URI    string            `json:"uri,omitempty"`
+	Name   string            `json:"name,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// SLSARunDetails describes the build's execution.
+type SLSARunDetails struct {
+	Builder  SLSABuilder   `json:"builder"`
+	Metadata *SLSAMetadata `json:"metadata,omitempty"`
+}
+
+// SLSABuilder names the pipeline or person that did the build.
+type SLSABuilder struct {
+	ID string `json:"id"`
+}
+
+// SLSAMetadata identifies one run of the builder.
+type SLSAMetadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// VerifiedAttestation is an attestation whose signer is enrolled in the
+// engagement.
+type VerifiedAttestation struct {
+	Signer    Identity
+	Statement Statement
+}
+
+// TaskSubject names a signed task by the digest of its canonical JSON, the
+// bytes the operator signed; for a detached task that is its Digest.
+func TaskSubject(st *SignedTask) (Subject, error) {
+	if st == nil {
+		return Subject{}, errors.New("signed task is nil")
+	}
+	digest := st.Digest
+	if !st.Detached() {
+		payload, err := json.Marshal(st.Task)
+		if err != nil {
+			return Subject{}, fmt.Errorf("marshal task: %w", err)
+		}
+		sum := sha256.Sum256(payload)
+		digest = sum[:]
+	}
+	return Subject{Name: "task:" + st.Task.Engagement + "/" + st.Task.ID, Digest: map[string]string{"sha256": hex.EncodeToString(digest)}}, nil
+}
+
+// ResultSubject names a signed result by the digest of the result JSON the
+// agent signed.
+func ResultSubject(sr *SignedResult) (Subject, error) {
+	if sr == nil {
+		return Subject{}, errors.New("signed result is nil")
+	}
+	payload, err := json.Marshal(sr.Result)
+	if err != nil {
+		return Subject{}, fmt.Errorf("marshal result: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return Subject{Name: "result:" + sr.Result.Engagement + "/" + sr.Result.TaskID, Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])}}, nil
+}
+
+// Attest signs an in-toto statement about subject and returns its envelope.
+func Attest(ctx context.Context, s Signer, subject Subject, predicateType string, predicate any) (*Envelope, error) {
+	pub, err := signerKey(s)
+	if err != nil {
+		return nil, err
+	}
+	if predicateType == "" {
+		return nil, errors.New("predicate type is required")
+	}
+	pred, err := json.Marshal(predicate)
+	if err != nil {
+		return nil, fmt.Errorf("marshal predicate: %w", err)
+	}
+	payload, err := json.Marshal(Statement{Type: InTotoStatementType, Subject: []Subject{subject}, PredicateType: predicateType, Predicate: pred})
+	if err != nil {
+		return nil, fmt.Errorf("marshal statement: %w", err)
+	}
+	sig, err := s.Sign(ctx, pae(InTotoPayloadType, payload))
+	if err != nil {
+		return nil, fmt.Errorf("sign attestation: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, errors.New("signer returned an invalid signature size")
+	}
+	return &Envelope{
+		PayloadType: InTotoPayloadType,
+		Payload:     payload,
+		Signatures:  []EnvelopeSignature{{KeyID: Fingerprint(pub), Sig: sig}},
+	}, nil
+}
+
+// AttestTask attaches a signed attestation about the task to st.
+// Attestations are not part of the task signature, so anyone in the
+// pipeline may add one without invalidating it.
+func AttestTask(ctx context.Context, st *SignedTask, s Signer, predicateType string, predicate any) error {
+	subject, err := TaskSubject(st)
+	if err != nil {
+		return err
+	}
+	env, err := Attest(ctx, s, subject, predicateType, predicate)
+	if err != nil {
+		return err
+	}
+	st.Attestations = append(st.Attestations, *env)
+	return nil
+}
+
+// AttestResult attaches a signed attestation about the result to sr.
+func AttestResult(ctx context.Context, sr *SignedResult, s Signer, predicateType string, predicate any) error {
+	subject, err := ResultSubject(sr)
+	if err != nil {
+		return err
+	}
+	env, err := Attest(ctx, s, subject, predicateType, predicate)
+	if err != nil {
+		return err
+	}
+	sr.Attestations = append(sr.Attestations, *env)
+	return nil
+}
+
+// VerifyEnvelope checks that pub signed env and returns its statement.
+func VerifyEnvelope(env *Envelope, pub ed25519.PublicKey) (*Statement, error) {
+	if env == nil {
+		return nil, errors.New("envelope is nil")
+	}
+	if env.PayloadType != InTotoPayloadType {
+		return nil, fmt.Errorf("unsupported payload type %q", env.PayloadType)
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	msg := pae(env.PayloadType, env.Payload)
+	for _, s := range env.Signatures {
+		if len(s.Sig) == ed25519.SignatureSize && ed25519.Verify(pub, msg, s.Sig) {
+			var st Statement
+			if err := json.Unmarshal(env.Payload, &st); err != nil {
+				return nil, fmt.Errorf("decode statement: %w", err)
+			}
+			if st.Type != InTotoStatementType {
+				return nil, fmt.Errorf("unsupported statement type %q", st.Type)
+			}
+			return &st, nil
+		}
+	}
+	return nil, errors.New("attestation signature verification failed")
+}
+
+// VerifyTaskAttestations verifies every attestation on st against the
+// engagement's enrolled identities and checks each is about this task.
+func (r *IdentityRegistry) VerifyTaskAttestations(st *SignedTask) ([]VerifiedAttestation, error) {
+	subject, err := TaskSubject(st)
+	if err != nil {
+		return nil, err
+	}
+	return r.verifyAttestations(st.Task.Engagement, st.Attestations, subject)
+}
+
+// VerifyResultAttestations is VerifyTaskAttestations for a signed result.
+func (r *IdentityRegistry) VerifyResultAttestations(sr *SignedResult) ([]VerifiedAttestation, error) {
+	subject, err := ResultSubject(sr)
+	if err != nil {
+		return nil, err
+	}
+	return r.verifyAttestations(sr.Result.Engagement, sr.Attestations, subject)
+}
+
+func (r *IdentityRegistry) verifyAttestations(engagement string, envs []Envelope, subject Subject) ([]VerifiedAttestation, error) {
+	if engagement == "" {
+		return nil, errors.New("engagement is required")
+	}
+	r.mu.RLock()
+	ids := make([]Identity, 0, len(r.engagements[engagement]))
+	for _, id := range r.engagements[engagement] {
+		ids = append(ids, id)
+	}
+	r.mu.RUnlock()
+
+	out := make([]VerifiedAttestation, 0, len(envs))
+	for i := range envs {
+		v, err := verifyAttestation(&envs[i], ids, subject)
+		if err != nil {
+			return nil, fmt.Errorf("attestation %d: %w", i, err)
+		}
+		out = append(out, *v)
+	}
+	return out, nil
+}
+
+func verifyAttestation(env *Envelope, ids []Identity, subject Subject) (*VerifiedAttestation, error) {
+	for _, id := range ids {
+		st, err := VerifyEnvelope(env, id.PublicKey)
+		if err != nil {
+			continue
+		}
+		for _, s := range st.Subject {
+			if s.Digest["sha256"] != "" && s.Digest["sha256"] == subject.Digest["sha256"] {
+				return &VerifiedAttestation{Signer: id, Statement: *st}, nil
+			}
+		}
+		return nil, fmt.Errorf("signed by %s but not about %s", id.Name, subject.Name)
+	}
+	return nil, errors.New("not signed by an identity enrolled in the engagement")
+}
+
+// pae is DSSE's pre-authentication encoding, the bytes actually signed.
+func pae(payloadType string, payload []byte) []byte {
+	b := []byte("DSSEv1 ")
+	b = strconv.AppendInt(b, int64(len(payloadType)), 10)
+	b = append(b, ' ')
+	b = append(b, payloadType...)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(len(payload)), 10)
+	b = append(b, ' ')
+	return append(b, payload...)
+}
@@ -0,0 +1,128 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func templateProvenance() SLSAProvenance {
+	return SLSAProvenance{
+		BuildDefinition: SLSABuildDefinition{
+			BuildType:          "https://rte-a.example/template/v1",
+			ExternalParameters: map[string]any{"template": "templates/login-spray.json"},
+			ResolvedDependencies: []SLSAResourceDesc{
+				{URI: "git+https://git.example/redteam/playbooks", Digest: map[string]string{"gitCommit": "0f3c2a9"}},
+			},
+		},
+		RunDetails: SLSARunDetails{Builder: SLSABuilder{ID: "https://ci.example/pipelines/engagements"}},
+	}
+}
+
+func TestAttestTask_Verify(t *testing.T) {
+	r, op, lead := enrolled(t)
+	ci := newKeyPair(t)
+	if err := r.Add("eng-2026-q1", Identity{Name: "ci-pipeline", Role: RoleObserver, PublicKey: ci.pub}); err != nil {
+		t.Fatal(err)
+	}
+	st := signAndApprove(t, validTask(time.Now().UTC()), op, lead)
+	signer, _ := NewKeySigner(ci.priv, ci.pub)
+	if err := AttestTask(context.Background(), st, signer, SLSAProvenanceType, templateProvenance()); err != nil {
+		t.Fatal(err)
+	}
+	// Attaching an attestation leaves the task's own signatures intact.
+	if err := r.VerifySignedTask(st); err != nil {
+		t.Fatalf("VerifySignedTask after attesting: %v", err)
+	}
+
+	// Round-trip through JSON as a transport would.
+	data, _ := json.Marshal(st)
+	var got SignedTask
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	verified, err := r.VerifyTaskAttestations(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verified) != 1 || verified[0].Signer.Name != "ci-pipeline" || verified[0].Statement.PredicateType != SLSAProvenanceType {
+		t.Fatalf("verified = %+v", verified)
+	}
+	var prov SLSAProvenance
+	if err := json.Unmarshal(verified[0].Statement.Predicate, &prov); err != nil {
+		t.Fatal(err)
+	}
+	if prov.RunDetails.Builder.ID != "https://ci.example/pipelines/engagements" {
+		t.Fatalf("provenance = %+v", prov)
+	}
+}
+
+func TestVerifyTaskAttestations_Rejects(t *testing.T) {
+	r, op, lead := enrolled(t)
+	signer, _ := NewKeySigner(op.priv, op.pub)
+	st := signAndApprove(t, validTask(time.Now().UTC()), op, lead)
+	other := signAndApprove(t, validTask(time.Now().UTC().Add(time.Second)), op, lead)
+	if err := AttestTask(context.Background(), other, signer, SLSAProvenanceType, templateProvenance()); err != nil {
+		t.Fatal(err)
+	}
+
+	// An attestation about a different task does not transfer.
+	st.Attestations = other.Attestations
+	if _, err := r.VerifyTaskAttestations(st); err == nil || !strings.Contains(err.Error(), "not about") {
+		t.Fatalf("moved attestation: got %v", err)
+	}
+
+	// Unenrolled signers are not trusted.
+	stranger := newKeyPair(t)
+	strangerSigner, _ := NewKeySigner(stranger.priv, stranger.pub)
+	st.Attestations = nil
+	if err := AttestTask(context.Background(), st, strangerSigner, SLSAProvenanceType, templateProvenance()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.VerifyTaskAttestations(st); err == nil || !strings.Contains(err.Error(), "not signed by an identity") {
+		t.Fatalf("stranger: got %v", err)
+	}
+
+	// Tampering with the statement breaks the envelope signature.
+	st.Attestations = nil
+	if err := AttestTask(context.Background(), st, signer, SLSAProvenanceType, templateProvenance()); err != nil {
+		t.Fatal(err)
+	}
+	st.Attestations[0].Payload = []byte(strings.Replace(string(st.Attestations[0].Payload), "engagements", "attacker", 1))
+	if _, err := r.VerifyTaskAttestations(st); err == nil {
+		t.Fatal("expected a tampered statement to fail")
+	}
+}
+
+func TestAttestResult_Verify(t *testing.T) {
+	r, op, _ := enrolled(t)
+	sr, err := SignResult(testResult(), op.priv, op.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, _ := NewKeySigner(op.priv, op.pub)
+	if err := AttestResult(context.Background(), sr, signer, SLSAProvenanceType, templateProvenance()); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyResult(sr); err != nil {
+		t.Fatal(err)
+	}
+	verified, err := r.VerifyResultAttestations(sr)
+	if err != nil || len(verified) != 1 || verified[0].Signer.Name != "op-alice" {
+		t.Fatalf("verified = %+v, %v", verified, err)
+	}
+	sr.Result.Error = "edited"
+	if _, err := r.VerifyResultAttestations(sr); err == nil {
+		t.Fatal("expected an edited result to fail")
+	}
+}
+
+func TestPAE(t *testing.T) {
+	// The DSSE specification's worked example.
+	got := string(pae("http://example.com/HelloWorld", []byte("hello world")))
+	if want := "DSSEv1 29 http://example.com/HelloWorld 11 hello world"; got != want {
+		t.Fatalf("pae = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,262 @@
+package rte
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+// AuditSchemaVersion is the schema_version AuditLog writes.
+const AuditSchemaVersion = "1.0"
+
+// InitialChainHash is the prev_chain_hash of the first audit record.
+const InitialChainHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// AuditRecord is one entry of an engagement's audit trail, as written by
+// AuditLog or by the Python rte_a_audit logger.
+type AuditRecord struct {
+	SchemaVersion string  `json:"schema_version"`
+	EngagementID  string  `json:"engagement_id"`
+	OperatorID    string  `json:"operator_id"`
+	Sequence      int     `json:"sequence"`
+	Timestamp     string  `json:"timestamp"`
+	Action        string  `json:"action"`
+	TaskID        *string `json:"task_id"`
+	Authorization string  `json:"authorization"`
+	ResultHash    string  `json:"result_hash"`
+	PrevChainHash string  `json:"prev_chain_hash"`
+	ChainHash     string  `json:"chain_hash"`
+	// Redacted marks a record whose contents Redact hid. It is not part
+	// of the chain hash.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// Redact returns r with its contents hidden, keeping only what places it
+// on its chain: the engagement, sequence, and chain hashes.
+func (r AuditRecord) Redact() AuditRecord {
+	return AuditRecord{
+		SchemaVersion: r.SchemaVersion,
+		EngagementID:  r.EngagementID,
+		Sequence:      r.Sequence,
+		PrevChainHash: r.PrevChainHash,
+		ChainHash:     r.ChainHash,
+		Redacted:      true,
+	}
+}
+
+// VerifyAuditChain checks the hash chain across records in order, exactly
+// as AuditLogger.verify_chain does, and reports the first broken link.
+func VerifyAuditChain(records []AuditRecord) error {
+	return verifyAuditChain(records, false)
+}
+
+// VerifyRedactedAuditChain is VerifyAuditChain for a chain in which some
+// records were redacted: their links to their neighbours are checked, but
+// not their own hashes, which cover the hidden contents.
+func VerifyRedactedAuditChain(records []AuditRecord) error {
+	return verifyAuditChain(records, true)
+}
+
+func verifyAuditChain(records []AuditRecord, redacted bool) error {
+	prev := InitialChainHash
+	for i, r := range records {
+		if r.PrevChainHash != prev {
+			return fmt.Errorf("audit record %d: prev_chain_hash does not match the preceding record", i)
+		}
+		if r.Redacted {
+			if !redacted {
+				return fmt.Errorf("audit record %d is redacted", i)
+			}
+			prev = r.ChainHash
+			continue
+		}
+		sum, err := r.hash()
+		if err != nil {
+			return fmt.Errorf("audit record %d: %w", i, err)
+		}
+		if r.ChainHash != sum {
+			return fmt.Errorf("audit record %d: chain_hash mismatch", i)
+		}
+		prev = r.ChainHash
+	}
+	return nil
+}
+
+// hash is the SHA-256 of the record without chain_hash, serialized the way
+// Python's json.dumps(sort_keys=True, separators=(",", ":")) does.
+func (r AuditRecord) hash() (string, error) {
+	fields := map[string]any{
+		"schema_version":  r.SchemaVersion,
+		"engagement_id":   r.EngagementID,
+		"operator_id":     r.OperatorID,
+		"sequence":        r.Sequence,
+		"timestamp":       r.Timestamp,
+		"action":          r.Action,
+		"task_id":         r.TaskID,
+		"authorization":   r.Authorization,
+		"result_hash":     r.ResultHash,
+		"prev_chain_hash": r.PrevChainHash,
+	}
+	data, err := pythonJSON(fields)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// pythonJSON serializes v the way Python's json.dumps(sort_keys=True,
+// separators=(",", ":")) does. v is round-tripped through generic maps
+// first so struct fields sort by key too.
+func pythonJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return asciiEscape(strings.TrimSuffix(buf.String(), "\n")), nil
+}
+
+// asciiEscape mirrors json.dumps' default ensure_ascii: everything outside
+// printable ASCII becomes a \uXXXX escape. Go's encoder has already escaped
+// quotes, backslashes, and control characters.
+func asciiEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c < 0x7f:
+			b.WriteRune(c)
+		case c > 0xffff:
+			hi, lo := utf16.EncodeRune(c)
+			fmt.Fprintf(&b, `\u%04x\u%04x`, hi, lo)
+		default:
+			fmt.Fprintf(&b, `\u%04x`, c)
+		}
+	}
+	return b.String()
+}
+
+// AuditLog appends hash-chained audit records, one chain per engagement,
+// in the format the Python rte_a_audit logger writes, so
+// VerifyAuditChain and AuditLogger.verify_chain both accept its output. It
+// is safe for concurrent use.
+type AuditLog struct {
+	// Operator is recorded as each record's operator_id.
+	Operator string
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// OnAppend, if set, is called with each record once it is on the
+	// chain, as for forwarding to a SIEM. It runs with the log locked, so
+	// records arrive in chain order; it must be quick and must not use the
+	// log.
+	OnAppend func(AuditRecord)
+
+	mu     sync.Mutex
+	chains map[string][]AuditRecord
+}
+
+// NewAuditLog returns an empty log writing as operator.
+func NewAuditLog(operator string) *AuditLog {
+	return &AuditLog{Operator: operator, chains: make(map[string][]AuditRecord)}
+}
+
+// Append adds a record to the engagement's chain. result is hashed, not
+// stored; taskID may be empty for events about no particular task.
+func (l *AuditLog) Append(engagement, action, authorization, taskID string, result any) (AuditRecord, error) {
+	resultJSON, err := pythonJSON(map[string]any{"result": result})
+	if err != nil {
+		return AuditRecord{}, fmt.Errorf("hash audit result: %w", err)
+	}
+	sum := sha256.Sum256([]byte(resultJSON))
+	now := time.Now
+	if l.Now != nil {
+		now = l.Now
+	}
+	r := AuditRecord{
+		SchemaVersion: AuditSchemaVersion,
+		EngagementID:  engagement,
+		OperatorID:    l.Operator,
+		Timestamp:     now().UTC().Format("2006-01-02T15:04:05Z"),
+		Action:        action,
+		Authorization: authorization,
+		ResultHash:    hex.EncodeToString(sum[:])[:16],
+	}
+	if taskID != "" {
+		r.TaskID = &taskID
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.chains == nil {
+		l.chains = make(map[string][]AuditRecord)
+	}
+	chain := l.chains[engagement]
+	r.Sequence, r.PrevChainHash = 1, InitialChainHash
+	if n := len(chain); n > 0 {
+		r.Sequence, r.PrevChainHash = chain[n-1].Sequence+1, chain[n-1].ChainHash
+	}
+	if r.ChainHash, err = r.hash(); err != nil {
+		return AuditRecord{}, err
+	}
+	l.chains[engagement] = append(chain, r)
+	if l.OnAppend != nil {
+		l.OnAppend(r)
+	}
+	return r, nil
+}
+
+// Records returns the engagement's chain, oldest first.
+func (l *AuditLog) Records(engagement string) []AuditRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]AuditRecord(nil), l.chains[engagement]...)
+}
+
+// Snapshot returns every engagement's chain as of one instant.
+func (l *AuditLog) Snapshot() map[string][]AuditRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string][]AuditRecord, len(l.chains))
+	for eng, chain := range l.chains {
+		out[eng] = append([]AuditRecord(nil), chain...)
+	}
+	return out
+}
+
+// Load installs a previously written chain for an engagement that has none
+// yet, as when restoring from a backup; later appends continue it.
+func (l *AuditLog) Load(engagement string, records []AuditRecord) error {
+	for i, r := range records {
+		if r.EngagementID != engagement {
+			return fmt.Errorf("audit record %d belongs to engagement %q", i, r.EngagementID)
+		}
+	}
+	if err := VerifyAuditChain(records); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.chains == nil {
+		l.chains = make(map[string][]AuditRecord)
+	}
+	if len(l.chains[engagement]) > 0 {
+		return fmt.Errorf("engagement %s already has an audit chain", engagement)
+	}
+	l.chains[engagement] = append([]AuditRecord(nil), records...)
+	return nil
+}
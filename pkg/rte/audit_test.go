@@ -0,0 +1,54 @@
+package rte
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAuditLog_MatchesPython checks AuditLog against records produced by
+// rte_a_audit.AuditLogger for the same events.
+func TestAuditLog_MatchesPython(t *testing.T) {
+	l := NewAuditLog("janitor")
+	l.Now = func() time.Time { return time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC) }
+	result := struct {
+		Kind   string `json:"kind"`
+		TaskID string `json:"task_id"`
+		Detail string `json:"detail"`
+		N      int    `json:"n"`
+		OK     bool   `json:"ok"`
+		None   *int   `json:"none"`
+		List   []any  `json:"list"`
+	}{"requeue", "t-1", "café <x> & y", 3, true, nil, []any{1, "a"}}
+	a, err := l.Append("eng-7", "janitor.requeue", "janitor", "t-1", result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := l.Append("eng-7", "janitor.sweep", "janitor", "", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ResultHash != "5b4569ac906a7521" || a.ChainHash != "25153468823db94e16a89988134ebd4550043f8948a889ead690f0eeaffbe417" {
+		t.Errorf("first record = %s / %s", a.ResultHash, a.ChainHash)
+	}
+	if b.Sequence != 2 || b.TaskID != nil || b.ResultHash != "270ab41ffeb30eca" ||
+		b.ChainHash != "4bf7635095bd5eb8812f55e751b452727f46ec795f860a5880420b97f6b9c7e3" {
+		t.Errorf("second record = %+v", b)
+	}
+	if err := VerifyAuditChain(l.Records("eng-7")); err != nil {
+		t.Fatal(err)
+	}
+	redacted := []AuditRecord{a.Redact(), b}
+	if err := VerifyAuditChain(redacted); err == nil {
+		t.Error("VerifyAuditChain accepted a redacted record")
+	}
+	if err := VerifyRedactedAuditChain(redacted); err != nil {
+		t.Fatalf("VerifyRedactedAuditChain: %v", err)
+	}
+	redacted[0].ChainHash = b.ChainHash
+	if err := VerifyRedactedAuditChain(redacted); err == nil {
+		t.Error("expected a broken link across a redacted record to fail")
+	}
+	if c, _ := l.Append("eng-8", "janitor.sweep", "janitor", "", nil); c.Sequence != 1 || c.PrevChainHash != InitialChainHash {
+		t.Errorf("engagements must have separate chains: %+v", c)
+	}
+}
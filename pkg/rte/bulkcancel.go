@@ -0,0 +1,210 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// BatchStore is a TaskStore that can change many records in one atomic
+// step, as bulk operations need.
+type BatchStore interface {
+	TaskStore
+	// UpdateWhere applies fn to every record of engagement, or of every
+	// engagement when it is empty, that match selects. It stores all the
+	// results, or none if fn fails for any record, and returns the stored
+	// records sorted by engagement and ID.
+	UpdateWhere(ctx context.Context, engagement string, match func(TaskRecord) bool, fn func(*TaskRecord) error) ([]TaskRecord, error)
+}
+
+// TaskFilter selects an engagement's task records for a bulk operation.
+// Empty fields other than Engagement match every record.
+type TaskFilter struct {
+	Engagement string     `json:"engagement"`
+	Types      []TaskType `json:"types,omitempty"`
+	// States defaults to the states a task can be cancelled from: pending,
+	// executing, and paused.
+	States   []TaskState `json:"states,omitempty"`
+	Operator string      `json:"operator,omitempty"`
+	// IDs, if set, limits the filter to these tasks.
+	IDs []string `json:"ids,omitempty"`
+}
+
+// Validate checks that the filter names an engagement and only known task
+// types and states.
+func (f TaskFilter) Validate() error {
+	var errs []error
+	if f.Engagement == "" {
+		errs = append(errs, ErrMissingEngagement)
+	}
+	for _, t := range f.Types {
+		if _, ok := allowedTaskTypes[t]; !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUnsupportedType, t))
+		}
+	}
+	for _, s := range f.States {
+		if _, ok := validTaskStates[s]; !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidState, s))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Match reports whether the filter selects rec.
+func (f TaskFilter) Match(rec TaskRecord) bool {
+	t := rec.Task.Task
+	states := f.States
+	if len(states) == 0 {
+		states = []TaskState{StatePending, StateExecuting, StatePaused}
+	}
+	return t.Engagement == f.Engagement &&
+		slices.Contains(states, rec.State) &&
+		(len(f.Types) == 0 || slices.Contains(f.Types, t.Type)) &&
+		(f.Operator == "" || t.Operator == f.Operator) &&
+		(len(f.IDs) == 0 || slices.Contains(f.IDs, t.ID))
+}
+
+// Cancelled is a task CancelWhere cancelled and the state it was in.
+type Cancelled struct {
+	Record TaskRecord `json:"record"`
+	From   TaskState  `json:"from"`
+}
+
+// Message returns the cancel to send the agent running the task. Only
+// tasks cancelled while executing or paused need one; queued tasks are
+// dropped by Queue.Remove.
+func (c Cancelled) Message(requestedBy string) Message {
+	t := c.Record.Task.Task
+	return CancelMessage(TaskCancel{Engagement: t.Engagement, TaskID: t.ID, Token: t.CancelToken, RequestedBy: requestedBy})
+}
+
+// CancelWhere cancels every task f selects in one atomic store update,
+// so an abort cannot stop halfway, and then appends one "task.cancel"
+// audit record per task to audit, if set. A selected task in a state it
+// cannot be cancelled from fails the whole update. Cancelled records get
+// a cancelled result naming requestedBy.
+func CancelWhere(ctx context.Context, s BatchStore, audit *AuditLog, f TaskFilter, requestedBy string, now time.Time) ([]Cancelled, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	if requestedBy == "" {
+		return nil, errors.New("requested_by is required")
+	}
+	from := make(map[string]TaskState)
+	recs, err := s.UpdateWhere(ctx, f.Engagement, f.Match, func(rec *TaskRecord) error {
+		if err := ValidTransition(rec.State, StateCancelled); err != nil {
+			return fmt.Errorf("task %s: %w", rec.ID(), err)
+		}
+		from[rec.ID()] = rec.State
+		res := &TaskResult{
+			TaskID:     rec.ID(),
+			Engagement: rec.Engagement(),
+			Type:       rec.Task.Task.Type,
+			Operator:   rec.Task.Task.Operator,
+			State:      StateCancelled,
+			FinishedAt: now,
+			Error:      TaskCancel{RequestedBy: requestedBy}.cause().Error(),
+		}
+		if rec.Result != nil {
+			res.StartedAt = rec.Result.StartedAt
+		}
+		rec.State, rec.Result, rec.Lease, rec.UpdatedAt = StateCancelled, res, nil, now
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cancel %s tasks: %w", f.Engagement, err)
+	}
+	out := make([]Cancelled, len(recs))
+	var errs []error
+	for i, rec := range recs {
+		out[i] = Cancelled{Record: rec, From: from[rec.ID()]}
+		if audit == nil {
+			continue
+		}
+		detail := map[string]any{"from": out[i].From, "filter": f}
+		if _, err := audit.Append(rec.Engagement(), "task.cancel", requestedBy, rec.ID(), detail); err != nil {
+			errs = append(errs, fmt.Errorf("audit cancel of %s: %w", rec.ID(), err))
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// BulkCancelRequest is the body of a bulk cancel.
+type BulkCancelRequest struct {
+	Filter      TaskFilter `json:"filter"`
+	RequestedBy string     `json:"requested_by"`
+}
+
+// BulkCancelResponse lists the tasks a bulk cancel stopped, by ID.
+type BulkCancelResponse struct {
+	Cancelled []string `json:"cancelled"`
+}
+
+// BulkCancelHandler serves a POSTed BulkCancelRequest, as rtectl cancel
+// sends to /v1/engagements/{engagement}/tasks/cancel: it runs CancelWhere
+// and answers with a BulkCancelResponse, or 409 if the update failed and
+// nothing was cancelled. Tasks cancelled in the queue are removed from it;
+// agents running the others are sent their cancels. Authenticate callers
+// before it: RequestedBy is recorded as given.
+type BulkCancelHandler struct {
+	Store BatchStore
+	Audit *AuditLog
+	// Queue, if set, holds the engagement's queued work.
+	Queue *Queue
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// ServeHTTP implements http.Handler.
+func (h *BulkCancelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req BulkCancelRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "malformed bulk cancel request", http.StatusBadRequest)
+		return
+	}
+	if err := req.Filter.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RequestedBy == "" {
+		http.Error(w, "requested_by is required", http.StatusBadRequest)
+		return
+	}
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+	cancelled, err := CancelWhere(r.Context(), h.Store, h.Audit, req.Filter, req.RequestedBy, now().UTC())
+	if cancelled == nil && err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	resp := BulkCancelResponse{Cancelled: make([]string, len(cancelled))}
+	ids := make(map[string]bool, len(cancelled))
+	for i, c := range cancelled {
+		resp.Cancelled[i] = c.Record.ID()
+		ids[c.Record.ID()] = true
+	}
+	if h.Queue != nil {
+		h.Queue.Remove(func(m Message) bool {
+			return m.Kind == MessageTask && m.Task.Task.Engagement == req.Filter.Engagement && ids[m.Task.Task.ID]
+		})
+		for _, c := range cancelled {
+			if c.From != StatePending {
+				_ = h.Queue.Push(c.Message(req.RequestedBy))
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
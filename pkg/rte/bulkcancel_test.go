@@ -0,0 +1,153 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bulkStore holds beacons b-1 (pending), b-2 (executing), and b-3
+// (completed), and login l-1 (pending), all in eng-2026-q1.
+func bulkStore(t *testing.T, s BatchStore) {
+	t.Helper()
+	now := time.Now().UTC()
+	for _, r := range []struct {
+		id    string
+		typ   TaskType
+		state TaskState
+	}{{"b-1", TaskSimulateBeacon, StatePending}, {"b-2", TaskSimulateBeacon, StateExecuting}, {"b-3", TaskSimulateBeacon, StateCompleted}, {"l-1", TaskSimulateLogin, StatePending}} {
+		rec := storedTask(t, r.id, now, r.state)
+		rec.Task.Task.Type = r.typ
+		rec.Task.Task.CancelToken = "tok-" + r.id
+		if r.state == StateExecuting {
+			rec.Lease = &Lease{Agent: "agent-1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}
+		}
+		if err := s.Put(context.Background(), rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCancelWhere(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	bulkStore(t, s)
+	audit := NewAuditLog("controller")
+	now := time.Now().UTC()
+	f := TaskFilter{Engagement: "eng-2026-q1", Types: []TaskType{TaskSimulateBeacon}}
+
+	got, err := CancelWhere(ctx, s, audit, f, "lead-bob", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Record.ID() != "b-1" || got[0].From != StatePending || got[1].From != StateExecuting {
+		t.Fatalf("cancelled %+v", got)
+	}
+	for _, id := range []string{"b-1", "b-2"} {
+		rec, _ := s.Get(ctx, "eng-2026-q1", id)
+		if rec.State != StateCancelled || rec.Lease != nil || rec.Result == nil || rec.Result.Error != "task cancelled by lead-bob" {
+			t.Errorf("%s after cancel: %+v", id, rec)
+		}
+	}
+	for _, id := range []string{"b-3", "l-1"} {
+		if rec, _ := s.Get(ctx, "eng-2026-q1", id); rec.State == StateCancelled {
+			t.Errorf("%s was cancelled", id)
+		}
+	}
+	records := audit.Records("eng-2026-q1")
+	if len(records) != 2 || records[0].Action != "task.cancel" || *records[0].TaskID != "b-1" || records[1].Authorization != "lead-bob" {
+		t.Errorf("audit = %+v", records)
+	}
+	if m := got[1].Message("lead-bob"); m.Kind != MessageCancel || m.Cancel.Token != "tok-b-2" {
+		t.Errorf("cancel message = %+v", m)
+	}
+
+	// Selecting a task that cannot be cancelled cancels nothing.
+	f = TaskFilter{Engagement: "eng-2026-q1", States: []TaskState{StatePending, StateCompleted}}
+	if _, err := CancelWhere(ctx, s, audit, f, "lead-bob", now); err == nil {
+		t.Fatal("cancelled a completed task")
+	}
+	if rec, _ := s.Get(ctx, "eng-2026-q1", "l-1"); rec.State != StatePending {
+		t.Errorf("l-1 = %s after a failed bulk cancel", rec.State)
+	}
+
+	for _, bad := range []TaskFilter{{}, {Engagement: "e", Types: []TaskType{"nuke"}}, {Engagement: "e", States: []TaskState{"gone"}}} {
+		if _, err := CancelWhere(ctx, s, audit, bad, "lead-bob", now); err == nil {
+			t.Errorf("CancelWhere accepted filter %+v", bad)
+		}
+	}
+	if _, err := CancelWhere(ctx, s, audit, TaskFilter{Engagement: "eng-2026-q1"}, "", now); err == nil {
+		t.Error("CancelWhere without requestedBy")
+	}
+}
+
+func TestFileStore_UpdateWhere(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bulkStore(t, s)
+	if _, err := CancelWhere(context.Background(), s, nil, TaskFilter{Engagement: "eng-2026-q1", States: []TaskState{StatePending}}, "lead-bob", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, _ := reopened.List(context.Background(), "")
+	var states []string
+	for _, r := range recs {
+		states = append(states, r.ID()+"="+string(r.State))
+	}
+	if got := strings.Join(states, ","); got != "b-1=cancelled,b-2=executing,b-3=completed,l-1=cancelled" {
+		t.Errorf("persisted %s", got)
+	}
+}
+
+func TestBulkCancelHandler(t *testing.T) {
+	s := NewMemoryStore()
+	bulkStore(t, s)
+	q := NewQueue()
+	pending, _ := s.Get(context.Background(), "eng-2026-q1", "b-1")
+	login, _ := s.Get(context.Background(), "eng-2026-q1", "l-1")
+	_ = q.Push(TaskMessage(pending.Task))
+	_ = q.Push(TaskMessage(login.Task))
+	h := &BulkCancelHandler{Store: s, Audit: NewAuditLog("controller"), Queue: q}
+
+	post := func(req BulkCancelRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/engagements/eng-2026-q1/tasks/cancel", bytes.NewReader(body)))
+		return rec
+	}
+	rec := post(BulkCancelRequest{Filter: TaskFilter{Engagement: "eng-2026-q1", Types: []TaskType{TaskSimulateBeacon}}, RequestedBy: "lead-bob"})
+	var resp BulkCancelResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil || strings.Join(resp.Cancelled, ",") != "b-1,b-2" {
+		t.Fatalf("bulk cancel: %d %s", rec.Code, rec.Body)
+	}
+	var queued []string
+	for _, m := range q.Snapshot() {
+		if m.Kind == MessageTask {
+			queued = append(queued, m.Task.Task.ID)
+		} else {
+			queued = append(queued, "cancel:"+m.Cancel.TaskID)
+		}
+	}
+	if strings.Join(queued, ",") != "l-1,cancel:b-2" && strings.Join(queued, ",") != "cancel:b-2,l-1" {
+		t.Errorf("queue after bulk cancel = %v", queued)
+	}
+
+	if rec := post(BulkCancelRequest{Filter: TaskFilter{Engagement: "eng-2026-q1"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("no requested_by: %d", rec.Code)
+	}
+	if rec := post(BulkCancelRequest{Filter: TaskFilter{Engagement: "eng-2026-q1", States: []TaskState{StateCompleted}}, RequestedBy: "lead-bob"}); rec.Code != http.StatusConflict {
+		t.Errorf("uncancellable selection: %d", rec.Code)
+	}
+}
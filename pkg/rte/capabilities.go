@@ -0,0 +1,224 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Signature algorithms a Capabilities may list. SignatureEd25519 signs
+// the task's canonical JSON; SignatureEd25519Detached signs its SHA-256
+// digest, as SignTaskDetached does.
+const (
+	SignatureEd25519         = "ed25519"
+	SignatureEd25519Detached = "ed25519-sha256-detached"
+)
+
+// Optional SignedTask fields a Capabilities may list as features. A peer
+// that does not list one may reject a task that carries it.
+const (
+	FeatureApproval     = "approval"
+	FeatureCertificates = "certificates"
+	FeatureTimestamp    = "timestamp"
+	FeatureTrace        = "trace"
+	FeatureLegacy       = "legacy"
+)
+
+// PolicyBundle names a policy bundle a controller enforces, and its
+// revision, as an OPA bundle's manifest revision.
+type PolicyBundle struct {
+	Name     string `json:"name"`
+	Revision string `json:"revision"`
+}
+
+// Capabilities is what a controller or agent build supports, so peers can
+// negotiate before sending it a task rather than have it fail on an
+// unknown field or version.
+type Capabilities struct {
+	// TaskSchemaVersion is the schema version the build signs and
+	// executes; UpgradableSchemaVersions are older ones it migrates.
+	TaskSchemaVersion        int            `json:"task_schema_version"`
+	UpgradableSchemaVersions []int          `json:"upgradable_schema_versions"`
+	AuditSchemaVersion       string         `json:"audit_schema_version"`
+	SignatureAlgorithms      []string       `json:"signature_algorithms"`
+	TaskTypes                []TaskType     `json:"task_types"`
+	Features                 []string       `json:"features"`
+	PolicyBundles            []PolicyBundle `json:"policy_bundles"`
+}
+
+// LocalCapabilities returns this build's capabilities, with the policy
+// bundles the caller enforces.
+func LocalCapabilities(bundles ...PolicyBundle) Capabilities {
+	c := Capabilities{
+		TaskSchemaVersion:   TaskSchemaVersion,
+		AuditSchemaVersion:  AuditSchemaVersion,
+		SignatureAlgorithms: []string{SignatureEd25519, SignatureEd25519Detached},
+		Features:            []string{FeatureApproval, FeatureCertificates, FeatureLegacy, FeatureTimestamp, FeatureTrace},
+		PolicyBundles:       append([]PolicyBundle{}, bundles...),
+	}
+	migrationsMu.RLock()
+	c.UpgradableSchemaVersions = migrations.upgradable(TaskSchemaVersion)
+	migrationsMu.RUnlock()
+	for t := range allowedTaskTypes {
+		c.TaskTypes = append(c.TaskTypes, t)
+	}
+	slices.Sort(c.TaskTypes)
+	sort.Slice(c.PolicyBundles, func(i, j int) bool { return c.PolicyBundles[i].Name < c.PolicyBundles[j].Name })
+	return c
+}
+
+// upgradable lists the versions below current with migrations all the way
+// to it.
+func (ms migrationSet) upgradable(current int) []int {
+	out := []int{}
+	for v := current - 1; v >= 1; v-- {
+		if _, ok := ms[v]; !ok {
+			break
+		}
+		out = append(out, v)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Accepts reports why a peer with capabilities c would refuse st, or nil
+// if it would not: an unknown schema version, task type, or signature
+// algorithm, or an optional field it does not list.
+func (c Capabilities) Accepts(st *SignedTask) error {
+	var errs []error
+	if v := schemaVersion(st.Task.SchemaVersion); v != c.TaskSchemaVersion && !slices.Contains(c.UpgradableSchemaVersions, v) {
+		errs = append(errs, fmt.Errorf("%w: peer executes %d", ErrSchemaVersion, c.TaskSchemaVersion))
+	}
+	if !slices.Contains(c.TaskTypes, st.Task.Type) {
+		errs = append(errs, fmt.Errorf("peer does not support task type %q", st.Task.Type))
+	}
+	alg := SignatureEd25519
+	if st.Detached() {
+		alg = SignatureEd25519Detached
+	}
+	if !slices.Contains(c.SignatureAlgorithms, alg) {
+		errs = append(errs, fmt.Errorf("peer does not support %s signatures", alg))
+	}
+	for feature, used := range map[string]bool{
+		FeatureApproval:     st.Approval != nil,
+		FeatureCertificates: len(st.Certificates) > 0,
+		FeatureTimestamp:    len(st.Timestamp) > 0,
+		FeatureTrace:        len(st.Trace) > 0,
+		FeatureLegacy:       st.Legacy != nil,
+	} {
+		if used && !slices.Contains(c.Features, feature) {
+			errs = append(errs, fmt.Errorf("peer does not support the %s field", feature))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// Agreement is what two peers have in common.
+type Agreement struct {
+	// TaskSchemaVersion is the version both execute.
+	TaskSchemaVersion   int        `json:"task_schema_version"`
+	SignatureAlgorithms []string   `json:"signature_algorithms"`
+	TaskTypes           []TaskType `json:"task_types"`
+	Features            []string   `json:"features"`
+}
+
+// Negotiate finds what local and remote have in common. It fails if they
+// execute different schema versions, since neither can then run a task
+// the other signs, or share no signature algorithm.
+func Negotiate(local, remote Capabilities) (Agreement, error) {
+	if local.TaskSchemaVersion != remote.TaskSchemaVersion {
+		newer, older := local, remote
+		if older.TaskSchemaVersion > newer.TaskSchemaVersion {
+			newer, older = older, newer
+		}
+		hint := "the older peer must be upgraded"
+		if slices.Contains(newer.UpgradableSchemaVersions, older.TaskSchemaVersion) {
+			hint = "the newer peer can upgrade the older peer's tasks, but not the reverse"
+		}
+		return Agreement{}, fmt.Errorf("%w: local executes %d, remote %d; %s", ErrSchemaVersion,
+			local.TaskSchemaVersion, remote.TaskSchemaVersion, hint)
+	}
+	a := Agreement{
+		TaskSchemaVersion:   local.TaskSchemaVersion,
+		SignatureAlgorithms: intersect(local.SignatureAlgorithms, remote.SignatureAlgorithms),
+		TaskTypes:           intersect(local.TaskTypes, remote.TaskTypes),
+		Features:            intersect(local.Features, remote.Features),
+	}
+	if len(a.SignatureAlgorithms) == 0 {
+		return Agreement{}, fmt.Errorf("no common signature algorithm: local %s, remote %s",
+			strings.Join(local.SignatureAlgorithms, ", "), strings.Join(remote.SignatureAlgorithms, ", "))
+	}
+	return a, nil
+}
+
+// intersect returns the elements of a also in b, in a's order.
+func intersect[T comparable](a, b []T) []T {
+	out := []T{}
+	for _, x := range a {
+		if slices.Contains(b, x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// CapabilitiesHandler serves a build's Capabilities as JSON to GET
+// requests, as mounted at /capabilities. It needs no authentication:
+// capabilities are the same for every caller.
+type CapabilitiesHandler struct {
+	// Capabilities is called per request, so policy bundle revisions stay
+	// current; it defaults to LocalCapabilities().
+	Capabilities func() Capabilities
+}
+
+// ServeHTTP implements http.Handler.
+func (h *CapabilitiesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c := LocalCapabilities()
+	if h.Capabilities != nil {
+		c = h.Capabilities()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// FetchCapabilities gets a peer's capabilities from its /capabilities
+// endpoint under baseURL. A nil client means http.DefaultClient.
+func FetchCapabilities(ctx context.Context, client *http.Client, baseURL string) (Capabilities, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/capabilities", nil)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("fetch capabilities: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("fetch capabilities: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Capabilities{}, fmt.Errorf("fetch capabilities: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var c Capabilities
+	if err := json.Unmarshal(body, &c); err != nil {
+		return Capabilities{}, fmt.Errorf("decode capabilities: %w", err)
+	}
+	return c, nil
+}
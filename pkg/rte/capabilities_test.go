@@ -0,0 +1,125 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalCapabilities(t *testing.T) {
+	c := LocalCapabilities(PolicyBundle{Name: "scope", Revision: "r42"}, PolicyBundle{Name: "approvals", Revision: "7"})
+	if c.TaskSchemaVersion != TaskSchemaVersion || c.AuditSchemaVersion != AuditSchemaVersion {
+		t.Errorf("versions = %d, %s", c.TaskSchemaVersion, c.AuditSchemaVersion)
+	}
+	if len(c.TaskTypes) != len(allowedTaskTypes) || !slices.IsSorted(c.TaskTypes) {
+		t.Errorf("task types = %v", c.TaskTypes)
+	}
+	if c.PolicyBundles[0].Name != "approvals" || c.PolicyBundles[1].Revision != "r42" {
+		t.Errorf("policy bundles = %+v", c.PolicyBundles)
+	}
+}
+
+func TestMigrationSet_Upgradable(t *testing.T) {
+	noop := func(map[string]any) error { return nil }
+	if got := (migrationSet{1: noop, 3: noop}).upgradable(4); !slices.Equal(got, []int{3}) {
+		t.Errorf("upgradable = %v, want only the version with an unbroken chain", got)
+	}
+	if got := (migrationSet{}).upgradable(1); len(got) != 0 {
+		t.Errorf("upgradable = %v", got)
+	}
+}
+
+func TestCapabilities_Accepts(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	st, err := SignTask(validTask(time.Now().UTC()), priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := LocalCapabilities()
+	if err := c.Accepts(st); err != nil {
+		t.Fatal(err)
+	}
+
+	old := c
+	old.TaskTypes = []TaskType{TaskInventory}
+	old.Features = nil
+	st.Trace = map[string]string{"traceparent": "00-1-2-01"}
+	err = old.Accepts(st)
+	if err == nil || !strings.Contains(err.Error(), `task type "simulate_login"`) || !strings.Contains(err.Error(), "trace field") {
+		t.Errorf("accepts = %v", err)
+	}
+
+	newer := c
+	newer.TaskSchemaVersion = TaskSchemaVersion + 1
+	if err := newer.Accepts(st); !errors.Is(err, ErrSchemaVersion) {
+		t.Errorf("accepts on a newer peer = %v", err)
+	}
+	newer.UpgradableSchemaVersions = []int{TaskSchemaVersion}
+	if err := newer.Accepts(st); err != nil {
+		t.Errorf("a peer that upgrades the version refused it: %v", err)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	local := LocalCapabilities()
+	remote := local
+	remote.SignatureAlgorithms = []string{"rsa-pss", SignatureEd25519}
+	remote.TaskTypes = []TaskType{TaskSimulateExfil, TaskInventory, "simulate_ransomware"}
+	a, err := Negotiate(local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(a.SignatureAlgorithms, []string{SignatureEd25519}) ||
+		!slices.Equal(a.TaskTypes, []TaskType{TaskInventory, TaskSimulateExfil}) {
+		t.Errorf("agreement = %+v", a)
+	}
+
+	remote.SignatureAlgorithms = []string{"rsa-pss"}
+	if _, err := Negotiate(local, remote); err == nil || !strings.Contains(err.Error(), "no common signature") {
+		t.Errorf("negotiate without a common algorithm = %v", err)
+	}
+
+	remote = local
+	remote.TaskSchemaVersion = TaskSchemaVersion + 1
+	remote.UpgradableSchemaVersions = []int{TaskSchemaVersion}
+	if _, err := Negotiate(local, remote); !errors.Is(err, ErrSchemaVersion) || !strings.Contains(err.Error(), "newer peer can upgrade") {
+		t.Errorf("negotiate across versions = %v", err)
+	}
+}
+
+func TestCapabilitiesHandler(t *testing.T) {
+	rev := "1"
+	h := &CapabilitiesHandler{Capabilities: func() Capabilities {
+		return LocalCapabilities(PolicyBundle{Name: "scope", Revision: rev})
+	}}
+	mux := http.NewServeMux()
+	mux.Handle("/capabilities", h)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	rev = "2"
+	c, err := FetchCapabilities(context.Background(), srv.Client(), srv.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.TaskSchemaVersion != TaskSchemaVersion || len(c.PolicyBundles) != 1 || c.PolicyBundles[0].Revision != "2" {
+		t.Errorf("fetched %+v", c)
+	}
+
+	resp, err := srv.Client().Post(srv.URL+"/capabilities", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d", resp.StatusCode)
+	}
+	if _, err := FetchCapabilities(context.Background(), srv.Client(), srv.URL+"/missing"); err == nil {
+		t.Error("fetched capabilities from a path that has none")
+	}
+}
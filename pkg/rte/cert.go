@@ -0,0 +1,174 @@
+package rte
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// CertSigner is a Signer whose key is certified by an X.509 chain. Tasks
+// it signs carry the chain, so enterprise PKI rather than key enrollment
+// can govern who may sign them.
+type CertSigner interface {
+	Signer
+	// CertificateChain returns the DER certificates, leaf first.
+	CertificateChain() [][]byte
+}
+
+type certSigner struct {
+	keySigner
+	chain [][]byte
+}
+
+func (c certSigner) CertificateChain() [][]byte { return c.chain }
+
+// NewCertSigner returns a signer for priv that attaches chain, leaf first,
+// to the tasks it signs. The leaf must certify priv's public key.
+func NewCertSigner(priv ed25519.PrivateKey, chain []*x509.Certificate) (CertSigner, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("certificate chain is empty")
+	}
+	pub := priv.Public().(ed25519.PublicKey)
+	if leaf, ok := chain[0].PublicKey.(ed25519.PublicKey); !ok || !leaf.Equal(pub) {
+		return nil, errors.New("leaf certificate does not certify the signing key")
+	}
+	der := make([][]byte, len(chain))
+	for i, c := range chain {
+		der[i] = c.Raw
+	}
+	return certSigner{keySigner: keySigner{priv: priv, pub: pub}, chain: der}, nil
+}
+
+// leafKey checks that st's leaf certificate certifies st.PublicKey.
+func leafKey(st *SignedTask) (*x509.Certificate, error) {
+	leaf, err := x509.ParseCertificate(st.Certificates[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse leaf certificate: %w", err)
+	}
+	pub, ok := leaf.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("leaf certificate key is not ed25519")
+	}
+	if !bytes.Equal(pub, st.PublicKey) {
+		return nil, errors.New("public key does not match the leaf certificate")
+	}
+	return leaf, nil
+}
+
+// CertPolicy verifies tasks signed under a certificate chain against a CA
+// pool, for agents that trust enterprise PKI instead of an identity
+// registry.
+type CertPolicy struct {
+	// Roots are the trusted CAs.
+	Roots *x509.CertPool
+	// KeyUsages the leaf must allow; defaults to code signing.
+	KeyUsages []x509.ExtKeyUsage
+	// AllowedSANs, if set, are path.Match patterns at least one of the
+	// leaf's email, DNS, or URI SANs must match, such as "*@redteam.example".
+	AllowedSANs []string
+	// BindOperator requires the leaf to name the task's Operator: as a SAN,
+	// or as the local part of an email SAN.
+	BindOperator bool
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// VerifyTask checks st's chain and SAN constraints, then verifies it as
+// VerifyTask does. Tasks without a chain are rejected.
+func (p *CertPolicy) VerifyTask(st *SignedTask) error {
+	if _, err := p.VerifyChain(st); err != nil {
+		return err
+	}
+	return VerifyTask(st)
+}
+
+// VerifyChain checks st's certificate chain against the policy and returns
+// the verified leaf. It does not check the task signature.
+func (p *CertPolicy) VerifyChain(st *SignedTask) (*x509.Certificate, error) {
+	if st == nil {
+		return nil, errors.New("signed task is nil")
+	}
+	if len(st.Certificates) == 0 {
+		return nil, errors.New("signed task carries no certificate chain")
+	}
+	if p.Roots == nil {
+		return nil, errors.New("no trusted CA pool configured")
+	}
+	leaf, err := leafKey(st)
+	if err != nil {
+		return nil, err
+	}
+	intermediates := x509.NewCertPool()
+	for i, der := range st.Certificates[1:] {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate %d: %w", i+1, err)
+		}
+		intermediates.AddCert(c)
+	}
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+	usages := p.KeyUsages
+	if len(usages) == 0 {
+		usages = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots: p.Roots, Intermediates: intermediates, KeyUsages: usages, CurrentTime: now(),
+	}); err != nil {
+		return nil, fmt.Errorf("certificate chain: %w", err)
+	}
+	sans := certSANs(leaf)
+	if len(p.AllowedSANs) > 0 && !anySANMatches(sans, p.AllowedSANs) {
+		return nil, fmt.Errorf("certificate %q has no allowed SAN", leaf.Subject.CommonName)
+	}
+	if p.BindOperator && !namesOperator(leaf, sans, st.Task.Operator) {
+		return nil, fmt.Errorf("certificate %q does not name operator %s", leaf.Subject.CommonName, st.Task.Operator)
+	}
+	return leaf, nil
+}
+
+func certSANs(c *x509.Certificate) []string {
+	sans := append(append([]string(nil), c.EmailAddresses...), c.DNSNames...)
+	for _, u := range c.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+func anySANMatches(sans, patterns []string) bool {
+	for _, san := range sans {
+		for _, pat := range patterns {
+			if ok, _ := path.Match(pat, san); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func namesOperator(c *x509.Certificate, sans []string, operator string) bool {
+	if operator == "" {
+		return false
+	}
+	for _, san := range sans {
+		if san == operator {
+			return true
+		}
+	}
+	for _, email := range c.EmailAddresses {
+		if local, _, ok := strings.Cut(email, "@"); ok && local == operator {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPKI is a root CA, an intermediate, and a code-signing leaf for
+// op-alice@redteam.example.
+type testPKI struct {
+	roots    *x509.CertPool
+	chain    []*x509.Certificate
+	leafPriv ed25519.PrivateKey
+}
+
+func issue(t *testing.T, tmpl, parent *x509.Certificate, pub ed25519.PublicKey, signer ed25519.PrivateKey) *x509.Certificate {
+	t.Helper()
+	if parent == nil {
+		parent = tmpl
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	now := time.Now()
+	ca := func(serial int64, name string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber: big.NewInt(serial), Subject: pkix.Name{CommonName: name},
+			NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour),
+			IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+		}
+	}
+	rootPub, rootPriv, _ := ed25519.GenerateKey(nil)
+	root := issue(t, ca(1, "RTE-A Root"), nil, rootPub, rootPriv)
+	intPub, intPriv, _ := ed25519.GenerateKey(nil)
+	inter := issue(t, ca(2, "RTE-A Operators"), root, intPub, rootPriv)
+	leafPub, leafPriv, _ := ed25519.GenerateKey(nil)
+	leaf := issue(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3), Subject: pkix.Name{CommonName: "Alice"},
+		NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour),
+		KeyUsage: x509.KeyUsageDigitalSignature, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses: []string{"op-alice@redteam.example"},
+	}, inter, leafPub, intPriv)
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	return testPKI{roots: roots, chain: []*x509.Certificate{leaf, inter}, leafPriv: leafPriv}
+}
+
+func (p testPKI) sign(t *testing.T) *SignedTask {
+	t.Helper()
+	s, err := NewCertSigner(p.leafPriv, p.chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := SignTaskContext(context.Background(), validTask(time.Now().UTC()), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestCertPolicy_VerifyTask(t *testing.T) {
+	pki := newTestPKI(t)
+	st := pki.sign(t)
+	if len(st.Certificates) != 2 {
+		t.Fatalf("certificates = %d, want leaf and intermediate", len(st.Certificates))
+	}
+	p := &CertPolicy{Roots: pki.roots, AllowedSANs: []string{"*@redteam.example"}, BindOperator: true}
+	if err := p.VerifyTask(st); err != nil {
+		t.Fatal(err)
+	}
+	// Without a policy the chain is carried but only the key is checked.
+	if err := VerifyTask(st); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertPolicy_Rejects(t *testing.T) {
+	pki := newTestPKI(t)
+	for _, tc := range []struct {
+		name   string
+		policy CertPolicy
+		mutate func(*SignedTask)
+		want   string
+	}{
+		{"untrusted root", CertPolicy{Roots: x509.NewCertPool()}, nil, "certificate chain"},
+		{"expired", CertPolicy{Roots: pki.roots, Now: func() time.Time { return time.Now().Add(2 * time.Hour) }}, nil, "certificate chain"},
+		{"wrong eku", CertPolicy{Roots: pki.roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, nil, "certificate chain"},
+		{"san", CertPolicy{Roots: pki.roots, AllowedSANs: []string{"*@corp.example"}}, nil, "no allowed SAN"},
+		{"operator", CertPolicy{Roots: pki.roots, BindOperator: true}, func(st *SignedTask) {
+			st.Task.Operator = "op-mallory"
+		}, "does not name operator"},
+		{"missing intermediate", CertPolicy{Roots: pki.roots}, func(st *SignedTask) {
+			st.Certificates = st.Certificates[:1]
+		}, "certificate chain"},
+		{"key swap", CertPolicy{Roots: pki.roots}, func(st *SignedTask) {
+			other := newKeyPair(t)
+			st.PublicKey = other.pub
+		}, "does not match the leaf"},
+		{"no chain", CertPolicy{Roots: pki.roots}, func(st *SignedTask) {
+			st.Certificates = nil
+		}, "no certificate chain"},
+	} {
+		st := pki.sign(t)
+		if tc.mutate != nil {
+			tc.mutate(st)
+		}
+		err := tc.policy.VerifyTask(st)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestVerifyTask_CertKeyMismatch(t *testing.T) {
+	pki := newTestPKI(t)
+	st := pki.sign(t)
+	// Re-sign with another key while keeping Alice's chain.
+	other := newKeyPair(t)
+	resigned, err := SignTask(st.Task, other.priv, other.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resigned.Certificates = st.Certificates
+	if err := VerifyTask(resigned); err == nil {
+		t.Fatal("expected a chain for a different key to fail")
+	}
+}
+
+func TestNewCertSigner_LeafMustMatch(t *testing.T) {
+	pki := newTestPKI(t)
+	other := newKeyPair(t)
+	if _, err := NewCertSigner(other.priv, pki.chain); err == nil {
+		t.Fatal("expected a leaf for a different key to fail")
+	}
+}
@@ -0,0 +1,68 @@
+package rte
+
+import (
+	"fmt"
+)
+
+// Classification is a handling level for engagements and their artifacts.
+// Levels are ordered; "" is unmarked and ranks with ClassificationPublic.
+type Classification string
+
+const (
+	ClassificationPublic       Classification = "PUBLIC"
+	ClassificationInternal     Classification = "INTERNAL"
+	ClassificationConfidential Classification = "CONFIDENTIAL"
+	ClassificationRestricted   Classification = "RESTRICTED"
+)
+
+var classificationRank = map[Classification]int{
+	"":                         0,
+	ClassificationPublic:       0,
+	ClassificationInternal:     1,
+	ClassificationConfidential: 2,
+	ClassificationRestricted:   3,
+}
+
+// Validate checks that c is a known level.
+func (c Classification) Validate() error {
+	if _, ok := classificationRank[c]; !ok {
+		return fmt.Errorf("unknown classification %q (want PUBLIC, INTERNAL, CONFIDENTIAL, or RESTRICTED)", string(c))
+	}
+	return nil
+}
+
+// Dominates reports whether c is at least as high as o. An unknown level
+// ranks above every known one, so a mistyped artifact marking withholds the
+// artifact rather than releasing it; validate deliverable levels first.
+func (c Classification) Dominates(o Classification) bool {
+	return c.rank() >= o.rank()
+}
+
+func (c Classification) rank() int {
+	if r, ok := classificationRank[c]; ok {
+		return r
+	}
+	return len(classificationRank)
+}
+
+// MaxClassification returns the highest of levels, or "" if there are none.
+func MaxClassification(levels ...Classification) Classification {
+	var top Classification
+	for _, c := range levels {
+		if top == "" || !top.Dominates(c) {
+			top = c
+		}
+	}
+	return top
+}
+
+// Releasable reports whether an artifact marked artifact may go into a
+// deliverable marked deliverable. An unmarked artifact is taken to be at
+// its engagement's level, so lowering a deliverable below the engagement
+// withholds everything not explicitly marked down.
+func Releasable(artifact, engagement, deliverable Classification) bool {
+	if artifact == "" {
+		artifact = engagement
+	}
+	return deliverable.Dominates(artifact)
+}
@@ -0,0 +1,61 @@
+package rte
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClassification(t *testing.T) {
+	if !ClassificationRestricted.Dominates(ClassificationConfidential) || ClassificationInternal.Dominates(ClassificationConfidential) {
+		t.Error("levels out of order")
+	}
+	if !ClassificationPublic.Dominates("") || !Classification("").Dominates(ClassificationPublic) {
+		t.Error("unmarked should rank with PUBLIC")
+	}
+	if ClassificationRestricted.Dominates("SECRET") {
+		t.Error("an unknown level ranked below RESTRICTED")
+	}
+	if err := Classification("secret").Validate(); err == nil {
+		t.Error("validated an unknown level")
+	}
+	if got := MaxClassification("", ClassificationConfidential, ClassificationInternal); got != ClassificationConfidential {
+		t.Errorf("MaxClassification = %q", got)
+	}
+	if got := MaxClassification(); got != "" {
+		t.Errorf("MaxClassification() = %q", got)
+	}
+}
+
+func TestReleasable(t *testing.T) {
+	for _, c := range []struct {
+		artifact, engagement, deliverable Classification
+		want                              bool
+	}{
+		{"", ClassificationConfidential, ClassificationConfidential, true},
+		{"", ClassificationConfidential, ClassificationInternal, false},
+		{ClassificationPublic, ClassificationConfidential, ClassificationInternal, true},
+		{ClassificationRestricted, ClassificationConfidential, ClassificationConfidential, false},
+		{ClassificationRestricted, "", ClassificationRestricted, true},
+		{"SECRET", "", ClassificationRestricted, false},
+	} {
+		if got := Releasable(c.artifact, c.engagement, c.deliverable); got != c.want {
+			t.Errorf("Releasable(%q, %q, %q) = %v", c.artifact, c.engagement, c.deliverable, got)
+		}
+	}
+}
+
+func TestTaskValidate_Classification(t *testing.T) {
+	now := time.Now()
+	task := Task{
+		ID: "t1", Engagement: "eng-1", Type: TaskSimulateLogin, CreatedAt: now, TTLSeconds: 600,
+		Operator: "op-alice", ApprovedBy: "lead-bob", State: StatePending, Classification: ClassificationRestricted,
+	}
+	if err := task.Validate(now); err != nil {
+		t.Fatal(err)
+	}
+	task.Classification = "TOP SECRET"
+	if err := task.Validate(now); !errors.Is(err, ErrBadClassification) {
+		t.Fatalf("got %v, want ErrBadClassification", err)
+	}
+}
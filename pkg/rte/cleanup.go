@@ -0,0 +1,155 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// ErrNoCleanup is returned by AddCleanup when the run cannot issue cleanup
+// tasks: it is not under an Executor with a CleanupSigner and OnCleanup,
+// it is a dry run, or it is itself a cleanup task.
+var ErrNoCleanup = errors.New("run does not issue cleanup tasks")
+
+// CleanupAction is something a handler left behind that a later task must
+// undo: synthetic files to delete, a beacon loop to stop, a test account
+// to remove.
+type CleanupAction struct {
+	// Params are the cleanup task's params; they tell the handler's
+	// Cleanup what to undo.
+	Params map[string]string
+	// AtEngagementEnd holds the action until Executor.EndEngagement
+	// instead of issuing it when the task that registered it finishes.
+	AtEngagementEnd bool
+}
+
+// Cleaner is implemented by handlers that undo their own work. Cleanup runs
+// a cleanup task: one whose CleanupOf names the task that registered the
+// action, with the action's Params.
+type Cleaner interface {
+	Handler
+	Cleanup(ctx context.Context, task Task) (any, error)
+}
+
+// cleanupKey is the context key of a run's cleanups.
+type cleanupKey struct{}
+
+// cleanups collects the actions a run registers. Handlers may register
+// from several goroutines.
+type cleanups struct {
+	mu      sync.Mutex
+	actions []CleanupAction
+}
+
+// AddCleanup registers a cleanup action for the task whose run ctx belongs
+// to. Handlers call it as soon as they create what the action undoes, so a
+// run that fails or is cancelled part way still cleans up. The executor
+// issues the action as a signed cleanup task once the run finishes,
+// however it ends.
+func AddCleanup(ctx context.Context, a CleanupAction) error {
+	c, ok := ctx.Value(cleanupKey{}).(*cleanups)
+	if !ok {
+		return ErrNoCleanup
+	}
+	a.Params = maps.Clone(a.Params)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = append(c.actions, a)
+	return nil
+}
+
+// pendingCleanup is an action held for the end of its engagement.
+type pendingCleanup struct {
+	parent Task
+	action CleanupAction
+	n      int
+}
+
+// withCleanup returns ctx collecting the cleanups of task's run, or ctx and
+// nil if the run issues none.
+func (e *Executor) withCleanup(ctx context.Context, task Task) (context.Context, *cleanups) {
+	if e.CleanupSigner == nil || e.OnCleanup == nil || task.DryRun || task.CleanupOf != "" {
+		return ctx, nil
+	}
+	c := &cleanups{}
+	return context.WithValue(ctx, cleanupKey{}, c), c
+}
+
+// issueCleanups issues the actions registered by parent's run, holding
+// those marked AtEngagementEnd.
+func (e *Executor) issueCleanups(ctx context.Context, parent Task, c *cleanups) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	actions := c.actions
+	c.mu.Unlock()
+	for i, a := range actions {
+		if a.AtEngagementEnd {
+			e.mu.Lock()
+			e.held[parent.Engagement] = append(e.held[parent.Engagement], pendingCleanup{parent, a, i + 1})
+			e.mu.Unlock()
+			continue
+		}
+		if err := e.issueCleanup(ctx, parent, a, i+1); err != nil {
+			TaskLogger(e.Logger, parent).Error("cleanup not issued", "error", err)
+		}
+	}
+}
+
+// EndEngagement issues the cleanup tasks held for the end of the
+// engagement. Actions that fail to issue stay held, so a later call
+// retries them.
+func (e *Executor) EndEngagement(ctx context.Context, engagement string) error {
+	e.mu.Lock()
+	held := e.held[engagement]
+	delete(e.held, engagement)
+	e.mu.Unlock()
+	var errs []error
+	var failed []pendingCleanup
+	for _, p := range held {
+		if err := e.issueCleanup(ctx, p.parent, p.action, p.n); err != nil {
+			errs = append(errs, err)
+			failed = append(failed, p)
+		}
+	}
+	if len(failed) > 0 {
+		e.mu.Lock()
+		e.held[engagement] = append(failed, e.held[engagement]...)
+		e.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// issueCleanup signs the nth cleanup task of parent and hands it to
+// OnCleanup. The task inherits parent's attribution and approval, which
+// covered undoing what it did. It is never standing, so its TTL is
+// parent's held to the ordinary maximum; a standing beacon's cleanup
+// stops the loop, it does not run for days.
+func (e *Executor) issueCleanup(ctx context.Context, parent Task, a CleanupAction, n int) error {
+	task := Task{
+		ID:             fmt.Sprintf("%s-cleanup-%d", parent.ID, n),
+		Engagement:     parent.Engagement,
+		Type:           parent.Type,
+		CreatedAt:      time.Now().UTC(),
+		TTLSeconds:     min(parent.TTLSeconds, CurrentValidatorConfig().MaxTTLSeconds),
+		Operator:       parent.Operator,
+		ApprovedBy:     parent.ApprovedBy,
+		State:          StatePending,
+		Params:         a.Params,
+		Priority:       parent.Priority,
+		Classification: parent.Classification,
+		Selector:       parent.Selector,
+		CleanupOf:      parent.ID,
+	}
+	st, err := SignTaskContext(ctx, task, e.CleanupSigner)
+	if err != nil {
+		return fmt.Errorf("sign cleanup of %s: %w", parent.ID, err)
+	}
+	TaskLogger(e.Logger, parent).Info("cleanup issued", "cleanup_task_id", task.ID)
+	e.OnCleanup(st)
+	return nil
+}
@@ -0,0 +1,128 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fileHandler drops synthetic files and cleans them up: at once, or at the
+// end of the engagement for the one named "keep".
+type fileHandler struct {
+	mu      sync.Mutex
+	removed []string
+}
+
+func (h *fileHandler) Handle(ctx context.Context, task Task) (any, error) {
+	for _, f := range []string{"a.txt", "keep"} {
+		if err := AddCleanup(ctx, CleanupAction{Params: map[string]string{"path": f}, AtEngagementEnd: f == "keep"}); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errors.New("disk full")
+}
+
+func (h *fileHandler) Cleanup(ctx context.Context, task Task) (any, error) {
+	if err := AddCleanup(ctx, CleanupAction{}); !errors.Is(err, ErrNoCleanup) {
+		return nil, errors.New("cleanup task registered a cleanup")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removed = append(h.removed, task.Params["path"])
+	return nil, nil
+}
+
+func TestExecutor_Cleanup(t *testing.T) {
+	ctx := context.Background()
+	kp := newKeyPair(t)
+	signer, err := NewKeySigner(kp.priv, kp.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issued []*SignedTask
+	e := NewExecutor()
+	e.CleanupSigner = signer
+	e.OnCleanup = func(st *SignedTask) { issued = append(issued, st) }
+	h := &fileHandler{}
+	if err := e.Register(TaskSimulateLogin, h); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := e.Execute(ctx, signedValidTask(t))
+	if err != nil || res.State != StateFailed {
+		t.Fatalf("Execute: %+v, %v", res, err)
+	}
+	if len(issued) != 1 {
+		t.Fatalf("issued %d cleanup tasks, want 1", len(issued))
+	}
+	c := issued[0].Task
+	if c.ID != "task-001-cleanup-1" || c.CleanupOf != "task-001" || c.Type != TaskSimulateLogin ||
+		c.ApprovedBy != "lead-bob" || c.Params["path"] != "a.txt" {
+		t.Errorf("cleanup task = %+v", c)
+	}
+	if err := VerifyTask(issued[0]); err != nil {
+		t.Fatalf("cleanup task does not verify: %v", err)
+	}
+
+	if err := e.EndEngagement(ctx, "eng-2026-q1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(issued) != 2 || issued[1].Task.ID != "task-001-cleanup-2" {
+		t.Fatalf("engagement end issued %d", len(issued)-1)
+	}
+	for _, st := range issued {
+		if res, err := e.Execute(ctx, st); err != nil || res.State != StateCompleted {
+			t.Fatalf("cleanup %s: %+v, %v", st.Task.ID, res, err)
+		}
+	}
+	if len(h.removed) != 2 || h.removed[0] != "a.txt" || h.removed[1] != "keep" || len(issued) != 2 {
+		t.Errorf("removed %v, issued %d", h.removed, len(issued))
+	}
+
+	// A handler that cannot clean up refuses cleanup tasks.
+	plain := NewExecutor()
+	_ = plain.Register(TaskSimulateLogin, HandlerFunc(func(ctx context.Context, _ Task) (any, error) {
+		return nil, AddCleanup(ctx, CleanupAction{})
+	}))
+	if res, _ := plain.Execute(ctx, issued[0]); res.State != StateFailed {
+		t.Errorf("cleanup without Cleaner: %s", res.State)
+	}
+	if res, _ := plain.Execute(ctx, signedValidTask(t)); res.Error != ErrNoCleanup.Error() {
+		t.Errorf("AddCleanup without CleanupSigner: %q", res.Error)
+	}
+}
+
+func TestExecutor_CleanupOfStanding(t *testing.T) {
+	ctx := context.Background()
+	reg, op, lead := enrolled(t)
+	kp := newKeyPair(t)
+	signer, err := NewKeySigner(kp.priv, kp.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issued []*SignedTask
+	e := NewExecutor()
+	e.Identities = reg
+	e.CleanupSigner = signer
+	e.OnCleanup = func(st *SignedTask) { issued = append(issued, st) }
+	if err := e.Register(TaskSimulateBeacon, &fileHandler{}); err != nil {
+		t.Fatal(err)
+	}
+	task := standingTask(time.Now().UTC())
+	task.TTLSeconds = 2 * 24 * 60 * 60
+	if _, err := e.Execute(ctx, signAndApprove(t, task, op, lead)); err != nil {
+		t.Fatal(err)
+	}
+	if len(issued) != 1 {
+		t.Fatalf("issued %d cleanup tasks for a standing beacon, want 1", len(issued))
+	}
+	c := issued[0].Task
+	if c.Standing || c.TTLSeconds != CurrentValidatorConfig().MaxTTLSeconds {
+		t.Errorf("cleanup task = %+v", c)
+	}
+	if err := VerifyTask(issued[0]); err != nil {
+		t.Fatalf("cleanup task does not verify: %v", err)
+	}
+}
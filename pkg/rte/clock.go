@@ -0,0 +1,91 @@
+package rte
+
+import "time"
+
+// clockTolerance is how far the wall clock may drift from the monotonic
+// clock between two observations before the executor reports a step. NTP
+// slewing stays well inside it; snapshot restores and NTP steps do not.
+const clockTolerance = time.Second
+
+// ClockAnomaly reports the host's wall clock stepping relative to elapsed
+// monotonic time, as after a VM snapshot restore or an NTP jump.
+type ClockAnomaly struct {
+	// At is the wall-clock time the step was observed.
+	At time.Time
+	// Step is how far the wall clock moved beyond elapsed monotonic time;
+	// negative when it stepped backward.
+	Step time.Duration
+	// Engagement and TaskID name the task being started or finished when
+	// the step was observed.
+	Engagement string
+	TaskID     string
+}
+
+// Backward reports whether the wall clock stepped backward.
+func (a ClockAnomaly) Backward() bool { return a.Step < 0 }
+
+// clock separates wall time from monotonic elapsed time so tests can step
+// one without the other.
+type clock interface {
+	wall() time.Time
+	mono() time.Duration
+}
+
+var processStart = time.Now()
+
+type systemClock struct{}
+
+func (systemClock) wall() time.Time     { return time.Now().Round(0) }
+func (systemClock) mono() time.Duration { return time.Since(processStart) }
+
+// clockState tracks the executor's view of time. Its effective time is the
+// wall clock plus lag, where lag accumulates backward steps, so a clock
+// stepped back cannot make an expired task valid again. A later forward
+// step, such as NTP correcting the restore, pays the lag back down.
+type clockState struct {
+	src      clock
+	seen     bool
+	markWall time.Time
+	markMono time.Duration
+	lag      time.Duration
+}
+
+// observe returns the effective and wall-clock times and, if the wall
+// clock stepped since the last observation, how far.
+func (c *clockState) observe() (now, wall time.Time, step time.Duration) {
+	if c.src == nil {
+		c.src = systemClock{}
+	}
+	wall, mono := c.src.wall(), c.src.mono()
+	if !c.seen {
+		c.seen, c.markWall, c.markMono = true, wall, mono
+		return wall, wall, 0
+	}
+	step = wall.Sub(c.markWall) - (mono - c.markMono)
+	c.markWall, c.markMono = wall, mono
+	if step.Abs() <= clockTolerance {
+		return wall.Add(c.lag), wall, 0
+	}
+	c.lag = max(0, c.lag-step)
+	return wall.Add(c.lag), wall, step
+}
+
+// observeClock returns the executor's effective time, reporting any step
+// seen against the task.
+func (e *Executor) observeClock(task Task) time.Time {
+	e.mu.Lock()
+	now, wall, step := e.clock.observe()
+	e.mu.Unlock()
+	if step == 0 {
+		return now
+	}
+	a := ClockAnomaly{At: wall.UTC(), Step: step, Engagement: task.Engagement, TaskID: task.ID}
+	e.Metrics.ClockAnomaly(a)
+	if e.Logger != nil {
+		TaskLogger(e.Logger, task).Warn("clock anomaly", "step", step.String(), "backward", a.Backward())
+	}
+	if e.OnClockAnomaly != nil {
+		e.OnClockAnomaly(a)
+	}
+	return now
+}
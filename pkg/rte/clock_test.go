@@ -0,0 +1,105 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests step the wall clock independently of elapsed time.
+type fakeClock struct {
+	w time.Time
+	m time.Duration
+}
+
+func (c *fakeClock) wall() time.Time     { return c.w }
+func (c *fakeClock) mono() time.Duration { return c.m }
+
+// advance moves both clocks forward by d, as ordinary time passing does.
+func (c *fakeClock) advance(d time.Duration) {
+	c.w = c.w.Add(d)
+	c.m += d
+}
+
+func TestClockState_Observe(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fc := &fakeClock{w: start}
+	c := clockState{src: fc}
+	c.observe()
+
+	fc.advance(time.Minute)
+	fc.w = fc.w.Add(300 * time.Millisecond) // NTP slew stays unreported
+	if now, _, step := c.observe(); step != 0 || !now.Equal(fc.w) {
+		t.Fatalf("slew: now %s step %s", now, step)
+	}
+
+	fc.advance(time.Minute)
+	fc.w = fc.w.Add(-time.Hour) // snapshot restore
+	now, wall, step := c.observe()
+	if step != -time.Hour || !wall.Equal(fc.w) || !now.Equal(fc.w.Add(time.Hour)) {
+		t.Fatalf("backward: now %s wall %s step %s", now, wall, step)
+	}
+	// The effective clock keeps running from where it was, and the step
+	// is reported once.
+	fc.advance(time.Minute)
+	if now2, _, step := c.observe(); step != 0 || now2.Sub(now) != time.Minute {
+		t.Fatalf("after backward: now %s step %s", now2, step)
+	}
+
+	// NTP steps the clock forward again, paying the lag back.
+	fc.w = fc.w.Add(time.Hour)
+	if now, _, step := c.observe(); step != time.Hour || !now.Equal(fc.w) {
+		t.Fatalf("forward: now %s step %s", now, step)
+	}
+}
+
+func TestExecutor_ClockRegressionKeepsExpiredTasksExpired(t *testing.T) {
+	e := NewExecutor()
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil }))
+	// Stand in for a verifier that trusted the regressed wall clock.
+	e.Verify = func(*SignedTask) error { return nil }
+	var seen []ClockAnomaly
+	e.OnClockAnomaly = func(a ClockAnomaly) { seen = append(seen, a) }
+
+	fc := &fakeClock{w: time.Now().Round(0)}
+	e.clock.src = fc
+	e.observeClock(Task{})
+
+	kp := newKeyPair(t)
+	st, err := SignTask(validTask(time.Now().UTC().Add(-5*time.Minute)), kp.priv, kp.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ten minutes pass, the task expires, then the clock steps back.
+	fc.advance(10 * time.Minute)
+	fc.w = fc.w.Add(-30 * time.Minute)
+	if _, err := e.Execute(context.Background(), st); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Execute after regression: got %v, want ErrExpired", err)
+	}
+	if len(seen) != 1 || !seen[0].Backward() || seen[0].Step != -30*time.Minute || seen[0].TaskID != "task-001" {
+		t.Fatalf("anomalies = %+v", seen)
+	}
+}
+
+func TestExecutor_FinishedAtFollowsMonotonicClock(t *testing.T) {
+	e := NewExecutor()
+	fc := &fakeClock{w: time.Now().Round(0)}
+	e.clock.src = fc
+	var seen []ClockAnomaly
+	e.OnClockAnomaly = func(a ClockAnomaly) { seen = append(seen, a) }
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) {
+		fc.w = fc.w.Add(-time.Hour)
+		return nil, nil
+	}))
+	res, err := e.Execute(context.Background(), signedValidTask(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.State != StateCompleted || res.FinishedAt.Before(res.StartedAt) {
+		t.Fatalf("result %s from %s to %s", res.State, res.StartedAt, res.FinishedAt)
+	}
+	if len(seen) != 1 || !seen[0].Backward() {
+		t.Fatalf("anomalies = %+v", seen)
+	}
+}
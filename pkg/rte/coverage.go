@@ -0,0 +1,89 @@
+package rte
+
+import (
+	"sort"
+
+	"github.com/codethor0/rte-a-reference/pkg/attack"
+)
+
+// TechniqueCoverage is one technique's row in a coverage matrix.
+type TechniqueCoverage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Planned counts tagged tasks; Exercised counts those that completed.
+	Planned   int      `json:"planned"`
+	Exercised int      `json:"exercised"`
+	Tasks     []string `json:"tasks"`
+}
+
+// TacticCoverage groups technique coverage under one tactic.
+type TacticCoverage struct {
+	Tactic     attack.Tactic       `json:"tactic"`
+	Techniques []TechniqueCoverage `json:"techniques"`
+}
+
+// CoverageMatrix reports which ATT&CK tactics and techniques an engagement
+// exercised. Tactics appear in kill-chain order, including empty ones, so
+// gaps are visible.
+type CoverageMatrix struct {
+	Engagement string           `json:"engagement"`
+	Tactics    []TacticCoverage `json:"tactics"`
+	// Exercised is the number of distinct techniques with a completed task.
+	Exercised int `json:"exercised"`
+}
+
+// Coverage builds the coverage matrix for one engagement from its tasks and
+// their results. A technique counts as exercised when a tagged task has a
+// completed result; tasks from other engagements and unknown technique IDs
+// are ignored.
+func Coverage(engagement string, tasks []Task, results []TaskResult) CoverageMatrix {
+	completed := make(map[string]bool, len(results))
+	for _, r := range results {
+		if r.Engagement == engagement && r.State == StateCompleted {
+			completed[r.TaskID] = true
+		}
+	}
+	rows := make(map[string]*TechniqueCoverage)
+	for _, t := range tasks {
+		if t.Engagement != engagement {
+			continue
+		}
+		for _, id := range t.Techniques {
+			tech, ok := attack.Lookup(id)
+			if !ok {
+				continue
+			}
+			row := rows[id]
+			if row == nil {
+				row = &TechniqueCoverage{ID: id, Name: tech.Name}
+				rows[id] = row
+			}
+			row.Planned++
+			row.Tasks = append(row.Tasks, t.ID)
+			if completed[t.ID] {
+				row.Exercised++
+			}
+		}
+	}
+
+	m := CoverageMatrix{Engagement: engagement}
+	for _, row := range rows {
+		if row.Exercised > 0 {
+			m.Exercised++
+		}
+	}
+	for _, tac := range attack.Tactics() {
+		tc := TacticCoverage{Tactic: tac, Techniques: []TechniqueCoverage{}}
+		for id, row := range rows {
+			tech, _ := attack.Lookup(id)
+			for _, name := range tech.Tactics {
+				if name == tac.Shortname {
+					tc.Techniques = append(tc.Techniques, *row)
+				}
+			}
+		}
+		sort.Slice(tc.Techniques, func(i, j int) bool { return tc.Techniques[i].ID < tc.Techniques[j].ID })
+		m.Tactics = append(m.Tactics, tc)
+	}
+	return m
+}
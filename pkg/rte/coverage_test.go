@@ -0,0 +1,67 @@
+package rte
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskValidate_Techniques(t *testing.T) {
+	task := validTask(time.Now().UTC())
+	task.Techniques = []string{"T1110.003", "T1078"}
+	if err := task.Validate(time.Now().UTC()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	task.Techniques = []string{"T1110.999"}
+	if err := task.Validate(time.Now().UTC()); err == nil || !strings.Contains(err.Error(), "not in the catalog") {
+		t.Fatalf("expected unknown technique to fail, got %v", err)
+	}
+	task.Techniques = nil
+	task.ExpectedDetections = []ExpectedDetection{{Technique: "1110", Rule: "spray"}}
+	if err := task.Validate(time.Now().UTC()); err == nil {
+		t.Fatal("expected malformed detection technique to fail")
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	now := time.Now().UTC()
+	mk := func(id string, techniques ...string) Task {
+		task := validTask(now)
+		task.ID, task.Techniques = id, techniques
+		return task
+	}
+	other := mk("t-other", "T1486")
+	other.Engagement = "eng-other"
+	tasks := []Task{
+		mk("t1", "T1110.003"),
+		mk("t2", "T1110.003", "T1078"),
+		mk("t3", "T1071.001"),
+		other,
+	}
+	results := []TaskResult{
+		{TaskID: "t1", Engagement: tasks[0].Engagement, State: StateCompleted},
+		{TaskID: "t2", Engagement: tasks[0].Engagement, State: StateFailed},
+		{TaskID: "t-other", Engagement: "eng-other", State: StateCompleted},
+	}
+	m := Coverage(tasks[0].Engagement, tasks, results)
+	if len(m.Tactics) != 14 || m.Exercised != 1 {
+		t.Fatalf("unexpected matrix: %d tactics, %d exercised", len(m.Tactics), m.Exercised)
+	}
+	byTactic := map[string][]TechniqueCoverage{}
+	for _, tc := range m.Tactics {
+		byTactic[tc.Tactic.Shortname] = tc.Techniques
+	}
+	spray := byTactic["credential-access"]
+	if len(spray) != 1 || spray[0].Planned != 2 || spray[0].Exercised != 1 || spray[0].Name != "Brute Force: Password Spraying" {
+		t.Fatalf("unexpected credential-access row %+v", spray)
+	}
+	// Valid Accounts spans four tactics.
+	for _, tac := range []string{"initial-access", "persistence", "privilege-escalation", "defense-evasion"} {
+		if len(byTactic[tac]) != 1 || byTactic[tac][0].ID != "T1078" || byTactic[tac][0].Exercised != 0 {
+			t.Errorf("%s: unexpected row %+v", tac, byTactic[tac])
+		}
+	}
+	if len(byTactic["impact"]) != 0 {
+		t.Errorf("other engagement leaked into coverage: %+v", byTactic["impact"])
+	}
+}
@@ -0,0 +1,278 @@
+package rte
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Custody events recorded in an artifact's chain.
+const (
+	CustodyCollected = "collected"
+	CustodyAccessed  = "accessed"
+)
+
+// CustodyRecord is one link in an artifact's chain of custody. The first
+// record of a chain is its collection; each later one is an access. Every
+// record repeats the artifact's digest and the task and agent that
+// produced it, so any single record stands on its own, and is hash-chained
+// to the one before it.
+type CustodyRecord struct {
+	ArtifactID string `json:"artifact_id"`
+	// Digest is the hex SHA-256 of the artifact's content.
+	Digest     string `json:"digest"`
+	Engagement string `json:"engagement"`
+	TaskID     string `json:"task_id"`
+	// Agent and AgentKey identify the agent whose signed result the
+	// artifact was collected from.
+	Agent    string `json:"agent"`
+	AgentKey []byte `json:"agent_key"`
+	Sequence int    `json:"sequence"`
+	Event    string `json:"event"`
+	// Actor is who collected or accessed the artifact.
+	Actor    string    `json:"actor"`
+	Purpose  string    `json:"purpose,omitempty"`
+	At       time.Time `json:"at"`
+	PrevHash string    `json:"prev_hash"`
+	// Hash is the hex SHA-256 of the record's JSON with Hash, PublicKey,
+	// and Signature empty.
+	Hash string `json:"hash"`
+	// PublicKey and Signature, if set, are a custodian's signature over
+	// Hash.
+	PublicKey []byte `json:"public_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+func (r CustodyRecord) digest() (string, error) {
+	r.Hash, r.PublicKey, r.Signature = "", nil, nil
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sign sets r's signature over its Hash with a custodian's key.
+func (r *CustodyRecord) Sign(priv ed25519.PrivateKey) error {
+	if len(priv) != ed25519.PrivateKeySize {
+		return errors.New("invalid private key size")
+	}
+	if r.Hash == "" {
+		return errors.New("custody record has no hash to sign")
+	}
+	r.PublicKey = priv.Public().(ed25519.PublicKey)
+	r.Signature = ed25519.Sign(priv, []byte(r.Hash))
+	return nil
+}
+
+// verifySignature checks r's signature, if it has one.
+func (r CustodyRecord) verifySignature() error {
+	if len(r.Signature) == 0 && len(r.PublicKey) == 0 {
+		return nil
+	}
+	if len(r.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(r.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	if !ed25519.Verify(r.PublicKey, []byte(r.Hash), r.Signature) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// VerifyCustodyChain checks one artifact's chain of custody: that it
+// starts with its collection, that every record names the same artifact,
+// digest, task, and agent, that sequences, times, and hash links run in
+// order, and that signed records verify. It reports the first broken link.
+func VerifyCustodyChain(records []CustodyRecord) error {
+	if len(records) == 0 {
+		return errors.New("custody chain is empty")
+	}
+	first := records[0]
+	prev := InitialChainHash
+	for i, r := range records {
+		switch {
+		case i == 0 && r.Event != CustodyCollected:
+			return fmt.Errorf("custody record 0: chain starts with %q, not %q", r.Event, CustodyCollected)
+		case i > 0 && r.Event != CustodyAccessed:
+			return fmt.Errorf("custody record %d: unexpected %q event", i, r.Event)
+		case r.ArtifactID != first.ArtifactID || r.Digest != first.Digest || r.Engagement != first.Engagement ||
+			r.TaskID != first.TaskID || r.Agent != first.Agent || !bytes.Equal(r.AgentKey, first.AgentKey):
+			return fmt.Errorf("custody record %d: does not describe the artifact the chain collected", i)
+		case r.Sequence != i+1:
+			return fmt.Errorf("custody record %d: sequence %d, want %d", i, r.Sequence, i+1)
+		case r.At.Before(records[max(i-1, 0)].At):
+			return fmt.Errorf("custody record %d: recorded before the preceding record", i)
+		case r.PrevHash != prev:
+			return fmt.Errorf("custody record %d: prev_hash does not match the preceding record", i)
+		}
+		sum, err := r.digest()
+		if err != nil {
+			return fmt.Errorf("custody record %d: %w", i, err)
+		}
+		if r.Hash != sum {
+			return fmt.Errorf("custody record %d: hash mismatch", i)
+		}
+		if err := r.verifySignature(); err != nil {
+			return fmt.Errorf("custody record %d: %w", i, err)
+		}
+		prev = r.Hash
+	}
+	return nil
+}
+
+// CustodyLog keeps a chain of custody per artifact. It is safe for
+// concurrent use.
+type CustodyLog struct {
+	// PrivateKey, if set, signs every record the log appends.
+	PrivateKey ed25519.PrivateKey
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	chains map[string][]CustodyRecord
+}
+
+// NewCustodyLog returns an empty log.
+func NewCustodyLog() *CustodyLog {
+	return &CustodyLog{chains: make(map[string][]CustodyRecord)}
+}
+
+// Collect starts the chain of custody for an artifact taken from a task's
+// signed result. agent names the identity that signed sr, and content is
+// the artifact as collected. The collection time is the result's
+// FinishedAt, as attested by the agent.
+func (l *CustodyLog) Collect(sr *SignedResult, agent, artifactID string, content []byte) (CustodyRecord, error) {
+	if artifactID == "" {
+		return CustodyRecord{}, errors.New("artifact ID is required")
+	}
+	if agent == "" {
+		return CustodyRecord{}, errors.New("agent is required")
+	}
+	if err := VerifyResult(sr); err != nil {
+		return CustodyRecord{}, fmt.Errorf("verify result: %w", err)
+	}
+	at := sr.Result.FinishedAt
+	if at.IsZero() {
+		at = l.now()
+	}
+	sum := sha256.Sum256(content)
+	r := CustodyRecord{
+		ArtifactID: artifactID,
+		Digest:     hex.EncodeToString(sum[:]),
+		Engagement: sr.Result.Engagement,
+		TaskID:     sr.Result.TaskID,
+		Agent:      agent,
+		AgentKey:   append([]byte(nil), sr.PublicKey...),
+		Event:      CustodyCollected,
+		Actor:      agent,
+		At:         at.UTC(),
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.chains[artifactID]) > 0 {
+		return CustodyRecord{}, fmt.Errorf("artifact %s is already in custody", artifactID)
+	}
+	return l.append(r)
+}
+
+// Access records that actor accessed an artifact in custody, and why.
+func (l *CustodyLog) Access(artifactID, actor, purpose string) (CustodyRecord, error) {
+	if actor == "" {
+		return CustodyRecord{}, errors.New("actor is required")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	chain := l.chains[artifactID]
+	if len(chain) == 0 {
+		return CustodyRecord{}, fmt.Errorf("artifact %s is not in custody", artifactID)
+	}
+	r := chain[0]
+	r.Event, r.Actor, r.Purpose = CustodyAccessed, actor, purpose
+	r.At = l.now().UTC()
+	if last := chain[len(chain)-1].At; r.At.Before(last) {
+		r.At = last
+	}
+	return l.append(r)
+}
+
+// append links r to the end of its artifact's chain, hashes it, and signs
+// it if the log has a key. Callers hold l.mu.
+func (l *CustodyLog) append(r CustodyRecord) (CustodyRecord, error) {
+	if l.chains == nil {
+		l.chains = make(map[string][]CustodyRecord)
+	}
+	chain := l.chains[r.ArtifactID]
+	r.Sequence, r.PrevHash = 1, InitialChainHash
+	if n := len(chain); n > 0 {
+		r.Sequence, r.PrevHash = chain[n-1].Sequence+1, chain[n-1].Hash
+	}
+	r.PublicKey, r.Signature = nil, nil
+	var err error
+	if r.Hash, err = r.digest(); err != nil {
+		return CustodyRecord{}, err
+	}
+	if l.PrivateKey != nil {
+		if err := r.Sign(l.PrivateKey); err != nil {
+			return CustodyRecord{}, err
+		}
+	}
+	l.chains[r.ArtifactID] = append(chain, r)
+	return r, nil
+}
+
+func (l *CustodyLog) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// Records returns an artifact's chain, oldest first.
+func (l *CustodyLog) Records(artifactID string) []CustodyRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]CustodyRecord(nil), l.chains[artifactID]...)
+}
+
+// Verify checks an artifact's chain and that content is the artifact it
+// collected.
+func (l *CustodyLog) Verify(artifactID string, content []byte) error {
+	chain := l.Records(artifactID)
+	if err := VerifyCustodyChain(chain); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != chain[0].Digest {
+		return fmt.Errorf("artifact %s does not match the digest recorded at collection", artifactID)
+	}
+	return nil
+}
+
+// Load installs a previously written chain for an artifact that has none
+// yet, as when restoring from a backup; later accesses continue it.
+func (l *CustodyLog) Load(records []CustodyRecord) error {
+	if err := VerifyCustodyChain(records); err != nil {
+		return err
+	}
+	id := records[0].ArtifactID
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.chains == nil {
+		l.chains = make(map[string][]CustodyRecord)
+	}
+	if len(l.chains[id]) > 0 {
+		return fmt.Errorf("artifact %s already has a custody chain", id)
+	}
+	l.chains[id] = append([]CustodyRecord(nil), records...)
+	return nil
+}
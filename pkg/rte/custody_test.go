@@ -0,0 +1,121 @@
+package rte
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func custodyResult(t *testing.T) *SignedResult {
+	t.Helper()
+	pub, priv, _ := GenerateKeyPair()
+	finished := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	sr, err := SignResult(TaskResult{
+		TaskID: "t-1", Engagement: "eng-1", Type: TaskInventory, Operator: "op-alice",
+		State: StateCompleted, StartedAt: finished.Add(-time.Minute), FinishedAt: finished,
+	}, priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sr
+}
+
+func TestCustodyLog(t *testing.T) {
+	_, key, _ := GenerateKeyPair()
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	l := NewCustodyLog()
+	l.PrivateKey = key
+	l.Now = func() time.Time { now = now.Add(time.Minute); return now }
+
+	content := []byte("hostname,os\nws-01,windows\n")
+	sr := custodyResult(t)
+	c, err := l.Collect(sr, "agent-7", "art-1", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Sequence != 1 || c.Event != CustodyCollected || c.TaskID != "t-1" || c.Actor != "agent-7" ||
+		!c.At.Equal(sr.Result.FinishedAt) || c.PrevHash != InitialChainHash || len(c.Signature) == 0 {
+		t.Errorf("collection = %+v", c)
+	}
+	if _, err := l.Collect(sr, "agent-7", "art-1", content); err == nil {
+		t.Error("collected the same artifact twice")
+	}
+	a, err := l.Access("art-1", "analyst-carol", "draft report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Sequence != 2 || a.PrevHash != c.Hash || a.Digest != c.Digest || a.Agent != "agent-7" || a.Purpose != "draft report" {
+		t.Errorf("access = %+v", a)
+	}
+	if _, err := l.Access("art-2", "analyst-carol", ""); err == nil {
+		t.Error("accessed an artifact not in custody")
+	}
+	if err := l.Verify("art-1", content); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Verify("art-1", []byte("edited")); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("altered artifact verified: %v", err)
+	}
+
+	// A chain survives JSON and loads into a fresh log.
+	data, _ := json.Marshal(l.Records("art-1"))
+	var records []CustodyRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewCustodyLog()
+	if err := restored.Load(records); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Load(records); err == nil {
+		t.Error("loaded a chain over an existing one")
+	}
+	if r, err := restored.Access("art-1", "counsel-dan", "legal hold"); err != nil || r.Sequence != 3 {
+		t.Errorf("access after load: %+v, %v", r, err)
+	}
+}
+
+func TestCustodyLog_RejectsUnsignedResult(t *testing.T) {
+	sr := custodyResult(t)
+	sr.Signature[0] ^= 0xff
+	if _, err := NewCustodyLog().Collect(sr, "agent-7", "art-1", nil); err == nil {
+		t.Error("collected from a result whose signature does not verify")
+	}
+}
+
+func TestVerifyCustodyChain_DetectsTampering(t *testing.T) {
+	_, key, _ := GenerateKeyPair()
+	l := NewCustodyLog()
+	l.PrivateKey = key
+	if _, err := l.Collect(custodyResult(t), "agent-7", "art-1", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	for _, who := range []string{"analyst-carol", "counsel-dan"} {
+		if _, err := l.Access("art-1", who, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, tc := range map[string]struct {
+		edit func([]CustodyRecord) []CustodyRecord
+		want string
+	}{
+		"rewritten actor": {func(r []CustodyRecord) []CustodyRecord { r[1].Actor = "someone-else"; return r }, "hash mismatch"},
+		"dropped access":  {func(r []CustodyRecord) []CustodyRecord { return append(r[:1], r[2:]...) }, "sequence"},
+		"swapped task": {func(r []CustodyRecord) []CustodyRecord {
+			r[2].TaskID = "t-9"
+			return r
+		}, "does not describe"},
+		"resigned hash": {func(r []CustodyRecord) []CustodyRecord {
+			r[1].Purpose = "covered up"
+			r[1].Hash, _ = r[1].digest()
+			return r
+		}, "signature verification failed"},
+		"no collection": {func(r []CustodyRecord) []CustodyRecord { return r[1:] }, "chain starts with"},
+	} {
+		err := VerifyCustodyChain(tc.edit(l.Records("art-1")))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", name, err, tc.want)
+		}
+	}
+}
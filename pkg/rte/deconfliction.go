@@ -0,0 +1,184 @@
+package rte
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// targetParams are the task params that name systems a handler touches.
+var targetParams = []string{"target", "destination", "sink", "resolver", "recipients"}
+
+// Activity is one executing task's footprint on one target. End is the
+// task's expiry until the run finishes, then the actual finish time.
+type Activity struct {
+	TaskID     string    `json:"task_id"`
+	Engagement string    `json:"engagement"`
+	Type       TaskType  `json:"type"`
+	Target     string    `json:"target"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Running    bool      `json:"running"`
+
+	prefix netip.Prefix
+	host   string
+}
+
+// TaskTargets returns the targets named in a task's params. List-valued
+// params such as recipients contribute one target per entry.
+func TaskTargets(task Task) []string {
+	var out []string
+	for _, k := range targetParams {
+		for _, v := range strings.Split(task.Params[k], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// DeconflictionRegistry records which targets red team tasks touched and
+// when, so SOC responders can tell simulation activity from a real
+// intrusion. It is safe for concurrent use; a nil registry records nothing.
+type DeconflictionRegistry struct {
+	mu         sync.RWMutex
+	activities []Activity
+}
+
+// NewDeconflictionRegistry returns an empty registry.
+func NewDeconflictionRegistry() *DeconflictionRegistry {
+	return &DeconflictionRegistry{}
+}
+
+// Begin registers every target of a task that starts executing at start.
+// The window stays open until Finish or the task's expiry.
+func (r *DeconflictionRegistry) Begin(task Task, start time.Time) {
+	if r == nil {
+		return
+	}
+	expiry := task.RunExpiry(start)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, target := range TaskTargets(task) {
+		a := Activity{
+			TaskID: task.ID, Engagement: task.Engagement, Type: task.Type, Target: target,
+			Start: start.UTC(), End: expiry.UTC(), Running: true,
+		}
+		a.prefix, a.host = parseTarget(target)
+		r.activities = append(r.activities, a)
+	}
+}
+
+// Finish closes a task's windows at end.
+func (r *DeconflictionRegistry) Finish(engagement, taskID string, end time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.activities {
+		a := &r.activities[i]
+		if a.Engagement == engagement && a.TaskID == taskID && a.Running {
+			a.End, a.Running = end.UTC(), false
+		}
+	}
+}
+
+// Extend moves the end of a running task's windows to a new expiry.
+func (r *DeconflictionRegistry) Extend(engagement, taskID string, expiry time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.activities {
+		a := &r.activities[i]
+		if a.Engagement == engagement && a.TaskID == taskID && a.Running {
+			a.End = expiry.UTC()
+		}
+	}
+}
+
+// Touched returns the activity on target whose window, widened by tolerance
+// on both sides, contains at. IP queries match activity on that address or a
+// CIDR containing it; hostname queries match case-insensitively. Results are
+// ordered by start time.
+func (r *DeconflictionRegistry) Touched(target string, at time.Time, tolerance time.Duration) ([]Activity, error) {
+	prefix, host := parseTarget(target)
+	if !prefix.IsValid() && host == "" {
+		return nil, errors.New("target is required")
+	}
+	if tolerance < 0 {
+		return nil, errors.New("tolerance must not be negative")
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []Activity
+	for _, a := range r.activities {
+		if at.Before(a.Start.Add(-tolerance)) || at.After(a.End.Add(tolerance)) {
+			continue
+		}
+		switch {
+		case prefix.IsValid() && a.prefix.IsValid():
+			if !a.prefix.Overlaps(prefix) {
+				continue
+			}
+		case host != "" && a.host == host:
+		default:
+			continue
+		}
+		out = append(out, a)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out, nil
+}
+
+// parseTarget reduces a target to an address prefix or a lowercase host.
+// URLs and host:port pairs reduce to their host, and email addresses to
+// themselves.
+func parseTarget(target string) (netip.Prefix, string) {
+	target = strings.TrimSpace(target)
+	if u, err := url.Parse(target); err == nil && u.Scheme != "" && u.Host != "" {
+		target = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(target); err == nil {
+		target = h
+	}
+	if p, err := netip.ParsePrefix(target); err == nil {
+		return p.Masked(), ""
+	}
+	if a, err := netip.ParseAddr(target); err == nil {
+		return netip.PrefixFrom(a, a.BitLen()), ""
+	}
+	return netip.Prefix{}, strings.ToLower(target)
+}
+
+// InScope reports whether target lies within one of scope's entries: an
+// address or CIDR containing it, or a host pattern such as "*.lab.example"
+// matching its host. An email address matches on its domain.
+func InScope(target string, scope []string) bool {
+	prefix, host := parseTarget(target)
+	if _, domain, ok := strings.Cut(host, "@"); ok {
+		host = domain
+	}
+	for _, s := range scope {
+		sp, sh := parseTarget(s)
+		switch {
+		case prefix.IsValid() && sp.IsValid():
+			if sp.Bits() <= prefix.Bits() && sp.Contains(prefix.Addr()) {
+				return true
+			}
+		case host != "" && sh != "":
+			if ok, _ := path.Match(sh, host); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
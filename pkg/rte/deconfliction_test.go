@@ -0,0 +1,106 @@
+package rte
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDeconflictionRegistry_Touched(t *testing.T) {
+	start := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	task := validTask(start)
+	task.Params = map[string]string{
+		"target":     "10.4.2.0/24",
+		"sink":       "https://CB.eng-2026-q1.example/beacon",
+		"recipients": "qa@eng-2026-q1.example, ops@eng-2026-q1.example",
+	}
+	r := NewDeconflictionRegistry()
+	r.Begin(task, start)
+	r.Finish(task.Engagement, task.ID, start.Add(5*time.Minute))
+
+	cases := []struct {
+		target    string
+		at        time.Time
+		tolerance time.Duration
+		want      int
+	}{
+		{"10.4.2.7", start.Add(2 * time.Minute), 0, 1},
+		{"10.4.3.7", start.Add(2 * time.Minute), 0, 0},
+		{"10.4.2.7", start.Add(10 * time.Minute), 0, 0},
+		{"10.4.2.7", start.Add(10 * time.Minute), 5 * time.Minute, 1},
+		{"cb.eng-2026-q1.example", start, 0, 1},
+		{"cb.eng-2026-q1.example:443", start, 0, 1},
+		{"ops@eng-2026-q1.example", start, 0, 1},
+	}
+	for _, c := range cases {
+		got, err := r.Touched(c.target, c.at, c.tolerance)
+		if err != nil {
+			t.Fatalf("Touched(%s): %v", c.target, err)
+		}
+		if len(got) != c.want {
+			t.Errorf("Touched(%s, %s): got %d matches, want %d", c.target, c.at, len(got), c.want)
+		}
+	}
+	if _, err := r.Touched("", start, 0); err == nil {
+		t.Error("expected empty target to fail")
+	}
+}
+
+func TestDeconflictionRegistry_Extend(t *testing.T) {
+	start := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	task := validTask(start)
+	r := NewDeconflictionRegistry()
+	r.Begin(task, start)
+	late := task.Expiry().Add(5 * time.Minute)
+	if got, _ := r.Touched("192.168.1.40", late, 0); len(got) != 0 {
+		t.Fatalf("activity past expiry before extension: %+v", got)
+	}
+	r.Extend(task.Engagement, task.ID, task.Expiry().Add(10*time.Minute))
+	if got, _ := r.Touched("192.168.1.40", late, 0); len(got) != 1 {
+		t.Fatalf("extended window not visible: %+v", got)
+	}
+}
+
+func TestExecutor_Deconfliction(t *testing.T) {
+	reg := NewDeconflictionRegistry()
+	e := NewExecutor()
+	e.Deconfliction = reg
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(_ context.Context, task Task) (any, error) {
+		got, _ := reg.Touched("192.168.1.40", time.Now(), 0)
+		if len(got) != 1 || !got[0].Running {
+			t.Errorf("running task not registered: %+v", got)
+		}
+		return nil, nil
+	}))
+	res, err := e.Execute(context.Background(), signedValidTask(t))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	got, _ := reg.Touched("192.168.1.40", res.FinishedAt, 0)
+	if len(got) != 1 || got[0].Running || !got[0].End.Equal(res.FinishedAt) {
+		t.Fatalf("window not closed at finish: %+v", got)
+	}
+	if got, _ := reg.Touched("192.168.1.40", res.FinishedAt.Add(time.Second), 0); len(got) != 0 {
+		t.Errorf("activity matched after finish: %+v", got)
+	}
+}
+
+func TestInScope(t *testing.T) {
+	scope := []string{"10.0.0.0/16", "192.0.2.7", "*.lab.acme.example"}
+	for target, want := range map[string]bool{
+		"10.0.3.0/24":                   true,
+		"10.0.3.9":                      true,
+		"10.1.0.0/24":                   false,
+		"10.0.0.0/8":                    false,
+		"192.0.2.7":                     true,
+		"https://web.lab.acme.example/": true,
+		"ops@mail.lab.acme.example":     true,
+		"WEB.LAB.ACME.EXAMPLE:443":      true,
+		"acme.example":                  false,
+		"evil.example":                  false,
+	} {
+		if got := InScope(target, scope); got != want {
+			t.Errorf("InScope(%q) = %t, want %t", target, got, want)
+		}
+	}
+}
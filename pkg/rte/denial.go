@@ -0,0 +1,174 @@
+package rte
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrBadSignature wraps VerifyTask's signature failures.
+var ErrBadSignature = errors.New("task signature rejected")
+
+// ErrBadTimestamp wraps VerifyTask's failures to verify a task's RFC 3161
+// timestamp.
+var ErrBadTimestamp = errors.New("task timestamp rejected")
+
+// DenialCode classifies why a task was refused, for clients to act on
+// without parsing messages.
+type DenialCode string
+
+const (
+	// DenialInvalid is a task failing Task.Validate other than by time.
+	DenialInvalid DenialCode = "invalid_task"
+	// DenialExpired and DenialNotYetValid are tasks outside their
+	// validity window.
+	DenialExpired     DenialCode = "expired"
+	DenialNotYetValid DenialCode = "not_yet_valid"
+	// DenialSignature is a task whose signature does not verify.
+	DenialSignature DenialCode = "bad_signature"
+	// DenialTimestamp is a task whose timestamp token does not verify.
+	DenialTimestamp DenialCode = "bad_timestamp"
+	// DenialSchema is a task signed under an unsupported schema version.
+	DenialSchema DenialCode = "unsupported_schema"
+	// DenialUnpinnedKey is a task signed by a key the verifier does not
+	// trust.
+	DenialUnpinnedKey DenialCode = "unpinned_key"
+	// DenialHalted is a task for a halted engagement.
+	DenialHalted DenialCode = "engagement_halted"
+	// DenialPolicy is a task engagement policy denies.
+	DenialPolicy DenialCode = "policy_denied"
+)
+
+// Denial is one reason a task was refused: its code, the field at fault
+// and the policy rule broken, where known, and how to fix it.
+type Denial struct {
+	Code DenialCode `json:"code"`
+	Violation
+}
+
+// DenialError is a refused task with every reason found. It is what a
+// controller answers a refused submission with (see WriteDenial), and
+// unwraps to a *PolicyError of the same violations for callers that
+// predate it.
+type DenialError struct {
+	TaskID  string   `json:"task_id,omitempty"`
+	Denials []Denial `json:"denials"`
+}
+
+func (e *DenialError) Error() string {
+	msgs := make([]string, len(e.Denials))
+	for i, d := range e.Denials {
+		msgs[i] = fmt.Sprintf("%s: %s", d.Code, d.Message)
+	}
+	if e.TaskID == "" {
+		return "task denied: " + strings.Join(msgs, "; ")
+	}
+	return fmt.Sprintf("task %s denied: %s", e.TaskID, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the denials as a *PolicyError.
+func (e *DenialError) Unwrap() error {
+	return e.policyError()
+}
+
+func (e *DenialError) policyError() *PolicyError {
+	pe := &PolicyError{TaskID: e.TaskID, Violations: make([]Violation, len(e.Denials))}
+	for i, d := range e.Denials {
+		pe.Violations[i] = d.Violation
+	}
+	return pe
+}
+
+// keyDenials explains the verification failures that are not Task.Validate
+// failures.
+var keyDenials = []struct {
+	err         error
+	code        DenialCode
+	field       string
+	remediation string
+}{
+	{ErrBadSignature, DenialSignature, "signature", "re-sign the task, and check nothing rewrites it in transit"},
+	{ErrBadTimestamp, DenialTimestamp, "timestamp", "re-timestamp the task with a trusted TSA, or submit it without one"},
+	{ErrSchemaVersion, DenialSchema, "task.schema_version", "re-sign the task with a build that speaks the controller's schema version"},
+	{ErrUnpinnedKey, DenialUnpinnedKey, "public_key", "sign with a pinned operator key, or have the key pinned"},
+	{ErrEngagementHalted, DenialHalted, "task.engagement", "ask the engagement lead whether the halt has been lifted"},
+}
+
+// Deny explains an error from VerifyTask, Lint, EnforcePolicy, or a key,
+// halt, or validation check as a *DenialError for taskID. It returns nil
+// for other errors, such as a cancelled context or a failing store, which
+// say nothing about the task.
+func Deny(taskID string, err error) *DenialError {
+	if err == nil {
+		return nil
+	}
+	var de *DenialError
+	if errors.As(err, &de) {
+		return de
+	}
+	out := &DenialError{TaskID: taskID}
+	var verrs ValidationErrors
+	var pe *PolicyError
+	switch {
+	case errors.As(err, &verrs):
+		for _, err := range verrs {
+			out.Denials = append(out.Denials, validationDenial(err))
+		}
+	case errors.As(err, &pe):
+		if pe.TaskID != "" {
+			out.TaskID = pe.TaskID
+		}
+		for _, v := range pe.Violations {
+			d := Denial{Code: DenialPolicy, Violation: v}
+			if v.Source == "rte.Task.Validate" {
+				d.Code = DenialInvalid
+				for _, f := range violationFor {
+					if f.rule == v.Rule {
+						d.Code = f.code
+						break
+					}
+				}
+			}
+			out.Denials = append(out.Denials, d)
+		}
+	default:
+		for _, k := range keyDenials {
+			if errors.Is(err, k.err) {
+				v := Violation{Field: k.field, Source: "rte.VerifyTask", Message: err.Error(), Remediation: k.remediation}
+				out.Denials = append(out.Denials, Denial{Code: k.code, Violation: v})
+				return out
+			}
+		}
+		for _, f := range violationFor {
+			if errors.Is(err, f.err) {
+				out.Denials = append(out.Denials, validationDenial(err))
+				return out
+			}
+		}
+		return nil
+	}
+	return out
+}
+
+// WriteDenial answers a refused submission: if Deny explains err, it
+// writes the *DenialError as JSON with status 403, alongside an "error"
+// message and the "violations" WritePolicyError writes, for clients that
+// read only those, and reports true. Otherwise it writes nothing and
+// reports false.
+func WriteDenial(w http.ResponseWriter, taskID string, err error) bool {
+	de := Deny(taskID, err)
+	if de == nil {
+		return false
+	}
+	body := struct {
+		Error string `json:"error"`
+		*DenialError
+		Violations []Violation `json:"violations"`
+	}{de.Error(), de, de.policyError().Violations}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(body)
+	return true
+}
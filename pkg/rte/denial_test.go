@@ -0,0 +1,90 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeny(t *testing.T) {
+	kp := newKeyPair(t)
+	now := time.Now().UTC()
+	st, err := SignTask(validTask(now), kp.priv, kp.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := *st
+	tampered.Task.Priority = 5
+	expired := validTask(now.Add(-time.Hour))
+	expired.Priority = MaxPriority + 1
+	phish := validTask(now)
+	phish.Type = TaskSimulatePhish
+
+	for _, tc := range []struct {
+		name  string
+		err   error
+		codes []DenialCode
+		field string
+	}{
+		{"signature", VerifyTask(&tampered), []DenialCode{DenialSignature}, "signature"},
+		{"validation", expired.Validate(now), []DenialCode{DenialInvalid, DenialExpired}, "task.priority"},
+		{"schema", checkSchemaVersion(TaskSchemaVersion + 1), []DenialCode{DenialSchema}, "task.schema_version"},
+		{"policy", Lint(context.Background(), PhishApprovalPolicy("lead-carol"), phish, now), []DenialCode{DenialPolicy, DenialPolicy}, "task.approved_by"},
+		{"unpinned", fmt.Errorf("task-001: %w", ErrUnpinnedKey), []DenialCode{DenialUnpinnedKey}, "public_key"},
+		{"state", fmt.Errorf("%w: done", ErrInvalidState), []DenialCode{DenialInvalid}, "task.state"},
+	} {
+		de := Deny("task-001", tc.err)
+		if de == nil || len(de.Denials) != len(tc.codes) {
+			t.Errorf("%s: Deny(%v) = %+v", tc.name, tc.err, de)
+			continue
+		}
+		for i, d := range de.Denials {
+			if d.Code != tc.codes[i] || d.Message == "" {
+				t.Errorf("%s: denial %d = %+v, want code %s", tc.name, i, d, tc.codes[i])
+			}
+		}
+		if f := de.Denials[0].Field; f != tc.field {
+			t.Errorf("%s: field = %q, want %q", tc.name, f, tc.field)
+		}
+	}
+	if d := Deny("task-001", Lint(context.Background(), PhishApprovalPolicy("lead-carol"), phish, now)).Denials[0]; d.Rule != "phish.approver" {
+		t.Errorf("policy denial rule = %q", d.Rule)
+	}
+	if de := Deny("task-001", context.Canceled); de != nil {
+		t.Errorf("Deny(context.Canceled) = %+v", de)
+	}
+	var pe *PolicyError
+	if de := Deny("task-001", VerifyTask(&tampered)); !errors.As(error(de), &pe) || pe.Violations[0].Field != "signature" {
+		t.Errorf("DenialError does not unwrap to its violations: %+v", pe)
+	}
+}
+
+func TestWriteDenial(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if WriteDenial(rec, "t-1", errors.New("disk full")) || rec.Body.Len() != 0 {
+		t.Fatal("wrote an error that is no denial")
+	}
+	if !WriteDenial(rec, "t-1", fmt.Errorf("%w: t-1", ErrEngagementHalted)) {
+		t.Fatal("did not write a denial")
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d", rec.Code)
+	}
+	var body struct {
+		Error string `json:"error"`
+		DenialError
+		Violations []Violation `json:"violations"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.TaskID != "t-1" || len(body.Denials) != 1 || body.Denials[0].Code != DenialHalted ||
+		body.Denials[0].Field != "task.engagement" || len(body.Violations) != 1 || body.Error == "" {
+		t.Errorf("body = %+v", body)
+	}
+}
@@ -0,0 +1,126 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// digestPrefix domain-separates detached signatures from signatures over
+// task JSON, which always begins with '{'.
+const digestPrefix = "RTE-A task sha256:"
+
+// digestPayload is what a detached signature covers.
+func digestPayload(digest []byte) []byte {
+	return append([]byte(digestPrefix), digest...)
+}
+
+// Detached reports whether st was signed in detached mode, over the digest
+// of its task payload rather than the task itself.
+func (st *SignedTask) Detached() bool {
+	return len(st.Digest) > 0
+}
+
+// SignTaskDetached signs the SHA-256 digest of the task's canonical JSON.
+// It returns the envelope, which carries the digest instead of the task,
+// and the canonical payload, so a task with very large Params can travel
+// separately from its signature. Approvers countersign the envelope alone.
+func SignTaskDetached(ctx context.Context, task Task, s Signer) (*SignedTask, []byte, error) {
+	pub, err := signerKey(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, payload, err := canonicalTask(task)
+	if err != nil {
+		return nil, nil, err
+	}
+	digest := sha256.Sum256(payload)
+	sig, err := signWith(ctx, s, digestPayload(digest[:]))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &SignedTask{PublicKey: pub, Signature: sig, Digest: digest[:], Certificates: chainOf(s)}, payload, nil
+}
+
+// VerifyTaskPayload verifies a detached envelope against its payload as
+// it streams in: the bytes are hashed while the task is decoded, never
+// re-marshaled. On success it returns a copy of st with Task filled in,
+// checked as VerifyTask would, timestamp included; its errors wrap the same
+// sentinels.
+func VerifyTaskPayload(st *SignedTask, payload io.Reader) (*SignedTask, error) {
+	if st == nil {
+		return nil, errors.New("signed task is nil")
+	}
+	if !st.Detached() {
+		return nil, errors.New("signed task is not detached")
+	}
+	if err := verifyDigestSignature(st); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadSignature, err)
+	}
+	h := sha256.New()
+	var task Task
+	if err := json.NewDecoder(io.TeeReader(payload, h)).Decode(&task); err != nil {
+		return nil, fmt.Errorf("decode task payload: %w", err)
+	}
+	// The decoder may stop short of the end; hash the rest too.
+	if _, err := io.Copy(h, payload); err != nil {
+		return nil, fmt.Errorf("read task payload: %w", err)
+	}
+	if subtle.ConstantTimeCompare(h.Sum(nil), st.Digest) != 1 {
+		return nil, fmt.Errorf("%w: task payload does not match signed digest", ErrBadSignature)
+	}
+	out := *st
+	out.Task = task
+	if err := checkEnvelope(&out); err != nil {
+		return nil, err
+	}
+	if err := out.Task.Validate(time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// verifyDigestSignature checks a detached signature over the digest alone.
+func verifyDigestSignature(st *SignedTask) error {
+	if len(st.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(st.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	if len(st.Digest) != sha256.Size {
+		return errors.New("invalid task digest size")
+	}
+	if len(st.Certificates) > 0 {
+		if _, err := leafKey(st); err != nil {
+			return err
+		}
+	}
+	if !ed25519.Verify(st.PublicKey, digestPayload(st.Digest), st.Signature) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// checkDigest checks an attached task against a detached envelope's digest.
+func checkDigest(st *SignedTask) error {
+	if st.Task.ID == "" {
+		return errors.New("detached task payload is not attached; verify it with VerifyTaskPayload")
+	}
+	payload, err := json.Marshal(st.Task)
+	if err != nil {
+		return fmt.Errorf("marshal task: %w", err)
+	}
+	digest := sha256.Sum256(payload)
+	if !bytes.Equal(digest[:], st.Digest) {
+		return errors.New("task does not match signed digest")
+	}
+	return nil
+}
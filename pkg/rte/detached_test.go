@@ -0,0 +1,129 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signDetached(t *testing.T) (*SignedTask, []byte, Signer) {
+	t.Helper()
+	pub, priv, _ := GenerateKeyPair()
+	s, err := NewKeySigner(priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := validTask(time.Now().UTC())
+	task.Params["blob"] = strings.Repeat("A", 1<<20)
+	st, payload, err := SignTaskDetached(context.Background(), task, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st, payload, s
+}
+
+func TestSignTaskDetached_RoundTrip(t *testing.T) {
+	st, payload, _ := signDetached(t)
+	if !st.Detached() || st.Task.ID != "" {
+		t.Fatalf("envelope should carry only the digest, got task %q", st.Task.ID)
+	}
+	envelope, _ := json.Marshal(st)
+	if len(envelope) > 1024 {
+		t.Fatalf("envelope is %d bytes; the payload should travel separately", len(envelope))
+	}
+
+	got, err := VerifyTaskPayload(st, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.ID != "task-001" || len(got.Task.Params["blob"]) != 1<<20 {
+		t.Fatalf("decoded task = %s", got.Task.ID)
+	}
+	if err := VerifyTask(got); err != nil {
+		t.Fatalf("attached copy: %v", err)
+	}
+	if st.Task.ID != "" {
+		t.Fatal("VerifyTaskPayload modified its input")
+	}
+}
+
+func TestVerifyTaskPayload_Rejects(t *testing.T) {
+	st, payload, _ := signDetached(t)
+
+	tampered := bytes.Replace(payload, []byte("op-alice"), []byte("op-mallory"), 1)
+	if _, err := VerifyTaskPayload(st, bytes.NewReader(tampered)); err == nil {
+		t.Fatal("expected tampered payload to fail")
+	}
+	trailing := append(append([]byte(nil), payload...), []byte(` {"id":"x"}`)...)
+	if _, err := VerifyTaskPayload(st, bytes.NewReader(trailing)); err == nil {
+		t.Fatal("expected trailing bytes to fail")
+	}
+
+	forged := *st
+	forged.Digest = bytes.Repeat([]byte{1}, len(st.Digest))
+	if _, err := VerifyTaskPayload(&forged, bytes.NewReader(payload)); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("substituted digest: got %v", err)
+	}
+	if _, err := VerifyTaskPayload(&SignedTask{}, bytes.NewReader(payload)); err == nil {
+		t.Fatal("expected attached envelope to be refused")
+	}
+}
+
+func TestVerifyTaskPayload_Timestamp(t *testing.T) {
+	tsa := newTestTSA(t)
+	st, payload, _ := signDetached(t)
+	if err := TimestampTask(context.Background(), st, tsa); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyTaskPayload(st, bytes.NewReader(payload)); err != nil {
+		t.Fatalf("timestamped envelope: %v", err)
+	}
+	tsa.now = time.Now().Add(20 * time.Minute)
+	if err := TimestampTask(context.Background(), st, tsa); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyTaskPayload(st, bytes.NewReader(payload)); !errors.Is(err, ErrBadTimestamp) {
+		t.Fatalf("timestamped after expiry: got %v", err)
+	}
+}
+
+func TestVerifyTask_DetachedNeedsPayload(t *testing.T) {
+	st, payload, _ := signDetached(t)
+	if err := VerifyTask(st); err == nil || !strings.Contains(err.Error(), "VerifyTaskPayload") {
+		t.Fatalf("envelope alone: got %v", err)
+	}
+
+	var task Task
+	if err := json.Unmarshal(payload, &task); err != nil {
+		t.Fatal(err)
+	}
+	task.Operator = "op-mallory"
+	swapped := *st
+	swapped.Task = task
+	if err := VerifyTask(&swapped); err == nil {
+		t.Fatal("expected a task that does not match the digest to fail")
+	}
+}
+
+func TestCountersign_Detached(t *testing.T) {
+	st, payload, s := signDetached(t)
+	if err := CountersignContext(context.Background(), st, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := VerifyTaskPayload(st, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyApproval(got); err != nil {
+		t.Fatalf("approval over the envelope should hold once attached: %v", err)
+	}
+
+	data, _ := json.Marshal(st)
+	if _, err := UpgradeSignedTask(data); err == nil {
+		t.Fatal("expected detached envelope to be refused by UpgradeSignedTask")
+	}
+}
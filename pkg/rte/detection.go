@@ -0,0 +1,170 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const defaultDetectionPoll = 30 * time.Second
+
+// ExpectedDetection names an alert a task should trigger and how to find it.
+type ExpectedDetection struct {
+	// Technique is the technique the detection covers, such as "T1110.003".
+	Technique string `json:"technique"`
+	// Rule is the SIEM rule or analytic name.
+	Rule string `json:"rule"`
+	// Query is the SIEM confirmation query, in the SIEM's own language.
+	Query string `json:"query,omitempty"`
+}
+
+// SIEMQuerier runs a confirmation query and returns the time of the
+// earliest matching alert raised at or after since.
+type SIEMQuerier interface {
+	FindAlert(ctx context.Context, task Task, d ExpectedDetection, since time.Time) (alertAt time.Time, found bool, err error)
+}
+
+// SIEMQuerierFunc adapts a function to SIEMQuerier.
+type SIEMQuerierFunc func(ctx context.Context, task Task, d ExpectedDetection, since time.Time) (time.Time, bool, error)
+
+// FindAlert calls f.
+func (f SIEMQuerierFunc) FindAlert(ctx context.Context, task Task, d ExpectedDetection, since time.Time) (time.Time, bool, error) {
+	return f(ctx, task, d, since)
+}
+
+// DetectionRecord is the measured outcome of one expected detection.
+type DetectionRecord struct {
+	TaskID     string        `json:"task_id"`
+	Engagement string        `json:"engagement"`
+	Technique  string        `json:"technique"`
+	Rule       string        `json:"rule"`
+	EmittedAt  time.Time     `json:"emitted_at"`
+	Detected   bool          `json:"detected"`
+	AlertedAt  time.Time     `json:"alerted_at,omitempty"`
+	Latency    time.Duration `json:"latency_ns,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// DetectionConfirmer polls the SIEM for a finished task's expected
+// detections and records emit-to-alert latency.
+type DetectionConfirmer struct {
+	SIEM SIEMQuerier
+	// Interval between confirmation queries. Defaults to 30s.
+	Interval time.Duration
+	// Metrics, if set, records latencies and misses per technique.
+	Metrics *Metrics
+}
+
+// Confirm queries until every expected detection is found or ctx is done;
+// detections still missing then are recorded as not detected. The emit time
+// is when the task started executing.
+func (c *DetectionConfirmer) Confirm(ctx context.Context, task Task, res *TaskResult) ([]DetectionRecord, error) {
+	if c.SIEM == nil {
+		return nil, errors.New("SIEM querier is required")
+	}
+	if res == nil || res.TaskID != task.ID {
+		return nil, errors.New("result does not belong to task")
+	}
+	records := make([]DetectionRecord, len(task.ExpectedDetections))
+	for i, d := range task.ExpectedDetections {
+		records[i] = DetectionRecord{
+			TaskID: task.ID, Engagement: task.Engagement,
+			Technique: d.Technique, Rule: d.Rule, EmittedAt: res.StartedAt,
+		}
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultDetectionPoll
+	}
+	for {
+		pending := 0
+		for i, d := range task.ExpectedDetections {
+			if records[i].Detected {
+				continue
+			}
+			at, found, err := c.SIEM.FindAlert(ctx, task, d, res.StartedAt)
+			if err != nil {
+				records[i].Error = err.Error()
+			} else if found {
+				records[i].Detected, records[i].AlertedAt, records[i].Error = true, at.UTC(), ""
+				records[i].Latency = at.Sub(res.StartedAt)
+				c.Metrics.DetectionObserved(d.Technique, records[i].Latency)
+				continue
+			}
+			pending++
+		}
+		if pending == 0 {
+			return records, nil
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			for _, r := range records {
+				if !r.Detected {
+					c.Metrics.DetectionMissed(r.Technique)
+				}
+			}
+			return records, nil
+		case <-timer.C:
+		}
+	}
+}
+
+// LatencySummary aggregates detection latency for one technique.
+type LatencySummary struct {
+	Technique string        `json:"technique"`
+	Expected  int           `json:"expected"`
+	Detected  int           `json:"detected"`
+	P50       time.Duration `json:"p50_ns"`
+	P90       time.Duration `json:"p90_ns"`
+	P95       time.Duration `json:"p95_ns"`
+	P99       time.Duration `json:"p99_ns"`
+	Max       time.Duration `json:"max_ns"`
+}
+
+// SummarizeDetectionLatency groups records by technique and computes
+// nearest-rank latency percentiles over the detected ones, sorted by
+// technique.
+func SummarizeDetectionLatency(records []DetectionRecord) []LatencySummary {
+	byTech := make(map[string][]DetectionRecord)
+	for _, r := range records {
+		byTech[r.Technique] = append(byTech[r.Technique], r)
+	}
+	out := make([]LatencySummary, 0, len(byTech))
+	for tech, rs := range byTech {
+		s := LatencySummary{Technique: tech, Expected: len(rs)}
+		var lat []time.Duration
+		for _, r := range rs {
+			if r.Detected {
+				lat = append(lat, r.Latency)
+			}
+		}
+		s.Detected = len(lat)
+		if len(lat) > 0 {
+			sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+			s.P50, s.P90, s.P95, s.P99 = percentile(lat, 50), percentile(lat, 90), percentile(lat, 95), percentile(lat, 99)
+			s.Max = lat[len(lat)-1]
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Technique < out[j].Technique })
+	return out
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// String renders a one-line summary for reports and logs.
+func (s LatencySummary) String() string {
+	return fmt.Sprintf("%s: %d/%d detected, p50 %s, p95 %s, max %s",
+		s.Technique, s.Detected, s.Expected, s.P50, s.P95, s.Max)
+}
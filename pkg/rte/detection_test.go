@@ -0,0 +1,107 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/metrics"
+)
+
+func TestDetectionConfirmer_Confirm(t *testing.T) {
+	start := time.Now().UTC()
+	task := validTask(start)
+	task.ExpectedDetections = []ExpectedDetection{
+		{Technique: "T1110.003", Rule: "password-spray"},
+		{Technique: "T1078", Rule: "impossible-travel"},
+		{Technique: "T1071.001", Rule: "beacon-periodicity"},
+	}
+	res := &TaskResult{TaskID: task.ID, StartedAt: start}
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	siem := SIEMQuerierFunc(func(_ context.Context, _ Task, d ExpectedDetection, since time.Time) (time.Time, bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[d.Rule]++
+		switch d.Rule {
+		case "password-spray":
+			return since.Add(90 * time.Second), true, nil
+		case "impossible-travel":
+			// Found on the second poll.
+			return since.Add(10 * time.Minute), calls[d.Rule] > 1, nil
+		default:
+			return time.Time{}, false, errors.New("siem timeout")
+		}
+	})
+	reg := metrics.NewRegistry()
+	c := &DetectionConfirmer{SIEM: siem, Interval: 10 * time.Millisecond, Metrics: NewMetrics(reg)}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	records, err := c.Confirm(ctx, task, res)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if !records[0].Detected || records[0].Latency != 90*time.Second {
+		t.Errorf("unexpected spray record %+v", records[0])
+	}
+	if !records[1].Detected || calls["password-spray"] != 1 {
+		t.Errorf("confirmed detections should not be re-queried: %+v %v", records[1], calls)
+	}
+	if records[2].Detected || records[2].Error != "siem timeout" {
+		t.Errorf("unexpected missed record %+v", records[2])
+	}
+
+	var b strings.Builder
+	_, _ = reg.WriteTo(&b)
+	for _, want := range []string{
+		`rte_detection_latency_seconds_bucket{technique="T1110.003",le="300"} 1`,
+		`rte_detections_missed_total{technique="T1071.001"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+func TestDetectionConfirmer_Invalid(t *testing.T) {
+	task := validTask(time.Now())
+	if _, err := (&DetectionConfirmer{}).Confirm(context.Background(), task, &TaskResult{TaskID: task.ID}); err == nil {
+		t.Error("expected missing SIEM to fail")
+	}
+	c := &DetectionConfirmer{SIEM: SIEMQuerierFunc(func(context.Context, Task, ExpectedDetection, time.Time) (time.Time, bool, error) {
+		return time.Time{}, false, nil
+	})}
+	if _, err := c.Confirm(context.Background(), task, &TaskResult{TaskID: "other"}); err == nil {
+		t.Error("expected mismatched result to fail")
+	}
+}
+
+func TestSummarizeDetectionLatency(t *testing.T) {
+	var records []DetectionRecord
+	for i := 1; i <= 20; i++ {
+		records = append(records, DetectionRecord{Technique: "T1110", Detected: true, Latency: time.Duration(i) * time.Second})
+	}
+	records = append(records,
+		DetectionRecord{Technique: "T1110"},
+		DetectionRecord{Technique: "T1048"},
+	)
+	got := SummarizeDetectionLatency(records)
+	if len(got) != 2 || got[0].Technique != "T1048" {
+		t.Fatalf("unexpected summaries %+v", got)
+	}
+	s := got[1]
+	if s.Expected != 21 || s.Detected != 20 || s.P50 != 10*time.Second || s.P90 != 18*time.Second ||
+		s.P95 != 19*time.Second || s.P99 != 20*time.Second || s.Max != 20*time.Second {
+		t.Fatalf("unexpected percentiles %+v", s)
+	}
+	if got[0].Detected != 0 || got[0].P50 != 0 {
+		t.Fatalf("undetected technique should have no latency: %+v", got[0])
+	}
+	if !strings.HasPrefix(s.String(), "T1110: 20/21 detected, p50 10s") {
+		t.Errorf("unexpected String %q", s.String())
+	}
+}
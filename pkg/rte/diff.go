@@ -0,0 +1,123 @@
+package rte
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldDiff is one field that differs between two tasks, rendered for
+// display. Field is the path of the field in the task's JSON, such as
+// ttl_seconds or params.target; From and To are its values, strings as
+// is and anything else as JSON. Added and Removed mark a field only the
+// second or only the first task has, whose From or To is then "".
+type FieldDiff struct {
+	Field   string `json:"field"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Added   bool   `json:"added,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+}
+
+// String renders d as field: "from" -> "to", or field: added "to" or
+// field: removed "from" for a field one side lacks.
+func (d FieldDiff) String() string {
+	switch {
+	case d.Added:
+		return fmt.Sprintf("%s: added %q", d.Field, d.To)
+	case d.Removed:
+		return fmt.Sprintf("%s: removed %q", d.Field, d.From)
+	}
+	return fmt.Sprintf("%s: %q -> %q", d.Field, d.From, d.To)
+}
+
+// Diff lists the fields that differ between a and b, sorted by field. It
+// compares the tasks as they are signed, so any change that breaks a
+// signature shows up.
+func Diff(a, b Task) []FieldDiff {
+	from, to := flattenTask(a), flattenTask(b)
+	fields := make([]string, 0, len(from)+len(to))
+	for f := range from {
+		fields = append(fields, f)
+	}
+	for f := range to {
+		if _, ok := from[f]; !ok {
+			fields = append(fields, f)
+		}
+	}
+	sort.Strings(fields)
+	var diffs []FieldDiff
+	for _, f := range fields {
+		a, inFrom := from[f]
+		b, inTo := to[f]
+		if inFrom != inTo || a != b {
+			diffs = append(diffs, FieldDiff{Field: f, From: a, To: b, Added: !inFrom, Removed: !inTo})
+		}
+	}
+	return diffs
+}
+
+// flattenTask maps each leaf of t's JSON encoding to its rendered value.
+// Objects are walked; arrays are leaves.
+func flattenTask(t Task) map[string]string {
+	out := make(map[string]string)
+	data, err := json.Marshal(t)
+	if err != nil {
+		return out
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return out
+	}
+	flattenJSON(out, "", v)
+	return out
+}
+
+func flattenJSON(out map[string]string, path string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if path != "" {
+				k = path + "." + k
+			}
+			flattenJSON(out, k, e)
+		}
+	case string:
+		out[path] = v
+	default:
+		b, _ := json.Marshal(v)
+		out[path] = string(b)
+	}
+}
+
+// TamperError reports a signed task that differs from a reference copy of
+// what was signed, field by field.
+type TamperError struct {
+	TaskID string
+	Diffs  []FieldDiff
+}
+
+func (e *TamperError) Error() string {
+	fields := make([]string, len(e.Diffs))
+	for i, d := range e.Diffs {
+		fields[i] = d.String()
+	}
+	return fmt.Sprintf("task %s differs from what was signed: %s", e.TaskID, strings.Join(fields, "; "))
+}
+
+// CheckSigned returns a *TamperError if st's task differs from signed, the
+// task as its signer saw it, such as a copy kept at signing or approval.
+func CheckSigned(st *SignedTask, signed Task) error {
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	if diffs := Diff(signed, st.Task); len(diffs) > 0 {
+		return &TamperError{TaskID: st.Task.ID, Diffs: diffs}
+	}
+	return nil
+}
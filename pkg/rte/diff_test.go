@@ -0,0 +1,68 @@
+package rte
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	a := validTask(now)
+	if diffs := Diff(a, a); len(diffs) != 0 {
+		t.Fatalf("identical tasks: %+v", diffs)
+	}
+	b := validTask(now)
+	b.TTLSeconds = 3600
+	b.Params = map[string]string{"target": "10.0.0.0/8", "attempts": "50"}
+	b.Techniques = []string{"T1110"}
+	want := []FieldDiff{
+		{Field: "params.attempts", To: "50", Added: true},
+		{Field: "params.target", From: "192.168.1.0/24", To: "10.0.0.0/8"},
+		{Field: "techniques", To: `["T1110"]`, Added: true},
+		{Field: "ttl_seconds", From: "600", To: "3600"},
+	}
+	got := Diff(a, b)
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diff %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// An empty value is still a value: adding or removing it is a change.
+	bare, empty := validTask(now), validTask(now)
+	bare.Params = nil
+	empty.Params = map[string]string{"x": ""}
+	got = Diff(bare, empty)
+	if len(got) != 1 || got[0] != (FieldDiff{Field: "params.x", Added: true}) {
+		t.Fatalf("param set to empty: %+v", got)
+	}
+	if got[0].String() != `params.x: added ""` {
+		t.Errorf("String = %s", got[0])
+	}
+	got = Diff(empty, bare)
+	if len(got) != 1 || got[0] != (FieldDiff{Field: "params.x", Removed: true}) {
+		t.Fatalf("empty param removed: %+v", got)
+	}
+}
+
+func TestCheckSigned(t *testing.T) {
+	st := signedValidTask(t)
+	if err := CheckSigned(st, st.Task); err != nil {
+		t.Fatal(err)
+	}
+	signed := st.Task
+	st.Task.ApprovedBy = "lead-mallory"
+	err := CheckSigned(st, signed)
+	var te *TamperError
+	if !errors.As(err, &te) || len(te.Diffs) != 1 || te.Diffs[0].Field != "approved_by" {
+		t.Fatalf("CheckSigned = %v", err)
+	}
+	if !strings.Contains(err.Error(), `approved_by: "lead-bob" -> "lead-mallory"`) {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DryRunPlanType is the in-toto predicate type of a signed DryRunPlan.
+const DryRunPlanType = "https://github.com/codethor0/rte-a-reference/dry-run-plan/v1"
+
+// StepAction is the kind of thing a PlannedStep does.
+type StepAction string
+
+// Planned step actions.
+const (
+	// StepConnect contacts an endpoint: a login attempt, a callback, an
+	// upload, or a message sent through a relay.
+	StepConnect StepAction = "connect"
+	// StepEmit generates events or artifacts and delivers them.
+	StepEmit StepAction = "emit"
+	// StepRun runs a command or collector on the agent's host.
+	StepRun StepAction = "run"
+)
+
+// PlannedStep is one thing a handler would do for a task.
+type PlannedStep struct {
+	Action StepAction `json:"action"`
+	// Target is the endpoint, sink, or command the step uses.
+	Target string `json:"target,omitempty"`
+	// Detail says what the step sends or does, in words a customer can
+	// approve.
+	Detail string `json:"detail"`
+	// Count is how many times the step repeats; 0 means once.
+	Count int `json:"count,omitempty"`
+}
+
+// Planner is implemented by handlers that can run tasks with DryRun set:
+// Plan checks the task's params as Handle would and describes every step
+// Handle would take, without contacting anything or changing the host.
+type Planner interface {
+	Handler
+	Plan(ctx context.Context, task Task) ([]PlannedStep, error)
+}
+
+// DryRunPlan is what a dry run produces: the steps the task would take on
+// the agent that planned it.
+type DryRunPlan struct {
+	TaskID     string        `json:"task_id"`
+	Engagement string        `json:"engagement"`
+	Type       TaskType      `json:"type"`
+	PlannedAt  time.Time     `json:"planned_at"`
+	Steps      []PlannedStep `json:"steps"`
+}
+
+// DryRunOutput is the output of a dry run's result. Attestation is the
+// plan signed by the executor's PlanSigner, for the customer to approve
+// before the task runs for real.
+type DryRunOutput struct {
+	Plan        DryRunPlan `json:"plan"`
+	Attestation *Envelope  `json:"attestation,omitempty"`
+}
+
+// SignDryRunPlan signs plan as an in-toto statement about st, so an
+// approval of the envelope cannot be moved to another task.
+func SignDryRunPlan(ctx context.Context, st *SignedTask, plan DryRunPlan, s Signer) (*Envelope, error) {
+	subject, err := TaskSubject(st)
+	if err != nil {
+		return nil, err
+	}
+	return Attest(ctx, s, subject, DryRunPlanType, plan)
+}
+
+// VerifyDryRunPlan checks that pub signed env as a dry-run plan of st and
+// returns the plan.
+func VerifyDryRunPlan(env *Envelope, pub ed25519.PublicKey, st *SignedTask) (*DryRunPlan, error) {
+	stmt, err := VerifyEnvelope(env, pub)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.PredicateType != DryRunPlanType {
+		return nil, fmt.Errorf("attestation is a %q, not a dry-run plan", stmt.PredicateType)
+	}
+	subject, err := TaskSubject(st)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmt.Subject) != 1 || stmt.Subject[0].Digest["sha256"] != subject.Digest["sha256"] {
+		return nil, fmt.Errorf("dry-run plan is not about %s", subject.Name)
+	}
+	var plan DryRunPlan
+	if err := json.Unmarshal(stmt.Predicate, &plan); err != nil {
+		return nil, fmt.Errorf("decode dry-run plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// dryRun plans st with h and signs the plan if the executor has a
+// PlanSigner.
+func (e *Executor) dryRun(ctx context.Context, st *SignedTask, h Handler, at time.Time) (*DryRunOutput, error) {
+	p, ok := h.(Planner)
+	if !ok {
+		return nil, fmt.Errorf("%s handler does not support dry runs", st.Task.Type)
+	}
+	steps, err := p.Plan(ctx, st.Task)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("handler planned no steps")
+	}
+	out := &DryRunOutput{Plan: DryRunPlan{
+		TaskID: st.Task.ID, Engagement: st.Task.Engagement, Type: st.Task.Type,
+		PlannedAt: at.UTC(), Steps: steps,
+	}}
+	if e.PlanSigner != nil {
+		if out.Attestation, err = SignDryRunPlan(ctx, st, out.Plan, e.PlanSigner); err != nil {
+			return nil, fmt.Errorf("sign dry-run plan: %w", err)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,94 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type planningHandler struct{ handled bool }
+
+func (h *planningHandler) Handle(context.Context, Task) (any, error) {
+	h.handled = true
+	return nil, nil
+}
+
+func (h *planningHandler) Plan(_ context.Context, task Task) ([]PlannedStep, error) {
+	return []PlannedStep{{Action: StepConnect, Target: task.Params["target"], Detail: "read the SSH banner", Count: 2}}, nil
+}
+
+func TestExecutor_DryRun(t *testing.T) {
+	agent := newKeyPair(t)
+	signer, err := NewKeySigner(agent.priv, agent.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &planningHandler{}
+	e := NewExecutor()
+	e.Deconfliction = NewDeconflictionRegistry()
+	e.PlanSigner = signer
+	_ = e.Register(TaskSimulateLogin, h)
+
+	op := newKeyPair(t)
+	task := validTask(time.Now().UTC())
+	task.DryRun = true
+	st, err := SignTask(task, op.priv, op.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := e.Execute(context.Background(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.State != StateCompleted || h.handled {
+		t.Fatalf("state %s, handled %v", res.State, h.handled)
+	}
+	var out DryRunOutput
+	if err := json.Unmarshal(res.Output, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Plan.Steps) != 1 || out.Plan.Steps[0].Target != "192.168.1.0/24" || out.Attestation == nil {
+		t.Fatalf("output = %+v", out)
+	}
+	plan, err := VerifyDryRunPlan(out.Attestation, agent.pub, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.TaskID != "task-001" || plan.Steps[0].Count != 2 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if got, _ := e.Deconfliction.Touched("192.168.1.5", res.StartedAt, time.Minute); len(got) != 0 {
+		t.Fatalf("dry run registered activity: %+v", got)
+	}
+
+	other := task
+	other.ID = "task-002"
+	st2, _ := SignTask(other, op.priv, op.pub)
+	if _, err := VerifyDryRunPlan(out.Attestation, agent.pub, st2); err == nil || !strings.Contains(err.Error(), "not about") {
+		t.Fatalf("plan moved to another task: got %v", err)
+	}
+	if _, err := VerifyDryRunPlan(out.Attestation, op.pub, st); err == nil {
+		t.Fatal("expected wrong key to fail")
+	}
+}
+
+func TestExecutor_DryRunNeedsPlanner(t *testing.T) {
+	e := NewExecutor()
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) {
+		t.Error("handler ran during a dry run")
+		return nil, nil
+	}))
+	op := newKeyPair(t)
+	task := validTask(time.Now().UTC())
+	task.DryRun = true
+	st, _ := SignTask(task, op.priv, op.pub)
+	res, err := e.Execute(context.Background(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.State != StateFailed || !strings.Contains(res.Error, "does not support dry runs") {
+		t.Fatalf("result = %+v", res)
+	}
+}
@@ -0,0 +1,567 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/tracing"
+)
+
+// Handler performs the work for one task type. The returned output is
+// JSON-encoded into the TaskResult. Handlers must honor ctx, which is
+// cancelled when the task's TTL expires.
+type Handler interface {
+	Handle(ctx context.Context, task Task) (any, error)
+}
+
+// HandlerFunc adapts an ordinary function to Handler.
+type HandlerFunc func(ctx context.Context, task Task) (any, error)
+
+// Handle calls f(ctx, task).
+func (f HandlerFunc) Handle(ctx context.Context, task Task) (any, error) {
+	return f(ctx, task)
+}
+
+// TaskResult records the outcome of executing a task.
+type TaskResult struct {
+	TaskID     string          `json:"task_id"`
+	Engagement string          `json:"engagement"`
+	Type       TaskType        `json:"type"`
+	Operator   string          `json:"operator"`
+	State      TaskState       `json:"state"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Output     json.RawMessage `json:"output,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// Executor verifies signed tasks and dispatches them to registered handlers,
+// bounding each run by the task's TTL. It is safe for concurrent use.
+type Executor struct {
+	// Verify checks a signed task before execution. Defaults to VerifyTask;
+	// set it to an IdentityRegistry's VerifySignedTask to enforce roles.
+	Verify func(*SignedTask) error
+	// Pins, if set, are the controller keys this agent trusts. A task
+	// signed by any other key is rejected before Verify runs.
+	Pins *KeyPins
+	// Identities, if set, are the engagements' enrolled identities. A
+	// task's approval countersignature is trusted to authorize a standing
+	// run or an approval window, or to sign its halts, pauses,
+	// attestations, and extensions, only once it verifies and its key is
+	// in Pins or enrolled as the task's approver with approver rights.
+	Identities *IdentityRegistry
+	// Metrics, if set, records verification and execution outcomes.
+	Metrics *Metrics
+	// Tracer, if set, records rte.execute, rte.verify, and rte.handle spans
+	// as children of the trace context carried in the signed task.
+	Tracer *tracing.Tracer
+	// Logger, if set, receives one line per rejection and per finished run,
+	// tagged with the task's correlation keys.
+	Logger *slog.Logger
+	// Deconfliction, if set, registers each run's targets and time window
+	// so SOC responders can check activity against it.
+	Deconfliction *DeconflictionRegistry
+	// TargetLimits, if set, holds a task back before it starts until the
+	// limits on its targets admit it. A task still held at its expiry is
+	// rejected.
+	TargetLimits *TargetLimiter
+	// Impact, if set, holds a task back before it starts until its impact
+	// score fits its engagement's budget. A task that cannot fit before
+	// its expiry is rejected.
+	Impact *ImpactLimiter
+	// PlanSigner, if set, signs the plan of each dry run; see
+	// DryRunOutput.
+	PlanSigner Signer
+	// VerifyHalt checks a signed halt before it takes effect. Defaults to
+	// VerifyHalt plus a check that the halt is signed by a key in Pins or
+	// by the trusted approver key of one of the engagement's running tasks
+	// (see Identities); set it to an IdentityRegistry's VerifyHalt to
+	// accept any lead's enrolled key instead.
+	VerifyHalt func(*SignedHalt) error
+	// VerifyPause checks a signed pause or resume request. Defaults to
+	// VerifyPauseRequest plus a check that the request is signed by a key
+	// in Pins or by the task's trusted approver key (see Identities); set
+	// it to an IdentityRegistry's VerifyPauseRequest to accept any lead's
+	// enrolled key instead.
+	VerifyPause func(*SignedPauseRequest) error
+	// VerifyAttestation checks a signed standing attestation. Defaults to
+	// VerifyStandingAttestation plus a check that the attestation is
+	// signed by the task's trusted approver key (see Identities); set it to an
+	// IdentityRegistry's VerifyStandingAttestation to accept any lead's
+	// enrolled key instead.
+	VerifyAttestation func(*SignedStandingAttestation) error
+	// StandingWindow is how long a standing run may go before its first
+	// attestation. Defaults to DefaultStandingWindow.
+	StandingWindow time.Duration
+	// OnTransition, if set, is called after each state change of a running
+	// task, outside the executor's locks. It must not block; hand slow work
+	// such as ticket updates to another goroutine.
+	OnTransition func(Transition)
+	// OnClockAnomaly, if set, is called when the executor sees the wall
+	// clock step against the monotonic clock. After a backward step the
+	// executor keeps time from the monotonic clock, so tasks that had
+	// expired stay expired. It must not block.
+	OnClockAnomaly func(ClockAnomaly)
+	// CleanupSigner and OnCleanup, if both set, let handlers register
+	// cleanup actions with AddCleanup. Each action becomes a cleanup task
+	// signed by CleanupSigner and passed to OnCleanup, which must not
+	// block; queue it for an agent whose Pins trust CleanupSigner.
+	CleanupSigner Signer
+	OnCleanup     func(*SignedTask)
+
+	mu       sync.RWMutex
+	handlers map[TaskType]Handler
+	running  map[*run]struct{}
+	halted   map[string]EngagementHalt
+	held     map[string][]pendingCleanup
+	clock    clockState
+}
+
+// run is one in-flight Execute call. done is closed once res is final.
+// state, expiry, deadline, timer, extended, and applied are guarded by
+// Executor.mu. expiry is the wall-clock expiry reported to callers;
+// deadline is the same instant on the monotonic clock, which the timer
+// follows so wall-clock steps cannot shorten or stretch a run.
+type run struct {
+	engagement string
+	taskID     string
+	token      string
+	signed     *SignedTask
+	handler    Handler
+	cancel     context.CancelCauseFunc
+	done       chan struct{}
+	res        *TaskResult
+
+	state    TaskState
+	expiry   time.Time
+	deadline time.Time
+	timer    *time.Timer
+	extended int
+	applied  map[string]struct{}
+	// lapse stops a standing run when its attestation runs out; attested
+	// is when the last applied attestation was issued.
+	lapse    *time.Timer
+	attested time.Time
+}
+
+// NewExecutor returns an executor with no handlers registered.
+func NewExecutor() *Executor {
+	return &Executor{
+		handlers: make(map[TaskType]Handler),
+		running:  make(map[*run]struct{}),
+		halted:   make(map[string]EngagementHalt),
+		held:     make(map[string][]pendingCleanup),
+	}
+}
+
+// Register installs the handler for a task type, replacing any previous one.
+func (e *Executor) Register(tt TaskType, h Handler) error {
+	if _, ok := allowedTaskTypes[tt]; !ok {
+		return fmt.Errorf("unsupported task type: %s", tt)
+	}
+	if h == nil {
+		return errors.New("handler is nil")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers[tt] = h
+	return nil
+}
+
+// Execute verifies st and runs its handler until completion or TTL expiry.
+// An error is returned only if the task is rejected before running; handler
+// failures are reported in the result with StateFailed. A cleanup task runs
+// its handler's Cleanup instead of Handle.
+func (e *Executor) Execute(ctx context.Context, st *SignedTask) (*TaskResult, error) {
+	verify := e.Verify
+	if verify == nil {
+		verify = VerifyTask
+	}
+	var task Task
+	if st != nil {
+		task = st.Task
+	}
+	ctx, span := StartTaskSpan(ExtractTrace(ctx, st), e.Tracer, "rte.execute", task)
+	defer span.End()
+	log := TaskLogger(e.Logger, task)
+
+	_, vspan := StartTaskSpan(ctx, e.Tracer, "rte.verify", task)
+	verifyStart := time.Now()
+	var err error
+	if e.Pins != nil {
+		err = e.Pins.Check(st)
+	}
+	if err == nil {
+		err = verify(st)
+	}
+	if err == nil {
+		err = e.checkApproval(st)
+	}
+	e.Metrics.TaskVerified(task.Type, time.Since(verifyStart), err)
+	vspan.RecordError(err)
+	vspan.End()
+	if err != nil {
+		span.RecordError(err)
+		log.Warn("task rejected", "stage", RejectVerify, "error", err)
+		return nil, fmt.Errorf("verify task: %w", err)
+	}
+	now := e.observeClock(task)
+	if !now.Before(task.Expiry().Add(CurrentValidatorConfig().ClockSkew)) {
+		// Verify passed on a wall clock that has stepped backward.
+		e.Metrics.TaskRejected(task.Type, RejectClock)
+		err := fmt.Errorf("%w at %s (clock-adjusted now: %s)", ErrExpired, task.Expiry().UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+		span.RecordError(err)
+		log.Warn("task rejected", "stage", RejectClock, "error", err)
+		return nil, err
+	}
+	if task.State != StatePending {
+		e.Metrics.TaskRejected(task.Type, RejectState)
+		err := fmt.Errorf("task %s is %s, not pending", task.ID, task.State)
+		span.RecordError(err)
+		log.Warn("task rejected", "stage", RejectState, "error", err)
+		return nil, err
+	}
+	if e.TargetLimits != nil && !task.DryRun {
+		if wait, reason := e.TargetLimits.Wait(task, now); wait != 0 {
+			log.Info("task throttled", "reason", reason)
+		}
+		// The monotonic clock bounds the wait, as the TTL timer does.
+		wctx, cancel := context.WithTimeout(ctx, task.Expiry().Sub(now))
+		release, err := e.TargetLimits.Acquire(wctx, task)
+		cancel()
+		if err != nil {
+			e.Metrics.TaskRejected(task.Type, RejectThrottled)
+			span.RecordError(err)
+			log.Warn("task rejected", "stage", RejectThrottled, "error", err)
+			return nil, err
+		}
+		defer release()
+		now = e.observeClock(task)
+	}
+	if e.Impact != nil && !task.DryRun {
+		if wait, reason := e.Impact.Wait(task, now); wait > 0 {
+			log.Info("task delayed by impact budget", "reason", reason)
+		}
+		wctx, cancel := context.WithTimeout(ctx, task.Expiry().Sub(now))
+		err := e.Impact.Acquire(wctx, task)
+		cancel()
+		if err != nil {
+			e.Metrics.TaskRejected(task.Type, RejectOverBudget)
+			span.RecordError(err)
+			log.Warn("task rejected", "stage", RejectOverBudget, "error", err)
+			return nil, err
+		}
+		now = e.observeClock(task)
+	}
+	// The TTL is enforced by a timer rather than a context deadline so a
+	// TTLExtension can push it back while the handler runs.
+	runCtx, halt := context.WithCancelCause(ctx)
+	defer halt(nil)
+	res := &TaskResult{
+		TaskID:     task.ID,
+		Engagement: task.Engagement,
+		Type:       task.Type,
+		Operator:   task.Operator,
+	}
+	r := &run{
+		engagement: task.Engagement, taskID: task.ID, token: task.CancelToken, signed: st,
+		cancel: halt, done: make(chan struct{}), res: res, expiry: task.RunExpiry(now),
+	}
+
+	// The halt check and run registration share the lock, so a concurrent
+	// Halt either refuses this task or sees it and cancels it.
+	e.mu.Lock()
+	h, ok := e.handlers[task.Type]
+	stop, halted := e.halted[task.Engagement]
+	if ok && !halted {
+		r.handler, r.state = h, StateExecuting
+		e.running[r] = struct{}{}
+		budget := r.expiry.Sub(now)
+		r.deadline = time.Now().Add(budget)
+		r.timer = time.AfterFunc(budget, func() { halt(errTTLExpired) })
+		if task.Standing && !task.DryRun {
+			window := e.StandingWindow
+			if window <= 0 {
+				window = DefaultStandingWindow
+			}
+			r.lapse = time.AfterFunc(window, func() { halt(ErrAttestationLapsed) })
+		}
+	}
+	e.mu.Unlock()
+	if halted {
+		e.Metrics.TaskRejected(task.Type, RejectHalted)
+		err := stop.cause()
+		span.RecordError(err)
+		log.Warn("task rejected", "stage", RejectHalted, "error", err)
+		return nil, err
+	}
+	if !ok {
+		e.Metrics.TaskRejected(task.Type, RejectHandler)
+		err := fmt.Errorf("no handler registered for task type: %s", task.Type)
+		span.RecordError(err)
+		log.Warn("task rejected", "stage", RejectHandler, "error", err)
+		return nil, err
+	}
+	cleaner, _ := h.(Cleaner)
+	defer func() {
+		e.mu.Lock()
+		r.timer.Stop()
+		if r.lapse != nil {
+			r.lapse.Stop()
+		}
+		if ph, ok := h.(Pausable); ok && r.state == StatePaused {
+			// Clear the handler's gate for a run that ended while paused.
+			_ = ph.Resume(task)
+		}
+		delete(e.running, r)
+		e.mu.Unlock()
+		close(r.done)
+	}()
+
+	started := time.Now()
+	res.StartedAt = now.UTC()
+	task.State = StateExecuting
+	log.Info("task started", "params", task.Params)
+	e.notify(Transition{Task: task, From: StatePending, To: StateExecuting, At: res.StartedAt})
+	hctx, hspan := StartTaskSpan(runCtx, e.Tracer, "rte.handle", task)
+	hctx, cleanups := e.withCleanup(hctx, task)
+	var out any
+	switch {
+	case task.CleanupOf != "" && cleaner == nil:
+		err = fmt.Errorf("%s handler does not support cleanup", task.Type)
+	case task.CleanupOf != "" && !task.DryRun:
+		e.Deconfliction.Begin(task, res.StartedAt)
+		out, err = cleaner.Cleanup(hctx, task)
+	case task.DryRun:
+		// A dry run touches no target, so it is not registered for
+		// deconfliction.
+		out, err = e.dryRun(hctx, st, h, res.StartedAt)
+	default:
+		e.Deconfliction.Begin(task, res.StartedAt)
+		out, err = h.Handle(hctx, task)
+	}
+	hspan.RecordError(err)
+	hspan.End()
+	// Durations come from the monotonic clock; a step during the run is
+	// reported rather than folded into FinishedAt.
+	e.observeClock(task)
+	res.FinishedAt = res.StartedAt.Add(time.Since(started))
+	if !task.DryRun {
+		e.Deconfliction.Finish(task.Engagement, task.ID, res.FinishedAt)
+	}
+
+	switch {
+	case err == nil && runCtx.Err() == nil:
+		res.State = StateCompleted
+	case errors.Is(context.Cause(runCtx), ErrEngagementHalted), errors.Is(context.Cause(runCtx), ErrTaskCancelled),
+		errors.Is(context.Cause(runCtx), ErrAttestationLapsed):
+		res.State = StateCancelled
+		res.Error = context.Cause(runCtx).Error()
+	case errors.Is(context.Cause(runCtx), errTTLExpired):
+		e.mu.RLock()
+		expiry := r.expiry
+		e.mu.RUnlock()
+		res.State = StateFailed
+		res.Error = fmt.Sprintf("task TTL expired at %s", expiry.UTC().Format(time.RFC3339))
+	case ctx.Err() != nil:
+		res.State = StateCancelled
+		res.Error = ctx.Err().Error()
+	default:
+		res.State = StateFailed
+		res.Error = err.Error()
+	}
+	if out != nil {
+		data, merr := json.Marshal(out)
+		if merr != nil {
+			res.State = StateFailed
+			res.Error = fmt.Sprintf("marshal output: %v", merr)
+		} else {
+			res.Output = data
+		}
+	}
+	e.Metrics.TaskFinished(res)
+	span.SetAttributes(tracing.String(AttrTaskState, string(res.State)))
+	attrs := []any{"state", res.State, "duration_ms", res.FinishedAt.Sub(res.StartedAt).Milliseconds()}
+	if res.Error != "" {
+		attrs = append(attrs, "error", res.Error)
+	}
+	log.Info("task finished", attrs...)
+	e.mu.RLock()
+	from := r.state
+	e.mu.RUnlock()
+	final := *res
+	e.notify(Transition{Task: task, From: from, To: res.State, At: res.FinishedAt, Reason: res.Error, Result: &final})
+	if res.Error != "" {
+		span.RecordError(errors.New(res.Error))
+	}
+	e.issueCleanups(context.WithoutCancel(ctx), task, cleanups)
+	return res, nil
+}
+
+// Halt verifies a signed engagement halt, then refuses every later task for
+// the engagement and cancels those in flight. It waits for cancelled
+// handlers to return, or ctx to end, and returns a halt confirmation result
+// for each task that was running. Halting an engagement twice is harmless.
+func (e *Executor) Halt(ctx context.Context, sh *SignedHalt) ([]TaskResult, error) {
+	verify := e.VerifyHalt
+	if verify == nil {
+		verify = e.verifyHalt
+	}
+	if err := verify(sh); err != nil {
+		return nil, fmt.Errorf("verify halt: %w", err)
+	}
+	stop := sh.Halt
+	cause := stop.cause()
+	e.mu.Lock()
+	if _, ok := e.halted[stop.Engagement]; !ok {
+		e.halted[stop.Engagement] = stop
+	}
+	var runs []*run
+	for r := range e.running {
+		if r.engagement == stop.Engagement {
+			runs = append(runs, r)
+		}
+	}
+	e.mu.Unlock()
+	if e.Logger != nil {
+		e.Logger.Warn("engagement halted", "engagement", stop.Engagement, "issued_by", stop.IssuedBy,
+			"reason", stop.Reason, "in_flight", len(runs))
+	}
+
+	for _, r := range runs {
+		r.cancel(cause)
+	}
+	results := make([]TaskResult, 0, len(runs))
+	for _, r := range runs {
+		select {
+		case <-r.done:
+			results = append(results, *r.res)
+		case <-ctx.Done():
+			return results, fmt.Errorf("waiting for halted tasks: %w", ctx.Err())
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].TaskID < results[j].TaskID })
+	return results, nil
+}
+
+// Halted reports whether the engagement has been halted.
+func (e *Executor) Halted(engagement string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	_, ok := e.halted[engagement]
+	return ok
+}
+
+// findRun returns the in-flight run of a task, or nil. Callers hold e.mu.
+func (e *Executor) findRun(engagement, taskID string) *run {
+	for r := range e.running {
+		if r.engagement == engagement && r.taskID == taskID {
+			return r
+		}
+	}
+	return nil
+}
+
+// approverKey returns the key that countersigned st if the countersignature
+// verifies and the key is in e.Pins or enrolled in e.Identities as the
+// task's approver with approver rights, and nil otherwise.
+func (e *Executor) approverKey(st *SignedTask) ed25519.PublicKey {
+	if st == nil || st.Approval == nil || VerifyApproval(st) != nil {
+		return nil
+	}
+	pub := ed25519.PublicKey(st.Approval.PublicKey)
+	if e.Pins.Pinned(pub) {
+		return pub
+	}
+	if e.Identities != nil {
+		id, ok := e.Identities.Lookup(st.Task.Engagement, st.Task.ApprovedBy)
+		if ok && id.CanApprove() && bytes.Equal(id.PublicKey, pub) {
+			return pub
+		}
+	}
+	return nil
+}
+
+// Cancel stops a running task. The caller must present the task's
+// CancelToken; tasks issued without one cannot be cancelled this way. It
+// returns once the handler has returned or ctx ends.
+func (e *Executor) Cancel(ctx context.Context, c TaskCancel) (*TaskResult, error) {
+	if c.Token == "" {
+		return nil, errors.New("cancel token is required")
+	}
+	e.mu.RLock()
+	target := e.findRun(c.Engagement, c.TaskID)
+	e.mu.RUnlock()
+	if target == nil {
+		return nil, fmt.Errorf("task %s is not running in %s", c.TaskID, c.Engagement)
+	}
+	if target.token == "" || subtle.ConstantTimeCompare([]byte(target.token), []byte(c.Token)) != 1 {
+		return nil, errors.New("cancel token does not match")
+	}
+	target.cancel(c.cause())
+	select {
+	case <-target.done:
+		res := *target.res
+		return &res, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for cancelled task: %w", ctx.Err())
+	}
+}
+
+// Extend applies a TTL extension to a running task, pushing back the
+// deadline its handler runs under. The extension must verify against the
+// task's approver countersignature, whose key must be trusted (see
+// Identities), and arrive before the current expiry; re-sending an
+// applied extension is a no-op. It returns the new expiry.
+func (e *Executor) Extend(sx *SignedTTLExtension) (time.Time, error) {
+	if sx == nil {
+		return time.Time{}, errors.New("signed extension is nil")
+	}
+	x := sx.Extension
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	target := e.findRun(x.Engagement, x.TaskID)
+	if target == nil {
+		return time.Time{}, fmt.Errorf("task %s is not running in %s", x.TaskID, x.Engagement)
+	}
+	if err := VerifyTTLExtension(sx, target.signed); err != nil {
+		return time.Time{}, fmt.Errorf("verify extension: %w", err)
+	}
+	if e.approverKey(target.signed) == nil {
+		return time.Time{}, errors.New("verify extension: the task's approver key is not pinned or enrolled")
+	}
+	if _, ok := target.applied[x.ID]; ok {
+		return target.expiry, nil
+	}
+	if limit := CurrentValidatorConfig().MaxTTLExtensionSeconds; target.extended+x.ExtendSeconds > limit {
+		return time.Time{}, fmt.Errorf("extensions would total %ds, more than the %ds allowed",
+			target.extended+x.ExtendSeconds, limit)
+	}
+	expiry := target.expiry.Add(time.Duration(x.ExtendSeconds) * time.Second)
+	if !target.timer.Stop() {
+		return time.Time{}, fmt.Errorf("task %s already expired at %s", x.TaskID, target.expiry.UTC().Format(time.RFC3339))
+	}
+	target.deadline = target.deadline.Add(time.Duration(x.ExtendSeconds) * time.Second)
+	target.timer.Reset(time.Until(target.deadline))
+	if target.applied == nil {
+		target.applied = make(map[string]struct{})
+	}
+	target.applied[x.ID] = struct{}{}
+	target.extended += x.ExtendSeconds
+	target.expiry = expiry
+	e.Deconfliction.Extend(x.Engagement, x.TaskID, expiry)
+	if e.Logger != nil {
+		TaskLogger(e.Logger, target.signed.Task).Info("task extended", "extension", x.ID,
+			"approved_by", x.ApprovedBy, "expires_at", expiry.UTC().Format(time.RFC3339))
+	}
+	return expiry, nil
+}
@@ -0,0 +1,104 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func signedValidTask(t testing.TB) *SignedTask {
+	t.Helper()
+	pub, priv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("generate key pair: %v", err)
+	}
+	st, err := SignTask(validTask(time.Now().UTC()), priv, pub)
+	if err != nil {
+		t.Fatalf("SignTask: %v", err)
+	}
+	return st
+}
+
+func TestExecutor_Execute_Completed(t *testing.T) {
+	e := NewExecutor()
+	err := e.Register(TaskSimulateLogin, HandlerFunc(func(ctx context.Context, task Task) (any, error) {
+		if task.State != StateExecuting {
+			t.Errorf("handler saw state %s", task.State)
+		}
+		return map[string]int{"attempts": 1}, nil
+	}))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	res, err := e.Execute(context.Background(), signedValidTask(t))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if res.State != StateCompleted {
+		t.Fatalf("state: got %s, want %s (%s)", res.State, StateCompleted, res.Error)
+	}
+	var out map[string]int
+	if err := json.Unmarshal(res.Output, &out); err != nil || out["attempts"] != 1 {
+		t.Errorf("output: got %s", res.Output)
+	}
+}
+
+func TestExecutor_Execute_HandlerError(t *testing.T) {
+	e := NewExecutor()
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) {
+		return nil, errors.New("target unreachable")
+	}))
+	res, err := e.Execute(context.Background(), signedValidTask(t))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if res.State != StateFailed || res.Error != "target unreachable" {
+		t.Fatalf("got state %s error %q", res.State, res.Error)
+	}
+}
+
+func TestExecutor_Execute_Cancelled(t *testing.T) {
+	e := NewExecutor()
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(ctx context.Context, _ Task) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := e.Execute(ctx, signedValidTask(t))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if res.State != StateCancelled {
+		t.Fatalf("state: got %s, want %s", res.State, StateCancelled)
+	}
+}
+
+func TestExecutor_Execute_Rejected(t *testing.T) {
+	e := NewExecutor()
+	if _, err := e.Execute(context.Background(), signedValidTask(t)); err == nil {
+		t.Fatal("expected task without handler to be rejected")
+	}
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil }))
+	st := signedValidTask(t)
+	st.Signature[0] ^= 0xff
+	if _, err := e.Execute(context.Background(), st); err == nil {
+		t.Fatal("expected tampered task to be rejected")
+	}
+	e.Verify = func(*SignedTask) error { return errors.New("not enrolled") }
+	if _, err := e.Execute(context.Background(), signedValidTask(t)); err == nil {
+		t.Fatal("expected custom verifier rejection")
+	}
+}
+
+func TestExecutor_Register_Invalid(t *testing.T) {
+	e := NewExecutor()
+	if err := e.Register(TaskType("malware"), HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil })); err == nil {
+		t.Fatal("expected unsupported type to fail")
+	}
+	if err := e.Register(TaskInventory, nil); err == nil {
+		t.Fatal("expected nil handler to fail")
+	}
+}
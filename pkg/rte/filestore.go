@@ -0,0 +1,163 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore is a TaskStore kept in one JSON file. Every change rewrites the
+// file through a temporary file and a rename, so a crash leaves either the
+// old or the new contents, never a mix, and a change whose write fails is
+// rolled back in memory too. It suits a single controller; the file is not
+// locked against other processes.
+type FileStore struct {
+	path string
+	mem  *MemoryStore
+	// mu orders writes so the file always reflects the latest change.
+	mu sync.Mutex
+	// hub reports changes once they are on disk; mem's own watchers would
+	// see changes a failed write then rolls back.
+	hub watchHub
+}
+
+// OpenFileStore opens the store at path, creating it empty if the file
+// does not exist.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, mem: NewMemoryStore()}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recs []TaskRecord
+	if err := json.Unmarshal(data, &recs); err != nil {
+		return nil, fmt.Errorf("read task store %s: %w", path, err)
+	}
+	for _, rec := range recs {
+		if err := s.mem.Put(context.Background(), rec); err != nil {
+			return nil, fmt.Errorf("read task store %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+// Path returns the file the store is kept in.
+func (s *FileStore) Path() string { return s.path }
+
+// Put implements TaskStore.
+func (s *FileStore) Put(ctx context.Context, rec TaskRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.mem.Put(ctx, rec); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.mem.remove(rec.Engagement(), rec.ID())
+		return err
+	}
+	s.hub.publish(EventCreated, rec)
+	return nil
+}
+
+// Get implements TaskStore.
+func (s *FileStore) Get(ctx context.Context, engagement, id string) (TaskRecord, error) {
+	return s.mem.Get(ctx, engagement, id)
+}
+
+// List implements TaskStore.
+func (s *FileStore) List(ctx context.Context, engagement string) ([]TaskRecord, error) {
+	return s.mem.List(ctx, engagement)
+}
+
+// Update implements TaskStore.
+func (s *FileStore) Update(ctx context.Context, engagement, id string, fn func(*TaskRecord) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.mem.Get(ctx, engagement, id)
+	if err != nil {
+		return err
+	}
+	if err := s.mem.Update(ctx, engagement, id, fn); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.mem.replace(old)
+		return err
+	}
+	if rec, err := s.mem.Get(ctx, engagement, id); err == nil {
+		s.hub.publish(EventUpdated, rec)
+	}
+	return nil
+}
+
+// UpdateWhere implements BatchStore with a single write of the file; if
+// the write fails, every change is rolled back.
+func (s *FileStore) UpdateWhere(ctx context.Context, engagement string, match func(TaskRecord) bool, fn func(*TaskRecord) error) ([]TaskRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.mem.List(ctx, engagement)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.mem.UpdateWhere(ctx, engagement, match, fn)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(); err != nil {
+		for _, rec := range old {
+			s.mem.replace(rec)
+		}
+		return nil, err
+	}
+	s.hub.publish(EventUpdated, out...)
+	return out, nil
+}
+
+// Watch implements TaskStore as MemoryStore does, reporting each change
+// once it is written to the file.
+func (s *FileStore) Watch(ctx context.Context, f WatchFilter) (<-chan TaskEvent, error) {
+	return s.hub.watch(ctx, f)
+}
+
+// Snapshot returns every record as of one instant.
+func (s *FileStore) Snapshot(ctx context.Context) ([]TaskRecord, error) {
+	return s.mem.Snapshot(ctx)
+}
+
+// save writes the store to a temporary file beside path and renames it
+// into place. Callers hold s.mu.
+func (s *FileStore) save() error {
+	recs, err := s.mem.List(context.Background(), "")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(recs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,39 @@
+package rte
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore_Persists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	if err := s.Put(ctx, storedTask(t, "t-1", now, StatePending)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(ctx, "eng-2026-q1", "t-1", func(r *TaskRecord) error {
+		r.State = StateExecuting
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := reopened.Get(ctx, "eng-2026-q1", "t-1")
+	if err != nil || rec.State != StateExecuting {
+		t.Fatalf("reopened record = %+v, %v", rec, err)
+	}
+	if err := VerifyTaskSignature(rec.Task); err != nil {
+		t.Errorf("signature after reload: %v", err)
+	}
+}
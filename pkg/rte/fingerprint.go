@@ -0,0 +1,112 @@
+package rte
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var fingerprintEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ErrUnpinnedKey is returned when a task is signed by a key outside an
+// agent's pinned set.
+var ErrUnpinnedKey = errors.New("signing key is not pinned")
+
+// Fingerprint identifies a public key by the unpadded base32 encoding of
+// its SHA-256 digest. Configs name trusted keys by fingerprint; the keys
+// themselves travel with the messages they sign.
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return fingerprintEncoding.EncodeToString(sum[:])
+}
+
+// ParseFingerprint normalizes a fingerprint as written in a config,
+// accepting lower case and surrounding space.
+func ParseFingerprint(s string) (string, error) {
+	fp := strings.ToUpper(strings.TrimSpace(s))
+	b, err := fingerprintEncoding.DecodeString(fp)
+	if err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid key fingerprint %q", s)
+	}
+	return fp, nil
+}
+
+// KeyPins is the set of controller keys an agent trusts, by fingerprint.
+// It encodes in JSON as a list of fingerprints. The zero value pins
+// nothing and rejects every key.
+type KeyPins struct {
+	set map[string]struct{}
+}
+
+// NewKeyPins returns a pin set of the given fingerprints.
+func NewKeyPins(fingerprints ...string) (*KeyPins, error) {
+	p := &KeyPins{set: make(map[string]struct{}, len(fingerprints))}
+	for _, s := range fingerprints {
+		fp, err := ParseFingerprint(s)
+		if err != nil {
+			return nil, err
+		}
+		p.set[fp] = struct{}{}
+	}
+	return p, nil
+}
+
+// Pinned reports whether pub is in the set.
+func (p *KeyPins) Pinned(pub ed25519.PublicKey) bool {
+	if p == nil || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	_, ok := p.set[Fingerprint(pub)]
+	return ok
+}
+
+// Check rejects a signed task whose signing key is not pinned. It looks
+// only at the key, so it is cheap enough to run before any signature or
+// validity check.
+func (p *KeyPins) Check(st *SignedTask) error {
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	if !p.Pinned(st.PublicKey) {
+		return fmt.Errorf("%w: %s", ErrUnpinnedKey, Fingerprint(st.PublicKey))
+	}
+	return nil
+}
+
+// VerifyTask is VerifyTask behind the pin check, for use as an
+// Executor's Verify.
+func (p *KeyPins) VerifyTask(st *SignedTask) error {
+	if err := p.Check(st); err != nil {
+		return err
+	}
+	return VerifyTask(st)
+}
+
+// MarshalJSON encodes the set as a sorted list of fingerprints.
+func (p *KeyPins) MarshalJSON() ([]byte, error) {
+	fps := make([]string, 0, len(p.set))
+	for fp := range p.set {
+		fps = append(fps, fp)
+	}
+	sort.Strings(fps)
+	return json.Marshal(fps)
+}
+
+// UnmarshalJSON decodes a list of fingerprints.
+func (p *KeyPins) UnmarshalJSON(data []byte) error {
+	var fps []string
+	if err := json.Unmarshal(data, &fps); err != nil {
+		return err
+	}
+	pins, err := NewKeyPins(fps...)
+	if err != nil {
+		return err
+	}
+	*p = *pins
+	return nil
+}
@@ -0,0 +1,81 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	kp := newKeyPair(t)
+	fp := Fingerprint(kp.pub)
+	if len(fp) != 52 || strings.ContainsAny(fp, "=") {
+		t.Fatalf("fingerprint %q: want 52 unpadded base32 characters", fp)
+	}
+	if Fingerprint(newKeyPair(t).pub) == fp {
+		t.Fatal("distinct keys share a fingerprint")
+	}
+	got, err := ParseFingerprint("  " + strings.ToLower(fp) + "\n")
+	if err != nil || got != fp {
+		t.Fatalf("ParseFingerprint = %q, %v", got, err)
+	}
+	for _, bad := range []string{"", fp[:40], fp + "AA", "not base32!"} {
+		if _, err := ParseFingerprint(bad); err == nil {
+			t.Errorf("ParseFingerprint(%q): expected error", bad)
+		}
+	}
+}
+
+func TestKeyPins_JSON(t *testing.T) {
+	a, b := newKeyPair(t), newKeyPair(t)
+	var cfg struct {
+		Controllers *KeyPins `json:"controllers"`
+	}
+	in := `{"controllers": ["` + strings.ToLower(Fingerprint(a.pub)) + `", "` + Fingerprint(b.pub) + `"]}`
+	if err := json.Unmarshal([]byte(in), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Controllers.Pinned(a.pub) || !cfg.Controllers.Pinned(b.pub) || cfg.Controllers.Pinned(newKeyPair(t).pub) {
+		t.Fatal("pins do not match the configured fingerprints")
+	}
+	out, _ := json.Marshal(cfg)
+	var back struct {
+		Controllers *KeyPins `json:"controllers"`
+	}
+	if err := json.Unmarshal(out, &back); err != nil || !back.Controllers.Pinned(a.pub) {
+		t.Fatalf("round trip %s: %v", out, err)
+	}
+	if err := json.Unmarshal([]byte(`{"controllers": ["nope"]}`), &cfg); err == nil {
+		t.Fatal("expected an invalid fingerprint to fail")
+	}
+}
+
+func TestKeyPins_CheckedBeforeSignature(t *testing.T) {
+	st := signedValidTask(t)
+	pins, _ := NewKeyPins(Fingerprint(st.PublicKey))
+	if err := pins.VerifyTask(st); err != nil {
+		t.Fatalf("pinned key: %v", err)
+	}
+
+	other, _ := NewKeyPins(Fingerprint(newKeyPair(t).pub))
+	st.Signature = make([]byte, 3)
+	if err := other.VerifyTask(st); !errors.Is(err, ErrUnpinnedKey) {
+		t.Fatalf("unpinned key with a bad signature: got %v, want ErrUnpinnedKey first", err)
+	}
+}
+
+func TestExecutor_Pins(t *testing.T) {
+	e := NewExecutor()
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil }))
+	e.Pins, _ = NewKeyPins()
+	verified := false
+	e.Verify = func(*SignedTask) error { verified = true; return nil }
+	if _, err := e.Execute(context.Background(), signedValidTask(t)); !errors.Is(err, ErrUnpinnedKey) {
+		t.Fatalf("got %v, want ErrUnpinnedKey", err)
+	}
+	if verified {
+		t.Fatal("Verify ran for an unpinned key")
+	}
+}
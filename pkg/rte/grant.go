@@ -0,0 +1,249 @@
+package rte
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MaxGrantDuration bounds how long a temporary grant may last.
+const MaxGrantDuration = 7 * 24 * time.Hour
+
+// Grant action values recorded in the grant audit trail.
+const (
+	GrantIssued  = "issued"
+	GrantUsed    = "used"
+	GrantRevoked = "revoked"
+)
+
+// Grant temporarily lets a non-lead approve tasks of the listed types, for
+// example while the engagement lead is traveling. A lead issues and signs
+// it; it lapses on its own at ExpiresAt.
+type Grant struct {
+	ID         string `json:"id"`
+	Engagement string `json:"engagement"`
+	// Grantee is the enrolled identity receiving approver rights.
+	Grantee string `json:"grantee"`
+	// TaskTypes are the types the grantee may approve. It is required, so
+	// a grant never silently extends to high-risk types added later.
+	TaskTypes []TaskType `json:"task_types"`
+	NotBefore time.Time  `json:"not_before"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	IssuedBy  string     `json:"issued_by"`
+	Reason    string     `json:"reason"`
+	IssuedAt  time.Time  `json:"issued_at"`
+}
+
+// SignedGrant wraps a Grant with the issuing lead's signature.
+type SignedGrant struct {
+	Grant     Grant  `json:"grant"`
+	PublicKey []byte `json:"public_key"`
+	Signature []byte `json:"signature"`
+	// RevokedAt and RevokedBy record an early revocation (see
+	// RevokeGrant). They are not part of the signed payload.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy string     `json:"revoked_by,omitempty"`
+}
+
+// Revoked reports whether the grant was revoked before it expired.
+func (sg *SignedGrant) Revoked() bool {
+	return sg.RevokedAt != nil
+}
+
+// GrantEvent is one entry of a registry's grant audit trail.
+type GrantEvent struct {
+	At         time.Time `json:"at"`
+	Action     string    `json:"action"`
+	GrantID    string    `json:"grant_id"`
+	Engagement string    `json:"engagement"`
+	Grantee    string    `json:"grantee"`
+	// By is the issuer for issued, the revoker for revoked.
+	By string `json:"by,omitempty"`
+	// TaskID is the task approved under the grant, for used.
+	TaskID string `json:"task_id,omitempty"`
+}
+
+func (g Grant) validate() error {
+	switch {
+	case g.ID == "":
+		return errors.New("grant ID is required")
+	case g.Engagement == "":
+		return errors.New("engagement is required")
+	case g.Grantee == "":
+		return errors.New("grantee is required")
+	case g.IssuedBy == "":
+		return errors.New("issuer is required")
+	case g.Grantee == g.IssuedBy:
+		return errors.New("a lead cannot grant rights to themselves")
+	case g.Reason == "":
+		return errors.New("grant reason is required")
+	case len(g.TaskTypes) == 0:
+		return errors.New("grant must list the task types it covers")
+	case !g.ExpiresAt.After(g.NotBefore):
+		return errors.New("grant must expire after it starts")
+	case g.ExpiresAt.Sub(g.NotBefore) > MaxGrantDuration:
+		return fmt.Errorf("grant lasts longer than %s", MaxGrantDuration)
+	}
+	for _, tt := range g.TaskTypes {
+		if _, ok := allowedTaskTypes[tt]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnsupportedType, tt)
+		}
+	}
+	return nil
+}
+
+// Active reports whether the grant covers approving a task of type tt at
+// now.
+func (g Grant) Active(tt TaskType, now time.Time) bool {
+	return !now.Before(g.NotBefore) && now.Before(g.ExpiresAt) && slices.Contains(g.TaskTypes, tt)
+}
+
+// SignGrant signs a grant with the issuing lead's key.
+func SignGrant(g Grant, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedGrant, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(g)
+	if err != nil {
+		return nil, fmt.Errorf("marshal grant: %w", err)
+	}
+	return &SignedGrant{Grant: g, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// VerifyGrant checks a signed grant's signature and well-formedness, not
+// who issued it; IdentityRegistry.AddGrant does that.
+func VerifyGrant(sg *SignedGrant) error {
+	if sg == nil {
+		return errors.New("signed grant is nil")
+	}
+	if len(sg.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sg.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	if err := sg.Grant.validate(); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(sg.Grant)
+	if err != nil {
+		return fmt.Errorf("marshal grant: %w", err)
+	}
+	if !ed25519.Verify(sg.PublicKey, payload, sg.Signature) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// AddGrant verifies sg and records it. The issuer must be a lead enrolled
+// in the grant's engagement and must have signed it with their enrolled
+// key; the grantee must be enrolled too.
+func (r *IdentityRegistry) AddGrant(sg *SignedGrant) error {
+	if err := VerifyGrant(sg); err != nil {
+		return err
+	}
+	g := sg.Grant
+	issuer, ok := r.Lookup(g.Engagement, g.IssuedBy)
+	if !ok {
+		return fmt.Errorf("issuer %s is not enrolled in %s", g.IssuedBy, g.Engagement)
+	}
+	if !issuer.CanApprove() {
+		return fmt.Errorf("%s does not hold approver rights to grant", g.IssuedBy)
+	}
+	if !bytes.Equal(issuer.PublicKey, sg.PublicKey) {
+		return fmt.Errorf("grant was not signed by %s", g.IssuedBy)
+	}
+	if _, ok := r.Lookup(g.Engagement, g.Grantee); !ok {
+		return fmt.Errorf("grantee %s is not enrolled in %s", g.Grantee, g.Engagement)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.grants == nil {
+		r.grants = make(map[string]map[string]*SignedGrant)
+	}
+	gs, ok := r.grants[g.Engagement]
+	if !ok {
+		gs = make(map[string]*SignedGrant)
+		r.grants[g.Engagement] = gs
+	}
+	if _, exists := gs[g.ID]; exists {
+		return fmt.Errorf("grant %s already recorded in %s", g.ID, g.Engagement)
+	}
+	cp := *sg
+	gs[g.ID] = &cp
+	r.auditGrant(GrantEvent{Action: GrantIssued, GrantID: g.ID, Engagement: g.Engagement, Grantee: g.Grantee, By: g.IssuedBy})
+	return nil
+}
+
+// RevokeGrant ends a grant before it expires. The grant stays on record,
+// marked revoked by by and when, so Grants still shows it.
+func (r *IdentityRegistry) RevokeGrant(engagement, id, by string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sg, ok := r.grants[engagement][id]
+	if !ok {
+		return fmt.Errorf("grant %s not found in %s", id, engagement)
+	}
+	if sg.Revoked() {
+		return fmt.Errorf("grant %s already revoked by %s", id, sg.RevokedBy)
+	}
+	now := time.Now().UTC()
+	sg.RevokedAt, sg.RevokedBy = &now, by
+	r.auditGrant(GrantEvent{Action: GrantRevoked, GrantID: id, Engagement: engagement, Grantee: sg.Grant.Grantee, By: by})
+	return nil
+}
+
+// Grants returns the engagement's recorded grants, expired and revoked ones
+// included, as evidence of who could approve what and when.
+func (r *IdentityRegistry) Grants(engagement string) []SignedGrant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]SignedGrant, 0, len(r.grants[engagement]))
+	for _, sg := range r.grants[engagement] {
+		out = append(out, *sg)
+	}
+	slices.SortFunc(out, func(a, b SignedGrant) int { return a.Grant.IssuedAt.Compare(b.Grant.IssuedAt) })
+	return out
+}
+
+// GrantAudit returns every grant issuance, use, and revocation, oldest
+// first.
+func (r *IdentityRegistry) GrantAudit() []GrantEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.grantAudit)
+}
+
+// activeGrant returns a grant letting approver approve task at now.
+func (r *IdentityRegistry) activeGrant(task Task, approver string, now time.Time) (Grant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, sg := range r.grants[task.Engagement] {
+		if sg.Grant.Grantee == approver && !sg.Revoked() && sg.Grant.Active(task.Type, now) {
+			return sg.Grant, true
+		}
+	}
+	return Grant{}, false
+}
+
+func (r *IdentityRegistry) recordGrantUse(g Grant, taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditGrant(GrantEvent{Action: GrantUsed, GrantID: g.ID, Engagement: g.Engagement, Grantee: g.Grantee, TaskID: taskID})
+}
+
+// auditGrant appends to the audit trail; r.mu must be held.
+func (r *IdentityRegistry) auditGrant(e GrantEvent) {
+	e.At = time.Now().UTC()
+	r.grantAudit = append(r.grantAudit, e)
+}
@@ -0,0 +1,177 @@
+package rte
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// withDeputy enrolls op-carol, a second operator, in r.
+func withDeputy(t *testing.T, r *IdentityRegistry) keyPair {
+	t.Helper()
+	carol := newKeyPair(t)
+	if err := r.Add("eng-2026-q1", Identity{Name: "op-carol", Role: RoleOperator, PublicKey: carol.pub}); err != nil {
+		t.Fatalf("add deputy: %v", err)
+	}
+	return carol
+}
+
+func testGrant(now time.Time) Grant {
+	return Grant{
+		ID:         "grant-001",
+		Engagement: "eng-2026-q1",
+		Grantee:    "op-carol",
+		TaskTypes:  []TaskType{TaskSimulateLogin, TaskInventory},
+		NotBefore:  now.Add(-time.Minute),
+		ExpiresAt:  now.Add(48 * time.Hour),
+		IssuedBy:   "lead-bob",
+		Reason:     "lead traveling",
+		IssuedAt:   now,
+	}
+}
+
+func deputyApproved(t *testing.T, task Task, op, deputy keyPair) *SignedTask {
+	t.Helper()
+	task.ApprovedBy = "op-carol"
+	return signAndApprove(t, task, op, deputy)
+}
+
+func TestGrant_DeputyApproves(t *testing.T) {
+	r, op, lead := enrolled(t)
+	carol := withDeputy(t, r)
+	now := time.Now().UTC()
+	st := deputyApproved(t, validTask(now), op, carol)
+	if err := r.VerifySignedTask(st); err == nil {
+		t.Fatal("expected deputy approval without a grant to fail")
+	}
+
+	sg, err := SignGrant(testGrant(now), lead.priv, lead.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddGrant(sg); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.VerifySignedTask(st); err != nil {
+		t.Fatalf("VerifySignedTask under grant: %v", err)
+	}
+
+	task := validTask(now)
+	task.Type = TaskSimulateExfil
+	if err := r.VerifySignedTask(deputyApproved(t, task, op, carol)); err == nil {
+		t.Fatal("expected a type outside the grant to fail")
+	}
+
+	if err := r.RevokeGrant("eng-2026-q1", "grant-001", "lead-bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.VerifySignedTask(st); err == nil {
+		t.Fatal("expected approval after revocation to fail")
+	}
+	if gs := r.Grants("eng-2026-q1"); len(gs) != 1 || !gs[0].Revoked() || gs[0].RevokedBy != "lead-bob" {
+		t.Fatalf("grants after revocation = %+v, want the grant kept and marked revoked", gs)
+	}
+	if err := VerifyGrant(&r.Grants("eng-2026-q1")[0]); err != nil {
+		t.Fatalf("revoked grant no longer verifies: %v", err)
+	}
+	if err := r.RevokeGrant("eng-2026-q1", "grant-001", "lead-bob"); err == nil {
+		t.Fatal("expected a second revocation to fail")
+	}
+
+	var actions []string
+	for _, e := range r.GrantAudit() {
+		actions = append(actions, e.Action)
+	}
+	if got := strings.Join(actions, ","); got != "issued,used,revoked" {
+		t.Fatalf("audit = %s", got)
+	}
+	if e := r.GrantAudit()[1]; e.TaskID != "task-001" || e.Grantee != "op-carol" {
+		t.Fatalf("used event = %+v", e)
+	}
+}
+
+func TestGrant_Window(t *testing.T) {
+	now := time.Now().UTC()
+	g := testGrant(now)
+	if !g.Active(TaskSimulateLogin, now) {
+		t.Fatal("expected grant active now")
+	}
+	if g.Active(TaskSimulateLogin, g.ExpiresAt) {
+		t.Fatal("expected grant inactive at expiry")
+	}
+	if g.Active(TaskSimulateLogin, g.NotBefore.Add(-time.Second)) {
+		t.Fatal("expected grant inactive before it starts")
+	}
+
+	r, op, lead := enrolled(t)
+	carol := withDeputy(t, r)
+	g.ExpiresAt = now.Add(-time.Second)
+	g.NotBefore = now.Add(-time.Hour)
+	sg, err := SignGrant(g, lead.priv, lead.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddGrant(sg); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.VerifySignedTask(deputyApproved(t, validTask(now), op, carol)); err == nil {
+		t.Fatal("expected expired grant to fail")
+	}
+	if n := len(r.Grants("eng-2026-q1")); n != 1 {
+		t.Fatalf("grants = %d, want expired grant kept", n)
+	}
+}
+
+func TestGrant_Invalid(t *testing.T) {
+	now := time.Now().UTC()
+	lead := newKeyPair(t)
+	for name, mutate := range map[string]func(*Grant){
+		"no types":  func(g *Grant) { g.TaskTypes = nil },
+		"too long":  func(g *Grant) { g.ExpiresAt = g.NotBefore.Add(MaxGrantDuration + time.Second) },
+		"backwards": func(g *Grant) { g.ExpiresAt = g.NotBefore },
+		"self":      func(g *Grant) { g.Grantee = g.IssuedBy },
+		"no reason": func(g *Grant) { g.Reason = "" },
+		"bad type":  func(g *Grant) { g.TaskTypes = []TaskType{"destroy"} },
+	} {
+		g := testGrant(now)
+		mutate(&g)
+		if _, err := SignGrant(g, lead.priv, lead.pub); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestIdentityRegistry_AddGrant_Rejects(t *testing.T) {
+	r, op, lead := enrolled(t)
+	withDeputy(t, r)
+	now := time.Now().UTC()
+
+	g := testGrant(now)
+	g.IssuedBy = "op-alice"
+	g.Grantee = "op-carol"
+	sg, _ := SignGrant(g, op.priv, op.pub)
+	if err := r.AddGrant(sg); err == nil {
+		t.Fatal("expected grant from an operator to fail")
+	}
+
+	sg, _ = SignGrant(testGrant(now), op.priv, op.pub)
+	if err := r.AddGrant(sg); err == nil {
+		t.Fatal("expected grant signed by the wrong key to fail")
+	}
+
+	g = testGrant(now)
+	g.Grantee = "op-dave"
+	sg, _ = SignGrant(g, lead.priv, lead.pub)
+	if err := r.AddGrant(sg); err == nil {
+		t.Fatal("expected grant to an unenrolled grantee to fail")
+	}
+
+	sg, _ = SignGrant(testGrant(now), lead.priv, lead.pub)
+	sg.Grant.ExpiresAt = sg.Grant.ExpiresAt.Add(time.Hour)
+	if err := r.AddGrant(sg); err == nil {
+		t.Fatal("expected tampered grant to fail")
+	}
+	if len(r.GrantAudit()) != 0 {
+		t.Fatal("rejected grants must not be audited as issued")
+	}
+}
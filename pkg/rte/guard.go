@@ -0,0 +1,213 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"runtime"
+	"time"
+)
+
+// DefaultGuardGrace is how long a Guard waits by default for a stopped
+// handler to return.
+const DefaultGuardGrace = 5 * time.Second
+
+// Guard failures. A handler that dials outside its task's targets sees
+// ErrGuardDenied from the dialer. A run stopped at MaxRuntime fails with
+// ErrGuardTimeout, or ErrGuardAbandoned if it outlives Grace as well.
+var (
+	ErrGuardDenied              = errors.New("guard: destination outside the task's targets")
+	ErrGuardTimeout             = errors.New("guard: run exceeded its maximum runtime")
+	ErrGuardAbandoned           = errors.New("guard: handler did not stop")
+	ErrSyscallFilterUnsupported = fmt.Errorf("guard: syscall filtering is not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+)
+
+// Dialer opens network connections for a handler; *net.Dialer is one.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// guardKey is the context key of a guarded run's dialer.
+type guardKey struct{}
+
+// RunDialer returns the dialer a handler should open connections with
+// during the run ctx belongs to, and whether a Guard restricts it. Outside
+// a Guard it is a plain net.Dialer. Handlers that dial any other way are
+// not held to the Guard's network allowlist.
+func RunDialer(ctx context.Context) (Dialer, bool) {
+	if d, ok := ctx.Value(guardKey{}).(*guardDialer); ok {
+		return d, true
+	}
+	return &net.Dialer{}, false
+}
+
+// Guard wraps a handler to hold each run to the task's declared blast
+// radius: connections only to the task's targets, a hard wall-clock
+// limit, and on Linux an optional syscall filter. It passes dry runs,
+// pauses, and cleanups through to the wrapped handler.
+type Guard struct {
+	Handler Handler
+	// Allow lists destinations runs may dial beyond the task's targets,
+	// such as the agent's SMTP relay or event sink, in the forms task
+	// targets take: addresses, CIDRs, hostnames, and URLs.
+	Allow []string
+	// MaxRuntime, if set, stops every run after this long, however far
+	// its TTL or extensions reach.
+	MaxRuntime time.Duration
+	// Grace is how long to wait for a stopped handler to return before
+	// abandoning it and failing the run. Defaults to DefaultGuardGrace.
+	Grace time.Duration
+	// RestrictSyscalls runs the handler on an OS thread of its own under
+	// a seccomp filter refusing process execution, tracing, mounts,
+	// module loading, and reboots; the thread is discarded afterwards.
+	// Goroutines the handler starts run unfiltered. Only Linux on amd64
+	// and arm64 supports it; elsewhere runs fail with
+	// ErrSyscallFilterUnsupported rather than run unfiltered.
+	RestrictSyscalls bool
+}
+
+// Handle implements Handler.
+func (g *Guard) Handle(ctx context.Context, task Task) (any, error) {
+	return g.run(ctx, task, g.Handler.Handle)
+}
+
+// Plan implements Planner if the wrapped handler does. Planning contacts
+// nothing, so it runs unguarded.
+func (g *Guard) Plan(ctx context.Context, task Task) ([]PlannedStep, error) {
+	p, ok := g.Handler.(Planner)
+	if !ok {
+		return nil, fmt.Errorf("%s handler does not support dry runs", task.Type)
+	}
+	return p.Plan(ctx, task)
+}
+
+// Pause implements Pausable if the wrapped handler does.
+func (g *Guard) Pause(task Task) error {
+	p, ok := g.Handler.(Pausable)
+	if !ok {
+		return fmt.Errorf("%s handler does not support pausing", task.Type)
+	}
+	return p.Pause(task)
+}
+
+// Resume implements Pausable if the wrapped handler does.
+func (g *Guard) Resume(task Task) error {
+	p, ok := g.Handler.(Pausable)
+	if !ok {
+		return fmt.Errorf("%s handler does not support pausing", task.Type)
+	}
+	return p.Resume(task)
+}
+
+// Cleanup implements Cleaner if the wrapped handler does, under the same
+// guard as Handle.
+func (g *Guard) Cleanup(ctx context.Context, task Task) (any, error) {
+	c, ok := g.Handler.(Cleaner)
+	if !ok {
+		return nil, fmt.Errorf("%s handler does not support cleanup", task.Type)
+	}
+	return g.run(ctx, task, c.Cleanup)
+}
+
+// run calls fn under the guard. fn runs on a goroutine of its own so a
+// handler that ignores ctx can be abandoned.
+func (g *Guard) run(ctx context.Context, task Task, fn func(context.Context, Task) (any, error)) (any, error) {
+	if g.RestrictSyscalls && !syscallFilterSupported {
+		return nil, ErrSyscallFilterUnsupported
+	}
+	ctx = context.WithValue(ctx, guardKey{}, newGuardDialer(task, g.Allow))
+	if g.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, g.MaxRuntime, ErrGuardTimeout)
+		defer cancel()
+	}
+	type result struct {
+		out any
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		if g.RestrictSyscalls {
+			// The goroutine exits still locked, so the runtime discards
+			// the filtered thread rather than reuse it.
+			runtime.LockOSThread()
+			if err := restrictSyscalls(); err != nil {
+				done <- result{err: fmt.Errorf("guard: %w", err)}
+				return
+			}
+		}
+		out, err := fn(ctx, task)
+		done <- result{out, err}
+	}()
+	select {
+	case r := <-done:
+		return r.out, g.runErr(ctx, r.err)
+	case <-ctx.Done():
+	}
+	grace := g.Grace
+	if grace <= 0 {
+		grace = DefaultGuardGrace
+	}
+	t := time.NewTimer(grace)
+	defer t.Stop()
+	select {
+	case r := <-done:
+		return r.out, g.runErr(ctx, r.err)
+	case <-t.C:
+		return nil, fmt.Errorf("%w within %s of being stopped (%v)", ErrGuardAbandoned, grace, context.Cause(ctx))
+	}
+}
+
+// runErr reports a run the guard stopped at MaxRuntime as ErrGuardTimeout,
+// whatever the handler returned.
+func (g *Guard) runErr(ctx context.Context, err error) error {
+	if errors.Is(context.Cause(ctx), ErrGuardTimeout) {
+		return fmt.Errorf("%w of %s", ErrGuardTimeout, g.MaxRuntime)
+	}
+	return err
+}
+
+// guardDialer dials only the destinations a run may reach.
+type guardDialer struct {
+	taskID   string
+	prefixes []netip.Prefix
+	hosts    map[string]bool
+}
+
+func newGuardDialer(task Task, allow []string) *guardDialer {
+	d := &guardDialer{taskID: task.ID, hosts: make(map[string]bool)}
+	for _, t := range append(TaskTargets(task), allow...) {
+		switch prefix, host := parseTarget(t); {
+		case prefix.IsValid():
+			d.prefixes = append(d.prefixes, prefix)
+		case host != "":
+			d.hosts[host] = true
+		}
+	}
+	return d
+}
+
+// DialContext implements Dialer. Hostnames must be targets themselves;
+// resolving one to an allowed address does not admit it.
+func (d *guardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if !d.allowed(address) {
+		return nil, fmt.Errorf("%w: task %s dialed %s", ErrGuardDenied, d.taskID, address)
+	}
+	var nd net.Dialer
+	return nd.DialContext(ctx, network, address)
+}
+
+func (d *guardDialer) allowed(address string) bool {
+	prefix, host := parseTarget(address)
+	if prefix.IsValid() {
+		for _, p := range d.prefixes {
+			if p.Contains(prefix.Addr()) {
+				return true
+			}
+		}
+		return false
+	}
+	return host != "" && d.hosts[host]
+}
@@ -0,0 +1,7 @@
+//go:build !linux || !(amd64 || arm64)
+
+package rte
+
+const syscallFilterSupported = false
+
+func restrictSyscalls() error { return ErrSyscallFilterUnsupported }
@@ -0,0 +1,57 @@
+//go:build linux && (amd64 || arm64)
+
+package rte
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const syscallFilterSupported = true
+
+// prctl options and seccomp return actions; see prctl(2) and seccomp(2).
+const (
+	prSetNoNewPrivs   = 38
+	prSetSeccomp      = 22
+	seccompModeFilter = 2
+	seccompRetAllow   = 0x7fff0000
+	seccompRetErrno   = 0x00050000
+	// x32 syscalls on amd64 set this bit; the filter refuses them all
+	// rather than list each twice.
+	x32SyscallBit = 0x40000000
+)
+
+// restrictSyscalls installs a seccomp filter on the calling thread that
+// fails deniedSyscalls, and any call made under another ABI, with EPERM.
+// The thread must stay locked to its goroutine until that goroutine exits.
+func restrictSyscalls() error {
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return errno
+	}
+	filter := seccompFilter(auditArch, deniedSyscalls)
+	prog := syscall.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilter, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// seccompFilter assembles the BPF program: check the architecture, load
+// the syscall number, and jump to the deny return on any match.
+func seccompFilter(arch uint32, denied []uint32) []syscall.SockFilter {
+	stmt := func(code uint16, k uint32) syscall.SockFilter { return syscall.SockFilter{Code: code, K: k} }
+	deny := stmt(syscall.BPF_RET|syscall.BPF_K, seccompRetErrno|uint32(syscall.EPERM))
+	f := []syscall.SockFilter{
+		stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, 4), // seccomp_data.arch
+		{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, K: arch, Jt: 1},
+		deny,
+		stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, 0), // seccomp_data.nr
+	}
+	// Every jump below lands on the deny return, last in the program.
+	n := len(denied) + 1
+	f = append(f, syscall.SockFilter{Code: syscall.BPF_JMP | syscall.BPF_JGE | syscall.BPF_K, K: x32SyscallBit, Jt: uint8(n)})
+	for i, nr := range denied {
+		f = append(f, syscall.SockFilter{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, K: nr, Jt: uint8(n - 1 - i)})
+	}
+	return append(f, stmt(syscall.BPF_RET|syscall.BPF_K, seccompRetAllow), deny)
+}
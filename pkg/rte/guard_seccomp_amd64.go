@@ -0,0 +1,12 @@
+//go:build linux
+
+package rte
+
+// auditArch is AUDIT_ARCH_X86_64.
+const auditArch = 0xc000003e
+
+// deniedSyscalls are the syscalls a Guard with RestrictSyscalls refuses:
+// execve, execveat, ptrace, process_vm_writev, mount, umount2,
+// pivot_root, chroot, reboot, kexec_load, init_module, finit_module,
+// delete_module, swapon, swapoff, and bpf.
+var deniedSyscalls = []uint32{59, 322, 101, 311, 165, 166, 155, 161, 169, 246, 175, 313, 176, 167, 168, 321}
@@ -0,0 +1,10 @@
+//go:build linux
+
+package rte
+
+// auditArch is AUDIT_ARCH_AARCH64.
+const auditArch = 0xc00000b7
+
+// deniedSyscalls are the syscalls a Guard with RestrictSyscalls refuses,
+// in the order listed for amd64.
+var deniedSyscalls = []uint32{221, 281, 117, 271, 40, 39, 41, 51, 142, 104, 105, 273, 106, 224, 225, 280}
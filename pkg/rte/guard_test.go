@@ -0,0 +1,111 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuard_Network(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+	_, port, _ := net.SplitHostPort(addr)
+
+	dial := func(address string) HandlerFunc {
+		return func(ctx context.Context, _ Task) (any, error) {
+			d, guarded := RunDialer(ctx)
+			if !guarded {
+				return nil, errors.New("dialer is not guarded")
+			}
+			conn, err := d.DialContext(ctx, "tcp", address)
+			if err != nil {
+				return nil, err
+			}
+			return nil, conn.Close()
+		}
+	}
+	for _, tc := range []struct {
+		name    string
+		target  string
+		allow   []string
+		address string
+		denied  bool
+	}{
+		{"target", "127.0.0.1", nil, addr, false},
+		{"target CIDR", "127.0.0.0/8", nil, addr, false},
+		{"outside targets", "10.0.0.1", nil, addr, true},
+		{"allowed", "10.0.0.1", []string{"http://" + addr}, addr, false},
+		{"hostname not a target", "127.0.0.1", nil, net.JoinHostPort("localhost", port), true},
+	} {
+		task := validTask(time.Now())
+		task.Params = map[string]string{"target": tc.target}
+		g := &Guard{Handler: dial(tc.address), Allow: tc.allow}
+		_, err := g.Handle(context.Background(), task)
+		if denied := errors.Is(err, ErrGuardDenied); denied != tc.denied || (!tc.denied && err != nil) {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+	if _, guarded := RunDialer(context.Background()); guarded {
+		t.Error("unguarded context has a guarded dialer")
+	}
+}
+
+func TestGuard_MaxRuntime(t *testing.T) {
+	task := validTask(time.Now())
+	polite := &Guard{Handler: HandlerFunc(func(ctx context.Context, _ Task) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}), MaxRuntime: 10 * time.Millisecond}
+	if _, err := polite.Handle(context.Background(), task); !errors.Is(err, ErrGuardTimeout) {
+		t.Errorf("handler stopped at MaxRuntime: %v", err)
+	}
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	rude := &Guard{Handler: HandlerFunc(func(context.Context, Task) (any, error) {
+		<-stuck
+		return nil, nil
+	}), MaxRuntime: 10 * time.Millisecond, Grace: 10 * time.Millisecond}
+	start := time.Now()
+	if _, err := rude.Handle(context.Background(), task); !errors.Is(err, ErrGuardAbandoned) {
+		t.Errorf("handler ignoring ctx: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("abandoning the handler took too long")
+	}
+	if _, err := rude.Plan(context.Background(), task); err == nil {
+		t.Error("planned with a handler that cannot plan")
+	}
+}
+
+func TestGuard_RestrictSyscalls(t *testing.T) {
+	run := func(ctx context.Context, _ Task) (any, error) {
+		return nil, exec.CommandContext(ctx, os.Args[0], "-test.run=^$").Run()
+	}
+	g := &Guard{Handler: HandlerFunc(run), RestrictSyscalls: true}
+	_, err := g.Handle(context.Background(), validTask(time.Now()))
+	if !syscallFilterSupported {
+		if !errors.Is(err, ErrSyscallFilterUnsupported) {
+			t.Fatalf("unsupported platform: %v", err)
+		}
+		return
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "guard: ") {
+		t.Skipf("seccomp unavailable: %v", err)
+	}
+	if err == nil {
+		t.Fatal("guarded handler executed a process")
+	}
+	// The filtered thread is gone; other goroutines may still exec.
+	if _, err := run(context.Background(), Task{}); err != nil {
+		t.Fatalf("exec after a guarded run: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package rte
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEngagementHalted marks tasks cancelled or refused because their
+// engagement was halted.
+var ErrEngagementHalted = errors.New("engagement halted")
+
+// EngagementHalt is the emergency stop for an entire engagement, issued by a
+// lead when the customer calls the hotline.
+type EngagementHalt struct {
+	Engagement string    `json:"engagement"`
+	IssuedBy   string    `json:"issued_by"`
+	Reason     string    `json:"reason"`
+	IssuedAt   time.Time `json:"issued_at"`
+}
+
+// SignedHalt wraps an EngagementHalt with the issuer's signature.
+type SignedHalt struct {
+	Halt      EngagementHalt `json:"halt"`
+	PublicKey []byte         `json:"public_key"`
+	Signature []byte         `json:"signature"`
+}
+
+// SignHalt signs a halt with the issuer's key.
+func SignHalt(h EngagementHalt, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedHalt, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("marshal halt: %w", err)
+	}
+	return &SignedHalt{Halt: h, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// VerifyHalt verifies a signed halt's signature and required fields.
+func VerifyHalt(sh *SignedHalt) error {
+	if sh == nil {
+		return errors.New("signed halt is nil")
+	}
+	if len(sh.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sh.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	if err := sh.Halt.validate(); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(sh.Halt)
+	if err != nil {
+		return fmt.Errorf("marshal halt: %w", err)
+	}
+	if !ed25519.Verify(sh.PublicKey, payload, sh.Signature) {
+		return errors.New("halt signature verification failed")
+	}
+	return nil
+}
+
+// VerifyHalt verifies the halt and checks that its issuer is enrolled in
+// the engagement with approver rights and signed with their enrolled key.
+func (r *IdentityRegistry) VerifyHalt(sh *SignedHalt) error {
+	if err := VerifyHalt(sh); err != nil {
+		return err
+	}
+	h := sh.Halt
+	id, ok := r.Lookup(h.Engagement, h.IssuedBy)
+	if !ok {
+		return fmt.Errorf("%s is not enrolled in %s", h.IssuedBy, h.Engagement)
+	}
+	if !id.CanApprove() {
+		return fmt.Errorf("%s does not hold approver rights", h.IssuedBy)
+	}
+	if !bytes.Equal(id.PublicKey, sh.PublicKey) {
+		return fmt.Errorf("halt was not signed by %s", h.IssuedBy)
+	}
+	return nil
+}
+
+// verifyHalt is the Executor's default halt check: VerifyHalt, and a
+// signing key in e.Pins or the trusted approver key of a running task of
+// the halted engagement (see approverKey).
+func (e *Executor) verifyHalt(sh *SignedHalt) error {
+	if err := VerifyHalt(sh); err != nil {
+		return err
+	}
+	if e.Pins != nil && e.Pins.Pinned(sh.PublicKey) {
+		return nil
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for r := range e.running {
+		if r.engagement == sh.Halt.Engagement && bytes.Equal(sh.PublicKey, e.approverKey(r.signed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("halt was not signed by a pinned key or an approver of a running task in %s", sh.Halt.Engagement)
+}
+
+func (h EngagementHalt) validate() error {
+	if h.Engagement == "" {
+		return errors.New("engagement is required")
+	}
+	if h.IssuedBy == "" {
+		return errors.New("issued_by is required")
+	}
+	if h.IssuedAt.IsZero() {
+		return errors.New("issued_at is required")
+	}
+	return nil
+}
+
+// cause is the cancellation cause recorded in halted tasks' results.
+func (h EngagementHalt) cause() error {
+	if h.Reason == "" {
+		return fmt.Errorf("%w by %s", ErrEngagementHalted, h.IssuedBy)
+	}
+	return fmt.Errorf("%w by %s: %s", ErrEngagementHalted, h.IssuedBy, h.Reason)
+}
@@ -0,0 +1,124 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func signedHalt(t *testing.T, by string, k keyPair) *SignedHalt {
+	t.Helper()
+	sh, err := SignHalt(EngagementHalt{
+		Engagement: "eng-2026-q1", IssuedBy: by, Reason: "customer hotline call", IssuedAt: time.Now().UTC(),
+	}, k.priv, k.pub)
+	if err != nil {
+		t.Fatalf("SignHalt: %v", err)
+	}
+	return sh
+}
+
+func TestVerifyHalt(t *testing.T) {
+	r, op, lead := enrolled(t)
+	if err := r.VerifyHalt(signedHalt(t, "lead-bob", lead)); err != nil {
+		t.Fatalf("VerifyHalt: %v", err)
+	}
+	if err := r.VerifyHalt(signedHalt(t, "op-alice", op)); err == nil || !strings.Contains(err.Error(), "approver rights") {
+		t.Errorf("expected operator halt to be refused, got %v", err)
+	}
+	if err := r.VerifyHalt(signedHalt(t, "lead-bob", op)); err == nil {
+		t.Error("expected halt signed with the wrong key to be refused")
+	}
+	sh := signedHalt(t, "lead-bob", lead)
+	sh.Halt.Engagement = "eng-other"
+	if err := VerifyHalt(sh); err == nil {
+		t.Error("expected tampered halt to fail")
+	}
+	if _, err := SignHalt(EngagementHalt{Engagement: "eng-2026-q1"}, lead.priv, lead.pub); err == nil {
+		t.Error("expected halt without issuer to fail")
+	}
+}
+
+func TestExecutor_Halt(t *testing.T) {
+	reg, op, lead := enrolled(t)
+	e := NewExecutor()
+	e.Identities = reg
+	started := make(chan struct{}, 3)
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(ctx context.Context, _ Task) (any, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	other := signedValidTask(t)
+	otherTask := other.Task
+	otherTask.Engagement = "eng-other"
+	pub, priv, _ := GenerateKeyPair()
+	other, _ = SignTask(otherTask, priv, pub)
+
+	// The second task is validly countersigned, but by a key that is
+	// neither pinned nor enrolled.
+	stranger := newKeyPair(t)
+	strangerTask := validTask(time.Now().UTC())
+	strangerTask.ID = "task-002"
+	var wg sync.WaitGroup
+	var halted, untouched *TaskResult
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		halted, _ = e.Execute(context.Background(), signAndApprove(t, validTask(time.Now().UTC()), op, lead))
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = e.Execute(context.Background(), signAndApprove(t, strangerTask, op, stranger))
+	}()
+	ctx, cancelOther := context.WithCancel(context.Background())
+	go func() {
+		defer wg.Done()
+		untouched, _ = e.Execute(ctx, other)
+	}()
+	<-started
+	<-started
+	<-started
+
+	for name, k := range map[string]keyPair{"an unknown key": newKeyPair(t), "an unvouched approver": stranger} {
+		if _, err := e.Halt(context.Background(), signedHalt(t, "lead-bob", k)); err == nil ||
+			!strings.Contains(err.Error(), "approver of a running task") {
+			t.Fatalf("halt signed by %s: got %v", name, err)
+		}
+	}
+	if e.Halted("eng-2026-q1") {
+		t.Fatal("unknown key halted the engagement")
+	}
+	results, err := e.Halt(context.Background(), signedHalt(t, "lead-bob", lead))
+	if err != nil {
+		t.Fatalf("Halt: %v", err)
+	}
+	if len(results) != 2 || results[0].State != StateCancelled ||
+		!strings.Contains(results[0].Error, "engagement halted by lead-bob: customer hotline call") {
+		t.Fatalf("unexpected halt confirmations %+v", results)
+	}
+	if !e.Halted("eng-2026-q1") || e.Halted("eng-other") {
+		t.Error("halt applied to the wrong engagement")
+	}
+	if _, err := e.Execute(context.Background(), signedValidTask(t)); !errors.Is(err, ErrEngagementHalted) {
+		t.Errorf("expected new task to be refused, got %v", err)
+	}
+
+	cancelOther()
+	wg.Wait()
+	if halted.State != StateCancelled || untouched.Error == results[0].Error {
+		t.Errorf("unexpected results halted=%+v other=%+v", halted, untouched)
+	}
+	if _, err := e.Halt(context.Background(), &SignedHalt{}); err == nil {
+		t.Error("expected unsigned halt to fail")
+	}
+
+	pinned := NewExecutor()
+	pinned.Pins, _ = NewKeyPins(Fingerprint(lead.pub))
+	if _, err := pinned.Halt(context.Background(), signedHalt(t, "lead-bob", lead)); err != nil || !pinned.Halted("eng-2026-q1") {
+		t.Errorf("halt signed by a pinned key with nothing running: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Role is the authority an identity holds within an engagement.
+type Role string
+
+const (
+	RoleOperator Role = "operator"
+	RoleLead     Role = "lead"
+	RoleObserver Role = "observer"
+)
+
+var validRoles = map[Role]struct{}{
+	RoleOperator: {},
+	RoleLead:     {},
+	RoleObserver: {},
+}
+
+// Identity binds a named person to a signing key and a role.
+type Identity struct {
+	Name      string            `json:"name"`
+	Role      Role              `json:"role"`
+	PublicKey ed25519.PublicKey `json:"public_key"`
+}
+
+// CanApprove reports whether the identity holds approver rights.
+func (i Identity) CanApprove() bool {
+	return i.Role == RoleLead
+}
+
+// Approval is the approver's countersignature over a signed task.
+type Approval struct {
+	PublicKey []byte `json:"public_key"`
+	Signature []byte `json:"signature"`
+}
+
+// IdentityRegistry holds the identities enrolled in each engagement. It is
+// safe for concurrent use.
+type IdentityRegistry struct {
+	mu          sync.RWMutex
+	engagements map[string]map[string]Identity
+	grants      map[string]map[string]*SignedGrant
+	grantAudit  []GrantEvent
+}
+
+// NewIdentityRegistry returns an empty registry.
+func NewIdentityRegistry() *IdentityRegistry {
+	return &IdentityRegistry{
+		engagements: make(map[string]map[string]Identity),
+		grants:      make(map[string]map[string]*SignedGrant),
+	}
+}
+
+// Add enrolls an identity in an engagement. Names are unique per engagement.
+func (r *IdentityRegistry) Add(engagement string, id Identity) error {
+	if engagement == "" {
+		return errors.New("engagement is required")
+	}
+	if id.Name == "" {
+		return errors.New("identity name is required")
+	}
+	if _, ok := validRoles[id.Role]; !ok {
+		return fmt.Errorf("invalid role: %s", id.Role)
+	}
+	if len(id.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids, ok := r.engagements[engagement]
+	if !ok {
+		ids = make(map[string]Identity)
+		r.engagements[engagement] = ids
+	}
+	if _, exists := ids[id.Name]; exists {
+		return fmt.Errorf("identity %s already enrolled in %s", id.Name, engagement)
+	}
+	ids[id.Name] = id
+	return nil
+}
+
+// Lookup returns the named identity in an engagement.
+func (r *IdentityRegistry) Lookup(engagement, name string) (Identity, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.engagements[engagement][name]
+	return id, ok
+}
+
+// VerifySignedTask verifies the task signature and validates it, then checks
+// that the signer is the task's Operator holding the operator role, and that
+// the approval countersignature comes from the task's ApprovedBy holding
+// approver rights (R1, R5). Approver rights come from the lead role or from
+// a grant active now that covers the task's type.
+func (r *IdentityRegistry) VerifySignedTask(st *SignedTask) error {
+	if err := VerifyTask(st); err != nil {
+		return err
+	}
+	t := st.Task
+	op, ok := r.Lookup(t.Engagement, t.Operator)
+	if !ok {
+		return fmt.Errorf("operator %s is not enrolled in %s", t.Operator, t.Engagement)
+	}
+	if op.Role != RoleOperator {
+		return fmt.Errorf("%s does not hold the operator role", t.Operator)
+	}
+	if !bytes.Equal(op.PublicKey, st.PublicKey) {
+		return fmt.Errorf("task was not signed by operator %s", t.Operator)
+	}
+	if t.ApprovedBy == t.Operator {
+		return errors.New("operator cannot approve their own task")
+	}
+	if st.Approval == nil {
+		return errors.New("approval countersignature is required")
+	}
+	approver, ok := r.Lookup(t.Engagement, t.ApprovedBy)
+	if !ok {
+		return fmt.Errorf("approver %s is not enrolled in %s", t.ApprovedBy, t.Engagement)
+	}
+	var grant *Grant
+	if !approver.CanApprove() {
+		g, ok := r.activeGrant(t, t.ApprovedBy, time.Now())
+		if !ok {
+			return fmt.Errorf("%s does not hold approver rights", t.ApprovedBy)
+		}
+		grant = &g
+	}
+	if !bytes.Equal(approver.PublicKey, st.Approval.PublicKey) {
+		return fmt.Errorf("task was not countersigned by approver %s", t.ApprovedBy)
+	}
+	if err := VerifyApproval(st); err != nil {
+		return err
+	}
+	if grant != nil {
+		r.recordGrantUse(*grant, t.ID)
+	}
+	return nil
+}
+
+// Countersign adds the approver's signature to a signed task. The approval
+// covers the task and the operator's signature, binding it to this issuance.
+func Countersign(st *SignedTask, priv ed25519.PrivateKey, pub ed25519.PublicKey) error {
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	s, err := NewKeySigner(priv, pub)
+	if err != nil {
+		return err
+	}
+	return CountersignContext(context.Background(), st, s)
+}
+
+// VerifyApproval checks the approval countersignature on a signed task.
+func VerifyApproval(st *SignedTask) error {
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	if st.Approval == nil {
+		return errors.New("approval countersignature is required")
+	}
+	if len(st.Approval.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid approver public key size")
+	}
+	if len(st.Approval.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid approval signature size")
+	}
+	payload, err := approvalPayload(st)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(st.Approval.PublicKey, payload, st.Approval.Signature) {
+		return errors.New("approval signature verification failed")
+	}
+	return nil
+}
+
+func approvalPayload(st *SignedTask) ([]byte, error) {
+	payload, err := taskPayload(st)
+	if err != nil {
+		return nil, err
+	}
+	return append(payload, st.Signature...), nil
+}
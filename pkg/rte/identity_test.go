@@ -0,0 +1,124 @@
+package rte
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+)
+
+type keyPair struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func newKeyPair(t *testing.T) keyPair {
+	t.Helper()
+	pub, priv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("generate key pair: %v", err)
+	}
+	return keyPair{pub: pub, priv: priv}
+}
+
+// enrolled returns a registry with op-alice (operator) and lead-bob (lead)
+// enrolled in the validTask engagement, plus their keys.
+func enrolled(t *testing.T) (*IdentityRegistry, keyPair, keyPair) {
+	t.Helper()
+	op, lead := newKeyPair(t), newKeyPair(t)
+	r := NewIdentityRegistry()
+	if err := r.Add("eng-2026-q1", Identity{Name: "op-alice", Role: RoleOperator, PublicKey: op.pub}); err != nil {
+		t.Fatalf("add operator: %v", err)
+	}
+	if err := r.Add("eng-2026-q1", Identity{Name: "lead-bob", Role: RoleLead, PublicKey: lead.pub}); err != nil {
+		t.Fatalf("add lead: %v", err)
+	}
+	return r, op, lead
+}
+
+func signAndApprove(t *testing.T, task Task, op, lead keyPair) *SignedTask {
+	t.Helper()
+	st, err := SignTask(task, op.priv, op.pub)
+	if err != nil {
+		t.Fatalf("SignTask: %v", err)
+	}
+	if err := Countersign(st, lead.priv, lead.pub); err != nil {
+		t.Fatalf("Countersign: %v", err)
+	}
+	return st
+}
+
+func TestIdentityRegistry_VerifySignedTask_Valid(t *testing.T) {
+	r, op, lead := enrolled(t)
+	st := signAndApprove(t, validTask(time.Now().UTC()), op, lead)
+	if err := r.VerifySignedTask(st); err != nil {
+		t.Fatalf("VerifySignedTask: %v", err)
+	}
+}
+
+func TestIdentityRegistry_VerifySignedTask_WrongSigner(t *testing.T) {
+	r, _, lead := enrolled(t)
+	st := signAndApprove(t, validTask(time.Now().UTC()), newKeyPair(t), lead)
+	if err := r.VerifySignedTask(st); err == nil {
+		t.Fatal("expected task signed by an unenrolled key to fail")
+	}
+}
+
+func TestIdentityRegistry_VerifySignedTask_SignerLacksOperatorRole(t *testing.T) {
+	r, op, lead := enrolled(t)
+	obs := newKeyPair(t)
+	if err := r.Add("eng-2026-q1", Identity{Name: "obs-carol", Role: RoleObserver, PublicKey: obs.pub}); err != nil {
+		t.Fatalf("add observer: %v", err)
+	}
+	task := validTask(time.Now().UTC())
+	task.Operator = "obs-carol"
+	if err := r.VerifySignedTask(signAndApprove(t, task, obs, lead)); err == nil {
+		t.Fatal("expected observer-signed task to fail")
+	}
+	task = validTask(time.Now().UTC())
+	task.ApprovedBy = "obs-carol"
+	if err := r.VerifySignedTask(signAndApprove(t, task, op, obs)); err == nil {
+		t.Fatal("expected observer approval to fail")
+	}
+}
+
+func TestIdentityRegistry_VerifySignedTask_MissingOrForgedApproval(t *testing.T) {
+	r, op, lead := enrolled(t)
+	st, err := SignTask(validTask(time.Now().UTC()), op.priv, op.pub)
+	if err != nil {
+		t.Fatalf("SignTask: %v", err)
+	}
+	if err := r.VerifySignedTask(st); err == nil {
+		t.Fatal("expected missing approval to fail")
+	}
+	st = signAndApprove(t, validTask(time.Now().UTC()), op, lead)
+	st.Approval.Signature[0] ^= 0xff
+	if err := r.VerifySignedTask(st); err == nil {
+		t.Fatal("expected tampered approval to fail")
+	}
+	st = signAndApprove(t, validTask(time.Now().UTC()), op, newKeyPair(t))
+	if err := r.VerifySignedTask(st); err == nil {
+		t.Fatal("expected approval by a non-enrolled key to fail")
+	}
+}
+
+func TestIdentityRegistry_VerifySignedTask_OtherEngagement(t *testing.T) {
+	r, op, lead := enrolled(t)
+	task := validTask(time.Now().UTC())
+	task.Engagement = "eng-other"
+	if err := r.VerifySignedTask(signAndApprove(t, task, op, lead)); err == nil {
+		t.Fatal("expected identities from another engagement to fail")
+	}
+}
+
+func TestIdentityRegistry_Add_Invalid(t *testing.T) {
+	r, op, _ := enrolled(t)
+	if err := r.Add("eng-2026-q1", Identity{Name: "op-alice", Role: RoleOperator, PublicKey: op.pub}); err == nil {
+		t.Fatal("expected duplicate identity to fail")
+	}
+	if err := r.Add("eng-2026-q1", Identity{Name: "x", Role: Role("admin"), PublicKey: op.pub}); err == nil {
+		t.Fatal("expected invalid role to fail")
+	}
+	if err := r.Add("eng-2026-q1", Identity{Name: "y", Role: RoleLead, PublicKey: []byte("short")}); err == nil {
+		t.Fatal("expected invalid key to fail")
+	}
+}
@@ -0,0 +1,253 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrOverBudget is returned for a task whose impact score does not fit its
+// engagement's budget before the task expires.
+var ErrOverBudget = errors.New("task exceeds engagement impact budget")
+
+// ImpactRule adds to the score of tasks that set a param. For example,
+// one point per login attempt:
+//
+//	{"type": "simulate_login", "param": "attempts", "score": 1, "scale": true}
+type ImpactRule struct {
+	// Type limits the rule to one task type; empty means every type.
+	Type  TaskType `json:"type,omitempty"`
+	Param string   `json:"param"`
+	// Value, if set, is the only value of Param the rule matches,
+	// compared exactly.
+	Value string `json:"value,omitempty"`
+	Score int    `json:"score"`
+	// Scale multiplies Score by the param's value, which must be a
+	// non-negative integer for the rule to match.
+	Scale bool `json:"scale,omitempty"`
+}
+
+// ImpactModel computes a task's risk score: its type's base score plus
+// every rule it matches.
+type ImpactModel struct {
+	// Types are base scores by task type; types not listed score 1.
+	Types map[TaskType]int
+	Rules []ImpactRule
+}
+
+// DefaultImpactModel scores read-only and telemetry tasks lowest and
+// credential and data movement highest.
+var DefaultImpactModel = &ImpactModel{
+	Types: map[TaskType]int{
+		TaskInventory:               1,
+		TaskEmitSynthetic:           1,
+		TaskSimulateBeacon:          2,
+		TaskSimulateLogin:           2,
+		TaskSimulatePhish:           5,
+		TaskSimulateCredentialSpray: 8,
+		TaskSimulateExfil:           8,
+	},
+	Rules: []ImpactRule{
+		{Type: TaskSimulateLogin, Param: "attempts", Score: 1, Scale: true},
+		{Type: TaskSimulateExfil, Param: "documents", Score: 1, Scale: true},
+	},
+}
+
+// Score returns task's impact score under m; a nil model is
+// DefaultImpactModel.
+func (m *ImpactModel) Score(task Task) int {
+	if m == nil {
+		m = DefaultImpactModel
+	}
+	score, ok := m.Types[task.Type]
+	if !ok {
+		score = 1
+	}
+	for _, r := range m.Rules {
+		v, set := task.Params[r.Param]
+		if !set || (r.Type != "" && r.Type != task.Type) || (r.Value != "" && r.Value != v) {
+			continue
+		}
+		if !r.Scale {
+			score += r.Score
+		} else if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			score += r.Score * n
+		}
+	}
+	return score
+}
+
+// ImpactBudget caps the impact an engagement's tasks may add up to over a
+// sliding hour and day. A zero cap is no cap.
+type ImpactBudget struct {
+	MaxPerHour int `json:"max_per_hour,omitempty"`
+	MaxPerDay  int `json:"max_per_day,omitempty"`
+}
+
+// Validate checks that the budget caps something.
+func (b ImpactBudget) Validate() error {
+	if b.MaxPerHour < 0 || b.MaxPerDay < 0 {
+		return errors.New("impact budget values must not be negative")
+	}
+	if b.MaxPerHour == 0 && b.MaxPerDay == 0 {
+		return errors.New("impact budget needs max_per_hour or max_per_day")
+	}
+	return nil
+}
+
+// impactCharge is the score of a task the limiter admitted.
+type impactCharge struct {
+	engagement string
+	at         time.Time
+	score      int
+}
+
+// ImpactLimiter holds each engagement's simulation tasks to its
+// ImpactBudget. A task is charged its score when it starts, whether or
+// not it succeeds; a task that would exceed the budget waits until enough
+// earlier charges leave the window. It is safe for concurrent use; a nil
+// limiter admits everything.
+type ImpactLimiter struct {
+	// Model scores tasks; nil means DefaultImpactModel.
+	Model *ImpactModel
+	// Budgets maps an engagement to its budget. Engagements not listed
+	// get Default.
+	Budgets map[string]ImpactBudget
+	Default ImpactBudget
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	charges []impactCharge
+}
+
+func (l *ImpactLimiter) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+func (l *ImpactLimiter) budget(engagement string) ImpactBudget {
+	if b, ok := l.Budgets[engagement]; ok {
+		return b
+	}
+	return l.Default
+}
+
+// Acquire waits until task's score fits its engagement's budget, then
+// charges it. It returns an error wrapping ErrOverBudget at once if the
+// score can never fit, or would not fit before ctx's deadline, and if ctx
+// ends while waiting.
+func (l *ImpactLimiter) Acquire(ctx context.Context, task Task) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := l.now()
+		wait, reason := l.check(task, now)
+		if wait == 0 {
+			l.charges = append(l.charges, impactCharge{engagement: task.Engagement, at: now, score: l.Model.Score(task)})
+		}
+		l.mu.Unlock()
+		if wait == 0 {
+			return nil
+		}
+		if deadline, ok := ctx.Deadline(); wait < 0 || (ok && now.Add(wait).After(deadline)) {
+			return fmt.Errorf("%w: %s", ErrOverBudget, reason)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %s: %w", ErrOverBudget, reason, context.Cause(ctx))
+		case <-timer.C:
+		}
+	}
+}
+
+// Wait reports how long task would wait to start at now, and why, without
+// charging it. It returns 0 if the task fits, or -1 if its score exceeds a
+// cap on its own.
+func (l *ImpactLimiter) Wait(task Task, now time.Time) (time.Duration, string) {
+	if l == nil {
+		return 0, ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.check(task, now)
+}
+
+// Spent returns the impact engagement's tasks have been charged in the
+// hour and the day up to now.
+func (l *ImpactLimiter) Spent(engagement string, now time.Time) (hour, day int) {
+	if l == nil {
+		return 0, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range l.charges {
+		if c.engagement != engagement || !c.at.After(now.Add(-24*time.Hour)) {
+			continue
+		}
+		day += c.score
+		if c.at.After(now.Add(-time.Hour)) {
+			hour += c.score
+		}
+	}
+	return hour, day
+}
+
+// check returns how long task must wait at now and the cap holding it, as
+// Wait does. It also forgets charges older than a day. The caller holds
+// l.mu.
+func (l *ImpactLimiter) check(task Task, now time.Time) (time.Duration, string) {
+	kept := l.charges[:0]
+	for _, c := range l.charges {
+		if c.at.After(now.Add(-24 * time.Hour)) {
+			kept = append(kept, c)
+		}
+	}
+	clear(l.charges[len(kept):])
+	l.charges = kept
+
+	b := l.budget(task.Engagement)
+	score := l.Model.Score(task)
+	var wait time.Duration
+	var reason string
+	for _, w := range []struct {
+		name   string
+		window time.Duration
+		max    int
+	}{{"hour", time.Hour, b.MaxPerHour}, {"day", 24 * time.Hour, b.MaxPerDay}} {
+		if w.max == 0 {
+			continue
+		}
+		if score > w.max {
+			return -1, fmt.Sprintf("score %d exceeds the %d per %s budget", score, w.max, w.name)
+		}
+		// Charges are in admission order, so dropping the oldest first
+		// finds the earliest time the task fits.
+		var in []impactCharge
+		spent := 0
+		for _, c := range l.charges {
+			if c.engagement == task.Engagement && c.at.After(now.Add(-w.window)) {
+				in = append(in, c)
+				spent += c.score
+			}
+		}
+		used := spent
+		for i := 0; used+score > w.max; i++ {
+			used -= in[i].score
+			if d := in[i].at.Add(w.window).Sub(now); d > wait {
+				wait = d
+				reason = fmt.Sprintf("score %d with %d of %d spent this %s; fits in %s", score, spent, w.max, w.name, d.Round(time.Second))
+			}
+		}
+	}
+	return wait, reason
+}
@@ -0,0 +1,106 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImpactModel_Score(t *testing.T) {
+	task := validTask(time.Now())
+	if got := DefaultImpactModel.Score(task); got != 2 {
+		t.Errorf("login without attempts: %d", got)
+	}
+	task.Params["attempts"] = "5"
+	if got := (*ImpactModel)(nil).Score(task); got != 7 {
+		t.Errorf("login with 5 attempts: %d", got)
+	}
+	task.Params["attempts"] = "many"
+	if got := DefaultImpactModel.Score(task); got != 2 {
+		t.Errorf("unparseable attempts: %d", got)
+	}
+
+	m := &ImpactModel{Rules: []ImpactRule{
+		{Param: "protocol", Value: "ldap", Score: 3},
+		{Type: TaskSimulateExfil, Param: "target", Score: 10},
+	}}
+	task.Params["protocol"] = "ldap"
+	if got := m.Score(task); got != 4 {
+		t.Errorf("custom model: %d", got)
+	}
+}
+
+func TestImpactBudget_Validate(t *testing.T) {
+	for _, b := range []ImpactBudget{{}, {MaxPerHour: -1, MaxPerDay: 10}} {
+		if err := b.Validate(); err == nil {
+			t.Errorf("%+v: expected error", b)
+		}
+	}
+	if err := (ImpactBudget{MaxPerDay: 40}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImpactLimiter_Wait(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	clock := now
+	l := &ImpactLimiter{
+		Default: ImpactBudget{MaxPerHour: 5, MaxPerDay: 8},
+		Now:     func() time.Time { return clock },
+	}
+	task := validTask(now)
+	for i := 0; i < 2; i++ {
+		if err := l.Acquire(context.Background(), task); err != nil {
+			t.Fatal(err)
+		}
+		clock = clock.Add(10 * time.Minute)
+	}
+	if hour, day := l.Spent("eng-2026-q1", clock); hour != 4 || day != 4 {
+		t.Fatalf("spent %d/%d", hour, day)
+	}
+	wait, reason := l.Wait(task, clock)
+	if wait != 40*time.Minute || !strings.Contains(reason, "hour") {
+		t.Fatalf("over hourly cap: %v, %q", wait, reason)
+	}
+	other := task
+	other.Engagement = "eng-other"
+	if wait, _ := l.Wait(other, clock); wait != 0 {
+		t.Fatalf("other engagement delayed: %v", wait)
+	}
+
+	clock = now.Add(time.Hour)
+	if err := l.Acquire(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	clock = clock.Add(time.Hour)
+	if err := l.Acquire(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	if wait, reason := l.Wait(task, clock); wait != 22*time.Hour || !strings.Contains(reason, "day") {
+		t.Fatalf("over daily cap: %v, %q", wait, reason)
+	}
+
+	big := task
+	big.Params = map[string]string{"attempts": "9"}
+	if wait, _ := l.Wait(big, clock); wait != -1 {
+		t.Fatalf("task over the cap on its own: %v", wait)
+	}
+	if err := l.Acquire(context.Background(), big); !errors.Is(err, ErrOverBudget) {
+		t.Fatalf("oversized task: %v", err)
+	}
+}
+
+func TestExecutor_Impact(t *testing.T) {
+	e := NewExecutor()
+	e.Impact = &ImpactLimiter{Default: ImpactBudget{MaxPerHour: 3}}
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil }))
+	if _, err := e.Execute(context.Background(), signedValidTask(t)); err != nil {
+		t.Fatal(err)
+	}
+	// The hour the second task must wait is past its TTL.
+	if _, err := e.Execute(context.Background(), signedValidTask(t)); !errors.Is(err, ErrOverBudget) {
+		t.Fatalf("over budget: got %v", err)
+	}
+}
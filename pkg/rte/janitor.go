@@ -0,0 +1,276 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DefaultJanitorInterval is how often Janitor.Run sweeps by default.
+const DefaultJanitorInterval = time.Minute
+
+// RepairKind names a fix the janitor applied.
+type RepairKind string
+
+const (
+	// RepairRequeue: a pending task was neither queued nor leased, or its
+	// lease expired, so it was put back on the queue.
+	RepairRequeue RepairKind = "requeue"
+	// RepairReleaseLease: a terminal task still carried a lease.
+	RepairReleaseLease RepairKind = "release_lease"
+	// RepairFailStuck: a task was still executing or paused past its TTL
+	// with no live lease, its agent presumed lost.
+	RepairFailStuck RepairKind = "fail_stuck"
+	// RepairFailExpired: a pending task expired before any agent ran it.
+	RepairFailExpired RepairKind = "fail_expired"
+	// RepairDropQueued: a queued task was missing from the store or
+	// already terminal.
+	RepairDropQueued RepairKind = "drop_queued"
+	// RepairPurge: a soft-deleted task's restore window passed, so it was
+	// removed from a PurgeStore.
+	RepairPurge RepairKind = "purge"
+)
+
+// Repair records one fix made by a janitor sweep.
+type Repair struct {
+	Kind       RepairKind `json:"kind"`
+	Engagement string     `json:"engagement"`
+	TaskID     string     `json:"task_id"`
+	Detail     string     `json:"detail"`
+	At         time.Time  `json:"at"`
+}
+
+// Janitor repairs what a crashed controller or agent leaves behind: leases
+// nobody will renew, tasks stuck executing past their TTL, and a queue that
+// disagrees with the store. On a PurgeStore it also purges soft-deleted
+// tasks whose restore window has passed. Every repair is written to Audit.
+type Janitor struct {
+	Store TaskStore
+	// Queue, if set, is checked against the store and receives requeued
+	// tasks. Without it pending tasks are never requeued.
+	Queue *Queue
+	// Audit, if set, receives one record per repair under action
+	// "janitor.<kind>".
+	Audit *AuditLog
+	// Interval is the time between sweeps; 0 means DefaultJanitorInterval.
+	Interval time.Duration
+	// Now returns the current time; nil means time.Now.
+	Now    func() time.Time
+	Logger *slog.Logger
+}
+
+func (j *Janitor) now() time.Time {
+	if j.Now != nil {
+		return j.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// Run sweeps every Interval until ctx ends. Sweep errors are logged, not
+// returned, so one bad pass does not stop the janitor.
+func (j *Janitor) Run(ctx context.Context) error {
+	if j.Store == nil {
+		return errors.New("janitor needs a task store")
+	}
+	interval := j.Interval
+	if interval <= 0 {
+		interval = DefaultJanitorInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := j.Sweep(ctx); err != nil && j.Logger != nil && ctx.Err() == nil {
+			j.Logger.Error("janitor sweep", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Sweep makes one pass over the store and queue and returns the repairs
+// it made. It keeps going after a failed repair and returns the errors
+// joined.
+//
+// An executing task whose lease lapsed before its TTL is left alone: the
+// agent may still be running it behind a partition, and failing it early
+// would let a second agent start the same run. It becomes stuck, and is
+// failed, once the TTL passes too.
+func (j *Janitor) Sweep(ctx context.Context) ([]Repair, error) {
+	if j.Store == nil {
+		return nil, errors.New("janitor needs a task store")
+	}
+	now := j.now()
+	recs, err := j.Store.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list tasks: %w", err)
+	}
+	queued := make(map[[2]string]bool)
+	if j.Queue != nil {
+		for _, m := range j.Queue.Snapshot() {
+			if m.Kind == MessageTask {
+				queued[[2]string{m.Task.Task.Engagement, m.Task.Task.ID}] = true
+			}
+		}
+	}
+
+	var repairs []Repair
+	var errs []error
+	record := func(r Repair, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s/%s: %w", r.Kind, r.Engagement, r.TaskID, err))
+			return
+		}
+		r.At = now
+		repairs = append(repairs, r)
+	}
+	known := make(map[[2]string]bool, len(recs))
+	for _, rec := range recs {
+		key := [2]string{rec.Engagement(), rec.ID()}
+		if !rec.Terminal() {
+			known[key] = true
+		}
+		if r, ok := j.inspect(rec, now, queued[key]); ok {
+			record(r, j.apply(ctx, r, rec, now))
+		}
+	}
+	if j.Queue != nil {
+		for key := range queued {
+			if known[key] {
+				continue
+			}
+			r := Repair{Kind: RepairDropQueued, Engagement: key[0], TaskID: key[1], Detail: "queued task is missing from the store or already terminal"}
+			j.Queue.Remove(func(m Message) bool {
+				return m.Kind == MessageTask && m.Task.Task.Engagement == key[0] && m.Task.Task.ID == key[1]
+			})
+			record(r, nil)
+		}
+	}
+	for _, r := range repairs {
+		j.report(r)
+	}
+	return repairs, errors.Join(errs...)
+}
+
+// inspect decides what, if anything, rec needs.
+func (j *Janitor) inspect(rec TaskRecord, now time.Time, queued bool) (Repair, bool) {
+	r := Repair{Engagement: rec.Engagement(), TaskID: rec.ID()}
+	task := rec.Task.Task
+	skew := CurrentValidatorConfig().ClockSkew
+	expired := now.After(task.Expiry().Add(skew))
+	// A run may outlast its approval by up to the TTL.
+	overdue := now.After(task.RunExpiry(task.Expiry()).Add(skew))
+	_, purges := j.Store.(PurgeStore)
+	switch {
+	case purges && rec.Purgeable(now):
+		r.Kind, r.Detail = RepairPurge, fmt.Sprintf("deleted by %s; restore window ended %s", rec.Deletion.By, rec.Deletion.PurgeAfter.Format(time.RFC3339))
+	case rec.Terminal():
+		if rec.Lease == nil {
+			return r, false
+		}
+		r.Kind, r.Detail = RepairReleaseLease, fmt.Sprintf("%s task still leased by %s", rec.State, rec.Lease.Agent)
+	case rec.State == StatePending && expired:
+		r.Kind, r.Detail = RepairFailExpired, "task expired while pending"
+	case rec.State == StatePending:
+		switch {
+		case rec.Lease != nil && rec.Lease.Expired(now):
+			r.Kind, r.Detail = RepairRequeue, fmt.Sprintf("lease held by %s expired at %s", rec.Lease.Agent, rec.Lease.ExpiresAt.Format(time.RFC3339))
+		case rec.Lease == nil && !queued && j.Queue != nil:
+			r.Kind, r.Detail = RepairRequeue, "pending task was neither queued nor leased"
+		default:
+			return r, false
+		}
+	default: // executing or paused
+		if !overdue || (rec.Lease != nil && !rec.Lease.Expired(now)) {
+			return r, false
+		}
+		agent := "no agent"
+		if rec.Lease != nil {
+			agent = rec.Lease.Agent
+		}
+		r.Kind, r.Detail = RepairFailStuck, fmt.Sprintf("task %s past its TTL with no live lease (%s)", rec.State, agent)
+	}
+	return r, true
+}
+
+// apply makes r against the store, re-checking the record under Update so
+// a task that moved on since the List is left alone.
+func (j *Janitor) apply(ctx context.Context, r Repair, seen TaskRecord, now time.Time) error {
+	if r.Kind == RepairPurge {
+		err := j.Store.(PurgeStore).Purge(ctx, r.Engagement, r.TaskID, now)
+		if errors.Is(err, ErrNotPurgeable) {
+			return nil
+		}
+		return err
+	}
+	err := j.Store.Update(ctx, r.Engagement, r.TaskID, func(rec *TaskRecord) error {
+		if rec.State != seen.State || !sameLease(rec.Lease, seen.Lease) {
+			return errRaced
+		}
+		switch r.Kind {
+		case RepairReleaseLease, RepairRequeue:
+			rec.Lease = nil
+		case RepairFailStuck, RepairFailExpired:
+			rec.Lease = nil
+			rec.State = StateFailed
+			rec.Result = janitorResult(rec, r, now)
+		}
+		rec.UpdatedAt = now
+		return nil
+	})
+	if errors.Is(err, errRaced) {
+		return nil
+	}
+	if err != nil || r.Kind != RepairRequeue {
+		return err
+	}
+	if j.Queue == nil {
+		return nil
+	}
+	j.Queue.Remove(func(m Message) bool {
+		return m.Kind == MessageTask && m.Task.Task.Engagement == r.Engagement && m.Task.Task.ID == r.TaskID
+	})
+	return j.Queue.Push(TaskMessage(seen.Task))
+}
+
+// errRaced aborts a repair whose record changed after the sweep read it.
+var errRaced = errors.New("record changed during sweep")
+
+func sameLease(a, b *Lease) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func janitorResult(rec *TaskRecord, r Repair, now time.Time) *TaskResult {
+	res := &TaskResult{
+		TaskID:     rec.ID(),
+		Engagement: rec.Engagement(),
+		Type:       rec.Task.Task.Type,
+		Operator:   rec.Task.Task.Operator,
+		State:      StateFailed,
+		FinishedAt: now,
+		Error:      "janitor: " + r.Detail,
+	}
+	if rec.Result != nil {
+		res.StartedAt = rec.Result.StartedAt
+	}
+	return res
+}
+
+func (j *Janitor) report(r Repair) {
+	if j.Logger != nil {
+		j.Logger.Warn("janitor repair", "kind", string(r.Kind), "engagement", r.Engagement, "task_id", r.TaskID, "detail", r.Detail)
+	}
+	if j.Audit == nil {
+		return
+	}
+	if _, err := j.Audit.Append(r.Engagement, "janitor."+string(r.Kind), "janitor", r.TaskID, r); err != nil && j.Logger != nil {
+		j.Logger.Error("janitor audit", "error", err)
+	}
+}
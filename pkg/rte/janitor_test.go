@@ -0,0 +1,101 @@
+package rte
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestJanitor_Sweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Round(0)
+	s := NewMemoryStore()
+	q := NewQueue()
+	audit := NewAuditLog("janitor")
+	j := &Janitor{Store: s, Queue: q, Audit: audit, Now: func() time.Time { return now }}
+
+	put := func(id string, created time.Time, state TaskState, lease *Lease) TaskRecord {
+		rec := storedTask(t, id, created, state)
+		rec.Lease = lease
+		if err := s.Put(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	lapsed := &Lease{Agent: "agent-a", AcquiredAt: now.Add(-2 * time.Minute), ExpiresAt: now.Add(-time.Minute)}
+	live := &Lease{Agent: "agent-b", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}
+	old := now.Add(-time.Hour) // past validTask's ten-minute TTL
+
+	put("orphan-lease", now, StatePending, lapsed)
+	put("lost", now, StatePending, nil)
+	put("queued", now, StatePending, nil)
+	put("done-leased", now, StateCompleted, live)
+	put("stuck", old, StateExecuting, lapsed)
+	put("expired", old, StatePending, nil)
+	put("partitioned", now, StateExecuting, lapsed)
+	put("running", old, StateExecuting, live)
+	done := put("done-queued", now, StateCompleted, nil)
+	ghost := storedTask(t, "ghost", now, StatePending)
+	queued, _ := s.Get(ctx, "eng-2026-q1", "queued")
+	for _, st := range []*SignedTask{queued.Task, done.Task, ghost.Task} {
+		if err := q.Push(TaskMessage(st)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repairs, err := j.Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]RepairKind{}
+	for _, r := range repairs {
+		got[r.TaskID] = r.Kind
+	}
+	want := map[string]RepairKind{
+		"orphan-lease": RepairRequeue,
+		"lost":         RepairRequeue,
+		"done-leased":  RepairReleaseLease,
+		"stuck":        RepairFailStuck,
+		"expired":      RepairFailExpired,
+		"done-queued":  RepairDropQueued,
+		"ghost":        RepairDropQueued,
+	}
+	if len(got) != len(want) {
+		t.Errorf("repairs = %v, want %v", got, want)
+	}
+	for id, k := range want {
+		if got[id] != k {
+			t.Errorf("%s: repair %q, want %q", id, got[id], k)
+		}
+	}
+
+	for id, state := range map[string]TaskState{"stuck": StateFailed, "expired": StateFailed, "partitioned": StateExecuting, "running": StateExecuting} {
+		rec, _ := s.Get(ctx, "eng-2026-q1", id)
+		if rec.State != state {
+			t.Errorf("%s state = %s, want %s", id, rec.State, state)
+		}
+	}
+	if rec, _ := s.Get(ctx, "eng-2026-q1", "stuck"); rec.Lease != nil || rec.Result == nil || rec.Result.State != StateFailed {
+		t.Errorf("stuck record = %+v", rec)
+	}
+	ids := map[string]int{}
+	for _, m := range q.Snapshot() {
+		ids[m.Task.Task.ID]++
+	}
+	if len(ids) != 3 || ids["orphan-lease"] != 1 || ids["lost"] != 1 || ids["queued"] != 1 {
+		t.Errorf("queue after sweep = %v", ids)
+	}
+
+	recs := audit.Records("eng-2026-q1")
+	if len(recs) != len(want) || recs[0].Action[:8] != "janitor." {
+		t.Errorf("audit records = %d, want %d", len(recs), len(want))
+	}
+	if err := VerifyAuditChain(recs); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second sweep finds nothing left to repair.
+	if again, err := j.Sweep(ctx); err != nil || len(again) != 0 {
+		t.Errorf("second sweep = %+v, %v", again, err)
+	}
+}
@@ -0,0 +1,91 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Parent returns the task t re-issues or supersedes, and whether it names
+// one.
+func (t Task) Parent() (string, bool) {
+	if t.ReissuedFromID != "" {
+		return t.ReissuedFromID, true
+	}
+	return t.SupersedesID, t.SupersedesID != ""
+}
+
+// CheckLineage checks t's lineage against the engagement's records in s:
+// the task it re-issues or supersedes must be stored and terminal, and no
+// other task may already re-issue or supersede it, so every attempt at an
+// objective forms one chain. Failures wrap ErrBadLineage.
+func CheckLineage(ctx context.Context, s TaskStore, t Task) error {
+	parent, ok := t.Parent()
+	if !ok {
+		return nil
+	}
+	rec, err := s.Get(ctx, t.Engagement, parent)
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		return fmt.Errorf("%w: task %s names %s, which %s does not hold", ErrBadLineage, t.ID, parent, t.Engagement)
+	case err != nil:
+		return err
+	case !rec.Terminal():
+		return fmt.Errorf("%w: task %s is %s; only terminal tasks may be re-issued or superseded", ErrBadLineage, parent, rec.State)
+	}
+	recs, err := s.List(ctx, t.Engagement)
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		if p, ok := rec.Task.Task.Parent(); ok && p == parent && rec.ID() != t.ID {
+			return fmt.Errorf("%w: task %s already follows %s", ErrBadLineage, rec.ID(), parent)
+		}
+	}
+	return nil
+}
+
+// Lineage returns the chain of re-issued and superseding tasks that id
+// belongs to, first attempt first. A task without lineage is a chain of
+// one.
+func Lineage(ctx context.Context, s TaskStore, engagement, id string) ([]TaskRecord, error) {
+	recs, err := s.List(ctx, engagement)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]TaskRecord, len(recs))
+	next := make(map[string]string)
+	for _, rec := range recs {
+		byID[rec.ID()] = rec
+		if p, ok := rec.Task.Task.Parent(); ok {
+			next[p] = rec.ID()
+		}
+	}
+	rec, ok := byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrTaskNotFound, engagement, id)
+	}
+	// Walk back to the first attempt, then forward to the last. seen
+	// guards against cycles, which CheckLineage prevents but a store
+	// written around it might hold.
+	seen := map[string]bool{id: true}
+	for {
+		p, ok := rec.Task.Task.Parent()
+		if !ok || seen[p] {
+			break
+		}
+		prev, ok := byID[p]
+		if !ok {
+			break
+		}
+		seen[p] = true
+		rec = prev
+	}
+	chain := []TaskRecord{rec}
+	in := map[string]bool{rec.ID(): true}
+	for n, ok := next[rec.ID()]; ok && !in[n]; n, ok = next[n] {
+		in[n] = true
+		chain = append(chain, byID[n])
+	}
+	return chain, nil
+}
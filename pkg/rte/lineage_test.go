@@ -0,0 +1,92 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckLineage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	s := NewMemoryStore()
+	for _, r := range []struct {
+		id    string
+		state TaskState
+	}{{"t-1", StateFailed}, {"t-2", StateExecuting}, {"t-3", StateCancelled}} {
+		if err := s.Put(ctx, storedTask(t, r.id, now, r.state)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	retry := validTask(now)
+	retry.ID, retry.ReissuedFromID = "t-4", "t-1"
+	if err := CheckLineage(ctx, s, retry); err != nil {
+		t.Fatalf("re-issue of a failed task: %v", err)
+	}
+	for name, parent := range map[string]string{"missing": "t-9", "live": "t-2"} {
+		task := validTask(now)
+		task.ID, task.SupersedesID = "t-5", parent
+		if err := CheckLineage(ctx, s, task); !errors.Is(err, ErrBadLineage) {
+			t.Errorf("%s parent: %v", name, err)
+		}
+	}
+
+	rec := storedTask(t, "t-4", now, StatePending)
+	rec.Task.Task.ReissuedFromID = "t-1"
+	if err := s.Put(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	retry.ID = "t-6"
+	if err := CheckLineage(ctx, s, retry); !errors.Is(err, ErrBadLineage) {
+		t.Errorf("second re-issue of one task: %v", err)
+	}
+
+	both := validTask(now)
+	both.ReissuedFromID, both.SupersedesID = "t-1", "t-3"
+	if err := both.Validate(now); !errors.Is(err, ErrBadLineage) {
+		t.Errorf("task with two parents: %v", err)
+	}
+	both.ReissuedFromID, both.SupersedesID = both.ID, ""
+	if err := both.Validate(now); !errors.Is(err, ErrBadLineage) {
+		t.Errorf("task naming itself: %v", err)
+	}
+}
+
+func TestLineage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	s := NewMemoryStore()
+	for _, r := range []struct{ id, reissues, supersedes string }{
+		{"a-1", "", ""}, {"a-2", "a-1", ""}, {"a-3", "", "a-2"}, {"b-1", "", ""},
+	} {
+		rec := storedTask(t, r.id, now, StateFailed)
+		rec.Task.Task.ReissuedFromID, rec.Task.Task.SupersedesID = r.reissues, r.supersedes
+		if err := s.Put(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"a-1", "a-2", "a-3"} {
+		chain, err := Lineage(ctx, s, "eng-2026-q1", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chain) != 3 || chain[0].ID() != "a-1" || chain[1].ID() != "a-2" || chain[2].ID() != "a-3" {
+			t.Errorf("Lineage(%s) = %v", id, ids(chain))
+		}
+	}
+	if chain, err := Lineage(ctx, s, "eng-2026-q1", "b-1"); err != nil || len(chain) != 1 {
+		t.Errorf("Lineage(b-1) = %v, %v", ids(chain), err)
+	}
+	if _, err := Lineage(ctx, s, "eng-2026-q1", "z-1"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Lineage of a missing task: %v", err)
+	}
+}
+
+func ids(recs []TaskRecord) []string {
+	out := make([]string, len(recs))
+	for i, r := range recs {
+		out[i] = r.ID()
+	}
+	return out
+}
@@ -0,0 +1,175 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Violation is one rule a task breaks, explained well enough for the
+// operator to fix the task without asking whoever wrote the policy.
+type Violation struct {
+	// Rule names the rule, such as "phish.approver" or "data.rte.deny".
+	Rule string `json:"rule,omitempty"`
+	// Field, if known, is the JSON path of the signed task's field at
+	// fault, such as "task.priority" or "task.params.target".
+	Field string `json:"field,omitempty"`
+	// Source is where the rule is defined: a file and line, a policy
+	// bundle revision, or the Go evaluator that enforces it.
+	Source  string `json:"source,omitempty"`
+	Message string `json:"message"`
+	// Remediation says how to change the task, or whom to ask, so it
+	// passes.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// String renders the violation on one line, rule and source after the
+// message.
+func (v Violation) String() string {
+	var where []string
+	if v.Rule != "" {
+		where = append(where, v.Rule)
+	}
+	if v.Source != "" {
+		where = append(where, v.Source)
+	}
+	if len(where) == 0 {
+		return v.Message
+	}
+	return fmt.Sprintf("%s [%s]", v.Message, strings.Join(where, " @ "))
+}
+
+// PolicyError is a task rejected by Lint or EnforcePolicy, with every
+// violation found. It is what a controller returns, as JSON, for a
+// rejected submission (see WritePolicyError).
+type PolicyError struct {
+	TaskID     string      `json:"task_id,omitempty"`
+	Violations []Violation `json:"violations"`
+}
+
+func (e *PolicyError) Error() string {
+	if len(e.Violations) == 0 {
+		return "denied by policy"
+	}
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return fmt.Sprintf("denied by policy: [%s]", strings.Join(msgs, " "))
+}
+
+// Explain renders every violation with its rule, source, and remediation,
+// one indented block each, for a terminal.
+func (e *PolicyError) Explain() string {
+	var b strings.Builder
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "%s- %s\n", explainIndent, v.Message)
+		if v.Rule != "" {
+			fmt.Fprintf(&b, "%s    rule:   %s\n", explainIndent, v.Rule)
+		}
+		if v.Source != "" {
+			fmt.Fprintf(&b, "%s    source: %s\n", explainIndent, v.Source)
+		}
+		if v.Remediation != "" {
+			fmt.Fprintf(&b, "%s    fix:    %s\n", explainIndent, v.Remediation)
+		}
+	}
+	return b.String()
+}
+
+const explainIndent = "    "
+
+// violationFor names a Task.Validate failure, says how to fix it, and
+// gives its DenialCode and the field at fault.
+var violationFor = []struct {
+	err         error
+	rule        string
+	remediation string
+	code        DenialCode
+	field       string
+}{
+	{ErrMissingID, "task.id", "set id, or let the controller assign one", DenialInvalid, "task.id"},
+	{ErrMissingEngagement, "task.engagement", "set engagement to the engagement the task runs under", DenialInvalid, "task.engagement"},
+	{ErrMissingOperator, "task.operator", "set operator to the submitting operator", DenialInvalid, "task.operator"},
+	{ErrMissingApprover, "task.approved_by", "have an approver countersign the task", DenialInvalid, "task.approved_by"},
+	{ErrUnsupportedType, "task.type", "use one of the task types the controller supports", DenialInvalid, "task.type"},
+	{ErrBadTTL, "task.ttl_seconds", "set ttl_seconds within the bounds given, or mark a long-running task standing", DenialInvalid, "task.ttl_seconds"},
+	{ErrInvalidState, "task.state", "submit new tasks as pending", DenialInvalid, "task.state"},
+	{ErrBadPriority, "task.priority", fmt.Sprintf("set priority between 0 and %d", MaxPriority), DenialInvalid, "task.priority"},
+	{ErrBadTechnique, "task.techniques", "use ATT&CK technique IDs such as T1110 or T1110.003", DenialInvalid, "task.techniques"},
+	{ErrBadClassification, "task.classification", "use PUBLIC, INTERNAL, CONFIDENTIAL, or RESTRICTED", DenialInvalid, "task.classification"},
+	{ErrBadSelector, "task.selector", "write the selector as comma-separated key=value, key!=value, key, or !key terms", DenialInvalid, "task.selector"},
+	{ErrBadIdempotencyKey, "task.idempotency_key", fmt.Sprintf("use at most %d printable characters", maxIdempotencyKeyLen), DenialInvalid, "task.idempotency_key"},
+	{ErrBadPhase, "task.phase", "use recon, initial_access, persistence, or cleanup", DenialInvalid, "task.phase"},
+	{ErrBadLineage, "task.lineage", "name one terminal task of the engagement in reissued_from_id or supersedes_id", DenialInvalid, ""},
+	{ErrBadNotBefore, "task.not_before", "set not_before earlier, or raise ttl_seconds", DenialInvalid, "task.not_before"},
+	{ErrBadApprovalExpiry, "task.approval_expires_at", "set approval_expires_at after created_at and within the bounds given", DenialInvalid, "task.approval_expires_at"},
+	{ErrNotYetValid, "task.created_at", "check the submitting host's clock, or wait until not_before", DenialNotYetValid, "task.created_at"},
+	{ErrExpired, "task.ttl", "re-sign the task with a fresh created_at", DenialExpired, "task.created_at"},
+}
+
+// Lint checks a task as a controller does before accepting it: Validate
+// at now, then p, if not nil. It returns a *PolicyError listing every
+// problem, or nil; an evaluator failure is returned as is, since it says
+// nothing about the task.
+func Lint(ctx context.Context, p PolicyEvaluator, task Task, now time.Time) error {
+	var vs []Violation
+	var verrs ValidationErrors
+	if err := task.Validate(now); errors.As(err, &verrs) {
+		for _, err := range verrs {
+			vs = append(vs, validationViolation(err))
+		}
+	}
+	if p != nil {
+		d, err := p.Evaluate(ctx, task)
+		if err != nil {
+			return fmt.Errorf("policy evaluation: %w", err)
+		}
+		if !d.Allow {
+			vs = append(vs, d.violations()...)
+		}
+	}
+	if len(vs) == 0 {
+		return nil
+	}
+	return &PolicyError{TaskID: task.ID, Violations: vs}
+}
+
+func validationViolation(err error) Violation {
+	return validationDenial(err).Violation
+}
+
+// validationDenial explains a Task.Validate failure.
+func validationDenial(err error) Denial {
+	d := Denial{Code: DenialInvalid, Violation: Violation{Source: "rte.Task.Validate", Message: err.Error()}}
+	for _, f := range violationFor {
+		if errors.Is(err, f.err) {
+			d.Code, d.Rule, d.Field, d.Remediation = f.code, f.rule, f.field, f.remediation
+			break
+		}
+	}
+	return d
+}
+
+// WritePolicyError answers a rejected submission: if err is or wraps a
+// *PolicyError, it writes it as JSON with status 403, alongside an "error"
+// message for clients that read only that, and reports true. Otherwise it
+// writes nothing and reports false.
+func WritePolicyError(w http.ResponseWriter, err error) bool {
+	var pe *PolicyError
+	if !errors.As(err, &pe) {
+		return false
+	}
+	body := struct {
+		Error string `json:"error"`
+		*PolicyError
+	}{pe.Error(), pe}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(body)
+	return true
+}
@@ -0,0 +1,103 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLint(t *testing.T) {
+	now := time.Now().UTC()
+	task := validTask(now)
+	if err := Lint(context.Background(), allow(), task, now); err != nil {
+		t.Fatalf("valid task: %v", err)
+	}
+
+	task.Type = TaskSimulatePhish
+	task.Priority = MaxPriority + 1
+	policy := AllOf(deny("no phishing on fridays"), PhishApprovalPolicy("lead-carol"))
+	err := Lint(context.Background(), policy, task, now)
+	var pe *PolicyError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Lint = %v, want a *PolicyError", err)
+	}
+	if pe.TaskID != task.ID {
+		t.Errorf("TaskID = %q", pe.TaskID)
+	}
+	rules := make([]string, len(pe.Violations))
+	for i, v := range pe.Violations {
+		rules[i] = v.Rule
+	}
+	want := []string{"task.priority", "", "phish.approver", "phish.approval_ref"}
+	if strings.Join(rules, ",") != strings.Join(want, ",") {
+		t.Fatalf("rules = %q, want %q", rules, want)
+	}
+	if v := pe.Violations[0]; v.Source != "rte.Task.Validate" || !strings.Contains(v.Remediation, "between 0 and") {
+		t.Errorf("validation violation = %+v", v)
+	}
+	if v := pe.Violations[2]; v.Source != "rte.PhishApprovalPolicy" || v.Remediation == "" {
+		t.Errorf("phish violation = %+v", v)
+	}
+	explained := pe.Explain()
+	for _, s := range []string{"- no phishing on fridays\n", "rule:   phish.approval_ref", "fix:    set the phish_approval_ref"} {
+		if !strings.Contains(explained, s) {
+			t.Errorf("Explain lacks %q:\n%s", s, explained)
+		}
+	}
+
+	boom := errors.New("boom")
+	failing := PolicyFunc(func(context.Context, Task) (Decision, error) { return Decision{}, boom })
+	if err := Lint(context.Background(), failing, validTask(now), now); !errors.Is(err, boom) || errors.As(err, &pe) {
+		t.Errorf("evaluator failure = %v", err)
+	}
+}
+
+func TestParamPolicy_ViolationExplains(t *testing.T) {
+	above := 100.0
+	p := &ParamPolicy{Rules: []ParamRule{
+		{Key: "target", Values: []string{"prod-db"}},
+		{Key: "rate_per_second", Above: &above, Roles: []Role{RoleLead}, Operators: []string{"op-dana"}, Source: "rules.json:7"},
+	}}
+	task := validTask(time.Now().UTC())
+	task.Params = map[string]string{"rate_per_second": "500", "target": "prod-db"}
+	d, _ := p.Evaluate(context.Background(), task)
+	if d.Allow || len(d.Violations) != 2 {
+		t.Fatalf("decision = %+v", d)
+	}
+	rate, target := d.Violations[0], d.Violations[1]
+	if rate.Rule != "param_rules[1]" || rate.Source != "rules.json:7" || rate.Remediation != "choose another value, or have a lead or op-dana set rate_per_second" {
+		t.Errorf("rate violation = %+v", rate)
+	}
+	if target.Source != "rte.ParamPolicy" || !strings.Contains(target.Remediation, "no one may set target") {
+		t.Errorf("target violation = %+v", target)
+	}
+}
+
+func TestWritePolicyError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if WritePolicyError(rec, errors.New("disk full")) || rec.Body.Len() != 0 {
+		t.Fatal("wrote a non-policy error")
+	}
+	pe := &PolicyError{TaskID: "t-1", Violations: []Violation{{Rule: "r", Source: "s", Message: "m", Remediation: "fix"}}}
+	if !WritePolicyError(rec, errors.Join(errors.New("submit t-1"), pe)) {
+		t.Fatal("did not write a wrapped policy error")
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d", rec.Code)
+	}
+	var body struct {
+		Error string `json:"error"`
+		PolicyError
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error != "denied by policy: [m]" || body.TaskID != "t-1" || len(body.Violations) != 1 || body.Violations[0] != pe.Violations[0] {
+		t.Errorf("body = %+v", body)
+	}
+}
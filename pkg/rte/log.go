@@ -0,0 +1,22 @@
+package rte
+
+import (
+	"log/slog"
+
+	"github.com/codethor0/rte-a-reference/pkg/logging"
+)
+
+// TaskLogger returns l tagged with the task's correlation keys. Params are
+// logged whole and rely on the logging package's redaction; a nil l yields a
+// discarding logger.
+func TaskLogger(l *slog.Logger, task Task) *slog.Logger {
+	if l == nil {
+		l = logging.Discard()
+	}
+	return l.With(
+		logging.KeyEngagement, task.Engagement,
+		logging.KeyTaskID, task.ID,
+		logging.KeyOperator, task.Operator,
+		logging.KeyTaskType, string(task.Type),
+	)
+}
@@ -0,0 +1,54 @@
+package rte
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/logging"
+)
+
+func TestExecutor_LogsWithCorrelation(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewExecutor()
+	e.Logger = logging.New(&buf, logging.Options{Component: "agent"})
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil }))
+
+	pub, priv, _ := GenerateKeyPair()
+	task := validTask(time.Now().UTC())
+	task.Params = map[string]string{"target": "10.0.0.5:22", "password": "Winter2026!"}
+	st, _ := SignTask(task, priv, pub)
+	if _, err := e.Execute(context.Background(), st); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if strings.Contains(buf.String(), "Winter2026!") {
+		t.Fatalf("password leaked into logs: %s", buf.String())
+	}
+
+	var lines []map[string]any
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var m map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("bad log line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, m)
+	}
+	if len(lines) != 2 || lines[1]["msg"] != "task finished" || lines[1]["state"] != "completed" {
+		t.Fatalf("unexpected log lines %v", lines)
+	}
+	for _, l := range lines {
+		if l[logging.KeyComponent] != "agent" || l[logging.KeyEngagement] != task.Engagement ||
+			l[logging.KeyTaskID] != task.ID || l[logging.KeyOperator] != task.Operator {
+			t.Errorf("line missing correlation keys: %v", l)
+		}
+	}
+}
+
+func TestTaskLogger_Nil(t *testing.T) {
+	TaskLogger(nil, validTask(time.Now())).Info("dropped")
+}
@@ -0,0 +1,62 @@
+package rte
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EngagementMetadata is how an engagement's deliverables are branded and
+// marked. Report generators and exporters carry it onto every artifact, so
+// markings do not depend on whoever runs the export remembering them.
+type EngagementMetadata struct {
+	// ClientName is the customer's display name.
+	ClientName string `json:"client_name,omitempty"`
+	// Logo is the path of the client's PNG or JPEG logo.
+	Logo string `json:"logo,omitempty"`
+	// Classification is the engagement's level, printed on every page of
+	// its deliverables. Artifacts not marked otherwise are at this level.
+	Classification Classification `json:"classification,omitempty"`
+	// Distribution is the distribution statement, such as "Distribution
+	// limited to ACME security staff."
+	Distribution string `json:"distribution,omitempty"`
+}
+
+// maxDistribution keeps markings to what fits a page header.
+const maxDistribution = 512
+
+// Validate checks that the classification is a known level and the
+// markings fit on one line.
+func (m EngagementMetadata) Validate() error {
+	var errs []error
+	if err := m.Classification.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, f := range []struct {
+		name, v string
+		max     int
+	}{
+		{"client name", m.ClientName, maxDistribution},
+		{"distribution statement", m.Distribution, maxDistribution},
+	} {
+		if strings.ContainsAny(f.v, "\r\n") {
+			errs = append(errs, fmt.Errorf("%s must be one line", f.name))
+		}
+		if len(f.v) > f.max {
+			errs = append(errs, fmt.Errorf("%s is longer than %d bytes", f.name, f.max))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Marking returns the classification and distribution statement as one
+// line, or "" if there are neither.
+func (m EngagementMetadata) Marking() string {
+	switch {
+	case m.Classification == "":
+		return m.Distribution
+	case m.Distribution == "":
+		return string(m.Classification)
+	}
+	return string(m.Classification) + " // " + m.Distribution
+}
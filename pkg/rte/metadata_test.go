@@ -0,0 +1,27 @@
+package rte
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEngagementMetadata(t *testing.T) {
+	m := EngagementMetadata{ClientName: "ACME", Classification: "CONFIDENTIAL", Distribution: "ACME security staff only."}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got := m.Marking(); got != "CONFIDENTIAL // ACME security staff only." {
+		t.Errorf("Marking = %q", got)
+	}
+	if got := (EngagementMetadata{Distribution: "Internal."}).Marking(); got != "Internal." {
+		t.Errorf("Marking = %q", got)
+	}
+	m.Classification = "SECRET"
+	m.ClientName = "ACME\nInc"
+	m.Distribution = strings.Repeat("x", maxDistribution+1)
+	err := m.Validate()
+	if err == nil || !strings.Contains(err.Error(), "unknown classification") ||
+		!strings.Contains(err.Error(), "one line") || !strings.Contains(err.Error(), "longer than") {
+		t.Errorf("Validate = %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package rte
+
+import (
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/metrics"
+)
+
+// Stages at which a task can be rejected, used as the "stage" label on
+// rte_tasks_rejected_total.
+const (
+	RejectVerify     = "verify"
+	RejectState      = "state"
+	RejectHandler    = "handler"
+	RejectHalted     = "halted"
+	RejectClock      = "clock"
+	RejectThrottled  = "throttled"
+	RejectOverBudget = "over_budget"
+)
+
+// Metrics are the task lifecycle instruments shared by controllers,
+// executors, and agents. A nil *Metrics records nothing, so components can
+// take one as an optional field.
+type Metrics struct {
+	signed    *metrics.CounterVec
+	verified  *metrics.CounterVec
+	rejected  *metrics.CounterVec
+	verifyDur *metrics.HistogramVec
+	execDur   *metrics.HistogramVec
+	cancelled *metrics.CounterVec
+	queue     *metrics.GaugeVec
+	detectLat *metrics.HistogramVec
+	missed    *metrics.CounterVec
+	clockStep *metrics.CounterVec
+	control   *metrics.HistogramVec
+}
+
+// NewMetrics registers the task lifecycle metrics in reg.
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		signed:    reg.NewCounterVec("rte_tasks_signed_total", "Tasks signed by an operator.", "type"),
+		verified:  reg.NewCounterVec("rte_tasks_verified_total", "Signed tasks that passed verification.", "type"),
+		rejected:  reg.NewCounterVec("rte_tasks_rejected_total", "Tasks rejected before execution.", "type", "stage"),
+		verifyDur: reg.NewHistogramVec("rte_verification_duration_seconds", "Time spent verifying signed tasks.", nil),
+		execDur:   reg.NewHistogramVec("rte_task_execution_duration_seconds", "Handler run time by task type and final state.", nil, "type", "state"),
+		cancelled: reg.NewCounterVec("rte_tasks_cancelled_total", "Tasks cancelled before their handler finished.", "type"),
+		queue:     reg.NewGaugeVec("rte_queue_depth", "Tasks waiting to execute."),
+		detectLat: reg.NewHistogramVec("rte_detection_latency_seconds", "Time from task emission to SIEM alert, by technique.",
+			[]float64{10, 30, 60, 300, 900, 1800, 3600, 4 * 3600, 24 * 3600}, "technique"),
+		missed:    reg.NewCounterVec("rte_detections_missed_total", "Expected detections never confirmed in the SIEM.", "technique"),
+		clockStep: reg.NewCounterVec("rte_clock_anomalies_total", "Wall-clock steps observed against the monotonic clock.", "direction"),
+		control: reg.NewHistogramVec("rte_control_delivery_seconds", "Time from sending a cancel or halt to an agent receiving it, by transport.",
+			[]float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 5}, "transport", "kind"),
+	}
+}
+
+// TaskSigned counts a task signed with SignTask.
+func (m *Metrics) TaskSigned(tt TaskType) {
+	if m != nil {
+		m.signed.Inc(string(tt))
+	}
+}
+
+// TaskVerified records a verification attempt and how long it took.
+func (m *Metrics) TaskVerified(tt TaskType, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.verifyDur.Observe(d.Seconds())
+	if err != nil {
+		m.rejected.Inc(string(tt), RejectVerify)
+		return
+	}
+	m.verified.Inc(string(tt))
+}
+
+// TaskRejected counts a task refused at the given stage.
+func (m *Metrics) TaskRejected(tt TaskType, stage string) {
+	if m != nil {
+		m.rejected.Inc(string(tt), stage)
+	}
+}
+
+// TaskFinished records a handler run from its result.
+func (m *Metrics) TaskFinished(res *TaskResult) {
+	if m == nil || res == nil {
+		return
+	}
+	m.execDur.Observe(res.FinishedAt.Sub(res.StartedAt).Seconds(), string(res.Type), string(res.State))
+	if res.State == StateCancelled {
+		m.cancelled.Inc(string(res.Type))
+	}
+}
+
+// SetQueueDepth reports the number of tasks waiting to execute.
+func (m *Metrics) SetQueueDepth(n int) {
+	if m != nil {
+		m.queue.Set(float64(n))
+	}
+}
+
+// DetectionObserved records the emit-to-alert latency of a confirmed
+// detection.
+func (m *Metrics) DetectionObserved(technique string, latency time.Duration) {
+	if m != nil {
+		m.detectLat.Observe(latency.Seconds(), technique)
+	}
+}
+
+// DetectionMissed counts an expected detection that was never confirmed.
+func (m *Metrics) DetectionMissed(technique string) {
+	if m != nil {
+		m.missed.Inc(technique)
+	}
+}
+
+// ClockAnomaly counts a wall-clock step seen by an executor.
+func (m *Metrics) ClockAnomaly(a ClockAnomaly) {
+	if m == nil {
+		return
+	}
+	dir := "forward"
+	if a.Backward() {
+		dir = "backward"
+	}
+	m.clockStep.Inc(dir)
+}
+
+// ControlDelivered records how long a cancel or halt took from being sent
+// to reaching an agent. Negative latencies, from skew between the
+// sender's and the agent's clocks, are recorded as zero.
+func (m *Metrics) ControlDelivered(transport string, kind MessageKind, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.control.Observe(max(latency, 0).Seconds(), transport, string(kind))
+}
@@ -0,0 +1,61 @@
+package rte
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/metrics"
+)
+
+func TestMetrics_Executor(t *testing.T) {
+	reg := metrics.NewRegistry()
+	e := NewExecutor()
+	e.Metrics = NewMetrics(reg)
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil }))
+
+	if _, err := e.Execute(context.Background(), signedValidTask(t)); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	st := signedValidTask(t)
+	st.Signature[0] ^= 0xff
+	_, _ = e.Execute(context.Background(), st)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _ = e.Execute(ctx, signedValidTask(t))
+	e.Metrics.SetQueueDepth(3)
+	e.Metrics.ClockAnomaly(ClockAnomaly{Step: -time.Hour})
+	e.Metrics.ControlDelivered("nats", MessageHalt, 3*time.Millisecond)
+	e.Metrics.ControlDelivered("nats", MessageHalt, -time.Second)
+
+	var b strings.Builder
+	_, _ = reg.WriteTo(&b)
+	out := b.String()
+	for _, want := range []string{
+		`rte_tasks_verified_total{type="simulate_login"} 2`,
+		`rte_tasks_rejected_total{type="simulate_login",stage="verify"} 1`,
+		`rte_task_execution_duration_seconds_count{type="simulate_login",state="completed"} 1`,
+		`rte_tasks_cancelled_total{type="simulate_login"} 1`,
+		`rte_verification_duration_seconds_count 3`,
+		"rte_queue_depth 3",
+		`rte_clock_anomalies_total{direction="backward"} 1`,
+		`rte_control_delivery_seconds_bucket{transport="nats",kind="halt",le="0.001"} 1`,
+		`rte_control_delivery_seconds_count{transport="nats",kind="halt"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.TaskSigned(TaskInventory)
+	m.TaskVerified(TaskInventory, 0, nil)
+	m.TaskRejected(TaskInventory, RejectState)
+	m.TaskFinished(&TaskResult{})
+	m.SetQueueDepth(1)
+	m.ClockAnomaly(ClockAnomaly{})
+	m.ControlDelivered("nats", MessageCancel, 0)
+}
@@ -0,0 +1,250 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// MaxOfflineBundleSeconds bounds how long an offline bundle stays valid, so
+// an agent cut off from the controller still picks up revocations within a
+// month.
+const MaxOfflineBundleSeconds = 30 * 24 * 60 * 60
+
+var (
+	// ErrRevokedKey is returned for a task signed or countersigned by a
+	// key an offline bundle revokes.
+	ErrRevokedKey = errors.New("signing key is revoked")
+	// ErrBundleExpired is returned once an offline bundle is past its
+	// ExpiresAt.
+	ErrBundleExpired = errors.New("offline bundle expired")
+	// ErrClockBehindAnchor is returned when the agent's clock reads
+	// earlier than an offline bundle was issued, so it cannot be trusted
+	// to tell expired tasks from live ones.
+	ErrClockBehindAnchor = errors.New("clock is behind the offline bundle's issue time")
+)
+
+// OfflineBundle is everything an air-gapped agent needs to verify tasks
+// without reaching the controller: the keys it trusts, the keys it must
+// no longer trust, and the controller's policy as of IssuedAt. The
+// controller signs it (see SignOfflineBundle) and the agent loads it with
+// LoadOfflineBundle against a trust key provisioned out of band.
+type OfflineBundle struct {
+	// IssuedAt is the bundle's time anchor: an agent whose clock reads
+	// earlier, beyond the policy's ClockSkew, refuses the bundle.
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Keys are the controller keys tasks may be signed by.
+	Keys *KeyPins `json:"keys"`
+	// Revoked are fingerprints of keys that may neither sign nor
+	// countersign a task, even if pinned.
+	Revoked []string      `json:"revoked,omitempty"`
+	Policy  OfflinePolicy `json:"policy"`
+}
+
+// OfflinePolicy is the policy snapshot an OfflineBundle carries.
+type OfflinePolicy struct {
+	// Bundles names the policy bundles the controller enforced when the
+	// snapshot was taken, for the record; their rules do not travel.
+	Bundles    []PolicyBundle  `json:"bundles,omitempty"`
+	Validator  ValidatorConfig `json:"validator"`
+	ParamRules []ParamRule     `json:"param_rules,omitempty"`
+	// Phases maps an engagement to its current phase.
+	Phases        map[string]Phase         `json:"phases,omitempty"`
+	TargetLimits  map[string][]TargetLimit `json:"target_limits,omitempty"`
+	ImpactBudgets map[string]ImpactBudget  `json:"impact_budgets,omitempty"`
+}
+
+// Validate checks the bundle's validity window, keys, and policy.
+func (b OfflineBundle) Validate() error {
+	if b.IssuedAt.IsZero() || b.ExpiresAt.IsZero() {
+		return errors.New("issued_at and expires_at are required")
+	}
+	if !b.ExpiresAt.After(b.IssuedAt) {
+		return errors.New("expires_at must be after issued_at")
+	}
+	if b.ExpiresAt.Sub(b.IssuedAt) > MaxOfflineBundleSeconds*time.Second {
+		return fmt.Errorf("bundle may be valid for at most %d seconds", MaxOfflineBundleSeconds)
+	}
+	if b.Keys == nil || len(b.Keys.set) == 0 {
+		return errors.New("keys must pin at least one key")
+	}
+	for _, fp := range b.Revoked {
+		if _, err := ParseFingerprint(fp); err != nil {
+			return fmt.Errorf("revoked: %w", err)
+		}
+	}
+	p := b.Policy
+	if err := p.Validator.Validate(); err != nil {
+		return fmt.Errorf("validator: %w", err)
+	}
+	if err := (&ParamPolicy{Rules: p.ParamRules}).Validate(); err != nil {
+		return err
+	}
+	for eng, phase := range p.Phases {
+		if err := phase.Validate(); err != nil {
+			return fmt.Errorf("phase of %s: %w", eng, err)
+		}
+	}
+	for eng, limits := range p.TargetLimits {
+		for i, l := range limits {
+			if err := l.Validate(); err != nil {
+				return fmt.Errorf("target limit %d of %s: %w", i, eng, err)
+			}
+		}
+	}
+	for eng, budget := range p.ImpactBudgets {
+		if err := budget.Validate(); err != nil {
+			return fmt.Errorf("impact budget of %s: %w", eng, err)
+		}
+	}
+	return nil
+}
+
+// SignedOfflineBundle wraps an OfflineBundle with the controller's
+// signature.
+type SignedOfflineBundle struct {
+	Bundle    OfflineBundle `json:"bundle"`
+	PublicKey []byte        `json:"public_key"`
+	Signature []byte        `json:"signature"`
+}
+
+// SignOfflineBundle signs a bundle; ctx bounds the signing call.
+func SignOfflineBundle(ctx context.Context, b OfflineBundle, s Signer) (*SignedOfflineBundle, error) {
+	pub, err := signerKey(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("marshal offline bundle: %w", err)
+	}
+	sig, err := s.Sign(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("sign offline bundle: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, errors.New("signer returned an invalid signature size")
+	}
+	return &SignedOfflineBundle{Bundle: b, PublicKey: pub, Signature: sig}, nil
+}
+
+// VerifyOfflineBundle checks that sb is signed by a key in trust, is
+// well formed, and is valid at now.
+func VerifyOfflineBundle(sb *SignedOfflineBundle, trust *KeyPins, now time.Time) error {
+	if sb == nil {
+		return errors.New("signed offline bundle is nil")
+	}
+	if len(sb.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sb.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	if !trust.Pinned(sb.PublicKey) {
+		return fmt.Errorf("%w: %s", ErrUnpinnedKey, Fingerprint(sb.PublicKey))
+	}
+	payload, err := json.Marshal(sb.Bundle)
+	if err != nil {
+		return fmt.Errorf("marshal offline bundle: %w", err)
+	}
+	if !ed25519.Verify(sb.PublicKey, payload, sb.Signature) {
+		return errors.New("offline bundle signature verification failed")
+	}
+	if err := sb.Bundle.Validate(); err != nil {
+		return err
+	}
+	return sb.Bundle.checkTime(now)
+}
+
+// LoadOfflineBundle reads a signed bundle from path, as written by rtectl
+// bundle, and verifies it against trust at the current time.
+func LoadOfflineBundle(path string, trust *KeyPins) (*OfflineBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sb SignedOfflineBundle
+	if err := json.Unmarshal(data, &sb); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := VerifyOfflineBundle(&sb, trust, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &sb.Bundle, nil
+}
+
+func (b *OfflineBundle) checkTime(now time.Time) error {
+	skew := b.Policy.Validator.withDefaults().ClockSkew
+	if now.Before(b.IssuedAt.Add(-skew)) {
+		return fmt.Errorf("%w: now %s, issued %s", ErrClockBehindAnchor,
+			now.UTC().Format(time.RFC3339), b.IssuedAt.UTC().Format(time.RFC3339))
+	}
+	if !now.Before(b.ExpiresAt.Add(skew)) {
+		return fmt.Errorf("%w at %s", ErrBundleExpired, b.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Evaluator returns the bundle's param and phase policy.
+func (b *OfflineBundle) Evaluator() PolicyEvaluator {
+	phases := b.Policy.Phases
+	return AllOf(
+		&ParamPolicy{Rules: b.Policy.ParamRules},
+		&PhasePolicy{Current: func(_ context.Context, engagement string) (Phase, error) {
+			return phases[engagement], nil
+		}},
+	)
+}
+
+// VerifyTask verifies st as the controller would have when the bundle was
+// issued: signed by a pinned, unrevoked key, countersigned by an unrevoked
+// key if at all (and at all if the bundle's validator config requires
+// approval), valid under the bundle's validator bounds, and allowed by its
+// policy. It fails once the bundle expires. Use it as an Executor's Verify.
+func (b *OfflineBundle) VerifyTask(st *SignedTask) error {
+	if err := b.checkTime(time.Now().UTC()); err != nil {
+		return err
+	}
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	keys := [][]byte{st.PublicKey}
+	if st.Approval != nil {
+		keys = append(keys, st.Approval.PublicKey)
+	}
+	for _, pub := range keys {
+		fp := Fingerprint(pub)
+		for _, r := range b.Revoked {
+			if r, _ := ParseFingerprint(r); r == fp {
+				return fmt.Errorf("%w: %s", ErrRevokedKey, fp)
+			}
+		}
+	}
+	if err := b.Keys.Check(st); err != nil {
+		return err
+	}
+	if err := VerifyTaskWith(st, b.Policy.Validator); err != nil {
+		return err
+	}
+	return EnforcePolicy(context.Background(), b.Evaluator(), st.Task)
+}
+
+// Configure sets e to verify tasks with the bundle and to enforce its
+// target limits and impact budgets.
+func (b *OfflineBundle) Configure(e *Executor) {
+	e.Verify = b.VerifyTask
+	if len(b.Policy.TargetLimits) > 0 {
+		e.TargetLimits = &TargetLimiter{Limits: b.Policy.TargetLimits}
+	}
+	if len(b.Policy.ImpactBudgets) > 0 {
+		e.Impact = &ImpactLimiter{Budgets: b.Policy.ImpactBudgets}
+	}
+}
@@ -0,0 +1,142 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func offlineBundle(t *testing.T, now time.Time, pinned ...keyPair) OfflineBundle {
+	t.Helper()
+	var fps []string
+	for _, k := range pinned {
+		fps = append(fps, Fingerprint(k.pub))
+	}
+	keys, err := NewKeyPins(fps...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return OfflineBundle{IssuedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour), Keys: keys}
+}
+
+func TestLoadOfflineBundle(t *testing.T) {
+	ctl, op := newKeyPair(t), newKeyPair(t)
+	now := time.Now().UTC()
+	signer, err := NewKeySigner(ctl.priv, ctl.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := offlineBundle(t, now, op)
+	b.Policy.Phases = map[string]Phase{"eng-2026-q1": PhaseInitialAccess}
+	sb, err := SignOfflineBundle(context.Background(), b, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(sb, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	trust, _ := NewKeyPins(Fingerprint(ctl.pub))
+	got, err := LoadOfflineBundle(path, trust)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Keys.Pinned(op.pub) || got.Policy.Phases["eng-2026-q1"] != PhaseInitialAccess {
+		t.Fatalf("loaded bundle: %+v", got)
+	}
+	if _, err := LoadOfflineBundle(path, &KeyPins{}); !errors.Is(err, ErrUnpinnedKey) {
+		t.Fatalf("untrusted signer: got %v", err)
+	}
+
+	if err := VerifyOfflineBundle(sb, trust, now.Add(-time.Hour)); !errors.Is(err, ErrClockBehindAnchor) {
+		t.Fatalf("clock behind the anchor: got %v", err)
+	}
+	if err := VerifyOfflineBundle(sb, trust, now.Add(2*time.Hour)); !errors.Is(err, ErrBundleExpired) {
+		t.Fatalf("expired bundle: got %v", err)
+	}
+	lenient := sb.Bundle
+	lenient.Policy.Validator.ClockSkew = MaxClockSkew
+	if err := lenient.checkTime(lenient.IssuedAt.Add(-time.Minute)); err != nil {
+		t.Fatalf("clock behind within the bundle's skew: %v", err)
+	}
+	if err := sb.Bundle.checkTime(sb.Bundle.IssuedAt.Add(-time.Minute)); !errors.Is(err, ErrClockBehindAnchor) {
+		t.Fatalf("clock behind beyond the default skew: got %v", err)
+	}
+	sb.Bundle.Revoked = []string{Fingerprint(op.pub)}
+	if err := VerifyOfflineBundle(sb, trust, now); err == nil {
+		t.Fatal("tampered bundle verified")
+	}
+
+	b.ExpiresAt = b.IssuedAt.Add(MaxOfflineBundleSeconds*time.Second + time.Second)
+	if _, err := SignOfflineBundle(context.Background(), b, signer); err == nil {
+		t.Fatal("signed a bundle valid past the cap")
+	}
+}
+
+func TestOfflineBundle_VerifyTask(t *testing.T) {
+	_, op, lead := enrolled(t)
+	now := time.Now().UTC()
+	b := offlineBundle(t, now, op)
+	st := signAndApprove(t, validTask(now), op, lead)
+	if err := b.VerifyTask(st); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.VerifyTask(signedValidTask(t)); !errors.Is(err, ErrUnpinnedKey) {
+		t.Fatalf("unpinned signer: got %v", err)
+	}
+
+	revoked := b
+	revoked.Revoked = []string{Fingerprint(lead.pub)}
+	if err := revoked.VerifyTask(st); !errors.Is(err, ErrRevokedKey) {
+		t.Fatalf("revoked approver: got %v", err)
+	}
+	forged := *st
+	forged.Approval = &Approval{PublicKey: lead.pub, Signature: make([]byte, 64)}
+	if err := b.VerifyTask(&forged); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("forged approval: got %v", err)
+	}
+	approving := b
+	approving.Policy.Validator = ValidatorConfig{RequireApproval: true}
+	unapproved, err := SignTask(validTask(now), op.priv, op.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := approving.VerifyTask(unapproved); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("unapproved task under a bundle requiring approval: got %v", err)
+	}
+	if err := approving.VerifyTask(st); err != nil {
+		t.Fatalf("approved task under a bundle requiring approval: %v", err)
+	}
+	bounded := b
+	bounded.Policy.Validator = ValidatorConfig{MaxTTLSeconds: 300}
+	if err := bounded.VerifyTask(st); !errors.Is(err, ErrBadTTL) {
+		t.Fatalf("TTL over the bundle's bound: got %v", err)
+	}
+	phased := b
+	phased.Policy.Phases = map[string]Phase{"eng-2026-q1": PhaseRecon}
+	var pe *PolicyError
+	if err := phased.VerifyTask(st); !errors.As(err, &pe) || pe.Violations[0].Rule != "phase.type" {
+		t.Fatalf("login in recon: got %v", err)
+	}
+	expired := b
+	expired.IssuedAt, expired.ExpiresAt = now.Add(-2*time.Hour), now.Add(-time.Hour)
+	if err := expired.VerifyTask(st); !errors.Is(err, ErrBundleExpired) {
+		t.Fatalf("expired bundle: got %v", err)
+	}
+
+	e := NewExecutor()
+	b.Policy.ImpactBudgets = map[string]ImpactBudget{"eng-2026-q1": {MaxPerHour: 1}}
+	b.Configure(e)
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil }))
+	if _, err := e.Execute(context.Background(), st); !errors.Is(err, ErrOverBudget) {
+		t.Fatalf("over the bundle's budget: got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const defaultOPAQuery = "data.rte.allow"
+
+// OPAEvaluator evaluates tasks against Rego policies by running the OPA CLI
+// (`opa eval`) with the task JSON as input. The queried rule may evaluate to
+// a boolean, or to an object of the form {"allow": bool, "reasons": [...]}.
+// The object may explain denials in "violations", a list of Violation
+// objects, to have rejections name the Rego rule, its file and line, and a
+// remediation hint.
+type OPAEvaluator struct {
+	// Binary is the path to the opa executable. Defaults to "opa" on PATH.
+	Binary string
+	// Paths are Rego files, directories, or bundles passed with --data.
+	Paths []string
+	// Query is the rule to evaluate. Defaults to data.rte.allow.
+	Query string
+	// Revision, such as a bundle's manifest revision, is the source given
+	// for violations that do not name one. Defaults to Paths.
+	Revision string
+}
+
+type opaOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// Evaluate runs opa eval with the task as input and maps the result to a
+// Decision. An undefined result is treated as a deny.
+func (o *OPAEvaluator) Evaluate(ctx context.Context, task Task) (Decision, error) {
+	if len(o.Paths) == 0 {
+		return Decision{}, errors.New("at least one policy path is required")
+	}
+	input, err := json.Marshal(task)
+	if err != nil {
+		return Decision{}, fmt.Errorf("marshal task: %w", err)
+	}
+	bin := o.Binary
+	if bin == "" {
+		bin = "opa"
+	}
+	query := o.Query
+	if query == "" {
+		query = defaultOPAQuery
+	}
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, p := range o.Paths {
+		args = append(args, "--data", p)
+	}
+	args = append(args, query)
+
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return Decision{}, fmt.Errorf("opa eval: %w", err)
+		}
+		return Decision{}, fmt.Errorf("opa eval: %w: %s", err, msg)
+	}
+	d, err := parseOPAOutput(stdout.Bytes())
+	if err != nil {
+		return d, err
+	}
+	source := o.Revision
+	if source == "" {
+		source = strings.Join(o.Paths, ",")
+	}
+	for i := range d.Violations {
+		if d.Violations[i].Rule == "" {
+			d.Violations[i].Rule = query
+		}
+		if d.Violations[i].Source == "" {
+			d.Violations[i].Source = source
+		}
+	}
+	return d, nil
+}
+
+func parseOPAOutput(data []byte) (Decision, error) {
+	var out opaOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Decision{}, fmt.Errorf("decode opa output: %w", err)
+	}
+	if len(out.Result) == 0 || len(out.Result[0].Expressions) == 0 {
+		return Decision{Allow: false, Reasons: []string{"policy result undefined"}}, nil
+	}
+	value := out.Result[0].Expressions[0].Value
+
+	var allow bool
+	if err := json.Unmarshal(value, &allow); err == nil {
+		return Decision{Allow: allow}, nil
+	}
+	var obj struct {
+		Allow      *bool       `json:"allow"`
+		Reasons    []string    `json:"reasons"`
+		Deny       []string    `json:"deny"`
+		Violations []Violation `json:"violations"`
+	}
+	if err := json.Unmarshal(value, &obj); err != nil {
+		return Decision{}, fmt.Errorf("unsupported policy result: %s", value)
+	}
+	if obj.Allow == nil {
+		return Decision{}, errors.New("policy result object has no allow field")
+	}
+	d := Decision{Allow: *obj.Allow, Reasons: append(obj.Reasons, obj.Deny...), Violations: obj.Violations}
+	for _, v := range obj.Violations {
+		d.Reasons = append(d.Reasons, v.Message)
+	}
+	if len(d.Reasons) > 0 && d.Allow {
+		d.Allow = false
+	}
+	return d, nil
+}
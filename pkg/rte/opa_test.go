@@ -0,0 +1,79 @@
+package rte
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseOPAOutput_Bool(t *testing.T) {
+	d, err := parseOPAOutput([]byte(`{"result":[{"expressions":[{"value":true,"text":"data.rte.allow"}]}]}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !d.Allow {
+		t.Fatal("expected allow")
+	}
+}
+
+func TestParseOPAOutput_Object(t *testing.T) {
+	d, err := parseOPAOutput([]byte(`{"result":[{"expressions":[{"value":{"allow":true,"deny":["ttl too long for dmz"]}}]}]}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if d.Allow {
+		t.Fatal("expected deny reasons to override allow")
+	}
+	if len(d.Reasons) != 1 {
+		t.Errorf("reasons: got %v", d.Reasons)
+	}
+}
+
+func TestParseOPAOutput_Undefined(t *testing.T) {
+	d, err := parseOPAOutput([]byte(`{}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if d.Allow {
+		t.Fatal("expected undefined result to deny")
+	}
+}
+
+func TestParseOPAOutput_BadValue(t *testing.T) {
+	if _, err := parseOPAOutput([]byte(`{"result":[{"expressions":[{"value":"yes"}]}]}`)); err == nil {
+		t.Fatal("expected string result to fail")
+	}
+	if _, err := parseOPAOutput([]byte(`{"result":[{"expressions":[{"value":{}}]}]}`)); err == nil {
+		t.Fatal("expected object without allow to fail")
+	}
+}
+
+func TestOPAEvaluator_RunsBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of opa")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "opa")
+	body := "#!/bin/sh\ngrep -q simulate_login && echo '{\"result\":[{\"expressions\":[{\"value\":true}]}]}'\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	e := &OPAEvaluator{Binary: script, Paths: []string{dir}}
+	d, err := e.Evaluate(context.Background(), validTask(time.Now().UTC()))
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if !d.Allow {
+		t.Fatal("expected allow from stub opa")
+	}
+}
+
+func TestOPAEvaluator_RequiresPaths(t *testing.T) {
+	e := &OPAEvaluator{}
+	if _, err := e.Evaluate(context.Background(), validTask(time.Now().UTC())); err == nil {
+		t.Fatal("expected missing policy paths to fail")
+	}
+}
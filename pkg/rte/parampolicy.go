@@ -0,0 +1,174 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParamRule restricts who may set a param. A rule applies to a param when
+// its key matches Key (a path.Match pattern) and its value falls in the
+// rule's range; a task setting such a value must be authored by one of
+// Roles or Operators. For example, only leads may set event rates above
+// 100/s:
+//
+//	{"key": "rate_per_second", "above": 100, "roles": ["lead"]}
+type ParamRule struct {
+	Key string `json:"key"`
+	// Above and Below bound numeric values, exclusively. A value that is
+	// not a finite number, including "NaN" and "Inf", is inside any bound,
+	// so the rule applies to it.
+	Above *float64 `json:"above,omitempty"`
+	Below *float64 `json:"below,omitempty"`
+	// Values, if set, limits the rule to these exact values.
+	Values []string `json:"values,omitempty"`
+	// Roles and Operators may set values the rule applies to; with both
+	// empty, no one may.
+	Roles     []Role   `json:"roles,omitempty"`
+	Operators []string `json:"operators,omitempty"`
+	// Name, Source, and Remediation explain a denial; see Violation. Name
+	// defaults to the rule's index and Remediation to naming who may set
+	// the value.
+	Name        string `json:"name,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// applies reports whether the rule governs setting key to value.
+func (r ParamRule) applies(key, value string) bool {
+	if ok, _ := path.Match(r.Key, key); !ok {
+		return false
+	}
+	if len(r.Values) > 0 && !slices.Contains(r.Values, value) {
+		return false
+	}
+	if r.Above == nil && r.Below == nil {
+		return true
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return true
+	}
+	return (r.Above == nil || n > *r.Above) && (r.Below == nil || n < *r.Below)
+}
+
+func (r ParamRule) permits(actor Identity) bool {
+	return slices.Contains(r.Roles, actor.Role) || slices.Contains(r.Operators, actor.Name)
+}
+
+// violation explains the rule, the ith in its policy, denying key=value.
+func (r ParamRule) violation(i int, actor Identity, key, value string) Violation {
+	v := Violation{
+		Rule:        r.Name,
+		Field:       "task.params." + key,
+		Source:      r.Source,
+		Message:     fmt.Sprintf("%s may not set %s=%s", describeActor(actor), key, value),
+		Remediation: r.Remediation,
+	}
+	if v.Rule == "" {
+		v.Rule = fmt.Sprintf("param_rules[%d]", i)
+	}
+	if v.Source == "" {
+		v.Source = "rte.ParamPolicy"
+	}
+	if v.Remediation == "" {
+		var who []string
+		for _, role := range r.Roles {
+			who = append(who, "a "+string(role))
+		}
+		who = append(who, r.Operators...)
+		if len(who) == 0 {
+			v.Remediation = fmt.Sprintf("no one may set %s to this value; choose another", key)
+		} else {
+			v.Remediation = fmt.Sprintf("choose another value, or have %s set %s", strings.Join(who, " or "), key)
+		}
+	}
+	return v
+}
+
+// ParamPolicy enforces ParamRules. Evaluate checks a task's params against
+// its Operator when it is signed or executed; CheckAmendment checks the
+// params an amendment changes against whoever makes it, so a lead can
+// raise a value an operator may not set.
+type ParamPolicy struct {
+	Rules []ParamRule
+	// Identities resolves operators to roles. Without it, or for operators
+	// not enrolled, only rules naming the operator can permit a value.
+	Identities *IdentityRegistry
+}
+
+// Evaluate allows the task if its Operator may set every param it carries.
+func (p *ParamPolicy) Evaluate(_ context.Context, task Task) (Decision, error) {
+	actor := Identity{Name: task.Operator}
+	if p.Identities != nil {
+		if id, ok := p.Identities.Lookup(task.Engagement, task.Operator); ok {
+			actor = id
+		}
+	}
+	return p.decide(actor, task.Params, nil), nil
+}
+
+// CheckAmendment returns an error if actor may not make the param changes
+// from before to after. Params left unchanged are not re-checked, so an
+// operator may amend other fields of a task a lead raised a value on.
+func (p *ParamPolicy) CheckAmendment(actor Identity, before, after Task) error {
+	d := p.decide(actor, after.Params, before.Params)
+	if d.Allow {
+		return nil
+	}
+	return fmt.Errorf("amendment to %s: %w", after.ID, &PolicyError{TaskID: after.ID, Violations: d.Violations})
+}
+
+// decide checks each param in params whose value differs from unchanged.
+func (p *ParamPolicy) decide(actor Identity, params, unchanged map[string]string) Decision {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	d := Decision{Allow: true}
+	for _, k := range keys {
+		v := params[k]
+		if old, ok := unchanged[k]; ok && old == v {
+			continue
+		}
+		for i, r := range p.Rules {
+			if r.applies(k, v) && !r.permits(actor) {
+				d.deny(r.violation(i, actor, k, v))
+				break
+			}
+		}
+	}
+	return d
+}
+
+func describeActor(id Identity) string {
+	if id.Role == "" {
+		return id.Name
+	}
+	return fmt.Sprintf("%s (%s)", id.Name, id.Role)
+}
+
+// Validate checks that every rule's key pattern is well formed.
+func (p *ParamPolicy) Validate() error {
+	var errs []error
+	for i, r := range p.Rules {
+		if r.Key == "" {
+			errs = append(errs, fmt.Errorf("param rule %d: key is required", i))
+		} else if _, err := path.Match(r.Key, ""); err != nil {
+			errs = append(errs, fmt.Errorf("param rule %d: key %q: %w", i, r.Key, err))
+		}
+		for _, role := range r.Roles {
+			if _, ok := validRoles[role]; !ok {
+				errs = append(errs, fmt.Errorf("param rule %d: invalid role: %s", i, role))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func rateLimitPolicy(t *testing.T) (*ParamPolicy, *IdentityRegistry) {
+	t.Helper()
+	r, _, _ := enrolled(t)
+	var rules []ParamRule
+	err := json.Unmarshal([]byte(`[
+		{"key": "rate_per_second", "above": 100, "roles": ["lead"]},
+		{"key": "sink", "values": ["siem-prod"], "operators": ["op-carol"]}
+	]`), &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &ParamPolicy{Rules: rules, Identities: r}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return p, r
+}
+
+func TestParamPolicy_Evaluate(t *testing.T) {
+	p, _ := rateLimitPolicy(t)
+	task := validTask(time.Now().UTC())
+	for _, tc := range []struct {
+		params map[string]string
+		allow  bool
+	}{
+		{map[string]string{"rate_per_second": "100"}, true},
+		{map[string]string{"rate_per_second": "250"}, false},
+		{map[string]string{"rate_per_second": "fast"}, false},
+		{map[string]string{"rate_per_second": "NaN"}, false},
+		{map[string]string{"rate_per_second": "+Inf"}, false},
+		{map[string]string{"rate_per_second": "-Inf"}, false},
+		{map[string]string{"sink": "siem-test"}, true},
+		{map[string]string{"sink": "siem-prod"}, false},
+	} {
+		task.Params = tc.params
+		d, err := p.Evaluate(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Allow != tc.allow {
+			t.Errorf("%v: allow = %v, want %v (%v)", tc.params, d.Allow, tc.allow, d.Reasons)
+		}
+	}
+	task.Params = map[string]string{"rate_per_second": "250"}
+	err := EnforcePolicy(context.Background(), p, task)
+	if err == nil || !strings.Contains(err.Error(), "op-alice (operator) may not set rate_per_second=250") {
+		t.Fatalf("EnforcePolicy: %v", err)
+	}
+}
+
+func TestParamPolicy_CheckAmendment(t *testing.T) {
+	p, r := rateLimitPolicy(t)
+	before := validTask(time.Now().UTC())
+	before.Params = map[string]string{"rate_per_second": "50"}
+	after := before
+	after.Params = map[string]string{"rate_per_second": "500"}
+
+	op, _ := r.Lookup("eng-2026-q1", "op-alice")
+	lead, _ := r.Lookup("eng-2026-q1", "lead-bob")
+	if err := p.CheckAmendment(op, before, after); err == nil {
+		t.Fatal("expected an operator raising the rate to be denied")
+	}
+	if err := p.CheckAmendment(lead, before, after); err != nil {
+		t.Fatalf("lead raising the rate: %v", err)
+	}
+	// Once a lead has raised it, the operator may change other params.
+	later := after
+	later.Params = map[string]string{"rate_per_second": "500", "count": "20"}
+	if err := p.CheckAmendment(op, after, later); err != nil {
+		t.Fatalf("unchanged restricted value: %v", err)
+	}
+}
+
+func TestParamPolicy_Validate(t *testing.T) {
+	p := &ParamPolicy{Rules: []ParamRule{{Key: "["}, {Key: "x", Roles: []Role{"admin"}}, {}}}
+	err := p.Validate()
+	if err == nil || strings.Count(err.Error(), "param rule") != 3 {
+		t.Fatalf("Validate = %v", err)
+	}
+}
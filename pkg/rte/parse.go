@@ -0,0 +1,272 @@
+package rte
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Limits ParseSignedTask and ParseMessage hold untrusted input to.
+const (
+	// MaxSignedTaskBytes bounds an encoded signed task or queue message.
+	MaxSignedTaskBytes = 1 << 20
+	// MaxTaskParams bounds the number of params a task may set.
+	MaxTaskParams = 256
+	// MaxParamKeyBytes and MaxParamValueBytes bound each param.
+	MaxParamKeyBytes   = 128
+	MaxParamValueBytes = 8 << 10
+	// maxJSONDepth bounds how deeply the encoding may nest objects and
+	// arrays; well-formed messages need at most seven levels.
+	maxJSONDepth = 16
+)
+
+// ErrMalformed is returned by ParseSignedTask and ParseMessage for input
+// that is not a well-formed encoding within their limits.
+var ErrMalformed = errors.New("malformed input")
+
+// ParseSignedTask decodes a signed task from untrusted bytes, such as a
+// request body. Unlike json.Unmarshal it refuses input over
+// MaxSignedTaskBytes, nested more than a few levels, repeating a key in an
+// object (ignoring case, as encoding/json matches field names), naming a
+// field SignedTask does not have, or followed by trailing data, and tasks
+// whose params exceed MaxTaskParams or the per-param limits. It does not
+// verify the task; pass the result to VerifyTask.
+func ParseSignedTask(data []byte) (*SignedTask, error) {
+	var st SignedTask
+	if err := DecodeStrict(data, &st); err != nil {
+		return nil, err
+	}
+	if err := checkParams(st.Task.Params); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// ParseMessage is ParseSignedTask for a queue message, as the transports
+// carry them. The message must also carry the payload its Kind names.
+func ParseMessage(data []byte) (Message, error) {
+	var m Message
+	if err := DecodeStrict(data, &m); err != nil {
+		return Message{}, err
+	}
+	if err := m.Validate(); err != nil {
+		return Message{}, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	if m.Task != nil {
+		if err := checkParams(m.Task.Task.Params); err != nil {
+			return Message{}, err
+		}
+	}
+	return m, nil
+}
+
+// DecodeStrict decodes untrusted JSON into v with the checks
+// ParseSignedTask applies to any encoding: the size and nesting limits, no
+// repeated keys, no unknown fields, and no trailing data. Errors wrap
+// ErrMalformed.
+func DecodeStrict(data []byte, v any) error {
+	if len(data) > MaxSignedTaskBytes {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrMalformed, len(data), MaxSignedTaskBytes)
+	}
+	if err := scanJSON(data, reflect.TypeOf(v)); err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return nil
+}
+
+// scanJSON checks that data is a single JSON value nested at most
+// maxJSONDepth deep in which no object repeats a key. encoding/json would
+// otherwise keep the last of two keys, so the same bytes could read
+// differently to another decoder. t is the type data decodes into: keys of
+// an object that decodes into a struct are compared case-folded, since
+// encoding/json merges "id" and "ID" into one field, while a map keeps "a"
+// and "A" apart and so compares its keys exactly.
+func scanJSON(data []byte, t reflect.Type) error {
+	type level struct {
+		object bool
+		key    bool         // the next token in the object is a key
+		fold   bool         // the object decodes into a struct
+		typ    reflect.Type // what the object or array decodes into
+		next   reflect.Type // what the next value decodes into
+		keys   map[string]struct{}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var stack []*level
+	done := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if done {
+			return errors.New("trailing data after the top-level value")
+		}
+		var top *level
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			done = len(stack) == 0
+			continue
+		}
+		if top != nil && top.object {
+			if top.key {
+				k := tok.(string)
+				id := k
+				if top.fold {
+					id = foldKey(k)
+				}
+				if _, dup := top.keys[id]; dup {
+					return fmt.Errorf("duplicate key %q", k)
+				}
+				top.keys[id] = struct{}{}
+				top.key = false
+				top.next = valueType(top.typ, k)
+				continue
+			}
+			top.key = true
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			if len(stack) == maxJSONDepth {
+				return fmt.Errorf("nested more than %d levels deep", maxJSONDepth)
+			}
+			l := &level{object: tok == json.Delim('{'), typ: decodedType(t)}
+			if top != nil {
+				l.typ = top.next
+			}
+			if l.object {
+				l.key, l.keys = true, make(map[string]struct{})
+				l.fold = l.typ != nil && l.typ.Kind() == reflect.Struct
+			} else {
+				l.next = valueType(l.typ, "")
+			}
+			stack = append(stack, l)
+		default:
+			done = len(stack) == 0
+		}
+	}
+	if !done {
+		return errors.New("no value")
+	}
+	return nil
+}
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// decodedType is the type encoding/json fills in when decoding into t:
+// pointers are followed, and nil stands for a type whose encoding scanJSON
+// cannot follow, because it is an interface or decodes itself.
+func decodedType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+	return t
+}
+
+// valueType is the decoded type of the value under key in an object of
+// type t, or of an element if t is a slice or array.
+func valueType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return decodedType(t.Elem())
+	case reflect.Struct:
+		exact, folded := fieldType(t, key)
+		if exact == nil {
+			exact = folded
+		}
+		return decodedType(exact)
+	}
+	return nil
+}
+
+// fieldType finds the field of struct t, or of a struct embedded in it,
+// that encoding/json decodes key into: exact is the field named key and
+// folded one whose name matches key only ignoring case.
+func fieldType(t reflect.Type, key string) (exact, folded reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			e, fo := fieldType(ft, key)
+			if e != nil {
+				return e, nil
+			}
+			if folded == nil {
+				folded = fo
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		switch {
+		case name == key:
+			return f.Type, nil
+		case folded == nil && strings.EqualFold(name, key):
+			folded = f.Type
+		}
+	}
+	return nil, folded
+}
+
+// foldKey maps every rune of s to the least rune it case-folds with, so
+// two keys fold alike exactly when strings.EqualFold matches them, as
+// encoding/json does.
+func foldKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		least := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			least = min(least, f)
+		}
+		return least
+	}, s)
+}
+
+// checkParams holds params to MaxTaskParams and the per-param limits.
+func checkParams(params map[string]string) error {
+	if len(params) > MaxTaskParams {
+		return fmt.Errorf("%w: %d params exceeds %d", ErrMalformed, len(params), MaxTaskParams)
+	}
+	for k, v := range params {
+		if len(k) > MaxParamKeyBytes {
+			return fmt.Errorf("%w: param key of %d bytes exceeds %d", ErrMalformed, len(k), MaxParamKeyBytes)
+		}
+		if len(v) > MaxParamValueBytes {
+			return fmt.Errorf("%w: param %s of %d bytes exceeds %d", ErrMalformed, k, len(v), MaxParamValueBytes)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package rte
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseSignedTask(t *testing.T) {
+	st := signedValidTask(t)
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseSignedTask(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTask(got); err != nil {
+		t.Fatalf("parsed task does not verify: %v", err)
+	}
+
+	// Maps keep keys that differ only in case apart, so only structs fold.
+	cased := `{"task": {"id": "t", "params": {"target": "a", "Target": "b"}}, "trace": {"k": "a", "K": "b"}}`
+	if got, err := ParseSignedTask([]byte(cased)); err != nil || got.Task.Params["Target"] != "b" || got.Trace["K"] != "b" {
+		t.Fatalf("keys differing in case in a map: got %+v, %v", got, err)
+	}
+
+	many := make([]string, MaxTaskParams+1)
+	for i := range many {
+		many[i] = fmt.Sprintf(`"p%d": "v"`, i)
+	}
+	task := `{"task": {"id": "t", "params": %s}, "public_key": null, "signature": null}`
+	for name, in := range map[string]string{
+		"empty":          ``,
+		"not an object":  `[]`,
+		"unknown field":  `{"task": {}, "signer": "op"}`,
+		"duplicate key":  `{"task": {"id": "a", "id": "b"}}`,
+		"folded key":     `{"task": {"id": "a", "ID": "b"}}`,
+		"folded field":   `{"task": {"id": "a"}, "Task": {"id": "b"}}`,
+		"kelvin sign":    `{"task": {"id": "a"}, "tas\u212a": {"id": "b"}}`,
+		"trailing data":  string(data) + `{}`,
+		"too deep":       `{"task": {"expected_detections": ` + strings.Repeat("[", 20) + strings.Repeat("]", 20) + `}}`,
+		"repeated param": fmt.Sprintf(task, `{"target": "a", "target": "b"}`),
+		"nested params":  fmt.Sprintf(task, `{"target": {"host": "a"}}`),
+		"many params":    fmt.Sprintf(task, "{"+strings.Join(many, ", ")+"}"),
+		"long param":     fmt.Sprintf(task, `{"target": "`+strings.Repeat("a", MaxParamValueBytes+1)+`"}`),
+		"too large":      `"` + strings.Repeat("a", MaxSignedTaskBytes) + `"`,
+	} {
+		if _, err := ParseSignedTask([]byte(in)); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	data, err := json.Marshal(TaskMessage(signedValidTask(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := ParseMessage(data); err != nil || m.Task == nil {
+		t.Fatalf("ParseMessage = %+v, %v", m, err)
+	}
+	if _, err := ParseMessage([]byte(`{"kind": "halt"}`)); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("halt without payload: got %v", err)
+	}
+}
+
+func FuzzParseSignedTask(f *testing.F) {
+	st := signedValidTask(f)
+	data, err := json.Marshal(st)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Add([]byte(`{"task": {"params": {"a": "b"}}}`))
+	f.Add([]byte(`{"task": {"expected_detections": [{"source": "x"}]}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		st, err := ParseSignedTask(data)
+		if err != nil {
+			return
+		}
+		// Whatever parses re-encodes to something that parses the same.
+		again, err := json.Marshal(st)
+		if err != nil {
+			t.Fatalf("re-encode: %v", err)
+		}
+		st2, err := ParseSignedTask(again)
+		if err != nil {
+			t.Fatalf("re-encoded task does not parse: %v\n%s", err, again)
+		}
+		if third, _ := json.Marshal(st2); !bytes.Equal(third, again) {
+			t.Fatalf("re-encoding is not stable:\n%s\n%s", again, third)
+		}
+		_ = VerifyTask(st)
+	})
+}
+
+func FuzzParseMessage(f *testing.F) {
+	for _, m := range []Message{
+		TaskMessage(signedValidTask(f)),
+		CancelMessage(TaskCancel{Engagement: "eng-2026-q1", TaskID: "task-001", Token: "tok"}),
+	} {
+		data, err := json.Marshal(m)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := ParseMessage(data)
+		if err != nil {
+			return
+		}
+		if err := m.Validate(); err != nil {
+			t.Fatalf("parsed message does not validate: %v", err)
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PauseAction says whether a PauseRequest suspends or resumes a task.
+type PauseAction string
+
+const (
+	ActionPause  PauseAction = "pause"
+	ActionResume PauseAction = "resume"
+)
+
+// PauseRequest suspends or resumes one running task, for example while the
+// customer investigates an unrelated incident. The task's TTL keeps running
+// while it is paused; extend it with a TTLExtension if needed.
+type PauseRequest struct {
+	Engagement string      `json:"engagement"`
+	TaskID     string      `json:"task_id"`
+	Action     PauseAction `json:"action"`
+	IssuedBy   string      `json:"issued_by"`
+	Reason     string      `json:"reason,omitempty"`
+	IssuedAt   time.Time   `json:"issued_at"`
+}
+
+// SignedPauseRequest wraps a PauseRequest with the issuer's signature.
+type SignedPauseRequest struct {
+	Request   PauseRequest `json:"request"`
+	PublicKey []byte       `json:"public_key"`
+	Signature []byte       `json:"signature"`
+}
+
+// SignPauseRequest signs a pause or resume request with the issuer's key.
+func SignPauseRequest(p PauseRequest, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedPauseRequest, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshal pause request: %w", err)
+	}
+	return &SignedPauseRequest{Request: p, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// VerifyPauseRequest verifies a signed pause request's signature and
+// required fields.
+func VerifyPauseRequest(sp *SignedPauseRequest) error {
+	if sp == nil {
+		return errors.New("signed pause request is nil")
+	}
+	if len(sp.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sp.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	if err := sp.Request.validate(); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(sp.Request)
+	if err != nil {
+		return fmt.Errorf("marshal pause request: %w", err)
+	}
+	if !ed25519.Verify(sp.PublicKey, payload, sp.Signature) {
+		return errors.New("pause request signature verification failed")
+	}
+	return nil
+}
+
+// VerifyPauseRequest verifies the request and checks that its issuer is
+// enrolled in the engagement with approver rights and signed with their
+// enrolled key.
+func (r *IdentityRegistry) VerifyPauseRequest(sp *SignedPauseRequest) error {
+	if err := VerifyPauseRequest(sp); err != nil {
+		return err
+	}
+	p := sp.Request
+	id, ok := r.Lookup(p.Engagement, p.IssuedBy)
+	if !ok {
+		return fmt.Errorf("%s is not enrolled in %s", p.IssuedBy, p.Engagement)
+	}
+	if !id.CanApprove() {
+		return fmt.Errorf("%s does not hold approver rights", p.IssuedBy)
+	}
+	if !bytes.Equal(id.PublicKey, sp.PublicKey) {
+		return fmt.Errorf("pause request was not signed by %s", p.IssuedBy)
+	}
+	return nil
+}
+
+// verifyPause is the Executor's default pause check: VerifyPauseRequest,
+// and a signing key in e.Pins or the task's trusted approver key (see
+// approverKey).
+func (e *Executor) verifyPause(sp *SignedPauseRequest) error {
+	if err := VerifyPauseRequest(sp); err != nil {
+		return err
+	}
+	if e.Pins != nil && e.Pins.Pinned(sp.PublicKey) {
+		return nil
+	}
+	p := sp.Request
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	r := e.findRun(p.Engagement, p.TaskID)
+	if r == nil {
+		return fmt.Errorf("task %s is not running in %s", p.TaskID, p.Engagement)
+	}
+	if !bytes.Equal(sp.PublicKey, e.approverKey(r.signed)) {
+		return errors.New("pause request was not signed by a pinned key or the task's approver key")
+	}
+	return nil
+}
+
+func (p PauseRequest) validate() error {
+	if p.Engagement == "" || p.TaskID == "" {
+		return errors.New("engagement and task_id are required")
+	}
+	if p.Action != ActionPause && p.Action != ActionResume {
+		return fmt.Errorf("unsupported pause action: %s", p.Action)
+	}
+	if p.IssuedBy == "" {
+		return errors.New("issued_by is required")
+	}
+	if p.IssuedAt.IsZero() {
+		return errors.New("issued_at is required")
+	}
+	return nil
+}
+
+// Pausable is implemented by handlers that can suspend a run between
+// actions without giving up their place. Pause and Resume must return
+// promptly; the handler itself stops acting until resumed. Embed PauseGates
+// for a ready-made implementation.
+type Pausable interface {
+	Handler
+	Pause(task Task) error
+	Resume(task Task) error
+}
+
+// PauseGates tracks which of a handler's runs are paused. The zero value is
+// ready to use; handlers embed it and call Wait between actions.
+type PauseGates struct {
+	mu    sync.Mutex
+	gates map[string]chan struct{}
+}
+
+// Pause marks the task paused; later Wait calls for it block.
+func (g *PauseGates) Pause(task Task) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.gates == nil {
+		g.gates = make(map[string]chan struct{})
+	}
+	k := gateKey(task)
+	if _, ok := g.gates[k]; ok {
+		return fmt.Errorf("task %s is already paused", task.ID)
+	}
+	g.gates[k] = make(chan struct{})
+	return nil
+}
+
+// Resume releases Wait calls blocked on the task.
+func (g *PauseGates) Resume(task Task) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	k := gateKey(task)
+	ch, ok := g.gates[k]
+	if !ok {
+		return fmt.Errorf("task %s is not paused", task.ID)
+	}
+	close(ch)
+	delete(g.gates, k)
+	return nil
+}
+
+// Wait blocks while the task is paused. It returns ctx's error if ctx ends
+// first, so a halt or TTL expiry still stops a paused task.
+func (g *PauseGates) Wait(ctx context.Context, task Task) error {
+	g.mu.Lock()
+	ch := g.gates[gateKey(task)]
+	g.mu.Unlock()
+	if ch == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func gateKey(t Task) string { return t.Engagement + "/" + t.ID }
+
+// Pause verifies a signed pause request and suspends the running task. The
+// task's handler must implement Pausable.
+func (e *Executor) Pause(sp *SignedPauseRequest) error {
+	return e.applyPause(sp, ActionPause)
+}
+
+// Resume verifies a signed resume request and continues a paused task.
+func (e *Executor) Resume(sp *SignedPauseRequest) error {
+	return e.applyPause(sp, ActionResume)
+}
+
+func (e *Executor) applyPause(sp *SignedPauseRequest, action PauseAction) error {
+	verify := e.VerifyPause
+	if verify == nil {
+		verify = e.verifyPause
+	}
+	if err := verify(sp); err != nil {
+		return fmt.Errorf("verify %s request: %w", action, err)
+	}
+	p := sp.Request
+	if p.Action != action {
+		return fmt.Errorf("request is a %s, not a %s", p.Action, action)
+	}
+	to := StatePaused
+	if action == ActionResume {
+		to = StateExecuting
+	}
+	tr, err := e.setPaused(p, action, to)
+	if err != nil {
+		return err
+	}
+	if e.Logger != nil {
+		msg := "task paused"
+		if action == ActionResume {
+			msg = "task resumed"
+		}
+		TaskLogger(e.Logger, tr.Task).Warn(msg, "issued_by", p.IssuedBy, "reason", p.Reason)
+	}
+	e.notify(tr)
+	return nil
+}
+
+// setPaused moves a run between executing and paused under e.mu.
+func (e *Executor) setPaused(p PauseRequest, action PauseAction, to TaskState) (Transition, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	target := e.findRun(p.Engagement, p.TaskID)
+	if target == nil {
+		return Transition{}, fmt.Errorf("task %s is not running in %s", p.TaskID, p.Engagement)
+	}
+	ph, ok := target.handler.(Pausable)
+	if !ok {
+		return Transition{}, fmt.Errorf("%s handler does not support pausing", target.signed.Task.Type)
+	}
+	from := target.state
+	if err := ValidTransition(from, to); err != nil {
+		return Transition{}, err
+	}
+	task := target.signed.Task
+	var err error
+	if action == ActionPause {
+		err = ph.Pause(task)
+	} else {
+		err = ph.Resume(task)
+	}
+	if err != nil {
+		return Transition{}, fmt.Errorf("%s task %s: %w", action, task.ID, err)
+	}
+	target.state = to
+	return Transition{Task: task, From: from, To: to, At: time.Now().UTC(), Reason: p.Reason}, nil
+}
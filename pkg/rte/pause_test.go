@@ -0,0 +1,155 @@
+package rte
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func pauseRequest(t *testing.T, action PauseAction, by string, k keyPair) *SignedPauseRequest {
+	t.Helper()
+	sp, err := SignPauseRequest(PauseRequest{
+		Engagement: "eng-2026-q1", TaskID: "task-001", Action: action, IssuedBy: by,
+		Reason: "customer incident bridge", IssuedAt: time.Now().UTC(),
+	}, k.priv, k.pub)
+	if err != nil {
+		t.Fatalf("SignPauseRequest: %v", err)
+	}
+	return sp
+}
+
+func TestVerifyPauseRequest(t *testing.T) {
+	r, op, lead := enrolled(t)
+	if err := r.VerifyPauseRequest(pauseRequest(t, ActionPause, "lead-bob", lead)); err != nil {
+		t.Fatalf("VerifyPauseRequest: %v", err)
+	}
+	if err := r.VerifyPauseRequest(pauseRequest(t, ActionPause, "op-alice", op)); err == nil {
+		t.Error("expected operator pause to be refused")
+	}
+	sp := pauseRequest(t, ActionResume, "lead-bob", lead)
+	sp.Request.Action = ActionPause
+	if err := VerifyPauseRequest(sp); err == nil {
+		t.Error("expected tampered request to fail")
+	}
+	if _, err := SignPauseRequest(PauseRequest{Engagement: "e", TaskID: "t", Action: "stop", IssuedBy: "x",
+		IssuedAt: time.Now()}, lead.priv, lead.pub); err == nil {
+		t.Error("expected unknown action to fail")
+	}
+}
+
+// countingHandler acts every few milliseconds until ctx ends, honoring
+// pauses between actions.
+type countingHandler struct {
+	PauseGates
+	actions atomic.Int32
+}
+
+func (h *countingHandler) Handle(ctx context.Context, task Task) (any, error) {
+	for {
+		if err := h.Wait(ctx, task); err != nil {
+			return nil, err
+		}
+		h.actions.Add(1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Millisecond):
+		}
+	}
+}
+
+func TestExecutor_PauseResume(t *testing.T) {
+	reg, op, lead := enrolled(t)
+	e := NewExecutor()
+	e.Identities = reg
+	h := &countingHandler{}
+	_ = e.Register(TaskSimulateLogin, h)
+	task := validTask(time.Now().UTC())
+	task.CancelToken = "tok"
+	st := signAndApprove(t, task, op, lead)
+	done := make(chan *TaskResult, 1)
+	go func() {
+		res, _ := e.Execute(context.Background(), st)
+		done <- res
+	}()
+	for h.actions.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := e.Resume(pauseRequest(t, ActionResume, "lead-bob", lead)); err == nil ||
+		!strings.Contains(err.Error(), "executing to executing") {
+		t.Fatalf("expected resume of a running task to fail, got %v", err)
+	}
+	if err := e.Pause(pauseRequest(t, ActionResume, "lead-bob", lead)); err == nil {
+		t.Fatal("expected resume request passed to Pause to fail")
+	}
+	if err := e.Pause(pauseRequest(t, ActionPause, "lead-bob", newKeyPair(t))); err == nil ||
+		!strings.Contains(err.Error(), "approver key") {
+		t.Fatalf("expected pause signed by an unknown key to fail, got %v", err)
+	}
+	e.Identities = nil
+	if err := e.Pause(pauseRequest(t, ActionPause, "lead-bob", lead)); err == nil ||
+		!strings.Contains(err.Error(), "approver key") {
+		t.Fatalf("expected pause by an approver neither pinned nor enrolled to fail, got %v", err)
+	}
+	e.Identities = reg
+	e.Pins, _ = NewKeyPins(Fingerprint(op.pub))
+	if err := e.Pause(pauseRequest(t, ActionPause, "op-alice", op)); err != nil {
+		t.Fatalf("Pause signed by a pinned key: %v", err)
+	}
+	if err := e.Resume(pauseRequest(t, ActionResume, "op-alice", op)); err != nil {
+		t.Fatalf("Resume signed by a pinned key: %v", err)
+	}
+	e.Pins = nil
+	if err := e.Pause(pauseRequest(t, ActionPause, "lead-bob", lead)); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	paused := h.actions.Load()
+	time.Sleep(30 * time.Millisecond)
+	if got := h.actions.Load(); got != paused {
+		t.Fatalf("handler kept acting while paused: %d -> %d", paused, got)
+	}
+	if err := e.Resume(pauseRequest(t, ActionResume, "lead-bob", lead)); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	for h.actions.Load() == paused {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A paused task can still be cancelled.
+	if err := e.Pause(pauseRequest(t, ActionPause, "lead-bob", lead)); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if _, err := e.Cancel(context.Background(), TaskCancel{Engagement: task.Engagement, TaskID: task.ID, Token: "tok"}); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if res := <-done; res.State != StateCancelled {
+		t.Fatalf("state: got %s, want %s", res.State, StateCancelled)
+	}
+	if err := h.Wait(context.Background(), task); err != nil {
+		t.Fatalf("gate left closed after the run ended: %v", err)
+	}
+}
+
+func TestExecutor_Pause_NotPausable(t *testing.T) {
+	reg, op, lead := enrolled(t)
+	e := NewExecutor()
+	e.Identities = reg
+	started := make(chan struct{})
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(ctx context.Context, _ Task) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _, _ = e.Execute(ctx, signAndApprove(t, validTask(time.Now().UTC()), op, lead)) }()
+	<-started
+	if err := e.Pause(pauseRequest(t, ActionPause, "lead-bob", lead)); err == nil ||
+		!strings.Contains(err.Error(), "does not support pausing") {
+		t.Fatalf("expected non-pausable handler to refuse, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package rte
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Phase is a stage of an engagement. Phases are ordered; "" is no phase.
+type Phase string
+
+const (
+	PhaseRecon         Phase = "recon"
+	PhaseInitialAccess Phase = "initial_access"
+	PhasePersistence   Phase = "persistence"
+	PhaseCleanup       Phase = "cleanup"
+)
+
+// Phases returns every phase in engagement order.
+func Phases() []Phase {
+	return []Phase{PhaseRecon, PhaseInitialAccess, PhasePersistence, PhaseCleanup}
+}
+
+// Validate checks that p is a known phase or "".
+func (p Phase) Validate() error {
+	if p == "" || slices.Contains(Phases(), p) {
+		return nil
+	}
+	return fmt.Errorf("unknown phase %q (want recon, initial_access, persistence, or cleanup)", string(p))
+}
+
+// DefaultPhaseTypes is which task types each phase permits unless a
+// PhasePolicy says otherwise. Inventory and synthetic telemetry are
+// harmless in every phase; cleanup permits nothing else, so no new
+// activity starts once an engagement is winding down.
+var DefaultPhaseTypes = map[Phase][]TaskType{
+	PhaseRecon:         {TaskInventory, TaskEmitSynthetic},
+	PhaseInitialAccess: {TaskInventory, TaskEmitSynthetic, TaskSimulateLogin, TaskSimulatePhish, TaskSimulateCredentialSpray},
+	PhasePersistence:   {TaskInventory, TaskEmitSynthetic, TaskSimulateLogin, TaskSimulateBeacon, TaskSimulateExfil},
+	PhaseCleanup:       {TaskInventory, TaskEmitSynthetic},
+}
+
+// PhasePolicy restricts which task types run in which phase. A task is
+// checked against its own Phase, or the engagement's current phase if it
+// names none; a task in neither passes unless RequirePhase is set. Cleanup
+// tasks pass in every phase, since they only undo earlier work.
+type PhasePolicy struct {
+	// Types lists the task types each phase permits; nil means
+	// DefaultPhaseTypes. A phase it does not list permits nothing.
+	Types map[Phase][]TaskType
+	// Current, if set, returns the engagement's phase. Tasks naming
+	// another phase are denied, so work queued for a later phase waits
+	// for the engagement to reach it.
+	Current func(ctx context.Context, engagement string) (Phase, error)
+	// RequirePhase denies tasks that end up with no phase.
+	RequirePhase bool
+}
+
+// Evaluate implements PolicyEvaluator.
+func (p *PhasePolicy) Evaluate(ctx context.Context, task Task) (Decision, error) {
+	d := Decision{Allow: true}
+	if task.CleanupOf != "" {
+		return d, nil
+	}
+	var current Phase
+	if p.Current != nil {
+		var err error
+		if current, err = p.Current(ctx, task.Engagement); err != nil {
+			return Decision{}, fmt.Errorf("phase of %s: %w", task.Engagement, err)
+		}
+	}
+	phase := task.Phase
+	if phase == "" {
+		phase = current
+	}
+	switch {
+	case phase == "" && p.RequirePhase:
+		d.deny(Violation{
+			Rule:        "phase.required",
+			Field:       "task.phase",
+			Source:      "rte.PhasePolicy",
+			Message:     fmt.Sprintf("task %s names no phase", task.ID),
+			Remediation: "set phase to one of " + phaseList(Phases()),
+		})
+		return d, nil
+	case phase == "":
+		return d, nil
+	case current != "" && phase != current:
+		d.deny(Violation{
+			Rule:        "phase.current",
+			Field:       "task.phase",
+			Source:      "rte.PhasePolicy",
+			Message:     fmt.Sprintf("task %s is for phase %s, but %s is in %s", task.ID, phase, task.Engagement, current),
+			Remediation: "wait for the engagement lead to move the engagement to " + string(phase),
+		})
+		return d, nil
+	}
+	types := p.Types
+	if types == nil {
+		types = DefaultPhaseTypes
+	}
+	if !slices.Contains(types[phase], task.Type) {
+		var in []Phase
+		for _, ph := range Phases() {
+			if slices.Contains(types[ph], task.Type) {
+				in = append(in, ph)
+			}
+		}
+		v := Violation{
+			Rule:        "phase.type",
+			Field:       "task.type",
+			Source:      "rte.PhasePolicy",
+			Message:     fmt.Sprintf("%s tasks are not permitted in phase %s", task.Type, phase),
+			Remediation: fmt.Sprintf("%s tasks are permitted in no phase", task.Type),
+		}
+		if len(in) > 0 {
+			v.Remediation = fmt.Sprintf("run %s tasks in %s", task.Type, phaseList(in))
+		}
+		d.deny(v)
+	}
+	return d, nil
+}
+
+func phaseList(phases []Phase) string {
+	s := make([]string, len(phases))
+	for i, p := range phases {
+		s[i] = string(p)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,68 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPhasePolicy(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	current := PhaseInitialAccess
+	p := &PhasePolicy{Current: func(context.Context, string) (Phase, error) { return current, nil }}
+	task := validTask(now)
+
+	for _, tc := range []struct {
+		name  string
+		phase Phase
+		typ   TaskType
+		rule  string
+	}{
+		{"current phase", "", TaskSimulateLogin, ""},
+		{"named current phase", PhaseInitialAccess, TaskSimulatePhish, ""},
+		{"type outside phase", "", TaskSimulateBeacon, "phase.type"},
+		{"later phase", PhasePersistence, TaskSimulateBeacon, "phase.current"},
+	} {
+		task.Phase, task.Type = tc.phase, tc.typ
+		d, err := p.Evaluate(ctx, task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case tc.rule == "" && !d.Allow:
+			t.Errorf("%s: denied: %+v", tc.name, d.Violations)
+		case tc.rule != "" && (d.Allow || d.Violations[0].Rule != tc.rule):
+			t.Errorf("%s: decision %+v, want rule %s", tc.name, d, tc.rule)
+		}
+	}
+	task.Phase, task.Type = "", TaskSimulateBeacon
+	d, _ := p.Evaluate(ctx, task)
+	if v := d.Violations[0]; v.Field != "task.type" || v.Remediation != "run simulate_beacon tasks in persistence" {
+		t.Errorf("violation = %+v", v)
+	}
+
+	open := &PhasePolicy{}
+	if d, _ := open.Evaluate(ctx, task); !d.Allow {
+		t.Error("denied a task with no phase")
+	}
+	open.RequirePhase = true
+	if d, _ := open.Evaluate(ctx, task); d.Allow || d.Violations[0].Rule != "phase.required" {
+		t.Errorf("decision = %+v", d)
+	}
+	task.Phase = PhaseCleanup
+	if d, _ := open.Evaluate(ctx, task); d.Allow {
+		t.Error("allowed a beacon during cleanup")
+	}
+
+	task.CleanupOf = "task-000"
+	if d, _ := open.Evaluate(ctx, task); !d.Allow {
+		t.Errorf("cleanup task denied: %+v", d.Violations)
+	}
+
+	task.Phase = "exploitation"
+	if err := task.Validate(now); !errors.Is(err, ErrBadPhase) {
+		t.Errorf("unknown phase: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Decision is the outcome of evaluating a task against engagement policy.
+// A denying evaluator gives Reasons, and may explain each in Violations;
+// reasons without one are reported as bare violations.
+type Decision struct {
+	Allow      bool        `json:"allow"`
+	Reasons    []string    `json:"reasons,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// deny adds v to d, denying it.
+func (d *Decision) deny(v Violation) {
+	d.Allow = false
+	d.Reasons = append(d.Reasons, v.Message)
+	d.Violations = append(d.Violations, v)
+}
+
+// violations returns d's Violations, plus one for each reason none of
+// them gives.
+func (d Decision) violations() []Violation {
+	out := append([]Violation(nil), d.Violations...)
+	explained := make(map[string]bool, len(d.Violations))
+	for _, v := range d.Violations {
+		explained[v.Message] = true
+	}
+	for _, r := range d.Reasons {
+		if !explained[r] {
+			out = append(out, Violation{Message: r})
+		}
+	}
+	return out
+}
+
+// PolicyEvaluator decides whether a task may be signed or executed. It is the
+// extension point for engagement guardrails that live outside Go code.
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, task Task) (Decision, error)
+}
+
+// PolicyFunc adapts an ordinary function to PolicyEvaluator.
+type PolicyFunc func(ctx context.Context, task Task) (Decision, error)
+
+// Evaluate calls f(ctx, task).
+func (f PolicyFunc) Evaluate(ctx context.Context, task Task) (Decision, error) {
+	return f(ctx, task)
+}
+
+// AllOf returns an evaluator that allows a task only if every evaluator does.
+// Reasons from all denying evaluators are collected; an evaluator error stops
+// evaluation and is returned.
+func AllOf(evaluators ...PolicyEvaluator) PolicyEvaluator {
+	return PolicyFunc(func(ctx context.Context, task Task) (Decision, error) {
+		out := Decision{Allow: true}
+		for i, e := range evaluators {
+			if e == nil {
+				return Decision{}, fmt.Errorf("policy evaluator %d is nil", i)
+			}
+			d, err := e.Evaluate(ctx, task)
+			if err != nil {
+				return Decision{}, err
+			}
+			if !d.Allow {
+				out.Allow = false
+				out.Reasons = append(out.Reasons, d.Reasons...)
+				out.Violations = append(out.Violations, d.violations()...)
+			}
+		}
+		return out, nil
+	})
+}
+
+// EnforcePolicy evaluates the task and returns a *PolicyError if it is
+// denied.
+func EnforcePolicy(ctx context.Context, p PolicyEvaluator, task Task) error {
+	if p == nil {
+		return errors.New("policy evaluator is nil")
+	}
+	d, err := p.Evaluate(ctx, task)
+	if err != nil {
+		return fmt.Errorf("policy evaluation: %w", err)
+	}
+	if !d.Allow {
+		return &PolicyError{TaskID: task.ID, Violations: d.violations()}
+	}
+	return nil
+}
+
+// PhishApprovalPolicy is the dedicated gate for simulate_phish tasks: they
+// are allowed only when approved by one of the named phishing approvers and
+// when they cite an approval record in the phish_approval_ref param. Tasks of
+// other types pass through unchanged, so the policy composes with AllOf.
+func PhishApprovalPolicy(approvers ...string) PolicyEvaluator {
+	allowed := make(map[string]struct{}, len(approvers))
+	for _, a := range approvers {
+		allowed[a] = struct{}{}
+	}
+	return PolicyFunc(func(_ context.Context, task Task) (Decision, error) {
+		if task.Type != TaskSimulatePhish {
+			return Decision{Allow: true}, nil
+		}
+		d := Decision{Allow: true}
+		if _, ok := allowed[task.ApprovedBy]; !ok {
+			d.deny(Violation{
+				Rule:        "phish.approver",
+				Field:       "task.approved_by",
+				Source:      "rte.PhishApprovalPolicy",
+				Message:     fmt.Sprintf("%s is not a phishing approver", task.ApprovedBy),
+				Remediation: "have one of the engagement's phishing approvers countersign the task",
+			})
+		}
+		if task.Params["phish_approval_ref"] == "" {
+			d.deny(Violation{
+				Rule:        "phish.approval_ref",
+				Field:       "task.params.phish_approval_ref",
+				Source:      "rte.PhishApprovalPolicy",
+				Message:     "simulate_phish requires phish_approval_ref",
+				Remediation: "set the phish_approval_ref param to the approval record's ID",
+			})
+		}
+		return d, nil
+	})
+}
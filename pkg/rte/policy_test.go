@@ -0,0 +1,83 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func allow() PolicyEvaluator {
+	return PolicyFunc(func(context.Context, Task) (Decision, error) {
+		return Decision{Allow: true}, nil
+	})
+}
+
+func deny(reason string) PolicyEvaluator {
+	return PolicyFunc(func(context.Context, Task) (Decision, error) {
+		return Decision{Allow: false, Reasons: []string{reason}}, nil
+	})
+}
+
+func TestAllOf_AllowsWhenAllAllow(t *testing.T) {
+	d, err := AllOf(allow(), allow()).Evaluate(context.Background(), validTask(time.Now().UTC()))
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if !d.Allow {
+		t.Fatal("expected allow")
+	}
+}
+
+func TestAllOf_CollectsDenyReasons(t *testing.T) {
+	d, err := AllOf(deny("a"), allow(), deny("b")).Evaluate(context.Background(), validTask(time.Now().UTC()))
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if d.Allow {
+		t.Fatal("expected deny")
+	}
+	if len(d.Reasons) != 2 || d.Reasons[0] != "a" || d.Reasons[1] != "b" {
+		t.Errorf("reasons: got %v", d.Reasons)
+	}
+}
+
+func TestAllOf_PropagatesError(t *testing.T) {
+	boom := errors.New("boom")
+	failing := PolicyFunc(func(context.Context, Task) (Decision, error) { return Decision{}, boom })
+	if _, err := AllOf(allow(), failing).Evaluate(context.Background(), validTask(time.Now().UTC())); !errors.Is(err, boom) {
+		t.Fatalf("expected evaluator error, got %v", err)
+	}
+}
+
+func TestEnforcePolicy(t *testing.T) {
+	task := validTask(time.Now().UTC())
+	if err := EnforcePolicy(context.Background(), allow(), task); err != nil {
+		t.Fatalf("expected allow, got %v", err)
+	}
+	if err := EnforcePolicy(context.Background(), deny("no beacons on fridays"), task); err == nil {
+		t.Fatal("expected denied task to fail")
+	}
+	if err := EnforcePolicy(context.Background(), nil, task); err == nil {
+		t.Fatal("expected nil evaluator to fail")
+	}
+}
+
+func TestPhishApprovalPolicy(t *testing.T) {
+	p := PhishApprovalPolicy("lead-bob")
+	task := validTask(time.Now().UTC())
+	if d, _ := p.Evaluate(context.Background(), task); !d.Allow {
+		t.Fatalf("non-phish task denied: %v", d.Reasons)
+	}
+	task.Type = TaskSimulatePhish
+	task.ApprovedBy = "lead-carol"
+	d, _ := p.Evaluate(context.Background(), task)
+	if d.Allow || len(d.Reasons) != 2 {
+		t.Fatalf("expected two deny reasons, got %+v", d)
+	}
+	task.ApprovedBy = "lead-bob"
+	task.Params = map[string]string{"phish_approval_ref": "CHG-1042"}
+	if d, _ := p.Evaluate(context.Background(), task); !d.Allow {
+		t.Fatalf("approved phish denied: %v", d.Reasons)
+	}
+}
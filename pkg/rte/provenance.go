@@ -0,0 +1,48 @@
+package rte
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Provenance records where a task's declared content came from. It is part
+// of the signed task, so the operator's signature vouches for it.
+type Provenance struct {
+	// Commit is the SHA of the signed Git commit the task was declared in.
+	Commit string `json:"commit"`
+	// Branch is the allowed branch the commit was found on.
+	Branch string `json:"branch"`
+	// Signer is the commit signer's principal, when the signature names one.
+	Signer string `json:"signer,omitempty"`
+}
+
+// ProvenancePolicy allows only tasks whose provenance names a commit on
+// one of the given branches, for controllers that accept nothing but
+// reviewed engagement-as-code content.
+func ProvenancePolicy(branches ...string) PolicyEvaluator {
+	return PolicyFunc(func(_ context.Context, task Task) (Decision, error) {
+		p := task.Provenance
+		d := Decision{Allow: true}
+		switch {
+		case p == nil || p.Commit == "":
+			d.deny(Violation{
+				Rule:        "provenance.commit",
+				Field:       "task.provenance",
+				Source:      "rte.ProvenancePolicy",
+				Message:     "task has no Git provenance",
+				Remediation: "declare the task in the engagement repository and submit it with rtectl apply -verify-commit",
+			})
+		case !slices.Contains(branches, p.Branch):
+			d.deny(Violation{
+				Rule:        "provenance.branch",
+				Field:       "task.provenance.branch",
+				Source:      "rte.ProvenancePolicy",
+				Message:     fmt.Sprintf("commit %s is from branch %q, which is not allowed", p.Commit, p.Branch),
+				Remediation: fmt.Sprintf("merge the change to one of %s and apply from there", strings.Join(branches, ", ")),
+			})
+		}
+		return d, nil
+	})
+}
@@ -0,0 +1,31 @@
+package rte
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProvenancePolicy(t *testing.T) {
+	p := ProvenancePolicy("main", "release")
+	task := validTask(time.Now().UTC())
+	for _, tc := range []struct {
+		name  string
+		prov  *Provenance
+		allow bool
+	}{
+		{"none", nil, false},
+		{"no commit", &Provenance{Branch: "main"}, false},
+		{"other branch", &Provenance{Commit: "abc123", Branch: "feature"}, false},
+		{"allowed", &Provenance{Commit: "abc123", Branch: "release"}, true},
+	} {
+		task.Provenance = tc.prov
+		d, err := p.Evaluate(context.Background(), task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Allow != tc.allow {
+			t.Errorf("%s: allow = %v, want %v (%v)", tc.name, d.Allow, tc.allow, d.Reasons)
+		}
+	}
+}
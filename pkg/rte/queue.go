@@ -0,0 +1,324 @@
+package rte
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTaskCancelled marks tasks stopped by a TaskCancel.
+var ErrTaskCancelled = errors.New("task cancelled")
+
+// TaskCancel asks the executor to stop one running task. Token must match
+// the task's signed CancelToken, which is what authorizes the request.
+type TaskCancel struct {
+	Engagement  string `json:"engagement"`
+	TaskID      string `json:"task_id"`
+	Token       string `json:"token"`
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+func (c TaskCancel) cause() error {
+	if c.RequestedBy == "" {
+		return ErrTaskCancelled
+	}
+	return fmt.Errorf("%w by %s", ErrTaskCancelled, c.RequestedBy)
+}
+
+// MessageKind distinguishes simulation work from control messages.
+type MessageKind string
+
+const (
+	MessageTask   MessageKind = "task"
+	MessageCancel MessageKind = "cancel"
+	MessageHalt   MessageKind = "halt"
+	MessagePause  MessageKind = "pause"
+	MessageAttest MessageKind = "attest"
+)
+
+// Message is one queue entry: a signed task, a cancel, a halt, a pause or
+// resume request, or a standing task's attestation.
+type Message struct {
+	Kind   MessageKind                `json:"kind"`
+	Task   *SignedTask                `json:"task,omitempty"`
+	Cancel *TaskCancel                `json:"cancel,omitempty"`
+	Halt   *SignedHalt                `json:"halt,omitempty"`
+	Pause  *SignedPauseRequest        `json:"pause,omitempty"`
+	Attest *SignedStandingAttestation `json:"attest,omitempty"`
+}
+
+// TaskMessage, CancelMessage, and HaltMessage build queue messages.
+func TaskMessage(st *SignedTask) Message { return Message{Kind: MessageTask, Task: st} }
+func CancelMessage(c TaskCancel) Message { return Message{Kind: MessageCancel, Cancel: &c} }
+func HaltMessage(sh *SignedHalt) Message { return Message{Kind: MessageHalt, Halt: sh} }
+
+// AttestMessage builds a queue message for a standing task's attestation.
+func AttestMessage(sa *SignedStandingAttestation) Message {
+	return Message{Kind: MessageAttest, Attest: sa}
+}
+
+// Control reports whether m is anything but a task.
+func (m Message) Control() bool {
+	return m.Kind != MessageTask
+}
+
+// Validate checks that m carries the payload its Kind names.
+func (m Message) Validate() error {
+	switch {
+	case m.Kind == MessageTask && m.Task != nil:
+	case m.Kind == MessageCancel && m.Cancel != nil:
+	case m.Kind == MessageHalt && m.Halt != nil:
+	case m.Kind == MessagePause && m.Pause != nil:
+	case m.Kind == MessageAttest && m.Attest != nil:
+	default:
+		return fmt.Errorf("malformed %q queue message", m.Kind)
+	}
+	return nil
+}
+
+// preemptPriority ranks control messages above every task priority.
+const preemptPriority = MaxPriority + 1
+
+// ErrQueueFull is returned by Push when the queue is at MaxDepth.
+var ErrQueueFull = errors.New("queue is full")
+
+// ErrQueueClosed is returned by Push and Pop once the queue is closed.
+var ErrQueueClosed = errors.New("queue is closed")
+
+// Queue is the scheduler's priority queue. Higher priorities pop first and
+// equal priorities pop in arrival order. Tasks queue at their signed
+// Priority; control messages queue at MaxPriority, or ahead of every task
+// when Preempt is set. It is safe for concurrent use.
+type Queue struct {
+	// Preempt lets cancels and halts jump ahead of all queued tasks.
+	Preempt bool
+	// MaxDepth bounds queued tasks; 0 means unbounded. Control messages
+	// are always accepted.
+	MaxDepth int
+	// Metrics, if set, tracks queue depth.
+	Metrics *Metrics
+
+	mu     sync.Mutex
+	items  queueHeap
+	seq    uint64
+	tasks  int
+	ready  chan struct{}
+	closed bool
+	// changed is closed, and cleared, on every push and on close, waking
+	// all PopMatch callers to rescan.
+	changed chan struct{}
+}
+
+// NewQueue returns an empty queue.
+func NewQueue() *Queue {
+	return &Queue{ready: make(chan struct{}, 1)}
+}
+
+// Push enqueues m.
+func (q *Queue) Push(m Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	prio := MaxPriority
+	switch {
+	case m.Kind == MessageTask:
+		prio = m.Task.Task.Priority
+	case q.Preempt:
+		prio = preemptPriority
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if m.Kind == MessageTask {
+		if q.MaxDepth > 0 && q.tasks >= q.MaxDepth {
+			return ErrQueueFull
+		}
+		q.tasks++
+	}
+	q.seq++
+	heap.Push(&q.items, queueItem{msg: m, priority: prio, seq: q.seq})
+	q.Metrics.SetQueueDepth(q.items.Len())
+	q.signal()
+	q.broadcast()
+	return nil
+}
+
+// Pop blocks until a message is available, the queue is closed and
+// drained, or ctx ends.
+func (q *Queue) Pop(ctx context.Context) (Message, error) {
+	for {
+		q.mu.Lock()
+		if q.items.Len() > 0 {
+			it := heap.Pop(&q.items).(queueItem)
+			if it.msg.Kind == MessageTask {
+				q.tasks--
+			}
+			q.Metrics.SetQueueDepth(q.items.Len())
+			if q.items.Len() > 0 {
+				q.signal()
+			}
+			q.mu.Unlock()
+			return it.msg, nil
+		}
+		closed := q.closed
+		q.mu.Unlock()
+		if closed {
+			return Message{}, ErrQueueClosed
+		}
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-q.ready:
+		}
+	}
+}
+
+// PopMatch is Pop for a consumer that can take only some messages, such
+// as an agent taking the tasks its labels match: it blocks until a message
+// match accepts is available and pops the first, in queue order, leaving
+// the rest queued.
+func (q *Queue) PopMatch(ctx context.Context, match func(Message) bool) (Message, error) {
+	for {
+		q.mu.Lock()
+		best := -1
+		for i, it := range q.items {
+			if match(it.msg) && (best < 0 || q.items.Less(i, best)) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			it := heap.Remove(&q.items, best).(queueItem)
+			if it.msg.Kind == MessageTask {
+				q.tasks--
+			}
+			q.Metrics.SetQueueDepth(q.items.Len())
+			if q.items.Len() > 0 {
+				q.signal()
+			}
+			q.mu.Unlock()
+			return it.msg, nil
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return Message{}, ErrQueueClosed
+		}
+		if q.changed == nil {
+			q.changed = make(chan struct{})
+		}
+		changed := q.changed
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// popControl pops the head message if it is a cancel or halt.
+func (q *Queue) popControl() (Message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.items.Len() == 0 || !q.items[0].msg.Control() {
+		return Message{}, false
+	}
+	it := heap.Pop(&q.items).(queueItem)
+	q.Metrics.SetQueueDepth(q.items.Len())
+	return it.msg, true
+}
+
+// Remove drops queued messages matching drop and returns how many it
+// removed; a halt uses it to discard its engagement's queued tasks.
+func (q *Queue) Remove(drop func(Message) bool) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.items[:0]
+	n := 0
+	for _, it := range q.items {
+		if drop(it.msg) {
+			n++
+			if it.msg.Kind == MessageTask {
+				q.tasks--
+			}
+			continue
+		}
+		kept = append(kept, it)
+	}
+	q.items = kept
+	heap.Init(&q.items)
+	q.Metrics.SetQueueDepth(q.items.Len())
+	return n
+}
+
+// Len returns the number of queued messages.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
+
+// Snapshot returns the queued messages in no particular order, leaving
+// them queued.
+func (q *Queue) Snapshot() []Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Message, len(q.items))
+	for i, it := range q.items {
+		out[i] = it.msg
+	}
+	return out
+}
+
+// Close stops new pushes; Pop drains what is left, then reports
+// ErrQueueClosed.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.signal()
+	q.broadcast()
+}
+
+// signal wakes one waiting Pop. Callers hold q.mu.
+func (q *Queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// broadcast wakes every waiting PopMatch. Callers hold q.mu.
+func (q *Queue) broadcast() {
+	if q.changed != nil {
+		close(q.changed)
+		q.changed = nil
+	}
+}
+
+type queueItem struct {
+	msg      Message
+	priority int
+	seq      uint64
+}
+
+type queueHeap []queueItem
+
+func (h queueHeap) Len() int { return len(h) }
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h queueHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *queueHeap) Push(x any)   { *h = append(*h, x.(queueItem)) }
+func (h *queueHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,177 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func queuedTask(t *testing.T, id string, prio int) Message {
+	t.Helper()
+	task := validTask(time.Now().UTC())
+	task.ID = id
+	task.Priority = prio
+	pub, priv, _ := GenerateKeyPair()
+	st, err := SignTask(task, priv, pub)
+	if err != nil {
+		t.Fatalf("SignTask: %v", err)
+	}
+	return TaskMessage(st)
+}
+
+func popIDs(t *testing.T, q *Queue) []string {
+	t.Helper()
+	var ids []string
+	for q.Len() > 0 {
+		m, err := q.Pop(context.Background())
+		if err != nil {
+			t.Fatalf("Pop: %v", err)
+		}
+		switch m.Kind {
+		case MessageTask:
+			ids = append(ids, m.Task.Task.ID)
+		case MessageCancel:
+			ids = append(ids, "cancel:"+m.Cancel.TaskID)
+		}
+	}
+	return ids
+}
+
+func TestQueue_PriorityOrder(t *testing.T) {
+	q := NewQueue()
+	for _, m := range []Message{
+		queuedTask(t, "low-1", 0), queuedTask(t, "high", 9), queuedTask(t, "mid", 4), queuedTask(t, "low-2", 0),
+	} {
+		if err := q.Push(m); err != nil {
+			t.Fatalf("Push: %v", err)
+		}
+	}
+	got := popIDs(t, q)
+	want := []string{"high", "mid", "low-1", "low-2"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestQueue_Preempt(t *testing.T) {
+	cancel := CancelMessage(TaskCancel{Engagement: "eng-2026-q1", TaskID: "x", Token: "tok"})
+	for _, tc := range []struct {
+		preempt bool
+		want    string
+	}{{true, "cancel:x"}, {false, "top"}} {
+		q := NewQueue()
+		q.Preempt = tc.preempt
+		_ = q.Push(queuedTask(t, "top", MaxPriority))
+		_ = q.Push(cancel)
+		if got := popIDs(t, q); got[0] != tc.want {
+			t.Errorf("preempt=%v: got %v first, want %s", tc.preempt, got, tc.want)
+		}
+	}
+}
+
+func TestQueue_MaxDepthAndClose(t *testing.T) {
+	q := NewQueue()
+	q.MaxDepth = 1
+	if err := q.Push(queuedTask(t, "a", 0)); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if err := q.Push(queuedTask(t, "b", 0)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if err := q.Push(CancelMessage(TaskCancel{TaskID: "a", Token: "t"})); err != nil {
+		t.Fatalf("control messages should bypass MaxDepth: %v", err)
+	}
+	if err := q.Push(Message{Kind: MessageHalt}); err == nil {
+		t.Error("expected malformed message to be refused")
+	}
+	q.Close()
+	if err := q.Push(queuedTask(t, "c", 0)); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("expected ErrQueueClosed, got %v", err)
+	}
+	if got := popIDs(t, q); len(got) != 2 {
+		t.Fatalf("expected closed queue to drain, got %v", got)
+	}
+	if _, err := q.Pop(context.Background()); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("expected ErrQueueClosed after drain, got %v", err)
+	}
+}
+
+func TestQueue_PopBlocks(t *testing.T) {
+	q := NewQueue()
+	got := make(chan Message, 1)
+	go func() {
+		m, _ := q.Pop(context.Background())
+		got <- m
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_ = q.Push(queuedTask(t, "late", 0))
+	select {
+	case m := <-got:
+		if m.Task.Task.ID != "late" {
+			t.Fatalf("unexpected message %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop did not wake on Push")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.Pop(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestQueue_PopMatch(t *testing.T) {
+	q := NewQueue()
+	win := queuedTask(t, "win", 1)
+	win.Task.Task.Selector = "os=windows"
+	_ = q.Push(win)
+	_ = q.Push(queuedTask(t, "any-low", 0))
+	_ = q.Push(queuedTask(t, "any-high", 2))
+	linux := LabelMatcher(Labels{"os": "linux"})
+
+	for _, want := range []string{"any-high", "any-low"} {
+		m, err := q.PopMatch(context.Background(), linux)
+		if err != nil || m.Task.Task.ID != want {
+			t.Fatalf("PopMatch = %v, %v; want %s", m.Task, err, want)
+		}
+	}
+	got := make(chan string, 1)
+	go func() {
+		m, err := q.PopMatch(context.Background(), linux)
+		if err != nil {
+			got <- err.Error()
+			return
+		}
+		got <- m.Task.Task.ID
+	}()
+	time.Sleep(10 * time.Millisecond)
+	_ = q.Push(queuedTask(t, "late", 0))
+	select {
+	case id := <-got:
+		if id != "late" {
+			t.Fatalf("woke with %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PopMatch did not wake on Push")
+	}
+	if q.Len() != 1 || popIDs(t, q)[0] != "win" {
+		t.Error("the targeted task did not stay queued")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_ = q.Push(win)
+	if _, err := q.PopMatch(ctx, linux); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("PopMatch with only unmatched work = %v", err)
+	}
+	q.Close()
+	if _, err := q.PopMatch(context.Background(), linux); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("PopMatch on a closed queue = %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// ErrThrottled is returned for a task that could not start before its
+// context ended because a TargetLimit held it back.
+var ErrThrottled = errors.New("task throttled by target rate limit")
+
+// TargetLimit bounds how hard an engagement's tasks may hit one system.
+// Targets are compared as DeconflictionRegistry compares them: addresses
+// overlap when one's prefix contains the other's, hostnames and accounts
+// match case-insensitively. For example, at most two tasks at once
+// anywhere in the DMZ, each starting at least a minute apart:
+//
+//	{"target": "10.20.0.0/16", "max_concurrent": 2, "min_interval_seconds": 60}
+type TargetLimit struct {
+	// Target is the host, subnet, or account the limit covers, counted as
+	// a whole: a subnet limit caps all tasks in the subnet together. Empty
+	// means each target on its own, so every system gets the limit.
+	Target string `json:"target,omitempty"`
+	// MaxConcurrent caps the tasks running against the target at once;
+	// 0 means no cap.
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// MinIntervalSeconds is the least time between task starts against
+	// the target; 0 means none.
+	MinIntervalSeconds int `json:"min_interval_seconds,omitempty"`
+}
+
+// Validate checks that the limit limits something and its target parses.
+func (l TargetLimit) Validate() error {
+	if l.MaxConcurrent < 0 || l.MinIntervalSeconds < 0 {
+		return errors.New("target limit values must not be negative")
+	}
+	if l.MaxConcurrent == 0 && l.MinIntervalSeconds == 0 {
+		return errors.New("target limit needs max_concurrent or min_interval_seconds")
+	}
+	if l.Target != "" {
+		if p, h := parseTarget(l.Target); !p.IsValid() && h == "" {
+			return fmt.Errorf("target limit target %q is not a host, subnet, or account", l.Target)
+		}
+	}
+	return nil
+}
+
+func (l TargetLimit) interval() time.Duration {
+	return time.Duration(l.MinIntervalSeconds) * time.Second
+}
+
+// targetKey is a parsed target: an address prefix or a lowercase host.
+type targetKey struct {
+	prefix netip.Prefix
+	host   string
+}
+
+func keyOf(target string) targetKey {
+	p, h := parseTarget(target)
+	return targetKey{prefix: p, host: h}
+}
+
+func (k targetKey) overlaps(o targetKey) bool {
+	if k.prefix.IsValid() && o.prefix.IsValid() {
+		return k.prefix.Overlaps(o.prefix)
+	}
+	return k.host != "" && k.host == o.host
+}
+
+// limitedRun is a task the limiter admitted. It is forgotten once it has
+// finished and its start is older than every interval.
+type limitedRun struct {
+	engagement string
+	targets    []targetKey
+	start      time.Time
+	running    bool
+}
+
+func (r *limitedRun) touches(k targetKey) bool {
+	for _, t := range r.targets {
+		if t.overlaps(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// TargetLimiter throttles simulation tasks against the same system, so an
+// engagement cannot fire a burst of tasks at one host, subnet, or account.
+// Limits are per engagement and count only that engagement's tasks. Tasks
+// naming no target are never throttled. It is safe for concurrent use; a
+// nil limiter admits everything.
+type TargetLimiter struct {
+	// Limits maps an engagement to the limits on its tasks; every limit a
+	// task's targets fall under must admit it. Engagements not listed get
+	// Default.
+	Limits  map[string][]TargetLimit
+	Default []TargetLimit
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	runs    []*limitedRun
+	changed chan struct{}
+}
+
+func (l *TargetLimiter) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+func (l *TargetLimiter) limits(engagement string) []TargetLimit {
+	if ls, ok := l.Limits[engagement]; ok {
+		return ls
+	}
+	return l.Default
+}
+
+// Acquire waits until every limit on task's targets admits it, then
+// counts it as running from now until release is called. It returns an
+// error wrapping ErrThrottled if ctx ends first.
+func (l *TargetLimiter) Acquire(ctx context.Context, task Task) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	for {
+		l.mu.Lock()
+		r, wait, reason := l.admit(task, l.now())
+		if l.changed == nil {
+			l.changed = make(chan struct{})
+		}
+		changed := l.changed
+		l.mu.Unlock()
+		if r != nil {
+			return func() { l.release(r) }, nil
+		}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("%w: %s: %w", ErrThrottled, reason, context.Cause(ctx))
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Wait reports how long task would wait to start at now, and why, without
+// admitting it. It returns 0 if the task may start, or -1 if it must wait
+// for a running task to finish.
+func (l *TargetLimiter) Wait(task Task, now time.Time) (time.Duration, string) {
+	if l == nil {
+		return 0, ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.check(task, now)
+}
+
+// admit registers task as running if check allows it at now. Otherwise
+// it returns the wait and reason. The caller holds l.mu.
+func (l *TargetLimiter) admit(task Task, now time.Time) (*limitedRun, time.Duration, string) {
+	wait, reason := l.check(task, now)
+	if wait != 0 {
+		return nil, wait, reason
+	}
+	r := &limitedRun{engagement: task.Engagement, start: now, running: true}
+	for _, t := range TaskTargets(task) {
+		r.targets = append(r.targets, keyOf(t))
+	}
+	l.runs = append(l.runs, r)
+	return r, 0, ""
+}
+
+// check returns how long task must wait at now and the limit holding it,
+// as Wait does. It also forgets runs no limit needs any more. The caller
+// holds l.mu.
+func (l *TargetLimiter) check(task Task, now time.Time) (time.Duration, string) {
+	kept := l.runs[:0]
+	for _, r := range l.runs {
+		if r.running || now.Sub(r.start) < l.longest(r.engagement) {
+			kept = append(kept, r)
+		}
+	}
+	clear(l.runs[len(kept):])
+	l.runs = kept
+
+	limits := l.limits(task.Engagement)
+	var wait time.Duration
+	var reason string
+	for _, target := range TaskTargets(task) {
+		k := keyOf(target)
+		for _, lim := range limits {
+			scope := k
+			if lim.Target != "" {
+				scope = keyOf(lim.Target)
+				if !scope.overlaps(k) {
+					continue
+				}
+			}
+			running := 0
+			var last time.Time
+			for _, r := range l.runs {
+				if r.engagement != task.Engagement || !r.touches(scope) {
+					continue
+				}
+				if r.running {
+					running++
+				}
+				if r.start.After(last) {
+					last = r.start
+				}
+			}
+			if lim.MaxConcurrent > 0 && running >= lim.MaxConcurrent {
+				return -1, fmt.Sprintf("%d tasks already running against %s", running, describeScope(lim, target))
+			}
+			if d := last.Add(lim.interval()).Sub(now); !last.IsZero() && d > wait {
+				wait = d
+				reason = fmt.Sprintf("next task against %s may start in %s", describeScope(lim, target), d.Round(time.Second))
+			}
+		}
+	}
+	return wait, reason
+}
+
+// longest returns the longest interval among engagement's limits.
+func (l *TargetLimiter) longest(engagement string) time.Duration {
+	var d time.Duration
+	for _, lim := range l.limits(engagement) {
+		d = max(d, lim.interval())
+	}
+	return d
+}
+
+func describeScope(lim TargetLimit, target string) string {
+	if lim.Target != "" {
+		return lim.Target
+	}
+	return target
+}
+
+func (l *TargetLimiter) release(r *limitedRun) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !r.running {
+		return
+	}
+	r.running = false
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
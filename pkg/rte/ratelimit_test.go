@@ -0,0 +1,164 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func targetTask(id, target string) Task {
+	task := validTask(time.Now().UTC())
+	task.ID = id
+	task.Params = map[string]string{"target": target}
+	return task
+}
+
+func TestTargetLimit_Validate(t *testing.T) {
+	for _, l := range []TargetLimit{
+		{},
+		{MaxConcurrent: -1},
+		{Target: " ", MaxConcurrent: 1},
+	} {
+		if err := l.Validate(); err == nil {
+			t.Errorf("%+v: expected error", l)
+		}
+	}
+	if err := (TargetLimit{Target: "10.0.0.0/8", MinIntervalSeconds: 30}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTargetLimiter_MaxConcurrent(t *testing.T) {
+	l := &TargetLimiter{Default: []TargetLimit{{MaxConcurrent: 1}}}
+	ctx := context.Background()
+	release, err := l.Acquire(ctx, targetTask("a", "10.0.0.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if wait, reason := l.Wait(targetTask("b", "10.0.0.0/24"), now); wait != -1 || !strings.Contains(reason, "already running") {
+		t.Fatalf("overlapping subnet: wait %v, %q", wait, reason)
+	}
+	if wait, _ := l.Wait(targetTask("c", "10.0.1.5"), now); wait != 0 {
+		t.Fatalf("other host throttled: %v", wait)
+	}
+	other := targetTask("d", "10.0.0.5")
+	other.Engagement = "eng-other"
+	if wait, _ := l.Wait(other, now); wait != 0 {
+		t.Fatalf("other engagement throttled: %v", wait)
+	}
+	if wait, _ := l.Wait(targetTask("e", ""), now); wait != 0 {
+		t.Fatalf("untargeted task throttled: %v", wait)
+	}
+
+	got := make(chan error, 1)
+	go func() {
+		r, err := l.Acquire(ctx, targetTask("b", "10.0.0.0/24"))
+		if err == nil {
+			r()
+		}
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		t.Fatalf("second task admitted while first ran: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	release()
+	if err := <-got; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	hold, _ := l.Acquire(context.Background(), targetTask("a", "HOST.example"))
+	defer hold()
+	if _, err := l.Acquire(ctx, targetTask("b", "host.example")); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("held task: got %v", err)
+	}
+}
+
+func TestTargetLimiter_MinInterval(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := &TargetLimiter{
+		Limits: map[string][]TargetLimit{
+			"eng-2026-q1": {{Target: "10.20.0.0/16", MinIntervalSeconds: 60}},
+		},
+		Now: func() time.Time { return now },
+	}
+	release, err := l.Acquire(context.Background(), targetTask("a", "10.20.1.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	wait, reason := l.Wait(targetTask("b", "10.20.9.9"), now.Add(15*time.Second))
+	if wait != 45*time.Second || !strings.Contains(reason, "10.20.0.0/16") {
+		t.Fatalf("wait %v, %q", wait, reason)
+	}
+	if wait, _ := l.Wait(targetTask("c", "10.30.0.1"), now); wait != 0 {
+		t.Fatalf("target outside the limit throttled: %v", wait)
+	}
+	if wait, _ := l.Wait(targetTask("b", "10.20.9.9"), now.Add(time.Minute)); wait != 0 {
+		t.Fatalf("wait after interval: %v", wait)
+	}
+	if len(l.runs) != 0 {
+		t.Fatalf("expired run kept: %d", len(l.runs))
+	}
+}
+
+func TestExecutor_TargetLimits(t *testing.T) {
+	e := NewExecutor()
+	e.TargetLimits = &TargetLimiter{Default: []TargetLimit{{MaxConcurrent: 1}}}
+	started, unblock := make(chan struct{}), make(chan struct{})
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) {
+		started <- struct{}{}
+		<-unblock
+		return nil, nil
+	}))
+	pub, priv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(id string) *SignedTask {
+		st, err := SignTask(targetTask(id, "192.168.1.40"), priv, pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	done := make(chan *TaskResult, 2)
+	for _, id := range []string{"task-001", "task-002"} {
+		go func(st *SignedTask) {
+			res, err := e.Execute(context.Background(), st)
+			if err != nil {
+				t.Error(err)
+			}
+			done <- res
+		}(sign(id))
+	}
+	<-started
+	select {
+	case <-started:
+		t.Fatal("both tasks ran against one target at once")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unblock <- struct{}{}
+	<-started
+	unblock <- struct{}{}
+	for range 2 {
+		if res := <-done; res == nil || res.State != StateCompleted {
+			t.Fatalf("result = %+v", res)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hold, _ := e.TargetLimits.Acquire(ctx, targetTask("other", "192.168.1.40"))
+	defer hold()
+	cancel()
+	if _, err := e.Execute(ctx, sign("task-003")); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("throttled task: got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package rte
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SignedResult wraps a TaskResult with the executing agent's attestation so
+// reports can prove which agent produced each outcome.
+type SignedResult struct {
+	Result    TaskResult `json:"result"`
+	PublicKey []byte     `json:"public_key"`
+	Signature []byte     `json:"signature"`
+	// Attestations are in-toto statements about the result (see
+	// AttestResult).
+	Attestations []Envelope `json:"attestations,omitempty"`
+}
+
+// SignResult signs a task result with the agent's key.
+func SignResult(res TaskResult, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedResult, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	if res.TaskID == "" {
+		return nil, errors.New("result task ID is required")
+	}
+	payload, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("marshal result: %w", err)
+	}
+	return &SignedResult{Result: res, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// VerifyResult verifies a signed result's signature.
+func VerifyResult(sr *SignedResult) error {
+	if sr == nil {
+		return errors.New("signed result is nil")
+	}
+	if len(sr.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sr.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	payload, err := json.Marshal(sr.Result)
+	if err != nil {
+		return fmt.Errorf("marshal result: %w", err)
+	}
+	if !ed25519.Verify(sr.PublicKey, payload, sr.Signature) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
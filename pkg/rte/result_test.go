@@ -0,0 +1,68 @@
+package rte
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testResult() TaskResult {
+	now := time.Now().UTC()
+	return TaskResult{
+		TaskID:     "task-001",
+		Engagement: "eng-2026-q1",
+		Type:       TaskInventory,
+		Operator:   "op-alice",
+		State:      StateCompleted,
+		StartedAt:  now.Add(-time.Second),
+		FinishedAt: now,
+		Output:     json.RawMessage(`{"hostname":"ws-finance-001"}`),
+	}
+}
+
+func TestSignResult_Verify(t *testing.T) {
+	pub, priv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("generate key pair: %v", err)
+	}
+	sr, err := SignResult(testResult(), priv, pub)
+	if err != nil {
+		t.Fatalf("SignResult: %v", err)
+	}
+	data, err := json.Marshal(sr)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded SignedResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if err := VerifyResult(&decoded); err != nil {
+		t.Fatalf("VerifyResult after roundtrip: %v", err)
+	}
+}
+
+func TestVerifyResult_Tampered(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	sr, err := SignResult(testResult(), priv, pub)
+	if err != nil {
+		t.Fatalf("SignResult: %v", err)
+	}
+	sr.Result.State = StateFailed
+	if err := VerifyResult(sr); err == nil {
+		t.Fatal("expected tampered result to fail verification")
+	}
+	if err := VerifyResult(nil); err == nil {
+		t.Fatal("expected nil result to fail")
+	}
+}
+
+func TestSignResult_Invalid(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	if _, err := SignResult(testResult(), priv[:10], pub); err == nil {
+		t.Fatal("expected invalid key to fail")
+	}
+	if _, err := SignResult(TaskResult{}, priv, pub); err == nil {
+		t.Fatal("expected result without task ID to fail")
+	}
+}
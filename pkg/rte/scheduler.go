@@ -0,0 +1,125 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Scheduler drains a Queue into an Executor. Tasks run on a bounded worker
+// pool; cancels and halts are applied on the dispatch loop as soon as they
+// are popped, so with Queue.Preempt set they never wait behind simulation
+// work.
+type Scheduler struct {
+	Queue    *Queue
+	Executor *Executor
+	// Workers bounds concurrent task runs; 0 means 1.
+	Workers int
+	// OnResult, if set, receives every task result or rejection. For a
+	// halt it is called once per cancelled in-flight task. Calls from
+	// concurrent workers may overlap.
+	OnResult func(Message, *TaskResult, error)
+}
+
+// Run dispatches messages until ctx ends or the queue is closed and drained,
+// then waits for running tasks to finish.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if s.Queue == nil || s.Executor == nil {
+		return errors.New("scheduler needs a queue and an executor")
+	}
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		if err := s.waitSlot(ctx, slots); err != nil {
+			return err
+		}
+		m, err := s.Queue.Pop(ctx)
+		if err != nil {
+			<-slots
+			if errors.Is(err, ErrQueueClosed) {
+				return nil
+			}
+			return err
+		}
+		if m.Control() {
+			<-slots
+			s.control(ctx, m)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			res, err := s.Executor.Execute(ctx, m.Task)
+			s.report(m, res, err)
+		}()
+	}
+}
+
+// waitSlot blocks until a worker slot is free. Meanwhile it applies control
+// messages that reach the head of the queue, so a cancel or halt is not
+// stuck behind the busy workers it is meant to stop.
+func (s *Scheduler) waitSlot(ctx context.Context, slots chan struct{}) error {
+	for {
+		select {
+		case slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.Queue.ready:
+			for {
+				m, ok := s.Queue.popControl()
+				if !ok {
+					break
+				}
+				s.control(ctx, m)
+			}
+		}
+	}
+}
+
+// control applies a cancel, halt, pause request, or attestation. A halt also drops the engagement's
+// queued tasks, which the executor would refuse anyway.
+func (s *Scheduler) control(ctx context.Context, m Message) {
+	switch m.Kind {
+	case MessageCancel:
+		res, err := s.Executor.Cancel(ctx, *m.Cancel)
+		s.report(m, res, err)
+	case MessagePause:
+		var err error
+		if m.Pause.Request.Action == ActionResume {
+			err = s.Executor.Resume(m.Pause)
+		} else {
+			err = s.Executor.Pause(m.Pause)
+		}
+		s.report(m, nil, err)
+	case MessageAttest:
+		_, err := s.Executor.Attest(m.Attest)
+		s.report(m, nil, err)
+	case MessageHalt:
+		results, err := s.Executor.Halt(ctx, m.Halt)
+		if err == nil {
+			eng := m.Halt.Halt.Engagement
+			s.Queue.Remove(func(q Message) bool {
+				return q.Kind == MessageTask && q.Task.Task.Engagement == eng
+			})
+		}
+		for i := range results {
+			s.report(m, &results[i], nil)
+		}
+		if err != nil || len(results) == 0 {
+			s.report(m, nil, err)
+		}
+	}
+}
+
+func (s *Scheduler) report(m Message, res *TaskResult, err error) {
+	if s.OnResult != nil {
+		s.OnResult(m, res, err)
+	}
+}
@@ -0,0 +1,120 @@
+package rte
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduler_RunsByPriority(t *testing.T) {
+	e := NewExecutor()
+	var mu sync.Mutex
+	var order []string
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(_ context.Context, task Task) (any, error) {
+		mu.Lock()
+		order = append(order, task.ID)
+		mu.Unlock()
+		return nil, nil
+	}))
+	q := NewQueue()
+	_ = q.Push(queuedTask(t, "low", 1))
+	_ = q.Push(queuedTask(t, "high", 8))
+	q.Close()
+	s := &Scheduler{Queue: q, Executor: e}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(order) != 2 || order[0] != "high" || order[1] != "low" {
+		t.Fatalf("unexpected order %v", order)
+	}
+}
+
+func TestScheduler_CancelPreemptsQueuedWork(t *testing.T) {
+	e := NewExecutor()
+	started := make(chan struct{})
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(ctx context.Context, task Task) (any, error) {
+		if task.ID != "long" {
+			return nil, nil
+		}
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	q := NewQueue()
+	q.Preempt = true
+	task := validTask(time.Now().UTC())
+	task.ID = "long"
+	task.CancelToken = "tok-1"
+	pub, priv, _ := GenerateKeyPair()
+	st, _ := SignTask(task, priv, pub)
+	_ = q.Push(TaskMessage(st))
+
+	var mu sync.Mutex
+	results := map[string]*TaskResult{}
+	var cancelErr error
+	s := &Scheduler{Queue: q, Executor: e, OnResult: func(m Message, res *TaskResult, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if m.Kind == MessageCancel {
+			cancelErr = err
+		}
+		if res != nil {
+			results[string(m.Kind)+":"+res.TaskID] = res
+		}
+	}}
+	done := make(chan error, 1)
+	go func() { done <- s.Run(context.Background()) }()
+	<-started
+	// The queued task would wait for the single worker; the cancel must not.
+	_ = q.Push(queuedTask(t, "queued", MaxPriority))
+	_ = q.Push(CancelMessage(TaskCancel{Engagement: task.Engagement, TaskID: "long", Token: "tok-1", RequestedBy: "lead-bob"}))
+	q.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if cancelErr != nil {
+		t.Fatalf("cancel: %v", cancelErr)
+	}
+	res := results["cancel:long"]
+	if res == nil || res.State != StateCancelled || !strings.Contains(res.Error, "task cancelled by lead-bob") {
+		t.Fatalf("unexpected cancel result %+v", res)
+	}
+	if results["task:queued"] == nil {
+		t.Error("expected queued task to run after the cancel")
+	}
+}
+
+func TestExecutor_Cancel_Token(t *testing.T) {
+	e := NewExecutor()
+	started := make(chan struct{})
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(ctx context.Context, _ Task) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	task := validTask(time.Now().UTC())
+	task.CancelToken = "right"
+	pub, priv, _ := GenerateKeyPair()
+	st, _ := SignTask(task, priv, pub)
+	go func() { _, _ = e.Execute(context.Background(), st) }()
+	<-started
+
+	ctx := context.Background()
+	c := TaskCancel{Engagement: task.Engagement, TaskID: task.ID, Token: "wrong"}
+	if _, err := e.Cancel(ctx, c); err == nil {
+		t.Fatal("expected wrong token to be refused")
+	}
+	if _, err := e.Cancel(ctx, TaskCancel{Engagement: task.Engagement, TaskID: "nope", Token: "right"}); err == nil {
+		t.Fatal("expected unknown task to be refused")
+	}
+	c.Token = "right"
+	res, err := e.Cancel(ctx, c)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if res.State != StateCancelled {
+		t.Fatalf("state: got %s, want %s", res.State, StateCancelled)
+	}
+}
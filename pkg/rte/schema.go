@@ -0,0 +1,216 @@
+package rte
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TaskSchemaVersion is the Task schema this build signs and executes. Tasks
+// signed before versioning carry no schema_version and are read as
+// version 1.
+const TaskSchemaVersion = 1
+
+// ErrSchemaVersion is returned for a task whose schema version this build
+// cannot execute as is.
+var ErrSchemaVersion = errors.New("unsupported task schema version")
+
+// Migration upgrades a decoded task from one schema version to the next in
+// place. It must be deterministic: verifiers re-run it to check an upgraded
+// task still matches what the operator signed.
+type Migration func(task map[string]any) error
+
+// migrationSet maps a schema version to the migration out of it.
+type migrationSet map[int]Migration
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = migrationSet{}
+)
+
+// RegisterMigration registers the migration from schema version from to
+// from+1. It panics if from is not positive or already has a migration, as
+// registration happens at init time.
+func RegisterMigration(from int, m Migration) {
+	if from < 1 || m == nil {
+		panic("rte: RegisterMigration needs a positive version and a migration")
+	}
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	if _, dup := migrations[from]; dup {
+		panic(fmt.Sprintf("rte: migration from schema version %d registered twice", from))
+	}
+	migrations[from] = m
+}
+
+// LegacyTask is the task exactly as the operator signed it, kept alongside
+// an upgraded task so the original signature and approval still verify.
+type LegacyTask struct {
+	SchemaVersion int             `json:"schema_version"`
+	Task          json.RawMessage `json:"task"`
+}
+
+// schemaVersion returns a task's effective schema version.
+func schemaVersion(v int) int {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
+
+// checkSchemaVersion is the negotiation rule VerifyTask applies: this build
+// executes only its own schema version. Newer tasks need a newer build;
+// older ones must first pass through UpgradeSignedTask.
+func checkSchemaVersion(v int) error {
+	switch v = schemaVersion(v); {
+	case v > TaskSchemaVersion:
+		return fmt.Errorf("%w: %d is newer than this build's %d", ErrSchemaVersion, v, TaskSchemaVersion)
+	case v < TaskSchemaVersion:
+		return fmt.Errorf("%w: %d must be upgraded to %d before execution", ErrSchemaVersion, v, TaskSchemaVersion)
+	}
+	return nil
+}
+
+// UpgradeSignedTask decodes a stored SignedTask of any supported schema
+// version, verifies the operator's signature over the task as signed, and
+// migrates it to TaskSchemaVersion. An upgraded task keeps its signed
+// original in Legacy, so VerifyTask and VerifyApproval accept it.
+func UpgradeSignedTask(data []byte) (*SignedTask, error) {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	return migrations.upgradeSigned(data, TaskSchemaVersion)
+}
+
+func (ms migrationSet) upgradeSigned(data []byte, current int) (*SignedTask, error) {
+	var env struct {
+		Task      json.RawMessage   `json:"task"`
+		PublicKey []byte            `json:"public_key"`
+		Signature []byte            `json:"signature"`
+		Approval  *Approval         `json:"approval,omitempty"`
+		Trace     map[string]string `json:"trace,omitempty"`
+		Legacy    *LegacyTask       `json:"legacy,omitempty"`
+		Digest    []byte            `json:"digest,omitempty"`
+		Certs     [][]byte          `json:"certificates,omitempty"`
+		Timestamp []byte            `json:"timestamp,omitempty"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("decode signed task: %w", err)
+	}
+	if len(env.Digest) > 0 {
+		return nil, errors.New("detached signed tasks cannot be upgraded")
+	}
+	legacy := env.Legacy
+	if legacy == nil {
+		var raw bytes.Buffer
+		if err := json.Compact(&raw, env.Task); err != nil {
+			return nil, fmt.Errorf("decode signed task: %w", err)
+		}
+		var v struct {
+			SchemaVersion int `json:"schema_version"`
+		}
+		if err := json.Unmarshal(raw.Bytes(), &v); err != nil {
+			return nil, fmt.Errorf("decode signed task: %w", err)
+		}
+		legacy = &LegacyTask{SchemaVersion: schemaVersion(v.SchemaVersion), Task: raw.Bytes()}
+	}
+	st := &SignedTask{PublicKey: env.PublicKey, Signature: env.Signature, Certificates: env.Certs, Timestamp: env.Timestamp, Approval: env.Approval, Trace: env.Trace}
+	if legacy.SchemaVersion == current {
+		if err := json.Unmarshal(legacy.Task, &st.Task); err != nil {
+			return nil, fmt.Errorf("decode task: %w", err)
+		}
+	} else {
+		task, err := ms.upgrade(legacy.Task, legacy.SchemaVersion, current)
+		if err != nil {
+			return nil, err
+		}
+		st.Task, st.Legacy = task, legacy
+	}
+	if err := ms.verifySignature(st, current); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// upgrade runs the migrations from one schema version to another and
+// decodes the result, refusing fields the current Task does not know.
+func (ms migrationSet) upgrade(raw []byte, from, to int) (Task, error) {
+	if from < 1 || from > to {
+		return Task{}, fmt.Errorf("%w: cannot upgrade %d to %d", ErrSchemaVersion, from, to)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return Task{}, fmt.Errorf("decode task: %w", err)
+	}
+	for v := from; v < to; v++ {
+		m, ok := ms[v]
+		if !ok {
+			return Task{}, fmt.Errorf("%w: no migration from %d", ErrSchemaVersion, v)
+		}
+		if err := m(doc); err != nil {
+			return Task{}, fmt.Errorf("migrate task from schema version %d: %w", v, err)
+		}
+	}
+	doc["schema_version"] = to
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return Task{}, fmt.Errorf("marshal migrated task: %w", err)
+	}
+	dec = json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var t Task
+	if err := dec.Decode(&t); err != nil {
+		return Task{}, fmt.Errorf("decode migrated task: %w", err)
+	}
+	return t, nil
+}
+
+// signedPayload returns the bytes the operator signed: the digest for a
+// detached task, the Legacy original for an upgraded task after checking
+// it still migrates to st.Task, or else st.Task itself.
+func (ms migrationSet) signedPayload(st *SignedTask, current int) ([]byte, error) {
+	if st.Detached() {
+		if st.Legacy != nil {
+			return nil, errors.New("a detached task cannot carry a legacy original")
+		}
+		if len(st.Digest) != sha256.Size {
+			return nil, errors.New("invalid task digest size")
+		}
+		return digestPayload(st.Digest), nil
+	}
+	if st.Legacy == nil {
+		payload, err := json.Marshal(st.Task)
+		if err != nil {
+			return nil, fmt.Errorf("marshal task: %w", err)
+		}
+		return payload, nil
+	}
+	want, err := ms.upgrade(st.Legacy.Task, st.Legacy.SchemaVersion, current)
+	if err != nil {
+		return nil, err
+	}
+	got, err := json.Marshal(st.Task)
+	if err != nil {
+		return nil, fmt.Errorf("marshal task: %w", err)
+	}
+	migrated, err := json.Marshal(want)
+	if err != nil {
+		return nil, fmt.Errorf("marshal task: %w", err)
+	}
+	if !bytes.Equal(got, migrated) {
+		return nil, fmt.Errorf("upgraded task does not match its signed original: %w",
+			&TamperError{TaskID: st.Task.ID, Diffs: Diff(want, st.Task)})
+	}
+	return st.Legacy.Task, nil
+}
+
+// taskPayload is signedPayload with the registered migrations.
+func taskPayload(st *SignedTask) ([]byte, error) {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	return migrations.signedPayload(st, TaskSchemaVersion)
+}
@@ -0,0 +1,179 @@
+package rte
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// signRaw signs a task document as some earlier build would have, without
+// going through the current Task struct.
+func signRaw(t *testing.T, doc map[string]any, priv ed25519.PrivateKey, pub ed25519.PublicKey) []byte {
+	t.Helper()
+	task, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(map[string]any{
+		"task":       json.RawMessage(task),
+		"public_key": []byte(pub),
+		"signature":  ed25519.Sign(priv, task),
+	}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSignTask_StampsSchemaVersion(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	st, err := SignTask(validTask(time.Now().UTC()), priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Task.SchemaVersion != TaskSchemaVersion {
+		t.Fatalf("schema version = %d, want %d", st.Task.SchemaVersion, TaskSchemaVersion)
+	}
+	task := validTask(time.Now().UTC())
+	task.SchemaVersion = TaskSchemaVersion + 1
+	if _, err := SignTask(task, priv, pub); !errors.Is(err, ErrSchemaVersion) {
+		t.Fatalf("signing a future schema: got %v, want ErrSchemaVersion", err)
+	}
+}
+
+func TestVerifyTask_SchemaNegotiation(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	now := time.Now().UTC()
+
+	// Tasks signed before versioning carry no schema_version.
+	unversioned := validTask(now)
+	payload, _ := json.Marshal(unversioned)
+	st := &SignedTask{Task: unversioned, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}
+	if err := VerifyTask(st); err != nil {
+		t.Fatalf("unversioned task: %v", err)
+	}
+
+	future := validTask(now)
+	future.SchemaVersion = TaskSchemaVersion + 1
+	payload, _ = json.Marshal(future)
+	st = &SignedTask{Task: future, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}
+	if err := VerifyTask(st); !errors.Is(err, ErrSchemaVersion) {
+		t.Fatalf("future task: got %v, want ErrSchemaVersion", err)
+	}
+}
+
+func TestUpgradeSignedTask_Current(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	st, err := SignTask(validTask(time.Now().UTC()), priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Countersign(st, priv, pub); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.MarshalIndent(st, "", "  ")
+	got, err := UpgradeSignedTask(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Legacy != nil {
+		t.Fatal("current-schema task should not carry a legacy original")
+	}
+	if err := VerifyTask(got); err != nil {
+		t.Fatalf("VerifyTask: %v", err)
+	}
+	if err := VerifyApproval(got); err != nil {
+		t.Fatalf("VerifyApproval: %v", err)
+	}
+}
+
+// testMigrations simulates a Task that evolved through two more schema
+// versions: v1 named the operator "operator_id", and v2 added a
+// "ticket_url" field that v3 dropped.
+var testMigrations = migrationSet{
+	1: func(doc map[string]any) error {
+		doc["operator"] = doc["operator_id"]
+		delete(doc, "operator_id")
+		return nil
+	},
+	2: func(doc map[string]any) error {
+		delete(doc, "ticket_url")
+		return nil
+	},
+}
+
+func v1Doc(now time.Time) map[string]any {
+	return map[string]any{
+		"id": "task-v1", "engagement": "eng-1", "type": "inventory",
+		"created_at": now.Format(time.RFC3339Nano), "ttl_seconds": 600,
+		"operator_id": "op-alice", "approved_by": "lead-bob", "state": "pending",
+		"ticket_url": "https://tickets.example/SEC-1",
+	}
+}
+
+func TestUpgradeSignedTask_Migrates(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	data := signRaw(t, v1Doc(time.Now().UTC()), priv, pub)
+
+	st, err := testMigrations.upgradeSigned(data, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Task.Operator != "op-alice" || st.Task.SchemaVersion != 3 {
+		t.Fatalf("migrated task = %+v", st.Task)
+	}
+	if st.Legacy == nil || st.Legacy.SchemaVersion != 1 {
+		t.Fatalf("legacy = %+v", st.Legacy)
+	}
+	if err := testMigrations.verifySignature(st, 3); err != nil {
+		t.Fatalf("upgraded task no longer verifies: %v", err)
+	}
+
+	// The upgraded envelope round-trips through storage.
+	stored, _ := json.Marshal(st)
+	again, err := testMigrations.upgradeSigned(stored, 3)
+	if err != nil {
+		t.Fatalf("re-upgrade: %v", err)
+	}
+	if again.Task.Operator != "op-alice" {
+		t.Fatalf("re-upgraded task = %+v", again.Task)
+	}
+
+	// Editing the upgraded task is caught even though Legacy still verifies.
+	st.Task.Operator = "op-mallory"
+	err = testMigrations.verifySignature(st, 3)
+	var te *TamperError
+	if !errors.As(err, &te) || len(te.Diffs) != 1 || te.Diffs[0] != (FieldDiff{Field: "operator", From: "op-alice", To: "op-mallory"}) {
+		t.Fatalf("tampered upgrade: got %v", err)
+	}
+}
+
+func TestUpgradeSignedTask_Refuses(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	now := time.Now().UTC()
+
+	data := signRaw(t, v1Doc(now), priv, pub)
+	if _, err := (migrationSet{1: testMigrations[1]}).upgradeSigned(data, 3); !errors.Is(err, ErrSchemaVersion) {
+		t.Fatalf("missing migration: got %v, want ErrSchemaVersion", err)
+	}
+	// Without the v2 migration dropping ticket_url, the field is unknown.
+	if _, err := (migrationSet{1: testMigrations[1], 2: func(map[string]any) error { return nil }}).upgradeSigned(data, 3); err == nil {
+		t.Fatal("expected unknown field to be refused")
+	}
+
+	doc := v1Doc(now)
+	doc["schema_version"] = 4
+	if _, err := testMigrations.upgradeSigned(signRaw(t, doc, priv, pub), 3); !errors.Is(err, ErrSchemaVersion) {
+		t.Fatalf("future task: got %v, want ErrSchemaVersion", err)
+	}
+
+	var tampered map[string]any
+	_ = json.Unmarshal(data, &tampered)
+	tampered["task"].(map[string]any)["operator_id"] = "op-mallory"
+	data, _ = json.Marshal(tampered)
+	if _, err := testMigrations.upgradeSigned(data, 3); err == nil {
+		t.Fatal("expected tampered legacy task to fail signature verification")
+	}
+}
@@ -0,0 +1,172 @@
+package rte
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels describe an agent, such as os=windows or zone=dmz, for tasks to
+// target with a Selector.
+type Labels map[string]string
+
+// String renders the labels as a selector matching them exactly, keys in
+// order.
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + l[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+// Validate checks that every key and value is a well-formed label.
+func (l Labels) Validate() error {
+	for k, v := range l {
+		if err := checkLabel("key", k); err != nil {
+			return err
+		}
+		if err := checkLabel("value", v); err != nil {
+			return fmt.Errorf("label %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// SelectorOp is how a Requirement tests a label.
+type SelectorOp string
+
+// Selector operators.
+const (
+	OpEquals    SelectorOp = "="
+	OpNotEquals SelectorOp = "!="
+	OpExists    SelectorOp = "exists"
+	OpNotExists SelectorOp = "!exists"
+)
+
+// Requirement is one term of a Selector.
+type Requirement struct {
+	Key   string
+	Op    SelectorOp
+	Value string
+}
+
+func (r Requirement) matches(l Labels) bool {
+	v, ok := l[r.Key]
+	switch r.Op {
+	case OpEquals:
+		return ok && v == r.Value
+	case OpNotEquals:
+		return !ok || v != r.Value
+	case OpExists:
+		return ok
+	case OpNotExists:
+		return !ok
+	}
+	return false
+}
+
+func (r Requirement) String() string {
+	switch r.Op {
+	case OpExists:
+		return r.Key
+	case OpNotExists:
+		return "!" + r.Key
+	}
+	return r.Key + string(r.Op) + r.Value
+}
+
+// Selector picks agents by label. Its text form is a comma-separated list
+// of requirements, all of which must hold: key=value, key!=value, key (the
+// label is set), and !key (it is not), as in Kubernetes equality-based
+// selectors. "os=windows,zone=dmz" selects Windows agents in the DMZ. The
+// empty selector selects every agent.
+type Selector []Requirement
+
+// ParseSelector parses a selector's text form.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			if strings.TrimSpace(s) == "" {
+				break
+			}
+			return nil, fmt.Errorf("selector %q has an empty requirement", s)
+		}
+		var r Requirement
+		switch {
+		case strings.Contains(term, "!="):
+			k, v, _ := strings.Cut(term, "!=")
+			r = Requirement{Key: strings.TrimSpace(k), Op: OpNotEquals, Value: strings.TrimSpace(v)}
+		case strings.Contains(term, "="):
+			k, v, _ := strings.Cut(term, "=")
+			r = Requirement{Key: strings.TrimSpace(k), Op: OpEquals, Value: strings.TrimSpace(strings.TrimPrefix(v, "="))}
+		case strings.HasPrefix(term, "!"):
+			r = Requirement{Key: strings.TrimSpace(term[1:]), Op: OpNotExists}
+		default:
+			r = Requirement{Key: term, Op: OpExists}
+		}
+		if err := checkLabel("key", r.Key); err != nil {
+			return nil, fmt.Errorf("selector requirement %q: %w", term, err)
+		}
+		if r.Op == OpEquals || r.Op == OpNotEquals {
+			if err := checkLabel("value", r.Value); err != nil {
+				return nil, fmt.Errorf("selector requirement %q: %w", term, err)
+			}
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels satisfy every requirement.
+func (s Selector) Matches(labels Labels) bool {
+	for _, r := range s {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// LabelMatcher returns a Queue.PopMatch filter for an agent with labels:
+// it accepts tasks whose Selector the labels satisfy, and every control
+// message, since a cancel or halt must reach whichever agent is asking.
+// Tasks with a selector that does not parse match no agent.
+func LabelMatcher(labels Labels) func(Message) bool {
+	return func(m Message) bool {
+		if m.Kind != MessageTask {
+			return true
+		}
+		sel, err := ParseSelector(m.Task.Task.Selector)
+		return err == nil && sel.Matches(labels)
+	}
+}
+
+// checkLabel keeps label keys and values to 1 to 63 letters, digits, and
+// '-', '_', '.', and '/', so they fit selectors and certificate URIs.
+func checkLabel(kind, v string) error {
+	if v == "" || len(v) > 63 {
+		return fmt.Errorf("label %s must be 1 to 63 characters", kind)
+	}
+	for _, c := range v {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '/') {
+			return fmt.Errorf("label %s %q may only contain letters, digits, '-', '_', '.', and '/'", kind, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package rte
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSelector(t *testing.T) {
+	win := Labels{"os": "windows", "zone": "dmz"}
+	lin := Labels{"os": "linux", "zone": "corp", "quarantined": "true"}
+	for _, tc := range []struct {
+		sel      string
+		norm     string
+		win, lin bool
+	}{
+		{"", "", true, true},
+		{"os=windows", "os=windows", true, false},
+		{" os == windows , zone=dmz ", "os=windows,zone=dmz", true, false},
+		{"zone!=dmz", "zone!=dmz", false, true},
+		{"quarantined", "quarantined", false, true},
+		{"!quarantined", "!quarantined", true, false},
+		{"os=windows,zone=corp", "os=windows,zone=corp", false, false},
+		{"team!=red", "team!=red", true, true},
+	} {
+		sel, err := ParseSelector(tc.sel)
+		if err != nil {
+			t.Errorf("ParseSelector(%q): %v", tc.sel, err)
+			continue
+		}
+		if sel.String() != tc.norm {
+			t.Errorf("ParseSelector(%q) = %q, want %q", tc.sel, sel, tc.norm)
+		}
+		if sel.Matches(win) != tc.win || sel.Matches(lin) != tc.lin {
+			t.Errorf("%q matches windows %v, linux %v", tc.sel, sel.Matches(win), sel.Matches(lin))
+		}
+	}
+	for _, bad := range []string{"os=windows,", "=windows", "os=", "!", "os=win dows", "os=a=b"} {
+		if _, err := ParseSelector(bad); err == nil {
+			t.Errorf("ParseSelector(%q) accepted", bad)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	l := Labels{"zone": "dmz", "os": "windows"}
+	if l.String() != "os=windows,zone=dmz" {
+		t.Errorf("String = %q", l)
+	}
+	if err := l.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (Labels{"os": "windows xp"}).Validate(); err == nil || !strings.Contains(err.Error(), "label os") {
+		t.Errorf("Validate bad value: %v", err)
+	}
+}
+
+func TestLabelMatcher(t *testing.T) {
+	targeted := queuedTask(t, "win-only", 0)
+	targeted.Task.Task.Selector = "os=windows"
+	broken := queuedTask(t, "broken", 0)
+	broken.Task.Task.Selector = "os=="
+	match := LabelMatcher(Labels{"os": "linux"})
+	if match(targeted) || match(broken) || !match(queuedTask(t, "any", 0)) {
+		t.Error("linux agent matched the wrong tasks")
+	}
+	if !match(CancelMessage(TaskCancel{Engagement: "eng-1", TaskID: "win-only"})) {
+		t.Error("control messages must reach every agent")
+	}
+	if !LabelMatcher(Labels{"os": "windows"})(targeted) {
+		t.Error("windows agent did not match its task")
+	}
+}
+
+func TestTask_ValidateSelector(t *testing.T) {
+	task := validTask(time.Now().UTC())
+	task.Selector = "os=windows,,zone=dmz"
+	if err := task.Validate(time.Now()); !errors.Is(err, ErrBadSelector) {
+		t.Fatalf("Validate = %v, want ErrBadSelector", err)
+	}
+}
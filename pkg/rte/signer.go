@@ -0,0 +1,148 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Signer produces ed25519 signatures. It is the seam for keys held outside
+// the process, such as in a KMS or HSM, where signing is a network call
+// that must honor ctx.
+type Signer interface {
+	PublicKey() ed25519.PublicKey
+	Sign(ctx context.Context, message []byte) ([]byte, error)
+}
+
+// keySigner signs with an in-memory private key.
+type keySigner struct {
+	priv ed25519.PrivateKey
+	pub  ed25519.PublicKey
+}
+
+// NewKeySigner returns a Signer for an in-memory key pair.
+func NewKeySigner(priv ed25519.PrivateKey, pub ed25519.PublicKey) (Signer, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	return keySigner{priv: priv, pub: pub}, nil
+}
+
+func (k keySigner) PublicKey() ed25519.PublicKey { return k.pub }
+
+func (k keySigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ed25519.Sign(k.priv, message), nil
+}
+
+// SignTaskContext is SignTask with a Signer; ctx bounds the signing call.
+func SignTaskContext(ctx context.Context, task Task, s Signer) (*SignedTask, error) {
+	pub, err := signerKey(s)
+	if err != nil {
+		return nil, err
+	}
+	task, payload, err := canonicalTask(task)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signWith(ctx, s, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedTask{Task: task, PublicKey: pub, Signature: sig, Certificates: chainOf(s)}, nil
+}
+
+// chainOf returns the certificate chain of a CertSigner, or nil.
+func chainOf(s Signer) [][]byte {
+	if cs, ok := s.(CertSigner); ok {
+		return cs.CertificateChain()
+	}
+	return nil
+}
+
+// signerKey returns the signer's public key after checking it.
+func signerKey(s Signer) (ed25519.PublicKey, error) {
+	if s == nil {
+		return nil, errors.New("signer is nil")
+	}
+	pub := s.PublicKey()
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	return pub, nil
+}
+
+// canonicalTask stamps the schema version, validates the task, and returns
+// it with its canonical JSON, the bytes a task signature covers.
+func canonicalTask(task Task) (Task, []byte, error) {
+	if task.SchemaVersion == 0 {
+		task.SchemaVersion = TaskSchemaVersion
+	}
+	if err := checkSchemaVersion(task.SchemaVersion); err != nil {
+		return Task{}, nil, err
+	}
+	if err := task.Validate(time.Now().UTC()); err != nil {
+		return Task{}, nil, fmt.Errorf("task validation failed: %w", err)
+	}
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return Task{}, nil, fmt.Errorf("marshal task: %w", err)
+	}
+	return task, payload, nil
+}
+
+func signWith(ctx context.Context, s Signer, payload []byte) ([]byte, error) {
+	sig, err := s.Sign(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("sign task: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, errors.New("signer returned an invalid signature size")
+	}
+	return sig, nil
+}
+
+// CountersignContext is Countersign with a Signer; ctx bounds the signing
+// call.
+func CountersignContext(ctx context.Context, st *SignedTask, s Signer) error {
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	if s == nil {
+		return errors.New("signer is nil")
+	}
+	pub := s.PublicKey()
+	if len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	payload, err := approvalPayload(st)
+	if err != nil {
+		return err
+	}
+	sig, err := s.Sign(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("countersign task: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return errors.New("signer returned an invalid signature size")
+	}
+	st.Approval = &Approval{PublicKey: pub, Signature: sig}
+	return nil
+}
+
+// VerifyTaskContext is VerifyTask that first honors ctx, so a caller's
+// deadline also covers verification queued behind other work.
+func VerifyTaskContext(ctx context.Context, st *SignedTask) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return VerifyTask(st)
+}
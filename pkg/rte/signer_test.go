@@ -0,0 +1,70 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+// remoteSigner stands in for a KMS: it blocks until released or ctx ends.
+type remoteSigner struct {
+	Signer
+	release chan struct{}
+}
+
+func (r remoteSigner) Sign(ctx context.Context, msg []byte) ([]byte, error) {
+	select {
+	case <-r.release:
+		return r.Signer.Sign(ctx, msg)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestSignTaskContext(t *testing.T) {
+	k := newKeyPair(t)
+	local, err := NewKeySigner(k.priv, k.pub)
+	if err != nil {
+		t.Fatalf("NewKeySigner: %v", err)
+	}
+	s := remoteSigner{Signer: local, release: make(chan struct{})}
+	task := validTask(time.Now().UTC())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := SignTaskContext(ctx, task, s); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline to stop signing, got %v", err)
+	}
+
+	close(s.release)
+	st, err := SignTaskContext(context.Background(), task, s)
+	if err != nil {
+		t.Fatalf("SignTaskContext: %v", err)
+	}
+	if err := CountersignContext(context.Background(), st, s); err != nil {
+		t.Fatalf("CountersignContext: %v", err)
+	}
+	if err := VerifyTaskContext(context.Background(), st); err != nil {
+		t.Fatalf("VerifyTaskContext: %v", err)
+	}
+	if err := VerifyApproval(st); err != nil {
+		t.Fatalf("VerifyApproval: %v", err)
+	}
+	done, stop := context.WithCancel(context.Background())
+	stop()
+	if err := VerifyTaskContext(done, st); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancelled verification, got %v", err)
+	}
+	if _, err := SignTaskContext(context.Background(), task, badSigner{local}); err == nil {
+		t.Error("expected short signature to be refused")
+	}
+	if _, err := NewKeySigner(make(ed25519.PrivateKey, 3), k.pub); err == nil {
+		t.Error("expected bad key to fail")
+	}
+}
+
+type badSigner struct{ Signer }
+
+func (badSigner) Sign(context.Context, []byte) ([]byte, error) { return make([]byte, 10), nil }
@@ -0,0 +1,158 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultRestoreWindow is how long a deleted task stays restorable when
+// DeleteTask is given no window.
+const DefaultRestoreWindow = 30 * 24 * time.Hour
+
+// ErrRestoreWindowClosed is returned by RestoreTask for a task deleted
+// longer ago than its restore window.
+var ErrRestoreWindowClosed = errors.New("restore window has closed")
+
+// ErrNotPurgeable is returned by PurgeStore.Purge for a record that is not
+// deleted, or whose restore window is still open.
+var ErrNotPurgeable = errors.New("task is not deleted past its restore window")
+
+// Deletion marks a soft-deleted task record. The record stays in the store,
+// hidden from ListTasks, until PurgeAfter; until then RestoreTask brings it
+// back.
+type Deletion struct {
+	By         string    `json:"by"`
+	Reason     string    `json:"reason,omitempty"`
+	At         time.Time `json:"at"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// Purgeable reports whether rec is deleted and its restore window has
+// passed at now.
+func (r TaskRecord) Purgeable(now time.Time) bool {
+	return r.Deletion != nil && now.After(r.Deletion.PurgeAfter)
+}
+
+// PurgeStore is a TaskStore that can remove records outright. Records
+// leave it only through Purge, and only once soft-deleted past their
+// restore window; Watch does not report purges.
+type PurgeStore interface {
+	TaskStore
+	// Purge removes the record if it is Purgeable at now, and otherwise
+	// returns ErrNotPurgeable, or ErrTaskNotFound.
+	Purge(ctx context.Context, engagement, id string, now time.Time) error
+}
+
+// DeleteTask soft-deletes a terminal task: it is hidden from ListTasks and
+// can be restored until now+window, after which a janitor on a PurgeStore
+// removes it. window 0 means DefaultRestoreWindow. Live tasks must be
+// cancelled first. A "task.delete" record is appended to audit, if set.
+func DeleteTask(ctx context.Context, s TaskStore, audit *AuditLog, engagement, id, by, reason string, window time.Duration, now time.Time) error {
+	if by == "" {
+		return errors.New("deleted_by is required")
+	}
+	if window <= 0 {
+		window = DefaultRestoreWindow
+	}
+	d := &Deletion{By: by, Reason: reason, At: now, PurgeAfter: now.Add(window)}
+	err := s.Update(ctx, engagement, id, func(rec *TaskRecord) error {
+		switch {
+		case rec.Deletion != nil:
+			return fmt.Errorf("task %s is already deleted", id)
+		case !rec.Terminal():
+			return fmt.Errorf("task %s is %s; cancel it before deleting", id, rec.State)
+		}
+		rec.Deletion, rec.UpdatedAt = d, now
+		return nil
+	})
+	if err != nil || audit == nil {
+		return err
+	}
+	if _, err := audit.Append(engagement, "task.delete", by, id, d); err != nil {
+		return fmt.Errorf("audit delete of %s: %w", id, err)
+	}
+	return nil
+}
+
+// RestoreTask undoes DeleteTask while the task's restore window is open,
+// appending a "task.restore" record to audit, if set.
+func RestoreTask(ctx context.Context, s TaskStore, audit *AuditLog, engagement, id, by string, now time.Time) error {
+	if by == "" {
+		return errors.New("restored_by is required")
+	}
+	var d Deletion
+	err := s.Update(ctx, engagement, id, func(rec *TaskRecord) error {
+		switch {
+		case rec.Deletion == nil:
+			return fmt.Errorf("task %s is not deleted", id)
+		case rec.Purgeable(now):
+			return fmt.Errorf("%w: task %s could be restored until %s", ErrRestoreWindowClosed, id,
+				rec.Deletion.PurgeAfter.UTC().Format(time.RFC3339))
+		}
+		d = *rec.Deletion
+		rec.Deletion, rec.UpdatedAt = nil, now
+		return nil
+	})
+	if err != nil || audit == nil {
+		return err
+	}
+	if _, err := audit.Append(engagement, "task.restore", by, id, map[string]any{"deleted": d}); err != nil {
+		return fmt.Errorf("audit restore of %s: %w", id, err)
+	}
+	return nil
+}
+
+// ListTasks is s.List without soft-deleted records, as listings show by
+// default; includeDeleted keeps them.
+func ListTasks(ctx context.Context, s TaskStore, engagement string, includeDeleted bool) ([]TaskRecord, error) {
+	recs, err := s.List(ctx, engagement)
+	if err != nil || includeDeleted {
+		return recs, err
+	}
+	out := recs[:0]
+	for _, rec := range recs {
+		if rec.Deletion == nil {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+// Purge implements PurgeStore.
+func (s *MemoryStore) Purge(ctx context.Context, engagement, id string, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[engagement][id]
+	if !ok {
+		return fmt.Errorf("%w: %s/%s", ErrTaskNotFound, engagement, id)
+	}
+	if !rec.Purgeable(now) {
+		return fmt.Errorf("%w: %s/%s", ErrNotPurgeable, engagement, id)
+	}
+	delete(s.records[engagement], id)
+	return nil
+}
+
+// Purge implements PurgeStore, rolling back if the file cannot be
+// written.
+func (s *FileStore) Purge(ctx context.Context, engagement, id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.mem.Get(ctx, engagement, id)
+	if err != nil {
+		return err
+	}
+	if err := s.mem.Purge(ctx, engagement, id, now); err != nil {
+		return err
+	}
+	if err := s.save(); err != nil {
+		s.mem.replace(old)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeleteTask_RestoreWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Round(0)
+	s := NewMemoryStore()
+	audit := NewAuditLog("controller")
+	for id, state := range map[string]TaskState{"done": StateCompleted, "live": StateExecuting, "other": StateFailed} {
+		if err := s.Put(ctx, storedTask(t, id, now, state)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := DeleteTask(ctx, s, audit, "eng-2026-q1", "live", "lead-bob", "", 0, now); err == nil {
+		t.Error("deleted an executing task")
+	}
+	if err := DeleteTask(ctx, s, audit, "eng-2026-q1", "done", "lead-bob", "duplicate", time.Hour, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteTask(ctx, s, audit, "eng-2026-q1", "done", "lead-bob", "", time.Hour, now); err == nil {
+		t.Error("deleted a task twice")
+	}
+
+	recs, _ := ListTasks(ctx, s, "eng-2026-q1", false)
+	if len(recs) != 2 || recs[0].ID() != "live" || recs[1].ID() != "other" {
+		t.Errorf("default listing = %v", recs)
+	}
+	if all, _ := ListTasks(ctx, s, "eng-2026-q1", true); len(all) != 3 {
+		t.Errorf("listing with deleted = %d records", len(all))
+	}
+
+	if err := RestoreTask(ctx, s, audit, "eng-2026-q1", "other", "lead-bob", now); err == nil {
+		t.Error("restored a task that is not deleted")
+	}
+	if err := RestoreTask(ctx, s, audit, "eng-2026-q1", "done", "lead-bob", now.Add(2*time.Hour)); !errors.Is(err, ErrRestoreWindowClosed) {
+		t.Errorf("restore after the window: %v", err)
+	}
+	if err := RestoreTask(ctx, s, audit, "eng-2026-q1", "done", "lead-bob", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := s.Get(ctx, "eng-2026-q1", "done"); rec.Deletion != nil || rec.State != StateCompleted {
+		t.Errorf("restored record = %+v", rec)
+	}
+
+	var actions []string
+	for _, r := range audit.Records("eng-2026-q1") {
+		actions = append(actions, r.Action)
+	}
+	if len(actions) != 2 || actions[0] != "task.delete" || actions[1] != "task.restore" {
+		t.Errorf("audit actions = %v", actions)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Round(0)
+	fs, err := OpenFileStore(filepath.Join(t.TempDir(), "tasks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range map[string]PurgeStore{"memory": NewMemoryStore(), "file": fs} {
+		for _, id := range []string{"keep", "gone"} {
+			if err := s.Put(ctx, storedTask(t, id, now, StateCompleted)); err != nil {
+				t.Fatal(err)
+			}
+			if err := DeleteTask(ctx, s, nil, "eng-2026-q1", id, "lead-bob", "", time.Hour, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Purge(ctx, "eng-2026-q1", "gone", now); !errors.Is(err, ErrNotPurgeable) {
+			t.Errorf("%s: purge inside the window: %v", name, err)
+		}
+		_ = RestoreTask(ctx, s, nil, "eng-2026-q1", "keep", "lead-bob", now)
+
+		audit := NewAuditLog("janitor")
+		j := &Janitor{Store: s, Audit: audit, Now: func() time.Time { return now.Add(2 * time.Hour) }}
+		repairs, err := j.Sweep(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(repairs) != 1 || repairs[0].Kind != RepairPurge || repairs[0].TaskID != "gone" {
+			t.Errorf("%s: repairs = %+v", name, repairs)
+		}
+		if _, err := s.Get(ctx, "eng-2026-q1", "gone"); !errors.Is(err, ErrTaskNotFound) {
+			t.Errorf("%s: purged task still stored: %v", name, err)
+		}
+		if _, err := s.Get(ctx, "eng-2026-q1", "keep"); err != nil {
+			t.Errorf("%s: restored task purged: %v", name, err)
+		}
+		if recs := audit.Records("eng-2026-q1"); len(recs) != 1 || recs[0].Action != "janitor.purge" {
+			t.Errorf("%s: audit = %+v", name, recs)
+		}
+	}
+
+	reopened, err := OpenFileStore(fs.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recs, _ := reopened.List(ctx, ""); len(recs) != 1 || recs[0].ID() != "keep" {
+		t.Errorf("file after purge = %v", recs)
+	}
+}
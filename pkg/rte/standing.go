@@ -0,0 +1,242 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MaxStandingTTLSeconds bounds the TTL of a standing task, in place of the
+// hour ordinary tasks are held to.
+const MaxStandingTTLSeconds = 7 * 24 * 60 * 60
+
+// MaxAttestationSeconds bounds how long one attestation keeps a standing
+// task authorized.
+const MaxAttestationSeconds = maxTTLSeconds
+
+// DefaultStandingWindow is how long a standing run may go on before its
+// first attestation.
+const DefaultStandingWindow = 15 * time.Minute
+
+// ErrAttestationLapsed marks standing tasks stopped because no fresh
+// attestation arrived in time.
+var ErrAttestationLapsed = errors.New("standing task attestation lapsed")
+
+// StandingAttestation is the controller's periodic heartbeat for a running
+// standing task: the engagement is still authorized, and the task may go
+// on until IssuedAt plus ValidSeconds.
+type StandingAttestation struct {
+	Engagement   string    `json:"engagement"`
+	TaskID       string    `json:"task_id"`
+	AttestedBy   string    `json:"attested_by"`
+	ValidSeconds int       `json:"valid_seconds"`
+	IssuedAt     time.Time `json:"issued_at"`
+}
+
+// Expiry returns when the attestation stops authorizing the task.
+func (a StandingAttestation) Expiry() time.Time {
+	return a.IssuedAt.Add(time.Duration(a.ValidSeconds) * time.Second)
+}
+
+// SignedStandingAttestation wraps a StandingAttestation with the
+// attester's signature.
+type SignedStandingAttestation struct {
+	Attestation StandingAttestation `json:"attestation"`
+	PublicKey   []byte              `json:"public_key"`
+	Signature   []byte              `json:"signature"`
+}
+
+// SignStandingAttestation signs an attestation with the attester's key.
+func SignStandingAttestation(a StandingAttestation, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedStandingAttestation, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("marshal attestation: %w", err)
+	}
+	return &SignedStandingAttestation{Attestation: a, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// VerifyStandingAttestation verifies a signed attestation's signature and
+// required fields.
+func VerifyStandingAttestation(sa *SignedStandingAttestation) error {
+	if sa == nil {
+		return errors.New("signed attestation is nil")
+	}
+	if len(sa.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sa.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	if err := sa.Attestation.validate(); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(sa.Attestation)
+	if err != nil {
+		return fmt.Errorf("marshal attestation: %w", err)
+	}
+	if !ed25519.Verify(sa.PublicKey, payload, sa.Signature) {
+		return errors.New("attestation signature verification failed")
+	}
+	return nil
+}
+
+// VerifyStandingAttestation verifies the attestation and checks that its
+// attester is enrolled in the engagement with approver rights and signed
+// with their enrolled key.
+func (r *IdentityRegistry) VerifyStandingAttestation(sa *SignedStandingAttestation) error {
+	if err := VerifyStandingAttestation(sa); err != nil {
+		return err
+	}
+	a := sa.Attestation
+	id, ok := r.Lookup(a.Engagement, a.AttestedBy)
+	if !ok {
+		return fmt.Errorf("%s is not enrolled in %s", a.AttestedBy, a.Engagement)
+	}
+	if !id.CanApprove() {
+		return fmt.Errorf("%s does not hold approver rights", a.AttestedBy)
+	}
+	if !bytes.Equal(id.PublicKey, sa.PublicKey) {
+		return fmt.Errorf("attestation was not signed by %s", a.AttestedBy)
+	}
+	return nil
+}
+
+func (a StandingAttestation) validate() error {
+	if a.Engagement == "" || a.TaskID == "" {
+		return errors.New("engagement and task_id are required")
+	}
+	if a.AttestedBy == "" {
+		return errors.New("attested_by is required")
+	}
+	if a.ValidSeconds < minTTLSeconds || a.ValidSeconds > MaxAttestationSeconds {
+		return fmt.Errorf("valid_seconds must be between %d and %d, got %d", minTTLSeconds, MaxAttestationSeconds, a.ValidSeconds)
+	}
+	if a.IssuedAt.IsZero() {
+		return errors.New("issued_at is required")
+	}
+	return nil
+}
+
+// checkApproval verifies st's approval countersignature, if it carries
+// one, whatever Verify checked, and refuses a standing task or one with an
+// approval window unless a trusted approver countersigned it; an
+// ApprovedBy name or a key no one vouches for does not grant days of
+// runtime or of replay.
+func (e *Executor) checkApproval(st *SignedTask) error {
+	if st.Approval != nil {
+		if err := VerifyApproval(st); err != nil {
+			return fmt.Errorf("%w: %w", ErrBadSignature, err)
+		}
+	}
+	if !st.Task.Standing && st.Task.ApprovalExpiresAt == nil || e.approverKey(st) != nil {
+		return nil
+	}
+	if st.Task.Standing {
+		return fmt.Errorf("standing task %s has no approval countersignature from a pinned or enrolled approver", st.Task.ID)
+	}
+	return fmt.Errorf("%w: task %s has no approval countersignature from a pinned or enrolled approver", ErrBadApprovalExpiry, st.Task.ID)
+}
+
+// StandingPolicy limits which tasks may be standing: only the listed types,
+// approved by the listed leads. Other tasks pass.
+type StandingPolicy struct {
+	// Types may be standing; empty means simulate_beacon only.
+	Types []TaskType
+	// Approvers may approve standing tasks; empty means any approver.
+	Approvers []string
+}
+
+// Evaluate implements PolicyEvaluator.
+func (p *StandingPolicy) Evaluate(_ context.Context, task Task) (Decision, error) {
+	d := Decision{Allow: true}
+	if !task.Standing {
+		return d, nil
+	}
+	types := p.Types
+	if len(types) == 0 {
+		types = []TaskType{TaskSimulateBeacon}
+	}
+	if !slices.Contains(types, task.Type) {
+		d.deny(Violation{
+			Rule:        "standing.type",
+			Field:       "task.standing",
+			Source:      "rte.StandingPolicy",
+			Message:     fmt.Sprintf("%s tasks may not be standing", task.Type),
+			Remediation: fmt.Sprintf("clear standing and keep ttl_seconds within %d", CurrentValidatorConfig().MaxTTLSeconds),
+		})
+	}
+	if len(p.Approvers) > 0 && !slices.Contains(p.Approvers, task.ApprovedBy) {
+		d.deny(Violation{
+			Rule:        "standing.approver",
+			Field:       "task.approved_by",
+			Source:      "rte.StandingPolicy",
+			Message:     fmt.Sprintf("%s may not approve standing tasks", task.ApprovedBy),
+			Remediation: "have a lead permitted to approve standing tasks countersign it",
+		})
+	}
+	return d, nil
+}
+
+// Attest renews a running standing task's authorization until the
+// attestation expires. Each attestation must be issued after the last one
+// applied to the run, so a captured heartbeat cannot be replayed, and no
+// later than the validator's ClockSkew from now, so one cannot be issued
+// ahead to cover the whole TTL. Unless VerifyAttestation is set, it must be
+// signed by the task's trusted approver key; see Executor.Identities.
+func (e *Executor) Attest(sa *SignedStandingAttestation) (time.Time, error) {
+	verify := e.VerifyAttestation
+	if verify == nil {
+		verify = VerifyStandingAttestation
+	}
+	if err := verify(sa); err != nil {
+		return time.Time{}, fmt.Errorf("verify attestation: %w", err)
+	}
+	a := sa.Attestation
+	now := e.observeClock(Task{Engagement: a.Engagement, ID: a.TaskID})
+	expiry := a.Expiry()
+	if !expiry.After(now) {
+		return time.Time{}, fmt.Errorf("attestation expired at %s", expiry.UTC().Format(time.RFC3339))
+	}
+	if a.IssuedAt.After(now.Add(CurrentValidatorConfig().ClockSkew)) {
+		return time.Time{}, fmt.Errorf("attestation issued %s is in the future", a.IssuedAt.UTC().Format(time.RFC3339))
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r := e.findRun(a.Engagement, a.TaskID)
+	if r == nil {
+		return time.Time{}, fmt.Errorf("task %s is not running in %s", a.TaskID, a.Engagement)
+	}
+	if r.lapse == nil {
+		return time.Time{}, fmt.Errorf("task %s is not a standing task", a.TaskID)
+	}
+	if e.VerifyAttestation == nil && !bytes.Equal(sa.PublicKey, e.approverKey(r.signed)) {
+		return time.Time{}, errors.New("verify attestation: attestation was not signed by the task's approver key")
+	}
+	if !a.IssuedAt.After(r.attested) {
+		return time.Time{}, fmt.Errorf("attestation issued %s is not newer than the last", a.IssuedAt.UTC().Format(time.RFC3339))
+	}
+	if !r.lapse.Stop() {
+		return time.Time{}, fmt.Errorf("task %s: %w", a.TaskID, ErrAttestationLapsed)
+	}
+	r.lapse.Reset(expiry.Sub(now))
+	r.attested = a.IssuedAt
+	if e.Logger != nil {
+		TaskLogger(e.Logger, r.signed.Task).Info("standing task attested", "attested_by", a.AttestedBy,
+			"valid_until", expiry.UTC().Format(time.RFC3339))
+	}
+	return expiry, nil
+}
@@ -0,0 +1,140 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func standingTask(now time.Time) Task {
+	task := validTask(now)
+	task.Type = TaskSimulateBeacon
+	task.TTLSeconds = 3 * 24 * 60 * 60
+	task.Standing = true
+	return task
+}
+
+func TestTask_Validate_Standing(t *testing.T) {
+	now := time.Now().UTC()
+	task := standingTask(now)
+	if err := task.Validate(now); err != nil {
+		t.Fatalf("standing task: %v", err)
+	}
+	task.Standing = false
+	if err := task.Validate(now); !errors.Is(err, ErrBadTTL) {
+		t.Fatalf("three-day ordinary task: got %v", err)
+	}
+	task.Standing, task.TTLSeconds = true, MaxStandingTTLSeconds+1
+	if err := task.Validate(now); !errors.Is(err, ErrBadTTL) {
+		t.Fatalf("standing TTL over the cap: got %v", err)
+	}
+}
+
+func TestStandingPolicy(t *testing.T) {
+	ctx := context.Background()
+	p := &StandingPolicy{Approvers: []string{"lead-bob"}}
+	task := standingTask(time.Now())
+	if d, err := p.Evaluate(ctx, task); err != nil || !d.Allow {
+		t.Fatalf("standing beacon: %+v, %v", d, err)
+	}
+	task.Type, task.ApprovedBy = TaskSimulateLogin, "lead-carol"
+	d, _ := p.Evaluate(ctx, task)
+	if d.Allow || len(d.Violations) != 2 || d.Violations[0].Rule != "standing.type" {
+		t.Fatalf("standing login by another lead: %+v", d)
+	}
+	task.Standing = false
+	if d, _ := p.Evaluate(ctx, task); !d.Allow {
+		t.Fatalf("ordinary task denied: %+v", d)
+	}
+}
+
+func TestExecutor_Standing(t *testing.T) {
+	reg, op, lead := enrolled(t)
+	release := make(chan struct{})
+	e := NewExecutor()
+	e.Identities = reg
+	e.StandingWindow = 50 * time.Millisecond
+	_ = e.Register(TaskSimulateBeacon, HandlerFunc(func(ctx context.Context, _ Task) (any, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-release:
+			return nil, nil
+		}
+	}))
+	now := time.Now().UTC()
+
+	st, err := SignTask(standingTask(now), op.priv, op.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Execute(context.Background(), st); err == nil || !strings.Contains(err.Error(), "countersignature") {
+		t.Fatalf("standing task without approval: got %v", err)
+	}
+	forged := *st
+	forged.Approval = &Approval{PublicKey: lead.pub, Signature: make([]byte, 64)}
+	if _, err := e.Execute(context.Background(), &forged); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("standing task with a forged approval: got %v", err)
+	}
+	stranger := newKeyPair(t)
+	if _, err := e.Execute(context.Background(), signAndApprove(t, standingTask(now), op, stranger)); err == nil || !strings.Contains(err.Error(), "enrolled approver") {
+		t.Fatalf("standing task approved by an unenrolled key: got %v", err)
+	}
+
+	res, err := e.Execute(context.Background(), signAndApprove(t, standingTask(now), op, lead))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.State != StateCancelled || !strings.Contains(res.Error, ErrAttestationLapsed.Error()) {
+		t.Fatalf("unattested run: %+v", res)
+	}
+
+	attestWith := func(k keyPair, issued time.Time) *SignedStandingAttestation {
+		sa, err := SignStandingAttestation(StandingAttestation{
+			Engagement: "eng-2026-q1", TaskID: "task-001", AttestedBy: "lead-bob",
+			ValidSeconds: 60, IssuedAt: issued,
+		}, k.priv, k.pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sa
+	}
+	attest := func(issued time.Time) *SignedStandingAttestation { return attestWith(lead, issued) }
+	done := make(chan *TaskResult)
+	go func() {
+		res, _ := e.Execute(context.Background(), signAndApprove(t, standingTask(now), op, lead))
+		done <- res
+	}()
+	var first *SignedStandingAttestation
+	for deadline := time.Now().Add(time.Second); ; {
+		first = attest(time.Now().UTC())
+		if _, err := e.Attest(first); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Attest: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := e.Attest(first); err == nil {
+		t.Fatal("replayed attestation accepted")
+	}
+	if _, err := e.Attest(attest(time.Now().UTC().Add(-2 * time.Minute))); err == nil {
+		t.Fatal("expired attestation accepted")
+	}
+	if _, err := e.Attest(attest(time.Now().UTC().Add(30 * time.Second))); err == nil || !strings.Contains(err.Error(), "future") {
+		t.Fatalf("future-dated attestation: got %v", err)
+	}
+	if _, err := e.Attest(attestWith(newKeyPair(t), time.Now().UTC())); err == nil || !strings.Contains(err.Error(), "approver key") {
+		t.Fatalf("attestation by another key: got %v", err)
+	}
+	time.Sleep(2 * e.StandingWindow)
+	close(release)
+	if res := <-done; res.State != StateCompleted {
+		t.Fatalf("attested run: %+v", res)
+	}
+	if m := AttestMessage(first); !m.Control() || m.Validate() != nil {
+		t.Fatalf("attest message: %+v", m)
+	}
+}
@@ -0,0 +1,309 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrTaskNotFound is returned by a TaskStore for unknown tasks.
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrDuplicateTaskID is returned by TaskStore.Put for an ID already stored
+// in the engagement.
+var ErrDuplicateTaskID = errors.New("duplicate task ID")
+
+// ErrLeaseHeld is returned when acquiring a task another agent holds.
+var ErrLeaseHeld = errors.New("task is leased by another agent")
+
+// TaskRecord is the controller's record of one task: the signed task as
+// issued, its latest state, and the agent lease on it, if any.
+type TaskRecord struct {
+	Task      *SignedTask `json:"task"`
+	State     TaskState   `json:"state"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Lease     *Lease      `json:"lease,omitempty"`
+	// Result is the final result once the task is terminal.
+	Result *TaskResult `json:"result,omitempty"`
+	// Deletion is set while the task is soft-deleted (see DeleteTask).
+	Deletion *Deletion `json:"deletion,omitempty"`
+}
+
+// Engagement and ID return the record's key.
+func (r TaskRecord) Engagement() string { return r.Task.Task.Engagement }
+func (r TaskRecord) ID() string         { return r.Task.Task.ID }
+
+// Terminal reports whether the record's state is completed, cancelled, or
+// failed.
+func (r TaskRecord) Terminal() bool {
+	return len(transitions[r.State]) == 0
+}
+
+// Lease is an agent's claim on a task it has taken from the queue. The
+// agent renews it while the task runs; a lease left to expire marks the
+// agent as gone.
+type Lease struct {
+	Agent      string    `json:"agent"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the lease has lapsed at now.
+func (l *Lease) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// TaskStore persists task records. Implementations must be safe for
+// concurrent use, and Update must apply fn atomically with respect to
+// other updates of the same record.
+type TaskStore interface {
+	// Put stores a new record. A record with the same engagement and ID
+	// is ErrDuplicateTaskID; use Update to change a stored record.
+	Put(ctx context.Context, rec TaskRecord) error
+	// Get returns the record, or ErrTaskNotFound.
+	Get(ctx context.Context, engagement, id string) (TaskRecord, error)
+	// List returns the engagement's records sorted by ID, or every record
+	// when engagement is empty.
+	List(ctx context.Context, engagement string) ([]TaskRecord, error)
+	// Update applies fn to the record and stores the result unless fn
+	// returns an error, which Update returns.
+	Update(ctx context.Context, engagement, id string, fn func(*TaskRecord) error) error
+	// Watch reports each record f selects that is stored or changed after
+	// Watch returns, in the order the changes landed. The channel is closed
+	// when ctx ends, or early if the store cannot keep the watcher up to
+	// date; the caller then Lists and watches again.
+	Watch(ctx context.Context, f WatchFilter) (<-chan TaskEvent, error)
+}
+
+// MemoryStore is an in-memory TaskStore.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]map[string]TaskRecord
+	hub     watchHub
+}
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]map[string]TaskRecord)}
+}
+
+// Validate checks what every TaskStore requires of a record: a task with
+// an engagement and ID, and a known state.
+func (rec TaskRecord) Validate() error {
+	if rec.Task == nil {
+		return errors.New("task record has no task")
+	}
+	if rec.Engagement() == "" || rec.ID() == "" {
+		return errors.New("task record needs an engagement and ID")
+	}
+	if _, ok := validTaskStates[rec.State]; !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidState, rec.State)
+	}
+	return nil
+}
+
+// Put implements TaskStore.
+func (s *MemoryStore) Put(ctx context.Context, rec TaskRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := rec.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recs, ok := s.records[rec.Engagement()]
+	if !ok {
+		recs = make(map[string]TaskRecord)
+		s.records[rec.Engagement()] = recs
+	}
+	if _, ok := recs[rec.ID()]; ok {
+		return fmt.Errorf("%w: %s/%s", ErrDuplicateTaskID, rec.Engagement(), rec.ID())
+	}
+	recs[rec.ID()] = rec
+	s.hub.publish(EventCreated, rec)
+	return nil
+}
+
+// Get implements TaskStore.
+func (s *MemoryStore) Get(ctx context.Context, engagement, id string) (TaskRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return TaskRecord{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, ok := s.records[engagement][id]
+	if !ok {
+		return TaskRecord{}, fmt.Errorf("%w: %s/%s", ErrTaskNotFound, engagement, id)
+	}
+	return rec, nil
+}
+
+// List implements TaskStore.
+func (s *MemoryStore) List(ctx context.Context, engagement string) ([]TaskRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	var out []TaskRecord
+	for eng, recs := range s.records {
+		if engagement != "" && eng != engagement {
+			continue
+		}
+		for _, rec := range recs {
+			out = append(out, rec)
+		}
+	}
+	s.mu.RUnlock()
+	sortRecords(out)
+	return out, nil
+}
+
+func sortRecords(recs []TaskRecord) {
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Engagement() != recs[j].Engagement() {
+			return recs[i].Engagement() < recs[j].Engagement()
+		}
+		return recs[i].ID() < recs[j].ID()
+	})
+}
+
+// Update implements TaskStore.
+func (s *MemoryStore) Update(ctx context.Context, engagement, id string, fn func(*TaskRecord) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[engagement][id]
+	if !ok {
+		return fmt.Errorf("%w: %s/%s", ErrTaskNotFound, engagement, id)
+	}
+	if rec.Lease != nil {
+		l := *rec.Lease
+		rec.Lease = &l
+	}
+	if err := fn(&rec); err != nil {
+		return err
+	}
+	if rec.Engagement() != engagement || rec.ID() != id {
+		return errors.New("update must not change a record's key")
+	}
+	if err := rec.Validate(); err != nil {
+		return err
+	}
+	s.records[engagement][id] = rec
+	s.hub.publish(EventUpdated, rec)
+	return nil
+}
+
+// UpdateWhere implements BatchStore, under a single lock.
+func (s *MemoryStore) UpdateWhere(ctx context.Context, engagement string, match func(TaskRecord) bool, fn func(*TaskRecord) error) ([]TaskRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []TaskRecord
+	for eng, recs := range s.records {
+		if engagement != "" && eng != engagement {
+			continue
+		}
+		for id, rec := range recs {
+			if !match(rec) {
+				continue
+			}
+			if rec.Lease != nil {
+				l := *rec.Lease
+				rec.Lease = &l
+			}
+			if err := fn(&rec); err != nil {
+				return nil, err
+			}
+			if rec.Engagement() != eng || rec.ID() != id {
+				return nil, errors.New("update must not change a record's key")
+			}
+			if err := rec.Validate(); err != nil {
+				return nil, err
+			}
+			out = append(out, rec)
+		}
+	}
+	for _, rec := range out {
+		s.records[rec.Engagement()][rec.ID()] = rec
+	}
+	sortRecords(out)
+	s.hub.publish(EventUpdated, out...)
+	return out, nil
+}
+
+// Watch implements TaskStore. A watcher more than WatchBuffer events
+// behind is closed rather than let it hold up writes.
+func (s *MemoryStore) Watch(ctx context.Context, f WatchFilter) (<-chan TaskEvent, error) {
+	return s.hub.watch(ctx, f)
+}
+
+// Snapshot returns every record as of one instant; it is List("") under a
+// single lock.
+func (s *MemoryStore) Snapshot(ctx context.Context) ([]TaskRecord, error) {
+	return s.List(ctx, "")
+}
+
+// remove and replace undo a change; FileStore uses them when a write fails.
+func (s *MemoryStore) remove(engagement, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records[engagement], id)
+}
+
+func (s *MemoryStore) replace(rec TaskRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.Engagement()][rec.ID()] = rec
+}
+
+// AcquireLease claims a pending task for agent until now+ttl. An agent may
+// re-acquire its own lease; another agent's live lease is ErrLeaseHeld.
+func AcquireLease(ctx context.Context, s TaskStore, engagement, id, agent string, ttl time.Duration, now time.Time) (*Lease, error) {
+	if agent == "" {
+		return nil, errors.New("agent is required")
+	}
+	var lease *Lease
+	err := s.Update(ctx, engagement, id, func(rec *TaskRecord) error {
+		if rec.State != StatePending {
+			return fmt.Errorf("task %s is %s, not pending", id, rec.State)
+		}
+		if l := rec.Lease; l != nil && l.Agent != agent && !l.Expired(now) {
+			return fmt.Errorf("%w: %s until %s", ErrLeaseHeld, l.Agent, l.ExpiresAt.UTC().Format(time.RFC3339))
+		}
+		lease = &Lease{Agent: agent, AcquiredAt: now, ExpiresAt: now.Add(ttl)}
+		rec.Lease, rec.UpdatedAt = lease, now
+		return nil
+	})
+	return lease, err
+}
+
+// RenewLease extends agent's lease on a task to now+ttl.
+func RenewLease(ctx context.Context, s TaskStore, engagement, id, agent string, ttl time.Duration, now time.Time) error {
+	return s.Update(ctx, engagement, id, func(rec *TaskRecord) error {
+		if rec.Lease == nil || rec.Lease.Agent != agent {
+			return fmt.Errorf("task %s is not leased by %s", id, agent)
+		}
+		rec.Lease.ExpiresAt = now.Add(ttl)
+		return nil
+	})
+}
+
+// ReleaseLease drops agent's lease on a task, typically with its result.
+func ReleaseLease(ctx context.Context, s TaskStore, engagement, id, agent string, now time.Time) error {
+	return s.Update(ctx, engagement, id, func(rec *TaskRecord) error {
+		if rec.Lease == nil || rec.Lease.Agent != agent {
+			return fmt.Errorf("task %s is not leased by %s", id, agent)
+		}
+		rec.Lease, rec.UpdatedAt = nil, now
+		return nil
+	})
+}
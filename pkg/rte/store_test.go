@@ -0,0 +1,88 @@
+package rte
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// storedTask returns a record for validTask with the given ID, created at
+// created. SignTask refuses expired tasks, so an expired task is backdated
+// after signing and its signature no longer verifies; stores do not check.
+func storedTask(t *testing.T, id string, created time.Time, state TaskState) TaskRecord {
+	t.Helper()
+	kp := newKeyPair(t)
+	task := validTask(created)
+	task.ID = id
+	expired := time.Now().After(task.Expiry())
+	if expired {
+		task.CreatedAt = time.Now().UTC()
+	}
+	st, err := SignTask(task, kp.priv, kp.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired {
+		st.Task.CreatedAt = created
+	}
+	return TaskRecord{Task: st, State: state, UpdatedAt: created}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Now().UTC()
+	for _, id := range []string{"t-2", "t-1"} {
+		if err := s.Put(ctx, storedTask(t, id, now, StatePending)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Put(ctx, storedTask(t, "t-1", now, StateCompleted)); !errors.Is(err, ErrDuplicateTaskID) {
+		t.Errorf("Put duplicate: %v", err)
+	}
+	if err := s.Put(ctx, TaskRecord{}); err == nil {
+		t.Error("Put accepted a record without a task")
+	}
+	if _, err := s.Get(ctx, "eng-2026-q1", "nope"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Get unknown: %v", err)
+	}
+	recs, _ := s.List(ctx, "eng-2026-q1")
+	if len(recs) != 2 || recs[0].ID() != "t-1" {
+		t.Fatalf("List = %+v", recs)
+	}
+	if recs, _ := s.List(ctx, "other"); len(recs) != 0 {
+		t.Errorf("List other engagement = %+v", recs)
+	}
+
+	lease, err := AcquireLease(ctx, s, "eng-2026-q1", "t-1", "agent-a", time.Minute, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AcquireLease(ctx, s, "eng-2026-q1", "t-1", "agent-b", time.Minute, now); !errors.Is(err, ErrLeaseHeld) {
+		t.Errorf("second agent: %v", err)
+	}
+	if _, err := AcquireLease(ctx, s, "eng-2026-q1", "t-1", "agent-b", time.Minute, lease.ExpiresAt); err != nil {
+		t.Errorf("expired lease should be takeable: %v", err)
+	}
+	if err := RenewLease(ctx, s, "eng-2026-q1", "t-1", "agent-a", time.Minute, now); err == nil {
+		t.Error("renewed a lease taken over by another agent")
+	}
+	if err := ReleaseLease(ctx, s, "eng-2026-q1", "t-1", "agent-b", now); err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := s.Get(ctx, "eng-2026-q1", "t-1"); rec.Lease != nil {
+		t.Errorf("lease after release = %+v", rec.Lease)
+	}
+
+	boom := errors.New("boom")
+	if err := s.Update(ctx, "eng-2026-q1", "t-2", func(r *TaskRecord) error {
+		r.State = StateFailed
+		return boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("Update: %v", err)
+	}
+	if rec, _ := s.Get(ctx, "eng-2026-q1", "t-2"); rec.State != StatePending {
+		t.Errorf("failed Update was stored: %s", rec.State)
+	}
+}
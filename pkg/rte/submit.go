@@ -0,0 +1,233 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrIdempotencyConflict is returned by Submitter.Submit for a task whose
+// IdempotencyKey the engagement already used for a different task.
+var ErrIdempotencyConflict = errors.New("idempotency key already used for a different task")
+
+// maxIdempotencyKeyLen bounds Task.IdempotencyKey.
+const maxIdempotencyKeyLen = 128
+
+func validIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLen {
+		return fmt.Errorf("%d bytes, over the limit of %d", len(key), maxIdempotencyKeyLen)
+	}
+	for _, r := range key {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return fmt.Errorf("%q contains whitespace or a control character", key)
+		}
+	}
+	return nil
+}
+
+// Submitter accepts signed tasks into the controller: it verifies each,
+// stores it as pending, appends a "task.submit" audit record, and queues
+// it. Transports call Submit, so a task retried over HTTP or gRPC is
+// deduplicated the same way.
+//
+// A submission is a duplicate of a stored task if it is the same signed
+// task, or if it carries the stored task's IdempotencyKey and the same
+// type and params. A duplicate is not stored again. A submission that
+// failed after storing its task is finished by the retry: the audit record
+// is appended if the log lacks it, and a task still pending but no longer
+// queued is queued again.
+type Submitter struct {
+	Store TaskStore
+	// Queue, if set, receives new tasks.
+	Queue *Queue
+	Audit *AuditLog
+	// Verify defaults to VerifyTaskContext.
+	Verify func(context.Context, *SignedTask) error
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	// mu serializes submissions, so two retries of one task racing each
+	// other cannot both miss the other's record.
+	mu sync.Mutex
+}
+
+// Submit accepts st, returning its record and whether it duplicated one
+// already stored. A task reusing a stored task's ID is ErrDuplicateTaskID
+// and one reusing its IdempotencyKey is ErrIdempotencyConflict; one that
+// fails verification or CheckLineage is refused with a *DenialError.
+func (s *Submitter) Submit(ctx context.Context, st *SignedTask) (TaskRecord, bool, error) {
+	verify := VerifyTaskContext
+	if s.Verify != nil {
+		verify = s.Verify
+	}
+	if err := verify(ctx, st); err != nil {
+		if de := Deny(st.Task.ID, err); de != nil {
+			return TaskRecord{}, false, de
+		}
+		return TaskRecord{}, false, err
+	}
+	if st.Task.State != StatePending {
+		err := fmt.Errorf("%w: tasks are submitted %s, not %s", ErrInvalidState, StatePending, st.Task.State)
+		return TaskRecord{}, false, Deny(st.Task.ID, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := st.Task
+	if rec, ok, err := s.duplicate(ctx, st); err != nil || ok {
+		if err == nil && !s.audited(rec) {
+			err = s.audit(rec)
+		}
+		if err == nil && rec.State == StatePending && s.Queue != nil && !queued(s.Queue, rec) {
+			err = s.Queue.Push(TaskMessage(rec.Task))
+		}
+		return rec, ok, err
+	}
+	if err := CheckLineage(ctx, s.Store, t); err != nil {
+		if errors.Is(err, ErrBadLineage) {
+			err = Deny(t.ID, err)
+		}
+		return TaskRecord{}, false, err
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	rec := TaskRecord{Task: st, State: StatePending, UpdatedAt: now().UTC()}
+	if err := s.Store.Put(ctx, rec); err != nil {
+		return TaskRecord{}, false, err
+	}
+	if err := s.audit(rec); err != nil {
+		return rec, false, err
+	}
+	if s.Queue != nil {
+		if err := s.Queue.Push(TaskMessage(st)); err != nil {
+			return rec, false, fmt.Errorf("queue %s: %w", t.ID, err)
+		}
+	}
+	return rec, false, nil
+}
+
+// audit appends rec's "task.submit" record.
+func (s *Submitter) audit(rec TaskRecord) error {
+	if s.Audit == nil {
+		return nil
+	}
+	t := rec.Task.Task
+	detail := map[string]any{"type": t.Type, "idempotency_key": t.IdempotencyKey}
+	if _, err := s.Audit.Append(t.Engagement, "task.submit", t.Operator, t.ID, detail); err != nil {
+		return fmt.Errorf("audit submit of %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+// audited reports whether the audit log already holds rec's "task.submit"
+// record, or keeps none.
+func (s *Submitter) audited(rec TaskRecord) bool {
+	if s.Audit == nil {
+		return true
+	}
+	for _, r := range s.Audit.Records(rec.Engagement()) {
+		if r.Action == "task.submit" && r.TaskID != nil && *r.TaskID == rec.ID() {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicate finds the stored record st duplicates, if any.
+func (s *Submitter) duplicate(ctx context.Context, st *SignedTask) (TaskRecord, bool, error) {
+	t := st.Task
+	rec, err := s.Store.Get(ctx, t.Engagement, t.ID)
+	switch {
+	case err == nil && bytes.Equal(rec.Task.Signature, st.Signature):
+		return rec, true, nil
+	case err == nil:
+		return TaskRecord{}, false, fmt.Errorf("%w: %s/%s", ErrDuplicateTaskID, t.Engagement, t.ID)
+	case !errors.Is(err, ErrTaskNotFound):
+		return TaskRecord{}, false, err
+	}
+	if t.IdempotencyKey == "" {
+		return TaskRecord{}, false, nil
+	}
+	recs, err := s.Store.List(ctx, t.Engagement)
+	if err != nil {
+		return TaskRecord{}, false, err
+	}
+	for _, rec := range recs {
+		prev := rec.Task.Task
+		if prev.IdempotencyKey != t.IdempotencyKey {
+			continue
+		}
+		if prev.Type != t.Type || !maps.Equal(prev.Params, t.Params) {
+			return TaskRecord{}, false, fmt.Errorf("%w: %s is task %s", ErrIdempotencyConflict, t.IdempotencyKey, prev.ID)
+		}
+		return rec, true, nil
+	}
+	return TaskRecord{}, false, nil
+}
+
+// queued reports whether q holds rec's task.
+func queued(q *Queue, rec TaskRecord) bool {
+	for _, m := range q.Snapshot() {
+		if m.Kind == MessageTask && m.Task.Task.Engagement == rec.Engagement() && m.Task.Task.ID == rec.ID() {
+			return true
+		}
+	}
+	return false
+}
+
+// SubmitResponse answers a submission with the ID of the task that will
+// run, which for a duplicate is the stored task's.
+type SubmitResponse struct {
+	TaskID    string `json:"task_id"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+}
+
+// ServeHTTP serves a POSTed SignedTask, as engagement.HTTPController
+// sends to /v1/engagements/{engagement}/tasks, answering 201 with a
+// SubmitResponse, or 200 if the task was a duplicate. Reused IDs and
+// idempotency keys are 409, and tasks failing verification are 403 with
+// a DenialError (see WriteDenial). Authenticate callers before it.
+func (s *Submitter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxSignedTaskBytes+1))
+	if err != nil {
+		http.Error(w, "malformed signed task", http.StatusBadRequest)
+		return
+	}
+	st, err := ParseSignedTask(body)
+	if err != nil {
+		http.Error(w, "malformed signed task: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	rec, dup, err := s.Submit(r.Context(), st)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrDuplicateTaskID), errors.Is(err, ErrIdempotencyConflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case WriteDenial(w, st.Task.ID, err):
+		default:
+			// Not stored, or stored but not queued or audited: either
+			// way the client should retry.
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !dup {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(SubmitResponse{TaskID: rec.ID(), Duplicate: dup})
+}
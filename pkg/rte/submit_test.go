@@ -0,0 +1,126 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubmitter_Submit(t *testing.T) {
+	ctx := context.Background()
+	kp := newKeyPair(t)
+	sign := func(id, key, target string) *SignedTask {
+		t.Helper()
+		task := validTask(time.Now().UTC())
+		task.ID, task.IdempotencyKey = id, key
+		task.Params = map[string]string{"target": target}
+		st, err := SignTask(task, kp.priv, kp.pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	q := NewQueue()
+	s := &Submitter{Store: NewMemoryStore(), Queue: q, Audit: NewAuditLog("controller")}
+
+	first := sign("t-1", "run-42", "10.0.0.1")
+	if _, dup, err := s.Submit(ctx, first); err != nil || dup {
+		t.Fatalf("first submit: dup %v, %v", dup, err)
+	}
+	// The same bytes again, and the same work re-signed under a new ID.
+	for _, st := range []*SignedTask{first, sign("t-2", "run-42", "10.0.0.1")} {
+		rec, dup, err := s.Submit(ctx, st)
+		if err != nil || !dup || rec.ID() != "t-1" {
+			t.Fatalf("retry of %s: %s, dup %v, %v", st.Task.ID, rec.ID(), dup, err)
+		}
+	}
+	if q.Len() != 1 || len(s.Audit.Records("eng-2026-q1")) != 1 {
+		t.Fatalf("queue %d, audit %d after retries", q.Len(), len(s.Audit.Records("eng-2026-q1")))
+	}
+
+	if _, _, err := s.Submit(ctx, sign("t-3", "run-42", "10.0.0.2")); !errors.Is(err, ErrIdempotencyConflict) {
+		t.Errorf("reused key for other params: %v", err)
+	}
+	if _, _, err := s.Submit(ctx, sign("t-1", "", "10.0.0.1")); !errors.Is(err, ErrDuplicateTaskID) {
+		t.Errorf("reused ID: %v", err)
+	}
+	if _, dup, err := s.Submit(ctx, sign("t-4", "", "10.0.0.1")); err != nil || dup {
+		t.Errorf("keyless task: dup %v, %v", dup, err)
+	}
+
+	// A retry of a task stored but lost from the queue queues it again.
+	q.Remove(func(Message) bool { return true })
+	if _, _, err := s.Submit(ctx, first); err != nil || q.Len() != 1 {
+		t.Errorf("requeue: %d queued, %v", q.Len(), err)
+	}
+
+	// So does one stored and queued but never audited.
+	s.Audit = nil
+	unaudited := sign("t-6", "", "10.0.0.3")
+	if _, _, err := s.Submit(ctx, unaudited); err != nil {
+		t.Fatal(err)
+	}
+	s.Audit = NewAuditLog("controller")
+	if _, dup, err := s.Submit(ctx, unaudited); err != nil || !dup {
+		t.Fatalf("retry of unaudited task: dup %v, %v", dup, err)
+	}
+	if _, _, err := s.Submit(ctx, unaudited); err != nil {
+		t.Fatal(err)
+	}
+	if recs := s.Audit.Records("eng-2026-q1"); len(recs) != 1 || recs[0].Action != "task.submit" || *recs[0].TaskID != "t-6" {
+		t.Fatalf("audit after retries = %+v, want one task.submit for t-6", recs)
+	}
+
+	bad := sign("t-5", "", "10.0.0.1")
+	bad.Task.Params["target"] = "10.0.0.9"
+	var de *DenialError
+	if _, _, err := s.Submit(ctx, bad); !errors.As(err, &de) || de.Denials[0].Code != DenialSignature {
+		t.Errorf("tampered task: %v", err)
+	}
+}
+
+func TestSubmitter_ServeHTTP(t *testing.T) {
+	kp := newKeyPair(t)
+	task := validTask(time.Now().UTC())
+	task.IdempotencyKey = "run-42"
+	st, err := SignTask(task, kp.priv, kp.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(st)
+	h := &Submitter{Store: NewMemoryStore(), Queue: NewQueue()}
+	for _, want := range []SubmitResponse{{TaskID: "task-001"}, {TaskID: "task-001", Duplicate: true}} {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/engagements/eng-2026-q1/tasks", bytes.NewReader(body)))
+		code := http.StatusCreated
+		if want.Duplicate {
+			code = http.StatusOK
+		}
+		var got SubmitResponse
+		if err := json.NewDecoder(rr.Body).Decode(&got); err != nil || rr.Code != code || got != want {
+			t.Errorf("status %d, %+v (%v); want %d, %+v", rr.Code, got, err, code, want)
+		}
+	}
+
+	task.ID, task.Params = "task-002", map[string]string{"target": "10.0.0.9"}
+	other, err := SignTask(task, kp.priv, kp.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = json.Marshal(other)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/engagements/eng-2026-q1/tasks", bytes.NewReader(body)))
+	if rr.Code != http.StatusConflict {
+		t.Errorf("reused key: status %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/engagements/eng-2026-q1/tasks", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d", rr.Code)
+	}
+}
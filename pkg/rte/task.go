@@ -1,69 +1,161 @@
 package rte
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/attack"
 )
 
 // TaskType represents the kind of task in the red team engagement.
 type TaskType string
 
 const (
-	TaskSimulateLogin  TaskType = "simulate_login"
-	TaskSimulateBeacon TaskType = "simulate_beacon"
-	TaskInventory     TaskType = "inventory"
-	TaskEmitSynthetic  TaskType = "emit_synthetic"
+	TaskSimulateLogin           TaskType = "simulate_login"
+	TaskSimulateBeacon          TaskType = "simulate_beacon"
+	TaskInventory               TaskType = "inventory"
+	TaskEmitSynthetic           TaskType = "emit_synthetic"
+	TaskSimulatePhish           TaskType = "simulate_phish"
+	TaskSimulateCredentialSpray TaskType = "simulate_credential_spray"
+	TaskSimulateExfil           TaskType = "simulate_exfil"
 )
 
 // TaskState represents the lifecycle state of a task.
 type TaskState string
 
 const (
-	StatePending    TaskState = "pending"
-	StateExecuting  TaskState = "executing"
-	StateCancelled  TaskState = "cancelled"
-	StateCompleted  TaskState = "completed"
-	StateFailed     TaskState = "failed"
+	StatePending   TaskState = "pending"
+	StateExecuting TaskState = "executing"
+	StatePaused    TaskState = "paused"
+	StateCancelled TaskState = "cancelled"
+	StateCompleted TaskState = "completed"
+	StateFailed    TaskState = "failed"
 )
 
 const (
 	maxTTLSeconds = 3600
 	minTTLSeconds = 1
+
+	// MaxPriority is the highest task priority; 0 is the lowest and default.
+	MaxPriority = 9
 )
 
 var (
 	allowedTaskTypes = map[TaskType]struct{}{
-		TaskSimulateLogin:  {},
-		TaskSimulateBeacon: {},
-		TaskInventory:     {},
-		TaskEmitSynthetic: {},
+		TaskSimulateLogin:           {},
+		TaskSimulateBeacon:          {},
+		TaskInventory:               {},
+		TaskEmitSynthetic:           {},
+		TaskSimulatePhish:           {},
+		TaskSimulateCredentialSpray: {},
+		TaskSimulateExfil:           {},
 	}
 
 	validTaskStates = map[TaskState]struct{}{
 		StatePending:   {},
 		StateExecuting: {},
+		StatePaused:    {},
 		StateCancelled: {},
 		StateCompleted: {},
 		StateFailed:    {},
 	}
 )
 
+// transitions lists the states each state may move to. Completed,
+// cancelled, and failed are terminal.
+var transitions = map[TaskState][]TaskState{
+	StatePending:   {StateExecuting, StateCancelled, StateFailed},
+	StateExecuting: {StatePaused, StateCompleted, StateCancelled, StateFailed},
+	StatePaused:    {StateExecuting, StateCancelled, StateFailed},
+}
+
+// ValidTransition reports whether a task may move from one state to another.
+// A paused task must resume before it can complete.
+func ValidTransition(from, to TaskState) error {
+	if _, ok := validTaskStates[from]; !ok {
+		return fmt.Errorf("invalid task state: %s", from)
+	}
+	for _, s := range transitions[from] {
+		if s == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("task cannot move from %s to %s", from, to)
+}
+
 // Task represents a typed red team task with attribution and lifecycle metadata.
 type Task struct {
-	ID          string            `json:"id"`
-	Engagement  string            `json:"engagement"`
-	Type        TaskType          `json:"type"`
-	CreatedAt   time.Time         `json:"created_at"`
-	TTLSeconds  int               `json:"ttl_seconds"`
-	Operator    string            `json:"operator"`
-	ApprovedBy  string            `json:"approved_by"`
-	State       TaskState         `json:"state"`
-	CancelToken string            `json:"cancel_token,omitempty"`
-	Params      map[string]string `json:"params,omitempty"`
+	// SchemaVersion is the Task schema the task was signed under; see
+	// TaskSchemaVersion.
+	SchemaVersion int               `json:"schema_version,omitempty"`
+	ID            string            `json:"id"`
+	Engagement    string            `json:"engagement"`
+	Type          TaskType          `json:"type"`
+	CreatedAt     time.Time         `json:"created_at"`
+	TTLSeconds    int               `json:"ttl_seconds"`
+	Operator      string            `json:"operator"`
+	ApprovedBy    string            `json:"approved_by"`
+	State         TaskState         `json:"state"`
+	CancelToken   string            `json:"cancel_token,omitempty"`
+	Params        map[string]string `json:"params,omitempty"`
+	// Priority orders queued tasks, 0 (default) to MaxPriority.
+	Priority int `json:"priority,omitempty"`
+	// Techniques are the ATT&CK technique IDs the task exercises.
+	Techniques []string `json:"techniques,omitempty"`
+	// ExpectedDetections are the alerts the blue team should raise for this
+	// task; they drive detection-latency measurement.
+	ExpectedDetections []ExpectedDetection `json:"expected_detections,omitempty"`
+	// Provenance is the signed Git commit the task was declared in, when it
+	// was applied from an engagement-as-code repository.
+	Provenance *Provenance `json:"provenance,omitempty"`
+	// NotBefore, if set, is when the task becomes valid; it must fall
+	// before the task expires. Tasks without it are valid from CreatedAt.
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	// Classification, if set, is the task's level where it differs from
+	// its engagement's. Deliverables below it leave the task out.
+	Classification Classification `json:"classification,omitempty"`
+	// Selector, if set, limits the task to agents whose labels it
+	// matches, such as "os=windows,zone=dmz"; see ParseSelector.
+	Selector string `json:"selector,omitempty"`
+	// DryRun asks the agent to describe what the task would do, as a
+	// signed DryRunPlan, instead of doing it. The handler must implement
+	// Planner.
+	DryRun bool `json:"dry_run,omitempty"`
+	// IdempotencyKey, if set, names the submission: the controller runs
+	// at most one task per key in an engagement, so a client retrying a
+	// submission, even re-signed under a new ID, cannot queue it twice.
+	// See Submitter.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// ReissuedFromID, if set, is the task this one retries unchanged in
+	// purpose, and SupersedesID the task this one replaces with a revised
+	// plan. Either must name a terminal task in the engagement; see
+	// CheckLineage and Lineage.
+	ReissuedFromID string `json:"reissued_from_id,omitempty"`
+	SupersedesID   string `json:"supersedes_id,omitempty"`
+	// Phase, if set, is the engagement phase the task belongs to; see
+	// PhasePolicy.
+	Phase Phase `json:"phase,omitempty"`
+	// CleanupOf, if set, makes this a cleanup task: it undoes what the
+	// named task left behind, and runs through its handler's Cleaner. The
+	// executor that ran that task issues it; see AddCleanup.
+	CleanupOf string `json:"cleanup_of,omitempty"`
+	// ApprovalExpiresAt, if set, ends the approver's sign-off separately
+	// from the TTL: the task may start at any time until then, and
+	// TTLSeconds bounds each run from its start instead of from
+	// CreatedAt. It may be at most MaxApprovalSeconds after CreatedAt, and
+	// an executor runs the task only with an approval countersignature
+	// from a trusted approver; see Executor.Identities.
+	ApprovalExpiresAt *time.Time `json:"approval_expires_at,omitempty"`
+	// Standing marks a standing authorization, such as a multi-day beacon
+	// simulation: TTLSeconds may reach MaxStandingTTLSeconds, but the
+	// task needs an approval countersignature and runs only while the
+	// controller keeps attesting it; see StandingAttestation and
+	// StandingPolicy.
+	Standing bool `json:"standing,omitempty"`
 }
 
 // SignedTask wraps a Task with cryptographic attestation.
@@ -71,68 +163,170 @@ type SignedTask struct {
 	Task      Task   `json:"task"`
 	PublicKey []byte `json:"public_key"`
 	Signature []byte `json:"signature"`
+	// Certificates, if set, is the X.509 chain certifying PublicKey, leaf
+	// first, DER encoded (see NewCertSigner and CertPolicy).
+	Certificates [][]byte  `json:"certificates,omitempty"`
+	Approval     *Approval `json:"approval,omitempty"`
+	// Legacy holds the task as originally signed when Task was upgraded
+	// from an older schema by UpgradeSignedTask.
+	Legacy *LegacyTask `json:"legacy,omitempty"`
+	// Digest is set for detached signatures (see SignTaskDetached): the
+	// SHA-256 of the task's canonical JSON, which the signature covers.
+	Digest []byte `json:"digest,omitempty"`
+	// Timestamp is an RFC 3161 token from a timestamping authority over
+	// the SHA-256 of Signature (see TimestampTask). It is not part of the
+	// signed payload; the TSA's own signature protects it.
+	Timestamp []byte `json:"timestamp,omitempty"`
+	// Attestations are in-toto statements about the task, such as SLSA
+	// provenance for the template it was built from (see AttestTask).
+	Attestations []Envelope `json:"attestations,omitempty"`
+	// Trace carries W3C trace context between controller and agents. It is
+	// metadata, not part of the signed payload, so each hop may rewrite it.
+	Trace map[string]string `json:"trace,omitempty"`
 }
 
-// Validate checks that the task meets RTE-A invariants (R1, R2).
-// now is typically time.Now() for runtime validation.
+// Validate checks that the task meets RTE-A invariants (R1, R2), within
+// the bounds of CurrentValidatorConfig. now is typically time.Now() for
+// runtime validation. Times are compared with the config's ClockSkew of
+// tolerance either way.
 func (t *Task) Validate(now time.Time) error {
-	if t == nil {
-		return errors.New("task is nil")
-	}
+	return t.ValidateWith(now, CurrentValidatorConfig())
+}
+
+// validateUntil is Validate against an explicit, possibly extended, expiry
+// and bounds with defaults filled in. It checks every field and reports
+// all problems together.
+func (t *Task) validateUntil(now, expiry time.Time, c ValidatorConfig) error {
+	var errs ValidationErrors
 	if t.ID == "" {
-		return errors.New("task ID is required")
+		errs = append(errs, ErrMissingID)
 	}
 	if t.Engagement == "" {
-		return errors.New("engagement is required")
+		errs = append(errs, ErrMissingEngagement)
 	}
 	if t.Operator == "" {
-		return errors.New("operator is required")
+		errs = append(errs, ErrMissingOperator)
 	}
 	if t.ApprovedBy == "" {
-		return errors.New("approved_by is required")
+		errs = append(errs, ErrMissingApprover)
 	}
 	if _, ok := allowedTaskTypes[t.Type]; !ok {
-		return fmt.Errorf("unsupported task type: %s", t.Type)
+		errs = append(errs, fmt.Errorf("%w: %s", ErrUnsupportedType, t.Type))
 	}
-	if t.TTLSeconds < minTTLSeconds || t.TTLSeconds > maxTTLSeconds {
-		return fmt.Errorf("TTLSeconds must be between %d and %d, got %d", minTTLSeconds, maxTTLSeconds, t.TTLSeconds)
+	maxTTL := c.MaxTTLSeconds
+	if t.Standing {
+		maxTTL = c.MaxStandingTTLSeconds
+	}
+	if t.TTLSeconds < c.MinTTLSeconds || t.TTLSeconds > maxTTL {
+		errs = append(errs, fmt.Errorf("%w: TTLSeconds must be between %d and %d, got %d", ErrBadTTL, c.MinTTLSeconds, maxTTL, t.TTLSeconds))
 	}
 	if _, ok := validTaskStates[t.State]; !ok {
-		return fmt.Errorf("invalid task state: %s", t.State)
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidState, t.State))
 	}
-	expiry := t.CreatedAt.Add(time.Duration(t.TTLSeconds) * time.Second)
-	if now.After(expiry) || now.Equal(expiry) {
-		return fmt.Errorf("task expired at %s (now: %s)", expiry.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	if t.Priority < 0 || t.Priority > MaxPriority {
+		errs = append(errs, fmt.Errorf("%w: must be between 0 and %d, got %d", ErrBadPriority, MaxPriority, t.Priority))
 	}
-	return nil
+	for _, id := range t.Techniques {
+		if err := attack.Validate(id); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrBadTechnique, err))
+		}
+	}
+	for _, d := range t.ExpectedDetections {
+		if d.Technique == "" {
+			continue
+		}
+		if err := attack.Validate(d.Technique); err != nil {
+			errs = append(errs, fmt.Errorf("%w: expected detection %s: %w", ErrBadTechnique, d.Rule, err))
+		}
+	}
+	if err := t.Classification.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrBadClassification, err))
+	}
+	if _, err := ParseSelector(t.Selector); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrBadSelector, err))
+	}
+	if err := validIdempotencyKey(t.IdempotencyKey); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrBadIdempotencyKey, err))
+	}
+	if err := t.Phase.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrBadPhase, err))
+	}
+	switch {
+	case t.ReissuedFromID != "" && t.SupersedesID != "":
+		errs = append(errs, fmt.Errorf("%w: a task either re-issues or supersedes another, not both", ErrBadLineage))
+	case t.ID != "" && (t.ReissuedFromID == t.ID || t.SupersedesID == t.ID || t.CleanupOf == t.ID):
+		errs = append(errs, fmt.Errorf("%w: task %s names itself", ErrBadLineage, t.ID))
+	}
+	if a := t.ApprovalExpiresAt; a != nil {
+		if !a.After(t.CreatedAt) || a.Sub(t.CreatedAt) > time.Duration(c.MaxApprovalSeconds)*time.Second {
+			errs = append(errs, fmt.Errorf("%w: approval_expires_at must be within %ds after created_at, got %s", ErrBadApprovalExpiry, c.MaxApprovalSeconds, a.UTC().Format(time.RFC3339)))
+		}
+	}
+	skew := c.ClockSkew
+	if t.NotBefore != nil && !t.NotBefore.Before(expiry) {
+		errs = append(errs, fmt.Errorf("%w: not_before %s is not before expiry %s", ErrBadNotBefore, t.NotBefore.UTC().Format(time.RFC3339), expiry.UTC().Format(time.RFC3339)))
+	}
+	if early := now.Add(skew); t.CreatedAt.After(early) {
+		errs = append(errs, fmt.Errorf("%w: created at %s (now: %s)", ErrNotYetValid, t.CreatedAt.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339)))
+	} else if t.NotBefore != nil && t.NotBefore.After(early) {
+		errs = append(errs, fmt.Errorf("%w until %s (now: %s)", ErrNotYetValid, t.NotBefore.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339)))
+	}
+	if !now.Before(expiry.Add(skew)) {
+		errs = append(errs, fmt.Errorf("%w at %s (now: %s)", ErrExpired, expiry.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339)))
+	}
+	return errs.err()
 }
 
 // SignTask cryptographically signs a task with the given private key.
 // Returns a SignedTask that attests to the task's integrity and provenance (R1).
 func SignTask(task Task, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedTask, error) {
-	if len(priv) != ed25519.PrivateKeySize {
-		return nil, errors.New("invalid private key size")
+	s, err := NewKeySigner(priv, pub)
+	if err != nil {
+		return nil, err
 	}
-	if len(pub) != ed25519.PublicKeySize {
-		return nil, errors.New("invalid public key size")
+	return SignTaskContext(context.Background(), task, s)
+}
+
+// VerifyTask verifies the signature and validates the task. Its errors
+// wrap ErrBadSignature, ErrSchemaVersion, or ErrBadTimestamp, or are
+// ValidationErrors; Deny turns any of them into a *DenialError.
+func VerifyTask(st *SignedTask) error {
+	if err := verifyTaskEnvelope(st); err != nil {
+		return err
 	}
-	if err := task.Validate(time.Now().UTC()); err != nil {
-		return nil, fmt.Errorf("task validation failed: %w", err)
+	return st.Task.Validate(time.Now().UTC())
+}
+
+// verifyTaskEnvelope is VerifyTask short of validating the task.
+func verifyTaskEnvelope(st *SignedTask) error {
+	if err := VerifyTaskSignature(st); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadSignature, err)
 	}
-	payload, err := json.Marshal(task)
-	if err != nil {
-		return nil, fmt.Errorf("marshal task: %w", err)
-	}
-	sig := ed25519.Sign(priv, payload)
-	return &SignedTask{
-		Task:      task,
-		PublicKey: pub,
-		Signature: sig,
-	}, nil
+	return checkEnvelope(st)
 }
 
-// VerifyTask verifies the signature and validates the task.
-func VerifyTask(st *SignedTask) error {
+// checkEnvelope is verifyTaskEnvelope short of the signature, for callers
+// that check the signature another way: the schema version and timestamp.
+func checkEnvelope(st *SignedTask) error {
+	if err := checkSchemaVersion(st.Task.SchemaVersion); err != nil {
+		return err
+	}
+	if err := checkTimestamp(st); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadTimestamp, err)
+	}
+	return nil
+}
+
+// VerifyTaskSignature checks only the operator's signature, not validity or
+// expiry. It is for after-the-fact evidence checks such as report appendices,
+// where every task has long since expired.
+func VerifyTaskSignature(st *SignedTask) error {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	return migrations.verifySignature(st, TaskSchemaVersion)
+}
+
+func (ms migrationSet) verifySignature(st *SignedTask, current int) error {
 	if st == nil {
 		return errors.New("signed task is nil")
 	}
@@ -142,14 +336,22 @@ func VerifyTask(st *SignedTask) error {
 	if len(st.Signature) != ed25519.SignatureSize {
 		return errors.New("invalid signature size")
 	}
-	payload, err := json.Marshal(st.Task)
+	if len(st.Certificates) > 0 {
+		if _, err := leafKey(st); err != nil {
+			return err
+		}
+	}
+	payload, err := ms.signedPayload(st, current)
 	if err != nil {
-		return fmt.Errorf("marshal task: %w", err)
+		return err
 	}
 	if !ed25519.Verify(st.PublicKey, payload, st.Signature) {
 		return errors.New("signature verification failed")
 	}
-	return st.Task.Validate(time.Now().UTC())
+	if st.Detached() {
+		return checkDigest(st)
+	}
+	return nil
 }
 
 // GenerateKeyPair generates a new ed25519 key pair for task signing.
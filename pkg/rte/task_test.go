@@ -3,6 +3,8 @@ package rte
 import (
 	"crypto/ed25519"
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -38,6 +40,95 @@ func TestTask_Validate_Expired(t *testing.T) {
 	}
 }
 
+func TestTask_Validate_ClockSkew(t *testing.T) {
+	now := time.Now().UTC()
+	task := validTask(now.Add(-10 * time.Minute))
+	// An agent a couple of seconds ahead still accepts the task at expiry.
+	if err := task.Validate(task.Expiry().Add(2 * time.Second)); err != nil {
+		t.Fatalf("within skew after expiry: %v", err)
+	}
+	if err := task.Validate(task.Expiry().Add(DefaultClockSkew)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("skew past expiry: got %v, want ErrExpired", err)
+	}
+	// One a couple of seconds behind accepts a fresh task.
+	task = validTask(now.Add(2 * time.Second))
+	if err := task.Validate(now); err != nil {
+		t.Fatalf("fresh task within skew: %v", err)
+	}
+	task = validTask(now.Add(time.Minute))
+	if err := task.Validate(now); !errors.Is(err, ErrNotYetValid) {
+		t.Fatalf("future-dated task: got %v, want ErrNotYetValid", err)
+	}
+
+	if err := (ValidatorConfig{ClockSkew: time.Hour}).Validate(); err == nil {
+		t.Fatal("expected skew beyond MaxClockSkew to fail")
+	}
+	wide := ValidatorConfig{ClockSkew: time.Minute}
+	task = validTask(now.Add(-10 * time.Minute))
+	if err := task.ValidateWith(task.Expiry().Add(30*time.Second), wide); err != nil {
+		t.Fatalf("within a wider skew after expiry: %v", err)
+	}
+	if err := task.ValidateWith(task.Expiry().Add(time.Minute), wide); !errors.Is(err, ErrExpired) {
+		t.Fatalf("wider skew past expiry: got %v, want ErrExpired", err)
+	}
+}
+
+func TestTask_Validate_NotBefore(t *testing.T) {
+	now := time.Now().UTC()
+	task := validTask(now)
+	nb := now.Add(5 * time.Minute)
+	task.NotBefore = &nb
+	if err := task.Validate(now); !errors.Is(err, ErrNotYetValid) {
+		t.Fatalf("before not_before: got %v, want ErrNotYetValid", err)
+	}
+	if err := task.Validate(nb.Add(-time.Second)); err != nil {
+		t.Fatalf("within skew of not_before: %v", err)
+	}
+	if err := task.Validate(nb.Add(time.Minute)); err != nil {
+		t.Fatalf("after not_before: %v", err)
+	}
+	late := task.Expiry()
+	task.NotBefore = &late
+	if err := task.Validate(now); !errors.Is(err, ErrBadNotBefore) {
+		t.Fatalf("not_before at expiry: got %v, want ErrBadNotBefore", err)
+	}
+}
+
+func TestTask_Validate_ApprovalExpiresAt(t *testing.T) {
+	now := time.Now().UTC()
+	task := validTask(now.Add(-2 * time.Hour))
+	if err := task.Validate(now); !errors.Is(err, ErrExpired) {
+		t.Fatalf("past TTL without approval window: got %v", err)
+	}
+	approval := now.Add(70 * time.Hour)
+	task.ApprovalExpiresAt = &approval
+	if err := task.Validate(now); err != nil {
+		t.Fatalf("within approval window: %v", err)
+	}
+	if got := task.RunExpiry(now); !got.Equal(now.Add(10 * time.Minute)) {
+		t.Fatalf("run expiry = %s", got)
+	}
+	if err := task.Validate(approval.Add(time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("after approval window: got %v", err)
+	}
+	for _, a := range []time.Time{task.CreatedAt, task.CreatedAt.Add(8 * 24 * time.Hour)} {
+		task.ApprovalExpiresAt = &a
+		if err := task.Validate(now); !errors.Is(err, ErrBadApprovalExpiry) {
+			t.Errorf("approval_expires_at %s: got %v", a, err)
+		}
+	}
+}
+
+func TestTask_NotBeforeOmittedWhenUnset(t *testing.T) {
+	data, err := json.Marshal(validTask(time.Now().UTC()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "not_before") {
+		t.Fatalf("not_before encoded for a task without one: %s", data)
+	}
+}
+
 func TestTask_Validate_UnsupportedType(t *testing.T) {
 	now := time.Now().UTC()
 	task := validTask(now)
@@ -78,6 +169,44 @@ func TestTask_Validate_TTLOutOfRange(t *testing.T) {
 	}
 }
 
+func TestTask_Validate_Priority(t *testing.T) {
+	now := time.Now().UTC()
+	task := validTask(now)
+	for _, p := range []int{0, 5, MaxPriority} {
+		task.Priority = p
+		if err := task.Validate(now); err != nil {
+			t.Errorf("priority %d: %v", p, err)
+		}
+	}
+	for _, p := range []int{-1, MaxPriority + 1} {
+		task.Priority = p
+		if err := task.Validate(now); err == nil {
+			t.Errorf("expected priority %d to fail validation", p)
+		}
+	}
+}
+
+func TestValidTransition(t *testing.T) {
+	ok := [][2]TaskState{
+		{StatePending, StateExecuting}, {StateExecuting, StatePaused}, {StatePaused, StateExecuting},
+		{StatePaused, StateCancelled}, {StateExecuting, StateCompleted},
+	}
+	for _, c := range ok {
+		if err := ValidTransition(c[0], c[1]); err != nil {
+			t.Errorf("%s -> %s: %v", c[0], c[1], err)
+		}
+	}
+	bad := [][2]TaskState{
+		{StatePending, StatePaused}, {StatePaused, StateCompleted}, {StateCompleted, StateExecuting},
+		{StateCancelled, StatePaused}, {"bogus", StateExecuting},
+	}
+	for _, c := range bad {
+		if err := ValidTransition(c[0], c[1]); err == nil {
+			t.Errorf("expected %s -> %s to be refused", c[0], c[1])
+		}
+	}
+}
+
 func TestSignTask_Valid(t *testing.T) {
 	pub, priv, err := GenerateKeyPair()
 	if err != nil {
@@ -130,6 +259,29 @@ func TestVerifyTask_InvalidSignature(t *testing.T) {
 	}
 }
 
+func TestVerifyTaskSignature_Expired(t *testing.T) {
+	pub, priv, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("generate key pair: %v", err)
+	}
+	task := validTask(time.Now().UTC().Add(-48 * time.Hour))
+	payload, err := json.Marshal(task)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	st := &SignedTask{Task: task, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}
+	if err := VerifyTask(st); err == nil {
+		t.Fatal("expected expired task to fail VerifyTask")
+	}
+	if err := VerifyTaskSignature(st); err != nil {
+		t.Fatalf("VerifyTaskSignature: %v", err)
+	}
+	st.Task.Operator = "mallory"
+	if err := VerifyTaskSignature(st); err == nil {
+		t.Fatal("expected tampered task to fail VerifyTaskSignature")
+	}
+}
+
 func TestVerifyTask_TamperedTask(t *testing.T) {
 	pub, priv, err := GenerateKeyPair()
 	if err != nil {
@@ -185,3 +337,33 @@ func TestMarshalUnmarshalConsistency(t *testing.T) {
 		t.Fatalf("VerifyTask after roundtrip: %v", err)
 	}
 }
+
+func TestTask_Validate_AllErrors(t *testing.T) {
+	now := time.Now().UTC()
+	task := validTask(now.Add(-time.Hour))
+	task.Operator = ""
+	task.Type = TaskType("malware")
+	task.TTLSeconds = 600
+	err := task.Validate(now)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+	if len(verrs) != 3 {
+		t.Fatalf("got %d errors, want 3: %v", len(verrs), err)
+	}
+	for _, want := range []error{ErrMissingOperator, ErrUnsupportedType, ErrExpired} {
+		if !errors.Is(err, want) {
+			t.Errorf("errors.Is(%v) = false: %v", want, err)
+		}
+	}
+	if errors.Is(err, ErrBadTTL) {
+		t.Errorf("unexpected ErrBadTTL: %v", err)
+	}
+
+	task = validTask(now)
+	task.TTLSeconds = 0
+	if err := task.Validate(now); !errors.Is(err, ErrBadTTL) {
+		t.Errorf("TTL 0: got %v, want ErrBadTTL", err)
+	}
+}
@@ -0,0 +1,112 @@
+package rte
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// crockford is the Crockford base32 alphabet ULIDs are written in.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGen makes IDs monotonic within a millisecond: a second ID in the
+// same millisecond increments the previous one's random part instead of
+// drawing a new one, so IDs from one process always sort in issue order.
+var ulidGen struct {
+	mu   sync.Mutex
+	ms   uint64
+	hi   uint16 // top 16 of the 80 random bits
+	lo   uint64
+	init bool
+}
+
+// NewTaskID returns a new ULID: a 26-character, lexically sortable ID whose
+// first 10 characters encode the issue time in milliseconds and whose rest
+// is 80 random bits.
+func NewTaskID() string {
+	id, err := newULID(time.Now())
+	if err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic(err)
+	}
+	return id
+}
+
+func newULID(now time.Time) (string, error) {
+	ms := uint64(now.UnixMilli())
+	g := &ulidGen
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.init && ms <= g.ms {
+		// Same millisecond, or the clock stepped back: keep the previous
+		// time and count up so the new ID still sorts after the last.
+		ms = g.ms
+		g.lo++
+		if g.lo == 0 {
+			g.hi++
+			if g.hi == 0 {
+				return "", fmt.Errorf("task ID space exhausted for millisecond %d", ms)
+			}
+		}
+	} else {
+		var b [10]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", fmt.Errorf("task ID entropy: %w", err)
+		}
+		g.hi, g.lo = binary.BigEndian.Uint16(b[:2]), binary.BigEndian.Uint64(b[2:])
+	}
+	g.ms, g.init = ms, true
+
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[:8], ms<<16)
+	binary.BigEndian.PutUint16(raw[6:8], g.hi)
+	binary.BigEndian.PutUint64(raw[8:], g.lo)
+	return encodeULID(raw), nil
+}
+
+// encodeULID writes 128 bits as 26 base32 characters, the first carrying
+// only the top 3 bits.
+func encodeULID(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// TaskIDTime returns the issue time encoded in a ULID task ID, and false if
+// id is not a ULID.
+func TaskIDTime(id string) (time.Time, bool) {
+	if len(id) != 26 || id[0] > '7' {
+		return time.Time{}, false
+	}
+	var ms uint64
+	for i := 0; i < 26; i++ {
+		v, ok := crockfordValue(id[i])
+		if !ok {
+			return time.Time{}, false
+		}
+		if i < 10 {
+			ms = ms<<5 | uint64(v)
+		}
+	}
+	return time.UnixMilli(int64(ms)).UTC(), true
+}
+
+func crockfordValue(c byte) (byte, bool) {
+	if 'a' <= c && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(crockford); i++ {
+		if crockford[i] == c {
+			return byte(i), true
+		}
+	}
+	return 0, false
+}
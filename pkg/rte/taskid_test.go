@@ -0,0 +1,59 @@
+package rte
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestEncodeULID(t *testing.T) {
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := encodeULID(max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("max ULID = %s", got)
+	}
+	// 1469918176385 ms is the timestamp of the ULID spec's example.
+	var raw [16]byte
+	ms := uint64(1469918176385)
+	for i := 5; i >= 0; i-- {
+		raw[i] = byte(ms)
+		ms >>= 8
+	}
+	if got := encodeULID(raw)[:10]; got != "01ARYZ6S41" {
+		t.Errorf("time prefix = %s, want 01ARYZ6S41", got)
+	}
+}
+
+func TestNewTaskID(t *testing.T) {
+	start := time.Now().Truncate(time.Millisecond)
+	ids := make([]string, 1000)
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		ids[i] = NewTaskID()
+		if len(ids[i]) != 26 || seen[ids[i]] {
+			t.Fatalf("ID %d = %q (seen %v)", i, ids[i], seen[ids[i]])
+		}
+		seen[ids[i]] = true
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Error("IDs do not sort in issue order")
+	}
+	at, ok := TaskIDTime(ids[0])
+	if !ok || at.Before(start) || at.After(time.Now()) {
+		t.Errorf("TaskIDTime = %s, %v (start %s)", at, ok, start)
+	}
+	if _, ok := TaskIDTime("task-001"); ok {
+		t.Error("TaskIDTime accepted a non-ULID")
+	}
+}
+
+func TestNewULID_ClockStepBack(t *testing.T) {
+	now := time.Now()
+	a, _ := newULID(now)
+	b, _ := newULID(now.Add(-time.Hour))
+	if b <= a {
+		t.Errorf("ID after clock step back %s sorts before %s", b, a)
+	}
+}
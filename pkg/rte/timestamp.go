@@ -0,0 +1,495 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// timestampSlack is how far a timestamp may precede a task's CreatedAt,
+// covering the round trip to the TSA and modest operator clock drift.
+const timestampSlack = 5 * time.Minute
+
+var (
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidContentType     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidRSA             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// Timestamper obtains RFC 3161 timestamp tokens from a timestamping
+// authority (TSA).
+type Timestamper interface {
+	// Timestamp returns a DER TimeStampToken over a SHA-256 digest.
+	Timestamp(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// Timestamp is the verified content of a timestamp token.
+type Timestamp struct {
+	// Time is when the TSA saw the digest, within Accuracy.
+	Time     time.Time
+	Accuracy time.Duration
+	Serial   *big.Int
+	// Certificate signed the token. It is not checked against any root;
+	// TimestampPolicy does that.
+	Certificate *x509.Certificate
+	// Certificates are all certificates the token carries.
+	Certificates []*x509.Certificate
+}
+
+// TimestampTask has tsa timestamp st's signature and attaches the token, so
+// st carries third-party evidence of when it was signed rather than only
+// the operator's CreatedAt.
+func TimestampTask(ctx context.Context, st *SignedTask, tsa Timestamper) error {
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	if tsa == nil {
+		return errors.New("timestamper is nil")
+	}
+	if len(st.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	digest := timestampDigest(st)
+	token, err := tsa.Timestamp(ctx, digest)
+	if err != nil {
+		return fmt.Errorf("timestamp task: %w", err)
+	}
+	if _, err := ParseTimestamp(token, digest); err != nil {
+		return fmt.Errorf("timestamp task: %w", err)
+	}
+	st.Timestamp = token
+	return nil
+}
+
+// timestampDigest is the message imprint a task's token covers: the
+// SHA-256 of the operator's signature, which in turn covers the task.
+func timestampDigest(st *SignedTask) []byte {
+	sum := sha256.Sum256(st.Signature)
+	return sum[:]
+}
+
+// VerifyTimestamp checks st's timestamp token: that the TSA signed it and
+// that it covers st's signature. It does not decide whether the TSA is
+// trusted; see TimestampPolicy.
+func VerifyTimestamp(st *SignedTask) (*Timestamp, error) {
+	if st == nil {
+		return nil, errors.New("signed task is nil")
+	}
+	if len(st.Timestamp) == 0 {
+		return nil, errors.New("signed task carries no timestamp")
+	}
+	return ParseTimestamp(st.Timestamp, timestampDigest(st))
+}
+
+// checkTimestamp verifies st's token, if any, and that the task was
+// timestamped while it was valid: not well before it claims to have been
+// created, and before it expired.
+func checkTimestamp(st *SignedTask) error {
+	if len(st.Timestamp) == 0 {
+		return nil
+	}
+	ts, err := VerifyTimestamp(st)
+	if err != nil {
+		return err
+	}
+	earliest, latest := ts.Time.Add(-ts.Accuracy), ts.Time.Add(ts.Accuracy)
+	if latest.Before(st.Task.CreatedAt.Add(-timestampSlack)) {
+		return fmt.Errorf("timestamp %s precedes task creation %s", ts.Time.Format(time.RFC3339), st.Task.CreatedAt.Format(time.RFC3339))
+	}
+	if !earliest.Before(st.Task.Expiry()) {
+		return fmt.Errorf("timestamp %s is not before task expiry %s", ts.Time.Format(time.RFC3339), st.Task.Expiry().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// TimestampPolicy requires tasks to carry a timestamp from a trusted TSA.
+type TimestampPolicy struct {
+	// Roots are the trusted TSA roots.
+	Roots *x509.CertPool
+}
+
+// VerifyTask checks st's timestamp against the policy, then verifies it as
+// VerifyTask does.
+func (p *TimestampPolicy) VerifyTask(st *SignedTask) error {
+	if _, err := p.Verify(st); err != nil {
+		return err
+	}
+	return VerifyTask(st)
+}
+
+// Verify returns st's timestamp once it is verified and its certificate
+// chains to a trusted root for timestamping, as of the time it asserts.
+func (p *TimestampPolicy) Verify(st *SignedTask) (*Timestamp, error) {
+	if p.Roots == nil {
+		return nil, errors.New("no trusted TSA pool configured")
+	}
+	ts, err := VerifyTimestamp(st)
+	if err != nil {
+		return nil, err
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range ts.Certificates {
+		intermediates.AddCert(c)
+	}
+	if _, err := ts.Certificate.Verify(x509.VerifyOptions{
+		Roots: p.Roots, Intermediates: intermediates, CurrentTime: ts.Time,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}); err != nil {
+		return nil, fmt.Errorf("TSA certificate: %w", err)
+	}
+	return ts, nil
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time     `asn1:"generalized"`
+	Accuracy       tsAccuracy    `asn1:"optional"`
+	Ordering       bool          `asn1:"optional,default:false"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type tsAccuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+func (a tsAccuracy) duration() time.Duration {
+	return time.Duration(a.Seconds)*time.Second + time.Duration(a.Millis)*time.Millisecond + time.Duration(a.Micros)*time.Microsecond
+}
+
+// ParseTimestamp verifies a DER TimeStampToken's signature and that it
+// covers the SHA-256 digest, and returns what it asserts.
+func ParseTimestamp(token, digest []byte) (*Timestamp, error) {
+	ts, info, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) {
+		return nil, errors.New("timestamp imprint is not SHA-256")
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, errors.New("timestamp does not cover this signature")
+	}
+	return ts, nil
+}
+
+func parseToken(token []byte) (*Timestamp, *tstInfo, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(token, &ci); err != nil {
+		return nil, nil, fmt.Errorf("parse timestamp token: %w", err)
+	} else if len(rest) > 0 {
+		return nil, nil, errors.New("parse timestamp token: trailing data")
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, nil, errors.New("timestamp token is not CMS signed data")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, nil, fmt.Errorf("parse timestamp signed data: %w", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, nil, errors.New("timestamp token does not hold TSTInfo")
+	}
+	var content []byte
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent.Bytes, &content); err != nil {
+		return nil, nil, fmt.Errorf("parse timestamp content: %w", err)
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(content, &info); err != nil {
+		return nil, nil, fmt.Errorf("parse TSTInfo: %w", err)
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse timestamp certificates: %w", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, nil, fmt.Errorf("timestamp token has %d signers, want 1", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+	signer := findSigner(certs, si.SID)
+	if signer == nil {
+		return nil, nil, errors.New("timestamp token does not carry its signer's certificate")
+	}
+	if err := verifySignerInfo(si, signer, content); err != nil {
+		return nil, nil, err
+	}
+	return &Timestamp{
+		Time:         info.GenTime.UTC(),
+		Accuracy:     info.Accuracy.duration(),
+		Serial:       info.SerialNumber,
+		Certificate:  signer,
+		Certificates: certs,
+	}, &info, nil
+}
+
+func findSigner(certs []*x509.Certificate, sid asn1.RawValue) *x509.Certificate {
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, c := range certs {
+			if bytes.Equal(c.SubjectKeyId, sid.Bytes) {
+				return c
+			}
+		}
+		return nil
+	}
+	var ias issuerAndSerial
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil
+	}
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.Serial) == 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+// verifySignerInfo checks the signed attributes bind content and that the
+// signer's key signed them.
+func verifySignerInfo(si signerInfo, signer *x509.Certificate, content []byte) error {
+	if len(si.SignedAttrs.Bytes) == 0 {
+		return errors.New("timestamp signer info has no signed attributes")
+	}
+	hash, err := digestHash(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	// The signature covers the attributes DER-encoded as a SET, not with
+	// the implicit [0] tag they carry in the signer info.
+	signed := append([]byte(nil), si.SignedAttrs.FullBytes...)
+	signed[0] = 0x31
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+		return fmt.Errorf("parse timestamp signed attributes: %w", err)
+	}
+	var gotType, gotDigest bool
+	for _, a := range attrs {
+		if len(a.Values) != 1 {
+			continue
+		}
+		switch {
+		case a.Type.Equal(oidContentType):
+			var ct asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(a.Values[0].FullBytes, &ct); err != nil || !ct.Equal(oidTSTInfo) {
+				return errors.New("timestamp content type attribute does not name TSTInfo")
+			}
+			gotType = true
+		case a.Type.Equal(oidMessageDigest):
+			var md []byte
+			if _, err := asn1.Unmarshal(a.Values[0].FullBytes, &md); err != nil {
+				return fmt.Errorf("parse timestamp message digest: %w", err)
+			}
+			h := hash.New()
+			h.Write(content)
+			if !bytes.Equal(md, h.Sum(nil)) {
+				return errors.New("timestamp message digest does not match its content")
+			}
+			gotDigest = true
+		}
+	}
+	if !gotType || !gotDigest {
+		return errors.New("timestamp signed attributes lack content type or message digest")
+	}
+	alg, err := signatureAlgorithm(si.SignatureAlgorithm.Algorithm, hash)
+	if err != nil {
+		return err
+	}
+	if err := signer.CheckSignature(alg, signed, si.Signature); err != nil {
+		return fmt.Errorf("timestamp signature: %w", err)
+	}
+	return nil
+}
+
+func digestHash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported timestamp digest algorithm %s", oid)
+}
+
+func signatureAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, error) {
+	switch {
+	case oid.Equal(oidEd25519):
+		return x509.PureEd25519, nil
+	case oid.Equal(oidSHA256WithRSA), oid.Equal(oidRSA) && hash == crypto.SHA256:
+		return x509.SHA256WithRSA, nil
+	case oid.Equal(oidSHA384WithRSA), oid.Equal(oidRSA) && hash == crypto.SHA384:
+		return x509.SHA384WithRSA, nil
+	case oid.Equal(oidSHA512WithRSA), oid.Equal(oidRSA) && hash == crypto.SHA512:
+		return x509.SHA512WithRSA, nil
+	case oid.Equal(oidECDSAWithSHA256):
+		return x509.ECDSAWithSHA256, nil
+	case oid.Equal(oidECDSAWithSHA384):
+		return x509.ECDSAWithSHA384, nil
+	case oid.Equal(oidECDSAWithSHA512):
+		return x509.ECDSAWithSHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported timestamp signature algorithm %s", oid)
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional,utf8"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+// HTTPTimestamper requests tokens from a TSA over the RFC 3161 HTTP
+// transport.
+type HTTPTimestamper struct {
+	URL string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Timestamp posts a TimeStampReq for digest and returns the granted token
+// after checking it echoes the request's imprint and nonce.
+func (h *HTTPTimestamper) Timestamp(ctx context.Context, digest []byte) ([]byte, error) {
+	if len(digest) != sha256.Size {
+		return nil, errors.New("digest is not SHA-256")
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	req, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: messageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256}, HashedMessage: digest},
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", "application/timestamp-query")
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	var tsr timeStampResp
+	if _, err := asn1.Unmarshal(body, &tsr); err != nil {
+		return nil, fmt.Errorf("parse TSA response: %w", err)
+	}
+	// 0 is granted, 1 granted with modifications.
+	if tsr.Status.Status > 1 {
+		return nil, fmt.Errorf("TSA rejected request: status %d %v", tsr.Status.Status, tsr.Status.StatusString)
+	}
+	token := tsr.TimeStampToken.FullBytes
+	_, info, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, errors.New("TSA token does not cover the requested digest")
+	}
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("TSA token does not echo the request nonce")
+	}
+	return token, nil
+}
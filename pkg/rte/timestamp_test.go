@@ -0,0 +1,227 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTSA issues RFC 3161 tokens signed by an ed25519 TSA certificate.
+type testTSA struct {
+	t     *testing.T
+	roots *x509.CertPool
+	cert  *x509.Certificate
+	priv  ed25519.PrivateKey
+	// now is the genTime the TSA asserts.
+	now    time.Time
+	serial int64
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	t.Helper()
+	now := time.Now()
+	rootPub, rootPriv, _ := ed25519.GenerateKey(nil)
+	root := issue(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "TSA Root"},
+		NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour),
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+	}, nil, rootPub, rootPriv)
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	cert := issue(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "TSA"},
+		NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour),
+		KeyUsage: x509.KeyUsageDigitalSignature, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}, root, pub, rootPriv)
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	return &testTSA{t: t, roots: roots, cert: cert, priv: priv, now: now}
+}
+
+func (a *testTSA) Timestamp(_ context.Context, digest []byte) ([]byte, error) {
+	return a.token(digest, nil), nil
+}
+
+func (a *testTSA) token(digest []byte, nonce *big.Int) []byte {
+	t := a.t
+	t.Helper()
+	mustMarshal := func(v any) []byte {
+		b, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	a.serial++
+	sha := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	info := mustMarshal(tstInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 2, 3, 4},
+		MessageImprint: messageImprint{HashAlgorithm: sha, HashedMessage: digest},
+		SerialNumber:   big.NewInt(a.serial),
+		GenTime:        a.now.UTC().Truncate(time.Second),
+		Accuracy:       tsAccuracy{Seconds: 1},
+		Nonce:          nonce,
+	})
+	sum := sha256.Sum256(info)
+	attrSet := mustMarshalSet(t, []attribute{
+		{Type: oidContentType, Values: []asn1.RawValue{{FullBytes: mustMarshal(oidTSTInfo)}}},
+		{Type: oidMessageDigest, Values: []asn1.RawValue{{FullBytes: mustMarshal(sum[:])}}},
+	})
+	var attrs asn1.RawValue
+	if _, err := asn1.Unmarshal(attrSet, &attrs); err != nil {
+		t.Fatal(err)
+	}
+	sd := mustMarshal(signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha},
+		EncapContentInfo: encapContentInfo{
+			EContentType: oidTSTInfo,
+			EContent:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: mustMarshal(info)},
+		},
+		Certificates: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: a.cert.Raw},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: mustMarshal(issuerAndSerial{Issuer: asn1.RawValue{FullBytes: a.cert.RawIssuer}, Serial: a.cert.SerialNumber})},
+			DigestAlgorithm:    sha,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrs.Bytes},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidEd25519},
+			Signature:          ed25519.Sign(a.priv, attrSet),
+		}},
+	})
+	return mustMarshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+}
+
+func mustMarshalSet(t *testing.T, attrs []attribute) []byte {
+	t.Helper()
+	b, err := asn1.MarshalWithParams(attrs, "set")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func timestamped(t *testing.T, tsa Timestamper) *SignedTask {
+	t.Helper()
+	kp := newKeyPair(t)
+	st, err := SignTask(validTask(time.Now().UTC()), kp.priv, kp.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := TimestampTask(context.Background(), st, tsa); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestTimestampTask_RoundTrip(t *testing.T) {
+	tsa := newTestTSA(t)
+	st := timestamped(t, tsa)
+	if err := VerifyTask(st); err != nil {
+		t.Fatalf("VerifyTask: %v", err)
+	}
+	ts, err := VerifyTimestamp(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ts.Time.Equal(tsa.now.UTC().Truncate(time.Second)) || ts.Accuracy != time.Second {
+		t.Fatalf("timestamp = %s ± %s", ts.Time, ts.Accuracy)
+	}
+	p := &TimestampPolicy{Roots: tsa.roots}
+	if err := p.VerifyTask(st); err != nil {
+		t.Fatalf("TimestampPolicy.VerifyTask: %v", err)
+	}
+}
+
+func TestTimestamp_Rejects(t *testing.T) {
+	tsa := newTestTSA(t)
+
+	st := timestamped(t, tsa)
+	other := timestamped(t, tsa)
+	st.Timestamp = other.Timestamp
+	if err := VerifyTask(st); err == nil || !strings.Contains(err.Error(), "does not cover") {
+		t.Errorf("token for another task: got %v", err)
+	}
+
+	st = timestamped(t, tsa)
+	st.Timestamp[len(st.Timestamp)-1] ^= 0xff
+	if err := VerifyTask(st); err == nil {
+		t.Error("expected tampered token to fail")
+	}
+
+	// A task claiming to be created long after the TSA saw it was
+	// pre-dated by the operator's clock.
+	tsa.now = time.Now().Add(-time.Hour)
+	st = timestamped(t, tsa)
+	if err := VerifyTask(st); err == nil || !strings.Contains(err.Error(), "precedes task creation") {
+		t.Errorf("pre-dated task: got %v", err)
+	}
+
+	tsa.now = time.Now().Add(20 * time.Minute)
+	st = timestamped(t, tsa)
+	if err := VerifyTask(st); err == nil || !strings.Contains(err.Error(), "not before task expiry") {
+		t.Errorf("timestamped after expiry: got %v", err)
+	}
+
+	tsa.now = time.Now()
+	st = timestamped(t, tsa)
+	p := &TimestampPolicy{Roots: x509.NewCertPool()}
+	if err := p.VerifyTask(st); err == nil || !strings.Contains(err.Error(), "TSA certificate") {
+		t.Errorf("untrusted TSA: got %v", err)
+	}
+	st.Timestamp = nil
+	p = &TimestampPolicy{Roots: tsa.roots}
+	if err := p.VerifyTask(st); err == nil {
+		t.Error("expected policy to require a timestamp")
+	}
+}
+
+func TestHTTPTimestamper(t *testing.T) {
+	tsa := newTestTSA(t)
+	var badNonce bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/timestamp-query" {
+			t.Errorf("content type = %q", ct)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req timeStampReq
+		if _, err := asn1.Unmarshal(body, &req); err != nil {
+			t.Errorf("parse request: %v", err)
+			return
+		}
+		nonce := req.Nonce
+		if badNonce {
+			nonce = big.NewInt(7)
+		}
+		resp, err := asn1.Marshal(timeStampResp{TimeStampToken: asn1.RawValue{FullBytes: tsa.token(req.MessageImprint.HashedMessage, nonce)}})
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(resp)
+	}))
+	defer srv.Close()
+
+	h := &HTTPTimestamper{URL: srv.URL}
+	st := timestamped(t, h)
+	p := &TimestampPolicy{Roots: tsa.roots}
+	if err := p.VerifyTask(st); err != nil {
+		t.Fatal(err)
+	}
+	badNonce = true
+	if _, err := h.Timestamp(context.Background(), timestampDigest(st)); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("replayed nonce: got %v", err)
+	}
+}
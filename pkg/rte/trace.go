@@ -0,0 +1,45 @@
+package rte
+
+import (
+	"context"
+
+	"github.com/codethor0/rte-a-reference/pkg/tracing"
+)
+
+// Span attribute keys for task lifecycle spans.
+const (
+	AttrTaskID     = "rte.task.id"
+	AttrEngagement = "rte.engagement"
+	AttrTaskType   = "rte.task.type"
+	AttrTaskState  = "rte.task.state"
+)
+
+// StartTaskSpan starts a lifecycle span (for example "rte.sign" or
+// "rte.report") tagged with the task's identity.
+func StartTaskSpan(ctx context.Context, tr *tracing.Tracer, name string, task Task) (context.Context, *tracing.Span) {
+	return tr.Start(ctx, name,
+		tracing.String(AttrTaskID, task.ID),
+		tracing.String(AttrEngagement, task.Engagement),
+		tracing.String(AttrTaskType, string(task.Type)),
+	)
+}
+
+// InjectTrace records the current span in st so the next hop continues the
+// same trace.
+func InjectTrace(ctx context.Context, st *SignedTask) {
+	if st == nil || !tracing.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	if st.Trace == nil {
+		st.Trace = make(map[string]string, 1)
+	}
+	tracing.Inject(ctx, st.Trace)
+}
+
+// ExtractTrace returns ctx with the remote parent carried by st, if any.
+func ExtractTrace(ctx context.Context, st *SignedTask) context.Context {
+	if st == nil {
+		return ctx
+	}
+	return tracing.Extract(ctx, st.Trace)
+}
@@ -0,0 +1,57 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/tracing"
+)
+
+func TestTrace_SignToExecute(t *testing.T) {
+	rec := &tracing.Recorder{}
+	tr := &tracing.Tracer{Exporter: rec}
+
+	// Controller: sign the task and carry the trace in the envelope.
+	st := signedValidTask(t)
+	ctx, sign := StartTaskSpan(context.Background(), tr, "rte.sign", st.Task)
+	InjectTrace(ctx, st)
+	sign.End()
+	if err := VerifyTask(st); err != nil {
+		t.Fatalf("trace metadata broke the signature: %v", err)
+	}
+
+	// Agent: decode and execute in a fresh context.
+	wire, _ := json.Marshal(st)
+	var got SignedTask
+	_ = json.Unmarshal(wire, &got)
+	e := NewExecutor()
+	e.Tracer = tr
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) { return nil, nil }))
+	if _, err := e.Execute(context.Background(), &got); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	byName := map[string]tracing.SpanData{}
+	for _, s := range rec.Spans() {
+		byName[s.Name] = s
+		if s.TraceID != sign.SpanContext().TraceIDString() {
+			t.Errorf("%s is in trace %s, want %s", s.Name, s.TraceID, sign.SpanContext().TraceIDString())
+		}
+	}
+	if byName["rte.execute"].ParentID != byName["rte.sign"].SpanID {
+		t.Error("rte.execute not parented by rte.sign")
+	}
+	for _, child := range []string{"rte.verify", "rte.handle"} {
+		if byName[child].ParentID != byName["rte.execute"].SpanID {
+			t.Errorf("%s not parented by rte.execute", child)
+		}
+	}
+	attrs := map[string]string{}
+	for _, a := range byName["rte.execute"].Attrs {
+		attrs[a.Key] = a.Value
+	}
+	if attrs[AttrTaskID] != st.Task.ID || attrs[AttrEngagement] != st.Task.Engagement || attrs[AttrTaskState] != string(StateCompleted) {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
@@ -0,0 +1,23 @@
+package rte
+
+import "time"
+
+// Transition records a task moving between lifecycle states during
+// execution: pending to executing when its handler starts, executing to
+// and from paused, and into its final state. Result is set on the final
+// transition only.
+type Transition struct {
+	Task   Task        `json:"task"`
+	From   TaskState   `json:"from"`
+	To     TaskState   `json:"to"`
+	At     time.Time   `json:"at"`
+	Reason string      `json:"reason,omitempty"`
+	Result *TaskResult `json:"result,omitempty"`
+}
+
+// notify passes tr to OnTransition, if set.
+func (e *Executor) notify(tr Transition) {
+	if e.OnTransition != nil {
+		e.OnTransition(tr)
+	}
+}
@@ -0,0 +1,59 @@
+package rte
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecutor_OnTransition(t *testing.T) {
+	reg, op, lead := enrolled(t)
+	e := NewExecutor()
+	e.Identities = reg
+	h := &countingHandler{}
+	_ = e.Register(TaskSimulateLogin, h)
+	var mu sync.Mutex
+	var got []Transition
+	e.OnTransition = func(tr Transition) {
+		mu.Lock()
+		got = append(got, tr)
+		mu.Unlock()
+	}
+	task := validTask(time.Now().UTC())
+	task.CancelToken = "tok"
+	st := signAndApprove(t, task, op, lead)
+	done := make(chan struct{})
+	go func() {
+		_, _ = e.Execute(context.Background(), st)
+		close(done)
+	}()
+	for h.actions.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := e.Pause(pauseRequest(t, ActionPause, "lead-bob", lead)); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if _, err := e.Cancel(context.Background(), TaskCancel{Engagement: task.Engagement, TaskID: task.ID, Token: "tok"}); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := [][2]TaskState{{StatePending, StateExecuting}, {StateExecuting, StatePaused}, {StatePaused, StateCancelled}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d transitions, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].From != w[0] || got[i].To != w[1] {
+			t.Errorf("transition %d: got %s -> %s, want %s -> %s", i, got[i].From, got[i].To, w[0], w[1])
+		}
+	}
+	if got[1].Reason != "customer incident bridge" {
+		t.Errorf("pause reason: got %q", got[1].Reason)
+	}
+	if last := got[2]; last.Result == nil || last.Result.State != StateCancelled {
+		t.Errorf("final transition missing result: %+v", last)
+	}
+}
@@ -0,0 +1,157 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SignedTreeHead is a transparency log's signed commitment to its Merkle
+// tree at one size. The signature covers the JSON of the head with
+// Signature empty.
+type SignedTreeHead struct {
+	TreeSize  uint64    `json:"tree_size"`
+	RootHash  []byte    `json:"root_hash"`
+	Timestamp time.Time `json:"timestamp"`
+	Signature []byte    `json:"signature,omitempty"`
+}
+
+func (h SignedTreeHead) payload() ([]byte, error) {
+	h.Signature = nil
+	return json.Marshal(h)
+}
+
+// Sign sets h's signature with the log's key.
+func (h *SignedTreeHead) Sign(priv ed25519.PrivateKey) error {
+	if len(priv) != ed25519.PrivateKeySize {
+		return errors.New("invalid private key size")
+	}
+	payload, err := h.payload()
+	if err != nil {
+		return err
+	}
+	h.Signature = ed25519.Sign(priv, payload)
+	return nil
+}
+
+// LogProof is a LogAnchor receipt's proof: where the checkpoint digest sits
+// in the log, the inclusion path to the root, and the signed head.
+type LogProof struct {
+	LeafIndex uint64         `json:"leaf_index"`
+	AuditPath [][]byte       `json:"audit_path"`
+	TreeHead  SignedTreeHead `json:"tree_head"`
+}
+
+// LogAnchor anchors checkpoints in an append-only transparency log built
+// on the RFC 6962 Merkle tree. It POSTs {"leaf": base64} to URL/add-leaf,
+// the leaf being the checkpoint digest, and expects a LogProof back.
+// Verify recomputes the root from the inclusion path and checks the tree
+// head against PublicKey, so it needs no network access; detecting a log
+// that shows different trees to different readers needs head gossip, as
+// with Certificate Transparency.
+type LogAnchor struct {
+	URL string
+	// PublicKey is the log's tree-head signing key.
+	PublicKey ed25519.PublicKey
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Anchor implements Anchor.
+func (l *LogAnchor) Anchor(ctx context.Context, c Checkpoint) (*AnchorReceipt, error) {
+	var proof LogProof
+	u := strings.TrimRight(l.URL, "/") + "/add-leaf"
+	if err := postJSON(ctx, l.Client, u, map[string][]byte{"leaf": c.Digest()}, &proof); err != nil {
+		return nil, fmt.Errorf("transparency log: %w", err)
+	}
+	raw, err := json.Marshal(proof)
+	if err != nil {
+		return nil, err
+	}
+	r := &AnchorReceipt{Checkpoint: c, Anchor: "rfc6962", Proof: raw}
+	if err := l.Verify(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Verify implements Anchor.
+func (l *LogAnchor) Verify(_ context.Context, r *AnchorReceipt) error {
+	if r.Anchor != "rfc6962" {
+		return fmt.Errorf("receipt is from a %q anchor, not a transparency log", r.Anchor)
+	}
+	if len(l.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("log public key is not configured")
+	}
+	var proof LogProof
+	if err := json.Unmarshal(r.Proof, &proof); err != nil {
+		return fmt.Errorf("decode log proof: %w", err)
+	}
+	head := proof.TreeHead
+	payload, err := head.payload()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(l.PublicKey, payload, head.Signature) {
+		return errors.New("tree head signature verification failed")
+	}
+	return VerifyInclusion(proof.LeafIndex, head.TreeSize, MerkleLeafHash(r.Checkpoint.Digest()), proof.AuditPath, head.RootHash)
+}
+
+// MerkleLeafHash is the RFC 6962 hash of a leaf.
+func MerkleLeafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+// MerkleNodeHash is the RFC 6962 hash of an interior node.
+func MerkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// VerifyInclusion checks an RFC 6962 audit path proving the leaf with hash
+// leafHash sits at index in the tree of size whose root is root, as
+// RFC 9162 section 2.1.3.2 describes.
+func VerifyInclusion(index, size uint64, leafHash []byte, path [][]byte, root []byte) error {
+	if index >= size {
+		return fmt.Errorf("leaf index %d is outside a tree of size %d", index, size)
+	}
+	fn, sn := index, size-1
+	r := leafHash
+	for _, p := range path {
+		if sn == 0 {
+			return errors.New("inclusion proof is too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = MerkleNodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = MerkleNodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("inclusion proof is too short")
+	}
+	if !bytes.Equal(r, root) {
+		return errors.New("inclusion proof does not match the tree head")
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// merkleRoot and merklePath follow RFC 6962 section 2.1.
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		return nil
+	case 1:
+		return MerkleLeafHash(leaves[0])
+	}
+	k := splitPoint(len(leaves))
+	return MerkleNodeHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+func merklePath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if m < k {
+		return append(merklePath(m, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(m-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+func splitPoint(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
+
+// fakeLog is an in-memory transparency log serving add-leaf.
+type fakeLog struct {
+	priv   ed25519.PrivateKey
+	mu     sync.Mutex
+	leaves [][]byte
+}
+
+func (f *fakeLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Leaf []byte `json:"leaf"`
+	}
+	if r.URL.Path != "/add-leaf" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.leaves = append(f.leaves, req.Leaf)
+	idx := len(f.leaves) - 1
+	head := SignedTreeHead{TreeSize: uint64(len(f.leaves)), RootHash: merkleRoot(f.leaves), Timestamp: time.Now().UTC()}
+	path := merklePath(idx, f.leaves)
+	f.mu.Unlock()
+	head.Sign(f.priv)
+	json.NewEncoder(w).Encode(LogProof{LeafIndex: uint64(idx), AuditPath: path, TreeHead: head})
+}
+
+func TestVerifyInclusion(t *testing.T) {
+	var leaves [][]byte
+	for n := 1; n <= 17; n++ {
+		leaves = append(leaves, []byte{byte(n)})
+		root := merkleRoot(leaves)
+		for m := range leaves {
+			if err := VerifyInclusion(uint64(m), uint64(n), MerkleLeafHash(leaves[m]), merklePath(m, leaves), root); err != nil {
+				t.Fatalf("leaf %d of %d: %v", m, n, err)
+			}
+		}
+	}
+	path := merklePath(3, leaves)
+	if err := VerifyInclusion(3, 17, MerkleLeafHash(leaves[4]), path, merkleRoot(leaves)); err == nil {
+		t.Error("proved the wrong leaf")
+	}
+	if err := VerifyInclusion(3, 17, MerkleLeafHash(leaves[3]), path[:len(path)-1], merkleRoot(leaves)); err == nil {
+		t.Error("accepted a truncated path")
+	}
+	if err := VerifyInclusion(17, 17, MerkleLeafHash(leaves[3]), path, merkleRoot(leaves)); err == nil {
+		t.Error("accepted an index outside the tree")
+	}
+}
+
+func TestLogAnchor(t *testing.T) {
+	pub, priv, _ := GenerateKeyPair()
+	srv := httptest.NewServer(&fakeLog{priv: priv})
+	defer srv.Close()
+	anchor := &LogAnchor{URL: srv.URL, PublicKey: pub}
+	ctx := context.Background()
+
+	var receipts []*AnchorReceipt
+	for i := 1; i <= 5; i++ {
+		r, err := anchor.Anchor(ctx, Checkpoint{Engagement: "eng-1", Sequence: i, ChainHash: strings.Repeat("a", 64), Time: time.Now().UTC()})
+		if err != nil {
+			t.Fatalf("Anchor %d: %v", i, err)
+		}
+		receipts = append(receipts, r)
+	}
+	// Earlier receipts stay valid against the tree heads they carry.
+	for i, r := range receipts {
+		if err := anchor.Verify(ctx, r); err != nil {
+			t.Errorf("receipt %d: %v", i, err)
+		}
+	}
+
+	forged := *receipts[2]
+	forged.Checkpoint.ChainHash = strings.Repeat("b", 64)
+	if err := anchor.Verify(ctx, &forged); err == nil || !strings.Contains(err.Error(), "inclusion proof") {
+		t.Errorf("forged checkpoint: %v", err)
+	}
+	other, _, _ := GenerateKeyPair()
+	if err := (&LogAnchor{PublicKey: other}).Verify(ctx, receipts[0]); err == nil {
+		t.Error("verified a tree head against another log's key")
+	}
+}
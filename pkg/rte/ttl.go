@@ -0,0 +1,209 @@
+package rte
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxTTLExtensionSeconds is the default ValidatorConfig bound on the total
+// time extensions may add to one task.
+const MaxTTLExtensionSeconds = maxTTLSeconds
+
+// errTTLExpired is the cancellation cause when a running task's TTL, as
+// extended, runs out.
+var errTTLExpired = fmt.Errorf("task TTL expired: %w", context.DeadlineExceeded)
+
+// TTLExtension lets a task's approver grant more time to a task that is
+// about to expire, so operators need not re-issue it mid-execution.
+type TTLExtension struct {
+	// ID is unique per extension; an extension applies at most once.
+	ID            string    `json:"id"`
+	Engagement    string    `json:"engagement"`
+	TaskID        string    `json:"task_id"`
+	ExtendSeconds int       `json:"extend_seconds"`
+	ApprovedBy    string    `json:"approved_by"`
+	Reason        string    `json:"reason,omitempty"`
+	IssuedAt      time.Time `json:"issued_at"`
+}
+
+// SignedTTLExtension wraps a TTLExtension with the approver's signature.
+type SignedTTLExtension struct {
+	Extension TTLExtension `json:"extension"`
+	PublicKey []byte       `json:"public_key"`
+	Signature []byte       `json:"signature"`
+}
+
+// SignTTLExtension signs an extension with the approver's key.
+func SignTTLExtension(x TTLExtension, priv ed25519.PrivateKey, pub ed25519.PublicKey) (*SignedTTLExtension, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	if err := x.validate(CurrentValidatorConfig()); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(x)
+	if err != nil {
+		return nil, fmt.Errorf("marshal extension: %w", err)
+	}
+	return &SignedTTLExtension{Extension: x, PublicKey: pub, Signature: ed25519.Sign(priv, payload)}, nil
+}
+
+// VerifyTTLExtension checks that sx is a well-formed extension of st's task,
+// signed by the same approver key that countersigned the task, and that the
+// countersignature verifies. Tasks without an approval countersignature
+// cannot be extended. It does not check who the approver key belongs to;
+// Executor.Extend also requires it to be pinned or enrolled.
+func VerifyTTLExtension(sx *SignedTTLExtension, st *SignedTask) error {
+	if sx == nil {
+		return errors.New("signed extension is nil")
+	}
+	if st == nil {
+		return errors.New("signed task is nil")
+	}
+	if len(sx.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(sx.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	x := sx.Extension
+	if err := x.validate(CurrentValidatorConfig()); err != nil {
+		return err
+	}
+	if x.Engagement != st.Task.Engagement || x.TaskID != st.Task.ID {
+		return fmt.Errorf("extension %s is for %s/%s, not %s/%s", x.ID, x.Engagement, x.TaskID, st.Task.Engagement, st.Task.ID)
+	}
+	if x.ApprovedBy != st.Task.ApprovedBy {
+		return fmt.Errorf("extension approved by %s, task approved by %s", x.ApprovedBy, st.Task.ApprovedBy)
+	}
+	if st.Approval == nil {
+		return errors.New("task has no approval countersignature to extend")
+	}
+	if err := VerifyApproval(st); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadSignature, err)
+	}
+	if !bytes.Equal(sx.PublicKey, st.Approval.PublicKey) {
+		return errors.New("extension was not signed by the task's approver key")
+	}
+	payload, err := json.Marshal(x)
+	if err != nil {
+		return fmt.Errorf("marshal extension: %w", err)
+	}
+	if !ed25519.Verify(sx.PublicKey, payload, sx.Signature) {
+		return errors.New("extension signature verification failed")
+	}
+	return nil
+}
+
+// MaxApprovalSeconds bounds how long after CreatedAt a task's
+// ApprovalExpiresAt may fall.
+const MaxApprovalSeconds = 7 * 24 * 60 * 60
+
+// Expiry returns when the task expires without extensions: the last moment
+// it may start. That is ApprovalExpiresAt if set, otherwise CreatedAt plus
+// the TTL.
+func (t *Task) Expiry() time.Time {
+	if t.ApprovalExpiresAt != nil {
+		return *t.ApprovalExpiresAt
+	}
+	return t.CreatedAt.Add(time.Duration(t.TTLSeconds) * time.Second)
+}
+
+// RunExpiry returns when a run of the task that starts at start must end
+// without extensions: start plus the TTL for a task with
+// ApprovalExpiresAt, otherwise Expiry.
+func (t *Task) RunExpiry(start time.Time) time.Time {
+	if t.ApprovalExpiresAt != nil {
+		return start.Add(time.Duration(t.TTLSeconds) * time.Second)
+	}
+	return t.Expiry()
+}
+
+// EffectiveExpiry verifies each extension against st and returns when a
+// run of the task that started at start must end: its RunExpiry, extended,
+// as Executor.Extend enforces it. start matters only for a task with
+// ApprovalExpiresAt, whose extensions lengthen the run, not the approval
+// window. Extensions apply in IssuedAt order, each only if issued before
+// the expiry it extends; duplicate IDs count once, and the total may not
+// exceed the MaxTTLExtensionSeconds of CurrentValidatorConfig.
+func EffectiveExpiry(st *SignedTask, start time.Time, exts ...*SignedTTLExtension) (time.Time, error) {
+	if st == nil {
+		return time.Time{}, errors.New("signed task is nil")
+	}
+	for _, sx := range exts {
+		if err := VerifyTTLExtension(sx, st); err != nil {
+			return time.Time{}, err
+		}
+	}
+	sorted := append([]*SignedTTLExtension(nil), exts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Extension.IssuedAt.Before(sorted[j].Extension.IssuedAt)
+	})
+	limit := CurrentValidatorConfig().MaxTTLExtensionSeconds
+	expiry := st.Task.RunExpiry(start)
+	seen := make(map[string]struct{}, len(sorted))
+	total := 0
+	for _, sx := range sorted {
+		x := sx.Extension
+		if _, ok := seen[x.ID]; ok {
+			continue
+		}
+		seen[x.ID] = struct{}{}
+		if !x.IssuedAt.Before(expiry) {
+			return time.Time{}, fmt.Errorf("extension %s issued after the task expired at %s", x.ID, expiry.UTC().Format(time.RFC3339))
+		}
+		if total += x.ExtendSeconds; total > limit {
+			return time.Time{}, fmt.Errorf("extensions total %ds, more than the %ds allowed", total, limit)
+		}
+		expiry = expiry.Add(time.Duration(x.ExtendSeconds) * time.Second)
+	}
+	return expiry, nil
+}
+
+// VerifyTaskExtended is VerifyTask for a run, started at start, whose TTL
+// has been extended: it makes the same envelope checks and validates the
+// task against the run's effective expiry. A task with ApprovalExpiresAt
+// must have started before its approval window closed.
+func VerifyTaskExtended(st *SignedTask, start time.Time, exts ...*SignedTTLExtension) error {
+	if err := verifyTaskEnvelope(st); err != nil {
+		return err
+	}
+	c := CurrentValidatorConfig()
+	if w := st.Task.Expiry(); !start.Before(w.Add(c.ClockSkew)) {
+		return fmt.Errorf("%w: started %s, after the approval window closed at %s", ErrExpired,
+			start.UTC().Format(time.RFC3339), w.UTC().Format(time.RFC3339))
+	}
+	expiry, err := EffectiveExpiry(st, start, exts...)
+	if err != nil {
+		return err
+	}
+	return st.Task.validateUntil(time.Now().UTC(), expiry, c)
+}
+
+func (x TTLExtension) validate(c ValidatorConfig) error {
+	if x.ID == "" {
+		return errors.New("extension ID is required")
+	}
+	if x.Engagement == "" || x.TaskID == "" {
+		return errors.New("engagement and task_id are required")
+	}
+	if x.ApprovedBy == "" {
+		return errors.New("approved_by is required")
+	}
+	if x.ExtendSeconds < minTTLSeconds || x.ExtendSeconds > c.MaxTTLExtensionSeconds {
+		return fmt.Errorf("extend_seconds must be between %d and %d, got %d", minTTLSeconds, c.MaxTTLExtensionSeconds, x.ExtendSeconds)
+	}
+	if x.IssuedAt.IsZero() {
+		return errors.New("issued_at is required")
+	}
+	return nil
+}
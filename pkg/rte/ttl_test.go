@@ -0,0 +1,209 @@
+package rte
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func extension(t *testing.T, id string, secs int, issued time.Time, k keyPair) *SignedTTLExtension {
+	t.Helper()
+	sx, err := SignTTLExtension(TTLExtension{
+		ID: id, Engagement: "eng-2026-q1", TaskID: "task-001", ExtendSeconds: secs,
+		ApprovedBy: "lead-bob", Reason: "spray still in progress", IssuedAt: issued,
+	}, k.priv, k.pub)
+	if err != nil {
+		t.Fatalf("SignTTLExtension: %v", err)
+	}
+	return sx
+}
+
+func TestEffectiveExpiry(t *testing.T) {
+	_, op, lead := enrolled(t)
+	now := time.Now().UTC()
+	st := signAndApprove(t, validTask(now.Add(-9*time.Minute)), op, lead)
+	base, start := st.Task.Expiry(), st.Task.CreatedAt
+
+	x1 := extension(t, "ext-1", 600, now, lead)
+	got, err := EffectiveExpiry(st, start, x1, x1)
+	if err != nil {
+		t.Fatalf("EffectiveExpiry: %v", err)
+	}
+	if want := base.Add(10 * time.Minute); !got.Equal(want) {
+		t.Fatalf("expiry: got %s, want %s", got, want)
+	}
+
+	// The first extension keeps the task alive, so the second, issued after
+	// the original expiry, still applies.
+	x2 := extension(t, "ext-2", 300, base.Add(time.Minute), lead)
+	if got, err = EffectiveExpiry(st, start, x2, x1); err != nil || !got.Equal(base.Add(15*time.Minute)) {
+		t.Fatalf("chained extensions: got %s, %v", got, err)
+	}
+	if _, err := EffectiveExpiry(st, start, x2); err == nil || !strings.Contains(err.Error(), "after the task expired") {
+		t.Errorf("expected late extension to fail, got %v", err)
+	}
+	if _, err := EffectiveExpiry(st, start, extension(t, "ext-3", 600, now, op)); err == nil {
+		t.Error("expected extension signed by the operator to fail")
+	}
+	over := []*SignedTTLExtension{
+		extension(t, "a", MaxTTLExtensionSeconds, now, lead), extension(t, "b", 1, now, lead),
+	}
+	if _, err := EffectiveExpiry(st, start, over...); err == nil {
+		t.Error("expected extensions over the bound to fail")
+	}
+	if _, err := SignTTLExtension(TTLExtension{ID: "x", Engagement: "e", TaskID: "t", ApprovedBy: "b",
+		ExtendSeconds: MaxTTLExtensionSeconds + 1, IssuedAt: now}, lead.priv, lead.pub); err == nil {
+		t.Error("expected oversized extension to fail")
+	}
+	if err := SetValidatorConfig(ValidatorConfig{MaxTTLExtensionSeconds: 300}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = EffectiveExpiry(st, start, x1)
+	SetValidatorConfig(ValidatorConfig{})
+	if err == nil {
+		t.Error("expected extension over the configured bound to fail")
+	}
+
+	unapproved, _ := SignTask(validTask(now), op.priv, op.pub)
+	if err := VerifyTTLExtension(x1, unapproved); err == nil {
+		t.Error("expected task without approval to refuse extensions")
+	}
+	forged := *st
+	forged.Approval = &Approval{PublicKey: lead.pub, Signature: make([]byte, 64)}
+	if err := VerifyTTLExtension(x1, &forged); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("extension of a task with a forged approval: got %v", err)
+	}
+
+	// A task with an approval window is extended from its run's expiry.
+	task := validTask(now.Add(-time.Hour))
+	window := now.Add(time.Hour)
+	task.ApprovalExpiresAt = &window
+	windowed := signAndApprove(t, task, op, lead)
+	started := now.Add(-5 * time.Minute)
+	if got, err := EffectiveExpiry(windowed, started, x1); err != nil || !got.Equal(started.Add(20*time.Minute)) {
+		t.Errorf("extended run of a windowed task: got %s, %v", got, err)
+	}
+}
+
+func TestVerifyTaskExtended(t *testing.T) {
+	_, op, lead := enrolled(t)
+	now := time.Now().UTC()
+	// Sign directly: SignTask refuses tasks that have already expired.
+	task := validTask(now.Add(-11 * time.Minute))
+	payload, err := json.Marshal(task)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	st := &SignedTask{Task: task, PublicKey: op.pub, Signature: ed25519.Sign(op.priv, payload)}
+	if err := Countersign(st, lead.priv, lead.pub); err != nil {
+		t.Fatalf("Countersign: %v", err)
+	}
+	if err := VerifyTask(st); err == nil {
+		t.Fatal("expected task to have expired without the extension")
+	}
+	x := extension(t, "ext-1", 600, now.Add(-90*time.Second), lead)
+	if err := VerifyTaskExtended(st, task.CreatedAt, x); err != nil {
+		t.Fatalf("VerifyTaskExtended: %v", err)
+	}
+	future := *st
+	future.Task.SchemaVersion = TaskSchemaVersion + 1
+	if err := VerifyTaskExtended(&future, task.CreatedAt, x); err == nil {
+		t.Error("expected an unknown schema version to fail")
+	}
+}
+
+func TestExecutor_Extend(t *testing.T) {
+	reg, op, lead := enrolled(t)
+	e := NewExecutor()
+	e.Identities = reg
+	started := make(chan struct{})
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(ctx context.Context, _ Task) (any, error) {
+		close(started)
+		select {
+		case <-time.After(800 * time.Millisecond):
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}))
+	now := time.Now().UTC()
+	task := validTask(now.Add(-time.Second + 300*time.Millisecond))
+	task.TTLSeconds = 1
+	st := signAndApprove(t, task, op, lead)
+
+	done := make(chan *TaskResult, 1)
+	go func() {
+		res, err := e.Execute(context.Background(), st)
+		if err != nil {
+			t.Errorf("Execute: %v", err)
+		}
+		done <- res
+	}()
+	<-started
+	if _, err := e.Extend(extension(t, "ext-1", 2, now, op)); err == nil {
+		t.Fatal("expected extension signed by the operator to be refused")
+	}
+	x := extension(t, "ext-1", 2, now, lead)
+	e.Identities = nil
+	if _, err := e.Extend(x); err == nil || !strings.Contains(err.Error(), "not pinned or enrolled") {
+		t.Fatalf("extension by an approver no one vouches for: got %v", err)
+	}
+	e.Identities = reg
+	expiry, err := e.Extend(x)
+	if err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	if again, err := e.Extend(x); err != nil || !again.Equal(expiry) {
+		t.Fatalf("replayed extension: got %s, %v", again, err)
+	}
+	res := <-done
+	if res == nil || res.State != StateCompleted {
+		t.Fatalf("expected extended task to complete, got %+v", res)
+	}
+	if _, err := e.Extend(x); err == nil {
+		t.Error("expected extension of a finished task to fail")
+	}
+}
+
+func TestExecutor_ApprovalWindow(t *testing.T) {
+	reg, op, lead := enrolled(t)
+	e := NewExecutor()
+	e.Identities = reg
+	e.Deconfliction = NewDeconflictionRegistry()
+	var end time.Time
+	_ = e.Register(TaskSimulateLogin, HandlerFunc(func(context.Context, Task) (any, error) {
+		acts, err := e.Deconfliction.Touched("192.168.1.5", time.Now(), 0)
+		if err == nil && len(acts) == 1 {
+			end = acts[0].End
+		}
+		return nil, err
+	}))
+	now := time.Now().UTC()
+	task := validTask(now.Add(-3 * time.Hour))
+	approval := now.Add(69 * time.Hour)
+	task.ApprovalExpiresAt = &approval
+	// Without a trusted approval the operator cannot widen the window on
+	// their own.
+	unapproved, err := SignTask(task, op.priv, op.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Execute(context.Background(), unapproved); !errors.Is(err, ErrBadApprovalExpiry) {
+		t.Fatalf("unapproved task with an approval window: got %v", err)
+	}
+	if _, err := e.Execute(context.Background(), signAndApprove(t, task, op, newKeyPair(t))); !errors.Is(err, ErrBadApprovalExpiry) {
+		t.Fatalf("approval window approved by an unenrolled key: got %v", err)
+	}
+	res, err := e.Execute(context.Background(), signAndApprove(t, task, op, lead))
+	if err != nil || res.State != StateCompleted {
+		t.Fatalf("Execute: %+v, %v", res, err)
+	}
+	// The run is bounded by the TTL from its start, not by the approval.
+	if want := res.StartedAt.Add(10 * time.Minute); !end.Equal(want) {
+		t.Fatalf("run window ends %s, want %s", end, want)
+	}
+}
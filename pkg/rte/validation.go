@@ -0,0 +1,54 @@
+package rte
+
+import (
+	"errors"
+	"strings"
+)
+
+// Validation failures reported by Task.Validate. Each is wrapped with the
+// offending value, so test with errors.Is.
+var (
+	ErrMissingID         = errors.New("task ID is required")
+	ErrMissingEngagement = errors.New("engagement is required")
+	ErrMissingOperator   = errors.New("operator is required")
+	ErrMissingApprover   = errors.New("approved_by is required")
+	ErrUnsupportedType   = errors.New("unsupported task type")
+	ErrBadTTL            = errors.New("TTL out of range")
+	ErrInvalidState      = errors.New("invalid task state")
+	ErrBadPriority       = errors.New("priority out of range")
+	ErrBadTechnique      = errors.New("technique rejected")
+	ErrExpired           = errors.New("task expired")
+	ErrNotYetValid       = errors.New("task not yet valid")
+	ErrBadNotBefore      = errors.New("not_before out of range")
+	ErrBadApprovalExpiry = errors.New("approval_expires_at out of range")
+	ErrBadClassification = errors.New("classification rejected")
+	ErrBadSelector       = errors.New("selector rejected")
+	ErrBadIdempotencyKey = errors.New("idempotency key rejected")
+	ErrBadLineage        = errors.New("lineage rejected")
+	ErrBadPhase          = errors.New("phase rejected")
+)
+
+// ValidationErrors is every problem Validate found with a task, in field
+// order. errors.Is and errors.As see through it to each problem.
+type ValidationErrors []error
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, err := range v {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual problems.
+func (v ValidationErrors) Unwrap() []error {
+	return v
+}
+
+// err returns v as an error, or nil if there were no problems.
+func (v ValidationErrors) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
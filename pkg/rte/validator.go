@@ -0,0 +1,152 @@
+package rte
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ValidatorConfig holds the bounds Task.Validate holds tasks to, and
+// extensions of them. A zero field takes its value from
+// DefaultValidatorConfig, so a deployment sets only the bounds it changes.
+// For example, a lab allowing day-long tasks:
+//
+//	{"max_ttl_seconds": 86400, "max_standing_ttl_seconds": 1209600}
+type ValidatorConfig struct {
+	MinTTLSeconds int `json:"min_ttl_seconds,omitempty"`
+	MaxTTLSeconds int `json:"max_ttl_seconds,omitempty"`
+	// MaxStandingTTLSeconds is MaxTTLSeconds for standing tasks.
+	MaxStandingTTLSeconds int `json:"max_standing_ttl_seconds,omitempty"`
+	// MaxApprovalSeconds bounds how long after CreatedAt a task's
+	// ApprovalExpiresAt may fall.
+	MaxApprovalSeconds int `json:"max_approval_seconds,omitempty"`
+	// MaxTTLExtensionSeconds bounds the total time TTL extensions may add
+	// to one task, on top of its signed TTL.
+	MaxTTLExtensionSeconds int `json:"max_ttl_extension_seconds,omitempty"`
+	// ClockSkew is the tolerance allowed when comparing a task's times
+	// with the local clock: a task stays valid until ClockSkew past its
+	// expiry, and may be created or start up to ClockSkew in the future.
+	// It may not exceed MaxClockSkew.
+	ClockSkew time.Duration `json:"clock_skew_ns,omitempty"`
+	// RequireApproval makes VerifyTaskWith refuse tasks without an
+	// approval countersignature. VerifyTask ignores it, since controllers
+	// verify tasks before they are approved.
+	RequireApproval bool `json:"require_approval,omitempty"`
+}
+
+// DefaultValidatorConfig returns the bounds tasks are held to unless a
+// deployment changes them.
+func DefaultValidatorConfig() ValidatorConfig {
+	return ValidatorConfig{
+		MinTTLSeconds:          minTTLSeconds,
+		MaxTTLSeconds:          maxTTLSeconds,
+		MaxStandingTTLSeconds:  MaxStandingTTLSeconds,
+		MaxApprovalSeconds:     MaxApprovalSeconds,
+		MaxTTLExtensionSeconds: MaxTTLExtensionSeconds,
+		ClockSkew:              DefaultClockSkew,
+	}
+}
+
+// withDefaults fills c's zero fields from DefaultValidatorConfig.
+func (c ValidatorConfig) withDefaults() ValidatorConfig {
+	d := DefaultValidatorConfig()
+	if c.MinTTLSeconds == 0 {
+		c.MinTTLSeconds = d.MinTTLSeconds
+	}
+	if c.MaxTTLSeconds == 0 {
+		c.MaxTTLSeconds = d.MaxTTLSeconds
+	}
+	if c.MaxStandingTTLSeconds == 0 {
+		c.MaxStandingTTLSeconds = d.MaxStandingTTLSeconds
+	}
+	if c.MaxApprovalSeconds == 0 {
+		c.MaxApprovalSeconds = d.MaxApprovalSeconds
+	}
+	if c.MaxTTLExtensionSeconds == 0 {
+		c.MaxTTLExtensionSeconds = d.MaxTTLExtensionSeconds
+	}
+	if c.ClockSkew == 0 {
+		c.ClockSkew = d.ClockSkew
+	}
+	return c
+}
+
+// Validate checks that the bounds, with defaults filled in, are ordered:
+// standing tasks may run at least as long as ordinary ones, and clocks may
+// drift by at most MaxClockSkew.
+func (c ValidatorConfig) Validate() error {
+	if c.MinTTLSeconds < 0 || c.MaxTTLSeconds < 0 || c.MaxStandingTTLSeconds < 0 || c.MaxApprovalSeconds < 0 ||
+		c.MaxTTLExtensionSeconds < 0 || c.ClockSkew < 0 {
+		return errors.New("validator bounds must not be negative")
+	}
+	if c.ClockSkew > MaxClockSkew {
+		return fmt.Errorf("clock_skew_ns %s is over %s", c.ClockSkew, MaxClockSkew)
+	}
+	c = c.withDefaults()
+	if c.MinTTLSeconds > c.MaxTTLSeconds {
+		return fmt.Errorf("min_ttl_seconds %d is over max_ttl_seconds %d", c.MinTTLSeconds, c.MaxTTLSeconds)
+	}
+	if c.MaxStandingTTLSeconds < c.MaxTTLSeconds {
+		return fmt.Errorf("max_standing_ttl_seconds %d is under max_ttl_seconds %d", c.MaxStandingTTLSeconds, c.MaxTTLSeconds)
+	}
+	return nil
+}
+
+const (
+	// DefaultClockSkew is the tolerance ValidatorConfig allows by default
+	// for drift between the controller's and an agent's clocks.
+	DefaultClockSkew = 5 * time.Second
+	// MaxClockSkew bounds ValidatorConfig.ClockSkew; a wider window would
+	// let expired tasks run for longer than operators expect.
+	MaxClockSkew = 5 * time.Minute
+)
+
+var validatorConfig atomic.Pointer[ValidatorConfig]
+
+// CurrentValidatorConfig returns the process-wide bounds Validate and
+// VerifyTask use, with defaults filled in.
+func CurrentValidatorConfig() ValidatorConfig {
+	if c := validatorConfig.Load(); c != nil {
+		return *c
+	}
+	return DefaultValidatorConfig()
+}
+
+// SetValidatorConfig sets the process-wide bounds returned by
+// CurrentValidatorConfig, which signing, Validate, and VerifyTask use. Set
+// it once at startup, before any task is signed or verified; to check
+// tasks against other bounds alongside, use ValidateWith or
+// VerifyTaskWith.
+func SetValidatorConfig(c ValidatorConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	c = c.withDefaults()
+	validatorConfig.Store(&c)
+	return nil
+}
+
+// ValidateWith is Validate against c's bounds rather than the
+// process-wide ones.
+func (t *Task) ValidateWith(now time.Time, c ValidatorConfig) error {
+	if t == nil {
+		return errors.New("task is nil")
+	}
+	return t.validateUntil(now, t.Expiry(), c.withDefaults())
+}
+
+// VerifyTaskWith is VerifyTask against c's bounds rather than the
+// process-wide ones. It also verifies the approval countersignature when
+// the task carries one or c requires one.
+func VerifyTaskWith(st *SignedTask, c ValidatorConfig) error {
+	if err := verifyTaskEnvelope(st); err != nil {
+		return err
+	}
+	if st.Approval != nil || c.RequireApproval {
+		if err := VerifyApproval(st); err != nil {
+			return fmt.Errorf("%w: %w", ErrBadSignature, err)
+		}
+	}
+	return st.Task.ValidateWith(time.Now().UTC(), c)
+}
@@ -0,0 +1,68 @@
+package rte
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidatorConfig_Validate(t *testing.T) {
+	for _, c := range []ValidatorConfig{
+		{MinTTLSeconds: -1},
+		{MinTTLSeconds: 7200},
+		{MaxTTLSeconds: 30 * 24 * 60 * 60},
+		{MaxTTLExtensionSeconds: -1},
+		{ClockSkew: -time.Second},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v: expected error", c)
+		}
+	}
+	if err := (ValidatorConfig{}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTask_ValidateWith(t *testing.T) {
+	now := time.Now().UTC()
+	task := validTask(now)
+	task.TTLSeconds = 6 * 60 * 60
+	if err := task.Validate(now); !errors.Is(err, ErrBadTTL) {
+		t.Fatalf("six-hour TTL under the defaults: got %v", err)
+	}
+	lab := ValidatorConfig{MaxTTLSeconds: 24 * 60 * 60}
+	if err := task.ValidateWith(now, lab); err != nil {
+		t.Fatalf("six-hour TTL in the lab: %v", err)
+	}
+	prod := ValidatorConfig{MinTTLSeconds: 60, MaxTTLSeconds: 900}
+	task.TTLSeconds = 30
+	if err := task.ValidateWith(now, prod); !errors.Is(err, ErrBadTTL) {
+		t.Fatalf("30s TTL in production: got %v", err)
+	}
+}
+
+func TestSetValidatorConfig(t *testing.T) {
+	defer SetValidatorConfig(ValidatorConfig{})
+	if err := SetValidatorConfig(ValidatorConfig{MinTTLSeconds: 7200}); err == nil {
+		t.Fatal("accepted min over max")
+	}
+	if got := CurrentValidatorConfig(); got != DefaultValidatorConfig() {
+		t.Fatalf("rejected config applied: %+v", got)
+	}
+	if err := SetValidatorConfig(ValidatorConfig{MaxTTLSeconds: 24 * 60 * 60}); err != nil {
+		t.Fatal(err)
+	}
+	task := validTask(time.Now().UTC())
+	task.TTLSeconds = 6 * 60 * 60
+	pub, priv, _ := GenerateKeyPair()
+	st, err := SignTask(task, priv, pub)
+	if err != nil {
+		t.Fatalf("sign under raised bounds: %v", err)
+	}
+	if err := VerifyTask(st); err != nil {
+		t.Fatalf("verify under raised bounds: %v", err)
+	}
+	if err := VerifyTaskWith(st, DefaultValidatorConfig()); !errors.Is(err, ErrBadTTL) {
+		t.Fatalf("verify against the defaults: got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package rte
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// WatchBuffer is how many events a watcher of a MemoryStore or FileStore
+// may fall behind before the store gives up on it. Other stores buffer as
+// they document; sqlstore's watchers are unbuffered and are never given up
+// on.
+const WatchBuffer = 64
+
+// TaskEventKind says how a record changed.
+type TaskEventKind string
+
+// Task event kinds.
+const (
+	EventCreated TaskEventKind = "created"
+	EventUpdated TaskEventKind = "updated"
+)
+
+// TaskEvent is a change to a stored task record: the record as stored by
+// the change.
+type TaskEvent struct {
+	Kind   TaskEventKind `json:"kind"`
+	Record TaskRecord    `json:"record"`
+}
+
+// WatchFilter selects the records a watch reports changes to. Empty fields
+// match every record.
+type WatchFilter struct {
+	Engagement string   `json:"engagement,omitempty"`
+	IDs        []string `json:"ids,omitempty"`
+	// States keeps changes that leave a record in one of these states.
+	States []TaskState `json:"states,omitempty"`
+}
+
+// Match reports whether the filter selects rec.
+func (f WatchFilter) Match(rec TaskRecord) bool {
+	return (f.Engagement == "" || rec.Engagement() == f.Engagement) &&
+		(len(f.IDs) == 0 || slices.Contains(f.IDs, rec.ID())) &&
+		(len(f.States) == 0 || slices.Contains(f.States, rec.State))
+}
+
+// watchHub fans a store's changes out to its watchers. Publishing never
+// blocks: a watcher whose buffer is full is closed, and must List and
+// Watch again to catch up.
+type watchHub struct {
+	mu   sync.Mutex
+	subs map[*watcher]struct{}
+}
+
+type watcher struct {
+	f  WatchFilter
+	ch chan TaskEvent
+}
+
+func (h *watchHub) watch(ctx context.Context, f WatchFilter) (<-chan TaskEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w := &watcher{f: f, ch: make(chan TaskEvent, WatchBuffer)}
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = make(map[*watcher]struct{})
+	}
+	h.subs[w] = struct{}{}
+	h.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		h.drop(w)
+		h.mu.Unlock()
+	}()
+	return w.ch, nil
+}
+
+func (h *watchHub) publish(kind TaskEventKind, recs ...TaskRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.subs {
+		for _, rec := range recs {
+			if !w.f.Match(rec) {
+				continue
+			}
+			select {
+			case w.ch <- TaskEvent{Kind: kind, Record: rec}:
+			default:
+				h.drop(w)
+			}
+			if _, ok := h.subs[w]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// drop closes w's channel once. The caller holds h.mu.
+func (h *watchHub) drop(w *watcher) {
+	if _, ok := h.subs[w]; ok {
+		delete(h.subs, w)
+		close(w.ch)
+	}
+}
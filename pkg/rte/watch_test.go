@@ -0,0 +1,93 @@
+package rte
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, ch <-chan TaskEvent) TaskEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("watch closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return TaskEvent{}
+}
+
+func TestWatch_Stores(t *testing.T) {
+	file, err := OpenFileStore(filepath.Join(t.TempDir(), "tasks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range map[string]BatchStore{"memory": NewMemoryStore(), "file": file} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			now := time.Now().UTC()
+			all, err := s.Watch(ctx, WatchFilter{Engagement: "eng-2026-q1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			done, _ := s.Watch(ctx, WatchFilter{States: []TaskState{StateCompleted}})
+			if err := s.Put(ctx, storedTask(t, "t-1", now, StatePending)); err != nil {
+				t.Fatal(err)
+			}
+			if ev := nextEvent(t, all); ev.Kind != EventCreated || ev.Record.ID() != "t-1" {
+				t.Errorf("put event = %+v", ev)
+			}
+			if err := s.Update(ctx, "eng-2026-q1", "t-1", func(r *TaskRecord) error { r.State = StateExecuting; return nil }); err != nil {
+				t.Fatal(err)
+			}
+			if ev := nextEvent(t, all); ev.Kind != EventUpdated || ev.Record.State != StateExecuting {
+				t.Errorf("update event = %+v", ev)
+			}
+			if _, err := s.UpdateWhere(ctx, "eng-2026-q1", func(TaskRecord) bool { return true }, func(r *TaskRecord) error {
+				r.State = StateCompleted
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			nextEvent(t, all)
+			if ev := nextEvent(t, done); ev.Record.State != StateCompleted {
+				t.Errorf("filtered watch got %+v", ev)
+			}
+			cancel()
+			for range all {
+			}
+		})
+	}
+}
+
+func TestWatch_SlowWatcherClosed(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	slow, _ := s.Watch(ctx, WatchFilter{})
+	now := time.Now().UTC()
+	if err := s.Put(ctx, storedTask(t, "t-1", now, StatePending)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < WatchBuffer; i++ {
+		if err := s.Update(ctx, "eng-2026-q1", "t-1", func(r *TaskRecord) error { r.UpdatedAt = now; return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != WatchBuffer {
+		t.Errorf("slow watcher got %d events before closing, want %d", n, WatchBuffer)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := s.Watch(cancelled, WatchFilter{}); err == nil {
+		t.Error("Watch with an ended context succeeded")
+	}
+}
@@ -0,0 +1,286 @@
+package rtepb
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// formatTime renders t exactly as encoding/json does, so the signed JSON
+// payload survives a protobuf round trip byte for byte.
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(field, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", field, err)
+	}
+	return t, nil
+}
+
+func int32Of(field string, v int) (int32, error) {
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return 0, fmt.Errorf("%s %d does not fit in int32", field, v)
+	}
+	return int32(v), nil
+}
+
+// FromTask converts a task to its protobuf form. It fails only for integer
+// fields beyond int32, which no valid task has.
+func FromTask(t rte.Task) (*Task, error) {
+	m := &Task{
+		Id:             t.ID,
+		Engagement:     t.Engagement,
+		Type:           string(t.Type),
+		CreatedAt:      formatTime(t.CreatedAt),
+		Operator:       t.Operator,
+		ApprovedBy:     t.ApprovedBy,
+		State:          string(t.State),
+		CancelToken:    t.CancelToken,
+		Params:         t.Params,
+		Techniques:     t.Techniques,
+		Classification: string(t.Classification),
+		Selector:       t.Selector,
+		DryRun:         t.DryRun,
+		IdempotencyKey: t.IdempotencyKey,
+		ReissuedFromId: t.ReissuedFromID,
+		SupersedesId:   t.SupersedesID,
+		Phase:          string(t.Phase),
+		CleanupOf:      t.CleanupOf,
+		Standing:       t.Standing,
+	}
+	var err error
+	if m.TtlSeconds, err = int32Of("ttl_seconds", t.TTLSeconds); err != nil {
+		return nil, err
+	}
+	if m.Priority, err = int32Of("priority", t.Priority); err != nil {
+		return nil, err
+	}
+	if m.SchemaVersion, err = int32Of("schema_version", t.SchemaVersion); err != nil {
+		return nil, err
+	}
+	for _, d := range t.ExpectedDetections {
+		m.ExpectedDetections = append(m.ExpectedDetections, &ExpectedDetection{Technique: d.Technique, Rule: d.Rule, Query: d.Query})
+	}
+	if p := t.Provenance; p != nil {
+		m.Provenance = &Provenance{Commit: p.Commit, Branch: p.Branch, Signer: p.Signer}
+	}
+	if t.NotBefore != nil {
+		m.NotBefore = formatTime(*t.NotBefore)
+	}
+	if t.ApprovalExpiresAt != nil {
+		m.ApprovalExpiresAt = formatTime(*t.ApprovalExpiresAt)
+	}
+	return m, nil
+}
+
+// ToTask converts a protobuf task back to an rte.Task.
+func (m *Task) ToTask() (rte.Task, error) {
+	if m == nil {
+		return rte.Task{}, fmt.Errorf("task is nil")
+	}
+	created, err := parseTime("created_at", m.CreatedAt)
+	if err != nil {
+		return rte.Task{}, err
+	}
+	t := rte.Task{
+		SchemaVersion:  int(m.SchemaVersion),
+		ID:             m.Id,
+		Engagement:     m.Engagement,
+		Type:           rte.TaskType(m.Type),
+		CreatedAt:      created,
+		TTLSeconds:     int(m.TtlSeconds),
+		Operator:       m.Operator,
+		ApprovedBy:     m.ApprovedBy,
+		State:          rte.TaskState(m.State),
+		CancelToken:    m.CancelToken,
+		Params:         m.Params,
+		Priority:       int(m.Priority),
+		Techniques:     m.Techniques,
+		Classification: rte.Classification(m.Classification),
+		Selector:       m.Selector,
+		DryRun:         m.DryRun,
+		IdempotencyKey: m.IdempotencyKey,
+		ReissuedFromID: m.ReissuedFromId,
+		SupersedesID:   m.SupersedesId,
+		Phase:          rte.Phase(m.Phase),
+		CleanupOf:      m.CleanupOf,
+		Standing:       m.Standing,
+	}
+	for _, d := range m.ExpectedDetections {
+		if d == nil {
+			continue
+		}
+		t.ExpectedDetections = append(t.ExpectedDetections, rte.ExpectedDetection{Technique: d.Technique, Rule: d.Rule, Query: d.Query})
+	}
+	if p := m.Provenance; p != nil {
+		t.Provenance = &rte.Provenance{Commit: p.Commit, Branch: p.Branch, Signer: p.Signer}
+	}
+	if m.NotBefore != "" {
+		nb, err := parseTime("not_before", m.NotBefore)
+		if err != nil {
+			return rte.Task{}, err
+		}
+		t.NotBefore = &nb
+	}
+	if m.ApprovalExpiresAt != "" {
+		ae, err := parseTime("approval_expires_at", m.ApprovalExpiresAt)
+		if err != nil {
+			return rte.Task{}, err
+		}
+		t.ApprovalExpiresAt = &ae
+	}
+	return t, nil
+}
+
+// FromSignedTask converts a signed task to its protobuf form.
+func FromSignedTask(st *rte.SignedTask) (*SignedTask, error) {
+	if st == nil {
+		return nil, fmt.Errorf("signed task is nil")
+	}
+	task, err := FromTask(st.Task)
+	if err != nil {
+		return nil, err
+	}
+	m := &SignedTask{Task: task, PublicKey: st.PublicKey, Signature: st.Signature, Trace: st.Trace, Digest: st.Digest, Certificates: st.Certificates, Timestamp: st.Timestamp}
+	if st.Approval != nil {
+		m.Approval = &Approval{PublicKey: st.Approval.PublicKey, Signature: st.Approval.Signature}
+	}
+	if st.Legacy != nil {
+		v, err := int32Of("legacy schema_version", st.Legacy.SchemaVersion)
+		if err != nil {
+			return nil, err
+		}
+		m.Legacy = &LegacyTask{SchemaVersion: v, Task: st.Legacy.Task}
+	}
+	for _, a := range st.Attestations {
+		env := &Envelope{PayloadType: a.PayloadType, Payload: a.Payload}
+		for _, s := range a.Signatures {
+			env.Signatures = append(env.Signatures, &EnvelopeSignature{Keyid: s.KeyID, Sig: s.Sig})
+		}
+		m.Attestations = append(m.Attestations, env)
+	}
+	return m, nil
+}
+
+// ToSignedTask converts a protobuf signed task back to an rte.SignedTask.
+// The result verifies exactly when the original did.
+func (m *SignedTask) ToSignedTask() (*rte.SignedTask, error) {
+	if m == nil {
+		return nil, fmt.Errorf("signed task is nil")
+	}
+	task, err := m.Task.ToTask()
+	if err != nil {
+		return nil, err
+	}
+	st := &rte.SignedTask{Task: task, PublicKey: m.PublicKey, Signature: m.Signature, Trace: m.Trace, Digest: m.Digest, Certificates: m.Certificates, Timestamp: m.Timestamp}
+	if m.Approval != nil {
+		st.Approval = &rte.Approval{PublicKey: m.Approval.PublicKey, Signature: m.Approval.Signature}
+	}
+	if m.Legacy != nil {
+		st.Legacy = &rte.LegacyTask{SchemaVersion: int(m.Legacy.SchemaVersion), Task: json.RawMessage(m.Legacy.Task)}
+	}
+	for _, a := range m.Attestations {
+		if a == nil {
+			continue
+		}
+		env := rte.Envelope{PayloadType: a.PayloadType, Payload: a.Payload}
+		for _, s := range a.Signatures {
+			if s != nil {
+				env.Signatures = append(env.Signatures, rte.EnvelopeSignature{KeyID: s.Keyid, Sig: s.Sig})
+			}
+		}
+		st.Attestations = append(st.Attestations, env)
+	}
+	return st, nil
+}
+
+// FromTaskResult converts a task result to its protobuf form.
+func FromTaskResult(r *rte.TaskResult) *TaskResult {
+	return &TaskResult{
+		TaskId:     r.TaskID,
+		Engagement: r.Engagement,
+		Type:       string(r.Type),
+		Operator:   r.Operator,
+		State:      string(r.State),
+		StartedAt:  formatTime(r.StartedAt),
+		FinishedAt: formatTime(r.FinishedAt),
+		Output:     r.Output,
+		Error:      r.Error,
+	}
+}
+
+// ToTaskResult converts a protobuf result back to an rte.TaskResult.
+func (m *TaskResult) ToTaskResult() (*rte.TaskResult, error) {
+	started, err := parseTime("started_at", m.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	finished, err := parseTime("finished_at", m.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rte.TaskResult{
+		TaskID:     m.TaskId,
+		Engagement: m.Engagement,
+		Type:       rte.TaskType(m.Type),
+		Operator:   m.Operator,
+		State:      rte.TaskState(m.State),
+		StartedAt:  started,
+		FinishedAt: finished,
+		Output:     json.RawMessage(m.Output),
+		Error:      m.Error,
+	}, nil
+}
+
+// FromTaskCancel converts a cancel request to its protobuf form.
+func FromTaskCancel(c rte.TaskCancel) *CancelRequest {
+	return &CancelRequest{Engagement: c.Engagement, TaskId: c.TaskID, Token: c.Token, RequestedBy: c.RequestedBy}
+}
+
+// ToTaskCancel converts a protobuf cancel request back to an rte.TaskCancel.
+func (m *CancelRequest) ToTaskCancel() rte.TaskCancel {
+	return rte.TaskCancel{Engagement: m.Engagement, TaskID: m.TaskId, Token: m.Token, RequestedBy: m.RequestedBy}
+}
+
+// FromDenialError converts a denial to its protobuf form.
+func FromDenialError(e *rte.DenialError) *DenialError {
+	m := &DenialError{TaskId: e.TaskID}
+	for _, d := range e.Denials {
+		m.Denials = append(m.Denials, &Denial{
+			Code:        string(d.Code),
+			Field:       d.Field,
+			Message:     d.Message,
+			Rule:        d.Rule,
+			Source:      d.Source,
+			Remediation: d.Remediation,
+		})
+	}
+	return m
+}
+
+// ToDenialError converts a protobuf denial back to an *rte.DenialError.
+func (m *DenialError) ToDenialError() *rte.DenialError {
+	e := &rte.DenialError{TaskID: m.TaskId}
+	for _, d := range m.Denials {
+		e.Denials = append(e.Denials, rte.Denial{
+			Code: rte.DenialCode(d.Code),
+			Violation: rte.Violation{
+				Rule:        d.Rule,
+				Field:       d.Field,
+				Source:      d.Source,
+				Message:     d.Message,
+				Remediation: d.Remediation,
+			},
+		})
+	}
+	return e
+}
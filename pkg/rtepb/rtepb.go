@@ -0,0 +1,705 @@
+// Package rtepb holds protobuf encodings of the RTE-A tasking messages
+// defined in rte.proto, with converters to and from the rte types. The
+// converters are lossless for everything a signature covers, so a task
+// carried over protobuf still verifies against its JSON signature.
+package rtepb
+
+import "fmt"
+
+// ExpectedDetection mirrors rte.ExpectedDetection.
+type ExpectedDetection struct {
+	Technique string
+	Rule      string
+	Query     string
+}
+
+func (m *ExpectedDetection) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.Technique)
+	e.string(2, m.Rule)
+	e.string(3, m.Query)
+	return e.b
+}
+
+func (m *ExpectedDetection) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.Technique, err = d.stringField(wire)
+		case 2:
+			m.Rule, err = d.stringField(wire)
+		case 3:
+			m.Query, err = d.stringField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("ExpectedDetection field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// Task mirrors rte.Task.
+type Task struct {
+	Id                 string
+	Engagement         string
+	Type               string
+	CreatedAt          string
+	TtlSeconds         int32
+	Operator           string
+	ApprovedBy         string
+	State              string
+	CancelToken        string
+	Params             map[string]string
+	Priority           int32
+	Techniques         []string
+	ExpectedDetections []*ExpectedDetection
+	SchemaVersion      int32
+	Provenance         *Provenance
+	NotBefore          string
+	Classification     string
+	Selector           string
+	DryRun             bool
+	IdempotencyKey     string
+	ReissuedFromId     string
+	SupersedesId       string
+	Phase              string
+	CleanupOf          string
+	ApprovalExpiresAt  string
+	Standing           bool
+}
+
+// Marshal returns the wire encoding of the task.
+func (m *Task) Marshal() ([]byte, error) { return m.appendTo(nil), nil }
+
+// Unmarshal decodes a wire-encoded task into m.
+func (m *Task) Unmarshal(b []byte) error {
+	*m = Task{}
+	return m.unmarshal(b)
+}
+
+func (m *Task) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.Id)
+	e.string(2, m.Engagement)
+	e.string(3, m.Type)
+	e.string(4, m.CreatedAt)
+	e.int32(5, m.TtlSeconds)
+	e.string(6, m.Operator)
+	e.string(7, m.ApprovedBy)
+	e.string(8, m.State)
+	e.string(9, m.CancelToken)
+	e.stringMap(10, m.Params)
+	e.int32(11, m.Priority)
+	e.strings(12, m.Techniques)
+	for _, x := range m.ExpectedDetections {
+		e.message(13, x)
+	}
+	e.int32(14, m.SchemaVersion)
+	if m.Provenance != nil {
+		e.message(15, m.Provenance)
+	}
+	e.string(16, m.NotBefore)
+	e.string(17, m.Classification)
+	e.string(18, m.Selector)
+	e.bool(19, m.DryRun)
+	e.string(20, m.IdempotencyKey)
+	e.string(21, m.ReissuedFromId)
+	e.string(22, m.SupersedesId)
+	e.string(23, m.Phase)
+	e.string(24, m.CleanupOf)
+	e.string(25, m.ApprovalExpiresAt)
+	e.bool(26, m.Standing)
+	return e.b
+}
+
+func (m *Task) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.Id, err = d.stringField(wire)
+		case 2:
+			m.Engagement, err = d.stringField(wire)
+		case 3:
+			m.Type, err = d.stringField(wire)
+		case 4:
+			m.CreatedAt, err = d.stringField(wire)
+		case 5:
+			m.TtlSeconds, err = d.int32Field(wire)
+		case 6:
+			m.Operator, err = d.stringField(wire)
+		case 7:
+			m.ApprovedBy, err = d.stringField(wire)
+		case 8:
+			m.State, err = d.stringField(wire)
+		case 9:
+			m.CancelToken, err = d.stringField(wire)
+		case 10:
+			err = d.mapEntry(wire, &m.Params)
+		case 11:
+			m.Priority, err = d.int32Field(wire)
+		case 12:
+			var s string
+			if s, err = d.stringField(wire); err == nil {
+				m.Techniques = append(m.Techniques, s)
+			}
+		case 13:
+			x := new(ExpectedDetection)
+			if err = d.messageField(wire, x); err == nil {
+				m.ExpectedDetections = append(m.ExpectedDetections, x)
+			}
+		case 14:
+			m.SchemaVersion, err = d.int32Field(wire)
+		case 15:
+			m.Provenance = new(Provenance)
+			err = d.messageField(wire, m.Provenance)
+		case 16:
+			m.NotBefore, err = d.stringField(wire)
+		case 17:
+			m.Classification, err = d.stringField(wire)
+		case 18:
+			m.Selector, err = d.stringField(wire)
+		case 19:
+			m.DryRun, err = d.boolField(wire)
+		case 20:
+			m.IdempotencyKey, err = d.stringField(wire)
+		case 21:
+			m.ReissuedFromId, err = d.stringField(wire)
+		case 22:
+			m.SupersedesId, err = d.stringField(wire)
+		case 23:
+			m.Phase, err = d.stringField(wire)
+		case 24:
+			m.CleanupOf, err = d.stringField(wire)
+		case 25:
+			m.ApprovalExpiresAt, err = d.stringField(wire)
+		case 26:
+			m.Standing, err = d.boolField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("Task field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// Provenance mirrors rte.Provenance.
+type Provenance struct {
+	Commit string
+	Branch string
+	Signer string
+}
+
+func (m *Provenance) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.Commit)
+	e.string(2, m.Branch)
+	e.string(3, m.Signer)
+	return e.b
+}
+
+func (m *Provenance) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.Commit, err = d.stringField(wire)
+		case 2:
+			m.Branch, err = d.stringField(wire)
+		case 3:
+			m.Signer, err = d.stringField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("Provenance field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// Approval mirrors rte.Approval.
+type Approval struct {
+	PublicKey []byte
+	Signature []byte
+}
+
+func (m *Approval) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.bytes(1, m.PublicKey)
+	e.bytes(2, m.Signature)
+	return e.b
+}
+
+func (m *Approval) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.PublicKey, err = d.bytesField(wire)
+		case 2:
+			m.Signature, err = d.bytesField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("Approval field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// LegacyTask mirrors rte.LegacyTask.
+type LegacyTask struct {
+	SchemaVersion int32
+	Task          []byte
+}
+
+func (m *LegacyTask) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.int32(1, m.SchemaVersion)
+	e.bytes(2, m.Task)
+	return e.b
+}
+
+func (m *LegacyTask) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.SchemaVersion, err = d.int32Field(wire)
+		case 2:
+			m.Task, err = d.bytesField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("LegacyTask field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// SignedTask mirrors rte.SignedTask.
+type SignedTask struct {
+	Task         *Task
+	PublicKey    []byte
+	Signature    []byte
+	Approval     *Approval
+	Trace        map[string]string
+	Legacy       *LegacyTask
+	Digest       []byte
+	Attestations []*Envelope
+	Certificates [][]byte
+	Timestamp    []byte
+}
+
+// Marshal returns the wire encoding of the signed task.
+func (m *SignedTask) Marshal() ([]byte, error) { return m.appendTo(nil), nil }
+
+// Unmarshal decodes a wire-encoded signed task into m.
+func (m *SignedTask) Unmarshal(b []byte) error {
+	*m = SignedTask{}
+	return m.unmarshal(b)
+}
+
+func (m *SignedTask) appendTo(b []byte) []byte {
+	e := encoder{b}
+	if m.Task != nil {
+		e.message(1, m.Task)
+	}
+	e.bytes(2, m.PublicKey)
+	e.bytes(3, m.Signature)
+	if m.Approval != nil {
+		e.message(4, m.Approval)
+	}
+	e.stringMap(5, m.Trace)
+	if m.Legacy != nil {
+		e.message(6, m.Legacy)
+	}
+	e.bytes(7, m.Digest)
+	for _, x := range m.Attestations {
+		e.message(8, x)
+	}
+	for _, c := range m.Certificates {
+		e.bytes(9, c)
+	}
+	e.bytes(10, m.Timestamp)
+	return e.b
+}
+
+func (m *SignedTask) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.Task = new(Task)
+			err = d.messageField(wire, m.Task)
+		case 2:
+			m.PublicKey, err = d.bytesField(wire)
+		case 3:
+			m.Signature, err = d.bytesField(wire)
+		case 4:
+			m.Approval = new(Approval)
+			err = d.messageField(wire, m.Approval)
+		case 5:
+			err = d.mapEntry(wire, &m.Trace)
+		case 6:
+			m.Legacy = new(LegacyTask)
+			err = d.messageField(wire, m.Legacy)
+		case 7:
+			m.Digest, err = d.bytesField(wire)
+		case 8:
+			x := new(Envelope)
+			if err = d.messageField(wire, x); err == nil {
+				m.Attestations = append(m.Attestations, x)
+			}
+		case 9:
+			var c []byte
+			if c, err = d.bytesField(wire); err == nil {
+				m.Certificates = append(m.Certificates, c)
+			}
+		case 10:
+			m.Timestamp, err = d.bytesField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("SignedTask field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// Envelope mirrors rte.Envelope.
+type Envelope struct {
+	PayloadType string
+	Payload     []byte
+	Signatures  []*EnvelopeSignature
+}
+
+func (m *Envelope) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.PayloadType)
+	e.bytes(2, m.Payload)
+	for _, x := range m.Signatures {
+		e.message(3, x)
+	}
+	return e.b
+}
+
+func (m *Envelope) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.PayloadType, err = d.stringField(wire)
+		case 2:
+			m.Payload, err = d.bytesField(wire)
+		case 3:
+			x := new(EnvelopeSignature)
+			if err = d.messageField(wire, x); err == nil {
+				m.Signatures = append(m.Signatures, x)
+			}
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("Envelope field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// EnvelopeSignature mirrors rte.EnvelopeSignature.
+type EnvelopeSignature struct {
+	Keyid string
+	Sig   []byte
+}
+
+func (m *EnvelopeSignature) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.Keyid)
+	e.bytes(2, m.Sig)
+	return e.b
+}
+
+func (m *EnvelopeSignature) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.Keyid, err = d.stringField(wire)
+		case 2:
+			m.Sig, err = d.bytesField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("EnvelopeSignature field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// TaskResult mirrors rte.TaskResult.
+type TaskResult struct {
+	TaskId     string
+	Engagement string
+	Type       string
+	Operator   string
+	State      string
+	StartedAt  string
+	FinishedAt string
+	Output     []byte
+	Error      string
+}
+
+// Marshal returns the wire encoding of the result.
+func (m *TaskResult) Marshal() ([]byte, error) { return m.appendTo(nil), nil }
+
+// Unmarshal decodes a wire-encoded result into m.
+func (m *TaskResult) Unmarshal(b []byte) error {
+	*m = TaskResult{}
+	return m.unmarshal(b)
+}
+
+func (m *TaskResult) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.TaskId)
+	e.string(2, m.Engagement)
+	e.string(3, m.Type)
+	e.string(4, m.Operator)
+	e.string(5, m.State)
+	e.string(6, m.StartedAt)
+	e.string(7, m.FinishedAt)
+	e.bytes(8, m.Output)
+	e.string(9, m.Error)
+	return e.b
+}
+
+func (m *TaskResult) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.TaskId, err = d.stringField(wire)
+		case 2:
+			m.Engagement, err = d.stringField(wire)
+		case 3:
+			m.Type, err = d.stringField(wire)
+		case 4:
+			m.Operator, err = d.stringField(wire)
+		case 5:
+			m.State, err = d.stringField(wire)
+		case 6:
+			m.StartedAt, err = d.stringField(wire)
+		case 7:
+			m.FinishedAt, err = d.stringField(wire)
+		case 8:
+			m.Output, err = d.bytesField(wire)
+		case 9:
+			m.Error, err = d.stringField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("TaskResult field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// CancelRequest mirrors rte.TaskCancel.
+type CancelRequest struct {
+	Engagement  string
+	TaskId      string
+	Token       string
+	RequestedBy string
+}
+
+// Marshal returns the wire encoding of the cancel request.
+func (m *CancelRequest) Marshal() ([]byte, error) { return m.appendTo(nil), nil }
+
+// Unmarshal decodes a wire-encoded cancel request into m.
+func (m *CancelRequest) Unmarshal(b []byte) error {
+	*m = CancelRequest{}
+	return m.unmarshal(b)
+}
+
+func (m *CancelRequest) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.Engagement)
+	e.string(2, m.TaskId)
+	e.string(3, m.Token)
+	e.string(4, m.RequestedBy)
+	return e.b
+}
+
+func (m *CancelRequest) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.Engagement, err = d.stringField(wire)
+		case 2:
+			m.TaskId, err = d.stringField(wire)
+		case 3:
+			m.Token, err = d.stringField(wire)
+		case 4:
+			m.RequestedBy, err = d.stringField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("CancelRequest field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// Denial mirrors rte.Denial.
+type Denial struct {
+	Code        string
+	Field       string
+	Message     string
+	Rule        string
+	Source      string
+	Remediation string
+}
+
+func (m *Denial) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.Code)
+	e.string(2, m.Field)
+	e.string(3, m.Message)
+	e.string(4, m.Rule)
+	e.string(5, m.Source)
+	e.string(6, m.Remediation)
+	return e.b
+}
+
+func (m *Denial) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.Code, err = d.stringField(wire)
+		case 2:
+			m.Field, err = d.stringField(wire)
+		case 3:
+			m.Message, err = d.stringField(wire)
+		case 4:
+			m.Rule, err = d.stringField(wire)
+		case 5:
+			m.Source, err = d.stringField(wire)
+		case 6:
+			m.Remediation, err = d.stringField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("Denial field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// DenialError mirrors rte.DenialError. gRPC services send it as a detail
+// of their PERMISSION_DENIED status.
+type DenialError struct {
+	TaskId  string
+	Denials []*Denial
+}
+
+// Marshal returns the wire encoding of the denial.
+func (m *DenialError) Marshal() ([]byte, error) { return m.appendTo(nil), nil }
+
+// Unmarshal decodes a wire-encoded denial into m.
+func (m *DenialError) Unmarshal(b []byte) error {
+	*m = DenialError{}
+	return m.unmarshal(b)
+}
+
+func (m *DenialError) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.TaskId)
+	for _, x := range m.Denials {
+		e.message(2, x)
+	}
+	return e.b
+}
+
+func (m *DenialError) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.TaskId, err = d.stringField(wire)
+		case 2:
+			x := new(Denial)
+			if err = d.messageField(wire, x); err == nil {
+				m.Denials = append(m.Denials, x)
+			}
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return fmt.Errorf("DenialError field %d: %w", num, err)
+		}
+	}
+	return nil
+}
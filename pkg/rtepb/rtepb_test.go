@@ -0,0 +1,259 @@
+package rtepb
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// golden compares got with testdata/name, or rewrites it under -update.
+func golden(t *testing.T, name string, got []byte) []byte {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s changed; field numbers or encodings must stay compatible\ngot  %x\nwant %x", name, got, want)
+	}
+	return want
+}
+
+// goldenSignedTask is signed with a fixed key, so its signature is stable.
+func goldenSignedTask(t *testing.T) *rte.SignedTask {
+	t.Helper()
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	pub := priv.Public().(ed25519.PublicKey)
+	task := rte.Task{
+		SchemaVersion: rte.TaskSchemaVersion,
+		ID:            "task-042",
+		Engagement:    "eng-2026-q1",
+		Type:          rte.TaskSimulateLogin,
+		// A non-UTC offset and nanoseconds must survive the round trip.
+		CreatedAt:   time.Date(2026, 3, 2, 9, 30, 0, 123456789, time.FixedZone("", -5*3600)),
+		TTLSeconds:  600,
+		Operator:    "op-alice",
+		ApprovedBy:  "lead-bob",
+		State:       rte.StatePending,
+		CancelToken: "c4nc3l",
+		Params:      map[string]string{"target": "10.0.0.5", "note": "<html> & \"quotes\"", "empty": ""},
+		Priority:    3,
+		Techniques:  []string{"T1110.003", "T1078"},
+		ExpectedDetections: []rte.ExpectedDetection{
+			{Technique: "T1110.003", Rule: "Password Spray", Query: `index=auth action=failure | stats dc(user) by src`},
+		},
+	}
+	payload, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &rte.SignedTask{Task: task, PublicKey: pub, Signature: ed25519.Sign(priv, payload),
+		Trace: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	if err := rte.Countersign(st, priv, pub); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestSignedTask_Golden(t *testing.T) {
+	st := goldenSignedTask(t)
+	js, _ := json.MarshalIndent(st, "", "  ")
+	golden(t, "signed_task.json", append(js, '\n'))
+
+	m, err := FromSignedTask(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _ := m.Marshal()
+	want := golden(t, "signed_task.pb", pb)
+
+	var back SignedTask
+	if err := back.Unmarshal(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := back.ToSignedTask()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rte.VerifyTaskSignature(got); err != nil {
+		t.Fatalf("signature broken by protobuf round trip: %v", err)
+	}
+	if err := rte.VerifyApproval(got); err != nil {
+		t.Fatalf("approval broken by protobuf round trip: %v", err)
+	}
+	origTask, _ := json.Marshal(st.Task)
+	gotTask, _ := json.Marshal(got.Task)
+	if !bytes.Equal(origTask, gotTask) {
+		t.Fatalf("signed JSON payload changed:\n%s\n%s", origTask, gotTask)
+	}
+	gotJSON, _ := json.MarshalIndent(got, "", "  ")
+	if !bytes.Equal(gotJSON, js) {
+		t.Fatalf("JSON representation changed:\n%s\n%s", gotJSON, js)
+	}
+}
+
+func TestTaskResult_Golden(t *testing.T) {
+	r := &rte.TaskResult{
+		TaskID: "task-042", Engagement: "eng-2026-q1", Type: rte.TaskSimulateLogin, Operator: "op-alice",
+		State:     rte.StateFailed,
+		StartedAt: time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC), FinishedAt: time.Date(2026, 3, 2, 14, 31, 5, 500, time.UTC),
+		Output: json.RawMessage(`{"attempts":3,"locked":false}`), Error: "target refused connection",
+	}
+	js, _ := json.Marshal(r)
+	pb, _ := FromTaskResult(r).Marshal()
+	want := golden(t, "task_result.pb", pb)
+
+	var back TaskResult
+	if err := back.Unmarshal(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := back.ToTaskResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	if !bytes.Equal(gotJSON, js) {
+		t.Fatalf("JSON representation changed:\n%s\n%s", gotJSON, js)
+	}
+}
+
+func TestCancelRequest_Golden(t *testing.T) {
+	c := rte.TaskCancel{Engagement: "eng-2026-q1", TaskID: "task-042", Token: "c4nc3l", RequestedBy: "lead-bob"}
+	pb, _ := FromTaskCancel(c).Marshal()
+	want := golden(t, "cancel_request.pb", pb)
+	var back CancelRequest
+	if err := back.Unmarshal(want); err != nil {
+		t.Fatal(err)
+	}
+	if back.ToTaskCancel() != c {
+		t.Fatalf("round trip = %+v", back.ToTaskCancel())
+	}
+}
+
+func TestUnmarshal_SkipsUnknownAndRejectsTruncated(t *testing.T) {
+	m, err := FromTask(goldenSignedTask(t).Task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _ := m.Marshal()
+	// Append unknown fields of each wire type: 100 varint, 101 fixed64,
+	// 102 bytes, 103 fixed32.
+	extra := append(append([]byte(nil), pb...), 0xa0, 0x06, 0x96, 0x01, 0xa9, 0x06, 1, 2, 3, 4, 5, 6, 7, 8,
+		0xb2, 0x06, 2, 'h', 'i', 0xbd, 0x06, 1, 2, 3, 4)
+	var back Task
+	if err := back.Unmarshal(extra); err != nil {
+		t.Fatalf("unknown fields: %v", err)
+	}
+	if back.Id != "task-042" || len(back.Params) != 3 || back.Params["empty"] != "" {
+		t.Fatalf("decoded = %+v", back)
+	}
+	if err := back.Unmarshal(pb[:len(pb)-3]); err == nil {
+		t.Fatal("expected truncated message to fail")
+	}
+	// Only where int is 64 bits wide can a ttl fall outside int32.
+	if math.MaxInt > math.MaxInt32 {
+		if _, err := FromTask(rte.Task{TTLSeconds: math.MaxInt}); err == nil {
+			t.Fatal("expected out-of-range ttl to fail")
+		}
+	}
+}
+
+func TestSignedTask_OptionalFieldsRoundTrip(t *testing.T) {
+	st := goldenSignedTask(t)
+	nb := st.Task.CreatedAt.Add(time.Minute)
+	st.Task.NotBefore = &nb
+	st.Task.Classification = rte.ClassificationRestricted
+	st.Task.Selector = "os=windows,!quarantined"
+	st.Task.DryRun = true
+	st.Task.IdempotencyKey = "retry-7f3a"
+	st.Task.ReissuedFromID = "task-041"
+	st.Task.Phase = rte.PhasePersistence
+	st.Task.CleanupOf = "task-000"
+	ae := st.Task.CreatedAt.Add(72 * time.Hour)
+	st.Task.ApprovalExpiresAt = &ae
+	st.Task.Standing = true
+	st.Timestamp = []byte{0x30, 0x03, 0x02, 0x01, 0x01}
+	m, err := FromSignedTask(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, _ := m.Marshal()
+	var back SignedTask
+	if err := back.Unmarshal(pb); err != nil {
+		t.Fatal(err)
+	}
+	got, err := back.ToSignedTask()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.NotBefore == nil || !got.Task.NotBefore.Equal(nb) || !bytes.Equal(got.Timestamp, st.Timestamp) {
+		t.Fatalf("not_before %v timestamp %x", got.Task.NotBefore, got.Timestamp)
+	}
+	origTask, _ := json.Marshal(st.Task)
+	gotTask, _ := json.Marshal(got.Task)
+	if !bytes.Equal(origTask, gotTask) {
+		t.Fatalf("task JSON changed:\n%s\n%s", origTask, gotTask)
+	}
+}
+
+func TestDenialError_RoundTrip(t *testing.T) {
+	e := &rte.DenialError{TaskID: "task-042", Denials: []rte.Denial{
+		{Code: rte.DenialSignature, Violation: rte.Violation{Field: "signature", Message: "task signature rejected"}},
+		{Code: rte.DenialPolicy, Violation: rte.Violation{Rule: "param_rules[0]", Field: "task.params.target", Source: "rules.json:3", Message: "no", Remediation: "ask"}},
+	}}
+	pb, _ := FromDenialError(e).Marshal()
+	var back DenialError
+	if err := back.Unmarshal(pb); err != nil {
+		t.Fatal(err)
+	}
+	got := back.ToDenialError()
+	if got.TaskID != e.TaskID || len(got.Denials) != 2 || got.Denials[0] != e.Denials[0] || got.Denials[1] != e.Denials[1] {
+		t.Fatalf("round trip = %+v", got)
+	}
+}
+
+func FuzzSignedTask_Unmarshal(f *testing.F) {
+	if pb, err := os.ReadFile(filepath.Join("testdata", "signed_task.pb")); err == nil {
+		f.Add(pb)
+	}
+	f.Add([]byte{0x0a, 0x00})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var m SignedTask
+		if err := m.Unmarshal(b); err != nil {
+			return
+		}
+		st, err := m.ToSignedTask()
+		if err != nil {
+			return
+		}
+		// A decoded task converts back and survives another round trip.
+		back, err := FromSignedTask(st)
+		if err != nil {
+			return
+		}
+		pb, _ := back.Marshal()
+		var again SignedTask
+		if err := again.Unmarshal(pb); err != nil {
+			t.Fatalf("re-encoded task does not decode: %v", err)
+		}
+		if _, err := again.ToSignedTask(); err != nil {
+			t.Fatalf("re-encoded task does not convert: %v", err)
+		}
+	})
+}
@@ -0,0 +1,2 @@
+
+eng-2026-q1task-042c4nc3l"lead-bob
\ No newline at end of file
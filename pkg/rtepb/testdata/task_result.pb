@@ -0,0 +1,2 @@
+
+task-042eng-2026-q1simulate_login"op-alice*failed22026-03-02T14:30:00Z:2026-03-02T14:31:05.0000005ZB{"attempts":3,"locked":false}Jtarget refused connection
\ No newline at end of file
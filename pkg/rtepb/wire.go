@@ -0,0 +1,252 @@
+package rtepb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Wire types used by these messages.
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+var errTruncated = errors.New("protobuf: truncated message")
+
+// encoder appends proto3 fields, omitting zero values as proto3 does for
+// fields without explicit presence. Output is deterministic: map entries
+// are written in key order.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) tag(num, wire int) {
+	e.b = binary.AppendUvarint(e.b, uint64(num)<<3|uint64(wire))
+}
+
+func (e *encoder) string(num int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(num, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+	e.b = append(e.b, s...)
+}
+
+// strings writes a repeated string; every element is written, even empty
+// ones.
+func (e *encoder) strings(num int, ss []string) {
+	for _, s := range ss {
+		e.tag(num, wireBytes)
+		e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+		e.b = append(e.b, s...)
+	}
+}
+
+func (e *encoder) bytes(num int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	e.tag(num, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// int32 writes a varint; negative values take ten bytes, as in protobuf.
+func (e *encoder) int32(num int, v int32) {
+	if v == 0 {
+		return
+	}
+	e.tag(num, wireVarint)
+	e.b = binary.AppendUvarint(e.b, uint64(int64(v)))
+}
+
+// bool writes a varint 1; false is the default and is not written.
+func (e *encoder) bool(num int, v bool) {
+	if !v {
+		return
+	}
+	e.tag(num, wireVarint)
+	e.b = append(e.b, 1)
+}
+
+// message writes a nested message. Present messages are written even when
+// empty, so presence survives the round trip.
+func (e *encoder) message(num int, m interface{ appendTo([]byte) []byte }) {
+	body := m.appendTo(nil)
+	e.tag(num, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(body)))
+	e.b = append(e.b, body...)
+}
+
+func (e *encoder) stringMap(num int, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.message(num, mapEntry{k, m[k]})
+	}
+}
+
+// mapEntry is the implicit key/value message of a map<string, string>.
+type mapEntry struct{ key, value string }
+
+func (m mapEntry) appendTo(b []byte) []byte {
+	e := encoder{b}
+	e.string(1, m.key)
+	e.string(2, m.value)
+	return e.b
+}
+
+// decoder walks the fields of one message.
+type decoder struct {
+	b []byte
+}
+
+// next returns the next field's number and wire type.
+func (d *decoder) next() (num, wire int, err error) {
+	v, err := d.uvarint()
+	if err != nil {
+		return 0, 0, err
+	}
+	num, wire = int(v>>3), int(v&7)
+	if num <= 0 {
+		return 0, 0, fmt.Errorf("protobuf: invalid field number %d", num)
+	}
+	return num, wire, nil
+}
+
+func (d *decoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.b = d.b[n:]
+	return v, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.b)) {
+		return nil, errTruncated
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b, nil
+}
+
+// field decoders check the wire type before reading.
+
+func (d *decoder) stringField(wire int) (string, error) {
+	if wire != wireBytes {
+		return "", fmt.Errorf("protobuf: wire type %d for string field", wire)
+	}
+	b, err := d.bytes()
+	return string(b), err
+}
+
+func (d *decoder) bytesField(wire int) ([]byte, error) {
+	if wire != wireBytes {
+		return nil, fmt.Errorf("protobuf: wire type %d for bytes field", wire)
+	}
+	b, err := d.bytes()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (d *decoder) int32Field(wire int) (int32, error) {
+	if wire != wireVarint {
+		return 0, fmt.Errorf("protobuf: wire type %d for int32 field", wire)
+	}
+	v, err := d.uvarint()
+	return int32(v), err
+}
+
+func (d *decoder) boolField(wire int) (bool, error) {
+	if wire != wireVarint {
+		return false, fmt.Errorf("protobuf: wire type %d for bool field", wire)
+	}
+	v, err := d.uvarint()
+	return v != 0, err
+}
+
+func (d *decoder) messageField(wire int, m interface{ unmarshal([]byte) error }) error {
+	if wire != wireBytes {
+		return fmt.Errorf("protobuf: wire type %d for message field", wire)
+	}
+	b, err := d.bytes()
+	if err != nil {
+		return err
+	}
+	return m.unmarshal(b)
+}
+
+func (d *decoder) mapEntry(wire int, m *map[string]string) error {
+	var entry mapEntry
+	if err := d.messageField(wire, &entry); err != nil {
+		return err
+	}
+	if *m == nil {
+		*m = make(map[string]string)
+	}
+	(*m)[entry.key] = entry.value
+	return nil
+}
+
+func (m *mapEntry) unmarshal(b []byte) error {
+	d := decoder{b}
+	for len(d.b) > 0 {
+		num, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			m.key, err = d.stringField(wire)
+		case 2:
+			m.value, err = d.stringField(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip discards a field this version does not know, as protobuf requires.
+func (d *decoder) skip(wire int) error {
+	switch wire {
+	case wireVarint:
+		_, err := d.uvarint()
+		return err
+	case wireI64:
+		if len(d.b) < 8 {
+			return errTruncated
+		}
+		d.b = d.b[8:]
+	case wireBytes:
+		_, err := d.bytes()
+		return err
+	case wireI32:
+		if len(d.b) < 4 {
+			return errTruncated
+		}
+		d.b = d.b[4:]
+	default:
+		return fmt.Errorf("protobuf: unsupported wire type %d", wire)
+	}
+	return nil
+}
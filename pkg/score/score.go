@@ -0,0 +1,220 @@
+// Package score turns an engagement's measured detections and SOC responses
+// into an overall score with per-tactic subscores, so readouts are computed
+// from recorded outcomes instead of hand-maintained spreadsheets.
+package score
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/attack"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// ResponseAction is the furthest step the SOC took on an alert.
+type ResponseAction string
+
+const (
+	ResponseNone      ResponseAction = "none"
+	ResponseTriaged   ResponseAction = "triaged"
+	ResponseEscalated ResponseAction = "escalated"
+	ResponseContained ResponseAction = "contained"
+)
+
+// Response records the SOC's action on one task's detection of a technique.
+type Response struct {
+	TaskID    string         `json:"task_id"`
+	Technique string         `json:"technique"`
+	Action    ResponseAction `json:"action"`
+}
+
+// Model configures scoring. Every detection record earns a credit in [0, 1]
+// blended from three components, and credits are averaged with technique
+// criticality as the weight.
+type Model struct {
+	// Criticality weights techniques by ID. Sub-techniques without an entry
+	// fall back to their parent, then to DefaultCriticality.
+	Criticality        map[string]float64 `json:"criticality,omitempty"`
+	DefaultCriticality float64            `json:"default_criticality"`
+
+	// LatencyTarget earns full latency credit; credit falls linearly to zero
+	// at LatencyMax.
+	LatencyTarget time.Duration `json:"latency_target_ns"`
+	LatencyMax    time.Duration `json:"latency_max_ns"`
+
+	// Response credits each action; unlisted actions earn nothing.
+	Response map[ResponseAction]float64 `json:"response"`
+
+	// Component weights for detection, latency, and response credit.
+	DetectionWeight float64 `json:"detection_weight"`
+	LatencyWeight   float64 `json:"latency_weight"`
+	ResponseWeight  float64 `json:"response_weight"`
+}
+
+// DefaultModel returns the reference scoring model: detection counts half,
+// latency and response a quarter each, with a 15-minute latency target.
+func DefaultModel() Model {
+	return Model{
+		DefaultCriticality: 1,
+		LatencyTarget:      15 * time.Minute,
+		LatencyMax:         4 * time.Hour,
+		Response: map[ResponseAction]float64{
+			ResponseNone:      0,
+			ResponseTriaged:   0.4,
+			ResponseEscalated: 0.7,
+			ResponseContained: 1,
+		},
+		DetectionWeight: 0.5,
+		LatencyWeight:   0.25,
+		ResponseWeight:  0.25,
+	}
+}
+
+// Validate reports whether the model can produce meaningful scores.
+func (m Model) Validate() error {
+	if m.DefaultCriticality < 0 {
+		return errors.New("default_criticality must not be negative")
+	}
+	for id, w := range m.Criticality {
+		if err := attack.Validate(id); err != nil {
+			return fmt.Errorf("criticality: %w", err)
+		}
+		if w < 0 {
+			return fmt.Errorf("criticality for %s must not be negative", id)
+		}
+	}
+	if m.LatencyTarget < 0 || m.LatencyMax <= m.LatencyTarget {
+		return errors.New("latency_max_ns must be greater than latency_target_ns")
+	}
+	for a, c := range m.Response {
+		if c < 0 || c > 1 {
+			return fmt.Errorf("response credit for %s must be between 0 and 1", a)
+		}
+	}
+	if m.DetectionWeight < 0 || m.LatencyWeight < 0 || m.ResponseWeight < 0 {
+		return errors.New("component weights must not be negative")
+	}
+	if m.DetectionWeight+m.LatencyWeight+m.ResponseWeight == 0 {
+		return errors.New("at least one component weight must be positive")
+	}
+	return nil
+}
+
+// Subscore is the score for one tactic.
+type Subscore struct {
+	Tactic   attack.Tactic `json:"tactic"`
+	Score    float64       `json:"score"`
+	Expected int           `json:"expected"`
+	Detected int           `json:"detected"`
+}
+
+// Scorecard is an engagement's overall score and per-tactic subscores, each
+// on a 0-100 scale. Tactics appear in kill-chain order; those without
+// measured detections are omitted.
+type Scorecard struct {
+	Engagement string     `json:"engagement"`
+	Score      float64    `json:"score"`
+	Expected   int        `json:"expected"`
+	Detected   int        `json:"detected"`
+	Tactics    []Subscore `json:"tactics"`
+}
+
+// Score computes the scorecard for one engagement from its detection
+// records and recorded responses. Records from other engagements are
+// ignored, and a detection with no recorded response counts as
+// ResponseNone.
+func (m Model) Score(engagement string, detections []rte.DetectionRecord, responses []Response) (Scorecard, error) {
+	if err := m.Validate(); err != nil {
+		return Scorecard{}, err
+	}
+	actions := make(map[[2]string]ResponseAction, len(responses))
+	for _, r := range responses {
+		actions[[2]string{r.TaskID, r.Technique}] = r.Action
+	}
+	type acc struct {
+		weighted, weight   float64
+		expected, detected int
+	}
+	var total acc
+	byTactic := make(map[string]*acc)
+	add := func(a *acc, credit, w float64, detected bool) {
+		a.weighted += credit * w
+		a.weight += w
+		a.expected++
+		if detected {
+			a.detected++
+		}
+	}
+	for _, d := range detections {
+		if d.Engagement != engagement {
+			continue
+		}
+		tech, ok := attack.Lookup(d.Technique)
+		if !ok {
+			return Scorecard{}, fmt.Errorf("task %s: ATT&CK technique %s is not in the catalog", d.TaskID, d.Technique)
+		}
+		action := actions[[2]string{d.TaskID, d.Technique}]
+		credit, w := m.credit(d, action), m.criticality(tech)
+		add(&total, credit, w, d.Detected)
+		for _, tac := range tech.Tactics {
+			a := byTactic[tac]
+			if a == nil {
+				a = &acc{}
+				byTactic[tac] = a
+			}
+			add(a, credit, w, d.Detected)
+		}
+	}
+	card := Scorecard{
+		Engagement: engagement,
+		Score:      percent(total.weighted, total.weight),
+		Expected:   total.expected,
+		Detected:   total.detected,
+		Tactics:    []Subscore{},
+	}
+	for _, tac := range attack.Tactics() {
+		a := byTactic[tac.Shortname]
+		if a == nil {
+			continue
+		}
+		card.Tactics = append(card.Tactics, Subscore{
+			Tactic: tac, Score: percent(a.weighted, a.weight), Expected: a.expected, Detected: a.detected,
+		})
+	}
+	return card, nil
+}
+
+// credit blends the three components for one detection record. A missed
+// detection earns no latency or response credit.
+func (m Model) credit(d rte.DetectionRecord, action ResponseAction) float64 {
+	if !d.Detected {
+		return 0
+	}
+	sum := m.DetectionWeight + m.LatencyWeight + m.ResponseWeight
+	latency := 1.0
+	if d.Latency > m.LatencyTarget {
+		latency = math.Max(0, 1-float64(d.Latency-m.LatencyTarget)/float64(m.LatencyMax-m.LatencyTarget))
+	}
+	c := m.DetectionWeight + m.LatencyWeight*latency + m.ResponseWeight*m.Response[action]
+	return c / sum
+}
+
+func (m Model) criticality(t attack.Technique) float64 {
+	if w, ok := m.Criticality[t.ID]; ok {
+		return w
+	}
+	if w, ok := m.Criticality[t.Parent()]; ok {
+		return w
+	}
+	return m.DefaultCriticality
+}
+
+// percent scales a weighted mean to 0-100, rounded to one decimal place.
+func percent(weighted, weight float64) float64 {
+	if weight == 0 {
+		return 0
+	}
+	return math.Round(weighted/weight*1000) / 10
+}
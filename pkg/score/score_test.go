@@ -0,0 +1,72 @@
+package score
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func TestModel_Score(t *testing.T) {
+	m := DefaultModel()
+	m.Criticality = map[string]float64{"T1110": 3}
+	detections := []rte.DetectionRecord{
+		// Fast, contained spray: full credit, criticality 3 via the parent.
+		{TaskID: "t1", Engagement: "eng-1", Technique: "T1110.003", Detected: true, Latency: time.Minute},
+		// Missed beacon: no credit.
+		{TaskID: "t2", Engagement: "eng-1", Technique: "T1071.001"},
+		// Slow, triaged login, halfway through the latency window.
+		{TaskID: "t3", Engagement: "eng-1", Technique: "T1078", Detected: true,
+			Latency: m.LatencyTarget + (m.LatencyMax-m.LatencyTarget)/2},
+		{TaskID: "t9", Engagement: "eng-other", Technique: "T1486", Detected: true},
+	}
+	responses := []Response{
+		{TaskID: "t1", Technique: "T1110.003", Action: ResponseContained},
+		{TaskID: "t3", Technique: "T1078", Action: ResponseTriaged},
+	}
+	card, err := m.Score("eng-1", detections, responses)
+	if err != nil {
+		t.Fatalf("Score: %v", err)
+	}
+	// t3 credit: 0.5 + 0.25*0.5 + 0.25*0.4 = 0.725; overall (3*1 + 0 + 0.725) / 5.
+	if card.Score != 74.5 || card.Expected != 3 || card.Detected != 2 {
+		t.Fatalf("unexpected scorecard %+v", card)
+	}
+	got := map[string]float64{}
+	for _, s := range card.Tactics {
+		got[s.Tactic.Shortname] = s.Score
+	}
+	if got["credential-access"] != 100 || got["command-and-control"] != 0 || got["initial-access"] != 72.5 {
+		t.Errorf("unexpected subscores %v", got)
+	}
+	if _, ok := got["impact"]; ok {
+		t.Error("other engagement leaked into subscores")
+	}
+	if card.Tactics[0].Tactic.Shortname != "initial-access" {
+		t.Errorf("tactics not in kill-chain order: %+v", card.Tactics)
+	}
+}
+
+func TestModel_Validate(t *testing.T) {
+	cases := map[string]func(*Model){
+		"latency":     func(m *Model) { m.LatencyMax = m.LatencyTarget },
+		"weights":     func(m *Model) { m.DetectionWeight, m.LatencyWeight, m.ResponseWeight = 0, 0, 0 },
+		"response":    func(m *Model) { m.Response[ResponseTriaged] = 2 },
+		"criticality": func(m *Model) { m.Criticality = map[string]float64{"T9999": 1} },
+	}
+	for name, mutate := range cases {
+		m := DefaultModel()
+		mutate(&m)
+		if err := m.Validate(); err == nil {
+			t.Errorf("%s: expected invalid model", name)
+		}
+	}
+	if err := DefaultModel().Validate(); err != nil {
+		t.Fatalf("default model: %v", err)
+	}
+	_, err := DefaultModel().Score("eng-1", []rte.DetectionRecord{{TaskID: "t1", Engagement: "eng-1", Technique: "T9999"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "t1") {
+		t.Errorf("expected unknown technique to fail, got %v", err)
+	}
+}
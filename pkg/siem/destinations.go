@@ -0,0 +1,303 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SplunkHEC sends events to a Splunk HTTP Event Collector.
+type SplunkHEC struct {
+	// URL is the collector's base URL, as https://splunk.example.com:8088.
+	URL   string
+	Token string
+	// Index and Source, if set, override the token's defaults.
+	Index  string
+	Source string
+	Client *http.Client
+}
+
+type hecEvent struct {
+	Time       float64         `json:"time"`
+	SourceType string          `json:"sourcetype,omitempty"`
+	Index      string          `json:"index,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// Send implements Destination, posting the batch to the collector's event
+// endpoint in one request.
+func (s *SplunkHEC) Send(ctx context.Context, events []Event) error {
+	if s.URL == "" || s.Token == "" {
+		return errors.New("splunk: URL and token are required")
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range events {
+		he := hecEvent{
+			Time:       float64(e.Time.UnixMilli()) / 1000,
+			SourceType: e.SourceType, Index: s.Index, Source: s.Source, Event: e.Body,
+		}
+		if err := enc.Encode(he); err != nil {
+			return fmt.Errorf("splunk: encode event: %w", err)
+		}
+	}
+	_, err := post(ctx, s.Client, strings.TrimRight(s.URL, "/")+"/services/collector/event", "application/json", body.Bytes(),
+		func(r *http.Request) error {
+			r.Header.Set("Authorization", "Splunk "+s.Token)
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("splunk: %w", err)
+	}
+	return nil
+}
+
+// Elastic indexes events through an Elasticsearch cluster's bulk API.
+// Each event gets an ID derived from its content and is indexed with a
+// create action, so a retried batch does not duplicate the events that
+// made it in the first time.
+type Elastic struct {
+	// URL is the cluster's base URL.
+	URL   string
+	Index string
+	// APIKey is the encoded API key; Username and Password are used when
+	// it is empty.
+	APIKey             string
+	Username, Password string
+	Client             *http.Client
+}
+
+type elasticDoc struct {
+	Timestamp  time.Time       `json:"@timestamp"`
+	SourceType string          `json:"source_type"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// elasticID is the event's document ID: the hex SHA-256 of its source
+// type, time, and body.
+func elasticID(e Event) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", e.SourceType, e.Time.UTC().Format(time.RFC3339Nano))
+	h.Write(e.Body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// Send implements Destination. The bulk API answers 200 even when items
+// fail, so Send checks each item: events that already exist count as
+// sent, and if any other item failed it returns an *HTTPError with that
+// item's status, preferring one worth retrying.
+func (s *Elastic) Send(ctx context.Context, events []Event) error {
+	if s.URL == "" || s.Index == "" {
+		return errors.New("elastic: URL and index are required")
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range events {
+		action := map[string]map[string]string{"create": {"_index": s.Index, "_id": elasticID(e)}}
+		if err := enc.Encode(action); err != nil {
+			return fmt.Errorf("elastic: encode action: %w", err)
+		}
+		if err := enc.Encode(elasticDoc{Timestamp: e.Time.UTC(), SourceType: e.SourceType, Event: e.Body}); err != nil {
+			return fmt.Errorf("elastic: encode event: %w", err)
+		}
+	}
+	msg, err := post(ctx, s.Client, strings.TrimRight(s.URL, "/")+"/_bulk", "application/x-ndjson", body.Bytes(),
+		func(r *http.Request) error {
+			switch {
+			case s.APIKey != "":
+				r.Header.Set("Authorization", "ApiKey "+s.APIKey)
+			case s.Username != "":
+				r.SetBasicAuth(s.Username, s.Password)
+			}
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("elastic: %w", err)
+	}
+	var resp bulkResponse
+	if err := json.Unmarshal(msg, &resp); err != nil {
+		return fmt.Errorf("elastic: decode response: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+	var failed *HTTPError
+	n := 0
+	for _, item := range resp.Items {
+		for _, res := range item {
+			if res.Status < 300 || res.Status == http.StatusConflict {
+				continue
+			}
+			n++
+			he := &HTTPError{Status: res.Status}
+			if res.Error != nil {
+				he.Body = res.Error.Type + ": " + res.Error.Reason
+			}
+			if failed == nil || (he.Temporary() && !failed.Temporary()) {
+				failed = he
+			}
+		}
+	}
+	if failed == nil {
+		return nil
+	}
+	return fmt.Errorf("elastic: %d of %d events failed: %w", n, len(events), failed)
+}
+
+// sentinelAPIVersion is the Logs Ingestion API version Sentinel is sent.
+const sentinelAPIVersion = "2023-01-01"
+
+// Sentinel sends events to a Log Analytics workspace, and so to Microsoft
+// Sentinel, through the Logs Ingestion API: a data collection rule maps
+// the stream's columns (TimeGenerated, SourceType, and Event, a dynamic
+// column) onto a table.
+type Sentinel struct {
+	// Endpoint is the data collection endpoint's logs ingestion URL.
+	Endpoint string
+	// RuleID is the data collection rule's immutable ID, and Stream the
+	// stream it declares, as Custom-RTEA_CL.
+	RuleID string
+	Stream string
+	// Token returns a bearer token for https://monitor.azure.com; see
+	// ClientCredentials.
+	Token  func(ctx context.Context) (string, error)
+	Client *http.Client
+}
+
+type sentinelRow struct {
+	TimeGenerated time.Time       `json:"TimeGenerated"`
+	SourceType    string          `json:"SourceType"`
+	Event         json.RawMessage `json:"Event"`
+}
+
+// Send implements Destination. The API takes at most 1 MB per request,
+// which bounds the Forwarder's BatchSize.
+func (s *Sentinel) Send(ctx context.Context, events []Event) error {
+	if s.Endpoint == "" || s.RuleID == "" || s.Stream == "" || s.Token == nil {
+		return errors.New("sentinel: endpoint, rule ID, stream, and token are required")
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	rows := make([]sentinelRow, len(events))
+	for i, e := range events {
+		rows[i] = sentinelRow{TimeGenerated: e.Time.UTC(), SourceType: e.SourceType, Event: e.Body}
+	}
+	body, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("sentinel: encode events: %w", err)
+	}
+	u := fmt.Sprintf("%s/dataCollectionRules/%s/streams/%s?api-version=%s", strings.TrimRight(s.Endpoint, "/"),
+		url.PathEscape(s.RuleID), url.PathEscape(s.Stream), sentinelAPIVersion)
+	_, err = post(ctx, s.Client, u, "application/json", body, func(r *http.Request) error {
+		tok, err := s.Token(ctx)
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Authorization", "Bearer "+tok)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("sentinel: %w", err)
+	}
+	return nil
+}
+
+// defaultSentinelScope is the scope of tokens for the Logs Ingestion API.
+const defaultSentinelScope = "https://monitor.azure.com/.default"
+
+// ClientCredentials gets Microsoft Entra ID tokens for an app registration
+// with the client credentials grant, caching each until shortly before it
+// expires. Its Token method fits Sentinel.Token.
+type ClientCredentials struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	// Scope defaults to https://monitor.azure.com/.default.
+	Scope string
+	// TokenURL defaults to the tenant's v2.0 token endpoint.
+	TokenURL string
+	Client   *http.Client
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns a cached token, or requests a new one.
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && now().Before(c.expires) {
+		return c.token, nil
+	}
+	if c.ClientID == "" || c.ClientSecret == "" || (c.TenantID == "" && c.TokenURL == "") {
+		return "", errors.New("client credentials: tenant, client ID, and secret are required")
+	}
+	tokenURL := c.TokenURL
+	if tokenURL == "" {
+		tokenURL = "https://login.microsoftonline.com/" + url.PathEscape(c.TenantID) + "/oauth2/v2.0/token"
+	}
+	scope := c.Scope
+	if scope == "" {
+		scope = defaultSentinelScope
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"scope":         {scope},
+	}
+	msg, err := post(ctx, c.Client, tokenURL, "application/x-www-form-urlencoded", []byte(form.Encode()),
+		func(*http.Request) error { return nil })
+	if err != nil {
+		return "", fmt.Errorf("client credentials: %w", err)
+	}
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(msg, &resp); err != nil {
+		return "", fmt.Errorf("client credentials: decode response: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("client credentials: response has no access token")
+	}
+	// Renew a minute early so a token never expires in flight.
+	c.token = resp.AccessToken
+	c.expires = now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
@@ -0,0 +1,149 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recorded struct {
+	path, query, auth, contentType string
+	body                           string
+}
+
+func recorder(t *testing.T, reply func(r *http.Request) (int, string)) (*httptest.Server, *[]recorded) {
+	t.Helper()
+	var calls []recorded
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		calls = append(calls, recorded{
+			path: r.URL.EscapedPath(), query: r.URL.RawQuery, auth: r.Header.Get("Authorization"),
+			contentType: r.Header.Get("Content-Type"), body: string(b),
+		})
+		status, body := reply(r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func testEvents() []Event {
+	at := time.Date(2026, 10, 15, 9, 0, 0, 500e6, time.UTC)
+	return []Event{
+		{Time: at, SourceType: SourceTypeAudit, Body: json.RawMessage(`{"action":"task.create"}`)},
+		{Time: at, SourceType: SourceTypeSynth, Body: json.RawMessage(`"login failed"`)},
+	}
+}
+
+func TestSplunkHEC_Send(t *testing.T) {
+	srv, calls := recorder(t, func(*http.Request) (int, string) { return 200, `{"text":"Success","code":0}` })
+	s := &SplunkHEC{URL: srv.URL + "/", Token: "hec-tok", Index: "rte"}
+	if err := s.Send(context.Background(), testEvents()); err != nil {
+		t.Fatal(err)
+	}
+	c := (*calls)[0]
+	if c.path != "/services/collector/event" || c.auth != "Splunk hec-tok" {
+		t.Errorf("call = %+v", c)
+	}
+	lines := strings.Split(strings.TrimSpace(c.body), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("body = %q", c.body)
+	}
+	var ev hecEvent
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Time != 1792054800.5 || ev.Index != "rte" || ev.SourceType != SourceTypeAudit || string(ev.Event) != `{"action":"task.create"}` {
+		t.Errorf("event = %+v", ev)
+	}
+
+	srv, _ = recorder(t, func(*http.Request) (int, string) { return 503, `{"text":"Server is busy","code":9}` })
+	s.URL = srv.URL
+	var he *HTTPError
+	if err := s.Send(context.Background(), testEvents()); !errors.As(err, &he) || !he.Temporary() {
+		t.Errorf("busy collector: %v", err)
+	}
+}
+
+func TestElastic_Send(t *testing.T) {
+	reply := `{"errors":true,"items":[{"create":{"status":201}},{"create":{"status":409,"error":{"type":"version_conflict_engine_exception","reason":"exists"}}}]}`
+	srv, calls := recorder(t, func(*http.Request) (int, string) { return 200, reply })
+	s := &Elastic{URL: srv.URL, Index: "rte-events", APIKey: "a2V5"}
+	if err := s.Send(context.Background(), testEvents()); err != nil {
+		t.Fatalf("conflicts should count as sent: %v", err)
+	}
+	c := (*calls)[0]
+	if c.path != "/_bulk" || c.auth != "ApiKey a2V5" || c.contentType != "application/x-ndjson" {
+		t.Errorf("call = %+v", c)
+	}
+	lines := strings.Split(strings.TrimSpace(c.body), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("body = %q", c.body)
+	}
+	want := `{"create":{"_id":"` + elasticID(testEvents()[0]) + `","_index":"rte-events"}}`
+	if lines[0] != want || !strings.Contains(lines[1], `"@timestamp":"2026-10-15T09:00:00.5Z"`) {
+		t.Errorf("first event = %s\n%s", lines[0], lines[1])
+	}
+	if elasticID(testEvents()[0]) == elasticID(testEvents()[1]) {
+		t.Error("distinct events share a document ID")
+	}
+
+	reply = `{"errors":true,"items":[{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad"}}},{"create":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}}]}`
+	var he *HTTPError
+	err := s.Send(context.Background(), testEvents())
+	if !errors.As(err, &he) || he.Status != 429 || !strings.Contains(err.Error(), "2 of 2") {
+		t.Errorf("partial failure: %v", err)
+	}
+}
+
+func TestSentinel_Send(t *testing.T) {
+	tokens := 0
+	idp, _ := recorder(t, func(r *http.Request) (int, string) {
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("scope") != defaultSentinelScope ||
+			r.PostForm.Get("client_secret") != "s3cret" {
+			return 400, `{"error":"invalid_request"}`
+		}
+		tokens++
+		return 200, `{"access_token":"entra-tok","expires_in":3600}`
+	})
+	srv, calls := recorder(t, func(*http.Request) (int, string) { return 204, "" })
+	creds := &ClientCredentials{TenantID: "tenant", ClientID: "app", ClientSecret: "s3cret", TokenURL: idp.URL}
+	s := &Sentinel{Endpoint: srv.URL, RuleID: "dcr-0123", Stream: "Custom-RTEA_CL", Token: creds.Token}
+	for i := 0; i < 2; i++ {
+		if err := s.Send(context.Background(), testEvents()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("token requests = %d, want the token cached", tokens)
+	}
+	c := (*calls)[0]
+	if c.path != "/dataCollectionRules/dcr-0123/streams/Custom-RTEA_CL" || c.query != "api-version="+sentinelAPIVersion ||
+		c.auth != "Bearer entra-tok" {
+		t.Errorf("call = %+v", c)
+	}
+	var rows []sentinelRow
+	if err := json.Unmarshal([]byte(c.body), &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1].SourceType != SourceTypeSynth || string(rows[1].Event) != `"login failed"` {
+		t.Errorf("rows = %+v", rows)
+	}
+
+	creds.ClientSecret = "wrong"
+	creds.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if err := s.Send(context.Background(), testEvents()); err == nil || !strings.Contains(err.Error(), "authenticate") {
+		t.Errorf("send with a refused token: %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+// Package siem forwards audit-log entries and synthetic events straight to
+// a SIEM: Splunk's HTTP Event Collector, Elasticsearch's bulk API, or
+// Azure Sentinel through the Logs Ingestion API. A Forwarder batches events
+// for one destination and retries failed batches with backoff; each
+// destination carries its own credentials.
+package siem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Source types the Forwarder gives the events it builds.
+const (
+	SourceTypeAudit = "rte:audit"
+	SourceTypeSynth = "rte:synth"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	defaultBuffer        = 1024
+	defaultAttempts      = 3
+	defaultBackoff       = time.Second
+	// drainTimeout bounds the final flush once Run's context ends.
+	drainTimeout     = 10 * time.Second
+	maxResponseBytes = 64 << 10
+)
+
+// Event is one record to forward.
+type Event struct {
+	Time time.Time
+	// SourceType tells the SIEM how to parse Body, as SourceTypeAudit.
+	SourceType string
+	// Body is the record itself, as a JSON value.
+	Body json.RawMessage
+}
+
+// Destination delivers a batch of events to one SIEM.
+type Destination interface {
+	Send(ctx context.Context, events []Event) error
+}
+
+// HTTPError is a SIEM's refusal of a request.
+type HTTPError struct {
+	Status int
+	Body   string
+	// RetryAfter is the wait the SIEM asked for, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.Status, e.Body)
+}
+
+// Temporary reports whether the request may succeed if retried: on a
+// timeout, throttling, or a server error.
+func (e *HTTPError) Temporary() bool {
+	return e.Status == http.StatusRequestTimeout || e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+// retryable reports whether a failed send is worth retrying. Errors other
+// than a SIEM's refusal are network failures, and are.
+func retryable(err error) bool {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.Temporary()
+	}
+	return true
+}
+
+// post sends body to u and returns the response body, or an *HTTPError
+// for a non-2xx status.
+func post(ctx context.Context, client *http.Client, u, contentType string, body []byte, auth func(*http.Request) error) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	if err := auth(req); err != nil {
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		he := &HTTPError{Status: resp.StatusCode, Body: string(bytes.TrimSpace(msg))}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			he.RetryAfter = time.Duration(secs) * time.Second
+		}
+		return nil, he
+	}
+	return msg, nil
+}
+
+// Forwarder batches events for one destination. Forward and Audit only
+// queue, so callers such as rte.AuditLog.OnAppend are never held up by a
+// slow SIEM; Run delivers queued events. Send delivers synthetic events
+// directly, so a Forwarder also serves as a synth.EventSink.
+type Forwarder struct {
+	Destination Destination
+	// BatchSize caps the events per request; defaults to 100. Run sends a
+	// partial batch after FlushInterval, which defaults to 5s.
+	BatchSize     int
+	FlushInterval time.Duration
+	// Buffer is how many events may wait for Run; defaults to 1024.
+	Buffer int
+	// Attempts per batch; defaults to 3. Backoff doubles after each
+	// failure, starting at 1s, unless the SIEM asks for a longer wait.
+	// Batches the SIEM rejects outright are not retried.
+	Attempts int
+	Backoff  time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// Logger, if set, records dropped and failed batches.
+	Logger *slog.Logger
+
+	once  sync.Once
+	queue chan Event
+}
+
+func (f *Forwarder) init() {
+	f.once.Do(func() {
+		n := f.Buffer
+		if n <= 0 {
+			n = defaultBuffer
+		}
+		f.queue = make(chan Event, n)
+	})
+}
+
+func (f *Forwarder) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+func (f *Forwarder) batchSize() int {
+	if f.BatchSize > 0 {
+		return f.BatchSize
+	}
+	return defaultBatchSize
+}
+
+// Forward queues an event for Run. It never blocks; if the queue is full
+// the event is dropped and logged.
+func (f *Forwarder) Forward(e Event) {
+	f.init()
+	select {
+	case f.queue <- e:
+	default:
+		if f.Logger != nil {
+			f.Logger.Warn("SIEM event dropped", "source_type", e.SourceType)
+		}
+	}
+}
+
+// Audit queues an audit record; it is meant for rte.AuditLog.OnAppend.
+func (f *Forwarder) Audit(r rte.AuditRecord) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	at, err := time.Parse(time.RFC3339, r.Timestamp)
+	if err != nil {
+		at = f.now()
+	}
+	f.Forward(Event{Time: at.UTC(), SourceType: SourceTypeAudit, Body: body})
+}
+
+// Send implements synth.EventSink, delivering lines in batches before it
+// returns. A line that is not JSON is forwarded as a JSON string.
+func (f *Forwarder) Send(ctx context.Context, lines [][]byte) error {
+	at := f.now().UTC()
+	events := make([]Event, 0, len(lines))
+	for _, l := range lines {
+		l = bytes.TrimRight(l, "\r\n")
+		body := json.RawMessage(l)
+		if !json.Valid(l) {
+			s, err := json.Marshal(string(l))
+			if err != nil {
+				return err
+			}
+			body = s
+		}
+		events = append(events, Event{Time: at, SourceType: SourceTypeSynth, Body: body})
+	}
+	for n := f.batchSize(); len(events) > 0; {
+		batch := events[:min(n, len(events))]
+		if err := f.deliver(ctx, batch); err != nil {
+			return err
+		}
+		events = events[len(batch):]
+	}
+	return nil
+}
+
+// Run delivers queued events until ctx ends, then makes a last attempt to
+// deliver what is still queued.
+func (f *Forwarder) Run(ctx context.Context) error {
+	f.init()
+	if f.Destination == nil {
+		return errors.New("destination is required")
+	}
+	interval := f.FlushInterval
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	size := f.batchSize()
+	var batch []Event
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := f.deliver(ctx, batch); err != nil && f.Logger != nil {
+			f.Logger.Error("SIEM batch failed", "events", len(batch), "error", err)
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			dctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), drainTimeout)
+			defer cancel()
+		drain:
+			for {
+				select {
+				case e := <-f.queue:
+					if batch = append(batch, e); len(batch) == size {
+						flush(dctx)
+					}
+				default:
+					break drain
+				}
+			}
+			flush(dctx)
+			return ctx.Err()
+		case e := <-f.queue:
+			if batch = append(batch, e); len(batch) == size {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// deliver sends one batch, retrying temporary failures with exponential
+// backoff.
+func (f *Forwarder) deliver(ctx context.Context, batch []Event) error {
+	if f.Destination == nil {
+		return errors.New("destination is required")
+	}
+	attempts := f.Attempts
+	if attempts <= 0 {
+		attempts = defaultAttempts
+	}
+	backoff := f.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	var errs []error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			wait := backoff
+			var he *HTTPError
+			if errors.As(errs[len(errs)-1], &he) && he.RetryAfter > wait {
+				wait = he.RetryAfter
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Join(append(errs, ctx.Err())...)
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+		err := f.Destination.Send(ctx, batch)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+		if !retryable(err) {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,95 @@
+package siem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// fakeDestination records batches and fails the first len(errs) sends.
+type fakeDestination struct {
+	mu      sync.Mutex
+	errs    []error
+	calls   int
+	batches [][]Event
+	sent    chan struct{}
+}
+
+func (d *fakeDestination) Send(_ context.Context, events []Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	if len(d.errs) > 0 {
+		err := d.errs[0]
+		d.errs = d.errs[1:]
+		return err
+	}
+	d.batches = append(d.batches, append([]Event(nil), events...))
+	if d.sent != nil {
+		d.sent <- struct{}{}
+	}
+	return nil
+}
+
+func TestForwarder_RetriesTemporaryFailures(t *testing.T) {
+	dest := &fakeDestination{errs: []error{&HTTPError{Status: 503}, errors.New("connection reset")}}
+	f := &Forwarder{Destination: dest, Backoff: time.Millisecond}
+	if err := f.Send(context.Background(), [][]byte{[]byte(`{"event":"login"}`), []byte("plain text\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if dest.calls != 3 || len(dest.batches) != 1 {
+		t.Fatalf("calls = %d, batches = %d", dest.calls, len(dest.batches))
+	}
+	b := dest.batches[0]
+	if string(b[0].Body) != `{"event":"login"}` || string(b[1].Body) != `"plain text"` || b[1].SourceType != SourceTypeSynth {
+		t.Errorf("batch = %+v", b)
+	}
+
+	dest = &fakeDestination{errs: []error{&HTTPError{Status: 400, Body: "bad token"}}}
+	f = &Forwarder{Destination: dest, Backoff: time.Millisecond}
+	var he *HTTPError
+	if err := f.Send(context.Background(), [][]byte{[]byte(`{}`)}); !errors.As(err, &he) || he.Status != 400 {
+		t.Errorf("send = %v", err)
+	}
+	if dest.calls != 1 {
+		t.Errorf("retried a rejected batch: %d calls", dest.calls)
+	}
+}
+
+func TestForwarder_BatchesAuditRecords(t *testing.T) {
+	dest := &fakeDestination{sent: make(chan struct{}, 4)}
+	f := &Forwarder{Destination: dest, BatchSize: 2, FlushInterval: time.Hour}
+	audit := rte.NewAuditLog("op-alice")
+	audit.OnAppend = f.Audit
+	for _, action := range []string{"task.create", "task.approve", "task.cancel"} {
+		if _, err := audit.Append("eng-1", action, "lead-bob", "t-1", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- f.Run(ctx) }()
+	<-dest.sent
+	cancel()
+	<-done
+
+	dest.mu.Lock()
+	defer dest.mu.Unlock()
+	if len(dest.batches) != 2 || len(dest.batches[0]) != 2 || len(dest.batches[1]) != 1 {
+		t.Fatalf("batches = %+v", dest.batches)
+	}
+	var rec rte.AuditRecord
+	last := dest.batches[1][0]
+	if err := json.Unmarshal(last.Body, &rec); err != nil {
+		t.Fatal(err)
+	}
+	if last.SourceType != SourceTypeAudit || rec.Action != "task.cancel" || rec.Sequence != 3 || last.Time.IsZero() {
+		t.Errorf("forwarded %+v: %+v", last, rec)
+	}
+}
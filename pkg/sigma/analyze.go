@@ -0,0 +1,247 @@
+package sigma
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/synth"
+)
+
+// Event is one emitted synthetic event, flattened to the field names
+// Sigma rules use (Windows event log and Sysmon names).
+type Event struct {
+	Time       time.Time
+	Engagement string
+	TaskID     string
+	LogSource  LogSource
+	Fields     map[string]string
+}
+
+// field looks a field up by name, falling back to a case-insensitive
+// match.
+func (e Event) field(name string) (string, bool) {
+	if v, ok := e.Fields[name]; ok {
+		return v, true
+	}
+	for k, v := range e.Fields {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// FromSecurityEvent converts a generated security event into the fields
+// its Windows record carries, as synth's EVTX format renders it.
+func FromSecurityEvent(e synth.SecurityEvent) Event {
+	f := map[string]string{
+		"EventID":  strconv.Itoa(e.EventID),
+		"Computer": e.Host + "." + e.Domain,
+	}
+	add := func(name, value string) {
+		if value != "" {
+			f[name] = value
+		}
+	}
+	src := LogSource{Product: "windows"}
+	switch e.Kind {
+	case synth.EventFailedLogin:
+		src.Service = "security"
+		f["Channel"], f["Provider_Name"] = "Security", "Microsoft-Windows-Security-Auditing"
+		add("TargetUserName", e.User)
+		add("TargetDomainName", e.Domain)
+		add("IpAddress", e.SourceIP)
+		f["LogonType"], f["Status"], f["SubStatus"] = "3", "0xc000006d", "0xc000006a"
+	case synth.EventProcessCreation:
+		// Process-creation rules are written against Sysmon's field
+		// names; the 4688 names are kept too.
+		src.Category, src.Service = "process_creation", "security"
+		f["Channel"], f["Provider_Name"] = "Security", "Microsoft-Windows-Security-Auditing"
+		add("Image", e.Image)
+		add("NewProcessName", e.Image)
+		add("ParentImage", e.Parent)
+		add("ParentProcessName", e.Parent)
+		add("CommandLine", e.Command)
+		add("User", e.Domain+`\`+e.User)
+		add("SubjectUserName", e.User)
+		if e.PID != 0 {
+			f["ProcessId"] = strconv.Itoa(e.PID)
+		}
+	case synth.EventDNSQuery:
+		src.Category, src.Service = "dns_query", "sysmon"
+		f["Channel"], f["Provider_Name"] = "Microsoft-Windows-Sysmon/Operational", "Microsoft-Windows-Sysmon"
+		add("User", e.Domain+`\`+e.User)
+		add("QueryName", e.Query)
+		f["QueryStatus"] = "0"
+	}
+	return Event{Time: e.Time.UTC(), Engagement: e.Engagement, TaskID: e.TaskID, LogSource: src, Fields: f}
+}
+
+// sysmonCategories maps Sysmon event IDs to Sigma log source categories.
+var sysmonCategories = map[int]string{
+	synth.SysmonProcessCreate:  "process_creation",
+	synth.SysmonNetworkConnect: "network_connection",
+	synth.SysmonImageLoad:      "image_load",
+}
+
+// FromSysmonEvent converts a generated Sysmon event. The task comes from
+// the event's RuleName attribution.
+func FromSysmonEvent(e synth.SysmonEvent, engagement string) Event {
+	f := map[string]string{
+		"EventID":  strconv.Itoa(e.EventID),
+		"Computer": e.Computer,
+		"Channel":  "Microsoft-Windows-Sysmon/Operational",
+	}
+	for _, d := range e.Data {
+		f[d.Name] = d.Value
+	}
+	return Event{
+		Time: e.Time.UTC(), Engagement: engagement,
+		TaskID:    strings.TrimPrefix(e.Field("RuleName"), "rte-a-synthetic:"),
+		LogSource: LogSource{Category: sysmonCategories[e.EventID], Product: "windows", Service: "sysmon"},
+		Fields:    f,
+	}
+}
+
+// Report is a detection-expectation report: for each emitted event, the
+// rules expected to fire on it, and for each rule, what it should have
+// fired on.
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// RulesEvaluated counts the rules evaluated; Skipped lists those that
+	// were not, whose alerts, if any, the report cannot predict.
+	RulesEvaluated int           `json:"rules_evaluated"`
+	Skipped        []SkippedRule `json:"skipped,omitempty"`
+	// Expected lists the rules expected to fire, by rule key.
+	Expected []RuleExpectation `json:"expected"`
+	// Events lists every event in the order given.
+	Events []EventExpectation `json:"events"`
+	// Unmatched counts the events no rule is expected to fire on.
+	Unmatched int `json:"unmatched"`
+}
+
+// RuleExpectation is one rule expected to fire.
+type RuleExpectation struct {
+	Key        string    `json:"key"`
+	Title      string    `json:"title"`
+	Level      string    `json:"level,omitempty"`
+	Techniques []string  `json:"techniques,omitempty"`
+	Events     int       `json:"events"`
+	Tasks      []string  `json:"tasks"`
+	First      time.Time `json:"first"`
+	Last       time.Time `json:"last"`
+}
+
+// EventExpectation is one emitted event and the rules expected to fire on
+// it.
+type EventExpectation struct {
+	Index      int       `json:"index"`
+	Time       time.Time `json:"time"`
+	Engagement string    `json:"engagement"`
+	TaskID     string    `json:"task_id"`
+	Computer   string    `json:"computer,omitempty"`
+	EventID    string    `json:"event_id,omitempty"`
+	Rules      []string  `json:"rules"`
+}
+
+// Analyze evaluates every rule in set against every event.
+func Analyze(set *RuleSet, events []Event, now time.Time) *Report {
+	rep := &Report{
+		GeneratedAt: now.UTC(), RulesEvaluated: len(set.Rules),
+		Skipped: set.Skipped, Expected: []RuleExpectation{}, Events: make([]EventExpectation, 0, len(events)),
+	}
+	byKey := make(map[string]*RuleExpectation)
+	tasks := make(map[string]map[string]bool)
+	for i, e := range events {
+		ee := EventExpectation{
+			Index: i, Time: e.Time.UTC(), Engagement: e.Engagement, TaskID: e.TaskID,
+			Computer: e.Fields["Computer"], EventID: e.Fields["EventID"], Rules: []string{},
+		}
+		for _, r := range set.Rules {
+			if !r.Matches(e) {
+				continue
+			}
+			key := r.Key()
+			ee.Rules = append(ee.Rules, key)
+			x, ok := byKey[key]
+			if !ok {
+				x = &RuleExpectation{Key: key, Title: r.Title, Level: r.Level, Techniques: r.Techniques, First: ee.Time, Last: ee.Time}
+				byKey[key], tasks[key] = x, map[string]bool{}
+			}
+			x.Events++
+			if ee.Time.Before(x.First) {
+				x.First = ee.Time
+			}
+			if ee.Time.After(x.Last) {
+				x.Last = ee.Time
+			}
+			if e.TaskID != "" && !tasks[key][e.TaskID] {
+				tasks[key][e.TaskID] = true
+				x.Tasks = append(x.Tasks, e.TaskID)
+			}
+		}
+		sort.Strings(ee.Rules)
+		if len(ee.Rules) == 0 {
+			rep.Unmatched++
+		}
+		rep.Events = append(rep.Events, ee)
+	}
+	for _, x := range byKey {
+		sort.Strings(x.Tasks)
+		if x.Tasks == nil {
+			x.Tasks = []string{}
+		}
+		rep.Expected = append(rep.Expected, *x)
+	}
+	sort.Slice(rep.Expected, func(i, j int) bool { return rep.Expected[i].Key < rep.Expected[j].Key })
+	return rep
+}
+
+// WriteCSV writes one row per expected alert (event and rule), sorted by
+// time, then rule, to diff against a SIEM's alert export. Events no rule
+// fires on are left out.
+func (r *Report) WriteCSV(w io.Writer) error {
+	titles := make(map[string]string, len(r.Expected))
+	for _, x := range r.Expected {
+		titles[x.Key] = x.Title
+	}
+	type row struct {
+		ev   EventExpectation
+		rule string
+	}
+	var rows []row
+	for _, ev := range r.Events {
+		for _, rule := range ev.Rules {
+			rows = append(rows, row{ev, rule})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !rows[i].ev.Time.Equal(rows[j].ev.Time) {
+			return rows[i].ev.Time.Before(rows[j].ev.Time)
+		}
+		return rows[i].rule < rows[j].rule
+	})
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "engagement", "task_id", "computer", "event_id", "rule", "title", "event_index"}); err != nil {
+		return err
+	}
+	for _, x := range rows {
+		rec := []string{
+			x.ev.Time.Format(time.RFC3339Nano), x.ev.Engagement, x.ev.TaskID, x.ev.Computer,
+			x.ev.EventID, x.rule, titles[x.rule], strconv.Itoa(x.ev.Index),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return nil
+}
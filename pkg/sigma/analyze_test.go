@@ -0,0 +1,88 @@
+package sigma
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/synth"
+)
+
+func TestAnalyze(t *testing.T) {
+	set, err := LoadRules(os.DirFS("testdata/rules"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Rules) != 3 || len(set.Skipped) != 1 || !strings.Contains(set.Skipped[0].Reason, "aggregation") {
+		t.Fatalf("rules = %d, skipped = %+v", len(set.Rules), set.Skipped)
+	}
+
+	ids, err := synth.NewIdentitySet("eng-2026-q1", synth.IdentityConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	gen, err := synth.NewEventGenerator(ids, "task-logins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	for i, kind := range []synth.EventKind{synth.EventFailedLogin, synth.EventDNSQuery, synth.EventFailedLogin} {
+		e, err := gen.Next(kind, start.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, FromSecurityEvent(e))
+	}
+	for i, parent := range []string{`C:\Windows\System32\cmd.exe`, `C:\Windows\explorer.exe`} {
+		events = append(events, FromSecurityEvent(synth.SecurityEvent{
+			Time: start.Add(time.Duration(3+i) * time.Second), Kind: synth.EventProcessCreation, EventID: 4688,
+			Engagement: "eng-2026-q1", TaskID: "task-recon", Host: "ws-01", Domain: "corp.example",
+			User: "alice", Image: `C:\Windows\System32\whoami.exe`, Parent: parent, Command: "whoami /all",
+		}))
+	}
+	sysmon, err := synth.SysmonEvents(ids, synth.SysmonConfig{Scenario: synth.ScenarioLOLBinDownload, Start: start, TaskID: "task-lolbin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range sysmon {
+		events = append(events, FromSysmonEvent(e, "eng-2026-q1"))
+	}
+
+	rep := Analyze(set, events, start)
+	keys := map[string]RuleExpectation{}
+	for _, x := range rep.Expected {
+		keys[x.Title] = x
+	}
+	if x := keys["Failed Network Logon"]; x.Events != 2 || len(x.Tasks) != 1 || x.Tasks[0] != "task-logins" ||
+		!x.First.Equal(start) || !x.Last.Equal(start.Add(2*time.Second)) || x.Techniques[0] != "T1110.003" {
+		t.Errorf("failed logon expectation = %+v", x)
+	}
+	if x := keys["Certutil Download"]; x.Events != 1 || x.Tasks[0] != "task-lolbin" || x.Level != "high" {
+		t.Errorf("certutil expectation = %+v", x)
+	}
+	if x := keys["Domain Discovery Commands"]; x.Events != 1 || x.Tasks[0] != "task-recon" || len(x.Techniques) != 2 {
+		t.Errorf("discovery expectation = %+v", x)
+	}
+	if len(rep.Events) != len(events) || len(rep.Events[1].Rules) != 0 || rep.Unmatched != len(events)-4 {
+		t.Errorf("events = %+v, unmatched = %d", rep.Events, rep.Unmatched)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+4 || rows[0][5] != "rule" {
+		t.Fatalf("csv = %v", rows)
+	}
+	if rows[1][0] != "2026-03-02T09:00:00Z" || rows[1][2] != "task-logins" || rows[1][6] != "Failed Network Logon" {
+		t.Errorf("first row = %v", rows[1])
+	}
+}
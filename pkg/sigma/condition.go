@@ -0,0 +1,167 @@
+package sigma
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// expr is a parsed rule condition.
+type expr interface {
+	eval(d map[string]*detection, e Event) bool
+}
+
+type (
+	ref     string
+	notExpr struct{ x expr }
+	andExpr struct{ x, y expr }
+	orExpr  struct{ x, y expr }
+	// ofExpr is "1 of" or "all of" a set of searches.
+	ofExpr struct {
+		all   bool
+		names []string
+	}
+)
+
+func (r ref) eval(d map[string]*detection, e Event) bool {
+	return d[string(r)].match(e)
+}
+
+func (n notExpr) eval(d map[string]*detection, e Event) bool {
+	return !n.x.eval(d, e)
+}
+
+func (a andExpr) eval(d map[string]*detection, e Event) bool {
+	return a.x.eval(d, e) && a.y.eval(d, e)
+}
+
+func (o orExpr) eval(d map[string]*detection, e Event) bool {
+	return o.x.eval(d, e) || o.y.eval(d, e)
+}
+
+func (o ofExpr) eval(d map[string]*detection, e Event) bool {
+	for _, n := range o.names {
+		if d[n].match(e) != o.all {
+			return !o.all
+		}
+	}
+	return o.all
+}
+
+// parseCondition parses a condition over the named searches.
+func parseCondition(s string, names []string) (expr, error) {
+	if strings.Contains(s, "|") {
+		return nil, fmt.Errorf("%w: aggregation", ErrUnsupported)
+	}
+	p := &condParser{names: names}
+	for _, f := range strings.FieldsFunc(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s), unicode.IsSpace) {
+		p.toks = append(p.toks, f)
+	}
+	if len(p.toks) == 0 {
+		return nil, errors.New("empty condition")
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.i])
+	}
+	return e, nil
+}
+
+type condParser struct {
+	toks  []string
+	i     int
+	names []string
+}
+
+func (p *condParser) peek() string {
+	if p.i < len(p.toks) {
+		return strings.ToLower(p.toks[p.i])
+	}
+	return ""
+}
+
+func (p *condParser) or() (expr, error) {
+	x, err := p.and()
+	for err == nil && p.peek() == "or" {
+		p.i++
+		var y expr
+		if y, err = p.and(); err == nil {
+			x = orExpr{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *condParser) and() (expr, error) {
+	x, err := p.unary()
+	for err == nil && p.peek() == "and" {
+		p.i++
+		var y expr
+		if y, err = p.unary(); err == nil {
+			x = andExpr{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *condParser) unary() (expr, error) {
+	if p.peek() == "not" {
+		p.i++
+		x, err := p.unary()
+		return notExpr{x}, err
+	}
+	return p.primary()
+}
+
+func (p *condParser) primary() (expr, error) {
+	switch tok := p.peek(); tok {
+	case "":
+		return nil, errors.New("condition ends early")
+	case "(":
+		p.i++
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.i++
+		return x, nil
+	case "1", "any", "all":
+		p.i++
+		if p.peek() != "of" {
+			return nil, fmt.Errorf("expected \"of\" after %q", tok)
+		}
+		p.i++
+		target := p.peek()
+		if target == "" {
+			return nil, errors.New("condition ends early")
+		}
+		pattern := p.toks[p.i]
+		p.i++
+		var names []string
+		for _, n := range p.names {
+			if ok, _ := path.Match(pattern, n); ok || target == "them" {
+				names = append(names, n)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%q matches no search", pattern)
+		}
+		return ofExpr{all: tok == "all", names: names}, nil
+	}
+	name := p.toks[p.i]
+	for _, n := range p.names {
+		if n == name {
+			p.i++
+			return ref(name), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown search %q", name)
+}
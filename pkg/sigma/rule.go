@@ -0,0 +1,495 @@
+// Package sigma evaluates Sigma detection rules against the synthetic
+// events an engagement emitted, producing a detection-expectation report:
+// which rules should have fired, on which events, so the blue team can
+// diff it against the alerts they actually saw.
+//
+// Rules are parsed from their YAML files. The common subset of the
+// specification is supported: selections of field maps, lists of maps,
+// and keywords; the contains, startswith, endswith, all, re, cidr, cased,
+// exists, and numeric comparison modifiers; wildcards; and conditions
+// with and, or, not, parentheses, and "1 of" or "all of" a pattern or
+// them. Rules that need anything else, such as aggregations or correlation
+// across events, are skipped with a reason rather than evaluated wrongly.
+package sigma
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/codethor0/rte-a-reference/pkg/miniyaml"
+)
+
+// ErrUnsupported marks a rule that uses Sigma features this package does
+// not evaluate.
+var ErrUnsupported = errors.New("unsupported Sigma feature")
+
+// LogSource is the log a rule applies to, or an event came from. Empty
+// fields of a rule's log source match anything.
+type LogSource struct {
+	Category string `json:"category,omitempty"`
+	Product  string `json:"product,omitempty"`
+	Service  string `json:"service,omitempty"`
+}
+
+// covers reports whether a rule with log source l applies to an event from
+// src.
+func (l LogSource) covers(src LogSource) bool {
+	eq := func(want, got string) bool { return want == "" || strings.EqualFold(want, got) }
+	return eq(l.Category, src.Category) && eq(l.Product, src.Product) && eq(l.Service, src.Service)
+}
+
+// Rule is a parsed Sigma rule.
+type Rule struct {
+	ID          string
+	Title       string
+	Status      string
+	Level       string
+	Description string
+	Tags        []string
+	LogSource   LogSource
+	// Techniques are the ATT&CK technique IDs from the rule's attack.t
+	// tags, as T1059.001.
+	Techniques []string
+
+	detections map[string]*detection
+	condition  expr
+}
+
+// Key identifies the rule in reports: its ID, or its title if it has none.
+func (r *Rule) Key() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Title
+}
+
+// Matches reports whether the rule fires on e.
+func (r *Rule) Matches(e Event) bool {
+	return r.LogSource.covers(e.LogSource) && r.condition.eval(r.detections, e)
+}
+
+// SkippedRule is a rule in a set that could not be evaluated.
+type SkippedRule struct {
+	File   string `json:"file"`
+	ID     string `json:"id,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// RuleSet is a collection of rules, with the ones that were skipped.
+type RuleSet struct {
+	Rules   []*Rule
+	Skipped []SkippedRule
+}
+
+// Add parses the rules in one file, named name, into the set. A file that
+// is not valid YAML or not a Sigma rule is an error; a rule that uses
+// unsupported features is recorded in Skipped.
+func (s *RuleSet) Add(name string, data []byte) error {
+	docs, err := miniyaml.DecodeAll(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		m, ok := doc.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: rule is not a mapping", name)
+		}
+		r, err := parseRule(m)
+		switch {
+		case errors.Is(err, ErrUnsupported):
+			s.Skipped = append(s.Skipped, SkippedRule{File: name, ID: str(m["id"]), Title: str(m["title"]), Reason: err.Error()})
+		case err != nil:
+			return fmt.Errorf("%s: %w", name, err)
+		default:
+			s.Rules = append(s.Rules, r)
+		}
+	}
+	return nil
+}
+
+// LoadRules reads every .yml and .yaml file under fsys into a rule set,
+// in path order.
+func LoadRules(fsys fs.FS) (*RuleSet, error) {
+	s := &RuleSet{}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := path.Ext(p); ext != ".yml" && ext != ".yaml" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return s.Add(p, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func str(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
+
+var techniqueTag = regexp.MustCompile(`^attack\.(t\d{4}(\.\d{3})?)$`)
+
+func parseRule(m map[string]any) (*Rule, error) {
+	r := &Rule{
+		ID: str(m["id"]), Title: str(m["title"]), Status: str(m["status"]),
+		Level: str(m["level"]), Description: str(m["description"]),
+	}
+	if r.Title == "" {
+		return nil, errors.New("rule has no title")
+	}
+	if _, ok := m["correlation"]; ok {
+		return nil, fmt.Errorf("%w: correlation rules", ErrUnsupported)
+	}
+	if _, ok := m["action"]; ok {
+		return nil, fmt.Errorf("%w: rule collections", ErrUnsupported)
+	}
+	if tags, ok := m["tags"].([]any); ok {
+		for _, t := range tags {
+			tag := strings.ToLower(str(t))
+			r.Tags = append(r.Tags, tag)
+			if sub := techniqueTag.FindStringSubmatch(tag); sub != nil {
+				r.Techniques = append(r.Techniques, strings.ToUpper(sub[1]))
+			}
+		}
+	}
+	if ls, ok := m["logsource"].(map[string]any); ok {
+		r.LogSource = LogSource{Category: str(ls["category"]), Product: str(ls["product"]), Service: str(ls["service"])}
+	}
+	det, ok := m["detection"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("rule %q has no detection", r.Title)
+	}
+	r.detections = make(map[string]*detection)
+	for name, v := range det {
+		if name == "condition" || name == "timeframe" {
+			continue
+		}
+		d, err := parseDetection(v)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q, %s: %w", r.Title, name, err)
+		}
+		r.detections[name] = d
+	}
+	var conds []string
+	switch c := det["condition"].(type) {
+	case string:
+		conds = []string{c}
+	case []any:
+		for _, x := range c {
+			conds = append(conds, str(x))
+		}
+	}
+	if len(conds) == 0 {
+		return nil, fmt.Errorf("rule %q has no condition", r.Title)
+	}
+	names := make([]string, 0, len(r.detections))
+	for n := range r.detections {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, c := range conds {
+		e, err := parseCondition(c, names)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q, condition %q: %w", r.Title, c, err)
+		}
+		if r.condition == nil {
+			r.condition = e
+		} else {
+			r.condition = orExpr{r.condition, e}
+		}
+	}
+	return r, nil
+}
+
+// detection is one named search: groups of field matchers, any of which
+// must all match, or keywords, any of which must appear in the event.
+type detection struct {
+	groups   [][]fieldMatcher
+	keywords []valueMatcher
+}
+
+func (d *detection) match(e Event) bool {
+	for _, g := range d.groups {
+		all := true
+		for _, fm := range g {
+			if !fm.match(e) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	for _, k := range d.keywords {
+		for _, v := range e.Fields {
+			if k.match(v, true) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseDetection(v any) (*detection, error) {
+	d := &detection{}
+	switch v := v.(type) {
+	case map[string]any:
+		g, err := parseGroup(v)
+		if err != nil {
+			return nil, err
+		}
+		d.groups = [][]fieldMatcher{g}
+	case []any:
+		for _, item := range v {
+			if m, ok := item.(map[string]any); ok {
+				g, err := parseGroup(m)
+				if err != nil {
+					return nil, err
+				}
+				d.groups = append(d.groups, g)
+				continue
+			}
+			vm, err := newValueMatcher(item, []string{"contains"})
+			if err != nil {
+				return nil, err
+			}
+			d.keywords = append(d.keywords, vm)
+		}
+	case nil:
+		return nil, errors.New("empty search")
+	default:
+		vm, err := newValueMatcher(v, []string{"contains"})
+		if err != nil {
+			return nil, err
+		}
+		d.keywords = []valueMatcher{vm}
+	}
+	return d, nil
+}
+
+// fieldMatcher matches one field against a list of values: any of them,
+// or with the all modifier every one.
+type fieldMatcher struct {
+	field  string
+	all    bool
+	exists *bool
+	values []valueMatcher
+}
+
+func (fm fieldMatcher) match(e Event) bool {
+	v, ok := e.field(fm.field)
+	if fm.exists != nil {
+		return ok == *fm.exists
+	}
+	for _, vm := range fm.values {
+		hit := vm.match(v, ok)
+		if hit && !fm.all {
+			return true
+		}
+		if !hit && fm.all {
+			return false
+		}
+	}
+	return fm.all && len(fm.values) > 0
+}
+
+func parseGroup(m map[string]any) ([]fieldMatcher, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var out []fieldMatcher
+	for _, k := range keys {
+		field, mods, _ := strings.Cut(k, "|")
+		fm := fieldMatcher{field: field}
+		var valueMods []string
+		if mods != "" {
+			for _, mod := range strings.Split(mods, "|") {
+				switch mod {
+				case "all":
+					fm.all = true
+				case "exists":
+					b, ok := m[k].(bool)
+					if !ok {
+						return nil, fmt.Errorf("%s: exists takes true or false", k)
+					}
+					fm.exists = &b
+				default:
+					valueMods = append(valueMods, mod)
+				}
+			}
+		}
+		if fm.exists != nil {
+			out = append(out, fm)
+			continue
+		}
+		vals, ok := m[k].([]any)
+		if !ok {
+			vals = []any{m[k]}
+		}
+		for _, v := range vals {
+			vm, err := newValueMatcher(v, valueMods)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			fm.values = append(fm.values, vm)
+		}
+		out = append(out, fm)
+	}
+	return out, nil
+}
+
+// valueMatcher matches one field value.
+type valueMatcher struct {
+	null   bool
+	re     *regexp.Regexp
+	prefix *netip.Prefix
+	cmp    string
+	num    float64
+}
+
+// match reports whether value, present or not, matches.
+func (vm valueMatcher) match(value string, present bool) bool {
+	switch {
+	case vm.null:
+		return !present || value == ""
+	case !present:
+		return false
+	case vm.prefix != nil:
+		a, err := netip.ParseAddr(value)
+		return err == nil && vm.prefix.Contains(a.Unmap())
+	case vm.cmp != "":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		switch vm.cmp {
+		case "lt":
+			return n < vm.num
+		case "lte":
+			return n <= vm.num
+		case "gt":
+			return n > vm.num
+		}
+		return n >= vm.num
+	}
+	return vm.re.MatchString(value)
+}
+
+func newValueMatcher(v any, mods []string) (valueMatcher, error) {
+	if v == nil {
+		return valueMatcher{null: true}, nil
+	}
+	if _, ok := v.(map[string]any); ok {
+		return valueMatcher{}, errors.New("a value cannot be a mapping")
+	}
+	if _, ok := v.([]any); ok {
+		return valueMatcher{}, errors.New("a value cannot be a nested list")
+	}
+	s := str(v)
+	var where, kind string
+	cased := false
+	for _, mod := range mods {
+		switch mod {
+		case "contains", "startswith", "endswith":
+			where = mod
+		case "re", "cidr", "lt", "lte", "gt", "gte":
+			kind = mod
+		case "cased":
+			cased = true
+		default:
+			return valueMatcher{}, fmt.Errorf("%w: modifier %q", ErrUnsupported, mod)
+		}
+	}
+	switch kind {
+	case "re":
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return valueMatcher{}, fmt.Errorf("regular expression: %w", err)
+		}
+		return valueMatcher{re: re}, nil
+	case "cidr":
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return valueMatcher{}, fmt.Errorf("cidr: %w", err)
+		}
+		p = p.Masked()
+		return valueMatcher{prefix: &p}, nil
+	case "":
+	default:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return valueMatcher{}, fmt.Errorf("%s needs a number: %w", kind, err)
+		}
+		return valueMatcher{cmp: kind, num: n}, nil
+	}
+	pattern := wildcard(s)
+	switch where {
+	case "contains":
+		pattern = ".*" + pattern + ".*"
+	case "startswith":
+		pattern += ".*"
+	case "endswith":
+		pattern = ".*" + pattern
+	}
+	flags := "(?s"
+	if !cased {
+		flags += "i"
+	}
+	re, err := regexp.Compile(flags + ")^" + pattern + "$")
+	if err != nil {
+		return valueMatcher{}, err
+	}
+	return valueMatcher{re: re}, nil
+}
+
+// wildcard translates a Sigma value, where * and ? are wildcards and a
+// backslash escapes them or itself, to a regular expression.
+func wildcard(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(`*?\`, s[i+1]) >= 0:
+			i++
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package sigma
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustRule(t *testing.T, src string) *Rule {
+	t.Helper()
+	var s RuleSet
+	if err := s.Add("rule.yml", []byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Rules) != 1 {
+		t.Fatalf("rules = %d, skipped = %+v", len(s.Rules), s.Skipped)
+	}
+	return s.Rules[0]
+}
+
+func fields(kv ...string) Event {
+	e := Event{Fields: map[string]string{}}
+	for i := 0; i < len(kv); i += 2 {
+		e.Fields[kv[i]] = kv[i+1]
+	}
+	return e
+}
+
+func TestRule_Matches(t *testing.T) {
+	r := mustRule(t, `
+title: Modifiers
+detection:
+    sel_cmd:
+        CommandLine|contains|all: [' -enc ', 'hidden']
+        Image|endswith: '\powershell.exe'
+    sel_wild:
+        - User: 'CORP\svc_*'
+        - User: 'CORP\adm?n'
+    sel_net:
+        DestinationIp|cidr: 10.0.0.0/8
+        DestinationPort|gte: 8000
+    sel_cased:
+        QueryName|cased|startswith: 'RTE'
+    sel_null:
+        ParentImage: null
+        Hashes|exists: false
+    keywords:
+        - 'rte-a-synthetic'
+    condition: sel_cmd or 1 of sel_w* or sel_net or sel_cased or (sel_null and keywords)
+`)
+	for name, tc := range map[string]struct {
+		e    Event
+		want bool
+	}{
+		"contains all":       {fields("Image", `C:\x\PowerShell.EXE`, "CommandLine", "powershell -w HIDDEN -enc AAA"), true},
+		"contains only one":  {fields("Image", `C:\x\powershell.exe`, "CommandLine", "powershell -enc AAA"), false},
+		"wildcard star":      {fields("User", `corp\svc_backup`), true},
+		"wildcard question":  {fields("User", `CORP\admin`), true},
+		"wildcard too long":  {fields("User", `CORP\administrator`), false},
+		"cidr and gte":       {fields("DestinationIp", "10.4.5.6", "DestinationPort", "8443"), true},
+		"cidr outside":       {fields("DestinationIp", "192.168.1.1", "DestinationPort", "8443"), false},
+		"port below":         {fields("DestinationIp", "10.4.5.6", "DestinationPort", "443"), false},
+		"cased":              {fields("QueryName", "RTE-1.example"), true},
+		"cased wrong case":   {fields("QueryName", "rte-1.example"), false},
+		"null and keyword":   {fields("Message", "tagged rte-a-synthetic:t-1"), true},
+		"field present":      {fields("Message", "rte-a-synthetic", "ParentImage", "x"), false},
+		"exists false fails": {fields("Message", "rte-a-synthetic", "Hashes", "SHA256=00"), false},
+		"nothing":            {fields("Message", "hello"), false},
+	} {
+		if got := r.Matches(tc.e); got != tc.want {
+			t.Errorf("%s: matched = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestRule_LogSource(t *testing.T) {
+	r := mustRule(t, "title: x\nlogsource:\n  category: process_creation\n  product: windows\ndetection:\n  sel:\n    EventID: 1\n  condition: sel\n")
+	e := fields("EventID", "1")
+	if r.Matches(e) {
+		t.Error("matched an event from another log source")
+	}
+	e.LogSource = LogSource{Category: "process_creation", Product: "Windows", Service: "sysmon"}
+	if !r.Matches(e) {
+		t.Error("did not match an event from its log source")
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	names := []string{"filter_a", "filter_b", "sel"}
+	for cond, want := range map[string]string{
+		"sel and not (filter_a or filter_b)": "",
+		"all of them":                        "",
+		"1 of filter_*":                      "",
+		"sel and":                            "ends early",
+		"sel or missing":                     `unknown search "missing"`,
+		"1 of nope*":                         "matches no search",
+		"(sel":                               "missing )",
+		"sel sel":                            `unexpected "sel"`,
+		"sel | count() > 5":                  "aggregation",
+	} {
+		_, err := parseCondition(cond, names)
+		if (want == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), want)) {
+			t.Errorf("%q: got %v, want %q", cond, err, want)
+		}
+	}
+}
+
+func TestRuleSet_Add(t *testing.T) {
+	var s RuleSet
+	err := s.Add("two.yml", []byte(`title: Unsupported modifier
+id: r-1
+detection:
+    sel:
+        CommandLine|base64offset|contains: 'IEX'
+    condition: sel
+---
+title: Fine
+detection:
+    sel: 'mimikatz'
+    condition: sel
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Rules) != 1 || s.Rules[0].Key() != "Fine" {
+		t.Errorf("rules = %+v", s.Rules)
+	}
+	if len(s.Skipped) != 1 || s.Skipped[0].ID != "r-1" || !strings.Contains(s.Skipped[0].Reason, "base64offset") {
+		t.Errorf("skipped = %+v", s.Skipped)
+	}
+
+	for name, src := range map[string]string{
+		"no detection": "title: x\n",
+		"no condition": "title: x\ndetection:\n  sel: a\n",
+		"bad regexp":   "title: x\ndetection:\n  sel:\n    F|re: '('\n  condition: sel\n",
+		"not yaml":     "title: [x\n",
+	} {
+		if err := s.Add(name, []byte(src)); err == nil || errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package sink
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// query is the part of a DNS query the sink needs to answer it.
+type query struct {
+	id    uint16
+	flags uint16
+	// question is the raw question section, echoed in the answer.
+	question []byte
+	name     string
+}
+
+// parseQuery reads the header and single question of a DNS query, as the
+// Go resolver and every stub resolver send them.
+func parseQuery(msg []byte) (query, error) {
+	if len(msg) < 12 {
+		return query{}, errors.New("dns message too short")
+	}
+	q := query{id: binary.BigEndian.Uint16(msg), flags: binary.BigEndian.Uint16(msg[2:])}
+	if q.flags&0x8000 != 0 {
+		return query{}, errors.New("dns message is a response")
+	}
+	if binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return query{}, errors.New("dns query must have one question")
+	}
+	var labels []string
+	off := 12
+	for {
+		if off >= len(msg) {
+			return query{}, errors.New("dns name runs past the message")
+		}
+		n := int(msg[off])
+		off++
+		if n == 0 {
+			break
+		}
+		// Compression pointers cannot appear in a query's only name.
+		if n > 63 || off+n > len(msg) {
+			return query{}, errors.New("malformed dns label")
+		}
+		labels = append(labels, string(msg[off:off+n]))
+		off += n
+	}
+	// QTYPE and QCLASS follow the name.
+	if off+4 > len(msg) {
+		return query{}, errors.New("dns question truncated")
+	}
+	q.question = msg[12 : off+4]
+	q.name = strings.ToLower(strings.Join(labels, "."))
+	return q, nil
+}
+
+// nxdomain returns the answer to q: the question echoed with RCODE 3, the
+// query's opcode and recursion-desired bit kept.
+func (q query) nxdomain() []byte {
+	out := make([]byte, 12, 12+len(q.question))
+	binary.BigEndian.PutUint16(out, q.id)
+	// QR and AA set; opcode and RD copied; RCODE NXDOMAIN.
+	binary.BigEndian.PutUint16(out[2:], 0x8000|0x0400|q.flags&0x7900|3)
+	binary.BigEndian.PutUint16(out[4:], 1)
+	return append(out, q.question...)
+}
@@ -0,0 +1,20 @@
+package sink
+
+import "testing"
+
+func TestParseQuery(t *testing.T) {
+	msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, 3, 'F', 'o', 'o', 3, 'o', 'r', 'g', 0, 0, 1, 0, 1}
+	q, err := parseQuery(msg)
+	if err != nil || q.name != "foo.org" || q.id != 0x1234 {
+		t.Fatalf("parseQuery = %+v, %v", q, err)
+	}
+	ans := q.nxdomain()
+	if ans[0] != 0x12 || ans[1] != 0x34 || ans[2] != 0x85 || ans[3] != 0x03 || string(ans[12:]) != string(msg[12:]) {
+		t.Errorf("nxdomain = % x", ans)
+	}
+	for _, bad := range [][]byte{msg[:10], msg[:18], append(append([]byte{}, msg[:12]...), 64)} {
+		if _, err := parseQuery(bad); err == nil {
+			t.Errorf("parseQuery(% x) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Package sink is the receiving end of beacon and login simulations: an
+// engagement-owned HTTP endpoint and DNS server that record each simulated
+// callback reaching them and sign a receipt for it. Comparing receipts with
+// what agents reported (see Sink.Reconcile) closes the loop: it shows the
+// simulated traffic actually crossed the customer's network, rather than
+// being dropped by an egress filter an agent could not see.
+//
+// Only traffic the reference handlers send is recorded: HTTP requests with
+// their User-Agent, watermarked beacon payloads, and DNS queries under the
+// sink's domain whose first label decodes to a watermarked payload.
+// Anything else, such as scanners finding the sink, is answered and
+// forgotten.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/handlers"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// maxBodyBytes bounds how much of an HTTP callback is read.
+const maxBodyBytes = 1 << 20
+
+// Callback transports.
+const (
+	TransportHTTP = "http"
+	TransportDNS  = "dns"
+)
+
+// Callback kinds, by the simulation that sent them.
+const (
+	KindBeacon = "beacon"
+	KindLogin  = "login"
+)
+
+// Callback is one simulated callback as the sink received it. Login
+// credentials are never recorded, only that a form arrived.
+type Callback struct {
+	// Seq numbers the sink's callbacks from 1 in the order received.
+	Seq        int64     `json:"seq"`
+	Transport  string    `json:"transport"`
+	Kind       string    `json:"kind"`
+	TaskID     string    `json:"task_id,omitempty"`
+	Source     string    `json:"source"`
+	ReceivedAt time.Time `json:"received_at"`
+	// Method and Path are set for HTTP callbacks, Name for DNS queries.
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Name   string `json:"name,omitempty"`
+	// Bytes and SHA256 describe the payload: the request body, or the
+	// decoded first label of a DNS query.
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// Receipt is a callback signed by the sink's key.
+type Receipt struct {
+	Callback  Callback `json:"callback"`
+	PublicKey []byte   `json:"public_key"`
+	Signature []byte   `json:"signature"`
+}
+
+// VerifyReceipt checks the receipt signature.
+func VerifyReceipt(r *Receipt) error {
+	if r == nil {
+		return errors.New("receipt is nil")
+	}
+	if len(r.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key size")
+	}
+	if len(r.Signature) != ed25519.SignatureSize {
+		return errors.New("invalid signature size")
+	}
+	payload, err := json.Marshal(r.Callback)
+	if err != nil {
+		return fmt.Errorf("marshal callback: %w", err)
+	}
+	if !ed25519.Verify(r.PublicKey, payload, r.Signature) {
+		return errors.New("receipt signature verification failed")
+	}
+	return nil
+}
+
+// Sink records simulated callbacks and signs a receipt for each. It serves
+// HTTP callbacks as an http.Handler and DNS queries with ServeDNS. Receipts
+// are kept in memory and passed to OnReceipt for storage. It is safe for
+// concurrent use.
+type Sink struct {
+	// Signer signs receipts.
+	Signer rte.Signer
+	// Domain is the zone beacon DNS queries are sent under, such as
+	// "sink.example.org"; without it ServeDNS records nothing.
+	Domain string
+	// OnReceipt, if set, receives each new receipt.
+	OnReceipt func(Receipt)
+	// Now returns the current time; nil means time.Now.
+	Now    func() time.Time
+	Logger *slog.Logger
+
+	mu       sync.Mutex
+	seq      int64
+	receipts []Receipt
+	names    map[string]bool
+}
+
+func (s *Sink) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// record numbers, signs, and keeps cb.
+func (s *Sink) record(ctx context.Context, cb Callback, payload []byte) (*Receipt, error) {
+	if s.Signer == nil {
+		return nil, errors.New("sink needs a signer")
+	}
+	sum := sha256.Sum256(payload)
+	cb.Bytes, cb.SHA256, cb.ReceivedAt = len(payload), hex.EncodeToString(sum[:]), s.now()
+	// Numbering and signing under one lock keeps receipts in Seq order.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cb.Seq = s.seq + 1
+	msg, err := json.Marshal(cb)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.Signer.Sign(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("sign receipt: %w", err)
+	}
+	s.seq++
+	r := Receipt{Callback: cb, PublicKey: s.Signer.PublicKey(), Signature: sig}
+	s.receipts = append(s.receipts, r)
+	if s.OnReceipt != nil {
+		s.OnReceipt(r)
+	}
+	return &r, nil
+}
+
+// Receipts returns the receipts for taskID's callbacks, or every receipt
+// when taskID is empty, in Seq order.
+func (s *Sink) Receipts(taskID string) []Receipt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Receipt
+	for _, r := range s.receipts {
+		if taskID == "" || r.Callback.TaskID == taskID {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ServeHTTP records a beacon POST or login form from the reference
+// handlers. A beacon gets 200 and its receipt as JSON. A login gets 401, as
+// from a server refusing the synthetic password, so the attempt is
+// reported rejected. Everything else gets 404 and is not recorded.
+func (s *Sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var kind string
+	switch r.UserAgent() {
+	case "rte-a-simulate-beacon":
+		kind = KindBeacon
+	case "rte-a-simulate-login":
+		kind = KindLogin
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil || r.Method != http.MethodPost || kind == "" || (kind == KindBeacon && !watermarked(body)) {
+		http.NotFound(w, r)
+		return
+	}
+	cb := Callback{
+		Transport: TransportHTTP, Kind: kind, TaskID: r.Header.Get("X-RTE-A-Task"),
+		Source: r.RemoteAddr, Method: r.Method, Path: r.URL.Path,
+	}
+	if kind == KindLogin {
+		// Keep the form, and so the password, out of receipts.
+		body = nil
+	}
+	rec, err := s.record(r.Context(), cb, body)
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Error("record callback", "error", err)
+		}
+		http.Error(w, "callback not recorded", http.StatusInternalServerError)
+		return
+	}
+	if kind == KindLogin {
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// Reconciliation compares what a task's agent reported delivering with the
+// callbacks the sink received for it.
+type Reconciliation struct {
+	TaskID string `json:"task_id"`
+	// Reported counts the callbacks or attempts the agent reported
+	// reaching the sink.
+	Reported int `json:"reported"`
+	// Received counts the sink's receipts for the task.
+	Received int `json:"received"`
+}
+
+// Confirmed reports whether the sink received every callback the agent
+// reported, and the agent reported at least one.
+func (r Reconciliation) Confirmed() bool {
+	return r.Reported > 0 && r.Received >= r.Reported
+}
+
+// Reconcile checks res, the result of a simulate_beacon or simulate_login
+// task aimed at this sink, against the sink's receipts. DNS beacons carry
+// no task ID, so their receipts are matched by the result's sink domain.
+func (s *Sink) Reconcile(res *rte.TaskResult) (Reconciliation, error) {
+	rec := Reconciliation{TaskID: res.TaskID}
+	match := func(cb Callback) bool { return cb.TaskID == res.TaskID }
+	switch res.Type {
+	case rte.TaskSimulateBeacon:
+		var out handlers.BeaconResult
+		if err := json.Unmarshal(res.Output, &out); err != nil {
+			return rec, fmt.Errorf("decode beacon result: %w", err)
+		}
+		for _, cb := range out.Callbacks {
+			if cb.Outcome == "delivered" {
+				rec.Reported++
+			}
+		}
+		if out.Transport == handlers.TransportDNS {
+			suffix := "." + strings.ToLower(strings.TrimSuffix(out.Sink, "."))
+			match = func(cb Callback) bool { return cb.Transport == TransportDNS && strings.HasSuffix(cb.Name, suffix) }
+		}
+	case rte.TaskSimulateLogin:
+		var out handlers.LoginResult
+		if err := json.Unmarshal(res.Output, &out); err != nil {
+			return rec, fmt.Errorf("decode login result: %w", err)
+		}
+		for _, a := range out.Attempts {
+			if a.Outcome == "rejected" || a.Outcome == "accepted" {
+				rec.Reported++
+			}
+		}
+	default:
+		return rec, fmt.Errorf("sink cannot reconcile %s tasks", res.Type)
+	}
+	for _, r := range s.Receipts("") {
+		if match(r.Callback) {
+			rec.Received++
+		}
+	}
+	return rec, nil
+}
+
+// ServeDNS answers DNS queries on conn until ctx ends, then closes conn.
+// Every query is answered NXDOMAIN, which the beacon handler counts as
+// delivered; a query naming a watermarked beacon payload under Domain is
+// recorded once, however many times and record types it is asked for.
+func (s *Sink) ServeDNS(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		q, err := parseQuery(buf[:n])
+		if err != nil {
+			continue
+		}
+		if payload, ok := s.beaconPayload(q.name); ok && s.firstQuery(q.name) {
+			cb := Callback{Transport: TransportDNS, Kind: KindBeacon, Source: addr.String(), Name: q.name}
+			if _, err := s.record(ctx, cb, payload); err != nil && s.Logger != nil {
+				s.Logger.Error("record callback", "error", err)
+			}
+		}
+		if _, err := conn.WriteTo(q.nxdomain(), addr); err != nil && s.Logger != nil {
+			s.Logger.Warn("answer dns query", "error", err)
+		}
+	}
+}
+
+// beaconPayload decodes the first label of a <payload>.<seq>.<Domain>
+// name and reports whether it is a watermarked beacon payload.
+func (s *Sink) beaconPayload(name string) ([]byte, bool) {
+	domain := strings.ToLower(strings.TrimSuffix(s.Domain, "."))
+	if domain == "" || !strings.HasSuffix(name, "."+domain) {
+		return nil, false
+	}
+	label, _, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil, false
+	}
+	payload, err := hex.DecodeString(label)
+	if err != nil || !watermarked(payload) {
+		return nil, false
+	}
+	return payload, true
+}
+
+// firstQuery reports whether name has not been recorded before: resolvers
+// ask for A and AAAA records, and retry, for one callback.
+func (s *Sink) firstQuery(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.names == nil {
+		s.names = make(map[string]bool)
+	}
+	if s.names[name] {
+		return false
+	}
+	s.names[name] = true
+	return true
+}
+
+// watermarked reports whether payload opens with the beacon watermark, or
+// is a payload too short to hold all of it.
+func watermarked(payload []byte) bool {
+	w := []byte(handlers.BeaconWatermark)
+	return len(payload) > 0 && (bytes.HasPrefix(payload, w) || bytes.HasPrefix(w, payload))
+}
@@ -0,0 +1,134 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/handlers"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func newSink(t *testing.T) *Sink {
+	t.Helper()
+	pub, priv, err := rte.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, _ := rte.NewKeySigner(priv, pub)
+	return &Sink{Signer: signer, Domain: "sink.example.org."}
+}
+
+// run executes task with h and returns its result as the executor would.
+func run(t *testing.T, h rte.Handler, task rte.Task) *rte.TaskResult {
+	t.Helper()
+	out, err := h.Handle(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	data, _ := json.Marshal(out)
+	return &rte.TaskResult{TaskID: task.ID, Type: task.Type, Output: data}
+}
+
+func TestSink_HTTP(t *testing.T) {
+	s := newSink(t)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	beacon := rte.Task{ID: "task-beacon", Type: rte.TaskSimulateBeacon, Params: map[string]string{
+		"sink": srv.URL + "/cb", "count": "3", "interval_seconds": "1", "payload_min": "32", "payload_max": "64",
+	}}
+	rec, err := s.Reconcile(run(t, &handlers.BeaconHandler{}, beacon))
+	if err != nil || !rec.Confirmed() || rec.Received != 3 {
+		t.Fatalf("beacon reconciliation = %+v, %v", rec, err)
+	}
+	login := rte.Task{ID: "task-login", Type: rte.TaskSimulateLogin, Params: map[string]string{
+		"protocol": "http", "target": srv.URL + "/login", "password": "Hunter2!", "rate_per_minute": "60",
+	}}
+	res := run(t, &handlers.LoginHandler{}, login)
+	if !strings.Contains(string(res.Output), `"outcome":"rejected"`) {
+		t.Errorf("login against sink: %s", res.Output)
+	}
+	if rec, err := s.Reconcile(res); err != nil || !rec.Confirmed() {
+		t.Errorf("login reconciliation = %+v, %v", rec, err)
+	}
+
+	receipts := s.Receipts("")
+	if len(receipts) != 4 {
+		t.Fatalf("%d receipts, want 4", len(receipts))
+	}
+	for i, r := range receipts {
+		if err := VerifyReceipt(&r); err != nil || r.Callback.Seq != int64(i+1) {
+			t.Errorf("receipt %d: seq %d, %v", i, r.Callback.Seq, err)
+		}
+	}
+	if cb := receipts[3].Callback; cb.Kind != KindLogin || cb.TaskID != "task-login" || cb.Bytes != 0 {
+		t.Errorf("login callback = %+v", cb)
+	}
+	data, _ := json.Marshal(receipts)
+	if strings.Contains(string(data), "Hunter2") {
+		t.Error("receipt records the login password")
+	}
+	receipts[0].Callback.TaskID = "task-other"
+	if err := VerifyReceipt(&receipts[0]); err == nil {
+		t.Error("edited receipt verified")
+	}
+
+	// Traffic that is not a simulation is not recorded.
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, srv.URL+"/", nil),
+		httptest.NewRequest(http.MethodPost, srv.URL+"/cb", strings.NewReader("hello")),
+	} {
+		req.RequestURI = ""
+		if req.Method == http.MethodPost {
+			req.Header.Set("User-Agent", "rte-a-simulate-beacon")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s %s = %d", req.Method, req.URL.Path, resp.StatusCode)
+		}
+	}
+	if n := len(s.Receipts("")); n != 4 {
+		t.Errorf("stray traffic recorded: %d receipts", n)
+	}
+}
+
+func TestSink_DNS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newSink(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.ServeDNS(ctx, conn) }()
+
+	beacon := rte.Task{ID: "task-dns", Type: rte.TaskSimulateBeacon, Params: map[string]string{
+		"transport": "dns", "sink": "sink.example.org", "resolver": conn.LocalAddr().String(),
+		"count": "2", "interval_seconds": "1", "payload_min": "16", "payload_max": "30",
+	}}
+	res := run(t, &handlers.BeaconHandler{}, beacon)
+	if !strings.Contains(string(res.Output), `"detail":"NXDOMAIN"`) {
+		t.Errorf("beacon result: %s", res.Output)
+	}
+	rec, err := s.Reconcile(res)
+	if err != nil || !rec.Confirmed() || rec.Received != 2 {
+		t.Errorf("dns reconciliation = %+v, %v", rec, err)
+	}
+	if cb := s.Receipts("")[0].Callback; cb.Transport != TransportDNS || !strings.HasSuffix(cb.Name, ".0.sink.example.org") {
+		t.Errorf("dns callback = %+v", cb)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("ServeDNS = %v", err)
+	}
+}
@@ -0,0 +1,496 @@
+// Package sqlstore is an rte.TaskStore on SQLite, for a controller with an
+// embedded database, or PostgreSQL, for controllers sharing one. It uses
+// database/sql and leaves the driver to the program: import one, such as
+// modernc.org/sqlite or github.com/jackc/pgx/v5/stdlib, open a *sql.DB,
+// and pass it to Open with the matching Dialect.
+//
+// Each record is stored whole as JSON beside indexed copies of the columns
+// it is queried by. Updates are optimistic: a row carries a version, and an
+// update applies only if the version is the one it read. Each write also
+// stamps the row with the next number of a change sequence the database
+// keeps, and watchers read the rows changed since the last number they
+// saw, on a timer or, on PostgreSQL, when a trigger's NOTIFY reaches the
+// program's Listener.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// ErrConflict is returned by Update when the record kept changing under it
+// for MaxRetries attempts.
+var ErrConflict = errors.New("task record changed concurrently")
+
+// DefaultMaxRetries is how many times Update retries a conflicting write by
+// default.
+const DefaultMaxRetries = 5
+
+// Dialect is the SQL flavour of a database.
+type Dialect struct {
+	name string
+	// jsonType is the column type records are stored as.
+	jsonType string
+	// numbered reports whether placeholders are $1, $2, ... rather than ?.
+	numbered bool
+}
+
+// The supported dialects.
+var (
+	SQLite   = Dialect{name: "sqlite", jsonType: "TEXT"}
+	Postgres = Dialect{name: "postgres", jsonType: "JSONB", numbered: true}
+)
+
+// String returns the dialect's name.
+func (d Dialect) String() string { return d.name }
+
+// bind rewrites the ? placeholders in q for the dialect.
+func (d Dialect) bind(q string) string {
+	if !d.numbered {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// migrations are the schema changes, applied in order and recorded in
+// rte_schema_migrations. Append new ones; never edit an applied one.
+var migrations = []func(Dialect) []string{
+	1: func(d Dialect) []string {
+		return []string{
+			`CREATE TABLE rte_tasks (
+	engagement TEXT NOT NULL,
+	id TEXT NOT NULL,
+	state TEXT NOT NULL,
+	operator TEXT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	version BIGINT NOT NULL,
+	record ` + d.jsonType + ` NOT NULL,
+	PRIMARY KEY (engagement, id)
+)`,
+			`CREATE INDEX rte_tasks_state ON rte_tasks (engagement, state)`,
+			`CREATE INDEX rte_tasks_operator ON rte_tasks (engagement, operator)`,
+			`CREATE INDEX rte_tasks_updated ON rte_tasks (updated_at)`,
+		}
+	},
+	2: func(d Dialect) []string {
+		if d.name != Postgres.name {
+			return nil
+		}
+		return []string{
+			`CREATE FUNCTION rte_tasks_notify() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('` + NotifyChannel + `', NEW.engagement || '/' || NEW.id);
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql`,
+			`CREATE TRIGGER rte_tasks_notify AFTER INSERT OR UPDATE ON rte_tasks FOR EACH ROW EXECUTE FUNCTION rte_tasks_notify()`,
+		}
+	},
+	3: func(Dialect) []string {
+		return []string{
+			`ALTER TABLE rte_tasks ADD COLUMN change_seq BIGINT NOT NULL DEFAULT 0`,
+			`CREATE INDEX rte_tasks_change_seq ON rte_tasks (change_seq)`,
+			`CREATE TABLE rte_changes (seq BIGINT NOT NULL)`,
+			`INSERT INTO rte_changes (seq) VALUES (0)`,
+		}
+	},
+}
+
+// SchemaVersion is the schema version Open migrates to, the index of the
+// last migration.
+const SchemaVersion = 3
+
+// Store is an rte.TaskStore on a SQL database. It is safe for concurrent
+// use, including by several controllers sharing a PostgreSQL database.
+type Store struct {
+	db *sql.DB
+	d  Dialect
+	// MaxRetries bounds Update's optimistic retries; 0 means
+	// DefaultMaxRetries.
+	MaxRetries int
+	// PollInterval is how often watchers read the table for changes; 0
+	// means DefaultPollInterval. With a Listener it only bounds how late a
+	// missed notification is noticed.
+	PollInterval time.Duration
+	// Listener, if set, wakes watchers as soon as PostgreSQL reports a
+	// change.
+	Listener Listener
+
+	notify notifier
+}
+
+// Open migrates db to SchemaVersion and returns a store on it.
+func Open(ctx context.Context, db *sql.DB, d Dialect) (*Store, error) {
+	s := &Store{db: db, d: d}
+	if err := s.migrate(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies each pending migration in its own transaction.
+func (s *Store) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS rte_schema_migrations (version INTEGER PRIMARY KEY, applied_at BIGINT NOT NULL)`); err != nil {
+		return fmt.Errorf("create migrations table: %w", err)
+	}
+	var current int
+	if err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM rte_schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if current > SchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this build's %d", current, SchemaVersion)
+	}
+	for v := current + 1; v <= SchemaVersion; v++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range migrations[v](s.d) {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d: %w", v, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, s.d.bind(`INSERT INTO rte_schema_migrations (version, applied_at) VALUES (?, ?)`), v, time.Now().UnixNano()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", v, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", v, err)
+		}
+	}
+	return nil
+}
+
+// Put implements rte.TaskStore.
+func (s *Store) Put(ctx context.Context, rec rte.TaskRecord) error {
+	if err := rec.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	seq, err := s.nextChange(ctx, tx)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, s.d.bind(`INSERT INTO rte_tasks (engagement, id, state, operator, updated_at, version, record, change_seq)
+VALUES (?, ?, ?, ?, ?, 1, ?, ?) ON CONFLICT (engagement, id) DO NOTHING`),
+		rec.Engagement(), rec.ID(), string(rec.State), rec.Task.Task.Operator, rec.UpdatedAt.UnixNano(), string(data), seq)
+	if err != nil {
+		return fmt.Errorf("insert %s/%s: %w", rec.Engagement(), rec.ID(), err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: %s/%s", rte.ErrDuplicateTaskID, rec.Engagement(), rec.ID())
+	}
+	return tx.Commit()
+}
+
+// nextChange takes the next number of the change sequence in tx. The
+// counter's row stays locked until tx ends, so writers commit in sequence
+// order: a watcher that has read a change can already read every earlier
+// one, whatever the writers' clocks say.
+func (s *Store) nextChange(ctx context.Context, tx *sql.Tx) (int64, error) {
+	if _, err := tx.ExecContext(ctx, `UPDATE rte_changes SET seq = seq + 1`); err != nil {
+		return 0, fmt.Errorf("next change: %w", err)
+	}
+	var seq int64
+	if err := tx.QueryRowContext(ctx, `SELECT seq FROM rte_changes`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("next change: %w", err)
+	}
+	return seq, nil
+}
+
+// Get implements rte.TaskStore.
+func (s *Store) Get(ctx context.Context, engagement, id string) (rte.TaskRecord, error) {
+	rec, _, err := s.get(ctx, engagement, id)
+	return rec, err
+}
+
+func (s *Store) get(ctx context.Context, engagement, id string) (rte.TaskRecord, int64, error) {
+	var data string
+	var version int64
+	err := s.db.QueryRowContext(ctx, s.d.bind(`SELECT record, version FROM rte_tasks WHERE engagement = ? AND id = ?`), engagement, id).Scan(&data, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rte.TaskRecord{}, 0, fmt.Errorf("%w: %s/%s", rte.ErrTaskNotFound, engagement, id)
+	}
+	if err != nil {
+		return rte.TaskRecord{}, 0, err
+	}
+	var rec rte.TaskRecord
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return rte.TaskRecord{}, 0, fmt.Errorf("decode %s/%s: %w", engagement, id, err)
+	}
+	return rec, version, nil
+}
+
+// List implements rte.TaskStore.
+func (s *Store) List(ctx context.Context, engagement string) ([]rte.TaskRecord, error) {
+	return s.Find(ctx, Query{Engagement: engagement})
+}
+
+// Snapshot returns every record as of one instant: a single SELECT reads
+// one consistent view in both SQLite and PostgreSQL.
+func (s *Store) Snapshot(ctx context.Context) ([]rte.TaskRecord, error) {
+	return s.Find(ctx, Query{})
+}
+
+// Query selects task records. Empty fields match every record; the
+// combinations with an engagement are served by indexes.
+type Query struct {
+	Engagement string
+	State      rte.TaskState
+	Operator   string
+	// UpdatedSince, if set, keeps records updated after it.
+	UpdatedSince time.Time
+}
+
+// Find returns the records q selects, sorted by engagement and ID.
+func (s *Store) Find(ctx context.Context, q Query) ([]rte.TaskRecord, error) {
+	var where []string
+	var args []any
+	if q.Engagement != "" {
+		where, args = append(where, "engagement = ?"), append(args, q.Engagement)
+	}
+	if q.State != "" {
+		where, args = append(where, "state = ?"), append(args, string(q.State))
+	}
+	if q.Operator != "" {
+		where, args = append(where, "operator = ?"), append(args, q.Operator)
+	}
+	if !q.UpdatedSince.IsZero() {
+		where, args = append(where, "updated_at > ?"), append(args, q.UpdatedSince.UnixNano())
+	}
+	stmt := `SELECT record FROM rte_tasks`
+	if len(where) > 0 {
+		stmt += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	stmt += ` ORDER BY engagement, id`
+	rows, err := s.db.QueryContext(ctx, s.d.bind(stmt), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []rte.TaskRecord
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var rec rte.TaskRecord
+		if err := json.Unmarshal([]byte(data), &rec); err != nil {
+			return nil, fmt.Errorf("decode task record: %w", err)
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// Update implements rte.TaskStore. fn runs against the stored record and
+// the result is written only if no other update landed in between;
+// otherwise Update reads the record again and reruns fn, so fn must not
+// have side effects beyond the record.
+func (s *Store) Update(ctx context.Context, engagement, id string, fn func(*rte.TaskRecord) error) error {
+	retries := s.MaxRetries
+	if retries <= 0 {
+		retries = DefaultMaxRetries
+	}
+	for attempt := 0; attempt <= retries; attempt++ {
+		rec, version, err := s.get(ctx, engagement, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(&rec); err != nil {
+			return err
+		}
+		if err := rec.Validate(); err != nil {
+			return err
+		}
+		if rec.Engagement() != engagement || rec.ID() != id {
+			return errors.New("update must not change a record's key")
+		}
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		done, err := s.write(ctx, rec, data, version)
+		if err != nil || done {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %s/%s", ErrConflict, engagement, id)
+}
+
+// write stores rec over the version read in a transaction of its own,
+// reporting false if another update landed first.
+func (s *Store) write(ctx context.Context, rec rte.TaskRecord, data []byte, version int64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	seq, err := s.nextChange(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	ok, err := s.update(ctx, tx, rec, data, version, seq)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// update writes rec over the version read in tx, stamped with change seq,
+// reporting false if another update landed first.
+func (s *Store) update(ctx context.Context, tx *sql.Tx, rec rte.TaskRecord, data []byte, version, seq int64) (bool, error) {
+	eng, id := rec.Engagement(), rec.ID()
+	res, err := tx.ExecContext(ctx, s.d.bind(`UPDATE rte_tasks SET state = ?, operator = ?, updated_at = ?, version = ?, record = ?, change_seq = ?
+WHERE engagement = ? AND id = ? AND version = ?`),
+		string(rec.State), rec.Task.Task.Operator, rec.UpdatedAt.UnixNano(), version+1, string(data), seq, eng, id, version)
+	if err != nil {
+		return false, fmt.Errorf("update %s/%s: %w", eng, id, err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Purge implements rte.PurgeStore. The delete applies only to the version
+// it checked, so a restore landing in between keeps the record.
+func (s *Store) Purge(ctx context.Context, engagement, id string, now time.Time) error {
+	rec, version, err := s.get(ctx, engagement, id)
+	if err != nil {
+		return err
+	}
+	if !rec.Purgeable(now) {
+		return fmt.Errorf("%w: %s/%s", rte.ErrNotPurgeable, engagement, id)
+	}
+	res, err := s.db.ExecContext(ctx, s.d.bind(`DELETE FROM rte_tasks WHERE engagement = ? AND id = ? AND version = ?`), engagement, id, version)
+	if err != nil {
+		return fmt.Errorf("purge %s/%s: %w", engagement, id, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: %s/%s", ErrConflict, engagement, id)
+	}
+	return nil
+}
+
+// UpdateWhere implements rte.BatchStore in one transaction. Each write
+// checks the version read, as Update does; if another update landed on
+// any record in between, the transaction is rolled back and the whole
+// batch rerun, so fn must not have side effects beyond the record.
+func (s *Store) UpdateWhere(ctx context.Context, engagement string, match func(rte.TaskRecord) bool, fn func(*rte.TaskRecord) error) ([]rte.TaskRecord, error) {
+	retries := s.MaxRetries
+	if retries <= 0 {
+		retries = DefaultMaxRetries
+	}
+	for attempt := 0; attempt <= retries; attempt++ {
+		out, conflict, err := s.updateWhere(ctx, engagement, match, fn)
+		if err != nil || !conflict {
+			return out, err
+		}
+	}
+	return nil, fmt.Errorf("%w: bulk update of %s", ErrConflict, engagement)
+}
+
+func (s *Store) updateWhere(ctx context.Context, engagement string, match func(rte.TaskRecord) bool, fn func(*rte.TaskRecord) error) ([]rte.TaskRecord, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+	stmt, args := `SELECT record, version FROM rte_tasks ORDER BY engagement, id`, []any(nil)
+	if engagement != "" {
+		stmt, args = `SELECT record, version FROM rte_tasks WHERE engagement = ? ORDER BY id`, []any{engagement}
+	}
+	rows, err := tx.QueryContext(ctx, s.d.bind(stmt), args...)
+	if err != nil {
+		return nil, false, err
+	}
+	type read struct {
+		rec     rte.TaskRecord
+		version int64
+	}
+	var reads []read
+	for rows.Next() {
+		var data string
+		var r read
+		if err := rows.Scan(&data, &r.version); err != nil {
+			rows.Close()
+			return nil, false, err
+		}
+		if err := json.Unmarshal([]byte(data), &r.rec); err != nil {
+			rows.Close()
+			return nil, false, fmt.Errorf("decode task record: %w", err)
+		}
+		if match(r.rec) {
+			reads = append(reads, r)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	out := make([]rte.TaskRecord, 0, len(reads))
+	var seq int64
+	for _, r := range reads {
+		eng, id := r.rec.Engagement(), r.rec.ID()
+		rec := r.rec
+		if err := fn(&rec); err != nil {
+			return nil, false, err
+		}
+		if err := rec.Validate(); err != nil {
+			return nil, false, err
+		}
+		if rec.Engagement() != eng || rec.ID() != id {
+			return nil, false, errors.New("update must not change a record's key")
+		}
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return nil, false, err
+		}
+		// The batch commits as one change.
+		if seq == 0 {
+			if seq, err = s.nextChange(ctx, tx); err != nil {
+				return nil, false, err
+			}
+		}
+		if ok, err := s.update(ctx, tx, rec, data, r.version, seq); err != nil {
+			return nil, false, err
+		} else if !ok {
+			return nil, true, nil
+		}
+		out = append(out, rec)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return out, false, nil
+}
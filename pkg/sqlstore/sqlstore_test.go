@@ -0,0 +1,513 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// fakeDB is a database/sql driver that understands exactly the statements
+// Store issues, in the Postgres dialect, so the store's logic can be tested
+// without a database server or cgo.
+type fakeDB struct {
+	mu         sync.Mutex
+	migrations []int64
+	ddl        []string
+	rows       map[[2]string]*fakeRow
+	// seq is rte_changes' counter.
+	seq int64
+	// changeReads counts the rows each watcher read returned.
+	changeReads []int
+}
+
+type fakeRow struct {
+	state, operator string
+	updated         int64
+	version         int64
+	record          string
+	change          int64
+}
+
+var fakes sync.Map
+
+func init() { sql.Register("rte-fake", fakeDriver{}) }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	db, ok := fakes.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("no fake database %q", name)
+	}
+	return &fakeConn{db: db.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+func (c *fakeConn) Prepare(q string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, c: c, q: q}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.tx = &fakeTx{c: c, saved: map[[2]string]*fakeRow{}}
+	return c.tx, nil
+}
+
+// fakeTx undoes the connection's writes on rollback. It does not isolate
+// them from other connections in the meantime.
+type fakeTx struct {
+	c     *fakeConn
+	saved map[[2]string]*fakeRow // rows as before the first write; nil if absent
+}
+
+// touch saves key's row before the transaction first writes it. Callers
+// hold db.mu.
+func (tx *fakeTx) touch(key [2]string) {
+	if tx == nil {
+		return
+	}
+	if _, ok := tx.saved[key]; ok {
+		return
+	}
+	if r, ok := tx.c.db.rows[key]; ok {
+		saved := *r
+		tx.saved[key] = &saved
+	} else {
+		tx.saved[key] = nil
+	}
+}
+
+func (tx *fakeTx) Commit() error { tx.c.tx = nil; return nil }
+func (tx *fakeTx) Rollback() error {
+	tx.c.tx = nil
+	db := tx.c.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for k, r := range tx.saved {
+		if r == nil {
+			delete(db.rows, k)
+		} else {
+			db.rows[k] = r
+		}
+	}
+	return nil
+}
+
+type fakeStmt struct {
+	db *fakeDB
+	c  *fakeConn
+	q  string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func str(v driver.Value) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	q := s.q
+	switch {
+	case strings.HasPrefix(q, "CREATE TABLE IF NOT EXISTS rte_schema_migrations"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(q, "CREATE"), strings.HasPrefix(q, "ALTER"), strings.HasPrefix(q, "INSERT INTO rte_changes"):
+		db.ddl = append(db.ddl, q)
+		return driver.RowsAffected(0), nil
+	case q == "UPDATE rte_changes SET seq = seq + 1":
+		db.seq++
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(q, "INSERT INTO rte_schema_migrations (version, applied_at) VALUES ($1, $2)"):
+		db.migrations = append(db.migrations, args[0].(int64))
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(q, "INSERT INTO rte_tasks") && strings.HasSuffix(q, "ON CONFLICT (engagement, id) DO NOTHING"):
+		key := [2]string{str(args[0]), str(args[1])}
+		if _, ok := db.rows[key]; ok {
+			return driver.RowsAffected(0), nil
+		}
+		s.c.tx.touch(key)
+		db.rows[key] = &fakeRow{state: str(args[2]), operator: str(args[3]), updated: args[4].(int64), version: 1, record: str(args[5]), change: args[6].(int64)}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(q, "UPDATE rte_tasks SET state = $1, operator = $2, updated_at = $3, version = $4, record = $5, change_seq = $6\nWHERE engagement = $7 AND id = $8 AND version = $9"):
+		key := [2]string{str(args[6]), str(args[7])}
+		r, ok := db.rows[key]
+		if !ok || r.version != args[8].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		s.c.tx.touch(key)
+		r.state, r.operator, r.updated, r.version, r.record, r.change = str(args[0]), str(args[1]), args[2].(int64), args[3].(int64), str(args[4]), args[5].(int64)
+		return driver.RowsAffected(1), nil
+	case q == "DELETE FROM rte_tasks WHERE engagement = $1 AND id = $2 AND version = $3":
+		key := [2]string{str(args[0]), str(args[1])}
+		r, ok := db.rows[key]
+		if !ok || r.version != args[2].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		s.c.tx.touch(key)
+		delete(db.rows, key)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("fake: unexpected exec %q", q)
+}
+
+var whereClause = regexp.MustCompile(`(\w+) (=|>) \$(\d+)`)
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	q := s.q
+	switch {
+	case q == "SELECT COALESCE(MAX(version), 0) FROM rte_schema_migrations":
+		var max int64
+		for _, v := range db.migrations {
+			if v > max {
+				max = v
+			}
+		}
+		return &fakeRows{cols: []string{"max"}, vals: [][]driver.Value{{max}}}, nil
+	case q == "SELECT seq FROM rte_changes":
+		return &fakeRows{cols: []string{"seq"}, vals: [][]driver.Value{{db.seq}}}, nil
+	case q == "SELECT record, version FROM rte_tasks WHERE engagement = $1 AND id = $2":
+		out := &fakeRows{cols: []string{"record", "version"}}
+		if r, ok := db.rows[[2]string{str(args[0]), str(args[1])}]; ok {
+			out.vals = append(out.vals, []driver.Value{r.record, r.version})
+		}
+		return out, nil
+	case q == "SELECT record, version FROM rte_tasks WHERE engagement = $1 ORDER BY id", q == "SELECT record, version FROM rte_tasks ORDER BY engagement, id":
+		var keys [][2]string
+		for key := range db.rows {
+			if len(args) == 0 || key[0] == str(args[0]) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i][0] != keys[j][0] {
+				return keys[i][0] < keys[j][0]
+			}
+			return keys[i][1] < keys[j][1]
+		})
+		out := &fakeRows{cols: []string{"record", "version"}}
+		for _, k := range keys {
+			out.vals = append(out.vals, []driver.Value{db.rows[k].record, db.rows[k].version})
+		}
+		return out, nil
+	case q == "SELECT record, change_seq FROM rte_tasks WHERE change_seq > $1 ORDER BY change_seq, engagement, id",
+		q == "SELECT record, change_seq FROM rte_tasks WHERE engagement = $1 AND change_seq > $2 ORDER BY change_seq, id":
+		after := args[len(args)-1].(int64)
+		var keys [][2]string
+		for key, r := range db.rows {
+			if (len(args) == 1 || key[0] == str(args[0])) && r.change > after {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := db.rows[keys[i]], db.rows[keys[j]]
+			if a.change != b.change {
+				return a.change < b.change
+			}
+			if keys[i][0] != keys[j][0] {
+				return keys[i][0] < keys[j][0]
+			}
+			return keys[i][1] < keys[j][1]
+		})
+		out := &fakeRows{cols: []string{"record", "change_seq"}}
+		for _, k := range keys {
+			r := db.rows[k]
+			out.vals = append(out.vals, []driver.Value{r.record, r.change})
+		}
+		db.changeReads = append(db.changeReads, len(keys))
+		return out, nil
+	case strings.HasPrefix(q, "SELECT record FROM rte_tasks") && strings.HasSuffix(q, " ORDER BY engagement, id"):
+		conds := whereClause.FindAllStringSubmatch(q, -1)
+		var keys [][2]string
+	rows:
+		for key, r := range db.rows {
+			for i, c := range conds {
+				want := args[i]
+				switch c[1] {
+				case "engagement":
+					if key[0] != str(want) {
+						continue rows
+					}
+				case "state":
+					if r.state != str(want) {
+						continue rows
+					}
+				case "operator":
+					if r.operator != str(want) {
+						continue rows
+					}
+				case "updated_at":
+					if r.updated <= want.(int64) {
+						continue rows
+					}
+				default:
+					return nil, fmt.Errorf("fake: unexpected condition %q", c[0])
+				}
+			}
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i][0] != keys[j][0] {
+				return keys[i][0] < keys[j][0]
+			}
+			return keys[i][1] < keys[j][1]
+		})
+		out := &fakeRows{cols: []string{"record"}}
+		for _, k := range keys {
+			out.vals = append(out.vals, []driver.Value{db.rows[k].record})
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("fake: unexpected query %q", q)
+}
+
+type fakeRows struct {
+	cols []string
+	vals [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.vals[0])
+	r.vals = r.vals[1:]
+	return nil
+}
+
+func openFake(t *testing.T) (*Store, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{rows: map[[2]string]*fakeRow{}}
+	fakes.Store(t.Name(), fake)
+	db, err := sql.Open("rte-fake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := Open(context.Background(), db, Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func record(t *testing.T, id, operator string) rte.TaskRecord {
+	t.Helper()
+	pub, priv, _ := rte.GenerateKeyPair()
+	st, err := rte.SignTask(rte.Task{
+		ID: id, Engagement: "eng-1", Type: rte.TaskInventory, CreatedAt: time.Now().UTC(),
+		TTLSeconds: 600, Operator: operator, ApprovedBy: "lead", State: rte.StatePending,
+	}, priv, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rte.TaskRecord{Task: st, State: rte.StatePending, UpdatedAt: st.Task.CreatedAt}
+}
+
+func TestOpen_Migrates(t *testing.T) {
+	if SchemaVersion != len(migrations)-1 {
+		t.Fatalf("SchemaVersion = %d, but there are %d migrations", SchemaVersion, len(migrations)-1)
+	}
+	s, fake := openFake(t)
+	if len(fake.migrations) != 3 || len(fake.ddl) != 10 || !strings.Contains(fake.ddl[0], "record JSONB NOT NULL") ||
+		!strings.Contains(fake.ddl[5], "CREATE TRIGGER rte_tasks_notify") || !strings.Contains(fake.ddl[6], "ADD COLUMN change_seq") {
+		t.Fatalf("migrations %v, ddl %q", fake.migrations, fake.ddl)
+	}
+	// Reopening applies nothing new.
+	if _, err := Open(context.Background(), s.db, Postgres); err != nil || len(fake.ddl) != 10 {
+		t.Fatalf("reopen: %v, ddl %d", err, len(fake.ddl))
+	}
+	fake.migrations = append(fake.migrations, SchemaVersion+1)
+	if _, err := Open(context.Background(), s.db, Postgres); err == nil {
+		t.Error("opened a database with a newer schema")
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s, _ := openFake(t)
+	for _, r := range []rte.TaskRecord{record(t, "t-2", "alice"), record(t, "t-1", "bob"), record(t, "t-3", "alice")} {
+		if err := s.Put(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Put(ctx, record(t, "t-1", "bob")); !errors.Is(err, rte.ErrDuplicateTaskID) {
+		t.Errorf("duplicate Put: %v", err)
+	}
+	if _, err := s.Get(ctx, "eng-1", "nope"); !errors.Is(err, rte.ErrTaskNotFound) {
+		t.Errorf("Get unknown: %v", err)
+	}
+	if err := s.Update(ctx, "eng-1", "t-3", func(r *rte.TaskRecord) error {
+		r.State, r.UpdatedAt = rte.StateExecuting, r.UpdatedAt.Add(time.Minute)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(recs []rte.TaskRecord, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, r := range recs {
+			out = append(out, r.ID())
+		}
+		return strings.Join(out, ",")
+	}
+	if got := ids(s.List(ctx, "eng-1")); got != "t-1,t-2,t-3" {
+		t.Errorf("List = %s", got)
+	}
+	if got := ids(s.Find(ctx, Query{Engagement: "eng-1", Operator: "alice", State: rte.StatePending})); got != "t-2" {
+		t.Errorf("Find pending alice = %s", got)
+	}
+	since := record(t, "x", "x").UpdatedAt.Add(30 * time.Second)
+	if got := ids(s.Find(ctx, Query{UpdatedSince: since})); got != "t-3" {
+		t.Errorf("Find updated since = %s", got)
+	}
+}
+
+func TestStore_UpdateRetriesOnConflict(t *testing.T) {
+	ctx := context.Background()
+	s, _ := openFake(t)
+	if err := s.Put(ctx, record(t, "t-1", "alice")); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	err := s.Update(ctx, "eng-1", "t-1", func(r *rte.TaskRecord) error {
+		calls++
+		if calls == 1 {
+			// Another controller leases the task between our read and write.
+			if _, err := rte.AcquireLease(ctx, s, "eng-1", "t-1", "agent-b", time.Minute, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if r.Lease != nil {
+			return fmt.Errorf("leased by %s", r.Lease.Agent)
+		}
+		r.State = rte.StateCancelled
+		return nil
+	})
+	if calls != 2 || err == nil || err.Error() != "leased by agent-b" {
+		t.Fatalf("Update after conflict: calls %d, err %v", calls, err)
+	}
+
+	s.MaxRetries = 1
+	err = s.Update(ctx, "eng-1", "t-1", func(r *rte.TaskRecord) error {
+		// Every attempt loses the race.
+		_ = s.Update(ctx, "eng-1", "t-1", func(r *rte.TaskRecord) error { return nil })
+		return nil
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("endless conflict: %v", err)
+	}
+}
+
+func TestStore_UpdateWhere(t *testing.T) {
+	ctx := context.Background()
+	s, _ := openFake(t)
+	for _, r := range []rte.TaskRecord{record(t, "t-1", "alice"), record(t, "t-2", "bob"), record(t, "t-3", "alice")} {
+		if err := s.Put(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	alice := rte.TaskFilter{Engagement: "eng-1", Operator: "alice"}
+	got, err := rte.CancelWhere(ctx, s, nil, alice, "lead", time.Now())
+	if err != nil || len(got) != 2 || got[0].Record.ID() != "t-1" || got[1].Record.ID() != "t-3" {
+		t.Fatalf("CancelWhere = %+v, %v", got, err)
+	}
+	if cancelled, _ := s.Find(ctx, Query{State: rte.StateCancelled}); len(cancelled) != 2 {
+		t.Errorf("%d cancelled records stored", len(cancelled))
+	}
+
+	// A failure part way rolls back the records already written.
+	_, err = s.UpdateWhere(ctx, "", func(rte.TaskRecord) bool { return true }, func(r *rte.TaskRecord) error {
+		if r.ID() == "t-3" {
+			return errors.New("refused")
+		}
+		r.State = rte.StateFailed
+		return nil
+	})
+	if err == nil || err.Error() != "refused" {
+		t.Fatalf("UpdateWhere = %v", err)
+	}
+	if failed, _ := s.Find(ctx, Query{State: rte.StateFailed}); len(failed) != 0 {
+		t.Errorf("partial update kept %d records", len(failed))
+	}
+
+	// A concurrent write to a selected record reruns the whole batch.
+	calls, sawLease := 0, false
+	_, err = s.UpdateWhere(ctx, "eng-1", func(r rte.TaskRecord) bool { return r.State == rte.StatePending }, func(r *rte.TaskRecord) error {
+		calls++
+		if calls == 1 {
+			if _, err := rte.AcquireLease(ctx, s, "eng-1", "t-2", "agent-b", time.Minute, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sawLease = sawLease || r.Lease != nil
+		r.UpdatedAt = r.UpdatedAt.Add(time.Second)
+		return nil
+	})
+	if err != nil || calls != 2 || !sawLease {
+		t.Fatalf("UpdateWhere after conflict: calls %d, saw lease %v, err %v", calls, sawLease, err)
+	}
+}
+
+func TestStore_Purge(t *testing.T) {
+	ctx := context.Background()
+	s, _ := openFake(t)
+	now := time.Now().UTC()
+	rec := record(t, "t-1", "alice")
+	rec.State = rte.StateCompleted
+	if err := s.Put(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Purge(ctx, "eng-1", "t-1", now); !errors.Is(err, rte.ErrNotPurgeable) {
+		t.Errorf("purged a live record: %v", err)
+	}
+	if err := rte.DeleteTask(ctx, s, nil, "eng-1", "t-1", "lead", "", time.Hour, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Purge(ctx, "eng-1", "t-1", now.Add(time.Minute)); !errors.Is(err, rte.ErrNotPurgeable) {
+		t.Errorf("purged inside the restore window: %v", err)
+	}
+	if err := s.Purge(ctx, "eng-1", "t-1", now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "eng-1", "t-1"); !errors.Is(err, rte.ErrTaskNotFound) {
+		t.Errorf("Get after purge: %v", err)
+	}
+}
+
+func TestDialect_Bind(t *testing.T) {
+	q := "SELECT a FROM t WHERE b = ? AND c = ?"
+	if got := SQLite.bind(q); got != q {
+		t.Errorf("sqlite bind = %q", got)
+	}
+	if got := Postgres.bind(q); got != "SELECT a FROM t WHERE b = $1 AND c = $2" {
+		t.Errorf("postgres bind = %q", got)
+	}
+}
@@ -0,0 +1,190 @@
+package sqlstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// DefaultPollInterval is how often watchers read the table by default.
+const DefaultPollInterval = time.Second
+
+// NotifyChannel is the PostgreSQL channel a trigger notifies on each insert
+// and update of rte_tasks, with the record's engagement/id as payload.
+const NotifyChannel = "rte_tasks"
+
+// Listener waits for notifications on NotifyChannel. database/sql has no
+// LISTEN, so the program builds one on its driver: with pgx, a dedicated
+// connection that has run LISTEN rte_tasks and calls WaitForNotification.
+// The store calls Wait from one goroutine at a time.
+type Listener interface {
+	// Wait blocks until a notification arrives or ctx ends.
+	Wait(ctx context.Context) error
+}
+
+// notifier shares one Listener among a store's watchers: it listens while
+// any watcher is open and wakes them all on each notification.
+type notifier struct {
+	mu       sync.Mutex
+	watchers int
+	cancel   context.CancelFunc
+	changed  chan struct{}
+}
+
+// join registers a watcher, starting to listen if it is the first, and
+// returns the function that unregisters it.
+func (n *notifier) join(l Listener) (leave func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.watchers++
+	if n.watchers == 1 && l != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		n.cancel = cancel
+		go n.listen(ctx, l)
+	}
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if n.watchers--; n.watchers == 0 && n.cancel != nil {
+			n.cancel()
+			n.cancel = nil
+		}
+	}
+}
+
+func (n *notifier) listen(ctx context.Context, l Listener) {
+	for ctx.Err() == nil {
+		if err := l.Wait(ctx); err != nil {
+			// Watchers still poll; back off rather than spin on a broken
+			// connection.
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		n.mu.Lock()
+		if n.changed != nil {
+			close(n.changed)
+			n.changed = nil
+		}
+		n.mu.Unlock()
+	}
+}
+
+// wake returns a channel closed at the next notification.
+func (n *notifier) wake() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.changed == nil {
+		n.changed = make(chan struct{})
+	}
+	return n.changed
+}
+
+// Watch implements rte.TaskStore. Each watcher reads the records of f's
+// engagement stamped with a change number past the last it has seen,
+// every PollInterval or on notification from Listener, and reports them.
+// Writers commit in change order, so no write is skipped however slow its
+// transaction or skewed its controller's clock. Several updates of one
+// record between reads are reported as one event with the latest record.
+// Unlike the rte stores' watchers, an SQL watcher is unbuffered and never
+// closed for falling behind: it holds back only its own reads, nothing is
+// dropped, and writes are never delayed.
+func (s *Store) Watch(ctx context.Context, f rte.WatchFilter) (<-chan rte.TaskEvent, error) {
+	// Take each wake-up channel before reading, so a notification landing
+	// during the read is not missed.
+	leave := s.notify.join(s.Listener)
+	wake := s.notify.wake()
+	rows, err := s.changes(ctx, f.Engagement, -1)
+	if err != nil {
+		leave()
+		return nil, err
+	}
+	seen := make(map[[2]string]bool, len(rows))
+	var last int64
+	for _, r := range rows {
+		seen[[2]string{r.rec.Engagement(), r.rec.ID()}] = true
+		last = max(last, r.seq)
+	}
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ch := make(chan rte.TaskEvent)
+	go func() {
+		defer close(ch)
+		defer leave()
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			case <-wake:
+			}
+			wake = s.notify.wake()
+			// A failed read is retried at the next wake-up.
+			rows, err := s.changes(ctx, f.Engagement, last)
+			if err != nil {
+				continue
+			}
+			for _, r := range rows {
+				last = max(last, r.seq)
+				key := [2]string{r.rec.Engagement(), r.rec.ID()}
+				ok := seen[key]
+				seen[key] = true
+				if !f.Match(r.rec) {
+					continue
+				}
+				ev := rte.TaskEvent{Kind: rte.EventCreated, Record: r.rec}
+				if ok {
+					ev.Kind = rte.EventUpdated
+				}
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+type change struct {
+	rec rte.TaskRecord
+	seq int64
+}
+
+// changes reads the records of engagement, or of every engagement when it
+// is empty, whose change number is past after, in the order they changed.
+func (s *Store) changes(ctx context.Context, engagement string, after int64) ([]change, error) {
+	stmt, args := `SELECT record, change_seq FROM rte_tasks WHERE change_seq > ? ORDER BY change_seq, engagement, id`, []any{after}
+	if engagement != "" {
+		stmt, args = `SELECT record, change_seq FROM rte_tasks WHERE engagement = ? AND change_seq > ? ORDER BY change_seq, id`, []any{engagement, after}
+	}
+	rows, err := s.db.QueryContext(ctx, s.d.bind(stmt), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []change
+	for rows.Next() {
+		var data string
+		var r change
+		if err := rows.Scan(&data, &r.seq); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &r.rec); err != nil {
+			return nil, fmt.Errorf("decode task record: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
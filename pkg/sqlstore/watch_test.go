@@ -0,0 +1,143 @@
+package sqlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// chanListener delivers a notification per value sent on it.
+type chanListener chan struct{}
+
+func (l chanListener) Wait(ctx context.Context) error {
+	select {
+	case <-l:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func next(t *testing.T, ch <-chan rte.TaskEvent) rte.TaskEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("watch closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return rte.TaskEvent{}
+}
+
+func TestStore_WatchPolls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, _ := openFake(t)
+	s.PollInterval = 10 * time.Millisecond
+	if err := s.Put(ctx, record(t, "t-1", "alice")); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := s.Watch(ctx, rte.WatchFilter{Engagement: "eng-1", States: []rte.TaskState{rte.StatePending, rte.StateExecuting}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, record(t, "t-2", "bob")); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(t, ch); ev.Kind != rte.EventCreated || ev.Record.ID() != "t-2" {
+		t.Errorf("event = %s %s, want created t-2", ev.Kind, ev.Record.ID())
+	}
+	if err := s.Update(ctx, "eng-1", "t-1", func(r *rte.TaskRecord) error { r.State = rte.StateExecuting; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(t, ch); ev.Kind != rte.EventUpdated || ev.Record.ID() != "t-1" || ev.Record.State != rte.StateExecuting {
+		t.Errorf("event = %s %s %s, want t-1 updated to executing", ev.Kind, ev.Record.ID(), ev.Record.State)
+	}
+	// Changes the filter drops are not reported.
+	if err := s.Update(ctx, "eng-1", "t-1", func(r *rte.TaskRecord) error { r.State = rte.StateCompleted; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(ctx, "eng-1", "t-2", func(r *rte.TaskRecord) error { r.State = rte.StateExecuting; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(t, ch); ev.Record.ID() != "t-2" {
+		t.Errorf("event for %s, want t-2", ev.Record.ID())
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestStore_WatchReadsOnlyChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, fake := openFake(t)
+	s.PollInterval = 10 * time.Millisecond
+	for _, id := range []string{"t-1", "t-2"} {
+		if err := s.Put(ctx, record(t, id, "alice")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ch, err := s.Watch(ctx, rte.WatchFilter{Engagement: "eng-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"t-3", "t-4"} {
+		rec := record(t, id, "bob")
+		// A controller whose clock runs an hour behind is still heard.
+		rec.UpdatedAt = rec.UpdatedAt.Add(-time.Hour)
+		if err := s.Put(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		if ev := next(t, ch); ev.Kind != rte.EventCreated || ev.Record.ID() != id {
+			t.Fatalf("event = %s %s, want created %s", ev.Kind, ev.Record.ID(), id)
+		}
+	}
+	fake.mu.Lock()
+	reads := fake.changeReads
+	fake.mu.Unlock()
+	polled := 0
+	for _, n := range reads[1:] {
+		polled += n
+	}
+	if reads[0] != 2 || polled != 2 {
+		t.Errorf("rows read per poll = %v, want 2 at the start and then t-3 and t-4 once each", reads)
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestStore_WatchListens(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, _ := openFake(t)
+	l := make(chanListener)
+	s.PollInterval, s.Listener = time.Hour, l
+	a, _ := s.Watch(ctx, rte.WatchFilter{})
+	b, _ := s.Watch(ctx, rte.WatchFilter{})
+	if err := s.Put(ctx, record(t, "t-1", "alice")); err != nil {
+		t.Fatal(err)
+	}
+	l <- struct{}{}
+	for _, ch := range []<-chan rte.TaskEvent{a, b} {
+		if ev := next(t, ch); ev.Record.ID() != "t-1" {
+			t.Errorf("event for %s, want t-1", ev.Record.ID())
+		}
+	}
+	cancel()
+	for range a {
+	}
+	for range b {
+	}
+	s.notify.mu.Lock()
+	defer s.notify.mu.Unlock()
+	if s.notify.watchers != 0 || s.notify.cancel != nil {
+		t.Errorf("listener still running with %d watchers", s.notify.watchers)
+	}
+}
@@ -0,0 +1,99 @@
+package stix
+
+import (
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// MarkingDefinition is a STIX statement marking.
+type MarkingDefinition struct {
+	Type           string            `json:"type"`
+	SpecVersion    string            `json:"spec_version"`
+	ID             string            `json:"id"`
+	CreatedByRef   string            `json:"created_by_ref,omitempty"`
+	Created        string            `json:"created"`
+	Name           string            `json:"name,omitempty"`
+	DefinitionType string            `json:"definition_type"`
+	Definition     map[string]string `json:"definition"`
+}
+
+// Mark adds a statement marking carrying m's classification and
+// distribution statement, and references it from every object in the
+// bundle. A bundle is left unchanged if m has no marking. The bundle ID
+// still identifies the activity, so a marked and an unmarked export of the
+// same activity share it.
+func (b *Bundle) Mark(m rte.EngagementMetadata) {
+	statement := m.Marking()
+	if statement == "" {
+		return
+	}
+	var author Identity
+	if len(b.Objects) > 0 {
+		author, _ = b.Objects[0].(Identity)
+	}
+	def := MarkingDefinition{
+		Type: "marking-definition", SpecVersion: specVersion,
+		ID:           sdoID("marking-definition", "statement/"+statement),
+		CreatedByRef: author.ID, Created: author.Created,
+		Name:           string(m.Classification),
+		DefinitionType: "statement",
+		Definition:     map[string]string{"statement": statement},
+	}
+	refs := []string{def.ID}
+	for i, o := range b.Objects {
+		switch v := o.(type) {
+		case Identity:
+			v.ObjectMarkingRefs = refs
+			b.Objects[i] = v
+		case AttackPattern:
+			v.ObjectMarkingRefs = refs
+			b.Objects[i] = v
+		case ObservedData:
+			v.ObjectMarkingRefs = refs
+			b.Objects[i] = v
+		case Relationship:
+			v.ObjectMarkingRefs = refs
+			b.Objects[i] = v
+		case Observable:
+			v["object_marking_refs"] = refs
+		}
+	}
+	// The definition follows its author, the first object.
+	at := min(1, len(b.Objects))
+	b.Objects = append(b.Objects[:at], append([]any{def}, b.Objects[at:]...)...)
+}
+
+// Options mark an export and bound what it releases.
+type Options struct {
+	// Metadata is the engagement's. Its classification is the level of
+	// every task not marked otherwise.
+	Metadata rte.EngagementMetadata
+	// Classification is the bundle's level; "" means the engagement's.
+	Classification rte.Classification
+}
+
+// ExportMarked is Export for a deliverable at opts.Classification. Tasks
+// classified above it are left out, with their results, and every object
+// carries the bundle's marking.
+func ExportMarked(engagement string, tasks []rte.Task, results []rte.TaskResult, opts Options) (*Bundle, error) {
+	level := opts.Classification
+	if level == "" {
+		level = opts.Metadata.Classification
+	}
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+	var released []rte.Task
+	for _, t := range tasks {
+		if rte.Releasable(t.Classification, opts.Metadata.Classification, level) {
+			released = append(released, t)
+		}
+	}
+	b, err := Export(engagement, released, results)
+	if err != nil {
+		return nil, err
+	}
+	m := opts.Metadata
+	m.Classification = level
+	b.Mark(m)
+	return b, nil
+}
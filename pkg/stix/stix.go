@@ -0,0 +1,326 @@
+// Package stix exports engagement activity as STIX 2.1 bundles so outcomes
+// can be imported into threat-intelligence platforms and shared with the
+// blue team. Object IDs are deterministic UUIDv5 values, so exporting the
+// same activity twice yields the same bundle.
+package stix
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/attack"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+const (
+	specVersion = "2.1"
+	timeLayout  = "2006-01-02T15:04:05.000Z"
+)
+
+var (
+	// scoNamespace is the STIX 2.1 namespace for deterministic SCO IDs.
+	scoNamespace = mustUUID("00abedb4-aa42-466c-9c01-fed23315a9b7")
+	// sdoNamespace scopes RTE-A's own SDO and SRO IDs.
+	sdoNamespace = mustUUID("6f1d6b2e-1f0a-5c7e-9a4b-72e3a0c9d1f5")
+)
+
+// Bundle is a STIX 2.1 bundle.
+type Bundle struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Objects []any  `json:"objects"`
+}
+
+// Identity is the STIX identity SDO that authors the export.
+type Identity struct {
+	Type              string   `json:"type"`
+	SpecVersion       string   `json:"spec_version"`
+	ID                string   `json:"id"`
+	Created           string   `json:"created"`
+	Modified          string   `json:"modified"`
+	Name              string   `json:"name"`
+	IdentityClass     string   `json:"identity_class"`
+	ObjectMarkingRefs []string `json:"object_marking_refs,omitempty"`
+}
+
+// ExternalReference links an object to an external catalog entry.
+type ExternalReference struct {
+	SourceName string `json:"source_name"`
+	ExternalID string `json:"external_id,omitempty"`
+	URL        string `json:"url,omitempty"`
+}
+
+// AttackPattern is the STIX attack-pattern SDO for an ATT&CK technique.
+type AttackPattern struct {
+	Type               string              `json:"type"`
+	SpecVersion        string              `json:"spec_version"`
+	ID                 string              `json:"id"`
+	CreatedByRef       string              `json:"created_by_ref"`
+	Created            string              `json:"created"`
+	Modified           string              `json:"modified"`
+	Name               string              `json:"name"`
+	ExternalReferences []ExternalReference `json:"external_references"`
+	KillChainPhases    []KillChainPhase    `json:"kill_chain_phases,omitempty"`
+	ObjectMarkingRefs  []string            `json:"object_marking_refs,omitempty"`
+}
+
+// KillChainPhase places an attack pattern in the ATT&CK kill chain.
+type KillChainPhase struct {
+	KillChainName string `json:"kill_chain_name"`
+	PhaseName     string `json:"phase_name"`
+}
+
+// ObservedData is the STIX observed-data SDO for one task execution.
+type ObservedData struct {
+	Type              string   `json:"type"`
+	SpecVersion       string   `json:"spec_version"`
+	ID                string   `json:"id"`
+	CreatedByRef      string   `json:"created_by_ref"`
+	Created           string   `json:"created"`
+	Modified          string   `json:"modified"`
+	FirstObserved     string   `json:"first_observed"`
+	LastObserved      string   `json:"last_observed"`
+	NumberObserved    int      `json:"number_observed"`
+	ObjectRefs        []string `json:"object_refs"`
+	Labels            []string `json:"labels,omitempty"`
+	TaskID            string   `json:"x_rte_a_task_id"`
+	TaskType          string   `json:"x_rte_a_task_type"`
+	Engagement        string   `json:"x_rte_a_engagement"`
+	Operator          string   `json:"x_rte_a_operator"`
+	ObjectMarkingRefs []string `json:"object_marking_refs,omitempty"`
+}
+
+// Relationship is a STIX SRO.
+type Relationship struct {
+	Type              string   `json:"type"`
+	SpecVersion       string   `json:"spec_version"`
+	ID                string   `json:"id"`
+	CreatedByRef      string   `json:"created_by_ref"`
+	Created           string   `json:"created"`
+	Modified          string   `json:"modified"`
+	RelationshipType  string   `json:"relationship_type"`
+	SourceRef         string   `json:"source_ref"`
+	TargetRef         string   `json:"target_ref"`
+	ObjectMarkingRefs []string `json:"object_marking_refs,omitempty"`
+}
+
+// Observable is a STIX cyber-observable object (ipv4-addr, domain-name,
+// url, email-addr), or the x-rte-a-task custom observable when a task has
+// no addressable target.
+type Observable map[string]any
+
+// Export builds a bundle from an engagement's completed tasks. Each task
+// with a completed result becomes an observed-data object referencing its
+// targets, related to the attack-pattern of every ATT&CK technique it is
+// tagged with.
+func Export(engagement string, tasks []rte.Task, results []rte.TaskResult) (*Bundle, error) {
+	if engagement == "" {
+		return nil, errors.New("engagement is required")
+	}
+	done := make(map[string]rte.TaskResult, len(results))
+	for _, r := range results {
+		if r.Engagement == engagement && r.State == rte.StateCompleted {
+			done[r.TaskID] = r
+		}
+	}
+	author := Identity{
+		Type: "identity", SpecVersion: specVersion,
+		ID:            sdoID("identity", "rte-a/"+engagement),
+		Name:          "RTE-A red team (" + engagement + ")",
+		IdentityClass: "group",
+	}
+	var (
+		objects   []any
+		objectIDs []string
+		patterns  = make(map[string]AttackPattern)
+		scos      = make(map[string]Observable)
+		earliest  time.Time
+	)
+	sorted := append([]rte.Task(nil), tasks...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	for _, t := range sorted {
+		res, ok := done[t.ID]
+		if t.Engagement != engagement || !ok {
+			continue
+		}
+		if earliest.IsZero() || res.StartedAt.Before(earliest) {
+			earliest = res.StartedAt
+		}
+		stamp := res.FinishedAt.UTC().Format(timeLayout)
+		var refs []string
+		for _, o := range observables(t) {
+			id := o["id"].(string)
+			scos[id] = o
+			refs = append(refs, id)
+		}
+		od := ObservedData{
+			Type: "observed-data", SpecVersion: specVersion,
+			ID:           sdoID("observed-data", t.Engagement+"/"+t.ID),
+			CreatedByRef: author.ID, Created: stamp, Modified: stamp,
+			FirstObserved:  res.StartedAt.UTC().Format(timeLayout),
+			LastObserved:   res.FinishedAt.UTC().Format(timeLayout),
+			NumberObserved: 1,
+			ObjectRefs:     refs,
+			Labels:         []string{"red-team-simulation"},
+			TaskID:         t.ID, TaskType: string(t.Type), Engagement: t.Engagement, Operator: t.Operator,
+		}
+		objects = append(objects, od)
+		objectIDs = append(objectIDs, od.ID)
+		for _, tech := range t.Techniques {
+			ap, err := attackPattern(author.ID, tech, stamp, patterns)
+			if err != nil {
+				return nil, fmt.Errorf("task %s: %w", t.ID, err)
+			}
+			rel := sdoID("relationship", od.ID+"/"+ap.ID)
+			objectIDs = append(objectIDs, rel)
+			objects = append(objects, Relationship{
+				Type: "relationship", SpecVersion: specVersion,
+				ID:           rel,
+				CreatedByRef: author.ID, Created: stamp, Modified: stamp,
+				RelationshipType: "related-to", SourceRef: od.ID, TargetRef: ap.ID,
+			})
+		}
+	}
+	if earliest.IsZero() {
+		earliest = time.Unix(0, 0)
+	}
+	author.Created = earliest.UTC().Format(timeLayout)
+	author.Modified = author.Created
+
+	out := []any{author}
+	for _, id := range sortedKeys(patterns) {
+		out = append(out, patterns[id])
+	}
+	for _, id := range sortedKeys(scos) {
+		out = append(out, scos[id])
+	}
+	out = append(out, objects...)
+	// The bundle ID is derived from the IDs of everything it carries, so a
+	// re-export of unchanged activity is byte-for-byte identical.
+	ids := append([]string{engagement}, sortedKeys(patterns)...)
+	ids = append(ids, sortedKeys(scos)...)
+	ids = append(ids, objectIDs...)
+	return &Bundle{Type: "bundle", ID: sdoID("bundle", strings.Join(ids, "/")), Objects: out}, nil
+}
+
+// Marshal renders the bundle as indented JSON.
+func (b *Bundle) Marshal() ([]byte, error) {
+	return json.MarshalIndent(b, "", "  ")
+}
+
+func attackPattern(author, techID, stamp string, seen map[string]AttackPattern) (AttackPattern, error) {
+	id := sdoID("attack-pattern", "mitre-attack/"+techID)
+	if ap, ok := seen[id]; ok {
+		return ap, nil
+	}
+	tech, ok := attack.Lookup(techID)
+	if !ok {
+		return AttackPattern{}, fmt.Errorf("ATT&CK technique %s is not in the catalog", techID)
+	}
+	ap := AttackPattern{
+		Type: "attack-pattern", SpecVersion: specVersion, ID: id,
+		CreatedByRef: author, Created: stamp, Modified: stamp,
+		Name: tech.Name,
+		ExternalReferences: []ExternalReference{{
+			SourceName: "mitre-attack", ExternalID: tech.ID,
+			URL: "https://attack.mitre.org/techniques/" + strings.ReplaceAll(tech.ID, ".", "/") + "/",
+		}},
+	}
+	for _, tac := range tech.Tactics {
+		ap.KillChainPhases = append(ap.KillChainPhases, KillChainPhase{KillChainName: "mitre-attack", PhaseName: tac})
+	}
+	seen[id] = ap
+	return ap, nil
+}
+
+// observables derives SCOs from the task's target-like params.
+func observables(t rte.Task) []Observable {
+	var out []Observable
+	add := func(typ, key, value string) {
+		out = append(out, Observable{"type": typ, "spec_version": specVersion, "id": scoID(typ, key, value), key: value})
+	}
+	host := func(h string) {
+		if a, err := netip.ParseAddr(h); err == nil {
+			if a.Is4() {
+				add("ipv4-addr", "value", a.String())
+			} else {
+				add("ipv6-addr", "value", a.String())
+			}
+		} else if h != "" {
+			add("domain-name", "value", strings.ToLower(h))
+		}
+	}
+	for _, k := range []string{"target", "destination", "sink"} {
+		v := t.Params[k]
+		if v == "" {
+			continue
+		}
+		if u, err := url.Parse(v); err == nil && u.Scheme != "" && u.Host != "" {
+			add("url", "value", v)
+			host(u.Hostname())
+		} else if h, _, err := net.SplitHostPort(v); err == nil {
+			host(h)
+		} else {
+			host(v)
+		}
+	}
+	for _, r := range strings.Split(t.Params["recipients"], ",") {
+		if r = strings.TrimSpace(strings.ToLower(r)); r != "" {
+			add("email-addr", "value", r)
+		}
+	}
+	if len(out) == 0 {
+		add("x-rte-a-task", "task_id", t.Engagement+"/"+t.ID)
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scoID follows STIX 2.1 §2.9: UUIDv5 over the JSON of the ID-contributing
+// properties in the SCO namespace.
+func scoID(typ, key, value string) string {
+	b, _ := json.Marshal(map[string]string{key: value})
+	return typ + "--" + uuidV5(scoNamespace, b)
+}
+
+func sdoID(typ, name string) string {
+	return typ + "--" + uuidV5(sdoNamespace, []byte(typ+"/"+name))
+}
+
+func uuidV5(ns [16]byte, name []byte) string {
+	h := sha1.New()
+	h.Write(ns[:])
+	h.Write(name)
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+func mustUUID(s string) [16]byte {
+	var u [16]byte
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != len(u) {
+		panic("stix: bad namespace UUID " + s)
+	}
+	copy(u[:], b)
+	return u
+}
@@ -0,0 +1,217 @@
+package stix
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+var idPattern = regexp.MustCompile(`^[a-z0-9-]+--[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func fixture() ([]rte.Task, []rte.TaskResult) {
+	start := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	tasks := []rte.Task{
+		{ID: "t-spray", Engagement: "eng-1", Type: rte.TaskSimulateCredentialSpray, Operator: "alice",
+			Params: map[string]string{"target": "10.20.0.5:443"}, Techniques: []string{"T1110.003"}},
+		{ID: "t-phish", Engagement: "eng-1", Type: rte.TaskSimulatePhish, Operator: "alice",
+			Params: map[string]string{"recipients": "Test.User@eng-1.example, qa@eng-1.example"}, Techniques: []string{"T1566.002", "T1110.003"}},
+		{ID: "t-failed", Engagement: "eng-1", Type: rte.TaskSimulateLogin, Operator: "alice", Techniques: []string{"T1078"}},
+		{ID: "t-other", Engagement: "eng-2", Type: rte.TaskSimulateLogin, Operator: "bob"},
+	}
+	results := []rte.TaskResult{
+		{TaskID: "t-spray", Engagement: "eng-1", State: rte.StateCompleted, StartedAt: start, FinishedAt: start.Add(time.Minute)},
+		{TaskID: "t-phish", Engagement: "eng-1", State: rte.StateCompleted, StartedAt: start.Add(-time.Hour), FinishedAt: start},
+		{TaskID: "t-failed", Engagement: "eng-1", State: rte.StateFailed, StartedAt: start, FinishedAt: start},
+		{TaskID: "t-other", Engagement: "eng-2", State: rte.StateCompleted, StartedAt: start, FinishedAt: start},
+	}
+	return tasks, results
+}
+
+func TestExport(t *testing.T) {
+	tasks, results := fixture()
+	b, err := Export("eng-1", tasks, results)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	raw, err := b.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var decoded struct {
+		Type    string           `json:"type"`
+		ID      string           `json:"id"`
+		Objects []map[string]any `json:"objects"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decoded.Type != "bundle" || !idPattern.MatchString(decoded.ID) {
+		t.Fatalf("bad bundle header %s %s", decoded.Type, decoded.ID)
+	}
+	byType := make(map[string][]map[string]any)
+	ids := make(map[string]bool)
+	for _, o := range decoded.Objects {
+		id := o["id"].(string)
+		if !idPattern.MatchString(id) || !strings.HasPrefix(id, o["type"].(string)+"--") {
+			t.Errorf("bad object id %s", id)
+		}
+		if o["spec_version"] != "2.1" {
+			t.Errorf("%s: spec_version %v", id, o["spec_version"])
+		}
+		ids[id] = true
+		byType[o["type"].(string)] = append(byType[o["type"].(string)], o)
+	}
+	counts := map[string]int{"identity": 1, "attack-pattern": 2, "observed-data": 2, "relationship": 3,
+		"ipv4-addr": 1, "email-addr": 2}
+	for typ, want := range counts {
+		if got := len(byType[typ]); got != want {
+			t.Errorf("%s objects: got %d, want %d", typ, got, want)
+		}
+	}
+	if got := byType["identity"][0]["created"]; got != "2026-03-02T13:00:00.000Z" {
+		t.Errorf("identity created %v, want earliest start", got)
+	}
+	for _, ap := range byType["attack-pattern"] {
+		ref := ap["external_references"].([]any)[0].(map[string]any)
+		if ref["source_name"] != "mitre-attack" || !strings.HasPrefix(ref["url"].(string), "https://attack.mitre.org/techniques/T") {
+			t.Errorf("bad external reference %v", ref)
+		}
+		if ref["external_id"] == "T1110.003" && ap["name"] != "Brute Force: Password Spraying" {
+			t.Errorf("name %v", ap["name"])
+		}
+	}
+	for _, od := range byType["observed-data"] {
+		if od["x_rte_a_task_id"] == "t-failed" {
+			t.Error("failed task exported")
+		}
+		for _, ref := range od["object_refs"].([]any) {
+			if !ids[ref.(string)] {
+				t.Errorf("dangling object ref %s", ref)
+			}
+		}
+	}
+	for _, rel := range byType["relationship"] {
+		if !ids[rel["source_ref"].(string)] || !ids[rel["target_ref"].(string)] {
+			t.Errorf("dangling relationship %v", rel)
+		}
+	}
+	var emails []string
+	for _, e := range byType["email-addr"] {
+		emails = append(emails, e["value"].(string))
+	}
+	if !strings.Contains(strings.Join(emails, ","), "test.user@eng-1.example") {
+		t.Errorf("email not normalised: %v", emails)
+	}
+
+	again, _ := Export("eng-1", tasks, results)
+	raw2, _ := again.Marshal()
+	if !bytes.Equal(raw, raw2) {
+		t.Error("export is not deterministic")
+	}
+}
+
+func TestExport_Observables(t *testing.T) {
+	cases := map[string]string{
+		"https://cb.eng-1.example/beacon": "url",
+		"cb.eng-1.example":                "domain-name",
+		"[2001:db8::1]:22":                "ipv6-addr",
+	}
+	for target, typ := range cases {
+		obs := observables(rte.Task{ID: "t", Params: map[string]string{"target": target}})
+		if obs[0]["type"] != typ {
+			t.Errorf("%s: got %v, want %s", target, obs[0]["type"], typ)
+		}
+	}
+	obs := observables(rte.Task{ID: "t", Engagement: "eng-1"})
+	if len(obs) != 1 || obs[0]["type"] != "x-rte-a-task" {
+		t.Fatalf("expected custom observable, got %v", obs)
+	}
+}
+
+func TestExport_Errors(t *testing.T) {
+	if _, err := Export("", nil, nil); err == nil {
+		t.Error("expected missing engagement to fail")
+	}
+	tasks, results := fixture()
+	tasks[0].Techniques = []string{"T9999"}
+	if _, err := Export("eng-1", tasks, results); err == nil || !strings.Contains(err.Error(), "t-spray") {
+		t.Errorf("expected unknown technique to fail, got %v", err)
+	}
+}
+
+func TestBundle_Mark(t *testing.T) {
+	tasks, results := fixture()
+	b, err := Export("eng-1", tasks, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(b.Objects)
+	b.Mark(rte.EngagementMetadata{ClientName: "Acme"})
+	if len(b.Objects) != n {
+		t.Fatal("metadata without a marking changed the bundle")
+	}
+	b.Mark(rte.EngagementMetadata{Classification: "CONFIDENTIAL", Distribution: "Acme security team only"})
+	raw, err := b.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Objects []map[string]any `json:"objects"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Objects) != n+1 {
+		t.Fatalf("%d objects, want %d", len(decoded.Objects), n+1)
+	}
+	def := decoded.Objects[1]
+	if def["type"] != "marking-definition" || !idPattern.MatchString(def["id"].(string)) ||
+		def["definition"].(map[string]any)["statement"] != "CONFIDENTIAL // Acme security team only" {
+		t.Fatalf("marking definition %v", def)
+	}
+	for _, o := range decoded.Objects {
+		if o["type"] == "marking-definition" {
+			continue
+		}
+		refs, _ := o["object_marking_refs"].([]any)
+		if len(refs) != 1 || refs[0] != def["id"] {
+			t.Errorf("%s has marking refs %v", o["id"], o["object_marking_refs"])
+		}
+	}
+}
+
+func TestExportMarked(t *testing.T) {
+	tasks, results := fixture()
+	tasks[0].Classification = rte.ClassificationRestricted
+	opts := Options{Metadata: rte.EngagementMetadata{Classification: rte.ClassificationConfidential}}
+	b, err := ExportMarked("eng-1", tasks, results, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range b.Objects {
+		if od, ok := o.(ObservedData); ok && od.TaskID == tasks[0].ID {
+			t.Errorf("RESTRICTED task %s exported at CONFIDENTIAL", od.TaskID)
+		}
+		if md, ok := o.(MarkingDefinition); ok && md.Definition["statement"] != "CONFIDENTIAL" {
+			t.Errorf("marking %v", md.Definition)
+		}
+	}
+	opts.Classification = rte.ClassificationPublic
+	if b, err = ExportMarked("eng-1", tasks, results, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range b.Objects {
+		if _, ok := o.(ObservedData); ok {
+			t.Error("unmarked task exported below its engagement's level")
+		}
+	}
+	opts.Classification = "secret"
+	if _, err := ExportMarked("eng-1", tasks, results, opts); err == nil {
+		t.Error("exported at an unknown level")
+	}
+}
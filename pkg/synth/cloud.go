@@ -0,0 +1,322 @@
+package synth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// CloudProvider selects the audit log schema to generate.
+type CloudProvider string
+
+const (
+	ProviderAWS   CloudProvider = "aws"
+	ProviderAzure CloudProvider = "azure"
+	ProviderGCP   CloudProvider = "gcp"
+)
+
+// CloudScenario is the activity pattern to simulate.
+type CloudScenario string
+
+const (
+	// ScenarioConsoleLogin is an interactive console sign-in from an external
+	// address without MFA.
+	ScenarioConsoleLogin CloudScenario = "console_login"
+	// ScenarioIAMChange grants a user broad privileges.
+	ScenarioIAMChange CloudScenario = "iam_change"
+	// ScenarioAPIBurst is a rapid enumeration burst from one principal.
+	ScenarioAPIBurst CloudScenario = "api_burst"
+)
+
+const (
+	defaultBurstEvents = 50
+	maxBurstEvents     = 5000
+)
+
+// CloudConfig parameterizes cloud audit log generation.
+type CloudConfig struct {
+	Provider CloudProvider
+	Scenario CloudScenario
+	Start    time.Time
+	// Actor selects the identity-set user performing the activity.
+	Actor int
+	// Events is the number of calls in an api_burst. Defaults to 50.
+	Events int
+	// Region is the cloud region; a provider-appropriate default is used.
+	Region string
+}
+
+// CloudEvent is one synthetic audit log record in the provider's schema.
+type CloudEvent struct {
+	Provider CloudProvider
+	Scenario CloudScenario
+	Time     time.Time
+	Record   map[string]any
+}
+
+// MarshalJSON renders the provider-native record.
+func (e CloudEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Record)
+}
+
+// cloudContext holds the per-engagement values that keep cloud events
+// consistent with the rest of the engagement's synthetic data.
+type cloudContext struct {
+	r        *rand.Rand
+	cfg      CloudConfig
+	ids      *IdentitySet
+	actor    User
+	external netip.Addr
+	account  string
+}
+
+var (
+	awsBurstCalls = []struct{ source, name string }{
+		{"s3.amazonaws.com", "ListBuckets"},
+		{"s3.amazonaws.com", "GetBucketAcl"},
+		{"ec2.amazonaws.com", "DescribeInstances"},
+		{"iam.amazonaws.com", "ListUsers"},
+		{"iam.amazonaws.com", "ListRoles"},
+		{"secretsmanager.amazonaws.com", "ListSecrets"},
+		{"kms.amazonaws.com", "ListKeys"},
+	}
+	azureBurstOps = []string{
+		"Microsoft.Storage/storageAccounts/listKeys/action",
+		"Microsoft.Compute/virtualMachines/read",
+		"Microsoft.KeyVault/vaults/read",
+		"Microsoft.Authorization/roleAssignments/read",
+		"Microsoft.Network/networkSecurityGroups/read",
+	}
+	gcpBurstCalls = []struct{ service, method string }{
+		{"storage.googleapis.com", "storage.buckets.list"},
+		{"compute.googleapis.com", "v1.compute.instances.list"},
+		{"iam.googleapis.com", "google.iam.admin.v1.ListServiceAccounts"},
+		{"secretmanager.googleapis.com", "google.cloud.secretmanager.v1.SecretManagerService.ListSecrets"},
+		{"cloudresourcemanager.googleapis.com", "GetIamPolicy"},
+	}
+)
+
+// CloudEvents generates audit log events for one scenario. Principals and
+// names come from ids; sign-ins come from RFC 5737 documentation addresses so
+// no real external host is ever referenced.
+func CloudEvents(ids *IdentitySet, cfg CloudConfig) ([]CloudEvent, error) {
+	if ids == nil {
+		return nil, errors.New("identity set is required")
+	}
+	if cfg.Start.IsZero() {
+		return nil, errors.New("start time is required")
+	}
+	if cfg.Events == 0 {
+		cfg.Events = defaultBurstEvents
+	}
+	if cfg.Events < 1 || cfg.Events > maxBurstEvents {
+		return nil, fmt.Errorf("events must be between 1 and %d, got %d", maxBurstEvents, cfg.Events)
+	}
+	switch cfg.Scenario {
+	case ScenarioConsoleLogin, ScenarioIAMChange, ScenarioAPIBurst:
+	default:
+		return nil, fmt.Errorf("unsupported cloud scenario: %s", cfg.Scenario)
+	}
+	r := seededRand(fmt.Sprintf("%s/cloud/%s/%s/%d", ids.Engagement, cfg.Provider, cfg.Scenario, cfg.Start.Unix()))
+	c := &cloudContext{
+		r:        r,
+		cfg:      cfg,
+		ids:      ids,
+		actor:    ids.UserAt(cfg.Actor),
+		external: netip.AddrFrom4([4]byte{198, 51, 100, byte(r.IntN(254) + 1)}),
+		account:  fmt.Sprintf("%012d", seededRand(ids.Engagement+"/account").Int64N(1e12)),
+	}
+	switch cfg.Provider {
+	case ProviderAWS:
+		if c.cfg.Region == "" {
+			c.cfg.Region = "us-east-1"
+		}
+		return c.aws(), nil
+	case ProviderAzure:
+		if c.cfg.Region == "" {
+			c.cfg.Region = "eastus"
+		}
+		return c.azure(), nil
+	case ProviderGCP:
+		if c.cfg.Region == "" {
+			c.cfg.Region = "us-central1"
+		}
+		return c.gcp(), nil
+	default:
+		return nil, fmt.Errorf("unsupported cloud provider: %s", cfg.Provider)
+	}
+}
+
+// DeliverCloudEvents renders events as JSON lines and sends them to sink.
+func DeliverCloudEvents(ctx context.Context, sink EventSink, events []CloudEvent) error {
+	return deliverJSON(ctx, sink, events)
+}
+
+// deliverJSON marshals each record onto its own line and sends the batch.
+func deliverJSON[T any](ctx context.Context, sink EventSink, records []T) error {
+	if sink == nil {
+		return errors.New("sink is required")
+	}
+	lines := make([][]byte, 0, len(records))
+	for _, r := range records {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("marshal event: %w", err)
+		}
+		lines = append(lines, b)
+	}
+	return sink.Send(ctx, lines)
+}
+
+// times returns n event times: bursts are sub-second, others a few seconds apart.
+func (c *cloudContext) times(n int) []time.Time {
+	out := make([]time.Time, n)
+	t := c.cfg.Start
+	for i := range out {
+		out[i] = t.UTC()
+		if c.cfg.Scenario == ScenarioAPIBurst {
+			t = t.Add(time.Duration(50+c.r.IntN(400)) * time.Millisecond)
+		} else {
+			t = t.Add(time.Duration(2+c.r.IntN(20)) * time.Second)
+		}
+	}
+	return out
+}
+
+func (c *cloudContext) aws() []CloudEvent {
+	arn := fmt.Sprintf("arn:aws:iam::%s:user/%s", c.account, c.actor.Username)
+	identity := map[string]any{
+		"type": "IAMUser", "principalId": fmt.Sprintf("AIDA%016X", c.r.Uint64()),
+		"arn": arn, "accountId": c.account, "userName": c.actor.Username,
+	}
+	record := func(at time.Time, source, name, eventType string) map[string]any {
+		return map[string]any{
+			"eventVersion": "1.08", "userIdentity": identity, "eventTime": at.Format(time.RFC3339),
+			"eventSource": source, "eventName": name, "awsRegion": c.cfg.Region,
+			"sourceIPAddress": c.external.String(), "userAgent": "aws-cli/2.15.0 Python/3.11",
+			"eventID": fakeUUID(c.r), "eventType": eventType, "recipientAccountId": c.account,
+			"readOnly": eventType == "AwsApiCall" && c.cfg.Scenario == ScenarioAPIBurst,
+		}
+	}
+	var out []CloudEvent
+	add := func(at time.Time, rec map[string]any) {
+		out = append(out, CloudEvent{Provider: ProviderAWS, Scenario: c.cfg.Scenario, Time: at, Record: rec})
+	}
+	switch c.cfg.Scenario {
+	case ScenarioConsoleLogin:
+		at := c.times(1)[0]
+		rec := record(at, "signin.amazonaws.com", "ConsoleLogin", "AwsConsoleSignIn")
+		rec["userAgent"] = "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"
+		rec["responseElements"] = map[string]any{"ConsoleLogin": "Success"}
+		rec["additionalEventData"] = map[string]any{"MFAUsed": "No", "MobileVersion": "No"}
+		add(at, rec)
+	case ScenarioIAMChange:
+		ts := c.times(2)
+		rec := record(ts[0], "iam.amazonaws.com", "AttachUserPolicy", "AwsApiCall")
+		rec["requestParameters"] = map[string]any{"userName": c.actor.Username, "policyArn": "arn:aws:iam::aws:policy/AdministratorAccess"}
+		add(ts[0], rec)
+		rec = record(ts[1], "iam.amazonaws.com", "CreateAccessKey", "AwsApiCall")
+		rec["requestParameters"] = map[string]any{"userName": c.actor.Username}
+		add(ts[1], rec)
+	case ScenarioAPIBurst:
+		for _, at := range c.times(c.cfg.Events) {
+			call := awsBurstCalls[c.r.IntN(len(awsBurstCalls))]
+			add(at, record(at, call.source, call.name, "AwsApiCall"))
+		}
+	}
+	return out
+}
+
+func (c *cloudContext) azure() []CloudEvent {
+	sub := fakeUUID(c.r)
+	tenant := fakeUUID(c.r)
+	claims := map[string]any{
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/upn": c.actor.Email,
+		"name": c.actor.DisplayName, "ipaddr": c.external.String(),
+	}
+	record := func(at time.Time, op, category string) map[string]any {
+		return map[string]any{
+			"time": at.Format(time.RFC3339Nano), "resourceId": fmt.Sprintf("/subscriptions/%s/resourceGroups/rg-%s", sub, c.actor.Department),
+			"operationName": op, "category": category, "resultType": "Success", "level": "Informational",
+			"callerIpAddress": c.external.String(), "correlationId": fakeUUID(c.r), "tenantId": tenant,
+			"location": c.cfg.Region, "identity": map[string]any{"claims": claims},
+		}
+	}
+	var out []CloudEvent
+	add := func(at time.Time, rec map[string]any) {
+		out = append(out, CloudEvent{Provider: ProviderAzure, Scenario: c.cfg.Scenario, Time: at, Record: rec})
+	}
+	switch c.cfg.Scenario {
+	case ScenarioConsoleLogin:
+		at := c.times(1)[0]
+		rec := map[string]any{
+			"time": at.Format(time.RFC3339Nano), "operationName": "Sign-in activity", "category": "SignInLogs",
+			"resultType": "0", "callerIpAddress": c.external.String(), "correlationId": fakeUUID(c.r), "tenantId": tenant,
+			"properties": map[string]any{
+				"userPrincipalName": c.actor.Email, "appDisplayName": "Azure Portal",
+				"authenticationRequirement": "singleFactorAuthentication", "clientAppUsed": "Browser",
+			},
+		}
+		add(at, rec)
+	case ScenarioIAMChange:
+		at := c.times(1)[0]
+		rec := record(at, "Microsoft.Authorization/roleAssignments/write", "Administrative")
+		rec["properties"] = map[string]any{
+			"requestbody": map[string]any{"roleDefinitionName": "Owner", "principalName": c.actor.Email},
+		}
+		add(at, rec)
+	case ScenarioAPIBurst:
+		for _, at := range c.times(c.cfg.Events) {
+			add(at, record(at, azureBurstOps[c.r.IntN(len(azureBurstOps))], "Administrative"))
+		}
+	}
+	return out
+}
+
+func (c *cloudContext) gcp() []CloudEvent {
+	// GCP project IDs are at most 30 lowercase letters, digits, and hyphens.
+	base := strings.TrimSuffix(c.ids.Domain, ".example")
+	project := strings.TrimRight("rte-"+base[:min(len(base), 26)], "-")
+	record := func(at time.Time, service, method, resource string) map[string]any {
+		return map[string]any{
+			"insertId": fmt.Sprintf("%x", c.r.Uint64()), "timestamp": at.Format(time.RFC3339Nano),
+			"severity": "NOTICE", "logName": fmt.Sprintf("projects/%s/logs/cloudaudit.googleapis.com%%2Factivity", project),
+			"resource": map[string]any{"type": "project", "labels": map[string]any{"project_id": project}},
+			"protoPayload": map[string]any{
+				"@type":              "type.googleapis.com/google.cloud.audit.AuditLog",
+				"serviceName":        service,
+				"methodName":         method,
+				"resourceName":       resource,
+				"authenticationInfo": map[string]any{"principalEmail": c.actor.Email},
+				"requestMetadata":    map[string]any{"callerIp": c.external.String(), "callerSuppliedUserAgent": "google-cloud-sdk gcloud/460.0.0"},
+			},
+		}
+	}
+	var out []CloudEvent
+	add := func(at time.Time, rec map[string]any) {
+		out = append(out, CloudEvent{Provider: ProviderGCP, Scenario: c.cfg.Scenario, Time: at, Record: rec})
+	}
+	switch c.cfg.Scenario {
+	case ScenarioConsoleLogin:
+		at := c.times(1)[0]
+		rec := record(at, "login.googleapis.com", "google.login.LoginService.loginSuccess", "projects/"+project)
+		rec["protoPayload"].(map[string]any)["metadata"] = map[string]any{"is_second_factor": false}
+		add(at, rec)
+	case ScenarioIAMChange:
+		ts := c.times(2)
+		add(ts[0], record(ts[0], "cloudresourcemanager.googleapis.com", "SetIamPolicy", "projects/"+project))
+		add(ts[1], record(ts[1], "iam.googleapis.com", "google.iam.admin.v1.CreateServiceAccountKey",
+			fmt.Sprintf("projects/%s/serviceAccounts/%s@%s.iam.gserviceaccount.com", project, c.actor.Username, project)))
+	case ScenarioAPIBurst:
+		for _, at := range c.times(c.cfg.Events) {
+			call := gcpBurstCalls[c.r.IntN(len(gcpBurstCalls))]
+			add(at, record(at, call.service, call.method, "projects/"+project))
+		}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloudEvents_AllProvidersAndScenarios(t *testing.T) {
+	ids, err := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	if err != nil {
+		t.Fatalf("NewIdentitySet: %v", err)
+	}
+	start := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	actor := ids.UserAt(2)
+	for _, p := range []CloudProvider{ProviderAWS, ProviderAzure, ProviderGCP} {
+		for _, s := range []CloudScenario{ScenarioConsoleLogin, ScenarioIAMChange, ScenarioAPIBurst} {
+			events, err := CloudEvents(ids, CloudConfig{Provider: p, Scenario: s, Start: start, Actor: 2, Events: 20})
+			if err != nil {
+				t.Fatalf("%s/%s: %v", p, s, err)
+			}
+			if len(events) == 0 {
+				t.Fatalf("%s/%s: no events", p, s)
+			}
+			if s == ScenarioAPIBurst && len(events) != 20 {
+				t.Errorf("%s burst: got %d events, want 20", p, len(events))
+			}
+			for _, e := range events {
+				data, err := json.Marshal(e)
+				if err != nil {
+					t.Fatalf("marshal: %v", err)
+				}
+				if !strings.Contains(string(data), actor.Username) {
+					t.Errorf("%s/%s event does not reference actor %s: %s", p, s, actor.Username, data)
+				}
+				if !strings.Contains(string(data), "198.51.100.") {
+					t.Errorf("%s/%s event missing documentation-range source IP", p, s)
+				}
+			}
+			if s == ScenarioAPIBurst {
+				if span := events[len(events)-1].Time.Sub(events[0].Time); span > 20*500*time.Millisecond {
+					t.Errorf("%s burst spans %s", p, span)
+				}
+			}
+		}
+	}
+}
+
+func TestCloudEvents_Deterministic(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	cfg := CloudConfig{Provider: ProviderAWS, Scenario: ScenarioAPIBurst, Start: time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)}
+	a, _ := CloudEvents(ids, cfg)
+	b, _ := CloudEvents(ids, cfg)
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	if !bytes.Equal(ja, jb) {
+		t.Fatal("expected identical events for identical config")
+	}
+}
+
+func TestCloudEvents_Invalid(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	start := time.Now()
+	cases := []CloudConfig{
+		{Provider: "oracle", Scenario: ScenarioConsoleLogin, Start: start},
+		{Provider: ProviderAWS, Scenario: "ransomware", Start: start},
+		{Provider: ProviderAWS, Scenario: ScenarioConsoleLogin},
+		{Provider: ProviderAWS, Scenario: ScenarioAPIBurst, Start: start, Events: maxBurstEvents + 1},
+	}
+	for _, cfg := range cases {
+		if _, err := CloudEvents(ids, cfg); err == nil {
+			t.Errorf("expected %+v to fail", cfg)
+		}
+	}
+	if _, err := CloudEvents(nil, CloudConfig{Provider: ProviderAWS, Scenario: ScenarioConsoleLogin, Start: start}); err == nil {
+		t.Error("expected nil identity set to fail")
+	}
+}
+
+func TestDeliverCloudEvents(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	events, err := CloudEvents(ids, CloudConfig{Provider: ProviderGCP, Scenario: ScenarioIAMChange, Start: time.Now()})
+	if err != nil {
+		t.Fatalf("CloudEvents: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := DeliverCloudEvents(context.Background(), &WriterSink{W: &buf}, events); err != nil {
+		t.Fatalf("DeliverCloudEvents: %v", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != len(events) {
+		t.Fatalf("lines: got %d, want %d", n, len(events))
+	}
+}
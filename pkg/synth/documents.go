@@ -0,0 +1,102 @@
+package synth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DocumentKind is the shape of a synthetic document.
+type DocumentKind string
+
+const (
+	// DocumentRecords is a CSV export of customer-like records with
+	// DLP-detectable (but invalid or published test) identifiers.
+	DocumentRecords DocumentKind = "csv_records"
+	// DocumentMemo is a plain-text memo with classification markings.
+	DocumentMemo DocumentKind = "memo"
+)
+
+const (
+	// DocumentWatermark appears at the start of and throughout every
+	// synthetic document.
+	DocumentWatermark = "RTE-A SYNTHETIC DATA - NOT REAL"
+	maxDocumentBytes  = 64 << 20
+)
+
+// Published card numbers that pass Luhn checks but are reserved for testing.
+var testCardNumbers = []string{
+	"4111111111111111", "5555555555554444", "378282246310005", "6011111111111117",
+}
+
+// DocumentConfig parameterizes GenerateDocument.
+type DocumentConfig struct {
+	Kind DocumentKind
+	// Size is the exact document size in bytes.
+	Size int
+	// Name distinguishes documents generated for the same engagement, such
+	// as the task ID plus an index.
+	Name string
+}
+
+// Document is a generated synthetic file.
+type Document struct {
+	Name    string       `json:"name"`
+	Kind    DocumentKind `json:"kind"`
+	Content []byte       `json:"-"`
+	SHA256  string       `json:"sha256"`
+}
+
+// GenerateDocument builds a deterministic watermarked document of exactly
+// cfg.Size bytes. Record identifiers use the 900-series SSN area (never
+// issued) and published test card numbers, so DLP rules fire on realistic
+// patterns without any real data being involved.
+func GenerateDocument(ids *IdentitySet, cfg DocumentConfig) (*Document, error) {
+	if ids == nil {
+		return nil, errors.New("identity set is required")
+	}
+	if cfg.Name == "" {
+		return nil, errors.New("document name is required")
+	}
+	if cfg.Size < len(DocumentWatermark)+1 || cfg.Size > maxDocumentBytes {
+		return nil, fmt.Errorf("document size must be between %d and %d bytes, got %d", len(DocumentWatermark)+1, maxDocumentBytes, cfg.Size)
+	}
+	r := seededRand(ids.Engagement + "/document/" + cfg.Name)
+	var b bytes.Buffer
+	b.Grow(cfg.Size + 256)
+	ext := ".txt"
+	switch cfg.Kind {
+	case DocumentRecords:
+		ext = ".csv"
+		b.WriteString("# " + DocumentWatermark + "\n")
+		b.WriteString("customer_id,name,email,ssn,card_number,department,marker\n")
+		for n := 0; b.Len() < cfg.Size; n++ {
+			u := ids.UserAt(r.IntN(len(ids.Users)))
+			fmt.Fprintf(&b, "C%07d,%s,%s,9%02d-%02d-%04d,%s,%s,rte-a-synthetic\n",
+				n+1, u.DisplayName, u.Email, r.IntN(100), 1+r.IntN(99), 1+r.IntN(9999),
+				testCardNumbers[r.IntN(len(testCardNumbers))], u.Department)
+		}
+	case DocumentMemo:
+		author := ids.UserAt(r.IntN(len(ids.Users)))
+		fmt.Fprintf(&b, "%s\nCONFIDENTIAL - INTERNAL USE ONLY\nFrom: %s <%s>\nSubject: Q%d planning notes\n\n",
+			DocumentWatermark, author.DisplayName, author.Email, 1+r.IntN(4))
+		for n := 0; b.Len() < cfg.Size; n++ {
+			u := ids.UserAt(r.IntN(len(ids.Users)))
+			fmt.Fprintf(&b, "%d. %s (%s) to review %s budget line %04d before the offsite. [%s]\n",
+				n+1, u.DisplayName, u.Department, strings.ToUpper(u.Department), r.IntN(10000), DocumentWatermark)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported document kind: %s", cfg.Kind)
+	}
+	content := b.Bytes()[:cfg.Size]
+	sum := sha256.Sum256(content)
+	return &Document{
+		Name:    slug(cfg.Name) + ext,
+		Kind:    cfg.Kind,
+		Content: content,
+		SHA256:  hex.EncodeToString(sum[:]),
+	}, nil
+}
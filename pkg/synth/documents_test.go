@@ -0,0 +1,51 @@
+package synth
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestGenerateDocument(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	for _, kind := range []DocumentKind{DocumentRecords, DocumentMemo} {
+		doc, err := GenerateDocument(ids, DocumentConfig{Kind: kind, Size: 10000, Name: "task-1/0"})
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if len(doc.Content) != 10000 {
+			t.Fatalf("%s: got %d bytes, want 10000", kind, len(doc.Content))
+		}
+		if !bytes.Contains(doc.Content[:64], []byte(DocumentWatermark)) {
+			t.Errorf("%s: watermark missing from header", kind)
+		}
+		again, _ := GenerateDocument(ids, DocumentConfig{Kind: kind, Size: 10000, Name: "task-1/0"})
+		if again.SHA256 != doc.SHA256 {
+			t.Errorf("%s: generation is not deterministic", kind)
+		}
+	}
+	doc, _ := GenerateDocument(ids, DocumentConfig{Kind: DocumentRecords, Size: 4096, Name: "Task 1"})
+	if doc.Name != "task-1.csv" {
+		t.Errorf("unexpected name %q", doc.Name)
+	}
+	// Identifiers must stay inside ranges that can never be real.
+	for _, ssn := range regexp.MustCompile(`,(\d{3})-\d{2}-\d{4},`).FindAllSubmatch(doc.Content, -1) {
+		if ssn[1][0] != '9' {
+			t.Fatalf("SSN-like value outside the 900 area: %s", ssn[0])
+		}
+	}
+}
+
+func TestGenerateDocument_Invalid(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	for _, cfg := range []DocumentConfig{
+		{Kind: DocumentMemo, Size: 10, Name: "x"},
+		{Kind: DocumentMemo, Size: maxDocumentBytes + 1, Name: "x"},
+		{Kind: "pdf", Size: 4096, Name: "x"},
+		{Kind: DocumentMemo, Size: 4096},
+	} {
+		if _, err := GenerateDocument(ids, cfg); err == nil {
+			t.Errorf("expected %+v to fail", cfg)
+		}
+	}
+}
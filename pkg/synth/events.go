@@ -0,0 +1,144 @@
+package synth
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// EventKind is a category of synthetic security event.
+type EventKind string
+
+const (
+	EventFailedLogin     EventKind = "failed_login"
+	EventProcessCreation EventKind = "process_creation"
+	EventDNSQuery        EventKind = "dns_query"
+)
+
+// Windows Security event IDs used for the matching kinds.
+const (
+	winEventFailedLogon    = 4625
+	winEventProcessCreated = 4688
+	sysmonEventDNSQuery    = 22
+)
+
+var validEventKinds = map[EventKind]struct{}{
+	EventFailedLogin:     {},
+	EventProcessCreation: {},
+	EventDNSQuery:        {},
+}
+
+// SecurityEvent is one synthetic host or network security event. Every event
+// carries the engagement and task that produced it so it can be traced back
+// and excluded from real incident handling.
+type SecurityEvent struct {
+	Time       time.Time `json:"time"`
+	Kind       EventKind `json:"kind"`
+	EventID    int       `json:"event_id"`
+	Engagement string    `json:"engagement"`
+	TaskID     string    `json:"task_id"`
+	Host       string    `json:"host"`
+	HostIP     string    `json:"host_ip"`
+	User       string    `json:"user"`
+	Domain     string    `json:"domain"`
+	SourceIP   string    `json:"source_ip,omitempty"`
+	Image      string    `json:"image,omitempty"`
+	Parent     string    `json:"parent_image,omitempty"`
+	Command    string    `json:"command_line,omitempty"`
+	PID        int       `json:"pid,omitempty"`
+	Query      string    `json:"query,omitempty"`
+	Outcome    string    `json:"outcome"`
+	Severity   int       `json:"severity"`
+	Synthetic  bool      `json:"rte_a_synthetic"`
+}
+
+// ParseEventKinds parses a comma-separated list of event kinds.
+func ParseEventKinds(s string) ([]EventKind, error) {
+	var out []EventKind
+	for _, part := range strings.Split(s, ",") {
+		k := EventKind(strings.TrimSpace(part))
+		if _, ok := validEventKinds[k]; !ok {
+			return nil, fmt.Errorf("unsupported event kind: %s", k)
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+var (
+	benignProcesses = []struct{ image, parent, cmd string }{
+		{`C:\Windows\System32\whoami.exe`, `C:\Windows\System32\cmd.exe`, "whoami /all"},
+		{`C:\Windows\System32\net.exe`, `C:\Windows\System32\cmd.exe`, "net group \"Domain Admins\" /domain"},
+		{`C:\Windows\System32\nltest.exe`, `C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`, "nltest /dclist:"},
+		{`C:\Windows\System32\ipconfig.exe`, `C:\Windows\System32\cmd.exe`, "ipconfig /all"},
+		{`C:\Windows\System32\tasklist.exe`, `C:\Windows\System32\cmd.exe`, "tasklist /v"},
+	}
+	queryLabels = []string{"update", "cdn", "telemetry", "sync", "api", "files"}
+)
+
+// EventGenerator produces security events attributed to one task, drawing
+// actors and hosts from the engagement's identity set.
+type EventGenerator struct {
+	ids    *IdentitySet
+	taskID string
+	r      *rand.Rand
+}
+
+// NewEventGenerator returns a deterministic generator for the task.
+func NewEventGenerator(ids *IdentitySet, taskID string) (*EventGenerator, error) {
+	if ids == nil {
+		return nil, errors.New("identity set is required")
+	}
+	if taskID == "" {
+		return nil, errors.New("task ID is required")
+	}
+	return &EventGenerator{ids: ids, taskID: taskID, r: seededRand(ids.Engagement + "/events/" + taskID)}, nil
+}
+
+// Next returns one event of the given kind at time at.
+func (g *EventGenerator) Next(kind EventKind, at time.Time) (SecurityEvent, error) {
+	if _, ok := validEventKinds[kind]; !ok {
+		return SecurityEvent{}, fmt.Errorf("unsupported event kind: %s", kind)
+	}
+	user := g.ids.Users[g.r.IntN(len(g.ids.Users))]
+	host, _ := g.ids.Host(user.Workstation)
+	e := SecurityEvent{
+		Time:       at.UTC(),
+		Kind:       kind,
+		Engagement: g.ids.Engagement,
+		TaskID:     g.taskID,
+		Host:       host.Hostname,
+		HostIP:     host.IP.String(),
+		User:       user.Username,
+		Domain:     g.ids.Domain,
+		Synthetic:  true,
+	}
+	switch kind {
+	case EventFailedLogin:
+		// Failed logons land on a server from a user's workstation.
+		src := host
+		if servers := g.ids.Servers(); len(servers) > 0 {
+			target := servers[g.r.IntN(len(servers))]
+			e.Host, e.HostIP = target.Hostname, target.IP.String()
+		}
+		e.EventID = winEventFailedLogon
+		e.SourceIP = src.IP.String()
+		e.Outcome = "failure"
+		e.Severity = 5
+	case EventProcessCreation:
+		p := benignProcesses[g.r.IntN(len(benignProcesses))]
+		e.EventID = winEventProcessCreated
+		e.Image, e.Parent, e.Command = p.image, p.parent, p.cmd
+		e.PID = 1000 + g.r.IntN(60000)
+		e.Outcome = "success"
+		e.Severity = 3
+	case EventDNSQuery:
+		e.EventID = sysmonEventDNSQuery
+		e.Query = fmt.Sprintf("%s-%x.%s", queryLabels[g.r.IntN(len(queryLabels))], g.r.Uint32(), g.ids.Domain)
+		e.Outcome = "success"
+		e.Severity = 2
+	}
+	return e, nil
+}
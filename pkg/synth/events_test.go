@@ -0,0 +1,54 @@
+package synth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventGenerator_Kinds(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	g, err := NewEventGenerator(ids, "task-1")
+	if err != nil {
+		t.Fatalf("NewEventGenerator: %v", err)
+	}
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for kind, id := range map[EventKind]int{EventFailedLogin: 4625, EventProcessCreation: 4688, EventDNSQuery: 22} {
+		e, err := g.Next(kind, at)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if e.EventID != id || !e.Synthetic || e.TaskID != "task-1" || e.Engagement != "eng-2026-q1" {
+			t.Errorf("%s: unexpected event %+v", kind, e)
+		}
+		if _, ok := ids.User(e.User); !ok {
+			t.Errorf("%s: user %q not in identity set", kind, e.User)
+		}
+	}
+	if _, err := g.Next("port_scan", at); err == nil {
+		t.Fatal("expected unsupported kind to fail")
+	}
+}
+
+func TestEventGenerator_Deterministic(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	a, _ := NewEventGenerator(ids, "task-1")
+	b, _ := NewEventGenerator(ids, "task-1")
+	at := time.Now()
+	for i := 0; i < 5; i++ {
+		ea, _ := a.Next(EventDNSQuery, at)
+		eb, _ := b.Next(EventDNSQuery, at)
+		if ea != eb {
+			t.Fatalf("event %d differs: %+v vs %+v", i, ea, eb)
+		}
+	}
+}
+
+func TestParseEventKinds(t *testing.T) {
+	kinds, err := ParseEventKinds("failed_login, dns_query")
+	if err != nil || len(kinds) != 2 || kinds[1] != EventDNSQuery {
+		t.Fatalf("ParseEventKinds = %v, %v", kinds, err)
+	}
+	if _, err := ParseEventKinds("failed_login,bogus"); err == nil {
+		t.Fatal("expected unknown kind to fail")
+	}
+}
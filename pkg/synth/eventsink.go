@@ -0,0 +1,93 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+const maxSinkResponseBytes = 4096
+
+// EventSink receives rendered synthetic records (one record per line) for
+// delivery to the pipeline under test.
+type EventSink interface {
+	Send(ctx context.Context, lines [][]byte) error
+}
+
+// WriterSink writes newline-terminated records to W. It is safe for
+// concurrent use.
+type WriterSink struct {
+	W  io.Writer
+	mu sync.Mutex
+}
+
+// Send implements EventSink.
+func (s *WriterSink) Send(ctx context.Context, lines [][]byte) error {
+	if s.W == nil {
+		return errors.New("writer is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range lines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.W.Write(append(bytes.TrimRight(l, "\n"), '\n')); err != nil {
+			return fmt.Errorf("write record: %w", err)
+		}
+	}
+	return nil
+}
+
+// HTTPSink POSTs each batch as a newline-delimited body to URL.
+type HTTPSink struct {
+	URL         string
+	ContentType string
+	Header      http.Header
+	Client      *http.Client
+}
+
+// Send implements EventSink.
+func (s *HTTPSink) Send(ctx context.Context, lines [][]byte) error {
+	if s.URL == "" {
+		return errors.New("sink URL is required")
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	for _, l := range lines {
+		body.Write(bytes.TrimRight(l, "\n"))
+		body.WriteByte('\n')
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, &body)
+	if err != nil {
+		return err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	ct := s.ContentType
+	if ct == "" {
+		ct = "application/x-ndjson"
+	}
+	req.Header.Set("Content-Type", ct)
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post records: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxSinkResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sink returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	s := &WriterSink{W: &buf}
+	if err := s.Send(context.Background(), [][]byte{[]byte(`{"a":1}`), []byte("b\n")}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if buf.String() != "{\"a\":1}\nb\n" {
+		t.Fatalf("got %q", buf.String())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Send(ctx, [][]byte{[]byte("x")}); err == nil {
+		t.Fatal("expected cancelled context to fail")
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		got, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	s := &HTTPSink{URL: srv.URL, Header: http.Header{"Authorization": {"Bearer test"}}}
+	if err := s.Send(context.Background(), [][]byte{[]byte("one"), []byte("two")}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if string(got) != "one\ntwo\n" {
+		t.Fatalf("body: got %q", got)
+	}
+	s.Header = nil
+	if err := s.Send(context.Background(), [][]byte{[]byte("one")}); err == nil {
+		t.Fatal("expected HTTP 401 to fail")
+	}
+}
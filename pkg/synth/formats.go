@@ -0,0 +1,250 @@
+package synth
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventFormat names a wire format for security events.
+type EventFormat string
+
+const (
+	FormatSyslog EventFormat = "syslog"
+	FormatCEF    EventFormat = "cef"
+	FormatLEEF   EventFormat = "leef"
+	FormatJSONL  EventFormat = "jsonl"
+	FormatEVTX   EventFormat = "evtx-xml"
+)
+
+const (
+	eventVendor         = "RTE-A"
+	eventProduct        = "Synthetic"
+	eventProductVersion = "1.0"
+	syslogAppName       = "rte-a-synth"
+
+	// syslogFacilityAuth is the RFC 5424 security/authorization facility.
+	syslogFacilityAuth = 4
+)
+
+// Formatter renders one event as a single record line.
+type Formatter interface {
+	Format(e SecurityEvent) ([]byte, error)
+}
+
+// FormatterFunc adapts a function to Formatter.
+type FormatterFunc func(SecurityEvent) ([]byte, error)
+
+// Format implements Formatter.
+func (f FormatterFunc) Format(e SecurityEvent) ([]byte, error) { return f(e) }
+
+var formatters = map[EventFormat]Formatter{
+	FormatSyslog: FormatterFunc(formatSyslog),
+	FormatCEF:    FormatterFunc(formatCEF),
+	FormatLEEF:   FormatterFunc(formatLEEF),
+	FormatJSONL:  FormatterFunc(formatJSONL),
+	FormatEVTX:   FormatterFunc(formatEVTX),
+}
+
+// FormatterFor returns the formatter registered for f.
+func FormatterFor(f EventFormat) (Formatter, error) {
+	fm, ok := formatters[f]
+	if !ok {
+		return nil, fmt.Errorf("unsupported event format: %s", f)
+	}
+	return fm, nil
+}
+
+// FormatEvents renders events into sink-ready lines.
+func FormatEvents(f Formatter, events []SecurityEvent) ([][]byte, error) {
+	lines := make([][]byte, 0, len(events))
+	for _, e := range events {
+		b, err := f.Format(e)
+		if err != nil {
+			return nil, fmt.Errorf("format %s event: %w", e.Kind, err)
+		}
+		lines = append(lines, b)
+	}
+	return lines, nil
+}
+
+// eventName is the human-readable title used by the header-based formats.
+func eventName(e SecurityEvent) string {
+	switch e.Kind {
+	case EventFailedLogin:
+		return "An account failed to log on"
+	case EventProcessCreation:
+		return "A new process has been created"
+	case EventDNSQuery:
+		return "DNS query"
+	}
+	return string(e.Kind)
+}
+
+// fields returns the event's key/value extension pairs in a fixed order,
+// using the ArcSight names that CEF and LEEF both understand.
+func fields(e SecurityEvent) [][2]string {
+	kv := [][2]string{
+		{"rt", strconv.FormatInt(e.Time.UnixMilli(), 10)},
+		{"dhost", e.Host},
+		{"dst", e.HostIP},
+		{"duser", e.User},
+		{"dntdom", e.Domain},
+		{"outcome", e.Outcome},
+	}
+	if e.SourceIP != "" {
+		kv = append(kv, [2]string{"src", e.SourceIP})
+	}
+	if e.Image != "" {
+		kv = append(kv, [2]string{"sproc", e.Parent}, [2]string{"dproc", e.Image},
+			[2]string{"dpid", strconv.Itoa(e.PID)}, [2]string{"cmd", e.Command})
+	}
+	if e.Query != "" {
+		kv = append(kv, [2]string{"query", e.Query})
+	}
+	return append(kv,
+		[2]string{"cs1Label", "engagement"}, [2]string{"cs1", e.Engagement},
+		[2]string{"cs2Label", "task"}, [2]string{"cs2", e.TaskID},
+		[2]string{"cs3Label", "rte_a_synthetic"}, [2]string{"cs3", "true"},
+	)
+}
+
+// formatSyslog renders an RFC 5424 message with the event as structured data.
+func formatSyslog(e SecurityEvent) ([]byte, error) {
+	sev := 5 // notice
+	if e.Kind == EventFailedLogin {
+		sev = 4 // warning
+	}
+	var sd strings.Builder
+	sd.WriteString("[rte-a@32473")
+	for _, kv := range [][2]string{
+		{"engagement", e.Engagement}, {"task", e.TaskID}, {"kind", string(e.Kind)},
+		{"user", e.User}, {"src", e.SourceIP}, {"image", e.Image}, {"query", e.Query},
+	} {
+		if kv[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&sd, " %s=\"%s\"", kv[0], syslogParamEscaper.Replace(kv[1]))
+	}
+	sd.WriteString(" synthetic=\"true\"]")
+	line := fmt.Sprintf("<%d>1 %s %s %s - %d %s %s",
+		syslogFacilityAuth*8+sev, e.Time.UTC().Format(time.RFC3339Nano), e.Host, syslogAppName,
+		e.EventID, sd.String(), eventName(e))
+	return []byte(line), nil
+}
+
+var (
+	syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	cefHeaderEscaper   = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefValueEscaper    = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+	leefValueEscaper   = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+)
+
+// formatCEF renders an ArcSight Common Event Format record.
+func formatCEF(e SecurityEvent) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%d|%s|%d|", eventVendor, eventProduct, eventProductVersion,
+		e.EventID, cefHeaderEscaper.Replace(eventName(e)), e.Severity)
+	for i, kv := range fields(e) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(kv[0] + "=" + cefValueEscaper.Replace(kv[1]))
+	}
+	return []byte(b.String()), nil
+}
+
+// formatLEEF renders an IBM QRadar LEEF 1.0 record (tab-delimited attributes).
+func formatLEEF(e SecurityEvent) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "LEEF:1.0|%s|%s|%s|%d|", eventVendor, eventProduct, eventProductVersion, e.EventID)
+	b.WriteString("devTimeFormat=epoch\tdevTime=" + strconv.FormatInt(e.Time.UnixMilli(), 10))
+	b.WriteString("\tsev=" + strconv.Itoa(e.Severity))
+	for _, kv := range fields(e)[1:] {
+		b.WriteString("\t" + kv[0] + "=" + leefValueEscaper.Replace(kv[1]))
+	}
+	return []byte(b.String()), nil
+}
+
+func formatJSONL(e SecurityEvent) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+type evtxEvent struct {
+	XMLName xml.Name   `xml:"Event"`
+	XMLNS   string     `xml:"xmlns,attr"`
+	System  evtxSystem `xml:"System"`
+	Data    []evtxData `xml:"EventData>Data"`
+}
+
+type evtxSystem struct {
+	Provider    evtxProvider `xml:"Provider"`
+	EventID     int          `xml:"EventID"`
+	Level       int          `xml:"Level"`
+	TimeCreated evtxTime     `xml:"TimeCreated"`
+	Channel     string       `xml:"Channel"`
+	Computer    string       `xml:"Computer"`
+}
+
+type evtxProvider struct {
+	Name string `xml:"Name,attr"`
+}
+
+type evtxTime struct {
+	SystemTime string `xml:"SystemTime,attr"`
+}
+
+type evtxData struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// formatEVTX renders the event the way Windows exports an EVTX record as XML.
+func formatEVTX(e SecurityEvent) ([]byte, error) {
+	ev := evtxEvent{
+		XMLNS: "http://schemas.microsoft.com/win/2004/08/events/event",
+		System: evtxSystem{
+			Provider:    evtxProvider{Name: "Microsoft-Windows-Security-Auditing"},
+			EventID:     e.EventID,
+			Level:       0,
+			TimeCreated: evtxTime{SystemTime: e.Time.UTC().Format("2006-01-02T15:04:05.0000000Z")},
+			Channel:     "Security",
+			Computer:    e.Host + "." + e.Domain,
+		},
+	}
+	add := func(name, value string) {
+		if value != "" {
+			ev.Data = append(ev.Data, evtxData{Name: name, Value: value})
+		}
+	}
+	switch e.Kind {
+	case EventFailedLogin:
+		add("TargetUserName", e.User)
+		add("TargetDomainName", e.Domain)
+		add("IpAddress", e.SourceIP)
+		add("LogonType", "3")
+		add("Status", "0xc000006d")
+		add("SubStatus", "0xc000006a")
+	case EventProcessCreation:
+		add("SubjectUserName", e.User)
+		add("SubjectDomainName", e.Domain)
+		add("NewProcessId", fmt.Sprintf("0x%x", e.PID))
+		add("NewProcessName", e.Image)
+		add("ParentProcessName", e.Parent)
+		add("CommandLine", e.Command)
+	case EventDNSQuery:
+		ev.System.Provider.Name = "Microsoft-Windows-Sysmon"
+		ev.System.Channel = "Microsoft-Windows-Sysmon/Operational"
+		ev.System.Level = 4
+		add("User", e.Domain+`\`+e.User)
+		add("QueryName", e.Query)
+		add("QueryStatus", "0")
+	}
+	add("RteaEngagement", e.Engagement)
+	add("RteaTask", e.TaskID)
+	add("RteaSynthetic", "true")
+	return xml.Marshal(ev)
+}
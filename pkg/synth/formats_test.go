@@ -0,0 +1,108 @@
+package synth
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEvent(t *testing.T, kind EventKind) SecurityEvent {
+	t.Helper()
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	g, _ := NewEventGenerator(ids, "task-1")
+	e, err := g.Next(kind, time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	return e
+}
+
+func format(t *testing.T, f EventFormat, e SecurityEvent) string {
+	t.Helper()
+	fm, err := FormatterFor(f)
+	if err != nil {
+		t.Fatalf("FormatterFor(%s): %v", f, err)
+	}
+	b, err := fm.Format(e)
+	if err != nil {
+		t.Fatalf("Format(%s): %v", f, err)
+	}
+	if strings.ContainsAny(string(b), "\n\r") {
+		t.Fatalf("%s record spans lines: %q", f, b)
+	}
+	return string(b)
+}
+
+func TestFormatSyslog(t *testing.T) {
+	e := testEvent(t, EventFailedLogin)
+	got := format(t, FormatSyslog, e)
+	if !strings.HasPrefix(got, "<36>1 2026-03-02T09:00:00Z "+e.Host+" rte-a-synth - 4625 [rte-a@32473 ") {
+		t.Errorf("unexpected syslog header: %s", got)
+	}
+	if !strings.Contains(got, `synthetic="true"]`) || !strings.Contains(got, `task="task-1"`) {
+		t.Errorf("missing structured data: %s", got)
+	}
+}
+
+func TestFormatCEFEscapes(t *testing.T) {
+	e := testEvent(t, EventProcessCreation)
+	e.Command = `cmd /c set a=b|c`
+	got := format(t, FormatCEF, e)
+	if !strings.HasPrefix(got, "CEF:0|RTE-A|Synthetic|1.0|4688|A new process has been created|3|") {
+		t.Errorf("unexpected CEF header: %s", got)
+	}
+	if !strings.Contains(got, `cmd=cmd /c set a\=b|c`) {
+		t.Errorf("extension value not escaped: %s", got)
+	}
+	if !strings.Contains(got, "cs3=true") {
+		t.Errorf("missing synthetic marker: %s", got)
+	}
+}
+
+func TestFormatLEEF(t *testing.T) {
+	e := testEvent(t, EventDNSQuery)
+	got := format(t, FormatLEEF, e)
+	header, attrs, ok := strings.Cut(got, "|22|")
+	if !ok || header != "LEEF:1.0|RTE-A|Synthetic|1.0" {
+		t.Fatalf("unexpected LEEF header: %s", got)
+	}
+	found := map[string]string{}
+	for _, a := range strings.Split(attrs, "\t") {
+		k, v, _ := strings.Cut(a, "=")
+		found[k] = v
+	}
+	if found["query"] != e.Query || found["devTimeFormat"] != "epoch" {
+		t.Errorf("unexpected attributes: %v", found)
+	}
+}
+
+func TestFormatJSONLAndEVTX(t *testing.T) {
+	e := testEvent(t, EventFailedLogin)
+	var decoded SecurityEvent
+	if err := json.Unmarshal([]byte(format(t, FormatJSONL, e)), &decoded); err != nil || decoded != e {
+		t.Fatalf("JSONL round trip = %+v, %v", decoded, err)
+	}
+
+	var ev evtxEvent
+	if err := xml.Unmarshal([]byte(format(t, FormatEVTX, e)), &ev); err != nil {
+		t.Fatalf("EVTX XML: %v", err)
+	}
+	if ev.System.EventID != 4625 || ev.System.Channel != "Security" {
+		t.Errorf("unexpected System: %+v", ev.System)
+	}
+	data := map[string]string{}
+	for _, d := range ev.Data {
+		data[d.Name] = d.Value
+	}
+	if data["TargetUserName"] != e.User || data["IpAddress"] != e.SourceIP || data["RteaSynthetic"] != "true" {
+		t.Errorf("unexpected EventData: %v", data)
+	}
+}
+
+func TestFormatterForUnknown(t *testing.T) {
+	if _, err := FormatterFor("gelf"); err == nil {
+		t.Fatal("expected unknown format to fail")
+	}
+}
@@ -0,0 +1,280 @@
+// Package synth generates clearly fake, deterministic data for RTE-A
+// simulation tasks. Nothing produced here refers to a real person or system.
+package synth
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"strings"
+)
+
+const (
+	defaultUsers   = 25
+	defaultServers = 5
+	maxUsers       = 240
+	maxServers     = 250
+)
+
+var (
+	defaultDepartments = []string{"finance", "engineering", "sales", "hr", "it"}
+
+	givenNames = []string{
+		"ada", "blake", "casey", "devon", "emery", "frankie", "gray", "harper",
+		"indigo", "jules", "kai", "logan", "morgan", "noel", "oakley", "parker",
+		"quinn", "reese", "sage", "taylor", "uma", "vale", "wren", "yael", "zion",
+	}
+	familyNames = []string{
+		"archer", "brooks", "carver", "dalton", "ellis", "fischer", "garner",
+		"hayes", "irwin", "jensen", "keller", "lowell", "mercer", "norris",
+		"orton", "pryor", "quill", "rowan", "sutton", "thorne", "upton",
+		"vance", "walsh", "yates", "zeller",
+	}
+	workstationOS = []string{"windows-11", "windows-10", "macos-14", "ubuntu-22.04"}
+	serverOS      = []string{"windows-server-2022", "ubuntu-22.04", "rhel-9"}
+)
+
+// IdentityConfig controls the size and shape of a synthetic identity set.
+// Zero values select the package defaults.
+type IdentityConfig struct {
+	Users       int
+	Servers     int
+	Departments []string
+}
+
+// User is a synthetic account belonging to one department.
+type User struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+	Department  string `json:"department"`
+	Workstation string `json:"workstation"`
+}
+
+// Host is a synthetic workstation or server with a stable address.
+type Host struct {
+	Hostname   string     `json:"hostname"`
+	IP         netip.Addr `json:"ip"`
+	Department string     `json:"department"`
+	OS         string     `json:"os"`
+	Server     bool       `json:"server"`
+}
+
+// Subnet maps a department to its synthetic address range.
+type Subnet struct {
+	Department string       `json:"department"`
+	Prefix     netip.Prefix `json:"prefix"`
+}
+
+// IdentitySet is the shared cast of users, hosts, and subnets for one
+// engagement. The same engagement ID always yields the same set, so every
+// synthetic scenario in the engagement tells a consistent story.
+type IdentitySet struct {
+	Engagement string   `json:"engagement"`
+	Domain     string   `json:"domain"`
+	Subnets    []Subnet `json:"subnets"`
+	Users      []User   `json:"users"`
+	Hosts      []Host   `json:"hosts"`
+
+	usersByName map[string]int
+	hostsByName map[string]int
+}
+
+// NewIdentitySet deterministically derives an identity set from the
+// engagement ID and config.
+func NewIdentitySet(engagement string, cfg IdentityConfig) (*IdentitySet, error) {
+	if engagement == "" {
+		return nil, errors.New("engagement is required")
+	}
+	if cfg.Users == 0 {
+		cfg.Users = defaultUsers
+	}
+	if cfg.Servers == 0 {
+		cfg.Servers = defaultServers
+	}
+	if cfg.Users < 1 || cfg.Users > maxUsers {
+		return nil, fmt.Errorf("users must be between 1 and %d, got %d", maxUsers, cfg.Users)
+	}
+	if cfg.Servers < 0 || cfg.Servers > maxServers {
+		return nil, fmt.Errorf("servers must be between 0 and %d, got %d", maxServers, cfg.Servers)
+	}
+	depts := cfg.Departments
+	if len(depts) == 0 {
+		depts = defaultDepartments
+	}
+	if len(depts) > 200 {
+		return nil, fmt.Errorf("at most 200 departments supported, got %d", len(depts))
+	}
+
+	r := seededRand(engagement)
+	set := &IdentitySet{
+		Engagement: engagement,
+		Domain:     domainFor(engagement),
+	}
+
+	// Each department gets its own /24 inside a per-engagement 10.X.0.0/16.
+	second := byte(r.IntN(254) + 1)
+	for i, d := range depts {
+		if d == "" {
+			return nil, fmt.Errorf("department %d is empty", i)
+		}
+		addr := netip.AddrFrom4([4]byte{10, second, byte(i + 10), 0})
+		set.Subnets = append(set.Subnets, Subnet{Department: d, Prefix: netip.PrefixFrom(addr, 24)})
+	}
+	serverAddr := netip.AddrFrom4([4]byte{10, second, 250, 0})
+	set.Subnets = append(set.Subnets, Subnet{Department: "servers", Prefix: netip.PrefixFrom(serverAddr, 24)})
+
+	set.usersByName = make(map[string]int, cfg.Users)
+	set.hostsByName = make(map[string]int, cfg.Users+cfg.Servers)
+	nextHost := make([]int, len(set.Subnets))
+	for i := 0; i < cfg.Users; i++ {
+		given := givenNames[r.IntN(len(givenNames))]
+		family := familyNames[r.IntN(len(familyNames))]
+		username := uniqueName(given[:1]+family, set.usersByName)
+		deptIdx := r.IntN(len(depts))
+		host := set.addHost(fmt.Sprintf("ws-%s-%03d", depts[deptIdx], i+1), deptIdx, nextHost,
+			workstationOS[r.IntN(len(workstationOS))], false)
+		set.usersByName[username] = len(set.Users)
+		set.Users = append(set.Users, User{
+			Username:    username,
+			DisplayName: titleCase(given) + " " + titleCase(family),
+			Email:       username + "@" + set.Domain,
+			Department:  depts[deptIdx],
+			Workstation: host.Hostname,
+		})
+	}
+	for i := 0; i < cfg.Servers; i++ {
+		set.addHost(fmt.Sprintf("srv-%03d", i+1), len(set.Subnets)-1, nextHost,
+			serverOS[r.IntN(len(serverOS))], true)
+	}
+	return set, nil
+}
+
+func (s *IdentitySet) addHost(name string, subnetIdx int, next []int, osName string, server bool) Host {
+	next[subnetIdx]++
+	base := s.Subnets[subnetIdx].Prefix.Addr().As4()
+	base[3] = byte(next[subnetIdx] + 9)
+	h := Host{
+		Hostname:   name,
+		IP:         netip.AddrFrom4(base),
+		Department: s.Subnets[subnetIdx].Department,
+		OS:         osName,
+		Server:     server,
+	}
+	s.hostsByName[name] = len(s.Hosts)
+	s.Hosts = append(s.Hosts, h)
+	return h
+}
+
+// User returns the user with the given username.
+func (s *IdentitySet) User(username string) (User, bool) {
+	s.index()
+	i, ok := s.usersByName[username]
+	if !ok {
+		return User{}, false
+	}
+	return s.Users[i], true
+}
+
+// Host returns the host with the given hostname.
+func (s *IdentitySet) Host(hostname string) (Host, bool) {
+	s.index()
+	i, ok := s.hostsByName[hostname]
+	if !ok {
+		return Host{}, false
+	}
+	return s.Hosts[i], true
+}
+
+// UserAt returns the n-th user, wrapping around the set. Scenarios use it to
+// pick the same actor for the same step across runs.
+func (s *IdentitySet) UserAt(n int) User {
+	if n < 0 {
+		n = -n
+	}
+	return s.Users[n%len(s.Users)]
+}
+
+// Servers returns the server hosts in the set.
+func (s *IdentitySet) Servers() []Host {
+	var out []Host
+	for _, h := range s.Hosts {
+		if h.Server {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// index rebuilds lookup maps for sets decoded from JSON.
+func (s *IdentitySet) index() {
+	if s.usersByName != nil && s.hostsByName != nil {
+		return
+	}
+	s.usersByName = make(map[string]int, len(s.Users))
+	for i, u := range s.Users {
+		s.usersByName[u.Username] = i
+	}
+	s.hostsByName = make(map[string]int, len(s.Hosts))
+	for i, h := range s.Hosts {
+		s.hostsByName[h.Hostname] = i
+	}
+}
+
+// seededRand returns a PRNG seeded from the engagement ID. It is not used for
+// anything security-relevant; it only keeps generated data reproducible.
+func seededRand(engagement string) *rand.Rand {
+	sum := sha256.Sum256([]byte("rte-a/synth/" + engagement))
+	return rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+}
+
+// fakeUUID returns a random-looking version 4 UUID drawn from r, so record
+// IDs stay reproducible along with the rest of the synthetic data.
+func fakeUUID(r *rand.Rand) string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(r.IntN(256))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// domainFor builds a reserved (RFC 2606) domain from the engagement ID.
+func domainFor(engagement string) string {
+	return slug(engagement) + ".example"
+}
+
+// slug lowercases s and replaces everything but letters, digits, and
+// hyphens with hyphens.
+func slug(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func uniqueName(base string, taken map[string]int) string {
+	name := base
+	for n := 2; ; n++ {
+		if _, ok := taken[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+}
+
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,101 @@
+package synth
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNewIdentitySet_Deterministic(t *testing.T) {
+	a, err := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	if err != nil {
+		t.Fatalf("NewIdentitySet: %v", err)
+	}
+	b, err := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	if err != nil {
+		t.Fatalf("NewIdentitySet: %v", err)
+	}
+	if !reflect.DeepEqual(a.Users, b.Users) || !reflect.DeepEqual(a.Hosts, b.Hosts) {
+		t.Fatal("expected identical sets for the same engagement")
+	}
+	c, err := NewIdentitySet("eng-2026-q2", IdentityConfig{})
+	if err != nil {
+		t.Fatalf("NewIdentitySet: %v", err)
+	}
+	if reflect.DeepEqual(a.Users, c.Users) {
+		t.Fatal("expected different sets for different engagements")
+	}
+}
+
+func TestNewIdentitySet_Coherent(t *testing.T) {
+	set, err := NewIdentitySet("eng-2026-q1", IdentityConfig{Users: 40, Servers: 3})
+	if err != nil {
+		t.Fatalf("NewIdentitySet: %v", err)
+	}
+	if len(set.Users) != 40 {
+		t.Errorf("users: got %d, want 40", len(set.Users))
+	}
+	if got := len(set.Servers()); got != 3 {
+		t.Errorf("servers: got %d, want 3", got)
+	}
+	if set.Domain != "eng-2026-q1.example" {
+		t.Errorf("domain: got %q", set.Domain)
+	}
+	seen := map[string]bool{}
+	for _, u := range set.Users {
+		if seen[u.Username] {
+			t.Fatalf("duplicate username %q", u.Username)
+		}
+		seen[u.Username] = true
+		ws, ok := set.Host(u.Workstation)
+		if !ok {
+			t.Fatalf("workstation %q for %s not in set", u.Workstation, u.Username)
+		}
+		if ws.Department != u.Department {
+			t.Errorf("workstation %s department %q, user department %q", ws.Hostname, ws.Department, u.Department)
+		}
+	}
+	for _, h := range set.Hosts {
+		var inSubnet bool
+		for _, sn := range set.Subnets {
+			if sn.Department == h.Department && sn.Prefix.Contains(h.IP) {
+				inSubnet = true
+			}
+		}
+		if !inSubnet {
+			t.Errorf("host %s (%s) outside its department subnet", h.Hostname, h.IP)
+		}
+	}
+}
+
+func TestNewIdentitySet_Invalid(t *testing.T) {
+	if _, err := NewIdentitySet("", IdentityConfig{}); err == nil {
+		t.Fatal("expected empty engagement to fail")
+	}
+	if _, err := NewIdentitySet("eng", IdentityConfig{Users: maxUsers + 1}); err == nil {
+		t.Fatal("expected too many users to fail")
+	}
+	if _, err := NewIdentitySet("eng", IdentityConfig{Departments: []string{"ok", ""}}); err == nil {
+		t.Fatal("expected empty department to fail")
+	}
+}
+
+func TestIdentitySet_LookupAfterJSONRoundtrip(t *testing.T) {
+	set, err := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	if err != nil {
+		t.Fatalf("NewIdentitySet: %v", err)
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded IdentitySet
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := set.UserAt(3)
+	got, ok := decoded.User(want.Username)
+	if !ok || got != want {
+		t.Fatalf("lookup after roundtrip: got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,238 @@
+package synth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// IdPProvider selects the identity provider log schema to generate.
+type IdPProvider string
+
+const (
+	ProviderOkta  IdPProvider = "okta"
+	ProviderEntra IdPProvider = "entra"
+)
+
+// IdPScenario is the identity attack pattern to simulate.
+type IdPScenario string
+
+const (
+	// ScenarioMFAFatigue is a burst of push challenges the user finally accepts.
+	ScenarioMFAFatigue IdPScenario = "mfa_fatigue"
+	// ScenarioImpossibleTravel is two successful sign-ins from distant
+	// locations closer together in time than travel allows.
+	ScenarioImpossibleTravel IdPScenario = "impossible_travel"
+	// ScenarioTokenReplay reuses an established session from a new client.
+	ScenarioTokenReplay IdPScenario = "token_replay"
+)
+
+const (
+	defaultMFAPushes = 12
+	maxMFAPushes     = 200
+)
+
+// IdPConfig parameterizes identity provider log generation.
+type IdPConfig struct {
+	Provider IdPProvider
+	Scenario IdPScenario
+	Start    time.Time
+	// Actor selects the identity-set user targeted by the activity.
+	Actor int
+	// Pushes is the number of MFA challenges in mfa_fatigue. Defaults to 12.
+	Pushes int
+}
+
+// IdPEvent is one synthetic sign-in or system log record.
+type IdPEvent struct {
+	Provider IdPProvider
+	Scenario IdPScenario
+	Time     time.Time
+	Record   map[string]any
+}
+
+// MarshalJSON renders the provider-native record.
+func (e IdPEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Record)
+}
+
+// geoSource is a sign-in origin. Addresses are RFC 5737 documentation ranges.
+type geoSource struct {
+	IP      string
+	City    string
+	Country string
+	Lat     float64
+	Lon     float64
+	UA      string
+}
+
+var (
+	corpEgress  = geoSource{IP: "192.0.2.10", City: "Chicago", Country: "US", Lat: 41.88, Lon: -87.63, UA: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/122.0"}
+	remoteSites = []geoSource{
+		{IP: "198.51.100.77", City: "Sydney", Country: "AU", Lat: -33.87, Lon: 151.21, UA: "Mozilla/5.0 (X11; Linux x86_64) Firefox/123.0"},
+		{IP: "203.0.113.41", City: "Sao Paulo", Country: "BR", Lat: -23.55, Lon: -46.63, UA: "python-requests/2.31"},
+		{IP: "198.51.100.150", City: "Helsinki", Country: "FI", Lat: 60.17, Lon: 24.94, UA: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_3) Safari/17.3"},
+	}
+)
+
+type idpContext struct {
+	r       *rand.Rand
+	cfg     IdPConfig
+	actor   User
+	userID  string
+	session string
+	remote  geoSource
+}
+
+// IdPEvents generates identity provider log events for one scenario, using
+// the actor from ids so the activity lines up with other synthetic telemetry.
+func IdPEvents(ids *IdentitySet, cfg IdPConfig) ([]IdPEvent, error) {
+	if ids == nil {
+		return nil, errors.New("identity set is required")
+	}
+	if cfg.Start.IsZero() {
+		return nil, errors.New("start time is required")
+	}
+	if cfg.Pushes == 0 {
+		cfg.Pushes = defaultMFAPushes
+	}
+	if cfg.Pushes < 2 || cfg.Pushes > maxMFAPushes {
+		return nil, fmt.Errorf("pushes must be between 2 and %d, got %d", maxMFAPushes, cfg.Pushes)
+	}
+	switch cfg.Scenario {
+	case ScenarioMFAFatigue, ScenarioImpossibleTravel, ScenarioTokenReplay:
+	default:
+		return nil, fmt.Errorf("unsupported identity scenario: %s", cfg.Scenario)
+	}
+	r := seededRand(fmt.Sprintf("%s/idp/%s/%s/%d", ids.Engagement, cfg.Provider, cfg.Scenario, cfg.Start.Unix()))
+	c := &idpContext{r: r, cfg: cfg, actor: ids.UserAt(cfg.Actor)}
+	c.userID = fmt.Sprintf("00u%016x", seededRand(ids.Engagement+"/idp-user/"+c.actor.Username).Uint64())
+	c.session = fmt.Sprintf("102%016x", r.Uint64())
+	c.remote = remoteSites[r.IntN(len(remoteSites))]
+
+	type step struct {
+		at      time.Time
+		kind    string // push, deny, success, session, replay
+		src     geoSource
+		attempt int
+	}
+	var steps []step
+	t := cfg.Start.UTC()
+	switch cfg.Scenario {
+	case ScenarioMFAFatigue:
+		// The attacker already holds the password and signs in remotely.
+		for i := 0; i < cfg.Pushes; i++ {
+			steps = append(steps, step{at: t, kind: "push", src: c.remote, attempt: i + 1})
+			t = t.Add(time.Duration(2+r.IntN(3)) * time.Second)
+			if i < cfg.Pushes-1 {
+				steps = append(steps, step{at: t, kind: "deny", src: c.remote, attempt: i + 1})
+			} else {
+				steps = append(steps, step{at: t, kind: "success", src: c.remote, attempt: i + 1})
+			}
+			t = t.Add(time.Duration(15+r.IntN(45)) * time.Second)
+		}
+	case ScenarioImpossibleTravel:
+		steps = append(steps, step{at: t, kind: "success", src: corpEgress})
+		steps = append(steps, step{at: t.Add(time.Duration(10+r.IntN(30)) * time.Minute), kind: "success", src: c.remote})
+	case ScenarioTokenReplay:
+		steps = append(steps, step{at: t, kind: "session", src: corpEgress})
+		steps = append(steps, step{at: t.Add(time.Duration(30+r.IntN(90)) * time.Minute), kind: "replay", src: c.remote})
+	}
+
+	out := make([]IdPEvent, 0, len(steps))
+	for _, s := range steps {
+		var rec map[string]any
+		switch cfg.Provider {
+		case ProviderOkta:
+			rec = c.okta(s.at, s.kind, s.src, s.attempt)
+		case ProviderEntra:
+			rec = c.entra(s.at, s.kind, s.src, s.attempt)
+		default:
+			return nil, fmt.Errorf("unsupported identity provider: %s", cfg.Provider)
+		}
+		out = append(out, IdPEvent{Provider: cfg.Provider, Scenario: cfg.Scenario, Time: s.at, Record: rec})
+	}
+	return out, nil
+}
+
+// DeliverIdPEvents renders events as JSON lines and sends them to sink.
+func DeliverIdPEvents(ctx context.Context, sink EventSink, events []IdPEvent) error {
+	return deliverJSON(ctx, sink, events)
+}
+
+func (c *idpContext) okta(at time.Time, kind string, src geoSource, attempt int) map[string]any {
+	eventType, message, result, reason := "", "", "SUCCESS", ""
+	switch kind {
+	case "push":
+		eventType, message = "system.push.send_factor_verify_push", "Push notification sent for verification"
+	case "deny":
+		eventType, message, result, reason = "user.mfa.okta_verify.deny_push", "User rejected Okta push verify", "DENY", "User rejected push"
+	case "success":
+		eventType, message = "user.authentication.auth_via_mfa", "Authentication of user via MFA"
+	case "session":
+		eventType, message = "user.session.start", "User login to Okta"
+	case "replay":
+		eventType, message = "user.session.access_admin_app", "User accessing Okta admin app"
+	}
+	debug := map[string]any{"requestUri": "/api/v1/authn/factors/push/verify"}
+	if attempt > 0 {
+		debug["pushAttempt"] = attempt
+	}
+	return map[string]any{
+		"uuid": fakeUUID(c.r), "published": at.Format("2006-01-02T15:04:05.000Z"), "version": "0",
+		"eventType": eventType, "severity": "INFO", "displayMessage": message, "legacyEventType": nil,
+		"actor": map[string]any{"id": c.userID, "type": "User", "alternateId": c.actor.Email, "displayName": c.actor.DisplayName},
+		"client": map[string]any{
+			"ipAddress": src.IP, "userAgent": map[string]any{"rawUserAgent": src.UA},
+			"geographicalContext": map[string]any{
+				"city": src.City, "country": src.Country,
+				"geolocation": map[string]any{"lat": src.Lat, "lon": src.Lon},
+			},
+		},
+		"outcome":               map[string]any{"result": result, "reason": reason},
+		"authenticationContext": map[string]any{"externalSessionId": c.session},
+		"debugContext":          map[string]any{"debugData": debug},
+	}
+}
+
+func (c *idpContext) entra(at time.Time, kind string, src geoSource, attempt int) map[string]any {
+	errorCode, failure := 0, ""
+	requirement := "multiFactorAuthentication"
+	var risks []string
+	tokenType := "none"
+	switch kind {
+	case "push":
+		errorCode, failure = 50074, "Strong Authentication is required."
+	case "deny":
+		errorCode, failure = 500121, "Authentication failed during strong authentication request."
+	case "replay":
+		requirement, tokenType = "singleFactorAuthentication", "primaryRefreshToken"
+		risks = []string{"anomalousToken"}
+	}
+	if c.cfg.Scenario == ScenarioImpossibleTravel && src != corpEgress {
+		risks = []string{"impossibleTravel"}
+	}
+	rec := map[string]any{
+		"id": fakeUUID(c.r), "createdDateTime": at.Format(time.RFC3339), "userPrincipalName": c.actor.Email,
+		"userDisplayName": c.actor.DisplayName, "userId": fakeUUID(c.r), "appDisplayName": "Office 365 Exchange Online",
+		"ipAddress": src.IP, "clientAppUsed": "Browser", "correlationId": fakeUUID(c.r),
+		"authenticationRequirement": requirement, "incomingTokenType": tokenType,
+		"status":                  map[string]any{"errorCode": errorCode, "failureReason": failure},
+		"location":                map[string]any{"city": src.City, "countryOrRegion": src.Country, "geoCoordinates": map[string]any{"latitude": src.Lat, "longitude": src.Lon}},
+		"deviceDetail":            map[string]any{"browser": src.UA},
+		"riskEventTypes":          risks,
+		"sessionId":               c.session,
+		"mfaDetail":               map[string]any{"authMethod": "Mobile app notification"},
+		"conditionalAccessStatus": "success",
+	}
+	if attempt > 0 {
+		rec["authenticationDetails"] = []map[string]any{{
+			"authenticationMethod": "Mobile app notification", "succeeded": kind == "success",
+			"authenticationStepResultDetail": failure, "authenticationStepRequirement": "Primary authentication",
+		}}
+	}
+	return rec
+}
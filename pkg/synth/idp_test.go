@@ -0,0 +1,94 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdPEvents_MFAFatigue(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	start := time.Date(2026, 3, 2, 22, 0, 0, 0, time.UTC)
+	for _, p := range []IdPProvider{ProviderOkta, ProviderEntra} {
+		events, err := IdPEvents(ids, IdPConfig{Provider: p, Scenario: ScenarioMFAFatigue, Start: start, Pushes: 5})
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		if len(events) != 10 {
+			t.Fatalf("%s: got %d events, want 10", p, len(events))
+		}
+		for i := 1; i < len(events); i++ {
+			if events[i].Time.Before(events[i-1].Time) {
+				t.Fatalf("%s: events out of order", p)
+			}
+		}
+		last, _ := json.Marshal(events[len(events)-1])
+		first, _ := json.Marshal(events[1])
+		if p == ProviderOkta {
+			if !strings.Contains(string(first), "deny_push") || !strings.Contains(string(last), "auth_via_mfa") {
+				t.Errorf("okta: unexpected sequence %s ... %s", first, last)
+			}
+		} else if !strings.Contains(string(first), "500121") {
+			t.Errorf("entra: expected denied strong auth, got %s", first)
+		}
+	}
+}
+
+func TestIdPEvents_ImpossibleTravel(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	events, err := IdPEvents(ids, IdPConfig{Provider: ProviderEntra, Scenario: ScenarioImpossibleTravel, Start: time.Now(), Actor: 4})
+	if err != nil {
+		t.Fatalf("IdPEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if gap := events[1].Time.Sub(events[0].Time); gap > time.Hour {
+		t.Errorf("sign-ins %s apart; too slow to be impossible travel", gap)
+	}
+	a := events[0].Record["location"].(map[string]any)["countryOrRegion"]
+	b := events[1].Record["location"].(map[string]any)["countryOrRegion"]
+	if a == b {
+		t.Errorf("expected distant countries, both %v", a)
+	}
+	if events[0].Record["userPrincipalName"] != ids.UserAt(4).Email {
+		t.Errorf("expected actor from identity set")
+	}
+}
+
+func TestIdPEvents_TokenReplaySharesSession(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	events, err := IdPEvents(ids, IdPConfig{Provider: ProviderOkta, Scenario: ScenarioTokenReplay, Start: time.Now()})
+	if err != nil {
+		t.Fatalf("IdPEvents: %v", err)
+	}
+	sess := func(e IdPEvent) any { return e.Record["authenticationContext"].(map[string]any)["externalSessionId"] }
+	ip := func(e IdPEvent) any { return e.Record["client"].(map[string]any)["ipAddress"] }
+	if sess(events[0]) != sess(events[1]) || ip(events[0]) == ip(events[1]) {
+		t.Fatalf("expected same session from different IPs")
+	}
+}
+
+func TestIdPEvents_InvalidAndDeliver(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	if _, err := IdPEvents(ids, IdPConfig{Provider: "ping", Scenario: ScenarioMFAFatigue, Start: time.Now()}); err == nil {
+		t.Fatal("expected unsupported provider to fail")
+	}
+	if _, err := IdPEvents(ids, IdPConfig{Provider: ProviderOkta, Scenario: "phish", Start: time.Now()}); err == nil {
+		t.Fatal("expected unsupported scenario to fail")
+	}
+	if _, err := IdPEvents(ids, IdPConfig{Provider: ProviderOkta, Scenario: ScenarioMFAFatigue, Start: time.Now(), Pushes: 1}); err == nil {
+		t.Fatal("expected one push to fail")
+	}
+	events, _ := IdPEvents(ids, IdPConfig{Provider: ProviderOkta, Scenario: ScenarioImpossibleTravel, Start: time.Now()})
+	var buf bytes.Buffer
+	if err := DeliverIdPEvents(context.Background(), &WriterSink{W: &buf}, events); err != nil {
+		t.Fatalf("DeliverIdPEvents: %v", err)
+	}
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
@@ -0,0 +1,247 @@
+package synth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+)
+
+// FlowFormat selects the wire format used by FlowExporter.
+type FlowFormat int
+
+const (
+	NetFlowV9 FlowFormat = 9
+	IPFIX     FlowFormat = 10
+)
+
+const (
+	flowTemplateID        = 256
+	netflowTemplateSetID  = 0
+	ipfixTemplateSetID    = 2
+	defaultFlowsPerPacket = 20
+	maxFlowsPerPacket     = 30
+	netflowHeaderLen      = 20
+	ipfixHeaderLen        = 16
+)
+
+// flowField is an information element (same IDs in NetFlow v9 and IPFIX).
+type flowField struct {
+	id     uint16
+	length uint16
+}
+
+var (
+	commonFlowFields = []flowField{
+		{8, 4},  // sourceIPv4Address
+		{12, 4}, // destinationIPv4Address
+		{7, 2},  // sourceTransportPort
+		{11, 2}, // destinationTransportPort
+		{4, 1},  // protocolIdentifier
+		{1, 8},  // octetDeltaCount
+		{2, 8},  // packetDeltaCount
+	}
+	netflowTimeFields = []flowField{{22, 4}, {21, 4}}   // FIRST_SWITCHED, LAST_SWITCHED (ms since boot)
+	ipfixTimeFields   = []flowField{{152, 8}, {153, 8}} // flowStart/EndMilliseconds
+)
+
+// BulkTransfer is a large outbound transfer split over several connections,
+// the flow-level signature of data exfiltration.
+type BulkTransfer struct {
+	Client      netip.Addr
+	Destination netip.Addr
+	Port        uint16
+	Bytes       int64
+	Connections int
+	Start       time.Time
+	Duration    time.Duration
+}
+
+// Conns implements ConnSource.
+func (x BulkTransfer) Conns() ([]Conn, error) {
+	if !x.Client.Is4() || !x.Destination.Is4() {
+		return nil, errors.New("client and destination must be IPv4 addresses")
+	}
+	if x.Bytes <= 0 {
+		return nil, errors.New("bytes must be positive")
+	}
+	if x.Duration <= 0 {
+		return nil, errors.New("duration must be positive")
+	}
+	n := x.Connections
+	if n == 0 {
+		n = 1
+	}
+	if n < 0 || int64(n) > x.Bytes {
+		return nil, fmt.Errorf("connections must be between 1 and %d", x.Bytes)
+	}
+	port := x.Port
+	if port == 0 {
+		port = 443
+	}
+	const mss = 1448
+	per := x.Bytes / int64(n)
+	slot := x.Duration / time.Duration(n)
+	out := make([]Conn, 0, n)
+	for i := 0; i < n; i++ {
+		size := per
+		if i == n-1 {
+			size = x.Bytes - per*int64(n-1)
+		}
+		pkts := int(size/mss) + 1
+		out = append(out, Conn{
+			Start:     x.Start.Add(time.Duration(i) * slot),
+			Duration:  slot * 9 / 10,
+			Orig:      netip.AddrPortFrom(x.Client, ephemeralPortLo+uint16(i%16000)),
+			Resp:      netip.AddrPortFrom(x.Destination, port),
+			Proto:     "tcp",
+			Service:   "ssl",
+			OrigBytes: int(size),
+			RespBytes: pkts * 40,
+			OrigPkts:  pkts + 3,
+			RespPkts:  pkts/2 + 2,
+		})
+	}
+	return out, nil
+}
+
+// FlowExporter encodes connection records as NetFlow v9 or IPFIX messages and
+// writes each message with a single Write call, so W may be a UDP socket
+// connected to a collector. Every message carries its template.
+type FlowExporter struct {
+	W        io.Writer
+	Format   FlowFormat
+	SourceID uint32
+	// BootTime anchors NetFlow v9 uptime-relative timestamps. Defaults to one
+	// minute before the earliest exported flow.
+	BootTime time.Time
+	// FlowsPerPacket bounds records per message. Defaults to 20.
+	FlowsPerPacket int
+
+	sequence uint32
+}
+
+// Export sends conns (both directions aggregated per record) at exportTime.
+func (e *FlowExporter) Export(conns []Conn, exportTime time.Time) error {
+	if e.W == nil {
+		return errors.New("writer is required")
+	}
+	if e.Format != NetFlowV9 && e.Format != IPFIX {
+		return fmt.Errorf("unsupported flow format: %d", e.Format)
+	}
+	per := e.FlowsPerPacket
+	if per == 0 {
+		per = defaultFlowsPerPacket
+	}
+	if per < 1 || per > maxFlowsPerPacket {
+		return fmt.Errorf("flows per packet must be between 1 and %d", maxFlowsPerPacket)
+	}
+	for _, c := range conns {
+		if !c.Orig.Addr().Is4() || !c.Resp.Addr().Is4() {
+			return errors.New("flow export supports IPv4 only")
+		}
+		if c.Proto != "tcp" && c.Proto != "udp" {
+			return fmt.Errorf("unsupported protocol: %s", c.Proto)
+		}
+	}
+	sorted := sortedConns(conns)
+	if e.BootTime.IsZero() && len(sorted) > 0 {
+		e.BootTime = sorted[0].Start.Add(-time.Minute)
+	}
+	for i := 0; i < len(sorted); i += per {
+		end := i + per
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+		var msg []byte
+		if e.Format == NetFlowV9 {
+			msg = e.netflowMessage(sorted[i:end], exportTime)
+		} else {
+			msg = e.ipfixMessage(sorted[i:end], exportTime)
+		}
+		if _, err := e.W.Write(msg); err != nil {
+			return fmt.Errorf("write flow message: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *FlowExporter) netflowMessage(conns []Conn, exportTime time.Time) []byte {
+	fields := append(append([]flowField{}, commonFlowFields...), netflowTimeFields...)
+	uptime := func(t time.Time) uint32 { return uint32(t.Sub(e.BootTime).Milliseconds()) }
+	msg := make([]byte, netflowHeaderLen)
+	binary.BigEndian.PutUint16(msg[0:], uint16(NetFlowV9))
+	binary.BigEndian.PutUint16(msg[2:], uint16(1+len(conns)))
+	binary.BigEndian.PutUint32(msg[4:], uptime(exportTime))
+	binary.BigEndian.PutUint32(msg[8:], uint32(exportTime.Unix()))
+	// NetFlow v9 sequence numbers count export packets.
+	binary.BigEndian.PutUint32(msg[12:], e.sequence)
+	binary.BigEndian.PutUint32(msg[16:], e.SourceID)
+	e.sequence++
+	msg = appendTemplateSet(msg, netflowTemplateSetID, fields)
+	msg = appendDataSet(msg, conns, func(b []byte, c Conn) []byte {
+		b = binary.BigEndian.AppendUint32(b, uptime(c.Start))
+		return binary.BigEndian.AppendUint32(b, uptime(c.Start.Add(c.Duration)))
+	})
+	return msg
+}
+
+func (e *FlowExporter) ipfixMessage(conns []Conn, exportTime time.Time) []byte {
+	fields := append(append([]flowField{}, commonFlowFields...), ipfixTimeFields...)
+	msg := make([]byte, ipfixHeaderLen)
+	binary.BigEndian.PutUint16(msg[0:], uint16(IPFIX))
+	binary.BigEndian.PutUint32(msg[4:], uint32(exportTime.Unix()))
+	// IPFIX sequence numbers count data records sent before this message.
+	binary.BigEndian.PutUint32(msg[8:], e.sequence)
+	binary.BigEndian.PutUint32(msg[12:], e.SourceID)
+	e.sequence += uint32(len(conns))
+	msg = appendTemplateSet(msg, ipfixTemplateSetID, fields)
+	msg = appendDataSet(msg, conns, func(b []byte, c Conn) []byte {
+		b = binary.BigEndian.AppendUint64(b, uint64(c.Start.UnixMilli()))
+		return binary.BigEndian.AppendUint64(b, uint64(c.Start.Add(c.Duration).UnixMilli()))
+	})
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)))
+	return msg
+}
+
+func appendTemplateSet(msg []byte, setID uint16, fields []flowField) []byte {
+	start := len(msg)
+	msg = binary.BigEndian.AppendUint16(msg, setID)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, flowTemplateID)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(fields)))
+	for _, f := range fields {
+		msg = binary.BigEndian.AppendUint16(msg, f.id)
+		msg = binary.BigEndian.AppendUint16(msg, f.length)
+	}
+	binary.BigEndian.PutUint16(msg[start+2:], uint16(len(msg)-start))
+	return msg
+}
+
+func appendDataSet(msg []byte, conns []Conn, times func([]byte, Conn) []byte) []byte {
+	start := len(msg)
+	msg = binary.BigEndian.AppendUint16(msg, flowTemplateID)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+	for _, c := range conns {
+		src, dst := c.Orig.Addr().As4(), c.Resp.Addr().As4()
+		msg = append(msg, src[:]...)
+		msg = append(msg, dst[:]...)
+		msg = binary.BigEndian.AppendUint16(msg, c.Orig.Port())
+		msg = binary.BigEndian.AppendUint16(msg, c.Resp.Port())
+		proto := byte(protoTCP)
+		if c.Proto == "udp" {
+			proto = protoUDP
+		}
+		msg = append(msg, proto)
+		msg = binary.BigEndian.AppendUint64(msg, uint64(c.OrigBytes+c.RespBytes))
+		msg = binary.BigEndian.AppendUint64(msg, uint64(c.OrigPkts+c.RespPkts))
+		msg = times(msg, c)
+	}
+	for (len(msg)-start)%4 != 0 {
+		msg = append(msg, 0)
+	}
+	binary.BigEndian.PutUint16(msg[start+2:], uint16(len(msg)-start))
+	return msg
+}
@@ -0,0 +1,124 @@
+package synth
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// messages records each Write as one datagram.
+type messages [][]byte
+
+func (m *messages) Write(p []byte) (int, error) {
+	*m = append(*m, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func flowConns(t *testing.T) []Conn {
+	t.Helper()
+	b, _ := testSessions()
+	x := BulkTransfer{
+		Client: netip.MustParseAddr("10.20.10.15"), Destination: netip.MustParseAddr("10.20.250.99"),
+		Bytes: 50 << 20, Connections: 4, Start: b.Start.Add(time.Hour), Duration: 10 * time.Minute,
+	}
+	conns, err := CollectConns(b, x)
+	if err != nil {
+		t.Fatalf("CollectConns: %v", err)
+	}
+	return conns
+}
+
+func TestBulkTransfer_Conns(t *testing.T) {
+	x := BulkTransfer{
+		Client: netip.MustParseAddr("10.0.0.1"), Destination: netip.MustParseAddr("10.0.0.2"),
+		Bytes: 1000, Connections: 3, Duration: time.Minute,
+	}
+	conns, err := x.Conns()
+	if err != nil {
+		t.Fatalf("Conns: %v", err)
+	}
+	var total int
+	for _, c := range conns {
+		total += c.OrigBytes
+	}
+	if len(conns) != 3 || total != 1000 {
+		t.Fatalf("got %d conns totalling %d bytes", len(conns), total)
+	}
+	if _, err := (BulkTransfer{Client: x.Client, Destination: x.Destination, Bytes: 10, Duration: time.Second, Connections: 20}).Conns(); err == nil {
+		t.Fatal("expected more connections than bytes to fail")
+	}
+}
+
+func TestFlowExporter_NetFlowV9(t *testing.T) {
+	conns := flowConns(t)
+	var out messages
+	e := &FlowExporter{W: &out, Format: NetFlowV9, SourceID: 7, FlowsPerPacket: 4}
+	if err := e.Export(conns, conns[len(conns)-1].Start.Add(time.Hour)); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("messages: got %d, want 2", len(out))
+	}
+	for i, msg := range out {
+		if v := binary.BigEndian.Uint16(msg); v != 9 {
+			t.Fatalf("version: got %d", v)
+		}
+		if seq := binary.BigEndian.Uint32(msg[12:]); seq != uint32(i) {
+			t.Errorf("sequence: got %d, want %d", seq, i)
+		}
+		// Walk flowsets and confirm they tile the message exactly.
+		off := netflowHeaderLen
+		for off < len(msg) {
+			off += int(binary.BigEndian.Uint16(msg[off+2:]))
+		}
+		if off != len(msg) {
+			t.Errorf("flowsets overrun message: %d vs %d", off, len(msg))
+		}
+	}
+	if count := binary.BigEndian.Uint16(out[0][2:]); count != 5 {
+		t.Errorf("record count: got %d, want 5 (template + 4 data)", count)
+	}
+}
+
+func TestFlowExporter_IPFIX(t *testing.T) {
+	conns := flowConns(t)
+	var out messages
+	e := &FlowExporter{W: &out, Format: IPFIX, SourceID: 7}
+	if err := e.Export(conns, time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("messages: got %d, want 1", len(out))
+	}
+	msg := out[0]
+	if binary.BigEndian.Uint16(msg) != 10 || int(binary.BigEndian.Uint16(msg[2:])) != len(msg) {
+		t.Fatal("bad IPFIX header")
+	}
+	tmplLen := int(binary.BigEndian.Uint16(msg[ipfixHeaderLen+2:]))
+	data := msg[ipfixHeaderLen+tmplLen:]
+	if binary.BigEndian.Uint16(data) != flowTemplateID {
+		t.Fatal("expected data set for template 256")
+	}
+	rec := data[4:]
+	if got := netip.AddrFrom4([4]byte(rec[0:4])); got != conns[0].Orig.Addr() {
+		t.Errorf("first record source: got %s", got)
+	}
+	if got := binary.BigEndian.Uint16(rec[10:]); got != conns[0].Resp.Port() {
+		t.Errorf("first record dest port: got %d", got)
+	}
+}
+
+func TestFlowExporter_Invalid(t *testing.T) {
+	var out messages
+	if err := (&FlowExporter{W: &out, Format: FlowFormat(5)}).Export(nil, time.Now()); err == nil {
+		t.Fatal("expected NetFlow v5 to be unsupported")
+	}
+	if err := (&FlowExporter{Format: IPFIX}).Export(nil, time.Now()); err == nil {
+		t.Fatal("expected missing writer to fail")
+	}
+	v6 := Conn{Proto: "tcp", Orig: netip.MustParseAddrPort("[::1]:1"), Resp: netip.MustParseAddrPort("[::1]:2")}
+	if err := (&FlowExporter{W: &out, Format: IPFIX}).Export([]Conn{v6}, time.Now()); err == nil {
+		t.Fatal("expected IPv6 flows to fail")
+	}
+}
@@ -0,0 +1,334 @@
+package synth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Conn is one simulated network conversation, the common input to the Zeek
+// and Suricata renderers. Sessions produce Conns that match their pcap output.
+type Conn struct {
+	Start     time.Time
+	Duration  time.Duration
+	Orig      netip.AddrPort
+	Resp      netip.AddrPort
+	Proto     string // "tcp" or "udp"
+	Service   string // "http", "dns", ...
+	OrigBytes int
+	RespBytes int
+	OrigPkts  int
+	RespPkts  int
+	DNS       *DNSInfo
+	HTTP      *HTTPInfo
+}
+
+// DNSInfo is the application-layer detail of a DNS conversation.
+type DNSInfo struct {
+	TransID uint16
+	Query   string
+	QType   string
+	RCode   string
+}
+
+// HTTPInfo is the application-layer detail of an HTTP conversation.
+type HTTPInfo struct {
+	Method       string
+	Host         string
+	URI          string
+	UserAgent    string
+	Status       int
+	ResponseBody int
+}
+
+// Conns returns the beacon's check-ins as connection records.
+func (b HTTPBeacon) Conns() ([]Conn, error) {
+	checkins, err := b.checkins()
+	if err != nil {
+		return nil, err
+	}
+	dport := b.ServerPort
+	if dport == 0 {
+		dport = 80
+	}
+	out := make([]Conn, 0, len(checkins))
+	for _, c := range checkins {
+		out = append(out, Conn{
+			Start:     c.At,
+			Duration:  tcpExchangeDuration,
+			Orig:      netip.AddrPortFrom(b.Client, c.Port),
+			Resp:      netip.AddrPortFrom(b.Server, dport),
+			Proto:     "tcp",
+			Service:   "http",
+			OrigBytes: len(c.Req),
+			RespBytes: len(c.Resp),
+			OrigPkts:  5,
+			RespPkts:  3,
+			HTTP: &HTTPInfo{
+				Method: "GET", Host: c.Host, URI: c.URI, UserAgent: c.UA,
+				Status: c.Status, ResponseBody: c.Body,
+			},
+		})
+	}
+	return out, nil
+}
+
+// Conns returns the tunnel's queries as connection records.
+func (d DNSTunnel) Conns() ([]Conn, error) {
+	exchanges, err := d.exchanges()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Conn, 0, len(exchanges))
+	for _, x := range exchanges {
+		out = append(out, Conn{
+			Start:     x.At,
+			Duration:  dnsRTT,
+			Orig:      netip.AddrPortFrom(d.Client, x.Port),
+			Resp:      netip.AddrPortFrom(d.Resolver, 53),
+			Proto:     "udp",
+			Service:   "dns",
+			OrigBytes: len(x.Query),
+			RespBytes: len(x.Reply),
+			OrigPkts:  1,
+			RespPkts:  1,
+			DNS:       &DNSInfo{TransID: x.ID, Query: x.Name, QType: "A", RCode: "NXDOMAIN"},
+		})
+	}
+	return out, nil
+}
+
+// UID returns a stable Zeek-style connection identifier derived from the
+// connection's 5-tuple and start time.
+func (c Conn) UID() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", c.Orig, c.Resp, c.Proto, c.Start.UnixNano())))
+	n := new(big.Int).SetBytes(sum[:12])
+	var b strings.Builder
+	b.WriteByte('C')
+	base := big.NewInt(int64(len(base62)))
+	mod := new(big.Int)
+	for i := 0; i < 17; i++ {
+		n.DivMod(n, base, mod)
+		b.WriteByte(base62[mod.Int64()])
+	}
+	return b.String()
+}
+
+func zeekTime(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000)
+}
+
+func zeekString(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.NewReplacer("\t", "\\x09", "\n", "\\x0a").Replace(s)
+}
+
+type zeekLog struct {
+	path   string
+	fields []string
+	types  []string
+	row    func(Conn) ([]string, bool)
+}
+
+var (
+	zeekConnLog = zeekLog{
+		path:   "conn",
+		fields: []string{"ts", "uid", "id.orig_h", "id.orig_p", "id.resp_h", "id.resp_p", "proto", "service", "duration", "orig_bytes", "resp_bytes", "conn_state", "orig_pkts", "resp_pkts"},
+		types:  []string{"time", "string", "addr", "port", "addr", "port", "enum", "string", "interval", "count", "count", "string", "count", "count"},
+		row: func(c Conn) ([]string, bool) {
+			// Simulated conversations always complete normally.
+			const state = "SF"
+			return []string{
+				zeekTime(c.Start), c.UID(),
+				c.Orig.Addr().String(), strconv.Itoa(int(c.Orig.Port())),
+				c.Resp.Addr().String(), strconv.Itoa(int(c.Resp.Port())),
+				c.Proto, zeekString(c.Service), fmt.Sprintf("%.6f", c.Duration.Seconds()),
+				strconv.Itoa(c.OrigBytes), strconv.Itoa(c.RespBytes), state,
+				strconv.Itoa(c.OrigPkts), strconv.Itoa(c.RespPkts),
+			}, true
+		},
+	}
+	zeekDNSLog = zeekLog{
+		path:   "dns",
+		fields: []string{"ts", "uid", "id.orig_h", "id.orig_p", "id.resp_h", "id.resp_p", "proto", "trans_id", "query", "qtype_name", "rcode_name"},
+		types:  []string{"time", "string", "addr", "port", "addr", "port", "enum", "count", "string", "string", "string"},
+		row: func(c Conn) ([]string, bool) {
+			if c.DNS == nil {
+				return nil, false
+			}
+			return []string{
+				zeekTime(c.Start), c.UID(),
+				c.Orig.Addr().String(), strconv.Itoa(int(c.Orig.Port())),
+				c.Resp.Addr().String(), strconv.Itoa(int(c.Resp.Port())),
+				c.Proto, strconv.Itoa(int(c.DNS.TransID)), zeekString(c.DNS.Query),
+				zeekString(c.DNS.QType), zeekString(c.DNS.RCode),
+			}, true
+		},
+	}
+	zeekHTTPLog = zeekLog{
+		path:   "http",
+		fields: []string{"ts", "uid", "id.orig_h", "id.orig_p", "id.resp_h", "id.resp_p", "method", "host", "uri", "user_agent", "status_code", "response_body_len"},
+		types:  []string{"time", "string", "addr", "port", "addr", "port", "string", "string", "string", "string", "count", "count"},
+		row: func(c Conn) ([]string, bool) {
+			if c.HTTP == nil {
+				return nil, false
+			}
+			return []string{
+				zeekTime(c.Start), c.UID(),
+				c.Orig.Addr().String(), strconv.Itoa(int(c.Orig.Port())),
+				c.Resp.Addr().String(), strconv.Itoa(int(c.Resp.Port())),
+				zeekString(c.HTTP.Method), zeekString(c.HTTP.Host), zeekString(c.HTTP.URI),
+				zeekString(c.HTTP.UserAgent), strconv.Itoa(c.HTTP.Status), strconv.Itoa(c.HTTP.ResponseBody),
+			}, true
+		},
+	}
+)
+
+// WriteZeekConn writes conns as a Zeek conn.log in TSV format.
+func WriteZeekConn(w io.Writer, conns []Conn) error { return writeZeek(w, zeekConnLog, conns) }
+
+// WriteZeekDNS writes the DNS conversations in conns as a Zeek dns.log.
+func WriteZeekDNS(w io.Writer, conns []Conn) error { return writeZeek(w, zeekDNSLog, conns) }
+
+// WriteZeekHTTP writes the HTTP conversations in conns as a Zeek http.log.
+func WriteZeekHTTP(w io.Writer, conns []Conn) error { return writeZeek(w, zeekHTTPLog, conns) }
+
+func writeZeek(w io.Writer, log zeekLog, conns []Conn) error {
+	sorted := sortedConns(conns)
+	open := time.Unix(0, 0).UTC()
+	if len(sorted) > 0 {
+		open = sorted[0].Start.UTC()
+	}
+	var b strings.Builder
+	b.WriteString("#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n")
+	fmt.Fprintf(&b, "#path\t%s\n#open\t%s\n", log.path, open.Format("2006-01-02-15-04-05"))
+	fmt.Fprintf(&b, "#fields\t%s\n#types\t%s\n", strings.Join(log.fields, "\t"), strings.Join(log.types, "\t"))
+	for _, c := range sorted {
+		row, ok := log.row(c)
+		if !ok {
+			continue
+		}
+		b.WriteString(strings.Join(row, "\t"))
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type eveEvent struct {
+	Timestamp string         `json:"timestamp"`
+	FlowID    uint64         `json:"flow_id"`
+	EventType string         `json:"event_type"`
+	SrcIP     string         `json:"src_ip"`
+	SrcPort   uint16         `json:"src_port"`
+	DestIP    string         `json:"dest_ip"`
+	DestPort  uint16         `json:"dest_port"`
+	Proto     string         `json:"proto"`
+	AppProto  string         `json:"app_proto,omitempty"`
+	Flow      map[string]any `json:"flow,omitempty"`
+	DNS       map[string]any `json:"dns,omitempty"`
+	HTTP      map[string]any `json:"http,omitempty"`
+}
+
+// WriteSuricataEVE writes conns as Suricata EVE JSON lines: one flow event
+// per connection plus a dns or http event for application-layer detail.
+func WriteSuricataEVE(w io.Writer, conns []Conn) error {
+	enc := json.NewEncoder(w)
+	for _, c := range sortedConns(conns) {
+		if c.Proto != "tcp" && c.Proto != "udp" {
+			return fmt.Errorf("unsupported protocol: %s", c.Proto)
+		}
+		sum := sha256.Sum256([]byte(c.UID()))
+		base := eveEvent{
+			FlowID:   new(big.Int).SetBytes(sum[:7]).Uint64(),
+			SrcIP:    c.Orig.Addr().String(),
+			SrcPort:  c.Orig.Port(),
+			DestIP:   c.Resp.Addr().String(),
+			DestPort: c.Resp.Port(),
+			Proto:    strings.ToUpper(c.Proto),
+			AppProto: c.Service,
+		}
+		if c.DNS != nil {
+			ev := base
+			ev.Timestamp = eveTime(c.Start)
+			ev.EventType = "dns"
+			ev.DNS = map[string]any{"type": "query", "id": c.DNS.TransID, "rrname": c.DNS.Query, "rrtype": c.DNS.QType}
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+			ev.Timestamp = eveTime(c.Start.Add(c.Duration))
+			ev.DNS = map[string]any{"type": "answer", "id": c.DNS.TransID, "rrname": c.DNS.Query, "rrtype": c.DNS.QType, "rcode": c.DNS.RCode}
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+		}
+		if c.HTTP != nil {
+			ev := base
+			ev.Timestamp = eveTime(c.Start)
+			ev.EventType = "http"
+			ev.HTTP = map[string]any{
+				"hostname": c.HTTP.Host, "url": c.HTTP.URI, "http_user_agent": c.HTTP.UserAgent,
+				"http_method": c.HTTP.Method, "protocol": "HTTP/1.1", "status": c.HTTP.Status, "length": c.HTTP.ResponseBody,
+			}
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+		}
+		ev := base
+		ev.Timestamp = eveTime(c.Start.Add(c.Duration))
+		ev.EventType = "flow"
+		ev.Flow = map[string]any{
+			"pkts_toserver": c.OrigPkts, "pkts_toclient": c.RespPkts,
+			"bytes_toserver": c.OrigBytes, "bytes_toclient": c.RespBytes,
+			"start": eveTime(c.Start), "end": eveTime(c.Start.Add(c.Duration)),
+			"state": "closed", "reason": "timeout",
+		}
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func eveTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000-0700")
+}
+
+func sortedConns(conns []Conn) []Conn {
+	out := append([]Conn(nil), conns...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// ConnSource is implemented by sessions that can describe themselves as
+// connection records.
+type ConnSource interface {
+	Conns() ([]Conn, error)
+}
+
+// CollectConns gathers the connection records of several sessions.
+func CollectConns(sessions ...ConnSource) ([]Conn, error) {
+	var out []Conn
+	for i, s := range sessions {
+		if s == nil {
+			return nil, fmt.Errorf("session %d is nil", i)
+		}
+		conns, err := s.Conns()
+		if err != nil {
+			return nil, fmt.Errorf("session %d: %w", i, err)
+		}
+		out = append(out, conns...)
+	}
+	return out, nil
+}
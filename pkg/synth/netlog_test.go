@@ -0,0 +1,112 @@
+package synth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSessions() (HTTPBeacon, DNSTunnel) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	b := HTTPBeacon{
+		Client: netip.MustParseAddr("10.20.10.15"), Server: netip.MustParseAddr("10.20.250.10"),
+		Host: "updates.eng-2026-q1.example", Path: "/check", Start: start, Interval: time.Minute, Count: 2,
+	}
+	d := DNSTunnel{
+		Client: netip.MustParseAddr("10.20.10.15"), Resolver: netip.MustParseAddr("10.20.250.53"),
+		Domain: "t.eng-2026-q1.example", Data: []byte("synthetic"), Start: start.Add(30 * time.Second),
+	}
+	return b, d
+}
+
+func TestWriteZeekLogs(t *testing.T) {
+	b, d := testSessions()
+	conns, err := CollectConns(b, d)
+	if err != nil {
+		t.Fatalf("CollectConns: %v", err)
+	}
+	var connLog, dnsLog, httpLog bytes.Buffer
+	if err := WriteZeekConn(&connLog, conns); err != nil {
+		t.Fatalf("WriteZeekConn: %v", err)
+	}
+	if err := WriteZeekDNS(&dnsLog, conns); err != nil {
+		t.Fatalf("WriteZeekDNS: %v", err)
+	}
+	if err := WriteZeekHTTP(&httpLog, conns); err != nil {
+		t.Fatalf("WriteZeekHTTP: %v", err)
+	}
+	check := func(name string, buf *bytes.Buffer, wantRows int) []string {
+		t.Helper()
+		var fields []string
+		var rows int
+		sc := bufio.NewScanner(buf)
+		for sc.Scan() {
+			line := sc.Text()
+			if strings.HasPrefix(line, "#fields\t") {
+				fields = strings.Split(line, "\t")[1:]
+				continue
+			}
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			rows++
+			if cols := strings.Split(line, "\t"); len(cols) != len(fields) {
+				t.Errorf("%s: row has %d columns, header %d", name, len(cols), len(fields))
+			}
+		}
+		if rows != wantRows {
+			t.Errorf("%s: got %d rows, want %d", name, rows, wantRows)
+		}
+		return fields
+	}
+	check("conn.log", &connLog, 3)
+	check("dns.log", &dnsLog, 1)
+	check("http.log", &httpLog, 2)
+}
+
+func TestConnUID_Stable(t *testing.T) {
+	b, _ := testSessions()
+	a, _ := b.Conns()
+	c, _ := b.Conns()
+	if a[0].UID() != c[0].UID() || a[0].UID() == a[1].UID() {
+		t.Fatal("expected stable, distinct UIDs")
+	}
+	if len(a[0].UID()) != 18 || a[0].UID()[0] != 'C' {
+		t.Errorf("unexpected UID format %q", a[0].UID())
+	}
+}
+
+func TestWriteSuricataEVE(t *testing.T) {
+	b, d := testSessions()
+	conns, err := CollectConns(b, d)
+	if err != nil {
+		t.Fatalf("CollectConns: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSuricataEVE(&buf, conns); err != nil {
+		t.Fatalf("WriteSuricataEVE: %v", err)
+	}
+	counts := map[string]int{}
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var ev map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid EVE line %q: %v", sc.Text(), err)
+		}
+		counts[ev["event_type"].(string)]++
+	}
+	if counts["flow"] != 3 || counts["http"] != 2 || counts["dns"] != 2 {
+		t.Fatalf("unexpected event counts %v", counts)
+	}
+}
+
+func TestWriteSuricataEVE_UnsupportedProto(t *testing.T) {
+	c := Conn{Proto: "icmp", Orig: netip.MustParseAddrPort("10.0.0.1:0"), Resp: netip.MustParseAddrPort("10.0.0.2:0")}
+	if err := WriteSuricataEVE(&bytes.Buffer{}, []Conn{c}); err == nil {
+		t.Fatal("expected icmp to fail")
+	}
+}
@@ -0,0 +1,415 @@
+package synth
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	pcapMagic       = 0xa1b2c3d4
+	pcapSnapLen     = 65535
+	linkTypeEther   = 1
+	etherTypeIPv4   = 0x0800
+	protoTCP        = 6
+	protoUDP        = 17
+	tcpFIN          = 0x01
+	tcpSYN          = 0x02
+	tcpPSH          = 0x08
+	tcpACK          = 0x10
+	maxDNSLabel     = 63
+	defaultTTLHops  = 64
+	defaultChunk    = 30
+	defaultUA       = "Mozilla/5.0 (RTE-A synthetic beacon)"
+	ephemeralPortLo = 49152
+)
+
+const (
+	tcpRTT              = 20 * time.Millisecond
+	tcpExchangeDuration = 3*tcpRTT + time.Millisecond
+	dnsRTT              = 15 * time.Millisecond
+)
+
+var dnsLabelEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Packet is one captured frame with its capture timestamp.
+type Packet struct {
+	Time time.Time
+	Data []byte
+}
+
+// Session produces the frames of one synthetic network conversation.
+type Session interface {
+	Packets() ([]Packet, error)
+}
+
+// WritePCAP writes the sessions' frames, merged in time order, to w as a
+// classic libpcap file with Ethernet link type.
+func WritePCAP(w io.Writer, sessions ...Session) error {
+	var all []Packet
+	for i, s := range sessions {
+		if s == nil {
+			return fmt.Errorf("session %d is nil", i)
+		}
+		pkts, err := s.Packets()
+		if err != nil {
+			return fmt.Errorf("session %d: %w", i, err)
+		}
+		all = append(all, pkts...)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Time.Before(all[j].Time) })
+
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(hdr[4:], 2)
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(hdr[20:], linkTypeEther)
+	if _, err := w.Write(hdr); err != nil {
+		return fmt.Errorf("write pcap header: %w", err)
+	}
+	rec := make([]byte, 16)
+	for _, p := range all {
+		binary.LittleEndian.PutUint32(rec[0:], uint32(p.Time.Unix()))
+		binary.LittleEndian.PutUint32(rec[4:], uint32(p.Time.Nanosecond()/1000))
+		binary.LittleEndian.PutUint32(rec[8:], uint32(len(p.Data)))
+		binary.LittleEndian.PutUint32(rec[12:], uint32(len(p.Data)))
+		if _, err := w.Write(rec); err != nil {
+			return fmt.Errorf("write packet header: %w", err)
+		}
+		if _, err := w.Write(p.Data); err != nil {
+			return fmt.Errorf("write packet: %w", err)
+		}
+	}
+	return nil
+}
+
+// HTTPBeacon is a series of short HTTP check-ins from an implant-like client
+// to a callback server, each on its own TCP connection.
+type HTTPBeacon struct {
+	Client       netip.Addr
+	Server       netip.Addr
+	ServerPort   uint16
+	Host         string
+	Path         string
+	UserAgent    string
+	Start        time.Time
+	Interval     time.Duration
+	Count        int
+	ResponseSize int
+}
+
+// beaconCheckin is one request/response exchange of an HTTPBeacon.
+type beaconCheckin struct {
+	At     time.Time
+	Port   uint16
+	URI    string
+	Host   string
+	UA     string
+	Status int
+	Req    []byte
+	Resp   []byte
+	Body   int
+}
+
+func (b HTTPBeacon) checkins() ([]beaconCheckin, error) {
+	if !b.Client.Is4() || !b.Server.Is4() {
+		return nil, errors.New("client and server must be IPv4 addresses")
+	}
+	if b.Count < 1 {
+		return nil, errors.New("count must be at least 1")
+	}
+	if b.Count > 1 && b.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	port := b.ServerPort
+	if port == 0 {
+		port = 80
+	}
+	host := b.Host
+	if host == "" {
+		host = b.Server.String()
+	}
+	path := b.Path
+	if path == "" {
+		path = "/"
+	}
+	ua := b.UserAgent
+	if ua == "" {
+		ua = defaultUA
+	}
+	body := strings.Repeat("0", b.ResponseSize)
+	req := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\nAccept: */*\r\n\r\n", path, host, ua)
+	resp := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	out := make([]beaconCheckin, b.Count)
+	for i := range out {
+		out[i] = beaconCheckin{
+			At: b.Start.Add(time.Duration(i) * b.Interval), Port: ephemeralPortLo + uint16(i%16000),
+			URI: path, Host: host, UA: ua, Status: 200,
+			Req: []byte(req), Resp: []byte(resp), Body: len(body),
+		}
+	}
+	return out, nil
+}
+
+// Packets implements Session.
+func (b HTTPBeacon) Packets() ([]Packet, error) {
+	checkins, err := b.checkins()
+	if err != nil {
+		return nil, err
+	}
+	dport := b.ServerPort
+	if dport == 0 {
+		dport = 80
+	}
+	var out []Packet
+	for i, c := range checkins {
+		conn := tcpConn{
+			src: b.Client, dst: b.Server,
+			sport: c.Port, dport: dport,
+			seqC: 1000 + uint32(i)*7919, seqS: 5000 + uint32(i)*104729,
+		}
+		out = append(out, conn.exchange(c.At, c.Req, c.Resp)...)
+	}
+	return out, nil
+}
+
+// DNSTunnel encodes Data into base32 subdomain labels under Domain, one query
+// per chunk, mimicking DNS-based exfiltration or command channels.
+type DNSTunnel struct {
+	Client    netip.Addr
+	Resolver  netip.Addr
+	Domain    string
+	Data      []byte
+	ChunkSize int
+	Start     time.Time
+	Interval  time.Duration
+}
+
+// dnsExchange is one query/response pair of a DNSTunnel.
+type dnsExchange struct {
+	At    time.Time
+	ID    uint16
+	Port  uint16
+	Name  string
+	Query []byte
+	Reply []byte
+}
+
+func (d DNSTunnel) exchanges() ([]dnsExchange, error) {
+	if !d.Client.Is4() || !d.Resolver.Is4() {
+		return nil, errors.New("client and resolver must be IPv4 addresses")
+	}
+	if d.Domain == "" {
+		return nil, errors.New("domain is required")
+	}
+	if len(d.Data) == 0 {
+		return nil, errors.New("data is required")
+	}
+	chunk := d.ChunkSize
+	if chunk == 0 {
+		chunk = defaultChunk
+	}
+	// base32 expands 5 bytes to 8 characters; keep each label within limits.
+	if chunk < 1 || dnsLabelEncoding.EncodedLen(chunk) > maxDNSLabel {
+		return nil, fmt.Errorf("chunk size must be between 1 and %d", maxDNSLabel*5/8)
+	}
+	interval := d.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	var out []dnsExchange
+	for i, n := 0, 0; n < len(d.Data); i, n = i+1, n+chunk {
+		end := n + chunk
+		if end > len(d.Data) {
+			end = len(d.Data)
+		}
+		label := strings.ToLower(dnsLabelEncoding.EncodeToString(d.Data[n:end]))
+		name := fmt.Sprintf("%s.%d.%s", label, i, strings.TrimSuffix(d.Domain, "."))
+		id := uint16(0x1000 + i)
+		q, err := dnsMessage(id, name, false)
+		if err != nil {
+			return nil, err
+		}
+		r, err := dnsMessage(id, name, true)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, dnsExchange{
+			At: d.Start.Add(time.Duration(i) * interval), ID: id,
+			Port: ephemeralPortLo + uint16(i%16000), Name: name, Query: q, Reply: r,
+		})
+	}
+	return out, nil
+}
+
+// Packets implements Session.
+func (d DNSTunnel) Packets() ([]Packet, error) {
+	exchanges, err := d.exchanges()
+	if err != nil {
+		return nil, err
+	}
+	var out []Packet
+	for _, x := range exchanges {
+		out = append(out,
+			Packet{Time: x.At, Data: frame(d.Client, d.Resolver, protoUDP, udpSegment(d.Client, d.Resolver, x.Port, 53, x.Query))},
+			Packet{Time: x.At.Add(dnsRTT), Data: frame(d.Resolver, d.Client, protoUDP, udpSegment(d.Resolver, d.Client, 53, x.Port, x.Reply))},
+		)
+	}
+	return out, nil
+}
+
+// dnsMessage builds an A query for name, or an NXDOMAIN response to it.
+func dnsMessage(id uint16, name string, response bool) ([]byte, error) {
+	msg := make([]byte, 12, 12+len(name)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	flags := uint16(0x0100) // RD
+	if response {
+		flags = 0x8183 // QR, RD, RA, NXDOMAIN
+	}
+	binary.BigEndian.PutUint16(msg[2:], flags)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > maxDNSLabel {
+			return nil, fmt.Errorf("invalid DNS label %q", label)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0, 0, 1, 0, 1) // root, QTYPE A, QCLASS IN
+	return msg, nil
+}
+
+// tcpConn tracks sequence numbers for one synthetic TCP connection.
+type tcpConn struct {
+	src, dst     netip.Addr
+	sport, dport uint16
+	seqC, seqS   uint32
+}
+
+// exchange yields handshake, one request, one response, and teardown:
+// five client and three server segments spanning tcpExchangeDuration.
+func (c *tcpConn) exchange(t time.Time, req, resp []byte) []Packet {
+	rtt := tcpRTT
+	var out []Packet
+	add := func(at time.Time, fromClient bool, flags byte, payload []byte) {
+		src, dst, sp, dp, seq, ack := c.src, c.dst, c.sport, c.dport, c.seqC, c.seqS
+		if !fromClient {
+			src, dst, sp, dp, seq, ack = c.dst, c.src, c.dport, c.sport, c.seqS, c.seqC
+		}
+		seg := tcpSegment(src, dst, sp, dp, seq, ack, flags, payload)
+		out = append(out, Packet{Time: at, Data: frame(src, dst, protoTCP, seg)})
+		adv := uint32(len(payload))
+		if flags&(tcpSYN|tcpFIN) != 0 {
+			adv++
+		}
+		if fromClient {
+			c.seqC += adv
+		} else {
+			c.seqS += adv
+		}
+	}
+	add(t, true, tcpSYN, nil)
+	add(t.Add(rtt/2), false, tcpSYN|tcpACK, nil)
+	add(t.Add(rtt), true, tcpACK, nil)
+	add(t.Add(rtt+time.Millisecond), true, tcpPSH|tcpACK, req)
+	add(t.Add(2*rtt), false, tcpPSH|tcpACK, resp)
+	add(t.Add(2*rtt+time.Millisecond), true, tcpFIN|tcpACK, nil)
+	add(t.Add(3*rtt), false, tcpFIN|tcpACK, nil)
+	add(t.Add(3*rtt+time.Millisecond), true, tcpACK, nil)
+	return out
+}
+
+func tcpSegment(src, dst netip.Addr, sport, dport uint16, seq, ack uint32, flags byte, payload []byte) []byte {
+	seg := make([]byte, 20+len(payload))
+	binary.BigEndian.PutUint16(seg[0:], sport)
+	binary.BigEndian.PutUint16(seg[2:], dport)
+	binary.BigEndian.PutUint32(seg[4:], seq)
+	if flags&tcpACK != 0 {
+		binary.BigEndian.PutUint32(seg[8:], ack)
+	}
+	seg[12] = 5 << 4
+	seg[13] = flags
+	binary.BigEndian.PutUint16(seg[14:], 64240)
+	copy(seg[20:], payload)
+	binary.BigEndian.PutUint16(seg[16:], transportChecksum(src, dst, protoTCP, seg))
+	return seg
+}
+
+func udpSegment(src, dst netip.Addr, sport, dport uint16, payload []byte) []byte {
+	seg := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint16(seg[0:], sport)
+	binary.BigEndian.PutUint16(seg[2:], dport)
+	binary.BigEndian.PutUint16(seg[4:], uint16(len(seg)))
+	copy(seg[8:], payload)
+	binary.BigEndian.PutUint16(seg[6:], transportChecksum(src, dst, protoUDP, seg))
+	return seg
+}
+
+// frame wraps a transport segment in IPv4 and Ethernet headers.
+func frame(src, dst netip.Addr, proto byte, segment []byte) []byte {
+	out := make([]byte, 14+20+len(segment))
+	copy(out[0:6], macFor(dst))
+	copy(out[6:12], macFor(src))
+	binary.BigEndian.PutUint16(out[12:], etherTypeIPv4)
+	ip := out[14:34]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+len(segment)))
+	binary.BigEndian.PutUint16(ip[6:], 0x4000) // don't fragment
+	ip[8] = defaultTTLHops
+	ip[9] = proto
+	s, d := src.As4(), dst.As4()
+	copy(ip[12:16], s[:])
+	copy(ip[16:20], d[:])
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip, 0))
+	copy(out[34:], segment)
+	return out
+}
+
+// macFor derives a stable locally administered MAC address from an IP.
+func macFor(a netip.Addr) []byte {
+	b := a.As4()
+	return []byte{0x02, 0x00, b[0], b[1], b[2], b[3]}
+}
+
+func transportChecksum(src, dst netip.Addr, proto byte, seg []byte) uint16 {
+	c := checksum(seg, pseudoHeaderSum(src, dst, proto, len(seg)))
+	if proto == protoUDP && c == 0 {
+		c = 0xffff
+	}
+	return c
+}
+
+// pseudoHeaderSum is the IPv4 pseudo-header contribution to TCP/UDP checksums.
+func pseudoHeaderSum(src, dst netip.Addr, proto byte, length int) uint32 {
+	s, d := src.As4(), dst.As4()
+	var sum uint32
+	sum += uint32(s[0])<<8 | uint32(s[1])
+	sum += uint32(s[2])<<8 | uint32(s[3])
+	sum += uint32(d[0])<<8 | uint32(d[1])
+	sum += uint32(d[2])<<8 | uint32(d[3])
+	sum += uint32(proto)
+	sum += uint32(length)
+	return sum
+}
+
+// checksum computes the Internet checksum (RFC 1071) of b plus an initial sum.
+func checksum(b []byte, initial uint32) uint16 {
+	sum := initial
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
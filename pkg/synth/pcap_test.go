@@ -0,0 +1,119 @@
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readPCAP returns the frames of a classic little-endian pcap file.
+func readPCAP(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	if len(data) < 24 || binary.LittleEndian.Uint32(data) != pcapMagic {
+		t.Fatal("missing pcap global header")
+	}
+	var frames [][]byte
+	for off := 24; off < len(data); {
+		n := int(binary.LittleEndian.Uint32(data[off+8:]))
+		off += 16
+		frames = append(frames, data[off:off+n])
+		off += n
+	}
+	return frames
+}
+
+func TestWritePCAP_HTTPBeacon(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	b := HTTPBeacon{
+		Client:   netip.MustParseAddr("10.20.10.15"),
+		Server:   netip.MustParseAddr("10.20.250.10"),
+		Host:     "updates.eng-2026-q1.example",
+		Path:     "/check",
+		Start:    start,
+		Interval: time.Minute,
+		Count:    3,
+	}
+	var buf bytes.Buffer
+	if err := WritePCAP(&buf, b); err != nil {
+		t.Fatalf("WritePCAP: %v", err)
+	}
+	frames := readPCAP(t, buf.Bytes())
+	if len(frames) != 3*8 {
+		t.Fatalf("frames: got %d, want %d", len(frames), 3*8)
+	}
+	var sawRequest bool
+	for _, f := range frames {
+		if checksum(f[14:34], 0) != 0 {
+			t.Fatal("bad IPv4 header checksum")
+		}
+		if strings.Contains(string(f), "Host: updates.eng-2026-q1.example") {
+			sawRequest = true
+		}
+	}
+	if !sawRequest {
+		t.Fatal("expected HTTP request with configured host")
+	}
+}
+
+func TestWritePCAP_DNSTunnel(t *testing.T) {
+	d := DNSTunnel{
+		Client:   netip.MustParseAddr("10.20.10.15"),
+		Resolver: netip.MustParseAddr("10.20.250.53"),
+		Domain:   "t.eng-2026-q1.example",
+		Data:     []byte(strings.Repeat("synthetic-payload ", 10)),
+		Start:    time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+	}
+	var buf bytes.Buffer
+	if err := WritePCAP(&buf, d); err != nil {
+		t.Fatalf("WritePCAP: %v", err)
+	}
+	frames := readPCAP(t, buf.Bytes())
+	chunks := (len(d.Data) + defaultChunk - 1) / defaultChunk
+	if len(frames) != 2*chunks {
+		t.Fatalf("frames: got %d, want %d", len(frames), 2*chunks)
+	}
+	udp := frames[0][34:]
+	if binary.BigEndian.Uint16(udp[2:]) != 53 {
+		t.Fatal("expected query to port 53")
+	}
+	if checksum(udp, pseudoHeaderSum(d.Client, d.Resolver, protoUDP, len(udp))) != 0 {
+		t.Fatal("bad UDP checksum")
+	}
+}
+
+func TestWritePCAP_MergesInTimeOrder(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	b := HTTPBeacon{Client: netip.MustParseAddr("10.0.0.1"), Server: netip.MustParseAddr("10.0.0.2"), Start: start.Add(time.Second), Count: 1}
+	d := DNSTunnel{Client: netip.MustParseAddr("10.0.0.1"), Resolver: netip.MustParseAddr("10.0.0.3"), Domain: "x.example", Data: []byte("hi"), Start: start}
+	var buf bytes.Buffer
+	if err := WritePCAP(&buf, b, d); err != nil {
+		t.Fatalf("WritePCAP: %v", err)
+	}
+	data := buf.Bytes()
+	prev := uint32(0)
+	for off := 24; off < len(data); {
+		sec := binary.LittleEndian.Uint32(data[off:])
+		if sec < prev {
+			t.Fatal("packets out of time order")
+		}
+		prev = sec
+		off += 16 + int(binary.LittleEndian.Uint32(data[off+8:]))
+	}
+}
+
+func TestSessions_Invalid(t *testing.T) {
+	if _, err := (HTTPBeacon{}).Packets(); err == nil {
+		t.Fatal("expected missing addresses to fail")
+	}
+	v6 := netip.MustParseAddr("::1")
+	if _, err := (DNSTunnel{Client: v6, Resolver: v6, Domain: "x", Data: []byte("a")}).Packets(); err == nil {
+		t.Fatal("expected IPv6 addresses to fail")
+	}
+	d := DNSTunnel{Client: netip.MustParseAddr("10.0.0.1"), Resolver: netip.MustParseAddr("10.0.0.2"), Domain: "x.example", Data: []byte("a"), ChunkSize: 100}
+	if _, err := d.Packets(); err == nil {
+		t.Fatal("expected oversized chunk to fail")
+	}
+}
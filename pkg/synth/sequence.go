@@ -0,0 +1,115 @@
+package synth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Stage is one step of a multi-technique story. Offset is measured from the
+// start of the story; Jitter adds a deterministic random delay in [0, Jitter).
+type Stage struct {
+	Name   string
+	Type   rte.TaskType
+	Offset time.Duration
+	Jitter time.Duration
+	// Actor selects the identity-set user performing the stage (see UserAt).
+	Actor  int
+	Params map[string]string
+}
+
+// Story is an ordered sequence of stages that should read like one intrusion.
+type Story struct {
+	Name   string
+	Stages []Stage
+}
+
+// ScheduledTask is a per-stage task together with the time it should run.
+type ScheduledTask struct {
+	Stage string
+	At    time.Time
+	Task  rte.Task
+}
+
+// LateralMovementStory is a reference story spreading initial access,
+// discovery, and lateral movement telemetry over roughly half a working day.
+func LateralMovementStory() Story {
+	return Story{
+		Name: "initial-access-to-lateral-movement",
+		Stages: []Stage{
+			{Name: "initial-access", Type: rte.TaskSimulateLogin, Offset: 0, Jitter: 5 * time.Minute},
+			{Name: "discovery", Type: rte.TaskEmitSynthetic, Offset: 40 * time.Minute, Jitter: 20 * time.Minute,
+				Params: map[string]string{"events": "process_creation,dns_query"}},
+			{Name: "lateral-movement", Type: rte.TaskEmitSynthetic, Offset: 3 * time.Hour, Jitter: 45 * time.Minute,
+				Params: map[string]string{"events": "failed_login,process_creation"}},
+			{Name: "command-and-control", Type: rte.TaskSimulateBeacon, Offset: 5 * time.Hour, Jitter: time.Hour},
+		},
+	}
+}
+
+// Plan spaces the story's stages across time starting at start and returns
+// one task per stage. Each task copies attribution and TTL from base, and
+// carries the acting user and host from ids so every stage references the
+// same cast. Planning is deterministic for a given engagement and story.
+func (s Story) Plan(base rte.Task, start time.Time, ids *IdentitySet) ([]ScheduledTask, error) {
+	if s.Name == "" {
+		return nil, errors.New("story name is required")
+	}
+	if len(s.Stages) == 0 {
+		return nil, errors.New("story has no stages")
+	}
+	if ids == nil {
+		return nil, errors.New("identity set is required")
+	}
+	if ids.Engagement != base.Engagement {
+		return nil, fmt.Errorf("identity set is for engagement %q, task is for %q", ids.Engagement, base.Engagement)
+	}
+	r := seededRand(base.Engagement + "/" + s.Name)
+	out := make([]ScheduledTask, 0, len(s.Stages))
+	var prev time.Time
+	for i, st := range s.Stages {
+		if st.Name == "" {
+			return nil, fmt.Errorf("stage %d has no name", i)
+		}
+		if st.Offset < 0 || st.Jitter < 0 {
+			return nil, fmt.Errorf("stage %s: offset and jitter must not be negative", st.Name)
+		}
+		if i > 0 && st.Offset < s.Stages[i-1].Offset {
+			return nil, fmt.Errorf("stage %s: offsets must not decrease", st.Name)
+		}
+		at := start.Add(st.Offset)
+		if st.Jitter > 0 {
+			at = at.Add(time.Duration(r.Int64N(int64(st.Jitter))))
+		}
+		// Jitter must never reorder the story.
+		if at.Before(prev) {
+			at = prev
+		}
+		prev = at
+
+		actor := ids.UserAt(st.Actor)
+		task := base
+		task.ID = fmt.Sprintf("%s-%02d-%s", base.ID, i+1, st.Name)
+		task.Type = st.Type
+		task.CreatedAt = at
+		task.State = rte.StatePending
+		task.Params = make(map[string]string, len(base.Params)+len(st.Params)+4)
+		for k, v := range base.Params {
+			task.Params[k] = v
+		}
+		for k, v := range st.Params {
+			task.Params[k] = v
+		}
+		task.Params["story"] = s.Name
+		task.Params["stage"] = st.Name
+		task.Params["actor"] = actor.Username
+		task.Params["host"] = actor.Workstation
+		if err := task.Validate(at); err != nil {
+			return nil, fmt.Errorf("stage %s: %w", st.Name, err)
+		}
+		out = append(out, ScheduledTask{Stage: st.Name, At: at, Task: task})
+	}
+	return out, nil
+}
@@ -0,0 +1,90 @@
+package synth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+func baseTask(now time.Time) rte.Task {
+	return rte.Task{
+		ID:         "story-001",
+		Engagement: "eng-2026-q1",
+		Type:       rte.TaskEmitSynthetic,
+		CreatedAt:  now,
+		TTLSeconds: 1800,
+		Operator:   "op-alice",
+		ApprovedBy: "lead-bob",
+		State:      rte.StatePending,
+	}
+}
+
+func TestStoryPlan_SpacesStages(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	ids, err := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	if err != nil {
+		t.Fatalf("NewIdentitySet: %v", err)
+	}
+	plan, err := LateralMovementStory().Plan(baseTask(start), start, ids)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan) != 4 {
+		t.Fatalf("stages: got %d, want 4", len(plan))
+	}
+	for i := 1; i < len(plan); i++ {
+		if gap := plan[i].At.Sub(plan[i-1].At); gap < 10*time.Minute {
+			t.Errorf("stage %s only %s after %s", plan[i].Stage, gap, plan[i-1].Stage)
+		}
+	}
+	if span := plan[len(plan)-1].At.Sub(plan[0].At); span < 4*time.Hour {
+		t.Errorf("story spans only %s", span)
+	}
+	for _, p := range plan {
+		if p.Task.Params["actor"] != ids.UserAt(0).Username {
+			t.Errorf("stage %s actor %q, want %q", p.Stage, p.Task.Params["actor"], ids.UserAt(0).Username)
+		}
+		if err := p.Task.Validate(p.At); err != nil {
+			t.Errorf("stage %s task invalid: %v", p.Stage, err)
+		}
+	}
+}
+
+func TestStoryPlan_Deterministic(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	a, err := LateralMovementStory().Plan(baseTask(start), start, ids)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	b, _ := LateralMovementStory().Plan(baseTask(start), start, ids)
+	for i := range a {
+		if !a[i].At.Equal(b[i].At) || a[i].Task.ID != b[i].Task.ID {
+			t.Fatalf("stage %d differs between runs", i)
+		}
+	}
+}
+
+func TestStoryPlan_Invalid(t *testing.T) {
+	start := time.Now().UTC()
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	other, _ := NewIdentitySet("eng-other", IdentityConfig{})
+	story := Story{Name: "bad", Stages: []Stage{
+		{Name: "b", Type: rte.TaskEmitSynthetic, Offset: time.Hour},
+		{Name: "a", Type: rte.TaskEmitSynthetic, Offset: time.Minute},
+	}}
+	if _, err := story.Plan(baseTask(start), start, ids); err == nil {
+		t.Fatal("expected decreasing offsets to fail")
+	}
+	if _, err := LateralMovementStory().Plan(baseTask(start), start, other); err == nil {
+		t.Fatal("expected identity set for another engagement to fail")
+	}
+	if _, err := (Story{Name: "empty"}).Plan(baseTask(start), start, ids); err == nil {
+		t.Fatal("expected empty story to fail")
+	}
+	bad := Story{Name: "bad-type", Stages: []Stage{{Name: "x", Type: rte.TaskType("malware")}}}
+	if _, err := bad.Plan(baseTask(start), start, ids); err == nil {
+		t.Fatal("expected unsupported task type to fail")
+	}
+}
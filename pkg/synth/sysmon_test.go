@@ -0,0 +1,98 @@
+package synth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSysmonEvents_Chains(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for _, sc := range []SysmonScenario{ScenarioOfficeMacro, ScenarioLOLBinDownload, ScenarioRundllSideload} {
+		events, err := SysmonEvents(ids, SysmonConfig{Scenario: sc, Start: start, Actor: 2, TaskID: "task-1"})
+		if err != nil {
+			t.Fatalf("%s: %v", sc, err)
+		}
+		seen := map[string]bool{}
+		kinds := map[int]int{}
+		for i, e := range events {
+			kinds[e.EventID]++
+			if i > 0 && !e.Time.After(events[i-1].Time) {
+				t.Errorf("%s: event %d not after previous", sc, i)
+			}
+			if e.Field("RuleName") != "rte-a-synthetic:task-1" {
+				t.Errorf("%s: missing attribution in %v", sc, e.Data)
+			}
+			// Every load or connect must reference a process created earlier.
+			guid := e.Field("ProcessGuid")
+			if e.EventID == SysmonProcessCreate {
+				seen[guid] = true
+			} else if !seen[guid] {
+				t.Errorf("%s: event %d references unknown process %s", sc, e.EventID, guid)
+			}
+		}
+		if kinds[SysmonProcessCreate] == 0 || kinds[SysmonImageLoad] == 0 || kinds[SysmonNetworkConnect] != 1 {
+			t.Errorf("%s: unexpected event mix %v", sc, kinds)
+		}
+		if !strings.HasPrefix(events[len(events)-1].Field("DestinationIp"), "198.51.100.") {
+			t.Errorf("%s: connect should target a documentation address", sc)
+		}
+	}
+}
+
+func TestSysmonEvents_ParentChild(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	events, err := SysmonEvents(ids, SysmonConfig{Scenario: ScenarioOfficeMacro, Start: time.Now()})
+	if err != nil {
+		t.Fatalf("SysmonEvents: %v", err)
+	}
+	var word, ps SysmonEvent
+	for _, e := range events {
+		if e.EventID != SysmonProcessCreate {
+			continue
+		}
+		if strings.HasSuffix(e.Field("Image"), "WINWORD.EXE") {
+			word = e
+		} else if strings.HasSuffix(e.Field("Image"), "powershell.exe") {
+			ps = e
+		}
+	}
+	if ps.Field("ParentProcessGuid") != word.Field("ProcessGuid") || ps.Field("ParentProcessId") != word.Field("ProcessId") {
+		t.Fatalf("powershell parent does not match winword: %v", ps.Data)
+	}
+}
+
+func TestSysmonEvents_RenderAndDeliver(t *testing.T) {
+	ids, _ := NewIdentitySet("eng-2026-q1", IdentityConfig{})
+	events, _ := SysmonEvents(ids, SysmonConfig{Scenario: ScenarioRundllSideload, Start: time.Now()})
+
+	var ev evtxEvent
+	raw, err := events[1].XML()
+	if err != nil || xml.Unmarshal(raw, &ev) != nil {
+		t.Fatalf("XML: %v", err)
+	}
+	if ev.System.EventID != SysmonImageLoad || ev.System.Channel != "Microsoft-Windows-Sysmon/Operational" {
+		t.Errorf("unexpected System %+v", ev.System)
+	}
+	var flat map[string]any
+	b, _ := json.Marshal(events[1])
+	if err := json.Unmarshal(b, &flat); err != nil || flat["Signed"] != "false" {
+		t.Errorf("unexpected JSON %s", b)
+	}
+
+	var buf bytes.Buffer
+	if err := DeliverSysmonEvents(context.Background(), &WriterSink{W: &buf}, events); err != nil {
+		t.Fatalf("DeliverSysmonEvents: %v", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != len(events) {
+		t.Fatalf("delivered %d lines, want %d", n, len(events))
+	}
+	if _, err := SysmonEvents(ids, SysmonConfig{Scenario: "mimikatz", Start: time.Now()}); err == nil {
+		t.Fatal("expected unsupported scenario to fail")
+	}
+}
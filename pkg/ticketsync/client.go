@@ -0,0 +1,144 @@
+package ticketsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// maxResponseBytes bounds how much of a ticket API response is read.
+const maxResponseBytes = 1 << 20
+
+// JiraClient comments on and transitions Jira issues through the REST API
+// v2, authenticating with an account email and API token.
+type JiraClient struct {
+	BaseURL string
+	Email   string
+	Token   string
+	// Transitions maps task states to Jira transition IDs. States with no
+	// entry get a comment only.
+	Transitions map[rte.TaskState]string
+	Client      *http.Client
+}
+
+// Update implements Client.
+func (c *JiraClient) Update(ctx context.Context, u Update) error {
+	if c.BaseURL == "" {
+		return errors.New("jira base URL is required")
+	}
+	issue := strings.TrimRight(c.BaseURL, "/") + "/rest/api/2/issue/" + url.PathEscape(u.Key)
+	comment := map[string]string{"body": u.Comment}
+	if err := c.do(ctx, http.MethodPost, issue+"/comment", comment, nil); err != nil {
+		return fmt.Errorf("comment on %s: %w", u.Key, err)
+	}
+	id, ok := c.Transitions[u.State]
+	if !ok {
+		return nil
+	}
+	transition := map[string]any{"transition": map[string]string{"id": id}}
+	if err := c.do(ctx, http.MethodPost, issue+"/transitions", transition, nil); err != nil {
+		return fmt.Errorf("transition %s: %w", u.Key, err)
+	}
+	return nil
+}
+
+func (c *JiraClient) do(ctx context.Context, method, u string, in, out any) error {
+	return send(ctx, c.Client, method, u, in, out, func(r *http.Request) { r.SetBasicAuth(c.Email, c.Token) })
+}
+
+// ServiceNowClient adds work notes to, and sets the state of, ServiceNow
+// change requests through the Table API, authenticating with basic auth.
+type ServiceNowClient struct {
+	BaseURL  string
+	User     string
+	Password string
+	// Table defaults to change_request.
+	Table string
+	// States maps task states to change_request state values. States with
+	// no entry get a work note only.
+	States map[rte.TaskState]string
+	Client *http.Client
+}
+
+// Update implements Client.
+func (c *ServiceNowClient) Update(ctx context.Context, u Update) error {
+	if c.BaseURL == "" {
+		return errors.New("servicenow base URL is required")
+	}
+	table := c.Table
+	if table == "" {
+		table = "change_request"
+	}
+	base := strings.TrimRight(c.BaseURL, "/") + "/api/now/table/" + url.PathEscape(table)
+	q := url.Values{"sysparm_query": {"number=" + u.Key}, "sysparm_fields": {"sys_id"}, "sysparm_limit": {"1"}}
+	var found struct {
+		Result []struct {
+			SysID string `json:"sys_id"`
+		} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodGet, base+"?"+q.Encode(), nil, &found); err != nil {
+		return fmt.Errorf("look up %s: %w", u.Key, err)
+	}
+	if len(found.Result) == 0 || found.Result[0].SysID == "" {
+		return fmt.Errorf("look up %s: no such %s record", u.Key, table)
+	}
+	patch := map[string]string{"work_notes": u.Comment}
+	if state, ok := c.States[u.State]; ok {
+		patch["state"] = state
+	}
+	if err := c.do(ctx, http.MethodPatch, base+"/"+url.PathEscape(found.Result[0].SysID), patch, nil); err != nil {
+		return fmt.Errorf("update %s: %w", u.Key, err)
+	}
+	return nil
+}
+
+func (c *ServiceNowClient) do(ctx context.Context, method, u string, in, out any) error {
+	return send(ctx, c.Client, method, u, in, out, func(r *http.Request) { r.SetBasicAuth(c.User, c.Password) })
+}
+
+// send makes one JSON API call, decoding a 2xx response into out if set.
+func send(ctx context.Context, client *http.Client, method, u string, in, out any, auth func(*http.Request)) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	auth(req)
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out != nil {
+		if err := json.Unmarshal(msg, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
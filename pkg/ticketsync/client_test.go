@@ -0,0 +1,128 @@
+package ticketsync
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+type recorded struct {
+	method, path, query string
+	body                map[string]any
+	user, pass          string
+}
+
+func recorder(t *testing.T, reply func(r *http.Request) string) (*httptest.Server, *[]recorded) {
+	t.Helper()
+	var calls []recorded
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recorded{method: r.Method, path: r.URL.EscapedPath(), query: r.URL.RawQuery}
+		rec.user, rec.pass, _ = r.BasicAuth()
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			if err := json.Unmarshal(b, &rec.body); err != nil {
+				t.Errorf("request body: %v", err)
+			}
+		}
+		calls = append(calls, rec)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, reply(r))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestJiraClient_Update(t *testing.T) {
+	srv, calls := recorder(t, func(*http.Request) string { return "{}" })
+	c := &JiraClient{
+		BaseURL: srv.URL + "/", Email: "bot@example.com", Token: "tok",
+		Transitions: map[rte.TaskState]string{rte.StateCompleted: "31"},
+	}
+	u := Update{Key: "SEC-142", State: rte.StateCompleted, Comment: "done"}
+	if err := c.Update(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 2 {
+		t.Fatalf("calls = %d, want comment and transition", len(*calls))
+	}
+	comment, tr := (*calls)[0], (*calls)[1]
+	if comment.path != "/rest/api/2/issue/SEC-142/comment" || comment.body["body"] != "done" {
+		t.Errorf("comment call = %+v", comment)
+	}
+	if comment.user != "bot@example.com" || comment.pass != "tok" {
+		t.Errorf("auth = %s:%s", comment.user, comment.pass)
+	}
+	if tr.path != "/rest/api/2/issue/SEC-142/transitions" {
+		t.Errorf("transition path = %s", tr.path)
+	}
+	if id := tr.body["transition"].(map[string]any)["id"]; id != "31" {
+		t.Errorf("transition id = %v", id)
+	}
+
+	*calls = nil
+	u.State = rte.StatePaused
+	if err := c.Update(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("unmapped state made %d calls, want comment only", len(*calls))
+	}
+}
+
+func TestJiraClient_UpdateError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "issue does not exist", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	c := &JiraClient{BaseURL: srv.URL}
+	err := c.Update(context.Background(), Update{Key: "SEC-9", Comment: "x"})
+	if err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestServiceNowClient_Update(t *testing.T) {
+	srv, calls := recorder(t, func(r *http.Request) string {
+		if r.Method == http.MethodGet {
+			return `{"result":[{"sys_id":"abc123"}]}`
+		}
+		return `{"result":{}}`
+	})
+	c := &ServiceNowClient{
+		BaseURL: srv.URL, User: "svc", Password: "pw",
+		States: map[rte.TaskState]string{rte.StateCompleted: "0"},
+	}
+	u := Update{Key: "CHG0030001", State: rte.StateCompleted, Comment: "done"}
+	if err := c.Update(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 2 {
+		t.Fatalf("calls = %d, want lookup and patch", len(*calls))
+	}
+	lookup, patch := (*calls)[0], (*calls)[1]
+	if lookup.path != "/api/now/table/change_request" || !strings.Contains(lookup.query, "sysparm_query=number%3DCHG0030001") {
+		t.Errorf("lookup = %+v", lookup)
+	}
+	if patch.method != http.MethodPatch || patch.path != "/api/now/table/change_request/abc123" {
+		t.Errorf("patch = %+v", patch)
+	}
+	if patch.body["work_notes"] != "done" || patch.body["state"] != "0" || patch.user != "svc" {
+		t.Errorf("patch = %+v", patch)
+	}
+}
+
+func TestServiceNowClient_UpdateNotFound(t *testing.T) {
+	srv, calls := recorder(t, func(*http.Request) string { return `{"result":[]}` })
+	c := &ServiceNowClient{BaseURL: srv.URL}
+	if err := c.Update(context.Background(), Update{Key: "CHG404"}); err == nil {
+		t.Fatal("expected error for missing record")
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("calls = %d, want lookup only", len(*calls))
+	}
+}
@@ -0,0 +1,185 @@
+// Package ticketsync keeps the change ticket behind each task current: when
+// an executor reports a state change, the linked Jira issue or ServiceNow
+// change request gets a summary comment, a status transition where one is
+// mapped, and, once the task finishes, a link to its evidence package.
+// Tasks are linked to tickets by the intake package's ticket param.
+package ticketsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/intake"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+// Update is one status change to post to a ticket.
+type Update struct {
+	System     intake.System `json:"system"`
+	Key        string        `json:"key"`
+	Engagement string        `json:"engagement"`
+	TaskID     string        `json:"task_id"`
+	State      rte.TaskState `json:"state"`
+	// Comment is the human-readable summary posted to the ticket.
+	Comment string `json:"comment"`
+	// EvidenceURL links the evidence package; set only on final states.
+	EvidenceURL string    `json:"evidence_url,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+// Final reports whether the update records a finished task.
+func (u Update) Final() bool {
+	switch u.State {
+	case rte.StateCompleted, rte.StateFailed, rte.StateCancelled:
+		return true
+	}
+	return false
+}
+
+// Client posts updates to one ticketing system.
+type Client interface {
+	Update(ctx context.Context, u Update) error
+}
+
+const (
+	defaultBuffer   = 256
+	defaultAttempts = 3
+	defaultBackoff  = 2 * time.Second
+)
+
+// Syncer turns executor transitions into ticket updates. Observe is meant
+// for rte.Executor.OnTransition: it only queues, so the executor is never
+// held up by a slow ticket system. Run delivers queued updates, retrying
+// failures with backoff.
+type Syncer struct {
+	Clients map[intake.System]Client
+	// EvidenceURL, if set, builds the evidence package link for a finished
+	// task.
+	EvidenceURL func(engagement, taskID string) string
+	// Attempts per update; defaults to 3. Backoff doubles after each
+	// failure, starting at 2s.
+	Attempts int
+	Backoff  time.Duration
+	// Logger, if set, records dropped and failed updates.
+	Logger *slog.Logger
+
+	once  sync.Once
+	queue chan Update
+}
+
+func (s *Syncer) init() {
+	s.once.Do(func() { s.queue = make(chan Update, defaultBuffer) })
+}
+
+// Observe queues an update for a transition of a ticket-linked task. It
+// never blocks; if the queue is full the update is dropped and logged.
+func (s *Syncer) Observe(tr rte.Transition) {
+	s.init()
+	u, ok := s.update(tr)
+	if !ok {
+		return
+	}
+	select {
+	case s.queue <- u:
+	default:
+		if s.Logger != nil {
+			s.Logger.Warn("ticket update dropped", "ticket", u.Key, "task_id", u.TaskID, "state", u.State)
+		}
+	}
+}
+
+// update maps a transition to a ticket update, if the task has a ticket.
+func (s *Syncer) update(tr rte.Transition) (Update, bool) {
+	system, key, ok := strings.Cut(tr.Task.Params[intake.ParamTicket], ":")
+	if !ok || key == "" {
+		return Update{}, false
+	}
+	u := Update{
+		System: intake.System(system), Key: key, Engagement: tr.Task.Engagement,
+		TaskID: tr.Task.ID, State: tr.To, At: tr.At.UTC(),
+	}
+	if u.Final() && s.EvidenceURL != nil {
+		u.EvidenceURL = s.EvidenceURL(u.Engagement, u.TaskID)
+	}
+	u.Comment = summary(tr, u.EvidenceURL)
+	return u, true
+}
+
+// summary renders the ticket comment for a transition.
+func summary(tr rte.Transition, evidence string) string {
+	t := tr.Task
+	var b strings.Builder
+	fmt.Fprintf(&b, "RTE-A task %s (%s) in %s is now %s", t.ID, t.Type, t.Engagement, tr.To)
+	fmt.Fprintf(&b, " as of %s.", tr.At.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "\nOperator: %s. Approved by: %s.", t.Operator, t.ApprovedBy)
+	if res := tr.Result; res != nil {
+		fmt.Fprintf(&b, "\nRan %s, from %s to %s.", res.FinishedAt.Sub(res.StartedAt).Round(time.Second),
+			res.StartedAt.UTC().Format(time.RFC3339), res.FinishedAt.UTC().Format(time.RFC3339))
+		if res.Error != "" {
+			fmt.Fprintf(&b, "\nError: %s", res.Error)
+		}
+	}
+	if tr.Reason != "" {
+		fmt.Fprintf(&b, "\nReason: %s", tr.Reason)
+	}
+	if evidence != "" {
+		fmt.Fprintf(&b, "\nEvidence: %s", evidence)
+	}
+	return b.String()
+}
+
+// Run delivers queued updates until ctx ends.
+func (s *Syncer) Run(ctx context.Context) error {
+	s.init()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case u := <-s.queue:
+			if err := s.deliver(ctx, u); err != nil && s.Logger != nil {
+				s.Logger.Error("ticket update failed", "ticket", u.Key, "task_id", u.TaskID,
+					"state", u.State, "error", err)
+			}
+		}
+	}
+}
+
+// deliver posts one update, retrying with exponential backoff.
+func (s *Syncer) deliver(ctx context.Context, u Update) error {
+	c, ok := s.Clients[u.System]
+	if !ok {
+		return fmt.Errorf("no client for %s tickets", u.System)
+	}
+	attempts := s.Attempts
+	if attempts <= 0 {
+		attempts = defaultAttempts
+	}
+	backoff := s.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	var errs []error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Join(append(errs, ctx.Err())...)
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+		err := c.Update(ctx, u)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
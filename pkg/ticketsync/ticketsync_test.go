@@ -0,0 +1,125 @@
+package ticketsync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/intake"
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+type fakeClient struct {
+	mu      sync.Mutex
+	fail    int
+	calls   int
+	updates []Update
+	done    chan struct{}
+}
+
+func (c *fakeClient) Update(_ context.Context, u Update) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.fail > 0 {
+		c.fail--
+		return errors.New("unavailable")
+	}
+	c.updates = append(c.updates, u)
+	if c.done != nil {
+		c.done <- struct{}{}
+	}
+	return nil
+}
+
+func transition(ticket string, to rte.TaskState) rte.Transition {
+	at := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	tr := rte.Transition{
+		Task: rte.Task{
+			ID: "tkt-jira-sec-142", Engagement: "eng-1", Type: rte.TaskInventory,
+			Operator: "op", ApprovedBy: "lead", Params: map[string]string{},
+		},
+		From: rte.StateExecuting, To: to, At: at,
+	}
+	if ticket != "" {
+		tr.Task.Params[intake.ParamTicket] = ticket
+	}
+	if to == rte.StateCompleted {
+		tr.Result = &rte.TaskResult{State: to, StartedAt: at.Add(-90 * time.Second), FinishedAt: at}
+	}
+	return tr
+}
+
+func TestSyncer_Update(t *testing.T) {
+	s := &Syncer{EvidenceURL: func(eng, id string) string { return "https://rte.example/evidence/" + eng + "/" + id }}
+
+	if _, ok := s.update(transition("", rte.StateCompleted)); ok {
+		t.Fatal("task without a ticket should be skipped")
+	}
+	if _, ok := s.update(transition("SEC-142", rte.StateCompleted)); ok {
+		t.Fatal("malformed ticket ref should be skipped")
+	}
+
+	u, ok := s.update(transition("jira:SEC-142", rte.StateCompleted))
+	if !ok {
+		t.Fatal("linked task was skipped")
+	}
+	if u.System != intake.SystemJira || u.Key != "SEC-142" || u.State != rte.StateCompleted {
+		t.Fatalf("update = %+v", u)
+	}
+	if u.EvidenceURL != "https://rte.example/evidence/eng-1/tkt-jira-sec-142" {
+		t.Fatalf("evidence URL = %q", u.EvidenceURL)
+	}
+	for _, want := range []string{"tkt-jira-sec-142", "completed", "Ran 1m30s", "Evidence: https://rte.example/evidence/"} {
+		if !strings.Contains(u.Comment, want) {
+			t.Errorf("comment missing %q:\n%s", want, u.Comment)
+		}
+	}
+
+	u, _ = s.update(transition("jira:SEC-142", rte.StatePaused))
+	if u.EvidenceURL != "" || strings.Contains(u.Comment, "Evidence") {
+		t.Fatalf("non-final update links evidence: %+v", u)
+	}
+}
+
+func TestSyncer_Run(t *testing.T) {
+	c := &fakeClient{fail: 1, done: make(chan struct{}, 2)}
+	s := &Syncer{Clients: map[intake.System]Client{intake.SystemJira: c}, Backoff: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	s.Observe(transition("servicenow:CHG0001", rte.StateCompleted)) // no client; dropped after logging
+	s.Observe(transition("jira:SEC-142", rte.StateExecuting))
+	s.Observe(transition("jira:SEC-142", rte.StateCompleted))
+	for i := 0; i < 2; i++ {
+		select {
+		case <-c.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("updates not delivered")
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.calls != 3 {
+		t.Fatalf("calls = %d, want 3 (one retry)", c.calls)
+	}
+	if c.updates[0].State != rte.StateExecuting || c.updates[1].State != rte.StateCompleted {
+		t.Fatalf("updates out of order: %+v", c.updates)
+	}
+}
+
+func TestSyncer_DeliverGivesUp(t *testing.T) {
+	c := &fakeClient{fail: 5}
+	s := &Syncer{Clients: map[intake.System]Client{intake.SystemJira: c}, Attempts: 2, Backoff: time.Millisecond}
+	u, _ := s.update(transition("jira:SEC-142", rte.StateFailed))
+	if err := s.deliver(context.Background(), u); err == nil {
+		t.Fatal("expected error after exhausting attempts")
+	}
+	if c.calls != 2 {
+		t.Fatalf("calls = %d, want 2", c.calls)
+	}
+}
@@ -0,0 +1,262 @@
+// Package tracing provides minimal span tracing with W3C Trace Context
+// propagation. Its shape follows the OpenTelemetry API (tracer, span,
+// attributes, exporter) so an OTel SDK can be bridged in through Exporter
+// without changing instrumented code, while keeping this module free of
+// third-party dependencies.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentKey is the carrier key for the W3C traceparent header.
+const TraceparentKey = "traceparent"
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are non-zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceIDString returns the trace ID as lowercase hex.
+func (sc SpanContext) TraceIDString() string { return hex.EncodeToString(sc.TraceID[:]) }
+
+// SpanIDString returns the span ID as lowercase hex.
+func (sc SpanContext) SpanIDString() string { return hex.EncodeToString(sc.SpanID[:]) }
+
+// Traceparent renders the span context as a version 00 traceparent value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceIDString() + "-" + sc.SpanIDString() + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent value.
+func ParseTraceparent(s string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, fmt.Errorf("malformed traceparent %q", s)
+	}
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("unsupported traceparent version %q", parts[0])
+	}
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, fmt.Errorf("traceparent trace ID: %w", err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, fmt.Errorf("traceparent span ID: %w", err)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}, fmt.Errorf("traceparent flags: %w", err)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, errors.New("traceparent has zero trace or span ID")
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// Attr is a span attribute.
+type Attr struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// String returns a string attribute.
+func String(key, value string) Attr { return Attr{Key: key, Value: value} }
+
+// SpanData is a finished span as handed to an Exporter.
+type SpanData struct {
+	Name     string    `json:"name"`
+	TraceID  string    `json:"trace_id"`
+	SpanID   string    `json:"span_id"`
+	ParentID string    `json:"parent_id,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Attrs    []Attr    `json:"attributes,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Exporter receives finished spans. Implementations must be safe for
+// concurrent use.
+type Exporter interface {
+	Export(SpanData)
+}
+
+// Tracer starts spans and hands them to Exporter when they end. A nil
+// *Tracer starts no-op spans, so tracing can be left unconfigured.
+type Tracer struct {
+	Exporter Exporter
+}
+
+type spanKey struct{}
+
+// Start begins a span named name as a child of the span (local or remote)
+// in ctx, and returns a context carrying the new span.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: true}
+	if !parent.IsValid() {
+		_, _ = rand.Read(sc.TraceID[:])
+	} else {
+		sc.Sampled = parent.Sampled
+	}
+	_, _ = rand.Read(sc.SpanID[:])
+	s := &Span{tracer: t, data: SpanData{
+		Name:    name,
+		TraceID: sc.TraceIDString(),
+		SpanID:  sc.SpanIDString(),
+		Start:   time.Now().UTC(),
+		Attrs:   append([]Attr(nil), attrs...),
+	}, sc: sc}
+	if parent.IsValid() {
+		s.data.ParentID = parent.SpanIDString()
+	}
+	return context.WithValue(ctx, spanKey{}, sc), s
+}
+
+// ContextWithSpanContext returns ctx with sc as the current (typically
+// remote) parent.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// SpanContextFromContext returns the current span context, if any.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanKey{}).(SpanContext)
+	return sc
+}
+
+// Inject writes the current span context into carrier.
+func Inject(ctx context.Context, carrier map[string]string) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() && carrier != nil {
+		carrier[TraceparentKey] = sc.Traceparent()
+	}
+}
+
+// Extract returns ctx with the remote parent found in carrier. Invalid or
+// missing trace context leaves ctx unchanged.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	sc, err := ParseTraceparent(carrier[TraceparentKey])
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// Span is an in-progress operation. All methods are safe on a nil *Span.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the span's identity.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and exports it. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now().UTC()
+	data := s.data
+	s.mu.Unlock()
+	if s.tracer.Exporter != nil && s.sc.Sampled {
+		s.tracer.Exporter.Export(data)
+	}
+}
+
+// Recorder is an Exporter that keeps finished spans in memory.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// Export implements Exporter.
+func (r *Recorder) Export(s SpanData) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+// Spans returns the recorded spans in the order they ended.
+func (r *Recorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SpanData(nil), r.spans...)
+}
+
+// JSONExporter writes each finished span to W as one JSON line.
+type JSONExporter struct {
+	W  io.Writer
+	mu sync.Mutex
+}
+
+// Export implements Exporter. Write errors are dropped; tracing must never
+// fail the traced operation.
+func (e *JSONExporter) Export(s SpanData) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = e.W.Write(append(b, '\n'))
+}
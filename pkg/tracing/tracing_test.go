@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(tp)
+	if err != nil {
+		t.Fatalf("ParseTraceparent: %v", err)
+	}
+	if !sc.Sampled || sc.TraceIDString() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.Traceparent() != tp {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+}
+
+func TestTracer_ParentChild(t *testing.T) {
+	rec := &Recorder{}
+	tr := &Tracer{Exporter: rec}
+	ctx, root := tr.Start(context.Background(), "root", String("k", "v"))
+	_, child := tr.Start(ctx, "child")
+	child.RecordError(errors.New("boom"))
+	child.End()
+	child.End()
+	root.End()
+
+	spans := rec.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].TraceID != spans[1].TraceID || spans[0].ParentID != spans[1].SpanID || spans[1].ParentID != "" {
+		t.Fatalf("bad linkage %+v", spans)
+	}
+	if spans[0].Error != "boom" || spans[1].Attrs[0] != String("k", "v") {
+		t.Fatalf("unexpected span data %+v", spans)
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	rec := &Recorder{}
+	tr := &Tracer{Exporter: rec}
+	ctx, span := tr.Start(context.Background(), "controller")
+	carrier := map[string]string{}
+	Inject(ctx, carrier)
+
+	remote := Extract(context.Background(), carrier)
+	_, agent := tr.Start(remote, "agent")
+	agent.End()
+	span.End()
+	spans := rec.Spans()
+	if spans[0].ParentID != spans[1].SpanID || spans[0].TraceID != span.SpanContext().TraceIDString() {
+		t.Fatalf("agent span not parented by controller: %+v", spans)
+	}
+	if got := Extract(context.Background(), map[string]string{TraceparentKey: "junk"}); SpanContextFromContext(got).IsValid() {
+		t.Fatal("junk traceparent should be ignored")
+	}
+}
+
+func TestNilTracerAndJSONExporter(t *testing.T) {
+	var tr *Tracer
+	ctx, span := tr.Start(context.Background(), "noop")
+	span.SetAttributes(String("a", "b"))
+	span.RecordError(errors.New("x"))
+	span.End()
+	if SpanContextFromContext(ctx).IsValid() {
+		t.Fatal("nil tracer should not set a span context")
+	}
+
+	var buf bytes.Buffer
+	_, s := (&Tracer{Exporter: &JSONExporter{W: &buf}}).Start(context.Background(), "json")
+	s.End()
+	var d SpanData
+	if err := json.Unmarshal(buf.Bytes(), &d); err != nil || d.Name != "json" {
+		t.Fatalf("JSONExporter wrote %q: %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,269 @@
+// Package vectors publishes deterministic test vectors for signed tasks, so
+// verifiers written in other languages can prove they accept and reject
+// exactly what this Go reference does.
+//
+// The vectors ship as vectors.json, embedded here and readable directly by
+// other test suites. Each one gives the signing key's seed, the task's
+// canonical JSON (the bytes encoding/json produces, which a verifier that
+// re-encodes tasks must reproduce byte for byte), the exact message
+// signed, the signed task as it travels on the wire, and whether it must
+// verify. Byte fields in the wire form are standard base64, as
+// encoding/json writes []byte; elsewhere they are hex.
+//
+// Verification here means the signature check of rte.VerifyTaskSignature,
+// not expiry: every vector's task has long since expired. Generate
+// rebuilds the file; its output never varies, so a changed vector is a
+// wire-compatibility break.
+package vectors
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+//go:embed vectors.json
+var vectorsJSON []byte
+
+// SuiteVersion changes whenever vectors are added or changed.
+const SuiteVersion = 1
+
+// Suite is a set of test vectors.
+type Suite struct {
+	Version           int      `json:"version"`
+	TaskSchemaVersion int      `json:"task_schema_version"`
+	Algorithm         string   `json:"algorithm"`
+	Vectors           []Vector `json:"vectors"`
+}
+
+// Vector is one signed task and the outcome verifying it must have.
+type Vector struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Seed is the hex Ed25519 seed of the key that signed the task, and
+	// PublicKey its hex public key. A vector may carry a different key in
+	// SignedTask.
+	Seed      string `json:"seed"`
+	PublicKey string `json:"public_key"`
+	// CanonicalTask is the canonical JSON of the task as signed.
+	CanonicalTask string `json:"canonical_task"`
+	// Message is the hex of the exact bytes signed: CanonicalTask, or for
+	// a detached signature "RTE-A task sha256:" and its raw digest.
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	// SignedTask is the signed task as JSON on the wire.
+	SignedTask json.RawMessage `json:"signed_task"`
+	// Valid is whether SignedTask must verify.
+	Valid bool `json:"valid"`
+}
+
+// Load returns the embedded suite.
+func Load() (*Suite, error) {
+	var s Suite
+	if err := json.Unmarshal(vectorsJSON, &s); err != nil {
+		return nil, fmt.Errorf("vectors: embedded suite: %w", err)
+	}
+	return &s, nil
+}
+
+// Verify is the reference verifier: it decodes a signed task from JSON and
+// checks its signature with rte.VerifyTaskSignature.
+func Verify(signedTask []byte) error {
+	var st rte.SignedTask
+	if err := json.Unmarshal(signedTask, &st); err != nil {
+		return fmt.Errorf("decode signed task: %w", err)
+	}
+	return rte.VerifyTaskSignature(&st)
+}
+
+// Mismatch is a vector a verifier got wrong.
+type Mismatch struct {
+	Name string
+	// Want is the vector's expected outcome; Err is what the verifier
+	// returned.
+	Want bool
+	Err  error
+}
+
+func (m Mismatch) String() string {
+	if m.Want {
+		return fmt.Sprintf("%s: rejected a valid task: %v", m.Name, m.Err)
+	}
+	return m.Name + ": accepted an invalid task"
+}
+
+// Run calls verify with each vector's signed task and returns the vectors
+// whose outcome differed from the expected one.
+func (s *Suite) Run(verify func(signedTask []byte) error) []Mismatch {
+	var out []Mismatch
+	for _, v := range s.Vectors {
+		err := verify(v.SignedTask)
+		if (err == nil) != v.Valid {
+			out = append(out, Mismatch{Name: v.Name, Want: v.Valid, Err: err})
+		}
+	}
+	return out
+}
+
+// key returns the deterministic key for seed byte b.
+func key(b byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{b}, ed25519.SeedSize))
+}
+
+func baseTask() rte.Task {
+	return rte.Task{
+		SchemaVersion: rte.TaskSchemaVersion,
+		ID:            "01JBVECT0R000000000000000A",
+		Engagement:    "eng-vectors",
+		Type:          rte.TaskSimulateLogin,
+		CreatedAt:     time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC),
+		TTLSeconds:    600,
+		Operator:      "op-alice",
+		ApprovedBy:    "lead-bob",
+		State:         rte.StatePending,
+		Params:        map[string]string{"target": "10.0.0.5"},
+	}
+}
+
+// builder signs vectors; the first error sticks.
+type builder struct {
+	vectors []Vector
+	err     error
+}
+
+// add signs task with the key for seed, lets edit tamper with the signed
+// task, and records the result. If edit returns a message, the task is
+// signed over it instead.
+func (b *builder) add(name, desc string, seed byte, task rte.Task, detached, valid bool, edit func(*rte.SignedTask) []byte) {
+	if b.err != nil {
+		return
+	}
+	priv := key(seed)
+	pub := priv.Public().(ed25519.PublicKey)
+	canonical, err := json.Marshal(task)
+	if err != nil {
+		b.err = fmt.Errorf("%s: %w", name, err)
+		return
+	}
+	st := &rte.SignedTask{Task: task, PublicKey: pub}
+	msg := canonical
+	if detached {
+		digest := sha256.Sum256(canonical)
+		st.Digest = digest[:]
+		msg = append([]byte("RTE-A task sha256:"), digest[:]...)
+	}
+	st.Signature = ed25519.Sign(priv, msg)
+	if edit != nil {
+		if m := edit(st); m != nil {
+			msg = m
+			st.Signature = ed25519.Sign(priv, msg)
+		}
+	}
+	wire, err := json.Marshal(st)
+	if err != nil {
+		b.err = fmt.Errorf("%s: %w", name, err)
+		return
+	}
+	b.vectors = append(b.vectors, Vector{
+		Name:          name,
+		Description:   desc,
+		Seed:          hex.EncodeToString(priv.Seed()),
+		PublicKey:     hex.EncodeToString(pub),
+		CanonicalTask: string(canonical),
+		Message:       hex.EncodeToString(msg),
+		Signature:     hex.EncodeToString(st.Signature),
+		SignedTask:    wire,
+		Valid:         valid,
+	})
+}
+
+// Generate builds the suite from fixed keys and tasks. Its output is the
+// same on every run, and matches the embedded suite.
+func Generate() (*Suite, error) {
+	var b builder
+
+	b.add("task-minimal", "A task with only the required fields.", 1, baseTask(), false, true, nil)
+
+	full := baseTask()
+	full.ID = "01JBVECT0R000000000000000B"
+	full.CreatedAt = time.Date(2026, 3, 2, 9, 30, 0, 123456789, time.FixedZone("", -5*3600))
+	full.CancelToken = "c4nc3l"
+	full.Params = map[string]string{"target": "10.0.0.5", "note": "<html> & \"quotes\"", "empty": ""}
+	full.Priority = 3
+	full.Techniques = []string{"T1110.003", "T1078"}
+	full.ExpectedDetections = []rte.ExpectedDetection{
+		{Technique: "T1110.003", Rule: "Password Spray", Query: `index=auth action=failure | stats dc(user) by src`},
+	}
+	full.Classification = rte.ClassificationConfidential
+	full.Selector = "os=windows,zone=dmz"
+	b.add("task-full", "Optional fields, a non-UTC time with nanoseconds, and HTML characters, which encoding/json escapes.", 2, full, false, true,
+		func(st *rte.SignedTask) []byte {
+			st.Trace = map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+			return nil
+		})
+
+	uni := baseTask()
+	uni.ID = "01JBVECT0R000000000000000C"
+	uni.Params = map[string]string{"user": "andré", "motto": "✓ ok 🚀", "sep": "a\u2028b", "tab": "a\tb"}
+	b.add("task-unicode", "Non-ASCII parameters: UTF-8 is kept as is, except U+2028, which is escaped, as are control characters.", 3, uni, false, true, nil)
+
+	b.add("task-detached", "A detached signature over the digest of the canonical task, with the task attached.", 4, baseTask(), true, true, nil)
+
+	b.add("task-tampered-param", "A parameter changed after signing.", 1, baseTask(), false, false,
+		func(st *rte.SignedTask) []byte {
+			st.Task.Params = map[string]string{"target": "10.0.0.6"}
+			return nil
+		})
+	b.add("task-wrong-key", "Signed by one key but carrying another.", 1, baseTask(), false, false,
+		func(st *rte.SignedTask) []byte {
+			st.PublicKey = key(5).Public().(ed25519.PublicKey)
+			return nil
+		})
+	b.add("task-short-signature", "A signature one byte short.", 1, baseTask(), false, false,
+		func(st *rte.SignedTask) []byte {
+			st.Signature = st.Signature[:ed25519.SignatureSize-1]
+			return nil
+		})
+	b.add("task-short-public-key", "A public key one byte short.", 1, baseTask(), false, false,
+		func(st *rte.SignedTask) []byte {
+			st.PublicKey = st.PublicKey[:ed25519.PublicKeySize-1]
+			return nil
+		})
+	b.add("task-detached-mismatch", "A detached signature whose attached task is not the one digested.", 4, baseTask(), true, false,
+		func(st *rte.SignedTask) []byte {
+			st.Task.TTLSeconds = 900
+			return nil
+		})
+
+	// Signing a different encoding of the same task must not verify: the
+	// signature covers the canonical bytes, not the task's meaning.
+	reenc := full
+	reenc.ID = "01JBVECT0R000000000000000D"
+	b.add("task-noncanonical", "Signed over the task encoded without HTML escaping, so the canonical bytes differ.", 2, reenc, false, false,
+		func(st *rte.SignedTask) []byte {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(st.Task); err != nil {
+				b.err = err
+				return nil
+			}
+			return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+		})
+
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.vectors) == 0 {
+		return nil, errors.New("vectors: none generated")
+	}
+	return &Suite{Version: SuiteVersion, TaskSchemaVersion: rte.TaskSchemaVersion, Algorithm: "ed25519", Vectors: b.vectors}, nil
+}
@@ -0,0 +1,85 @@
+package vectors
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/codethor0/rte-a-reference/pkg/rte"
+)
+
+var update = flag.Bool("update", false, "rewrite vectors.json")
+
+// TestGenerate_MatchesEmbedded fails when a change to this module alters
+// the wire format a vector pins down.
+func TestGenerate_MatchesEmbedded(t *testing.T) {
+	s, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+	if *update {
+		if err := os.WriteFile("vectors.json", data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if !bytes.Equal(data, vectorsJSON) {
+		t.Fatal("vectors.json is out of date; the wire format changed, or run go test -update and bump SuiteVersion")
+	}
+}
+
+// TestConformance is the runner other implementations mirror: every vector
+// verifies, or fails to, as marked.
+func TestConformance(t *testing.T) {
+	s, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Vectors) == 0 || s.Version != SuiteVersion {
+		t.Fatalf("suite version %d with %d vectors", s.Version, len(s.Vectors))
+	}
+	for _, m := range s.Run(Verify) {
+		t.Error(m)
+	}
+}
+
+// TestVectors_Consistent checks the fields a verifier uses to debug a
+// mismatch agree with the signed task.
+func TestVectors_Consistent(t *testing.T) {
+	s, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range s.Vectors {
+		var st rte.SignedTask
+		if err := json.Unmarshal(v.SignedTask, &st); err != nil {
+			t.Fatalf("%s: %v", v.Name, err)
+		}
+		if sig, _ := hex.DecodeString(v.Signature); !bytes.Equal(sig, st.Signature) {
+			t.Errorf("%s: signature field differs from the signed task", v.Name)
+		}
+		seed, _ := hex.DecodeString(v.Seed)
+		if len(seed) != ed25519.SeedSize || hex.EncodeToString(ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)) != v.PublicKey {
+			t.Errorf("%s: public key does not match seed", v.Name)
+		}
+		if !v.Valid {
+			continue
+		}
+		canonical, err := json.Marshal(st.Task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(canonical) != v.CanonicalTask {
+			t.Errorf("%s: re-encoded task is not canonical:\n got %s\nwant %s", v.Name, canonical, v.CanonicalTask)
+		}
+	}
+}